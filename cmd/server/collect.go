@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+type dataCollector interface {
+	Collect(ctx context.Context) error
+	Diff(ctx context.Context) (models.CatalogDiff, error)
+}
+
+// runCollect handles "collect" command, it collects the source once:
+//
+//	collect -dry-run -out=diff.json
+func runCollect(ctx context.Context, args []string, dc dataCollector, stdout io.Writer) error {
+	fs := flag.NewFlagSet("collect", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "do not save anything, write the diff with the catalog")
+	out := fs.String("out", "", "diff output file path, stdout if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !*dryRun {
+		return dc.Collect(ctx)
+	}
+	diff, err := dc.Diff(ctx)
+	if err != nil {
+		return err
+	}
+	w := stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diff)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/export"
+)
+
+type productExporter interface {
+	ExportProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error
+}
+
+type categoryExporter interface {
+	ExportCategories(ctx context.Context, fn func(models.Category) error) error
+}
+
+// runExport handles "export" command:
+//
+//	export -entity=products -format=xlsx -columns=name,category_codes -category=code -out=products.xlsx
+func runExport(ctx context.Context, args []string, pe productExporter, ce categoryExporter) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	entity := fs.String("entity", export.EntityProducts, "what to export: products or categories")
+	format := fs.String("format", export.FormatCSV, "output format: csv, ndjson or xlsx")
+	columns := fs.String("columns", "", "comma separated columns, all columns if empty")
+	category := fs.String("category", "", "comma separated category codes to filter products")
+	out := fs.String("out", "", "output file path, <entity>.<format> if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = fmt.Sprintf("%s.%s", *entity, *format)
+	}
+	var allowed []string
+	switch *entity {
+	case export.EntityProducts:
+		allowed = export.ProductColumns
+	case export.EntityCategories:
+		allowed = export.CategoryColumns
+	default:
+		return fmt.Errorf("unknown entity %s", *entity)
+	}
+	cols, err := export.Columns(splitComma(*columns), allowed)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if *entity == export.EntityProducts {
+		err = export.WriteProducts(ctx, file, *format, cols, models.ProductFilter{
+			CategoryCodes: splitComma(*category),
+		}, pe)
+	} else {
+		err = export.WriteCategories(ctx, file, *format, cols, ce)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func splitComma(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -11,13 +11,19 @@ import (
 	"syscall"
 
 	"github.com/EwvwGeN/cataloger/internal/app"
+	"github.com/EwvwGeN/cataloger/internal/collector"
 	c "github.com/EwvwGeN/cataloger/internal/config"
 	"github.com/EwvwGeN/cataloger/internal/http/middleware"
 	v1 "github.com/EwvwGeN/cataloger/internal/http/v1"
+	"github.com/EwvwGeN/cataloger/internal/jobs"
 	"github.com/EwvwGeN/cataloger/internal/jwt"
 	l "github.com/EwvwGeN/cataloger/internal/logger"
+	"github.com/EwvwGeN/cataloger/internal/media"
+	"github.com/EwvwGeN/cataloger/internal/outbox"
 	"github.com/EwvwGeN/cataloger/internal/service"
 	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/EwvwGeN/cataloger/internal/storage/memory"
+	"github.com/EwvwGeN/cataloger/internal/storage/sqlite"
 )
 
 var (
@@ -40,17 +46,109 @@ func main() {
 
 	jwtManager := jwt.NewJwtManager(cfg.SecretKey)
 
-	postgres, err := storage.NewPostgresProvider(mainCtx, cfg.PostgresConfig)
+	if flag.Arg(0) == "migrate" && cfg.Storage != c.StoragePostgres && cfg.Storage != "" {
+		logger.Error("failed to migrate", slog.String("error", "migrations are used only by postgres storage"))
+		os.Exit(1)
+	}
+	var repo catalogStorage
+	var sessionStarter readSessionStarter
+	switch cfg.Storage {
+	case c.StorageMemory:
+		logger.Warn("memory storage is used, data will be lost after stop")
+		repo = memory.NewMemoryProvider()
+	case c.StorageSqlite:
+		sqliteProvider, err := sqlite.NewSqliteProvider(mainCtx, cfg.SqliteConfig)
+		if err != nil {
+			logger.Error("failed to get sqlite provider", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		repo = sqliteProvider
+	case c.StoragePostgres, "":
+		postgres, err := storage.NewPostgresProvider(mainCtx, logger, cfg.PostgresConfig)
+		if err != nil {
+			logger.Error("failed to get postgres provider", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		if flag.Arg(0) == "migrate" {
+			err := runMigrate(mainCtx, flag.Args()[1:], postgres, os.Stdout)
+			postgres.Close()
+			if err != nil {
+				logger.Error("failed to migrate", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			logger.Info("migrate finished")
+			cancel()
+			return
+		}
+		if cfg.PostgresConfig.MigrateOnStart {
+			applied, err := postgres.MigrateUp(mainCtx)
+			if err != nil {
+				postgres.Close()
+				logger.Error("failed to apply migrations", slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+			logger.Info("migrations are applied", slog.Int("count", len(applied)))
+		}
+		if cfg.PostgresConfig.ReadYourWrites {
+			sessionStarter = postgres
+		}
+		repo = postgres
+	default:
+		logger.Error("unknown storage", slog.String("storage", cfg.Storage))
+		os.Exit(1)
+	}
+	defer repo.Close()
+
+	authService := service.NewAuthService(logger, cfg.TokenTTL, cfg.RefreshTTL, repo, jwtManager)
+	categoryService := service.NewCategoryService(logger, repo, repo, repo)
+	productService := service.NewProductService(logger, repo, repo, repo, repo)
+	variantService := service.NewVariantService(logger, repo, repo, repo, repo)
+	trashService := service.NewTrashService(logger, cfg.Trash, repo)
+	stockService := service.NewStockService(logger, repo, repo)
+	mediaStore, err := media.NewStore(cfg.Media)
 	if err != nil {
-		logger.Error("failed to get postgres provider", slog.String("error", err.Error()))
+		logger.Error("failed to create media store", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
+	mediaService := service.NewMediaService(logger, cfg.Media, mediaStore, repo, repo, repo, repo)
+
+	if flag.Arg(0) == "export" {
+		if err := runExport(mainCtx, flag.Args()[1:], productService, categoryService); err != nil {
+			logger.Error("failed to export", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("export finished")
+		cancel()
+		return
+	}
 
-	authService := service.NewAuthService(logger, cfg.TokenTTL, cfg.RefreshTTL, postgres, jwtManager)
-	categoryService := service.NewCategoryService(logger, postgres)
-	productService := service.NewProductService(logger, postgres, postgres)
+	dataCollector := collector.NewCollector(logger, cfg.DataCollectLink, cfg.DataCollectTime, cfg.DataCollectDryRun, cfg.Ingestion, repo)
+	if flag.Arg(0) == "collect" {
+		if err := runCollect(mainCtx, flag.Args()[1:], dataCollector, os.Stdout); err != nil {
+			logger.Error("failed to collect", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("collect finished")
+		cancel()
+		return
+	}
+	jobManager := jobs.NewManager(logger, cfg.Jobs, cfg.Validator, cfg.Ingestion, repo, productService)
+	sinks, err := outbox.NewSinks(logger, cfg.Outbox)
+	if err != nil {
+		logger.Error("failed to get outbox sinks", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	eventRelay := outbox.NewRelay(logger, cfg.Outbox, repo, sinks)
 
 	hserver := app.NewHttpServer(cfg.HttpConfig, logger)
+	if sessionStarter != nil {
+		hserver.Use(middleware.ReadYourWrites(sessionStarter))
+	}
+	hserver.RegisterHandler(
+		"/api/stats/db",
+		middleware.AuthMiddleware(logger, jwtManager, v1.DBStats(logger, repo)),
+		http.MethodGet,
+	)
 	hserver.RegisterHandler(
 		"/api/register",
 		v1.Register(logger, authService, cfg.Validator),
@@ -96,6 +194,16 @@ func main() {
 		middleware.AuthMiddleware(logger, jwtManager, v1.ProductAdd(logger, cfg.Validator, productService)),
 		http.MethodPost,
 	)
+	hserver.RegisterHandler(
+		"/api/products/import",
+		middleware.AuthMiddleware(logger, jwtManager, v1.ProductImport(logger, cfg.Validator, cfg.Ingestion, productService, jobManager)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/state",
+		middleware.AuthMiddleware(logger, jwtManager, v1.ProductChangeState(logger, productService)),
+		http.MethodPost,
+	)
 	hserver.RegisterHandler(
 		"/api/product/{productId}/edit",
 		middleware.AuthMiddleware(logger, jwtManager, v1.ProductEdit(logger, cfg.Validator, productService)),
@@ -106,23 +214,197 @@ func main() {
 		middleware.AuthMiddleware(logger, jwtManager, v1.ProductDelete(logger, productService)),
 		http.MethodDelete,
 	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/variants/add",
+		middleware.AuthMiddleware(logger, jwtManager, v1.VariantAdd(logger, variantService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/variants/{variantId}/edit",
+		middleware.AuthMiddleware(logger, jwtManager, v1.VariantEdit(logger, variantService)),
+		http.MethodPatch,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/variants/{variantId}/delete",
+		middleware.AuthMiddleware(logger, jwtManager, v1.VariantDelete(logger, variantService)),
+		http.MethodDelete,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/variants/{variantId}",
+		middleware.OptionalAuthMiddleware(logger, jwtManager, v1.PublishedProduct(logger, productService, v1.VariantGetOne(logger, variantService))),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/variants",
+		middleware.OptionalAuthMiddleware(logger, jwtManager, v1.PublishedProduct(logger, productService, v1.VariantGetAll(logger, variantService))),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/stock/adjust",
+		middleware.AuthMiddleware(logger, jwtManager, v1.StockAdjust(logger, stockService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/stock/reserve",
+		middleware.AuthMiddleware(logger, jwtManager, v1.StockReserve(logger, stockService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/stock/movements",
+		middleware.AuthMiddleware(logger, jwtManager, v1.StockMovements(logger, stockService)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/stock",
+		middleware.OptionalAuthMiddleware(logger, jwtManager, v1.PublishedProduct(logger, productService, v1.StockGet(logger, stockService))),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/media/order",
+		middleware.AuthMiddleware(logger, jwtManager, v1.MediaOrder(logger, mediaService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/media/{mediaId}/primary",
+		middleware.AuthMiddleware(logger, jwtManager, v1.MediaSetPrimary(logger, mediaService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/media/{mediaId}/delete",
+		middleware.AuthMiddleware(logger, jwtManager, v1.MediaDelete(logger, mediaService)),
+		http.MethodDelete,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/media",
+		middleware.AuthMiddleware(logger, jwtManager, v1.MediaAdd(logger, cfg.Media, mediaService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/media",
+		middleware.OptionalAuthMiddleware(logger, jwtManager, v1.PublishedProduct(logger, productService, v1.MediaGetAll(logger, mediaService))),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/media/{key:.+}",
+		v1.MediaFile(logger, mediaStore),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/warehouse/add",
+		middleware.AuthMiddleware(logger, jwtManager, v1.WarehouseAdd(logger, stockService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/warehouses",
+		v1.WarehouseGetAll(logger, stockService),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/reservation/{reservationId}/release",
+		middleware.AuthMiddleware(logger, jwtManager, v1.ReservationRelease(logger, stockService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/reservation/{reservationId}/commit",
+		middleware.AuthMiddleware(logger, jwtManager, v1.ReservationCommit(logger, stockService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/reservation/{reservationId}",
+		middleware.AuthMiddleware(logger, jwtManager, v1.ReservationGet(logger, stockService)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/product/by-sku/{sku:.+}",
+		middleware.OptionalAuthMiddleware(logger, jwtManager, v1.ProductGetBySku(logger, productService)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/product/by-barcode/{barcode}",
+		middleware.OptionalAuthMiddleware(logger, jwtManager, v1.ProductGetByBarcode(logger, productService)),
+		http.MethodGet,
+	)
 	hserver.RegisterHandler(
 		"/api/product/{productId}",
-		v1.ProductGetOne(logger, productService),
+		middleware.OptionalAuthMiddleware(logger, jwtManager, v1.ProductGetOne(logger, productService)),
 		http.MethodGet,
 	)
 	hserver.RegisterHandler(
 		"/api/products",
-		v1.ProductGetAll(logger, productService),
+		middleware.OptionalAuthMiddleware(logger, jwtManager, v1.ProductGetAll(logger, productService)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/trash",
+		middleware.AuthMiddleware(logger, jwtManager, v1.TrashGet(logger, trashService)),
 		http.MethodGet,
 	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/restore",
+		middleware.AuthMiddleware(logger, jwtManager, v1.ProductRestore(logger, trashService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/category/{catCode}/restore",
+		middleware.AuthMiddleware(logger, jwtManager, v1.CategoryRestore(logger, trashService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/versions",
+		middleware.AuthMiddleware(logger, jwtManager, v1.ProductVersions(logger, productService)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/versions/diff",
+		middleware.AuthMiddleware(logger, jwtManager, v1.ProductVersionsDiff(logger, productService)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/product/{productId}/versions/{version}/revert",
+		middleware.AuthMiddleware(logger, jwtManager, v1.ProductRevert(logger, productService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/category/{catCode}/versions",
+		middleware.AuthMiddleware(logger, jwtManager, v1.CategoryVersions(logger, categoryService)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/category/{catCode}/versions/diff",
+		middleware.AuthMiddleware(logger, jwtManager, v1.CategoryVersionsDiff(logger, categoryService)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/category/{catCode}/versions/{version}/revert",
+		middleware.AuthMiddleware(logger, jwtManager, v1.CategoryRevert(logger, categoryService)),
+		http.MethodPost,
+	)
+	hserver.RegisterHandler(
+		"/api/export/{entity}",
+		middleware.AuthMiddleware(logger, jwtManager, v1.Export(logger, productService, categoryService)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/jobs/{jobId}",
+		middleware.AuthMiddleware(logger, jwtManager, v1.JobGetOne(logger, jobManager)),
+		http.MethodGet,
+	)
+	hserver.RegisterHandler(
+		"/api/jobs/{jobId}/cancel",
+		middleware.AuthMiddleware(logger, jwtManager, v1.JobCancel(logger, jobManager)),
+		http.MethodPost,
+	)
 	hserver.RegisterHandler(
 		"/api/products/{catCode}",
-		v1.ProductGetAllByCategory(logger, productService),
+		middleware.OptionalAuthMiddleware(logger, jwtManager, v1.ProductGetAllByCategory(logger, productService)),
 		http.MethodGet,
 	)
 	logger.Info("loading end")
 	errCh := hserver.RunServer(mainCtx)
+	collectorDoneCh := dataCollector.Run(mainCtx)
+	jobsDoneCh := jobManager.Run(mainCtx)
+	purgeDoneCh := trashService.Run(mainCtx)
+	relayDoneCh := eventRelay.Run(mainCtx)
 	stopChecker := make(chan os.Signal, 1)
 	signal.Notify(stopChecker, syscall.SIGTERM, syscall.SIGINT)
 	<- stopChecker
@@ -132,5 +414,9 @@ func main() {
 	if err != nil {
 		logger.Error("error while stopping http server", slog.String("error", err.Error()))
 	}
+	<-collectorDoneCh
+	<-jobsDoneCh
+	<-purgeDoneCh
+	<-relayDoneCh
 	logger.Info("service stoped successfully")
 }
\ No newline at end of file
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+type migrator interface {
+	MigrateUp(ctx context.Context) ([]models.Migration, error)
+	MigrateDown(ctx context.Context, steps int) ([]models.Migration, error)
+	MigrationStatus(ctx context.Context) ([]models.Migration, error)
+}
+
+// runMigrate handles "migrate" command:
+//
+//	migrate up
+//	migrate down -steps=1
+//	migrate status
+func runMigrate(ctx context.Context, args []string, m migrator, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate command is required: up, down or status")
+	}
+	var (
+		migrations []models.Migration
+		err error
+	)
+	switch args[0] {
+	case "up":
+		migrations, err = m.MigrateUp(ctx)
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := fs.Int("steps", 1, "number of migrations to revert")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *steps <= 0 {
+			return fmt.Errorf("steps must be positive")
+		}
+		migrations, err = m.MigrateDown(ctx, *steps)
+	case "status":
+		migrations, err = m.MigrationStatus(ctx)
+	default:
+		return fmt.Errorf("unknown migrate command %s", args[0])
+	}
+	for _, migration := range migrations {
+		state := "pending"
+		switch {
+		case migration.AppliedAt != nil:
+			state = "applied at " + migration.AppliedAt.Format(time.RFC3339)
+		case migration.Applied:
+			state = "applied"
+		case args[0] == "down":
+			state = "reverted"
+		}
+		fmt.Fprintf(stdout, "%04d_%s\t%s\n", migration.Version, migration.Name, state)
+	}
+	return err
+}
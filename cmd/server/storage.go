@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// catalogStorage is the backend shared by services, collector and jobs,
+// it is implemented by postgres and memory providers
+type catalogStorage interface {
+	SaveUser(ctx context.Context, email string, passHash string) error
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	SaveRefreshToken(ctx context.Context, email string, refreshToken string, rttl int64) error
+
+	SaveCategory(ctx context.Context, category models.Category) error
+	InserOrGetCategiriesId(ctx context.Context, categories []models.Category) (map[string]int, error)
+	GetCategoryByCode(ctx context.Context, catCode string) (models.Category, error)
+	GetAllCategories(ctx context.Context) ([]models.Category, error)
+	UpdateCategoryByCode(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch, version int) (models.Category, error)
+	DeleteCategoryBycode(ctx context.Context, catCode string, version int) (models.Category, error)
+	StreamCategories(ctx context.Context, fn func(models.Category) error) error
+	GetCategoriesIdByCodes(ctx context.Context, catCodes []string) ([]int, error)
+	GetCategoriesIdMapByCodes(ctx context.Context, catCodes []string) (map[string]int, error)
+	GetCategoriesAttributes(ctx context.Context, catCodes []string) (map[string][]models.Attribute, error)
+
+	SaveProduct(ctx context.Context, product models.Product, catIds []int) (string, error)
+	SaveProducts(ctx context.Context, products []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error)
+	GetProductById(ctx context.Context, prodId string) (models.Product, error)
+	GetProductBySku(ctx context.Context, sku string) (models.Product, error)
+	GetProductByBarcode(ctx context.Context, code string) (models.Product, error)
+	GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error)
+	GetProductsByCategory(ctx context.Context, catCode string, states []string) ([]models.Product, error)
+	StreamProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error
+	UpdateProductById(ctx context.Context, prodId string, newPorductdata models.ProductForPatch, catIds []int, version int) (models.Product, error)
+	DeleteProductById(ctx context.Context, id string, version int) (models.Product, error)
+	SetProductState(ctx context.Context, prodId, from, to string, version int) (models.Product, error)
+
+	SaveVariant(ctx context.Context, prodId string, variant models.Variant) (string, error)
+	UpdateVariantById(ctx context.Context, prodId, variantId string, variant models.Variant) error
+	DeleteVariantById(ctx context.Context, prodId, variantId string) error
+
+	SaveMedia(ctx context.Context, prodId string, media models.Media) (models.Media, error)
+	DeleteMediaById(ctx context.Context, prodId, mediaId string) (models.Media, error)
+	SetMediaOrder(ctx context.Context, prodId string, mediaIds []int) error
+	SetPrimaryMedia(ctx context.Context, prodId, mediaId string) error
+
+	SaveWarehouse(ctx context.Context, warehouse models.Warehouse) error
+	GetWarehouses(ctx context.Context) ([]models.Warehouse, error)
+	GetStock(ctx context.Context, prodId string) ([]models.StockLevel, error)
+	AdjustStock(ctx context.Context, prodId string, change models.StockChange) (models.StockLevel, error)
+	ReserveStock(ctx context.Context, prodId string, change models.StockChange) (models.Reservation, error)
+	GetReservation(ctx context.Context, reservationId string) (models.Reservation, error)
+	ReleaseReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error)
+	CommitReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error)
+	GetStockMovements(ctx context.Context, prodId string) ([]models.StockMovement, error)
+
+	GetTrash(ctx context.Context) (models.Trash, error)
+	RestoreProductById(ctx context.Context, prodId string) error
+	RestoreCategoryByCode(ctx context.Context, catCode string) error
+	PurgeTrash(ctx context.Context, before time.Time) (models.PurgeResult, error)
+
+	SaveVersions(ctx context.Context, versions []models.Version) error
+	GetVersions(ctx context.Context, entity, key string) ([]models.Version, error)
+	GetVersion(ctx context.Context, entity, key string, number int) (models.Version, error)
+
+	RelayEvents(ctx context.Context, limit int, claimTimeout time.Duration, publish func([]models.Event) models.RelayResult) error
+
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+
+	SaveJob(ctx context.Context, job models.Job) (string, error)
+	GetJobById(ctx context.Context, jobId string) (models.Job, error)
+	ClaimQueuedJob(ctx context.Context) (models.Job, error)
+	UpdateJobProgress(ctx context.Context, jobId string, counters models.JobCounters) (bool, error)
+	FinishJob(ctx context.Context, job models.Job) error
+	RequestJobCancel(ctx context.Context, jobId string) (string, error)
+	RecoverRunningJobs(ctx context.Context, resume bool) (int, error)
+
+	PoolStats() models.PoolStats
+	Close()
+}
+
+// readSessionStarter is implemented by postgres provider when reads go to replicas
+type readSessionStarter interface {
+	ReadYourWrites(ctx context.Context) context.Context
+}
@@ -0,0 +1,18 @@
+// Package actor carries the user making a change in the context,
+// so the changes are recorded with the user without knowing how the user is authorized
+package actor
+
+import "context"
+
+type actorKey struct{}
+
+// With returns the context of the changes made by the user with email
+func With(ctx context.Context, email string) context.Context {
+	return context.WithValue(ctx, actorKey{}, email)
+}
+
+// From returns email of the user put in ctx by With, empty if there is none
+func From(ctx context.Context) string {
+	email, _ := ctx.Value(actorKey{}).(string)
+	return email
+}
@@ -53,6 +53,11 @@ func(s *server) RegisterHandler(url string, handler http.HandlerFunc, method str
 	).Methods(method)
 }
 
+// Use adds middleware applied to every registered handler
+func(s *server) Use(mw func(http.Handler) http.Handler) {
+	s.router.Use(mw)
+}
+
 func (s *server) configureRouter() {
 	s.router.HandleFunc(
 		"/api/healthcheck",
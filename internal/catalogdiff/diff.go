@@ -0,0 +1,143 @@
+package catalogdiff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// Compute compares incoming rows with the current catalog.
+//
+// A row matches an existing product by its id if it is set, otherwise by sku if it is set and by name
+// for rows without sku, so name changes are found only for rows with id or sku. Repeated rows of the same product
+// are ignored like duplicates are skipped on save. Rows referencing categories that
+// neither exist nor come with the feed in newCategories are invalid.
+// Products that no row matched are reported as missing from the feed
+func Compute(products []models.Product, categories []models.Category, rows []models.ImportRow, newCategories []models.Category) models.CatalogDiff {
+	var diff models.CatalogDiff
+	byId := make(map[int]int, len(products))
+	bySku := make(map[string]int, len(products))
+	byName := make(map[string]int, len(products))
+	for idx, product := range products {
+		byId[product.Id] = idx
+		if product.Sku != "" {
+			bySku[product.Sku] = idx
+		}
+		byName[product.Name] = idx
+	}
+	knownCodes := make(map[string]struct{}, len(categories))
+	for _, category := range categories {
+		knownCodes[category.Code] = struct{}{}
+	}
+	for _, category := range newCategories {
+		if _, ok := knownCodes[category.Code]; ok {
+			continue
+		}
+		knownCodes[category.Code] = struct{}{}
+		diff.NewCategories = append(diff.NewCategories, category)
+	}
+
+	matched := make(map[int]struct{}, len(products))
+	seenKeys := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		if unknown := unknownCodes(row.Product.CategoryСodes, knownCodes); len(unknown) != 0 {
+			diff.Invalid = append(diff.Invalid, models.ImportRowResult{
+				Row: row.Row,
+				Name: row.Product.Name,
+				Status: models.ImportStatusInvalid,
+				Reason: fmt.Sprintf("unknown category codes: %s", strings.Join(unknown, ", ")),
+			})
+			continue
+		}
+		idx, ok := byId[row.Product.Id]
+		// new products are told apart by the key they are matched by on save
+		key := "name:" + row.Product.Name
+		if row.Product.Id == 0 || !ok {
+			if row.Product.Sku != "" {
+				idx, ok = bySku[row.Product.Sku]
+				key = "sku:" + row.Product.Sku
+			} else {
+				idx, ok = byName[row.Product.Name]
+			}
+		}
+		if !ok {
+			if _, seen := seenKeys[key]; seen {
+				continue
+			}
+			seenKeys[key] = struct{}{}
+			diff.NewProducts = append(diff.NewProducts, models.DiffProduct{
+				Row: row.Row,
+				Product: row.Product,
+			})
+			continue
+		}
+		if _, seen := matched[idx]; seen {
+			continue
+		}
+		matched[idx] = struct{}{}
+		if change, changed := compareProduct(row, products[idx]); changed {
+			diff.ChangedProducts = append(diff.ChangedProducts, change)
+		} else {
+			diff.Summary.Unchanged++
+		}
+	}
+	for idx, product := range products {
+		if _, ok := matched[idx]; !ok {
+			diff.MissingProducts = append(diff.MissingProducts, product)
+		}
+	}
+	diff.Summary.New = len(diff.NewProducts)
+	diff.Summary.Changed = len(diff.ChangedProducts)
+	diff.Summary.Missing = len(diff.MissingProducts)
+	diff.Summary.NewCategories = len(diff.NewCategories)
+	diff.Summary.Invalid = len(diff.Invalid)
+	return diff
+}
+
+func compareProduct(row models.ImportRow, current models.Product) (models.ProductChange, bool) {
+	change := models.ProductChange{
+		Row: row.Row,
+		Id: current.Id,
+		Name: current.Name,
+	}
+	if row.Product.Name != current.Name {
+		change.NameChange = &models.FieldChange{Old: current.Name, New: row.Product.Name}
+	}
+	if row.Product.Description != "" && row.Product.Description != current.Description {
+		change.DescriptionChange = &models.FieldChange{Old: current.Description, New: row.Product.Description}
+	}
+	change.AddedCategories = subtract(row.Product.CategoryСodes, current.CategoryСodes)
+	change.RemovedCategories = subtract(current.CategoryСodes, row.Product.CategoryСodes)
+	changed := change.NameChange != nil || change.DescriptionChange != nil ||
+		len(change.AddedCategories) != 0 || len(change.RemovedCategories) != 0
+	return change, changed
+}
+
+// subtract returns codes from a that are not in b
+func subtract(a, b []string) []string {
+	var out []string
+	for _, code := range a {
+		found := false
+		for _, other := range b {
+			if code == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, code)
+		}
+	}
+	return out
+}
+
+func unknownCodes(codes []string, known map[string]struct{}) []string {
+	var unknown []string
+	for _, code := range codes {
+		if _, ok := known[code]; !ok {
+			unknown = append(unknown, code)
+		}
+	}
+	return unknown
+}
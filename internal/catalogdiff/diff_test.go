@@ -0,0 +1,160 @@
+package catalogdiff_test
+
+import (
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/catalogdiff"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/stretchr/testify/suite"
+)
+
+type diffTestSuite struct {
+	suite.Suite
+	products []models.Product
+	categories []models.Category
+}
+
+func TestDiffSuiteRun(t *testing.T) {
+	suite.Run(t, new(diffTestSuite))
+}
+
+func (suite *diffTestSuite) SetupSuite() {
+	suite.products = []models.Product{
+		{Id: 1, Name: "Shirt", Description: "Cotton shirt", CategoryСodes: []string{"clothes"}},
+		{Id: 2, Name: "Hat", Description: "Wool hat", CategoryСodes: []string{"clothes", "sale"}},
+		{Id: 3, Name: "Jeans", Description: "Blue jeans"},
+		{Id: 4, Name: "Scarf", Description: "Wool scarf"},
+	}
+	suite.categories = []models.Category{
+		{Name: "Clothes", Code: "clothes", Description: "Clothes"},
+		{Name: "Sale", Code: "sale", Description: "Sale"},
+	}
+}
+
+func (suite *diffTestSuite) Test_Compute() {
+	rows := []models.ImportRow{
+		{Row: 1, Product: models.Product{Name: "Shirt", Description: "Cotton shirt", CategoryСodes: []string{"clothes"}}},
+		{Row: 2, Product: models.Product{Name: "Hat", Description: "Warm hat", CategoryСodes: []string{"clothes", "winter"}}},
+		{Row: 3, Product: models.Product{Id: 3, Name: "Black jeans", Description: "Blue jeans"}},
+		{Row: 4, Product: models.Product{Name: "Gloves", Description: "Wool gloves", CategoryСodes: []string{"winter"}}},
+		{Row: 5, Product: models.Product{Name: "Gloves", Description: "Other gloves"}},
+		{Row: 6, Product: models.Product{Name: "Socks", Description: "Socks", CategoryСodes: []string{"unknown"}}},
+	}
+	newCategories := []models.Category{
+		{Name: "Clothes", Code: "clothes", Description: "Clothes"},
+		{Name: "Winter", Code: "winter", Description: "Winter"},
+	}
+	diff := catalogdiff.Compute(suite.products, suite.categories, rows, newCategories)
+
+	suite.Equal(models.DiffSummary{
+		New: 1,
+		Changed: 2,
+		Unchanged: 1,
+		Missing: 1,
+		NewCategories: 1,
+		Invalid: 1,
+	}, diff.Summary)
+	suite.Equal([]models.DiffProduct{{Row: 4, Product: rows[3].Product}}, diff.NewProducts)
+	suite.Equal([]models.ProductChange{
+		{
+			Row: 2,
+			Id: 2,
+			Name: "Hat",
+			DescriptionChange: &models.FieldChange{Old: "Wool hat", New: "Warm hat"},
+			AddedCategories: []string{"winter"},
+			RemovedCategories: []string{"sale"},
+		},
+		{
+			Row: 3,
+			Id: 3,
+			Name: "Jeans",
+			NameChange: &models.FieldChange{Old: "Jeans", New: "Black jeans"},
+		},
+	}, diff.ChangedProducts)
+	suite.Equal([]models.Product{suite.products[3]}, diff.MissingProducts)
+	suite.Equal([]models.Category{newCategories[1]}, diff.NewCategories)
+	suite.Require().Len(diff.Invalid, 1)
+	suite.Equal(6, diff.Invalid[0].Row)
+	suite.Equal(models.ImportStatusInvalid, diff.Invalid[0].Status)
+}
+
+func (suite *diffTestSuite) Test_ComputeBySku() {
+	products := []models.Product{
+		{Id: 1, Name: "Shirt", Description: "Cotton shirt", Sku: "SHIRT-1"},
+		{Id: 2, Name: "Hat", Description: "Wool hat"},
+	}
+	rows := []models.ImportRow{
+		{Row: 1, Product: models.Product{Name: "Linen shirt", Description: "Cotton shirt", Sku: "SHIRT-1"}},
+		{Row: 2, Product: models.Product{Name: "Hat", Description: "Wool hat", Sku: "HAT-1"}},
+		{Row: 3, Product: models.Product{Name: "Other hat", Description: "Wool hat", Sku: "HAT-1"}},
+	}
+	diff := catalogdiff.Compute(products, nil, rows, nil)
+
+	suite.Equal(models.DiffSummary{New: 1, Changed: 1, Missing: 1}, diff.Summary)
+	suite.Equal([]models.ProductChange{{
+		Row: 1,
+		Id: 1,
+		Name: "Shirt",
+		NameChange: &models.FieldChange{Old: "Shirt", New: "Linen shirt"},
+	}}, diff.ChangedProducts)
+	suite.Equal([]models.DiffProduct{{Row: 2, Product: rows[1].Product}}, diff.NewProducts)
+	suite.Equal([]models.Product{products[1]}, diff.MissingProducts)
+}
+
+func (suite *diffTestSuite) Test_ComputeEmptyFeed() {
+	diff := catalogdiff.Compute(suite.products, suite.categories, nil, nil)
+	suite.Equal(models.DiffSummary{Missing: len(suite.products)}, diff.Summary)
+	suite.Equal(suite.products, diff.MissingProducts)
+}
+
+func (suite *diffTestSuite) Test_Versions() {
+	from := models.Version{
+		Version: 1,
+		Snapshot: []byte(`{"id": 1, "name": "Shirt", "description": "Cotton shirt", "category_codes": ["clothes", "sale"]}`),
+	}
+	to := models.Version{
+		Version: 3,
+		Snapshot: []byte(`{"id": 1, "name": "Linen shirt", "description": "Cotton shirt", "category_codes": ["clothes", "summer"]}`),
+	}
+	diff, err := catalogdiff.Versions(from, to)
+	suite.Require().NoError(err)
+	suite.Equal(models.VersionDiff{
+		From: 1,
+		To: 3,
+		NameChange: &models.FieldChange{Old: "Shirt", New: "Linen shirt"},
+		AddedCategories: []string{"summer"},
+		RemovedCategories: []string{"sale"},
+	}, diff)
+
+	diff, err = catalogdiff.Versions(
+		models.Version{Version: 3, Snapshot: []byte(`{"name": "Shirt", "prices": [
+			{"kind": "list", "amount": 1000, "currency": "USD"},
+			{"kind": "sale", "amount": 800, "currency": "USD", "valid_to": "2024-02-01T00:00:00Z"}
+		]}`)},
+		models.Version{Version: 4, Snapshot: []byte(`{"name": "Shirt", "prices": [
+			{"kind": "list", "amount": 1000, "currency": "USD"},
+			{"kind": "sale", "amount": 800, "currency": "USD", "valid_to": "2024-02-01T03:00:00+03:00"},
+			{"kind": "list", "amount": 900, "currency": "EUR"}
+		]}`)},
+	)
+	suite.Require().NoError(err)
+	suite.Equal(models.VersionDiff{
+		From: 3,
+		To: 4,
+		AddedPrices: []models.Price{{Kind: "list", Amount: 900, Currency: "EUR"}},
+	}, diff)
+
+	diff, err = catalogdiff.Versions(
+		models.Version{Version: 2, Snapshot: []byte(`{"name": "Clothes", "code": "clothes", "description": "Clothes"}`)},
+		models.Version{Version: 1, Snapshot: []byte(`{"name": "Clothes", "code": "clothes", "description": "Wear"}`)},
+	)
+	suite.Require().NoError(err)
+	suite.Equal(models.VersionDiff{
+		From: 2,
+		To: 1,
+		DescriptionChange: &models.FieldChange{Old: "Clothes", New: "Wear"},
+	}, diff)
+
+	_, err = catalogdiff.Versions(models.Version{Snapshot: []byte(`not json`)}, to)
+	suite.Require().Error(err)
+}
@@ -0,0 +1,79 @@
+package catalogdiff
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// versionFields are fields of product and category snapshots, the ones
+// the entity doesn't have stay empty in both versions
+type versionFields struct {
+	Name          string         `json:"name"`
+	Description   string         `json:"description"`
+	CategoryСodes []string       `json:"category_codes"`
+	Prices        []models.Price `json:"prices"`
+	Sku           string         `json:"sku"`
+	Barcodes      []string       `json:"barcodes"`
+}
+
+// Versions compares snapshots of two versions of the same product or category
+func Versions(from, to models.Version) (models.VersionDiff, error) {
+	var before, after versionFields
+	if err := json.Unmarshal(from.Snapshot, &before); err != nil {
+		return models.VersionDiff{}, err
+	}
+	if err := json.Unmarshal(to.Snapshot, &after); err != nil {
+		return models.VersionDiff{}, err
+	}
+	diff := models.VersionDiff{
+		From: from.Version,
+		To: to.Version,
+		AddedCategories: subtract(after.CategoryСodes, before.CategoryСodes),
+		RemovedCategories: subtract(before.CategoryСodes, after.CategoryСodes),
+		AddedPrices: subtractPrices(after.Prices, before.Prices),
+		RemovedPrices: subtractPrices(before.Prices, after.Prices),
+		AddedBarcodes: subtract(after.Barcodes, before.Barcodes),
+		RemovedBarcodes: subtract(before.Barcodes, after.Barcodes),
+	}
+	if before.Name != after.Name {
+		diff.NameChange = &models.FieldChange{Old: before.Name, New: after.Name}
+	}
+	if before.Description != after.Description {
+		diff.DescriptionChange = &models.FieldChange{Old: before.Description, New: after.Description}
+	}
+	if before.Sku != after.Sku {
+		diff.SkuChange = &models.FieldChange{Old: before.Sku, New: after.Sku}
+	}
+	return diff, nil
+}
+
+func subtractPrices(a, b []models.Price) []models.Price {
+	var out []models.Price
+	for _, price := range a {
+		found := false
+		for _, other := range b {
+			if samePrice(price, other) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, price)
+		}
+	}
+	return out
+}
+
+func samePrice(a, b models.Price) bool {
+	return a.Kind == b.Kind && a.Amount == b.Amount && a.Currency == b.Currency &&
+		sameBound(a.ValidFrom, b.ValidFrom) && sameBound(a.ValidTo, b.ValidTo)
+}
+
+func sameBound(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,213 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/catalogdiff"
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/ingestion"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=catalogRepo --exported
+type catalogRepo interface {
+	InserOrGetCategiriesId(ctx context.Context, categories []models.Category) (map[string]int, error)
+	SaveProducts(ctx context.Context, products []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error)
+	GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error)
+	GetAllCategories(ctx context.Context) ([]models.Category, error)
+}
+
+type collector struct {
+	log *slog.Logger
+	link string
+	interval time.Duration
+	dryRun bool
+	ingestCfg config.IngestionConfig
+	client *http.Client
+	catalogRepo catalogRepo
+}
+
+func NewCollector(logger *slog.Logger, link string, interval time.Duration, dryRun bool, ingestCfg config.IngestionConfig, catalogRepo catalogRepo) *collector {
+	return &collector{
+		log: logger.With(slog.String("service", "collector")),
+		link: link,
+		interval: interval,
+		dryRun: dryRun,
+		ingestCfg: ingestCfg,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		catalogRepo: catalogRepo,
+	}
+}
+
+// Run starts collecting data from the source once right away and then every interval.
+// In dry run mode only the diff with the catalog is logged.
+//
+// The returned channel is closed when the collector has stopped after ctx cancellation.
+func (c *collector) Run(ctx context.Context) (doneCh chan struct{}) {
+	doneCh = make(chan struct{})
+	if c.link == "" || c.interval <= 0 {
+		c.log.Warn("collector is disabled: empty link or interval")
+		close(doneCh)
+		return
+	}
+	c.log.Info("starting collector", slog.String("link", c.link), slog.Duration("interval", c.interval), slog.Bool("dry_run", c.dryRun))
+	go func() {
+		defer close(doneCh)
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			if c.dryRun {
+				if diff, err := c.Diff(ctx); err != nil {
+					c.log.Error("failed to diff data", slog.String("error", err.Error()))
+				} else {
+					c.log.Info("data diff", slog.Any("summary", diff.Summary))
+				}
+			} else if err := c.Collect(ctx); err != nil {
+				c.log.Error("failed to collect data", slog.String("error", err.Error()))
+			}
+			select {
+			case <-ctx.Done():
+				c.log.Info("collector stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return
+}
+
+// Collect fetches the source once and upserts received products and categories
+func (c *collector) Collect(ctx context.Context) error {
+	c.log.Info("attempt to collect data")
+	rows, categories, err := c.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("can't fetch data: %w", err)
+	}
+	products := make([]models.Product, len(rows))
+	for idx, row := range rows {
+		products[idx] = row.Product
+	}
+	if len(products) == 0 {
+		c.log.Info("nothing to collect")
+		return nil
+	}
+	categoriesId, err := c.catalogRepo.InserOrGetCategiriesId(ctx, categories)
+	if err != nil {
+		return fmt.Errorf("can't save categories: %w", err)
+	}
+	catsIds := make([][]int, len(products))
+	for idx, product := range products {
+		for _, code := range product.CategoryСodes {
+			if id, ok := categoriesId[code]; ok {
+				catsIds[idx] = append(catsIds[idx], id)
+			}
+		}
+	}
+	results, err := c.catalogRepo.SaveProducts(ctx, products, catsIds, false)
+	if err != nil {
+		return fmt.Errorf("can't save products: %w", err)
+	}
+	statuses := make(map[string]int)
+	for _, res := range results {
+		statuses[res.Status]++
+		if res.Status == models.ImportStatusFailed {
+			c.log.Warn("failed to save product", slog.String("name", res.Name), slog.String("reason", res.Reason))
+		}
+	}
+	c.log.Info("data collected",
+		slog.Int("products", len(products)),
+		slog.Int("categories", len(categories)),
+		slog.Int(models.ImportStatusCreated, statuses[models.ImportStatusCreated]),
+		slog.Int(models.ImportStatusSkippedDuplicate, statuses[models.ImportStatusSkippedDuplicate]),
+		slog.Int(models.ImportStatusFailed, statuses[models.ImportStatusFailed]),
+	)
+	return nil
+}
+
+// Diff fetches the source once and compares it with the catalog, nothing is saved
+func (c *collector) Diff(ctx context.Context) (models.CatalogDiff, error) {
+	c.log.Info("attempt to diff data")
+	rows, categories, err := c.fetch(ctx)
+	if err != nil {
+		return models.CatalogDiff{}, fmt.Errorf("can't fetch data: %w", err)
+	}
+	currentProducts, err := c.catalogRepo.GetAllProducts(ctx, models.ProductFilter{})
+	if err != nil {
+		return models.CatalogDiff{}, fmt.Errorf("can't get products: %w", err)
+	}
+	currentCategories, err := c.catalogRepo.GetAllCategories(ctx)
+	if err != nil {
+		return models.CatalogDiff{}, fmt.Errorf("can't get categories: %w", err)
+	}
+	return catalogdiff.Compute(currentProducts, currentCategories, rows, categories), nil
+}
+
+// fetch reads products and unique categories from the source.
+// Items without name are skipped
+func (c *collector) fetch(ctx context.Context) ([]models.ImportRow, []models.Category, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.link, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%w: %s", ErrBadStatus, resp.Status)
+	}
+	adapter, err := c.adapter(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		rows []models.ImportRow
+		categories []models.Category
+		seenCategories = make(map[string]struct{})
+	)
+	err = adapter.Each(resp.Body, func(item ingestion.Item) error {
+		if item.Product.Name == "" {
+			c.log.Debug("skip item without name", slog.Int("row", item.Row))
+			return nil
+		}
+		if item.Product.Description == "" {
+			item.Product.Description = item.Product.Name
+		}
+		for _, category := range item.Categories {
+			if _, ok := seenCategories[category.Code]; ok {
+				continue
+			}
+			seenCategories[category.Code] = struct{}{}
+			categories = append(categories, category)
+		}
+		rows = append(rows, models.ImportRow{
+			Row: item.Row,
+			Product: item.Product,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	c.log.Debug("got items from source", slog.Int("count", len(rows)))
+	return rows, categories, nil
+}
+
+// adapter returns adapter of the configured format or chooses it by response content type
+func (c *collector) adapter(contentType string) (ingestion.Adapter, error) {
+	if c.ingestCfg.Format != "" {
+		return ingestion.NewAdapter(c.ingestCfg.Format, c.ingestCfg)
+	}
+	adapter, err := ingestion.AdapterByContentType(contentType, c.ingestCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: content type %q", err, contentType)
+	}
+	return adapter, nil
+}
@@ -0,0 +1,188 @@
+package collector_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/collector"
+	"github.com/EwvwGeN/cataloger/internal/collector/mocks"
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/ingestion"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type collectorTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+	ingestCfg config.IngestionConfig
+}
+
+func TestCollectorSuiteRun(t *testing.T) {
+	suite.Run(t, new(collectorTestSuite))
+}
+
+func (suite *collectorTestSuite) SetupSuite() {
+	suite.log = slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	suite.ingestCfg = config.IngestionConfig{
+		Mapping: config.FieldMapping{
+			Description: "group",
+		},
+	}
+}
+
+func (suite *collectorTestSuite) Test_Collect() {
+	feed := []map[string]interface{}{
+		{
+			"name": "grinning face",
+			"category": "Smileys and people",
+			"group": "face positive",
+			"unicode": []string{"U+1F600"},
+		},
+		{
+			"name": "dog face",
+			"category": "animals and nature",
+			"group": "animal mammal",
+		},
+		{
+			"name": "cat face",
+			"category": "animals and nature",
+			"unicode": []string{"U+1F431"},
+		},
+		{
+			"name": "",
+			"category": "animals and nature",
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(feed)
+	}))
+	defer srv.Close()
+	repoMock := mocks.NewCatalogRepo(suite.T())
+	repoMock.On("InserOrGetCategiriesId", mock.Anything, []models.Category{
+		{Name: "Smileys and people", Code: "smileys_and_people", Description: "Smileys and people"},
+		{Name: "animals and nature", Code: "animals_and_nature", Description: "animals and nature"},
+	}).Once().Return(map[string]int{
+		"smileys_and_people": 1,
+		"animals_and_nature": 2,
+	}, nil)
+	repoMock.On("SaveProducts", mock.Anything, []models.Product{
+		{Name: "grinning face", Description: "face positive", CategoryСodes: []string{"smileys_and_people"}},
+		{Name: "dog face", Description: "animal mammal", CategoryСodes: []string{"animals_and_nature"}},
+		{Name: "cat face", Description: "cat face", CategoryСodes: []string{"animals_and_nature"}},
+	}, [][]int{{1}, {2}, {2}}, false).Once().Return([]models.ImportRowResult{
+		{Name: "grinning face", Status: models.ImportStatusCreated, ProductId: "1"},
+		{Name: "dog face", Status: models.ImportStatusCreated, ProductId: "2"},
+		{Name: "cat face", Status: models.ImportStatusSkippedDuplicate},
+	}, nil)
+
+	c := collector.NewCollector(suite.log, srv.URL, time.Hour, false, suite.ingestCfg, repoMock)
+	suite.Require().NoError(c.Collect(context.Background()))
+}
+
+func (suite *collectorTestSuite) Test_Diff() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"name": "grinning face", "category": "Smileys and people", "group": "face positive"},
+			{"name": "dog face", "category": "animals and nature", "group": "animal mammal"}
+		]`))
+	}))
+	defer srv.Close()
+	repoMock := mocks.NewCatalogRepo(suite.T())
+	repoMock.On("GetAllProducts", mock.Anything, mock.Anything).Once().Return([]models.Product{
+		{Id: 1, Name: "grinning face", Description: "face smile", CategoryСodes: []string{"smileys_and_people"}},
+		{Id: 2, Name: "cat face", Description: "animal mammal", CategoryСodes: []string{"animals"}},
+	}, nil)
+	repoMock.On("GetAllCategories", mock.Anything).Once().Return([]models.Category{
+		{Name: "Smileys and people", Code: "smileys_and_people", Description: "Smileys and people"},
+		{Name: "Animals", Code: "animals", Description: "Animals"},
+	}, nil)
+
+	c := collector.NewCollector(suite.log, srv.URL, time.Hour, true, suite.ingestCfg, repoMock)
+	diff, err := c.Diff(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal(models.DiffSummary{New: 1, Changed: 1, Missing: 1, NewCategories: 1}, diff.Summary)
+	suite.Equal("dog face", diff.NewProducts[0].Product.Name)
+	suite.Equal(&models.FieldChange{Old: "face smile", New: "face positive"}, diff.ChangedProducts[0].DescriptionChange)
+	suite.Equal("cat face", diff.MissingProducts[0].Name)
+	suite.Equal("animals_and_nature", diff.NewCategories[0].Code)
+}
+
+func (suite *collectorTestSuite) Test_CollectBadSource() {
+	tests := []struct{
+		name string
+		handler http.HandlerFunc
+		wantErr error
+	}{
+		{
+			name: "bad_status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantErr: collector.ErrBadStatus,
+		},
+		{
+			name: "not_json",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "application/json")
+				w.Write([]byte("not json"))
+			},
+			wantErr: ingestion.ErrDecode,
+		},
+		{
+			name: "unknown_content_type",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "image/png")
+				w.Write([]byte("[]"))
+			},
+			wantErr: ingestion.ErrUnknownFormat,
+		},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(tt.handler)
+		repoMock := mocks.NewCatalogRepo(suite.T())
+		c := collector.NewCollector(suite.log, srv.URL, time.Hour, false, suite.ingestCfg, repoMock)
+		err := c.Collect(context.Background())
+		suite.Require().ErrorIs(err, tt.wantErr, "test: %s", tt.name)
+		srv.Close()
+	}
+}
+
+func (suite *collectorTestSuite) Test_RunStopsOnCancel() {
+	requested := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+	repoMock := mocks.NewCatalogRepo(suite.T())
+	c := collector.NewCollector(suite.log, srv.URL, time.Hour, false, suite.ingestCfg, repoMock)
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := c.Run(ctx)
+	select {
+	case <-requested:
+	case <-time.After(5 * time.Second):
+		suite.FailNow("collector did not request source")
+	}
+	cancel()
+	select {
+	case <-doneCh:
+	case <-time.After(5 * time.Second):
+		suite.FailNow("collector did not stop")
+	}
+}
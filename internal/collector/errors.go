@@ -0,0 +1,7 @@
+package collector
+
+import "errors"
+
+var (
+	ErrBadStatus = errors.New("source responded with not ok status")
+)
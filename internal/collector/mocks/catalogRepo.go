@@ -0,0 +1,149 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/EwvwGeN/cataloger/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// CatalogRepo is an autogenerated mock type for the catalogRepo type
+type CatalogRepo struct {
+	mock.Mock
+}
+
+// GetAllCategories provides a mock function with given fields: ctx
+func (_m *CatalogRepo) GetAllCategories(ctx context.Context) ([]models.Category, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllCategories")
+	}
+
+	var r0 []models.Category
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Category, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Category); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Category)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAllProducts provides a mock function with given fields: ctx, filter
+func (_m *CatalogRepo) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllProducts")
+	}
+
+	var r0 []models.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.ProductFilter) ([]models.Product, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.ProductFilter) []models.Product); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.ProductFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InserOrGetCategiriesId provides a mock function with given fields: ctx, categories
+func (_m *CatalogRepo) InserOrGetCategiriesId(ctx context.Context, categories []models.Category) (map[string]int, error) {
+	ret := _m.Called(ctx, categories)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InserOrGetCategiriesId")
+	}
+
+	var r0 map[string]int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Category) (map[string]int, error)); ok {
+		return rf(ctx, categories)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Category) map[string]int); ok {
+		r0 = rf(ctx, categories)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []models.Category) error); ok {
+		r1 = rf(ctx, categories)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveProducts provides a mock function with given fields: ctx, products, catsIds, atomic
+func (_m *CatalogRepo) SaveProducts(ctx context.Context, products []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error) {
+	ret := _m.Called(ctx, products, catsIds, atomic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveProducts")
+	}
+
+	var r0 []models.ImportRowResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Product, [][]int, bool) ([]models.ImportRowResult, error)); ok {
+		return rf(ctx, products, catsIds, atomic)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Product, [][]int, bool) []models.ImportRowResult); ok {
+		r0 = rf(ctx, products, catsIds, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImportRowResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []models.Product, [][]int, bool) error); ok {
+		r1 = rf(ctx, products, catsIds, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewCatalogRepo creates a new instance of CatalogRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCatalogRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CatalogRepo {
+	mock := &CatalogRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -7,14 +7,32 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	StoragePostgres = "postgres"
+	StorageMemory = "memory"
+	StorageSqlite = "sqlite"
+)
+
 type Config struct {
 	LogLevel     string     `yaml:"log_level"`
+	// Storage selects the backend: postgres (default), sqlite or memory, memory keeps nothing after restart
+	Storage string `yaml:"storage"`
 	HttpConfig   HttpConfig `yaml:"http"`
 	PostgresConfig PostgresConfig `yaml:"postgres"`
+	SqliteConfig SqliteConfig `yaml:"sqlite"`
 	Validator    Validator  `yaml:"validator"`
 	TokenTTL time.Duration `yaml:"token_ttl"`
 	RefreshTTL time.Duration `yaml:"refresh_ttl"`
 	SecretKey string `yaml:"secret_key"`
+	DataCollectTime time.Duration `yaml:"data_collect_time"`
+	DataCollectLink string `yaml:"data_collect_link"`
+	// DataCollectDryRun makes the collector only log the diff between the source and the catalog
+	DataCollectDryRun bool `yaml:"data_collect_dry_run"`
+	Ingestion IngestionConfig `yaml:"ingestion"`
+	Jobs JobsConfig `yaml:"jobs"`
+	Trash TrashConfig `yaml:"trash"`
+	Outbox OutboxConfig `yaml:"outbox"`
+	Media MediaConfig `yaml:"media"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -0,0 +1,56 @@
+package config
+
+type IngestionConfig struct {
+	// Format forces the adapter for the collector source: json, csv or xml.
+	// If empty the adapter is chosen by Content-Type of the response
+	Format       string       `yaml:"format"`
+	CSVDelimiter string       `yaml:"csv_delimiter"`
+	XMLItem      string       `yaml:"xml_item"`
+	Mapping      FieldMapping `yaml:"mapping"`
+	// ImportMapping is used for files uploaded to the import endpoint
+	ImportMapping FieldMapping `yaml:"import_mapping"`
+}
+
+// FieldMapping declares which source field holds product data.
+// For csv it is a column name from header, for json a key of item object,
+// for xml a child element or attribute of item element
+type FieldMapping struct {
+	// Id is optional, it lets a dry run match renamed products
+	Id                string `yaml:"id"`
+	Name              string `yaml:"name"`
+	Description       string `yaml:"description"`
+	Category          string `yaml:"category"`
+	CategoryCode      string `yaml:"category_code"`
+	CategorySeparator string `yaml:"category_separator"`
+	// Sku and Barcode are optional, barcodes are separated like categories
+	Sku               string `yaml:"sku"`
+	Barcode           string `yaml:"barcode"`
+	// Attributes is optional, the field holds a json object with values of the category attributes
+	Attributes        string `yaml:"attributes"`
+}
+
+// ForImport returns config with the import mapping used as the mapping.
+// Ids are read from "id", skus from "sku", barcodes from "barcodes", attributes from "attributes"
+// and category codes from "category_codes" separated by "|" if they are not set
+func (ic IngestionConfig) ForImport() IngestionConfig {
+	ic.Mapping = ic.ImportMapping
+	if ic.Mapping.Id == "" {
+		ic.Mapping.Id = "id"
+	}
+	if ic.Mapping.Sku == "" {
+		ic.Mapping.Sku = "sku"
+	}
+	if ic.Mapping.Barcode == "" {
+		ic.Mapping.Barcode = "barcodes"
+	}
+	if ic.Mapping.Attributes == "" {
+		ic.Mapping.Attributes = "attributes"
+	}
+	if ic.Mapping.CategoryCode == "" {
+		ic.Mapping.CategoryCode = "category_codes"
+	}
+	if ic.Mapping.CategorySeparator == "" {
+		ic.Mapping.CategorySeparator = "|"
+	}
+	return ic
+}
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+type JobsConfig struct {
+	// SpoolDir keeps uploaded files until their jobs are finished
+	SpoolDir     string        `yaml:"spool_dir"`
+	Workers      int           `yaml:"workers"`
+	BatchSize    int           `yaml:"batch_size"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// ResumeInterrupted requeues jobs left running by previous process, otherwise they are failed
+	ResumeInterrupted bool `yaml:"resume_interrupted"`
+}
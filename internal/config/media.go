@@ -0,0 +1,16 @@
+package config
+
+const MediaStoreLocal = "local"
+
+type MediaConfig struct {
+	// Store keeps uploaded files, local (default) is a directory on the disk
+	Store string `yaml:"store"`
+	// Dir is the directory of the local store, "media" if empty
+	Dir string `yaml:"dir"`
+	// BaseURL is put before /media/ in urls of files, empty makes urls relative to the service
+	BaseURL string `yaml:"base_url"`
+	// MaxSize limits uploaded files in bytes, 10 MiB if zero
+	MaxSize int `yaml:"max_size"`
+	// ThumbnailSize is the longest side of thumbnails in pixels, 256 if zero
+	ThumbnailSize int `yaml:"thumbnail_size"`
+}
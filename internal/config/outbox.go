@@ -0,0 +1,25 @@
+package config
+
+import "time"
+
+const (
+	OutboxSinkLog     = "log"
+	OutboxSinkFile    = "file"
+	OutboxSinkWebhook = "webhook"
+)
+
+type OutboxConfig struct {
+	// Sinks are names of sinks every event is published to: log, file and webhook.
+	// Empty list disables the relay, events are kept in the outbox
+	Sinks       []string      `yaml:"sinks"`
+	RelayPeriod time.Duration `yaml:"relay_period"`
+	BatchSize   int           `yaml:"batch_size"`
+	// MaxAttempts is the number of failed publications after which the event is dead,
+	// ClaimTimeout is the time a relay may publish the claimed batch before other relays take it
+	MaxAttempts  int           `yaml:"max_attempts"`
+	ClaimTimeout time.Duration `yaml:"claim_timeout"`
+	// FilePath is appended with events as json lines by the file sink
+	FilePath       string        `yaml:"file_path"`
+	WebhookURL     string        `yaml:"webhook_url"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout"`
+}
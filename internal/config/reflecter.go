@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -93,11 +94,38 @@ func getEnv(envKey string) string {
 func setValue(r reflect.Value, value string) error {
 	switch r.Kind() {
 	case reflect.Int64:
+		if value == "" {
+			return nil
+		}
 		dur, err := time.ParseDuration(value)
 		if err != nil {
 			return err
 		}
 		r.SetInt(reflect.ValueOf(dur).Int())
+	case reflect.Int:
+		if value == "" {
+			return nil
+		}
+		num, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		r.SetInt(int64(num))
+	case reflect.Slice:
+		// lists are comma separated
+		if value == "" {
+			return nil
+		}
+		r.Set(reflect.ValueOf(strings.Split(value, ",")))
+	case reflect.Bool:
+		if value == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		r.SetBool(b)
 	default:
 		r.Set(reflect.ValueOf(value))
 	}
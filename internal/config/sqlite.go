@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+type SqliteConfig struct {
+	// Path of the database file, it is created with the schema if not exist
+	Path        string        `yaml:"db_path"`
+	// BusyTimeout is how long a write waits for another write to finish
+	BusyTimeout time.Duration `yaml:"db_busy_timeout"`
+}
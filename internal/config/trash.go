@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+type TrashConfig struct {
+	// Retention is how long deleted products and categories are kept, zero keeps them forever
+	Retention   time.Duration `yaml:"retention"`
+	PurgePeriod time.Duration `yaml:"purge_period"`
+}
@@ -0,0 +1,8 @@
+package httpmodels
+
+import "github.com/EwvwGeN/cataloger/internal/domain/models"
+
+type AttributesErrorResponse struct {
+	Error      string                  `json:"error"`
+	Attributes []models.AttributeIssue `json:"attributes"`
+}
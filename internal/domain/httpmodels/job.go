@@ -0,0 +1,7 @@
+package httpmodels
+
+import "github.com/EwvwGeN/cataloger/internal/domain/models"
+
+type JobGetOneResponse struct {
+	Job models.Job `json:"job"`
+}
@@ -0,0 +1,15 @@
+package httpmodels
+
+import "github.com/EwvwGeN/cataloger/internal/domain/models"
+
+type MediaGetResponse struct {
+	Media []models.Media `json:"media"`
+}
+
+type MediaAddResponse struct {
+	Media models.Media `json:"media"`
+}
+
+type MediaOrderRequest struct {
+	MediaIds []int `json:"media_ids"`
+}
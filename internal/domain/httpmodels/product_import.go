@@ -0,0 +1,15 @@
+package httpmodels
+
+import "github.com/EwvwGeN/cataloger/internal/domain/models"
+
+type ProductImportResponse struct {
+	Atomic     bool                     `json:"atomic"`
+	RolledBack bool                     `json:"rolled_back"`
+	Summary    map[string]int           `json:"summary"`
+	Rows       []models.ImportRowResult `json:"rows"`
+}
+
+type ProductImportDryRunResponse struct {
+	DryRun bool               `json:"dry_run"`
+	Diff   models.CatalogDiff `json:"diff"`
+}
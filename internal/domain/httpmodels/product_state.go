@@ -0,0 +1,5 @@
+package httpmodels
+
+type ProductStateRequest struct {
+	State string `json:"state"`
+}
@@ -0,0 +1,7 @@
+package httpmodels
+
+import "github.com/EwvwGeN/cataloger/internal/domain/models"
+
+type DBStatsResponse struct {
+	Pool models.PoolStats `json:"pool"`
+}
@@ -0,0 +1,43 @@
+package httpmodels
+
+import "github.com/EwvwGeN/cataloger/internal/domain/models"
+
+type WarehouseAddRequest struct {
+	Warehouse models.Warehouse `json:"warehouse"`
+}
+
+type WarehouseAddResponse struct {
+	Added bool `json:"added"`
+}
+
+type WarehousesGetResponse struct {
+	Warehouses []models.Warehouse `json:"warehouses"`
+}
+
+type StockGetResponse struct {
+	Stock []models.StockLevel `json:"stock"`
+}
+
+type StockAdjustRequest struct {
+	Adjustment models.StockChange `json:"adjustment"`
+}
+
+type StockAdjustResponse struct {
+	Stock models.StockLevel `json:"stock"`
+}
+
+type StockReserveRequest struct {
+	Reservation models.StockChange `json:"reservation"`
+}
+
+type ReservationResponse struct {
+	Reservation models.Reservation `json:"reservation"`
+}
+
+type ReservationCloseRequest struct {
+	Reason string `json:"reason"`
+}
+
+type StockMovementsGetResponse struct {
+	Movements []models.StockMovement `json:"movements"`
+}
@@ -0,0 +1,8 @@
+package httpmodels
+
+import "github.com/EwvwGeN/cataloger/internal/domain/models"
+
+type TrashGetResponse struct {
+	Products []models.TrashProduct `json:"products"`
+	Categories []models.TrashCategory `json:"categories"`
+}
@@ -0,0 +1,24 @@
+package httpmodels
+
+import "github.com/EwvwGeN/cataloger/internal/domain/models"
+
+type VariantsGetResponse struct {
+	Options  []models.VariantOption `json:"options"`
+	Variants []models.Variant       `json:"variants"`
+}
+
+type VariantGetOneResponse struct {
+	Variant models.Variant `json:"variant"`
+}
+
+type VariantAddRequest struct {
+	Variant models.Variant `json:"variant"`
+}
+
+type VariantAddResponse struct {
+	VariantId string `json:"variant_id"`
+}
+
+type VariantEditRequest struct {
+	VariantNewData models.VariantForPatch `json:"variant_new_data"`
+}
@@ -0,0 +1,11 @@
+package httpmodels
+
+import "github.com/EwvwGeN/cataloger/internal/domain/models"
+
+type VersionsGetResponse struct {
+	Versions []models.Version `json:"versions"`
+}
+
+type VersionDiffResponse struct {
+	Diff models.VersionDiff `json:"diff"`
+}
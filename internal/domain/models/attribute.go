@@ -0,0 +1,38 @@
+package models
+
+const (
+	AttributeString = "string"
+	AttributeNumber = "number"
+	AttributeEnum   = "enum"
+	AttributeBool   = "bool"
+	AttributeUnit   = "unit"
+)
+
+// Attribute is a typed field of the attribute schema of a category.
+// Values limit string and enum attributes, an enum must have them.
+// Values of unit attributes are numbers measured in Unit
+type Attribute struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required,omitempty"`
+	Values   []string `json:"values,omitempty"`
+	Unit     string   `json:"unit,omitempty"`
+}
+
+// AttributeIssue is one entry of the report on attribute values of a product
+// that don't fit the schemas of its categories
+type AttributeIssue struct {
+	Attribute string `json:"attribute"`
+	Category  string `json:"category,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// AttributeFilter matches products whose attribute is equal to any of Values
+// and whose number value is within Min and Max, empty fields are not used.
+// Values are compared with the text of strings and booleans and by number with numbers
+type AttributeFilter struct {
+	Name   string
+	Values []string
+	Min    *float64
+	Max    *float64
+}
@@ -1,15 +1,22 @@
 package models
 
 type Category struct {
-	Name        string `json:"name"`
-	Code        string `json:"code"`
-	Description string `json:"description"`
+	Name        string      `json:"name"`
+	Code        string      `json:"code"`
+	Description string      `json:"description"`
+	// Attributes is the schema of attributes of products in the category
+	Attributes  []Attribute `json:"attributes,omitempty"`
+	// Version is counted by every update of the category, it is sent as ETag
+	// of the category read by code
+	Version     int         `json:"-"`
 }
 
 // better to use pointer in pure struct?
 
+// CategoryForPatch changes the fields that are not nil, not nil attributes replace the whole schema
 type CategoryForPatch struct {
-	Name        *string `json:"name"`
-	Code        *string `json:"code"`
-	Description *string `json:"description"`
+	Name        *string     `json:"name"`
+	Code        *string     `json:"code"`
+	Description *string     `json:"description"`
+	Attributes  []Attribute `json:"attributes,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,42 @@
+package models
+
+// CatalogDiff describes what would change in the catalog if the feed or import file were applied
+type CatalogDiff struct {
+	Summary         DiffSummary       `json:"summary"`
+	NewProducts     []DiffProduct     `json:"new_products"`
+	ChangedProducts []ProductChange   `json:"changed_products"`
+	MissingProducts []Product         `json:"missing_products"`
+	NewCategories   []Category        `json:"new_categories"`
+	Invalid         []ImportRowResult `json:"invalid,omitempty"`
+}
+
+type DiffSummary struct {
+	New           int `json:"new"`
+	Changed       int `json:"changed"`
+	Unchanged     int `json:"unchanged"`
+	Missing       int `json:"missing"`
+	NewCategories int `json:"new_categories"`
+	Invalid       int `json:"invalid"`
+}
+
+// DiffProduct is a product of the feed with its row number
+type DiffProduct struct {
+	Row     int     `json:"row"`
+	Product Product `json:"product"`
+}
+
+// ProductChange holds changes of the existing product, nil fields are not changed
+type ProductChange struct {
+	Row               int          `json:"row"`
+	Id                int          `json:"id"`
+	Name              string       `json:"name"`
+	NameChange        *FieldChange `json:"name_change,omitempty"`
+	DescriptionChange *FieldChange `json:"description_change,omitempty"`
+	AddedCategories   []string     `json:"added_categories,omitempty"`
+	RemovedCategories []string     `json:"removed_categories,omitempty"`
+}
+
+type FieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventAggregateProduct  = "product"
+	EventAggregateCategory = "category"
+)
+
+const (
+	EventProductCreated   = "product.created"
+	EventProductUpdated   = "product.updated"
+	EventProductDeleted   = "product.deleted"
+	EventProductRestored  = "product.restored"
+	EventCategoryCreated  = "category.created"
+	EventCategoryUpdated  = "category.updated"
+	EventCategoryDeleted  = "category.deleted"
+	EventCategoryRestored = "category.restored"
+)
+
+// Event is a change of the product or category written to the outbox with the change itself.
+// Aggregates are keyed by their id, payload is the row after the change
+type Event struct {
+	Id          int             `json:"id"`
+	Aggregate   string          `json:"aggregate"`
+	AggregateId string          `json:"aggregate_id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	// Attempts counts failed publications of the event, it is not published
+	Attempts    int             `json:"-"`
+}
+
+// RelayResult tells the outbox what became of the events passed to the relay,
+// events in none of the lists are passed again as they are
+type RelayResult struct {
+	// Published events are removed from the outbox
+	Published []int
+	// Failed events are passed again with their attempt counted
+	Failed []int
+	// Dead events have failed too many times, they are kept in the outbox and are not passed again
+	Dead []int
+}
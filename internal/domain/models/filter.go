@@ -0,0 +1,16 @@
+package models
+
+// ProductFilter narrows the products selection, empty fields are not used.
+// Price bounds are compared with the lowest price valid now in every currency of the product
+// or only in Currency if it is set, a product matches if any of them is within the bounds.
+// A product matches if it fits all of Attributes. InStock keeps products with or without available units
+// in any warehouse. A product matches if it is in one of States
+type ProductFilter struct {
+	CategoryCodes []string
+	MinPrice      *int64
+	MaxPrice      *int64
+	Currency      string
+	Attributes    []AttributeFilter
+	InStock       *bool
+	States        []string
+}
@@ -0,0 +1,23 @@
+package models
+
+const (
+	ImportStatusCreated          = "created"
+	ImportStatusSkippedDuplicate = "skipped_duplicate"
+	ImportStatusInvalid          = "invalid"
+	ImportStatusFailed           = "failed"
+	ImportStatusRolledBack       = "rolled_back"
+)
+
+// ImportRow is a product read from the import source with its row number
+type ImportRow struct {
+	Row     int
+	Product Product
+}
+
+type ImportRowResult struct {
+	Row       int    `json:"row"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+	ProductId string `json:"product_id,omitempty"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+const (
+	JobStateQueued    = "queued"
+	JobStateRunning   = "running"
+	JobStateDone      = "done"
+	JobStateFailed    = "failed"
+	JobStateCancelled = "cancelled"
+)
+
+const (
+	JobKindProductImport = "product_import"
+)
+
+type Job struct {
+	Id              string            `json:"id"`
+	Kind            string            `json:"kind"`
+	State           string            `json:"state"`
+	ContentType     string            `json:"-"`
+	PayloadPath     string            `json:"-"`
+	Atomic          bool              `json:"atomic"`
+	CancelRequested bool              `json:"cancel_requested"`
+	Counters        JobCounters       `json:"counters"`
+	Error           string            `json:"error,omitempty"`
+	Report          []ImportRowResult `json:"report,omitempty"`
+	CreatedBy       string            `json:"created_by,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	StartedAt       *time.Time        `json:"started_at,omitempty"`
+	FinishedAt      *time.Time        `json:"finished_at,omitempty"`
+}
+
+type JobCounters struct {
+	Processed int `json:"processed"`
+	Created   int `json:"created"`
+	Skipped   int `json:"skipped"`
+	Invalid   int `json:"invalid"`
+	Failed    int `json:"failed"`
+}
+
+// Add counts results of the import rows
+func (jc *JobCounters) Add(results []ImportRowResult) {
+	for _, res := range results {
+		jc.Processed++
+		switch res.Status {
+		case ImportStatusCreated:
+			jc.Created++
+		case ImportStatusSkippedDuplicate:
+			jc.Skipped++
+		case ImportStatusInvalid:
+			jc.Invalid++
+		case ImportStatusFailed, ImportStatusRolledBack:
+			jc.Failed++
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Media is an image of the product. Its files are kept by the media store under Key and ThumbnailKey,
+// Url and ThumbnailUrl are where the store serves them
+type Media struct {
+	Id           int       `json:"id"`
+	Url          string    `json:"url"`
+	ThumbnailUrl string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Size         int       `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	// Position orders media of the product from zero
+	Position     int       `json:"position"`
+	// Primary is set for exactly one media of the product that has any
+	Primary      bool      `json:"primary"`
+	CreatedAt    time.Time `json:"created_at"`
+	Key          string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+}
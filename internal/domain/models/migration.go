@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type Migration struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
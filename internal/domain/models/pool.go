@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PoolStats is a snapshot of the database connection pool
+type PoolStats struct {
+	AcquireCount         int64         `json:"acquire_count"`
+	AcquireDuration      time.Duration `json:"acquire_duration_ns"`
+	AcquiredConns        int32         `json:"acquired_conns"`
+	CanceledAcquireCount int64         `json:"canceled_acquire_count"`
+	ConstructingConns    int32         `json:"constructing_conns"`
+	EmptyAcquireCount    int64         `json:"empty_acquire_count"`
+	IdleConns            int32         `json:"idle_conns"`
+	MaxConns             int32         `json:"max_conns"`
+	TotalConns           int32         `json:"total_conns"`
+}
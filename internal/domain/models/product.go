@@ -1,14 +1,66 @@
 package models
 
+import "time"
+
+const (
+	PriceKindList = "list"
+	PriceKindSale = "sale"
+)
+
+// Product states, new products are drafts and only published ones are listed publicly
+const (
+	ProductStateDraft     = "draft"
+	ProductStateInReview  = "in_review"
+	ProductStatePublished = "published"
+	ProductStateArchived  = "archived"
+)
+
 type Product struct {
-	Id            int      `json:"id"`
-	Name          string   `json:"name"`
-	Description   string   `json:"description"`
-	CategoryСodes []string `json:"category_codes,omitempty"`
+	Id            int                    `json:"id"`
+	Name          string                 `json:"name"`
+	Description   string                 `json:"description"`
+	// State is changed only by transitions, see ProductState constants
+	State         string                 `json:"state,omitempty"`
+	CategoryСodes []string               `json:"category_codes,omitempty"`
+	Prices        []Price                `json:"prices,omitempty"`
+	// Sku is unique among not deleted products, empty if the product has no sku
+	Sku           string                 `json:"sku,omitempty"`
+	// Barcodes are GTIN-8, UPC-A (GTIN-12), EAN-13 or GTIN-14 codes,
+	// each belongs to one product
+	Barcodes      []string               `json:"barcodes,omitempty"`
+	// Options are dimensions the variants of the product differ by
+	Options       []VariantOption        `json:"options,omitempty"`
+	// Variants are read with the product and saved one by one
+	Variants      []Variant              `json:"variants,omitempty"`
+	// Media are read with the product ordered by position and uploaded one by one
+	Media         []Media                `json:"media,omitempty"`
+	// Attributes are values of the attributes declared by the categories,
+	// strings, numbers or booleans by the type of the attribute
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	// Version is counted by every update of the product, it is sent as ETag
+	// of the product read by id
+	Version       int                    `json:"-"`
 }
 
+// Price is the amount in minor units of the ISO 4217 currency, like cents for USD.
+// It is valid from ValidFrom inclusive until ValidTo exclusive, nil bounds are open
+type Price struct {
+	Kind      string     `json:"kind"`
+	Amount    int64      `json:"amount"`
+	Currency  string     `json:"currency"`
+	ValidFrom *time.Time `json:"valid_from,omitempty"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+}
+
+// ProductForPatch changes the fields that are not nil, empty sku removes it.
+// Not nil category codes, prices, barcodes, options and attributes replace the current ones
 type ProductForPatch struct {
-	Name          *string  `json:"name"`
-	Description   *string  `json:"description"`
-	CategoryСodes []string `json:"category_codes,omitempty"`
-}
\ No newline at end of file
+	Name          *string                `json:"name"`
+	Description   *string                `json:"description"`
+	CategoryСodes []string               `json:"category_codes,omitempty"`
+	Prices        []Price                `json:"prices,omitempty"`
+	Sku           *string                `json:"sku,omitempty"`
+	Barcodes      []string               `json:"barcodes,omitempty"`
+	Options       []VariantOption        `json:"options,omitempty"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+}
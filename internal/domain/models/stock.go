@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Warehouse is a place the stock of products is kept in, its code is unique
+type Warehouse struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// StockLevel is the stock of the product in the warehouse. Reserved units are the part
+// of on hand ones promised to orders, available units are left for sale
+type StockLevel struct {
+	Warehouse string `json:"warehouse"`
+	OnHand    int    `json:"on_hand"`
+	Reserved  int    `json:"reserved"`
+	Available int    `json:"available"`
+}
+
+// StockChange is a change of the stock of the product in the warehouse made by Actor,
+// Quantity is the number of units to reserve or the signed change of on hand units
+type StockChange struct {
+	Warehouse string `json:"warehouse"`
+	Quantity  int    `json:"quantity"`
+	Reason    string `json:"reason,omitempty"`
+	Actor     string `json:"-"`
+}
+
+const (
+	ReservationActive    = "active"
+	ReservationReleased  = "released"
+	ReservationCommitted = "committed"
+)
+
+// Reservation holds units of the product in the warehouse until it is released back to available units
+// or committed, then the units leave the warehouse
+type Reservation struct {
+	Id        int        `json:"id"`
+	ProductId int        `json:"product_id"`
+	Warehouse string     `json:"warehouse"`
+	Quantity  int        `json:"quantity"`
+	State     string     `json:"state"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+const (
+	MovementAdjust  = "adjust"
+	MovementReserve = "reserve"
+	MovementRelease = "release"
+	MovementCommit  = "commit"
+)
+
+// StockMovement is a record of the stock ledger, OnHand and Reserved are signed changes of the units
+type StockMovement struct {
+	Id            int       `json:"id"`
+	Warehouse     string    `json:"warehouse"`
+	Kind          string    `json:"kind"`
+	OnHand        int       `json:"on_hand"`
+	Reserved      int       `json:"reserved"`
+	ReservationId int       `json:"reservation_id,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	Actor         string    `json:"actor,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
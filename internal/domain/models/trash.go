@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// TrashProduct is the deleted product, its category links are kept until it is purged
+type TrashProduct struct {
+	Product
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type TrashCategory struct {
+	Category
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Trash holds deleted products and categories, the last deleted go first
+type Trash struct {
+	Products []TrashProduct `json:"products"`
+	Categories []TrashCategory `json:"categories"`
+}
+
+// PurgeResult counts products and categories removed from the trash for good
+type PurgeResult struct {
+	Products int
+	Categories int
+}
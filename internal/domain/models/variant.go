@@ -0,0 +1,25 @@
+package models
+
+// VariantOption is a dimension like size or colour with the values the variants may take
+type VariantOption struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// Variant is a version of the product with one value for every option of the product.
+// The set of option values is unique per product
+type Variant struct {
+	Id      int               `json:"id"`
+	// Sku is unique among variants of all products
+	Sku     string            `json:"sku"`
+	Options map[string]string `json:"options"`
+	// Prices replace prices of the product if not empty
+	Prices  []Price           `json:"prices,omitempty"`
+}
+
+// VariantForPatch changes the fields that are not nil, not nil options and prices replace the current ones
+type VariantForPatch struct {
+	Sku     *string           `json:"sku"`
+	Options map[string]string `json:"options,omitempty"`
+	Prices  []Price           `json:"prices,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	VersionEntityProduct  = "product"
+	VersionEntityCategory = "category"
+)
+
+const (
+	VersionActionCreate = "create"
+	VersionActionUpdate = "update"
+	VersionActionDelete = "delete"
+	VersionActionRevert = "revert"
+)
+
+// Version is a snapshot of the product or category after the change.
+// Products are keyed by id, categories by code
+type Version struct {
+	Entity     string          `json:"-"`
+	Key        string          `json:"-"`
+	Version    int             `json:"version"`
+	Action     string          `json:"action"`
+	Actor      string          `json:"actor"`
+	RevertedTo int             `json:"reverted_to,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Snapshot   json.RawMessage `json:"snapshot"`
+}
+
+// VersionDiff holds changes between two versions, nil fields are not changed
+type VersionDiff struct {
+	From              int          `json:"from"`
+	To                int          `json:"to"`
+	NameChange        *FieldChange `json:"name_change,omitempty"`
+	DescriptionChange *FieldChange `json:"description_change,omitempty"`
+	SkuChange         *FieldChange `json:"sku_change,omitempty"`
+	AddedCategories   []string     `json:"added_categories,omitempty"`
+	RemovedCategories []string     `json:"removed_categories,omitempty"`
+	AddedPrices       []Price      `json:"added_prices,omitempty"`
+	RemovedPrices     []Price      `json:"removed_prices,omitempty"`
+	AddedBarcodes     []string     `json:"added_barcodes,omitempty"`
+	RemovedBarcodes   []string     `json:"removed_barcodes,omitempty"`
+}
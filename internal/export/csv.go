@@ -0,0 +1,34 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	cw := &csvWriter{
+		writer: csv.NewWriter(w),
+		record: make([]string, len(columns)),
+	}
+	if err := cw.writer.Write(columns); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) WriteRow(values []interface{}) error {
+	for idx, value := range values {
+		cw.record[idx] = cellText(value)
+	}
+	return cw.writer.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
@@ -0,0 +1,8 @@
+package export
+
+import "errors"
+
+var (
+	ErrUnknownFormat = errors.New("unknown export format")
+	ErrUnknownColumn = errors.New("unknown export column")
+)
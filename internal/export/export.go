@@ -0,0 +1,131 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+const (
+	EntityProducts   = "products"
+	EntityCategories = "categories"
+)
+
+var (
+	ProductColumns  = []string{"id", "name", "description", "category_codes"}
+	CategoryColumns = []string{"name", "code", "description"}
+)
+
+// Writer writes rows one by one to the underlying stream
+type Writer interface {
+	WriteRow(values []interface{}) error
+	// Close flushes buffered data and finishes the document, it does not close the underlying stream
+	Close() error
+}
+
+// NewWriter returns writer of the format, columns are written as a header where the format has it
+func NewWriter(format string, w io.Writer, sheet string, columns []string) (Writer, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatNDJSON:
+		return newNDJSONWriter(w, columns), nil
+	case FormatXLSX:
+		return newXLSXWriter(w, sheet, columns)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType returns Content-Type header value for the format
+func ContentType(format string) string {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// Columns checks requested columns against allowed ones, empty request means all allowed columns
+func Columns(requested []string, allowed []string) ([]string, error) {
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+	columns := make([]string, 0, len(requested))
+	for _, column := range requested {
+		column = strings.TrimSpace(column)
+		found := false
+		for _, a := range allowed {
+			if a == column {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func ProductRow(product models.Product, columns []string) []interface{} {
+	row := make([]interface{}, len(columns))
+	for idx, column := range columns {
+		switch column {
+		case "id":
+			row[idx] = product.Id
+		case "name":
+			row[idx] = product.Name
+		case "description":
+			row[idx] = product.Description
+		case "category_codes":
+			codes := product.CategoryСodes
+			if codes == nil {
+				codes = []string{}
+			}
+			row[idx] = codes
+		}
+	}
+	return row
+}
+
+func CategoryRow(category models.Category, columns []string) []interface{} {
+	row := make([]interface{}, len(columns))
+	for idx, column := range columns {
+		switch column {
+		case "name":
+			row[idx] = category.Name
+		case "code":
+			row[idx] = category.Code
+		case "description":
+			row[idx] = category.Description
+		}
+	}
+	return row
+}
+
+// cellText formats value for the text formats, lists are joined with "|"
+func cellText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, "|")
+	default:
+		return fmt.Sprint(v)
+	}
+}
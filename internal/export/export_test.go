@@ -0,0 +1,117 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/export"
+	"github.com/stretchr/testify/suite"
+)
+
+type productsStub []models.Product
+
+func (ps productsStub) ExportProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error {
+	for _, p := range ps {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type exportTestSuite struct {
+	suite.Suite
+	products productsStub
+}
+
+func TestExportSuiteRun(t *testing.T) {
+	suite.Run(t, new(exportTestSuite))
+}
+
+func (suite *exportTestSuite) SetupSuite() {
+	suite.products = productsStub{
+		{
+			Id: 1,
+			Name: "Red shirt",
+			Description: "Cotton, \"red\"",
+			CategoryСodes: []string{"clothes", "sale"},
+		},
+		{
+			Id: 2,
+			Name: "Hat <new>",
+			Description: "Wool",
+		},
+	}
+}
+
+func (suite *exportTestSuite) Test_CSV() {
+	var buf bytes.Buffer
+	err := export.WriteProducts(context.Background(), &buf, export.FormatCSV, export.ProductColumns, models.ProductFilter{}, suite.products)
+	suite.Require().NoError(err)
+	suite.Require().Equal("id,name,description,category_codes\n" +
+		"1,Red shirt,\"Cotton, \"\"red\"\"\",clothes|sale\n" +
+		"2,Hat <new>,Wool,\n", buf.String())
+}
+
+func (suite *exportTestSuite) Test_NDJSON() {
+	var buf bytes.Buffer
+	err := export.WriteProducts(context.Background(), &buf, export.FormatNDJSON, []string{"name", "category_codes"}, models.ProductFilter{}, suite.products)
+	suite.Require().NoError(err)
+	suite.Require().Equal(`{"name":"Red shirt","category_codes":["clothes","sale"]}` + "\n" +
+		`{"name":"Hat \u003cnew\u003e","category_codes":[]}` + "\n", buf.String())
+}
+
+func (suite *exportTestSuite) Test_XLSX() {
+	var buf bytes.Buffer
+	err := export.WriteProducts(context.Background(), &buf, export.FormatXLSX, []string{"id", "name"}, models.ProductFilter{}, suite.products)
+	suite.Require().NoError(err)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	suite.Require().NoError(err)
+	var sheet []byte
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		suite.Require().NoError(err)
+		sheet, err = io.ReadAll(rc)
+		suite.Require().NoError(err)
+		rc.Close()
+	}
+	suite.Require().NotNil(sheet, "sheet not found")
+	var ws struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	suite.Require().NoError(xml.Unmarshal(sheet, &ws))
+	suite.Require().Len(ws.Rows, 3)
+	suite.Equal("id", ws.Rows[0].Cells[0].Inline)
+	suite.Equal("B1", ws.Rows[0].Cells[1].Ref)
+	suite.Equal("1", ws.Rows[1].Cells[0].Value)
+	suite.Equal("Hat <new>", ws.Rows[2].Cells[1].Inline)
+}
+
+func (suite *exportTestSuite) Test_Columns() {
+	columns, err := export.Columns(nil, export.ProductColumns)
+	suite.Require().NoError(err)
+	suite.Equal(export.ProductColumns, columns)
+	columns, err = export.Columns([]string{"name", " id"}, export.ProductColumns)
+	suite.Require().NoError(err)
+	suite.Equal([]string{"name", "id"}, columns)
+	_, err = export.Columns([]string{"price"}, export.ProductColumns)
+	suite.Require().ErrorIs(err, export.ErrUnknownColumn)
+	_, err = export.NewWriter("pdf", &bytes.Buffer{}, "", columns)
+	suite.Require().ErrorIs(err, export.ErrUnknownFormat)
+	suite.True(strings.HasPrefix(export.ContentType(export.FormatCSV), "text/csv"))
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// ndjsonWriter writes every row as json object with keys in the columns order
+type ndjsonWriter struct {
+	buf  *bufio.Writer
+	keys [][]byte
+}
+
+func newNDJSONWriter(w io.Writer, columns []string) *ndjsonWriter {
+	keys := make([][]byte, len(columns))
+	for idx, column := range columns {
+		keys[idx], _ = json.Marshal(column)
+	}
+	return &ndjsonWriter{
+		buf:  bufio.NewWriter(w),
+		keys: keys,
+	}
+}
+
+func (nw *ndjsonWriter) WriteRow(values []interface{}) error {
+	nw.buf.WriteByte('{')
+	for idx, value := range values {
+		if idx != 0 {
+			nw.buf.WriteByte(',')
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		nw.buf.Write(nw.keys[idx])
+		nw.buf.WriteByte(':')
+		nw.buf.Write(data)
+	}
+	_, err := nw.buf.WriteString("}\n")
+	return err
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nw.buf.Flush()
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"context"
+	"io"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+type productExporter interface {
+	ExportProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error
+}
+
+type categoryExporter interface {
+	ExportCategories(ctx context.Context, fn func(models.Category) error) error
+}
+
+// WriteProducts streams products matching the filter to w row by row
+func WriteProducts(ctx context.Context, w io.Writer, format string, columns []string, filter models.ProductFilter, pe productExporter) error {
+	writer, err := NewWriter(format, w, EntityProducts, columns)
+	if err != nil {
+		return err
+	}
+	err = pe.ExportProducts(ctx, filter, func(product models.Product) error {
+		return writer.WriteRow(ProductRow(product, columns))
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// WriteCategories streams all categories to w row by row
+func WriteCategories(ctx context.Context, w io.Writer, format string, columns []string, ce categoryExporter) error {
+	writer, err := NewWriter(format, w, EntityCategories, columns)
+	if err != nil {
+		return err
+	}
+	err = ce.ExportCategories(ctx, func(category models.Category) error {
+		return writer.WriteRow(CategoryRow(category, columns))
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
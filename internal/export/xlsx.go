@@ -0,0 +1,130 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter writes workbook with a single sheet. Service parts are written first,
+// so the sheet rows go straight to the zip stream and are never held in memory
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	row   int
+}
+
+func newXLSXWriter(w io.Writer, sheet string, columns []string) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	var sheetName strings.Builder
+	xml.EscapeText(&sheetName, []byte(sheet))
+	parts := []struct {
+		name string
+		data string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, sheetName.String())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		pw, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(pw, part.data); err != nil {
+			return nil, err
+		}
+	}
+	sw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw := &xlsxWriter{
+		zip:   zw,
+		sheet: bufio.NewWriter(sw),
+	}
+	if _, err := xw.sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	header := make([]interface{}, len(columns))
+	for idx, column := range columns {
+		header[idx] = column
+	}
+	if err := xw.WriteRow(header); err != nil {
+		return nil, err
+	}
+	return xw, nil
+}
+
+func (xw *xlsxWriter) WriteRow(values []interface{}) error {
+	xw.row++
+	rowNum := strconv.Itoa(xw.row)
+	fmt.Fprintf(xw.sheet, `<row r="%s">`, rowNum)
+	for idx, value := range values {
+		ref := columnName(idx) + rowNum
+		switch v := value.(type) {
+		case int:
+			fmt.Fprintf(xw.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case int64:
+			fmt.Fprintf(xw.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		default:
+			fmt.Fprintf(xw.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := xml.EscapeText(xw.sheet, []byte(cellText(value))); err != nil {
+				return err
+			}
+			xw.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+	_, err := xw.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (xw *xlsxWriter) Close() error {
+	if _, err := xw.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zip.Close()
+}
+
+// columnName returns spreadsheet column name by zero based index: 0 -> A, 26 -> AA
+func columnName(idx int) string {
+	name := ""
+	for idx >= 0 {
+		name = string(rune('A'+idx%26)) + name
+		idx = idx/26 - 1
+	}
+	return name
+}
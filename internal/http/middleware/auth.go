@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/EwvwGeN/cataloger/internal/actor"
 	myhttp "github.com/EwvwGeN/cataloger/internal/http"
 )
 
@@ -19,21 +20,52 @@ func AuthMiddleware(logger *slog.Logger, jwtParser jwtParser, next http.HandlerF
 		log.Info("attempt to authorize")
 		authHeader := r.Header.Get("Authorization")
 		log.Debug("got authorization header", slog.String("auth", authHeader))
-		bearer := strings.Split(authHeader, " ")
-		if len(bearer) < 2 && bearer[0] != "Bearer" {
-			log.Warn("wrong authorization header")
-			http.Error(w, "wrong authorization header", http.StatusBadRequest)
+		ctx, reason := authorize(r.Context(), jwtParser, authHeader)
+		if reason != "" {
+			log.Warn("failed to authorize", slog.String("reason", reason))
+			http.Error(w, reason, http.StatusBadRequest)
 			return
 		}
-		claims, err := jwtParser.ParseJwt(bearer[1])
-		if err != nil {
-			log.Warn("failed to parse jwt")
-			http.Error(w, "not valid authorization token", http.StatusBadRequest)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// OptionalAuthMiddleware authorizes the request like AuthMiddleware if it has a valid token,
+// requests without it or with a wrong one are passed to next as anonymous ones
+func OptionalAuthMiddleware(logger *slog.Logger, jwtParser jwtParser, next http.HandlerFunc) http.HandlerFunc {
+	log := logger.With(slog.String("middleware", "optional_auth"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			next(w, r)
 			return
 		}
-		for key, value := range claims {
-			r = r.WithContext(context.WithValue(r.Context(), myhttp.ContextKey(key), value))
+		ctx, reason := authorize(r.Context(), jwtParser, authHeader)
+		if reason != "" {
+			log.Debug("request is anonymous", slog.String("reason", reason))
+			next(w, r)
+			return
 		}
-		next(w,r)
+		next(w, r.WithContext(ctx))
 	}
-}
\ No newline at end of file
+}
+
+// authorize puts the claims of the bearer token and the actor into ctx,
+// reason is not empty if the header or the token is wrong
+func authorize(ctx context.Context, jwtParser jwtParser, authHeader string) (context.Context, string) {
+	bearer := strings.Split(authHeader, " ")
+	if len(bearer) != 2 || bearer[0] != "Bearer" {
+		return ctx, "wrong authorization header"
+	}
+	claims, err := jwtParser.ParseJwt(bearer[1])
+	if err != nil {
+		return ctx, "not valid authorization token"
+	}
+	for key, value := range claims {
+		ctx = context.WithValue(ctx, myhttp.ContextKey(key), value)
+	}
+	if email, ok := claims["email"].(string); ok {
+		ctx = actor.With(ctx, email)
+	}
+	return ctx, ""
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type readSessionStarter interface {
+	ReadYourWrites(ctx context.Context) context.Context
+}
+
+// ReadYourWrites starts the read session for every request,
+// so reads made after a write of the same request see this write
+func ReadYourWrites(starter readSessionStarter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(starter.ReadYourWrites(r.Context())))
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/EwvwGeN/cataloger/internal/validator"
+)
+
+const attributeParam = "attr."
+
+// attributesMismatch responds with 400 and the report of the attributes if err says they don't fit the categories
+// of the product and reports whether it did
+func attributesMismatch(w http.ResponseWriter, log *slog.Logger, err error) bool {
+	var attrErr *service.AttributesError
+	if !errors.As(err, &attrErr) {
+		return false
+	}
+	log.Warn("attributes don't fit categories", slog.Any("issues", attrErr.Issues))
+	res := &httpmodels.AttributesErrorResponse{
+		Error: service.ErrAttributes.Error(),
+		Attributes: attrErr.Issues,
+	}
+	resData, err := json.Marshal(res)
+	if err != nil {
+		log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+		http.Error(w, "error while saving product: " + service.ErrAttributes.Error(), http.StatusBadRequest)
+		return true
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(resData)
+	return true
+}
+
+// attributeFilters reads attr.<name> with allowed values and attr.<name>.min, attr.<name>.max
+// with numeric bounds from the query, filters are sorted by name
+func attributeFilters(query url.Values) ([]models.AttributeFilter, error) {
+	filters := make(map[string]*models.AttributeFilter)
+	for param, values := range query {
+		if !strings.HasPrefix(param, attributeParam) {
+			continue
+		}
+		name, bound, _ := strings.Cut(strings.TrimPrefix(param, attributeParam), ".")
+		if !validator.ValidAttributeName(name) {
+			return nil, fmt.Errorf("incorrect attribute name in %s", param)
+		}
+		filter, ok := filters[name]
+		if !ok {
+			filter = &models.AttributeFilter{Name: name}
+			filters[name] = filter
+		}
+		switch bound {
+		case "":
+			filter.Values = append(filter.Values, values...)
+		case "min", "max":
+			number, err := strconv.ParseFloat(values[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("incorrect %s", param)
+			}
+			if bound == "min" {
+				filter.Min = &number
+			} else {
+				filter.Max = &number
+			}
+		default:
+			return nil, fmt.Errorf("unknown attribute filter %s", param)
+		}
+		if filter.Min != nil && filter.Max != nil && *filter.Min > *filter.Max {
+			return nil, fmt.Errorf("min of attribute %s is greater than max", name)
+		}
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	res := make([]models.AttributeFilter, 0, len(filters))
+	for _, filter := range filters {
+		res = append(res, *filter)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
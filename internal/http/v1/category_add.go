@@ -44,8 +44,16 @@ func CategoryAdd(logger *slog.Logger, validCfg config.Validator, cacategoryAdder
 			http.Error(w, "error while validating category description", http.StatusBadRequest)
 			return
 		}
-		err := cacategoryAdder.AddCategory(context.Background(), req.Category)
+		if reason := validator.AttributesInvalidReason(req.Category.Attributes); reason != "" {
+			log.Info("validate error: " + reason, slog.Any("attributes", req.Category.Attributes))
+			http.Error(w, "error while validating category attributes: " + reason, http.StatusBadRequest)
+			return
+		}
+		err := cacategoryAdder.AddCategory(r.Context(), req.Category)
 		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
 			if errors.Is(err, service.ErrCategoryExist) {
 				log.Error("failed to add category", slog.String("error", service.ErrCategoryExist.Error()))
 				http.Error(w, "error while adding category: category already exist", http.StatusBadRequest)
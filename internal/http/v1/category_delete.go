@@ -11,7 +11,7 @@ import (
 )
 
 type categoryDeleter interface {
-	DeleteCategory(ctx context.Context, catCode string) (error)
+	DeleteCategory(ctx context.Context, catCode string, version int) (error)
 }
 
 func CategoryDelete(logger *slog.Logger, categoryDeleter categoryDeleter) http.HandlerFunc {
@@ -24,8 +24,17 @@ func CategoryDelete(logger *slog.Logger, categoryDeleter categoryDeleter) http.H
 			http.Error(w, "error while editing category: empty category code", http.StatusBadRequest)
 			return
 		}
-		err := categoryDeleter.DeleteCategory(context.Background(), catCode)
+		version, ok := ifMatch(r)
+		if !ok {
+			log.Warn("wrong if-match header", slog.String("if_match", r.Header.Get("If-Match")))
+			http.Error(w, "precondition failed: wrong If-Match header", http.StatusPreconditionFailed)
+			return
+		}
+		err := categoryDeleter.DeleteCategory(r.Context(), catCode, version)
 		if err != nil {
+			if unavailable(w, log, err) || preconditionFailed(w, log, err) {
+				return
+			}
 			if errors.Is(err, service.ErrCategoryExist) {
 				log.Error("failed to delete category", slog.String("error", service.ErrCategoryExist.Error()))
 				http.Error(w, "error while deleting category: category with this code is in use", http.StatusBadRequest)
@@ -16,7 +16,7 @@ import (
 )
 
 type categoryEditor interface {
-	EditCategory(ctx context.Context, catCode string, category models.CategoryForPatch) (error)
+	EditCategory(ctx context.Context, catCode string, category models.CategoryForPatch, version int) (error)
 }
 
 func CategoryEdit(logger *slog.Logger, validCfg config.Validator, categoryEditor categoryEditor) http.HandlerFunc {
@@ -30,6 +30,12 @@ func CategoryEdit(logger *slog.Logger, validCfg config.Validator, categoryEditor
 			return
 		}
 		log.Debug("got category code", slog.String("category_code", catCode))
+		version, ok := ifMatch(r)
+		if !ok {
+			log.Warn("wrong if-match header", slog.String("if_match", r.Header.Get("If-Match")))
+			http.Error(w, "precondition failed: wrong If-Match header", http.StatusPreconditionFailed)
+			return
+		}
 		req := &httpmodels.CategoryEditRequest{}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Error("failed to decode request body", slog.String("error", err.Error()))
@@ -37,7 +43,8 @@ func CategoryEdit(logger *slog.Logger, validCfg config.Validator, categoryEditor
 			return
 		}
 		log.Debug("got data from request", slog.Any("request_body", req))
-		if req.CategoryNewData.Code == nil && req.CategoryNewData.Name == nil && req.CategoryNewData.Description == nil {
+		if req.CategoryNewData.Code == nil && req.CategoryNewData.Name == nil && req.CategoryNewData.Description == nil &&
+			req.CategoryNewData.Attributes == nil {
 			log.Warn("nothing to update")
 			http.Error(w, "error while editing: nothing to update", http.StatusBadRequest)
 			return
@@ -57,8 +64,16 @@ func CategoryEdit(logger *slog.Logger, validCfg config.Validator, categoryEditor
 			http.Error(w, "error while validating category description", http.StatusBadRequest)
 			return
 		}
-		err := categoryEditor.EditCategory(context.Background(), catCode, req.CategoryNewData)
+		if reason := validator.AttributesInvalidReason(req.CategoryNewData.Attributes); reason != "" {
+			log.Info("validate error: " + reason, slog.Any("attributes", req.CategoryNewData.Attributes))
+			http.Error(w, "error while validating category attributes: " + reason, http.StatusBadRequest)
+			return
+		}
+		err := categoryEditor.EditCategory(r.Context(), catCode, req.CategoryNewData, version)
 		if err != nil {
+			if unavailable(w, log, err) || preconditionFailed(w, log, err) {
+				return
+			}
 			if errors.Is(err, service.ErrCategoryExist) {
 				log.Error("failed to edit category", slog.String("error", service.ErrCategoryExist.Error()))
 				http.Error(w, "error while edditing category: category with this code already exist", http.StatusBadRequest)
@@ -29,8 +29,11 @@ func CategoryGetOne(logger *slog.Logger, categoryOneGetter categoryOneGetter) ht
 			http.Error(w, "error while getting category: empty category code", http.StatusBadRequest)
 			return
 		}
-		category, err := categoryOneGetter.GetOneCategory(context.Background(), catCode)
+		category, err := categoryOneGetter.GetOneCategory(r.Context(), catCode)
 		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
 			log.Error("failed to get category", slog.String("error", err.Error()))
 			http.Error(w, "error while getting category", http.StatusBadRequest)
 			return
@@ -45,6 +48,7 @@ func CategoryGetOne(logger *slog.Logger, categoryOneGetter categoryOneGetter) ht
 			return
 		}
 		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("ETag", etag(category.Version))
 		w.WriteHeader(http.StatusOK)
 		w.Write(resData)
 	}
@@ -54,8 +58,11 @@ func CategoryGetAll(logger *slog.Logger, categoryAllGetter categoryAllGetter) ht
 	log := logger.With(slog.String("handler", "category_get_all"))
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Info("attempt to get categories")
-		categories, err := categoryAllGetter.GetAllCategories(context.Background())
+		categories, err := categoryAllGetter.GetAllCategories(r.Context())
 		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
 			log.Error("failed to get category", slog.String("error", err.Error()))
 			http.Error(w, "error while getting categories", http.StatusInternalServerError)
 			return
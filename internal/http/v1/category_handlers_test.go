@@ -26,6 +26,8 @@ type catgTestSuite struct {
 	suite.Suite
 	cfg config.Config
 	categoryRepoMock *mocks.CategoryRepo
+	versionRepoMock *mocks.VersionRepo
+	txManagerMock *mocks.TxManager
 	addHandler http.HandlerFunc
 	editHandler http.HandlerFunc
 	deletehHanlder http.HandlerFunc
@@ -47,10 +49,13 @@ func (suite *catgTestSuite) SetupSuite() {
 	}
 	suite.cfg = cfg
 	suite.categoryRepoMock = mocks.NewCategoryRepo(suite.T())
+	suite.versionRepoMock = mocks.NewVersionRepo(suite.T())
+	suite.txManagerMock = mocks.NewTxManager(suite.T())
+	passTx(suite.txManagerMock)
 	lg := slog.New(
 		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
 	)
-	categoryService := service.NewCategoryService(lg, suite.categoryRepoMock)
+	categoryService := service.NewCategoryService(lg, suite.categoryRepoMock, suite.versionRepoMock, suite.txManagerMock)
 	suite.addHandler = v1.CategoryAdd(lg, cfg.Validator, categoryService)
 	suite.editHandler = v1.CategoryEdit(lg, cfg.Validator, categoryService)
 	suite.deletehHanlder = v1.CategoryDelete(lg, categoryService)
@@ -151,6 +156,9 @@ func (suite *catgTestSuite) Test_Add() {
 				return nil
 			})
 		}
+		if tt.wantCode == http.StatusCreated {
+			expectVersions(suite.versionRepoMock, models.VersionActionCreate, tt.req.Category.Code)
+		}
 		suite.addHandler.ServeHTTP(w,r)
 		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
 	}
@@ -254,15 +262,18 @@ func (suite *catgTestSuite) Test_Edit() {
 		}
 		r = mux.SetURLVars(r, vars)
 		if tt.wantEdit {
-			suite.categoryRepoMock.On("UpdateCategoryByCode", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Once().
-			Return(func(ctx context.Context, catCode string, updateData models.CategoryForPatch) error {
+			suite.categoryRepoMock.On("UpdateCategoryByCode", mock.Anything, mock.AnythingOfType("string"), mock.Anything, 0).Once().
+			Return(func(ctx context.Context, catCode string, updateData models.CategoryForPatch, version int) (models.Category, error) {
 				_, ok := categories[catCode]
 				if !ok {
-					return storage.ErrQuery
+					return models.Category{}, storage.ErrQuery
 				}
-				return nil
+				return models.Category{Name: "New name", Code: "new_code", Description: "New description"}, nil
 			})
 		}
+		if tt.wantCode == http.StatusOK {
+			expectVersions(suite.versionRepoMock, models.VersionActionUpdate, "new_code")
+		}
 		suite.editHandler.ServeHTTP(w, r)
 		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
 	}
@@ -301,8 +312,9 @@ func (suite *catgTestSuite) Test_Delete() {
 		}
 		r = mux.SetURLVars(r, vars)
 		if tt.wantDelete {
-			suite.categoryRepoMock.On("DeleteCategoryBycode", mock.Anything, mock.AnythingOfType("string")).
-			Once().Return(nil)
+			suite.categoryRepoMock.On("DeleteCategoryBycode", mock.Anything, mock.AnythingOfType("string"), 0).
+			Once().Return(models.Category{Name: "Test name", Code: tt.catCode, Description: "Test description"}, nil)
+			expectVersions(suite.versionRepoMock, models.VersionActionDelete, tt.catCode)
 		}
 		suite.deletehHanlder.ServeHTTP(w, r)
 		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
@@ -376,6 +388,33 @@ func (suite *catgTestSuite) Test_GetOne() {
 	}
 }
 
+func (suite *catgTestSuite) Test_IfMatch() {
+	category := models.Category{Name: "Test category", Code: "test_code", Description: "Test category", Version: 2}
+	w := httptest.NewRecorder()
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/category/test_code", nil), map[string]string{"catCode": "test_code"})
+	suite.categoryRepoMock.On("GetCategoryByCode", mock.Anything, "test_code").Once().Return(category, nil)
+	suite.getOneHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.Require().Equal(`"2"`, w.Header().Get("ETag"))
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/api/category/test_code/edit", bytes.NewBufferString(`{"category_new_data": {"name": "New name"}}`))
+	r.Header.Set("If-Match", `"2"`)
+	r = mux.SetURLVars(r, map[string]string{"catCode": "test_code"})
+	suite.categoryRepoMock.On("UpdateCategoryByCode", mock.Anything, "test_code", mock.Anything, 2).Once().Return(category, nil)
+	expectVersions(suite.versionRepoMock, models.VersionActionUpdate, "test_code")
+	suite.editHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, "/api/category/test_code/delete", nil)
+	r.Header.Set("If-Match", `"2"`)
+	r = mux.SetURLVars(r, map[string]string{"catCode": "test_code"})
+	suite.categoryRepoMock.On("DeleteCategoryBycode", mock.Anything, "test_code", 2).Once().Return(models.Category{}, storage.ErrVersionConflict)
+	suite.deletehHanlder.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusPreconditionFailed, w.Code)
+}
+
 func (suite *catgTestSuite) Test_GetAll() {
 	categories := map[string]models.Category{
 		"test_category_one": {
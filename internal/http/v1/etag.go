@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/service"
+)
+
+// etag formats the version of the product or category as a strong entity tag
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatch returns the version from the If-Match header, zero if the header is absent or "*".
+// ok is false if the header holds no version of ours, such request can't match any state.
+// Weak tags never match as If-Match uses the strong comparison
+func ifMatch(r *http.Request) (version int, ok bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.Atoi(header[1:len(header)-1])
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// preconditionFailed responds with 412 if err says the version has moved on and reports whether it did
+func preconditionFailed(w http.ResponseWriter, log *slog.Logger, err error) bool {
+	if !errors.Is(err, service.ErrVersionConflict) {
+		return false
+	}
+	log.Warn("version has changed", slog.String("error", err.Error()))
+	http.Error(w, "precondition failed: version has changed", http.StatusPreconditionFailed)
+	return true
+}
@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/export"
+	"github.com/gorilla/mux"
+)
+
+type productExporter interface {
+	ExportProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error
+}
+
+type categoryExporter interface {
+	ExportCategories(ctx context.Context, fn func(models.Category) error) error
+}
+
+// Export streams products or categories in csv, ndjson or xlsx.
+// Query parameters: format, columns, category (comma separated category codes, products only)
+// and min_price, max_price and currency (products only)
+func Export(logger *slog.Logger, productExporter productExporter, categoryExporter categoryExporter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "export"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to export")
+		entity := mux.Vars(r)["entity"]
+		query := r.URL.Query()
+		format := strings.ToLower(query.Get("format"))
+		if format == "" {
+			format = export.FormatCSV
+		}
+		if format != export.FormatCSV && format != export.FormatNDJSON && format != export.FormatXLSX {
+			log.Warn("unknown export format", slog.String("format", format))
+			http.Error(w, "error while exporting: unknown format", http.StatusBadRequest)
+			return
+		}
+		var allowed []string
+		switch entity {
+		case export.EntityProducts:
+			allowed = export.ProductColumns
+		case export.EntityCategories:
+			allowed = export.CategoryColumns
+		default:
+			log.Warn("unknown export entity", slog.String("entity", entity))
+			http.Error(w, "error while exporting: unknown entity", http.StatusBadRequest)
+			return
+		}
+		columns, err := export.Columns(splitList(query.Get("columns")), allowed)
+		if err != nil {
+			log.Warn("wrong export columns", slog.String("error", err.Error()))
+			http.Error(w, "error while exporting: " + err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter, err := productFilter(query)
+		if err != nil {
+			log.Warn("wrong product filter", slog.String("error", err.Error()))
+			http.Error(w, "error while exporting: " + err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.CategoryCodes = splitList(query.Get("category"))
+		log.Debug("got export params", slog.String("entity", entity), slog.String("format", format), slog.Any("columns", columns))
+		// export of a big catalog may last longer than the server write timeout
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		w.Header().Add("Content-Type", export.ContentType(format))
+		w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", entity, format))
+		w.WriteHeader(http.StatusOK)
+		if entity == export.EntityProducts {
+			err = export.WriteProducts(r.Context(), w, format, columns, filter, productExporter)
+		} else {
+			err = export.WriteCategories(r.Context(), w, format, columns, categoryExporter)
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Error("failed to export", slog.String("entity", entity), slog.String("error", err.Error()))
+		}
+	}
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var out []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
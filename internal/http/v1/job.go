@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/jobs"
+	"github.com/gorilla/mux"
+)
+
+type jobGetter interface {
+	GetJob(ctx context.Context, jobId string) (models.Job, error)
+}
+
+type jobCanceller interface {
+	CancelJob(ctx context.Context, jobId string) (models.Job, error)
+}
+
+func JobGetOne(logger *slog.Logger, jobGetter jobGetter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "job_get_one"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get job")
+		jobId, ok := mux.Vars(r)["jobId"]
+		if !ok || jobId == "" {
+			log.Warn("failed to get job id")
+			http.Error(w, "error while getting job: empty job id", http.StatusBadRequest)
+			return
+		}
+		job, err := jobGetter.GetJob(r.Context(), jobId)
+		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
+			if errors.Is(err, jobs.ErrJobNotFound) {
+				log.Warn("failed to get job with this id", slog.String("error", err.Error()))
+				http.Error(w, "job with this id not found", http.StatusNotFound)
+				return
+			}
+			log.Error("failed to get job", slog.String("error", err.Error()))
+			http.Error(w, "error while getting job", http.StatusInternalServerError)
+			return
+		}
+		writeJob(w, log, job)
+	}
+}
+
+// JobCancel cancels queued or running job, rows imported before cancellation stay saved
+func JobCancel(logger *slog.Logger, jobCanceller jobCanceller) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "job_cancel"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to cancel job")
+		jobId, ok := mux.Vars(r)["jobId"]
+		if !ok || jobId == "" {
+			log.Warn("failed to get job id")
+			http.Error(w, "error while cancelling job: empty job id", http.StatusBadRequest)
+			return
+		}
+		job, err := jobCanceller.CancelJob(r.Context(), jobId)
+		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
+			if errors.Is(err, jobs.ErrJobNotFound) {
+				log.Warn("failed to get job with this id", slog.String("error", err.Error()))
+				http.Error(w, "job with this id not found", http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, jobs.ErrJobFinished) {
+				log.Warn("failed to cancel job", slog.String("error", err.Error()))
+				http.Error(w, "error while cancelling job: job is already finished", http.StatusConflict)
+				return
+			}
+			log.Error("failed to cancel job", slog.String("error", err.Error()))
+			http.Error(w, "error while cancelling job", http.StatusInternalServerError)
+			return
+		}
+		writeJob(w, log, job)
+	}
+}
+
+func writeJob(w http.ResponseWriter, log *slog.Logger, job models.Job) {
+	res := &httpmodels.JobGetOneResponse{
+		Job: job,
+	}
+	resData, err := json.Marshal(res)
+	if err != nil {
+		log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+		http.Error(w, "error while getting job", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resData)
+}
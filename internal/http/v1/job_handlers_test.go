@@ -0,0 +1,164 @@
+package v1_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/actor"
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	v1 "github.com/EwvwGeN/cataloger/internal/http/v1"
+	"github.com/EwvwGeN/cataloger/internal/jobs"
+	"github.com/EwvwGeN/cataloger/internal/jobs/mocks"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type jobTestSuite struct {
+	suite.Suite
+	jobRepoMock   *mocks.JobRepo
+	importHandler http.HandlerFunc
+	getOneHandler http.HandlerFunc
+	cancelHandler http.HandlerFunc
+}
+
+func TestJobSuiteRun(t *testing.T) {
+	suite.Run(t, new(jobTestSuite))
+}
+
+func (suite *jobTestSuite) SetupSuite() {
+	suite.jobRepoMock = mocks.NewJobRepo(suite.T())
+	lg := slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	validCfg := config.Validator{
+		ProductNameValidate: `([а-яА-я\w ]+)`,
+		ProductDescValidate: `([а-яА-я\w ]+)`,
+	}
+	jobManager := jobs.NewManager(lg, config.JobsConfig{SpoolDir: suite.T().TempDir()}, validCfg, config.IngestionConfig{}, suite.jobRepoMock, nil)
+	suite.importHandler = v1.ProductImport(lg, validCfg, config.IngestionConfig{}, nil, jobManager)
+	suite.getOneHandler = v1.JobGetOne(lg, jobManager)
+	suite.cancelHandler = v1.JobCancel(lg, jobManager)
+}
+
+func (suite *jobTestSuite) Test_ImportAsync() {
+	suite.jobRepoMock.On("SaveJob", mock.Anything, mock.MatchedBy(func(job models.Job) bool {
+		return job.CreatedBy == "test@test.test" && job.Atomic && job.State == models.JobStateQueued
+	})).Once().Return("12", nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/products/import?async=true&atomic=true", bytes.NewBufferString("name,description\nNew product,Description\n"))
+	r.Header.Set("Content-Type", "text/csv")
+	r = r.WithContext(actor.With(r.Context(), "test@test.test"))
+	suite.importHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusAccepted, w.Code)
+	suite.Require().Equal("/api/jobs/12", w.Header().Get("Location"))
+	var resp httpmodels.JobGetOneResponse
+	suite.Require().NoError(json.NewDecoder(w.Body).Decode(&resp))
+	suite.Require().Equal("12", resp.Job.Id)
+	suite.Require().Equal(models.JobStateQueued, resp.Job.State)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/api/products/import?async=maybe", bytes.NewBufferString(""))
+	r.Header.Set("Content-Type", "text/csv")
+	suite.importHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusBadRequest, w.Code)
+}
+
+func (suite *jobTestSuite) Test_GetOne() {
+	tests := []struct{
+		name string
+		jobId string
+		repoJob models.Job
+		repoErr error
+		wantCode int
+	}{
+		{
+			name: "running",
+			jobId: "1",
+			repoJob: models.Job{
+				Id: "1",
+				State: models.JobStateRunning,
+				Counters: models.JobCounters{Processed: 500, Created: 499, Invalid: 1},
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "not_found",
+			jobId: "2",
+			repoErr: storage.ErrJobNotFound,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		suite.jobRepoMock.On("GetJobById", mock.Anything, tt.jobId).Once().Return(tt.repoJob, tt.repoErr)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/jobs/" + tt.jobId, nil)
+		r = mux.SetURLVars(r, map[string]string{
+			"jobId": tt.jobId,
+		})
+		suite.getOneHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		var resp httpmodels.JobGetOneResponse
+		suite.Require().NoError(json.NewDecoder(w.Body).Decode(&resp), "test: %s", tt.name)
+		suite.Require().Equal(tt.repoJob, resp.Job, "test: %s", tt.name)
+	}
+}
+
+func (suite *jobTestSuite) Test_Cancel() {
+	tests := []struct{
+		name string
+		jobId string
+		state string
+		repoErr error
+		wantCode int
+	}{
+		{
+			name: "queued",
+			jobId: "1",
+			state: models.JobStateCancelled,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "running",
+			jobId: "2",
+			state: models.JobStateRunning,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "finished",
+			jobId: "3",
+			state: models.JobStateDone,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name: "not_found",
+			jobId: "4",
+			repoErr: storage.ErrJobNotFound,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		suite.jobRepoMock.On("RequestJobCancel", mock.Anything, tt.jobId).Once().Return(tt.state, tt.repoErr)
+		if tt.wantCode == http.StatusOK {
+			suite.jobRepoMock.On("GetJobById", mock.Anything, tt.jobId).Once().Return(models.Job{Id: tt.jobId, State: tt.state}, nil)
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/jobs/" + tt.jobId + "/cancel", nil)
+		r = mux.SetURLVars(r, map[string]string{
+			"jobId": tt.jobId,
+		})
+		suite.cancelHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
@@ -28,8 +28,11 @@ func Login(logger *slog.Logger, loginer loginer) http.HandlerFunc {
 		}
 		log.Debug("got data from request", slog.Any("request_body", req))
 
-		tp, err := loginer.Login(context.Background(), req.Email, req.Password)
+		tp, err := loginer.Login(r.Context(), req.Email, req.Password)
 		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
 			log.Warn("cant login user", slog.String("error", err.Error()))
 			if errors.Is(err, service.ErrInvalidCredentials) {
 				http.Error(w, "error while logging", http.StatusBadRequest)
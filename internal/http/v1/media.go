@@ -0,0 +1,327 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/media"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultMediaMaxSize = 10 << 20
+	// multipartOverhead is allowed above the file size for headers and other fields of the form
+	multipartOverhead = 64 << 10
+)
+
+type mediaGetter interface {
+	GetMedia(ctx context.Context, prodId string) ([]models.Media, error)
+}
+
+type mediaAdder interface {
+	AddMedia(ctx context.Context, prodId string, data []byte, primary bool) (models.Media, error)
+}
+
+type mediaEditor interface {
+	OrderMedia(ctx context.Context, prodId string, mediaIds []int) error
+	SetPrimaryMedia(ctx context.Context, prodId, mediaId string) error
+}
+
+type mediaDeleter interface {
+	DeleteMedia(ctx context.Context, prodId, mediaId string) error
+}
+
+type mediaOpener interface {
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// MediaGetAll returns media of the product ordered by position
+func MediaGetAll(logger *slog.Logger, mediaGetter mediaGetter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "media_get_all"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get media")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while getting media: empty product id", http.StatusBadRequest)
+			return
+		}
+		mediaList, err := mediaGetter.GetMedia(r.Context(), prodId)
+		if err != nil {
+			if unavailable(w, log, err) || mediaFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to get media", slog.String("error", err.Error()))
+			http.Error(w, "error while getting media", http.StatusBadRequest)
+			return
+		}
+		if mediaList == nil {
+			mediaList = []models.Media{}
+		}
+		res := &httpmodels.MediaGetResponse{
+			Media: mediaList,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting media", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+// MediaAdd accepts multipart/form-data with the image in the file field and optional primary=true,
+// the type of the image is detected by its content
+func MediaAdd(logger *slog.Logger, mediaCfg config.MediaConfig, mediaAdder mediaAdder) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "media_add"))
+	maxSize := mediaCfg.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultMediaMaxSize
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to add media")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while adding media: empty product id", http.StatusBadRequest)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, int64(maxSize + multipartOverhead))
+		data, primary, err := readMediaForm(r, maxSize)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.Is(err, errMediaTooLarge) || errors.As(err, &maxBytesErr) {
+				log.Warn("media is too large", slog.Int("max_size", maxSize))
+				http.Error(w, "error while adding media: file is larger than " + strconv.Itoa(maxSize) + " bytes", http.StatusRequestEntityTooLarge)
+				return
+			}
+			log.Warn("failed to read media form", slog.String("error", err.Error()))
+			http.Error(w, "error while adding media: " + err.Error(), http.StatusBadRequest)
+			return
+		}
+		added, err := mediaAdder.AddMedia(r.Context(), prodId, data, primary)
+		if err != nil {
+			if unavailable(w, log, err) || mediaFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to add media", slog.String("error", err.Error()))
+			http.Error(w, "failed to add media", http.StatusInternalServerError)
+			return
+		}
+		res := &httpmodels.MediaAddResponse{
+			Media: added,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while adding media", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(resData)
+	}
+}
+
+var (
+	errMediaTooLarge = errors.New("file is too large")
+	errMediaNoFile = errors.New("no file in the form")
+)
+
+// readMediaForm reads parts of the form one by one, so the file is held in memory only once
+func readMediaForm(r *http.Request, maxSize int) ([]byte, bool, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, false, err
+	}
+	var (
+		data []byte
+		primary bool
+	)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		switch part.FormName() {
+		case "file":
+			var buf bytes.Buffer
+			if _, err := io.Copy(&buf, io.LimitReader(part, int64(maxSize) + 1)); err != nil {
+				return nil, false, err
+			}
+			if buf.Len() > maxSize {
+				return nil, false, errMediaTooLarge
+			}
+			data = buf.Bytes()
+		case "primary":
+			value, err := io.ReadAll(io.LimitReader(part, 8))
+			if err != nil {
+				return nil, false, err
+			}
+			if primary, err = strconv.ParseBool(string(value)); err != nil {
+				return nil, false, errors.New("wrong primary field")
+			}
+		}
+		part.Close()
+	}
+	if len(data) == 0 {
+		return nil, false, errMediaNoFile
+	}
+	return data, primary, nil
+}
+
+// MediaOrder sets the order of media of the product, media_ids must have every media of the product once
+func MediaOrder(logger *slog.Logger, mediaEditor mediaEditor) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "media_order"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to order media")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while ordering media: empty product id", http.StatusBadRequest)
+			return
+		}
+		req := httpmodels.MediaOrderRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			http.Error(w, "error while decoding request", http.StatusBadRequest)
+			return
+		}
+		log.Debug("got data from request", slog.Any("request_body", req))
+		if len(req.MediaIds) == 0 {
+			log.Warn("empty media ids")
+			http.Error(w, "error while ordering media: empty media ids", http.StatusBadRequest)
+			return
+		}
+		if err := mediaEditor.OrderMedia(r.Context(), prodId, req.MediaIds); err != nil {
+			if unavailable(w, log, err) || mediaFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to order media", slog.String("error", err.Error()))
+			http.Error(w, "error while ordering media", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func MediaSetPrimary(logger *slog.Logger, mediaEditor mediaEditor) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "media_set_primary"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to set primary media")
+		prodId, mediaId, ok := mediaVars(w, log, r)
+		if !ok {
+			return
+		}
+		if err := mediaEditor.SetPrimaryMedia(r.Context(), prodId, mediaId); err != nil {
+			if unavailable(w, log, err) || mediaFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to set primary media", slog.String("error", err.Error()))
+			http.Error(w, "error while setting primary media", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func MediaDelete(logger *slog.Logger, mediaDeleter mediaDeleter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "media_delete"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to delete media")
+		prodId, mediaId, ok := mediaVars(w, log, r)
+		if !ok {
+			return
+		}
+		if err := mediaDeleter.DeleteMedia(r.Context(), prodId, mediaId); err != nil {
+			if unavailable(w, log, err) || mediaFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to delete media", slog.String("error", err.Error()))
+			http.Error(w, "error while deleting media", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// MediaFile serves files of the media store, keys are never reused so files are cached for good
+func MediaFile(logger *slog.Logger, mediaOpener mediaOpener) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "media_file"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := mux.Vars(r)["key"]
+		if !media.ValidKey(key) {
+			log.Warn("wrong media key", slog.String("key", key))
+			http.NotFound(w, r)
+			return
+		}
+		file, err := mediaOpener.Open(r.Context(), key)
+		if err != nil {
+			if errors.Is(err, media.ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			log.Error("failed to open media file", slog.String("key", key), slog.String("error", err.Error()))
+			http.Error(w, "error while reading media", http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+		w.Header().Add("Content-Type", mime.TypeByExtension(path.Ext(key)))
+		w.Header().Add("Cache-Control", "public, max-age=31536000, immutable")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, file); err != nil {
+			log.Warn("failed to send media file", slog.String("key", key), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// mediaVars reads ids of the product and its media from the path, it responds with 400 if one is empty
+func mediaVars(w http.ResponseWriter, log *slog.Logger, r *http.Request) (string, string, bool) {
+	vars := mux.Vars(r)
+	prodId, mediaId := vars["productId"], vars["mediaId"]
+	if prodId == "" || mediaId == "" {
+		log.Warn("failed to get media id")
+		http.Error(w, "error while handling media: empty product or media id", http.StatusBadRequest)
+		return "", "", false
+	}
+	return prodId, mediaId, true
+}
+
+// mediaFailed responds to the errors of the media service that a client can fix and reports whether it did
+func mediaFailed(w http.ResponseWriter, log *slog.Logger, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrProductNotFound):
+		log.Warn("product not found", slog.String("error", err.Error()))
+		http.Error(w, "product with this id not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrMediaNotFound):
+		log.Warn("media not found", slog.String("error", err.Error()))
+		http.Error(w, service.ErrMediaNotFound.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrMediaType):
+		log.Warn("unsupported media type", slog.String("error", err.Error()))
+		http.Error(w, "error while adding media: " + err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, service.ErrMediaInvalid), errors.Is(err, service.ErrMediaOrder):
+		log.Warn("wrong media", slog.String("error", err.Error()))
+		http.Error(w, "error while handling media: " + err.Error(), http.StatusBadRequest)
+	default:
+		return false
+	}
+	return true
+}
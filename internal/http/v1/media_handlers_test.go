@@ -0,0 +1,333 @@
+package v1_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	v1 "github.com/EwvwGeN/cataloger/internal/http/v1"
+	"github.com/EwvwGeN/cataloger/internal/media"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/EwvwGeN/cataloger/internal/service/mocks"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type mediaTestSuite struct {
+	suite.Suite
+	mediaRepoMock   *mocks.MediaRepo
+	mediaStoreMock  *mocks.MediaStore
+	productRepoMock *mocks.ProductRepo
+	versionRepoMock *mocks.VersionRepo
+	txManagerMock   *mocks.TxManager
+	getAllHandler   http.HandlerFunc
+	addHandler      http.HandlerFunc
+	orderHandler    http.HandlerFunc
+	primaryHandler  http.HandlerFunc
+	deleteHandler   http.HandlerFunc
+	product         models.Product
+	image           []byte
+}
+
+func TestMediaSuiteRun(t *testing.T) {
+	suite.Run(t, new(mediaTestSuite))
+}
+
+func (suite *mediaTestSuite) SetupSuite() {
+	suite.mediaRepoMock = mocks.NewMediaRepo(suite.T())
+	suite.mediaStoreMock = mocks.NewMediaStore(suite.T())
+	suite.productRepoMock = mocks.NewProductRepo(suite.T())
+	suite.versionRepoMock = mocks.NewVersionRepo(suite.T())
+	suite.txManagerMock = mocks.NewTxManager(suite.T())
+	passTx(suite.txManagerMock)
+	lg := slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	mediaCfg := config.MediaConfig{
+		MaxSize: 4096,
+		ThumbnailSize: 8,
+	}
+	mediaService := service.NewMediaService(lg, mediaCfg, suite.mediaStoreMock, suite.mediaRepoMock, suite.productRepoMock, suite.versionRepoMock, suite.txManagerMock)
+	suite.getAllHandler = v1.MediaGetAll(lg, mediaService)
+	suite.addHandler = v1.MediaAdd(lg, mediaCfg, mediaService)
+	suite.orderHandler = v1.MediaOrder(lg, mediaService)
+	suite.primaryHandler = v1.MediaSetPrimary(lg, mediaService)
+	suite.deleteHandler = v1.MediaDelete(lg, mediaService)
+	suite.product = models.Product{
+		Id: 1,
+		Name: "Shirt",
+		Media: []models.Media{
+			{Id: 1, Url: "/media/products/1/a.png", ThumbnailUrl: "/media/products/1/a_thumb.png", ContentType: "image/png", Size: 100, Width: 16, Height: 16, Position: 0, Primary: true},
+			{Id: 2, Url: "/media/products/1/b.png", ThumbnailUrl: "/media/products/1/b_thumb.png", ContentType: "image/png", Size: 100, Width: 16, Height: 16, Position: 1},
+		},
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 16))
+	for x := 0; x < 32; x++ {
+		for y := 0; y < 16; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 8), G: uint8(y * 16), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	suite.Require().NoError(png.Encode(&buf, img), "failed to encode image")
+	suite.image = buf.Bytes()
+}
+
+func mediaForm(fields map[string]string, file []byte) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	if file != nil {
+		part, _ := writer.CreateFormFile("file", "image.bin")
+		part.Write(file)
+	}
+	writer.Close()
+	return &body, writer.FormDataContentType()
+}
+
+func (suite *mediaTestSuite) Test_GetAll() {
+	suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+	suite.productRepoMock.On("GetProductById", mock.Anything, "2").Return(models.Product{}, storage.ErrProductNotFound).Once()
+	for prodId, wantCode := range map[string]int{"1": http.StatusOK, "2": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/product/media", nil), map[string]string{"productId": prodId})
+		suite.getAllHandler.ServeHTTP(w, r)
+		suite.Require().Equal(wantCode, w.Code, "product: %s", prodId)
+		if wantCode != http.StatusOK {
+			continue
+		}
+		res := httpmodels.MediaGetResponse{}
+		suite.Require().NoError(json.NewDecoder(w.Body).Decode(&res), "product: %s", prodId)
+		suite.Require().Equal(suite.product.Media, res.Media, "product: %s", prodId)
+	}
+}
+
+func (suite *mediaTestSuite) Test_Add() {
+	tests := []struct{
+		name string
+		fields map[string]string
+		file []byte
+		contentType string
+		saveErr error
+		wantGetProduct bool
+		wantSave bool
+		wantCode int
+	}{
+		{
+			name: "ok",
+			fields: map[string]string{"primary": "true"},
+			file: suite.image,
+			wantGetProduct: true,
+			wantSave: true,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "save_failed",
+			file: suite.image,
+			saveErr: storage.ErrProductNotFound,
+			wantGetProduct: true,
+			wantSave: true,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "not_an_image",
+			file: []byte("just some text, not an image"),
+			wantGetProduct: true,
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "broken_image",
+			file: suite.image[:64],
+			wantGetProduct: true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "too_large",
+			file: append(append([]byte{}, suite.image...), make([]byte, 4096)...),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "no_file",
+			fields: map[string]string{"primary": "true"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "wrong_primary",
+			fields: map[string]string{"primary": "maybe"},
+			file: suite.image,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "not_multipart",
+			contentType: "application/json",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		body, contentType := mediaForm(tt.fields, tt.file)
+		if tt.contentType != "" {
+			contentType = tt.contentType
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/product/1/media", body)
+		r.Header.Set("Content-Type", contentType)
+		r = mux.SetURLVars(r, map[string]string{"productId": "1"})
+		if tt.wantGetProduct {
+			suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+		}
+		if tt.wantSave {
+			suite.mediaStoreMock.On("Put", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return(nil).Twice()
+			suite.mediaStoreMock.On("URL", mock.AnythingOfType("string")).Return(func(key string) string {
+				return "/media/" + key
+			}).Twice()
+			suite.mediaRepoMock.On("SaveMedia", mock.Anything, "1", mock.MatchedBy(func(md models.Media) bool {
+				return md.ContentType == "image/png" && md.Width == 32 && md.Height == 16 && md.Size == len(suite.image) &&
+					md.Primary == (tt.fields["primary"] == "true") &&
+					strings.HasPrefix(md.Key, "products/1/") && strings.HasSuffix(md.ThumbnailKey, "_thumb.png") &&
+					md.Url == "/media/" + md.Key && md.ThumbnailUrl == "/media/" + md.ThumbnailKey
+			})).Return(func(_ context.Context, _ string, md models.Media) (models.Media, error) {
+				md.Id = 3
+				md.Position = 2
+				return md, tt.saveErr
+			}).Once()
+			if tt.saveErr != nil {
+				suite.mediaStoreMock.On("Delete", mock.Anything, mock.AnythingOfType("string")).Return(nil).Twice()
+			} else {
+				suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+				expectVersions(suite.versionRepoMock, models.VersionActionUpdate, "1")
+			}
+		}
+		suite.addHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+		if tt.wantCode != http.StatusCreated {
+			continue
+		}
+		res := httpmodels.MediaAddResponse{}
+		suite.Require().NoError(json.NewDecoder(w.Body).Decode(&res), "test: %s", tt.name)
+		suite.Require().Equal(3, res.Media.Id, "test: %s", tt.name)
+		suite.Require().True(res.Media.Primary, "test: %s", tt.name)
+		suite.Require().Empty(res.Media.Key, "test: %s", tt.name)
+	}
+}
+
+func (suite *mediaTestSuite) Test_Order() {
+	tests := []struct{
+		name string
+		body string
+		orderErr error
+		wantOrder bool
+		wantCode int
+	}{
+		{
+			name: "ok",
+			body: `{"media_ids":[2,1]}`,
+			wantOrder: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "wrong_ids",
+			body: `{"media_ids":[2,3]}`,
+			orderErr: storage.ErrMediaOrder,
+			wantOrder: true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "empty_ids",
+			body: `{"media_ids":[]}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "bad_json",
+			body: `{"media_ids":`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/product/1/media/order", strings.NewReader(tt.body))
+		r = mux.SetURLVars(r, map[string]string{"productId": "1"})
+		if tt.wantOrder {
+			var req httpmodels.MediaOrderRequest
+			suite.Require().NoError(json.Unmarshal([]byte(tt.body), &req), "test: %s", tt.name)
+			suite.mediaRepoMock.On("SetMediaOrder", mock.Anything, "1", req.MediaIds).Return(tt.orderErr).Once()
+			if tt.orderErr == nil {
+				suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+				expectVersions(suite.versionRepoMock, models.VersionActionUpdate, "1")
+			}
+		}
+		suite.orderHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
+
+func (suite *mediaTestSuite) Test_SetPrimary() {
+	suite.mediaRepoMock.On("SetPrimaryMedia", mock.Anything, "1", "2").Return(nil).Once()
+	suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+	expectVersions(suite.versionRepoMock, models.VersionActionUpdate, "1")
+	suite.mediaRepoMock.On("SetPrimaryMedia", mock.Anything, "1", "9").Return(storage.ErrMediaNotFound).Once()
+	for mediaId, wantCode := range map[string]int{"2": http.StatusOK, "9": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/product/1/media/%s/primary", mediaId), nil)
+		r = mux.SetURLVars(r, map[string]string{"productId": "1", "mediaId": mediaId})
+		suite.primaryHandler.ServeHTTP(w, r)
+		suite.Require().Equal(wantCode, w.Code, "media: %s", mediaId)
+	}
+}
+
+func (suite *mediaTestSuite) Test_Delete() {
+	removed := models.Media{Id: 1, Key: "products/1/a.png", ThumbnailKey: "products/1/a_thumb.png"}
+	suite.mediaRepoMock.On("DeleteMediaById", mock.Anything, "1", "1").Return(removed, nil).Once()
+	suite.mediaStoreMock.On("Delete", mock.Anything, removed.Key).Return(nil).Once()
+	suite.mediaStoreMock.On("Delete", mock.Anything, removed.ThumbnailKey).Return(nil).Once()
+	suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+	expectVersions(suite.versionRepoMock, models.VersionActionUpdate, "1")
+	suite.mediaRepoMock.On("DeleteMediaById", mock.Anything, "1", "9").Return(models.Media{}, storage.ErrMediaNotFound).Once()
+	for mediaId, wantCode := range map[string]int{"1": http.StatusOK, "9": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/product/1/media/%s/delete", mediaId), nil)
+		r = mux.SetURLVars(r, map[string]string{"productId": "1", "mediaId": mediaId})
+		suite.deleteHandler.ServeHTTP(w, r)
+		suite.Require().Equal(wantCode, w.Code, "media: %s", mediaId)
+	}
+}
+
+func (suite *mediaTestSuite) Test_File() {
+	store, err := media.NewStore(config.MediaConfig{Dir: suite.T().TempDir()})
+	suite.Require().NoError(err, "failed to create store")
+	suite.Require().NoError(store.Put(context.Background(), "products/1/a.png", bytes.NewReader(suite.image)), "failed to put file")
+	lg := slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	handler := v1.MediaFile(lg, store)
+	for key, wantCode := range map[string]int{
+		"products/1/a.png": http.StatusOK,
+		"products/1/b.png": http.StatusNotFound,
+		"products/../../etc/passwd": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/media/" + key, nil), map[string]string{"key": key})
+		handler.ServeHTTP(w, r)
+		suite.Require().Equal(wantCode, w.Code, "key: %s", key)
+		if wantCode != http.StatusOK {
+			continue
+		}
+		suite.Require().Equal("image/png", w.Header().Get("Content-Type"), "key: %s", key)
+		suite.Require().Equal(suite.image, w.Body.Bytes(), "key: %s", key)
+	}
+}
@@ -16,7 +16,7 @@ import (
 )
 
 type productEditor interface {
-	EditProduct(context.Context, string, models.ProductForPatch) (error)
+	EditProduct(ctx context.Context, prodId string, product models.ProductForPatch, version int) (error)
 }
 
 func ProductEdit(logger *slog.Logger, validCfg config.Validator, productEditor productEditor) http.HandlerFunc {
@@ -29,6 +29,12 @@ func ProductEdit(logger *slog.Logger, validCfg config.Validator, productEditor p
 			http.Error(w, "error while editing product: empty product id", http.StatusBadRequest)
 			return
 		}
+		version, ok := ifMatch(r)
+		if !ok {
+			log.Warn("wrong if-match header", slog.String("if_match", r.Header.Get("If-Match")))
+			http.Error(w, "precondition failed: wrong If-Match header", http.StatusPreconditionFailed)
+			return
+		}
 		req := httpmodels.ProductEditRequest{}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			log.Error("failed to decode request body", slog.String("error", err.Error()))
@@ -36,7 +42,9 @@ func ProductEdit(logger *slog.Logger, validCfg config.Validator, productEditor p
 			return
 		}
 		log.Debug("got data from request", slog.Any("request_body", req))
-		if req.ProductNewData.Name == nil && req.ProductNewData.Description == nil && req.ProductNewData.CategoryСodes == nil {
+		if req.ProductNewData.Name == nil && req.ProductNewData.Description == nil && req.ProductNewData.CategoryСodes == nil && req.ProductNewData.Prices == nil &&
+			req.ProductNewData.Sku == nil && req.ProductNewData.Barcodes == nil && req.ProductNewData.Options == nil &&
+			req.ProductNewData.Attributes == nil {
 			log.Warn("nothing to update")
 			http.Error(w, "error while editing: nothing to update", http.StatusBadRequest)
 			return
@@ -51,13 +59,45 @@ func ProductEdit(logger *slog.Logger, validCfg config.Validator, productEditor p
 			http.Error(w, "error while validating product description", http.StatusBadRequest)
 			return
 		}
-		err := productEditor.EditProduct(context.Background(), prodId, req.ProductNewData)
+		newSku := ""
+		if req.ProductNewData.Sku != nil {
+			newSku = *req.ProductNewData.Sku
+		}
+		if reason := validator.IdentifiersInvalidReason(newSku, req.ProductNewData.Barcodes); reason != "" {
+			log.Info("validate error: " + reason, slog.String("sku", newSku), slog.Any("barcodes", req.ProductNewData.Barcodes))
+			http.Error(w, "error while validating product identifiers: " + reason, http.StatusBadRequest)
+			return
+		}
+		if reason := validator.PricesInvalidReason(req.ProductNewData.Prices); reason != "" {
+			log.Info("validate error: " + reason, slog.Any("prices", req.ProductNewData.Prices))
+			http.Error(w, "error while validating product prices: " + reason, http.StatusBadRequest)
+			return
+		}
+		if reason := validator.OptionsInvalidReason(req.ProductNewData.Options); reason != "" {
+			log.Info("validate error: " + reason, slog.Any("options", req.ProductNewData.Options))
+			http.Error(w, "error while validating product options: " + reason, http.StatusBadRequest)
+			return
+		}
+		err := productEditor.EditProduct(r.Context(), prodId, req.ProductNewData, version)
 		if err != nil {
+			if unavailable(w, log, err) || preconditionFailed(w, log, err) || identifierTaken(w, log, err) || attributesMismatch(w, log, err) {
+				return
+			}
 			if errors.Is(err, service.ErrProductExist) {
 				log.Error("failed to edit category", slog.String("error", service.ErrProductExist.Error()))
 				http.Error(w, "error while edditing category: product with this name already exist", http.StatusBadRequest)
 				return
 			}
+			if errors.Is(err, service.ErrVariantOptions) {
+				log.Warn("failed to edit product", slog.String("error", err.Error()))
+				http.Error(w, "error while editing product: " + err.Error(), http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, service.ErrProductNotFound) {
+				log.Warn("failed to edit product", slog.String("error", err.Error()))
+				http.Error(w, "product with this id not found", http.StatusNotFound)
+				return
+			}
 			log.Error("error while editing product", slog.Any("product", req.ProductNewData))
 			http.Error(w, "failed to edit product", http.StatusBadRequest)
 			return
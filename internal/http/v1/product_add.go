@@ -39,8 +39,41 @@ func ProductAdd(logger *slog.Logger, validCfg config.Validator, productAdder pro
 			http.Error(w, "error while validating product description", http.StatusBadRequest)
 			return
 		}
-		prodId, err := productAdder.AddProduct(context.Background(), req.Product)
+		if reason := validator.IdentifiersInvalidReason(req.Product.Sku, req.Product.Barcodes); reason != "" {
+			log.Info("validate error: " + reason, slog.String("sku", req.Product.Sku), slog.Any("barcodes", req.Product.Barcodes))
+			http.Error(w, "error while validating product identifiers: " + reason, http.StatusBadRequest)
+			return
+		}
+		if reason := validator.PricesInvalidReason(req.Product.Prices); reason != "" {
+			log.Info("validate error: " + reason, slog.Any("prices", req.Product.Prices))
+			http.Error(w, "error while validating product prices: " + reason, http.StatusBadRequest)
+			return
+		}
+		if reason := validator.OptionsInvalidReason(req.Product.Options); reason != "" {
+			log.Info("validate error: " + reason, slog.Any("options", req.Product.Options))
+			http.Error(w, "error while validating product options: " + reason, http.StatusBadRequest)
+			return
+		}
+		if len(req.Product.Variants) != 0 {
+			log.Info("validate error: variants with new product")
+			http.Error(w, "error while validating product: variants are added after the product", http.StatusBadRequest)
+			return
+		}
+		if req.Product.State != "" && req.Product.State != models.ProductStateDraft {
+			log.Info("validate error: state of new product", slog.String("state", req.Product.State))
+			http.Error(w, "error while validating product: new product is a draft, its state is changed by transitions", http.StatusBadRequest)
+			return
+		}
+		if len(req.Product.Media) != 0 {
+			log.Info("validate error: media with new product")
+			http.Error(w, "error while validating product: media are uploaded after the product", http.StatusBadRequest)
+			return
+		}
+		prodId, err := productAdder.AddProduct(r.Context(), req.Product)
 		if err != nil {
+			if unavailable(w, log, err) || identifierTaken(w, log, err) || attributesMismatch(w, log, err) {
+				return
+			}
 			if errors.Is(err, service.ErrProductExist) {
 				log.Error("failed to add category", slog.String("error", service.ErrProductExist.Error()))
 				http.Error(w, "error while adding category: product with this name already exist", http.StatusBadRequest)
@@ -63,4 +96,20 @@ func ProductAdd(logger *slog.Logger, validCfg config.Validator, productAdder pro
 		w.WriteHeader(http.StatusCreated)
 		w.Write(resData)
 	}
-}
\ No newline at end of file
+}
+
+// identifierTaken responds with 400 if err says the sku or a barcode belongs to another product and reports whether it did
+func identifierTaken(w http.ResponseWriter, log *slog.Logger, err error) bool {
+	var reason string
+	switch {
+	case errors.Is(err, service.ErrSkuExist):
+		reason = service.ErrSkuExist.Error()
+	case errors.Is(err, service.ErrBarcodeExist):
+		reason = service.ErrBarcodeExist.Error()
+	default:
+		return false
+	}
+	log.Warn("product identifier is taken", slog.String("error", err.Error()))
+	http.Error(w, "error while saving product: " + reason, http.StatusBadRequest)
+	return true
+}
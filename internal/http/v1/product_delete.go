@@ -9,7 +9,7 @@ import (
 )
 
 type productDeleter interface {
-	DelteProduct(context.Context, string) (error)
+	DelteProduct(ctx context.Context, prodId string, version int) (error)
 }
 
 func ProductDelete(logger *slog.Logger, productDeleter productDeleter) http.HandlerFunc {
@@ -22,8 +22,17 @@ func ProductDelete(logger *slog.Logger, productDeleter productDeleter) http.Hand
 			http.Error(w, "error while editing product: empty product id", http.StatusBadRequest)
 			return
 		}
-		err := productDeleter.DelteProduct(context.Background(), prodId)
+		version, ok := ifMatch(r)
+		if !ok {
+			log.Warn("wrong if-match header", slog.String("if_match", r.Header.Get("If-Match")))
+			http.Error(w, "precondition failed: wrong If-Match header", http.StatusPreconditionFailed)
+			return
+		}
+		err := productDeleter.DelteProduct(r.Context(), prodId, version)
 		if err != nil {
+			if unavailable(w, log, err) || preconditionFailed(w, log, err) {
+				return
+			}
 			log.Error("failed to delete product", slog.String("error", err.Error()))
 			http.Error(w, "error while deleting product", http.StatusBadRequest)
 			return
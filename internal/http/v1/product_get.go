@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
 	"github.com/EwvwGeN/cataloger/internal/domain/models"
 	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/EwvwGeN/cataloger/internal/validator"
 	"github.com/gorilla/mux"
 )
 
@@ -17,12 +21,20 @@ type productOneGetter interface {
 	GetOneProduct(context.Context, string) (models.Product, error)
 }
 
+type productBySkuGetter interface {
+	GetProductBySku(context.Context, string) (models.Product, error)
+}
+
+type productByBarcodeGetter interface {
+	GetProductByBarcode(context.Context, string) (models.Product, error)
+}
+
 type productAllGetter interface {
-	GetAllProduct(context.Context) ([]models.Product, error)
+	GetAllProduct(context.Context, models.ProductFilter) ([]models.Product, error)
 }
 
 type productAllByCatCodeGetter interface {
-	GetAllProductsByCategory(context.Context, string) ([]models.Product, error)
+	GetAllProductsByCategory(context.Context, string, []string) ([]models.Product, error)
 }
 
 func ProductGetOne(logger *slog.Logger, productOneGetter productOneGetter) http.HandlerFunc {
@@ -35,17 +47,25 @@ func ProductGetOne(logger *slog.Logger, productOneGetter productOneGetter) http.
 			http.Error(w, "error while editing product: empty product id", http.StatusBadRequest)
 			return
 		}
-		product, err := productOneGetter.GetOneProduct(context.Background(), prodId)
+		product, err := productOneGetter.GetOneProduct(r.Context(), prodId)
 		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
 			if errors.Is(err, service.ErrProductNotFound) {
 				log.Warn("failed to get product with this id", slog.String("error", err.Error()))
-				http.Error(w, "product with this id not found", http.StatusBadRequest)
+				http.Error(w, "product with this id not found", http.StatusNotFound)
 				return
 			}
 			log.Error("failed to get product", slog.String("error", err.Error()))
 			http.Error(w, "error while getting product", http.StatusBadRequest)
 			return
 		}
+		if !productVisible(r, product) {
+			log.Warn("product is not published", slog.String("product_id", prodId))
+			http.Error(w, "product with this id not found", http.StatusNotFound)
+			return
+		}
 		res := &httpmodels.ProductGetOneResponse{
 			Product: product,
 		}
@@ -56,17 +76,90 @@ func ProductGetOne(logger *slog.Logger, productOneGetter productOneGetter) http.
 			return
 		}
 		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("ETag", etag(product.Version))
 		w.WriteHeader(http.StatusOK)
 		w.Write(resData)
 	}
 }
 
+// ProductGetBySku finds the not deleted product with the sku
+func ProductGetBySku(logger *slog.Logger, productBySkuGetter productBySkuGetter) http.HandlerFunc {
+	return productGetBy(logger.With(slog.String("handler", "product_get_by_sku")), "sku", productBySkuGetter.GetProductBySku)
+}
+
+// ProductGetByBarcode finds the product with the barcode
+func ProductGetByBarcode(logger *slog.Logger, productByBarcodeGetter productByBarcodeGetter) http.HandlerFunc {
+	return productGetBy(logger.With(slog.String("handler", "product_get_by_barcode")), "barcode", productByBarcodeGetter.GetProductByBarcode)
+}
+
+// productGetBy responds with the product found by the identifier from the path variable with the same name,
+// anonymous requests get only published products
+func productGetBy(log *slog.Logger, key string, get func(context.Context, string) (models.Product, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get product by " + key)
+		value, ok := mux.Vars(r)[key]
+		if !ok || value == "" {
+			log.Warn("failed to get product " + key)
+			http.Error(w, "error while getting product: empty " + key, http.StatusBadRequest)
+			return
+		}
+		product, err := get(r.Context(), value)
+		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
+			if errors.Is(err, service.ErrProductNotFound) {
+				log.Warn("failed to get product with this " + key, slog.String(key, value))
+				http.Error(w, "product with this " + key + " not found", http.StatusNotFound)
+				return
+			}
+			log.Error("failed to get product", slog.String("error", err.Error()))
+			http.Error(w, "error while getting product", http.StatusBadRequest)
+			return
+		}
+		if !productVisible(r, product) {
+			log.Warn("product is not published", slog.String(key, value))
+			http.Error(w, "product with this " + key + " not found", http.StatusNotFound)
+			return
+		}
+		res := &httpmodels.ProductGetOneResponse{
+			Product: product,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting product", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("ETag", etag(product.Version))
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+// ProductGetAll lists products matching the filter, only published ones if the request is not authorized
 func ProductGetAll(logger *slog.Logger, productAllGetter productAllGetter) http.HandlerFunc {
 	log := logger.With(slog.String("handler", "product_get_all"))
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Info("attempt to get all products")
-		products, err := productAllGetter.GetAllProduct(context.Background())
+		filter, err := productFilter(r.URL.Query())
+		if err != nil {
+			log.Warn("wrong product filter", slog.String("error", err.Error()))
+			http.Error(w, "error while getting products: " + err.Error(), http.StatusBadRequest)
+			return
+		}
+		var ok bool
+		if filter.States, ok = visibleStates(r, filter.States); !ok {
+			log.Warn("state filter without authorization", slog.Any("states", filter.States))
+			http.Error(w, "error while getting products: only published products are listed without authorization", http.StatusUnauthorized)
+			return
+		}
+		products, err := productAllGetter.GetAllProduct(r.Context(), filter)
 		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
 			log.Error("failed to get products", slog.String("error", err.Error()))
 			http.Error(w, "error while getting products", http.StatusBadRequest)
 			return
@@ -86,6 +179,55 @@ func ProductGetAll(logger *slog.Logger, productAllGetter productAllGetter) http.
 	}
 }
 
+// productFilter reads the price, attribute, stock and state filters from the query
+func productFilter(query url.Values) (models.ProductFilter, error) {
+	filter, err := priceFilter(query)
+	if err != nil {
+		return models.ProductFilter{}, err
+	}
+	filter.Attributes, err = attributeFilters(query)
+	if err != nil {
+		return models.ProductFilter{}, err
+	}
+	if value := query.Get("in_stock"); value != "" {
+		inStock, err := strconv.ParseBool(value)
+		if err != nil {
+			return models.ProductFilter{}, errors.New("incorrect in_stock")
+		}
+		filter.InStock = &inStock
+	}
+	filter.States, err = productStates(query)
+	if err != nil {
+		return models.ProductFilter{}, err
+	}
+	return filter, nil
+}
+
+// priceFilter reads min_price and max_price in minor units and currency from the query
+func priceFilter(query url.Values) (models.ProductFilter, error) {
+	var filter models.ProductFilter
+	for param, bound := range map[string]**int64{"min_price": &filter.MinPrice, "max_price": &filter.MaxPrice} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || amount < 0 {
+			return models.ProductFilter{}, fmt.Errorf("incorrect %s", param)
+		}
+		*bound = &amount
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return models.ProductFilter{}, errors.New("min_price is greater than max_price")
+	}
+	filter.Currency = query.Get("currency")
+	if filter.Currency != "" && !validator.ValidCurrency(filter.Currency) {
+		return models.ProductFilter{}, errors.New("unknown currency")
+	}
+	return filter, nil
+}
+
+// ProductGetAllByCategory lists products of the category, only published ones if the request is not authorized
 func ProductGetAllByCategory(logger *slog.Logger, pg productAllByCatCodeGetter) http.HandlerFunc {
 	log := logger.With(slog.String("handler", "product_get_all_by_category"))
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -96,8 +238,22 @@ func ProductGetAllByCategory(logger *slog.Logger, pg productAllByCatCodeGetter)
 			http.Error(w, "error while getting category: empty category code", http.StatusBadRequest)
 			return
 		}
-		products, err := pg.GetAllProductsByCategory(context.Background(), catCode)
+		states, err := productStates(r.URL.Query())
+		if err != nil {
+			log.Warn("wrong state filter", slog.String("error", err.Error()))
+			http.Error(w, "error while getting products: " + err.Error(), http.StatusBadRequest)
+			return
+		}
+		if states, ok = visibleStates(r, states); !ok {
+			log.Warn("state filter without authorization", slog.Any("states", states))
+			http.Error(w, "error while getting products: only published products are listed without authorization", http.StatusUnauthorized)
+			return
+		}
+		products, err := pg.GetAllProductsByCategory(r.Context(), catCode, states)
 		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
 			log.Error("failed to get products", slog.String("error", err.Error()))
 			http.Error(w, "error while getting products", http.StatusBadRequest)
 			return
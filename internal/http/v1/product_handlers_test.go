@@ -13,6 +13,7 @@ import (
 	"github.com/EwvwGeN/cataloger/internal/config"
 	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
 	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	myhttp "github.com/EwvwGeN/cataloger/internal/http"
 	v1 "github.com/EwvwGeN/cataloger/internal/http/v1"
 	"github.com/EwvwGeN/cataloger/internal/service"
 	"github.com/EwvwGeN/cataloger/internal/service/mocks"
@@ -27,12 +28,19 @@ type prodTestSuite struct {
 	cfg              config.Config
 	categoryCodesRepoMock *mocks.CategoryCodesRepo
 	productRepoMock *mocks.ProductRepo
+	versionRepoMock *mocks.VersionRepo
+	txManagerMock *mocks.TxManager
 	addHandler       http.HandlerFunc
 	editHandler      http.HandlerFunc
 	deletehHanlder   http.HandlerFunc
 	getOneHandler    http.HandlerFunc
+	getBySkuHandler  http.HandlerFunc
+	getByBarcodeHandler  http.HandlerFunc
 	getAllHandler    http.HandlerFunc
 	getAllByCategoryHandler    http.HandlerFunc
+	changeStateHandler    http.HandlerFunc
+	importHandler    http.HandlerFunc
+	exportHandler    http.HandlerFunc
 }
 
 func TestProductSuiteRun(t *testing.T) {
@@ -48,16 +56,49 @@ func (suite *prodTestSuite) SetupSuite() {
 	}
 	suite.categoryCodesRepoMock = mocks.NewCategoryCodesRepo(suite.T())
 	suite.productRepoMock = mocks.NewProductRepo(suite.T())
+	suite.versionRepoMock = mocks.NewVersionRepo(suite.T())
+	suite.txManagerMock = mocks.NewTxManager(suite.T())
+	passTx(suite.txManagerMock)
+	suite.categoryCodesRepoMock.On("GetCategoriesAttributes", mock.Anything, mock.Anything).
+	Return(func(ctx context.Context, catCodes []string) (map[string][]models.Attribute, error) {
+		schemas := make(map[string][]models.Attribute)
+		for _, code := range catCodes {
+			if schema, ok := categorySchemas[code]; ok {
+				schemas[code] = schema
+			}
+		}
+		return schemas, nil
+	}).Maybe()
 	lg := slog.New(
 		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
 	)
-	productServive := service.NewProductService(lg, suite.productRepoMock, suite.categoryCodesRepoMock)
+	productServive := service.NewProductService(lg, suite.productRepoMock, suite.categoryCodesRepoMock, suite.versionRepoMock, suite.txManagerMock)
 	suite.addHandler = v1.ProductAdd(lg, suite.cfg.Validator, productServive)
 	suite.editHandler = v1.ProductEdit(lg, suite.cfg.Validator, productServive)
 	suite.deletehHanlder = v1.ProductDelete(lg, productServive)
 	suite.getOneHandler = v1.ProductGetOne(lg, productServive)
+	suite.getBySkuHandler = v1.ProductGetBySku(lg, productServive)
+	suite.getByBarcodeHandler = v1.ProductGetByBarcode(lg, productServive)
 	suite.getAllHandler = v1.ProductGetAll(lg, productServive)
 	suite.getAllByCategoryHandler = v1.ProductGetAllByCategory(lg, productServive)
+	suite.changeStateHandler = v1.ProductChangeState(lg, productServive)
+	suite.exportHandler = v1.Export(lg, productServive, nil)
+	suite.importHandler = v1.ProductImport(lg, suite.cfg.Validator, suite.cfg.Ingestion, productServive, nil)
+}
+
+// categorySchemas are the attribute schemas of the test categories, others have none
+var categorySchemas = map[string][]models.Attribute{
+	"test_category_attrs": {
+		{Name: "colour", Type: models.AttributeEnum, Required: true, Values: []string{"red", "blue"}},
+		{Name: "weight", Type: models.AttributeUnit, Unit: "kg"},
+	},
+}
+
+// passTx makes the mock run fn with the same context, repository mocks do not need a transaction
+func passTx(txManager *mocks.TxManager) {
+	txManager.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	}).Maybe()
 }
 
 func (suite *prodTestSuite) Test_Add() {
@@ -154,6 +195,64 @@ func (suite *prodTestSuite) Test_Add() {
 			wantGetCategoryId: true,
 			wantCode: http.StatusBadRequest,
 		},
+		{
+			name: "sku_already_exist",
+			req: httpmodels.ProductAddRequest{
+				Product: models.Product{
+					Name: "New product",
+					Description: "Description for product",
+					Sku: "SP-01",
+				},
+			},
+			wantSave: true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "not_valid_sku",
+			req: httpmodels.ProductAddRequest{
+				Product: models.Product{
+					Name: "New product",
+					Description: "Description for product",
+					Sku: "-sku with spaces-",
+				},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "not_valid_barcode_check_digit",
+			req: httpmodels.ProductAddRequest{
+				Product: models.Product{
+					Name: "New product",
+					Description: "Description for product",
+					Barcodes: []string{"4006381333932"},
+				},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "not_valid_prices",
+			req: httpmodels.ProductAddRequest{
+				Product: models.Product{
+					Name: "New product",
+					Description: "Description for product",
+					Prices: []models.Price{
+						{Kind: models.PriceKindList, Amount: 1000, Currency: "XYZ"},
+					},
+				},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "published_on_create",
+			req: httpmodels.ProductAddRequest{
+				Product: models.Product{
+					Name: "New product",
+					Description: "Description for product",
+					State: models.ProductStatePublished,
+				},
+			},
+			wantCode: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -180,9 +279,15 @@ func (suite *prodTestSuite) Test_Add() {
 				if _, ok := products[product.Name]; ok {
 					return "", storage.ErrProductExist
 				}
+				if product.Sku == "SP-01" {
+					return "", storage.ErrSkuExist
+				}
 				return tt.newProdId, nil
 			})
 		}
+		if tt.wantCode == http.StatusCreated {
+			expectVersions(suite.versionRepoMock, models.VersionActionCreate, tt.newProdId)
+		}
 		suite.addHandler.ServeHTTP(w, r)
 		suite.Require().Equal(tt.wantCode, w.Code)
 		if tt.wantCode == http.StatusCreated {
@@ -201,12 +306,14 @@ func (suite *prodTestSuite) Test_Edit() {
 	categories := map[string]int{
 		"test_category_one": 1,
 		"test_category_two": 2,
+		"test_category_attrs": 4,
 	}
 	tests := []struct{
 		name string
 		prodId string
 		req httpmodels.ProductEditRequest
 		wantGetCategoryId bool
+		wantGetProduct bool
 		wantEdit bool
 		wantCode int
 	}{
@@ -229,9 +336,60 @@ func (suite *prodTestSuite) Test_Edit() {
 				},
 			},
 			wantGetCategoryId: true,
+			wantGetProduct: true,
 			wantEdit: true,
 			wantCode: http.StatusOK,
 		},
+		{
+			name: "attributes_happy_pass",
+			prodId: "1",
+			req: httpmodels.ProductEditRequest{
+				ProductNewData: models.ProductForPatch{
+					CategoryСodes: []string{
+						"test_category_attrs",
+					},
+					Attributes: map[string]interface{}{
+						"colour": "red",
+						"weight": 1.5,
+					},
+				},
+			},
+			wantGetCategoryId: true,
+			wantGetProduct: true,
+			wantEdit: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "attributes_not_fit",
+			prodId: "1",
+			req: httpmodels.ProductEditRequest{
+				ProductNewData: models.ProductForPatch{
+					CategoryСodes: []string{
+						"test_category_attrs",
+					},
+					Attributes: map[string]interface{}{
+						"colour": "green",
+					},
+				},
+			},
+			wantGetCategoryId: true,
+			wantGetProduct: true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "required_attribute_missing",
+			prodId: "1",
+			req: httpmodels.ProductEditRequest{
+				ProductNewData: models.ProductForPatch{
+					CategoryСodes: []string{
+						"test_category_attrs",
+					},
+				},
+			},
+			wantGetCategoryId: true,
+			wantGetProduct: true,
+			wantCode: http.StatusBadRequest,
+		},
 		{
 			name: "not_valid_categories",
 			prodId: "1",
@@ -283,11 +441,50 @@ func (suite *prodTestSuite) Test_Edit() {
 			},
 			wantCode: http.StatusBadRequest,
 		},
+		{
+			name: "overlapping_prices",
+			prodId: "1",
+			req: httpmodels.ProductEditRequest{
+				ProductNewData: models.ProductForPatch{
+					Prices: []models.Price{
+						{Kind: models.PriceKindList, Amount: 1000, Currency: "USD"},
+						{Kind: models.PriceKindList, Amount: 900, Currency: "USD"},
+					},
+				},
+			},
+			wantCode: http.StatusBadRequest,
+		},
 		{
 			name: "nothing_to_update",
 			prodId: "1",
 			wantCode: http.StatusBadRequest,
 		},
+		{
+			name: "options_without_variant_values",
+			prodId: "3",
+			req: httpmodels.ProductEditRequest{
+				ProductNewData: models.ProductForPatch{
+					Options: []models.VariantOption{
+						{Name: "size", Values: []string{"M", "L"}},
+					},
+				},
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "deleted_product",
+			prodId: "2",
+			req: httpmodels.ProductEditRequest{
+				ProductNewData: models.ProductForPatch{
+					Name: func () *string {
+						name := "New name"
+						return &name
+					}(),
+				},
+			},
+			wantEdit: true,
+			wantCode: http.StatusNotFound,
+		},
 	}
 	for _, tt := range tests {
 		var jsonBody bytes.Buffer
@@ -312,18 +509,36 @@ func (suite *prodTestSuite) Test_Edit() {
 				return categoriesId, nil
 			})
 		}
+		if tt.wantGetProduct {
+			suite.productRepoMock.On("GetProductById", mock.Anything, tt.prodId).Once().
+			Return(models.Product{Id: 1, Name: "Test product", Description: "Description"}, nil)
+		}
 		if tt.wantEdit {
-			suite.productRepoMock.On("UpdateProductById", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Once().
-			Return(func(ctx context.Context, prodID string, updateData models.ProductForPatch, catIds []int) error {
+			suite.productRepoMock.On("UpdateProductById", mock.Anything, mock.Anything, mock.Anything, mock.Anything, 0).Once().
+			Return(func(ctx context.Context, prodID string, updateData models.ProductForPatch, catIds []int, version int) (models.Product, error) {
 				_, ok := products[prodID]
 				if !ok {
-					return storage.ErrQuery
+					return models.Product{}, storage.ErrProductNotFound
 				}
-				return nil
+				return models.Product{Id: 1, Name: "New name", Description: "New description"}, nil
 			})
 		}
+		if tt.wantCode == http.StatusOK {
+			expectVersions(suite.versionRepoMock, models.VersionActionUpdate, tt.prodId)
+		}
+		if tt.req.ProductNewData.Options != nil {
+			suite.productRepoMock.On("GetProductById", mock.Anything, tt.prodId).Once().
+			Return(models.Product{Id: 3, Variants: []models.Variant{{Id: 1, Options: map[string]string{"size": "S"}}}}, nil)
+		}
 		suite.editHandler.ServeHTTP(w, r)
 		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+		if tt.wantGetProduct && tt.wantCode == http.StatusBadRequest {
+			var resp httpmodels.AttributesErrorResponse
+			err := json.NewDecoder(w.Body).Decode(&resp)
+			suite.Require().NoError(err, "test: %s", tt.name)
+			suite.Require().Len(resp.Attributes, 1, "test: %s", tt.name)
+			suite.Require().Equal("colour", resp.Attributes[0].Attribute, "test: %s", tt.name)
+		}
 	}
 }
 
@@ -336,25 +551,55 @@ func (suite *prodTestSuite) Test_GetOne() {
 				"test_category_one",
 			},
 		},
+		"3": {
+			Name: "Published product",
+			Description: "published product",
+			State: models.ProductStatePublished,
+		},
 	}
 	tests := []struct{
 		name string
 		prodId string
+		editor bool
 		wantGet bool
+		getErr error
 		wantCode int
 	}{
 		{
 			name: "happy_pass",
 			prodId: "1",
+			editor: true,
 			wantGet: true,
 			wantCode: http.StatusOK,
 		},
 		{
 			name: "product_not_exist",
 			prodId: "2",
+			editor: true,
 			wantGet: true,
 			wantCode: http.StatusOK,
 		},
+		{
+			name: "anonymous_published",
+			prodId: "3",
+			wantGet: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			// requests without authorization don't see products which are not published
+			name: "anonymous_draft",
+			prodId: "1",
+			wantGet: true,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			// missing product is answered like the one which is not published
+			name: "anonymous_missing",
+			prodId: "9",
+			wantGet: true,
+			getErr: storage.ErrProductNotFound,
+			wantCode: http.StatusNotFound,
+		},
 		{
 			name: "without_product_id",
 			prodId: "",
@@ -370,14 +615,17 @@ func (suite *prodTestSuite) Test_GetOne() {
 			"productId": tt.prodId,
 		}
 		r = mux.SetURLVars(r, vars)
+		if tt.editor {
+			r = r.WithContext(context.WithValue(r.Context(), myhttp.ContextKey("email"), "test@test.test"))
+		}
 		if tt.wantGet {
 			suite.productRepoMock.On("GetProductById", mock.Anything, mock.Anything).Once().
 			Return(func(ctx context.Context, prodId string) (models.Product, error) {
-				return products[prodId], nil
+				return products[prodId], tt.getErr
 			})
 		}
 		suite.getOneHandler.ServeHTTP(w, r)
-		suite.Require().Equal(tt.wantCode, w.Code)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
 		if tt.wantCode == http.StatusOK {
 			var resp httpmodels.ProductGetOneResponse
 			err := json.NewDecoder(w.Body).Decode(&resp)
@@ -387,6 +635,116 @@ func (suite *prodTestSuite) Test_GetOne() {
 	}
 }
 
+func (suite *prodTestSuite) Test_GetByIdentifier() {
+	product := models.Product{
+		Id: 1,
+		Name: "Test product",
+		Sku: "TP-01",
+		Barcodes: []string{"4006381333931"},
+		Version: 2,
+	}
+	tests := []struct{
+		name string
+		handler http.HandlerFunc
+		method string
+		key string
+		value string
+		state string
+		editor bool
+		wantCode int
+	}{
+		{
+			name: "by_sku",
+			handler: suite.getBySkuHandler,
+			method: "GetProductBySku",
+			key: "sku",
+			value: "TP-01",
+			editor: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "by_sku_not_found",
+			handler: suite.getBySkuHandler,
+			method: "GetProductBySku",
+			key: "sku",
+			value: "TP-02",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "by_barcode",
+			handler: suite.getByBarcodeHandler,
+			method: "GetProductByBarcode",
+			key: "barcode",
+			value: "4006381333931",
+			editor: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "by_sku_anonymous_published",
+			handler: suite.getBySkuHandler,
+			method: "GetProductBySku",
+			key: "sku",
+			value: "TP-01",
+			state: models.ProductStatePublished,
+			wantCode: http.StatusOK,
+		},
+		{
+			// requests without authorization don't see products which are not published
+			name: "by_sku_anonymous_draft",
+			handler: suite.getBySkuHandler,
+			method: "GetProductBySku",
+			key: "sku",
+			value: "TP-01",
+			state: models.ProductStateDraft,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "by_barcode_anonymous_archived",
+			handler: suite.getByBarcodeHandler,
+			method: "GetProductByBarcode",
+			key: "barcode",
+			value: "4006381333931",
+			state: models.ProductStateArchived,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "by_barcode_not_found",
+			handler: suite.getByBarcodeHandler,
+			method: "GetProductByBarcode",
+			key: "barcode",
+			value: "96385074",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/product/by-" + tt.key + "/" + tt.value, nil)
+		r = mux.SetURLVars(r, map[string]string{tt.key: tt.value})
+		if tt.editor {
+			r = r.WithContext(context.WithValue(r.Context(), myhttp.ContextKey("email"), "test@test.test"))
+		}
+		suite.productRepoMock.On(tt.method, mock.Anything, tt.value).Once().
+		Return(func(ctx context.Context, value string) (models.Product, error) {
+			if value != product.Sku && value != product.Barcodes[0] {
+				return models.Product{}, storage.ErrProductNotFound
+			}
+			found := product
+			found.State = tt.state
+			return found, nil
+		})
+		tt.handler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+		if tt.wantCode == http.StatusOK {
+			var resp httpmodels.ProductGetOneResponse
+			err := json.NewDecoder(w.Body).Decode(&resp)
+			suite.Require().NoError(err)
+			suite.Require().Equal(product.Sku, resp.Product.Sku)
+			suite.Require().Equal(product.Barcodes, resp.Product.Barcodes)
+			suite.Require().Equal(`"2"`, w.Header().Get("ETag"))
+		}
+	}
+}
+
 func (suite *prodTestSuite) Test_GetAll() {
 	products := map[string]models.Product{
 		"1": {
@@ -404,8 +762,14 @@ func (suite *prodTestSuite) Test_GetAll() {
 			},
 		},
 	}
+	minPrice, maxPrice := int64(100), int64(2000)
+	minWeight := 0.5
+	inStock := true
 	tests := []struct{
 		name string
+		query string
+		editor bool
+		wantFilter models.ProductFilter
 		wantGet bool
 		wantCode int
 	}{
@@ -414,14 +778,106 @@ func (suite *prodTestSuite) Test_GetAll() {
 			wantGet: true,
 			wantCode: http.StatusOK,
 		},
+		{
+			name: "price_filter",
+			query: "?min_price=100&max_price=2000&currency=EUR",
+			wantFilter: models.ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice, Currency: "EUR"},
+			wantGet: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "not_valid_min_price",
+			query: "?min_price=-1",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "min_price_greater_than_max_price",
+			query: "?min_price=2000&max_price=100",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "unknown_currency",
+			query: "?currency=usd",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "attribute_filter",
+			query: "?attr.weight.min=0.5&attr.colour=red&attr.colour=blue",
+			wantFilter: models.ProductFilter{Attributes: []models.AttributeFilter{
+				{Name: "colour", Values: []string{"red", "blue"}},
+				{Name: "weight", Min: &minWeight},
+			}},
+			wantGet: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "not_valid_attribute_name",
+			query: "?attr.Colour=red",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "not_valid_attribute_bound",
+			query: "?attr.weight.max=heavy",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "stock_filter",
+			query: "?in_stock=true",
+			wantFilter: models.ProductFilter{InStock: &inStock},
+			wantGet: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "not_valid_stock_filter",
+			query: "?in_stock=maybe",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "editor_any_state",
+			editor: true,
+			wantGet: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "editor_state_filter",
+			query: "?state=draft&state=in_review",
+			editor: true,
+			wantFilter: models.ProductFilter{States: []string{models.ProductStateDraft, models.ProductStateInReview}},
+			wantGet: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "published_state_filter",
+			query: "?state=published",
+			wantGet: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "anonymous_state_filter",
+			query: "?state=draft",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown_state",
+			query: "?state=deleted",
+			editor: true,
+			wantCode: http.StatusBadRequest,
+		},
 	}
 	for _, tt := range tests {
 		var jsonBody bytes.Buffer
 		w := httptest.NewRecorder()
-		r := httptest.NewRequest(http.MethodGet, "/api/products", &jsonBody)
+		r := httptest.NewRequest(http.MethodGet, "/api/products" + tt.query, &jsonBody)
+		wantFilter := tt.wantFilter
+		if tt.editor {
+			r = r.WithContext(context.WithValue(r.Context(), myhttp.ContextKey("email"), "test@test.test"))
+		} else {
+			// requests without authorization see only published products
+			wantFilter.States = []string{models.ProductStatePublished}
+		}
 		if tt.wantGet {
-			suite.productRepoMock.On("GetAllProducts", mock.Anything).Once().
-			Return(func(ctx context.Context) ([]models.Product, error) {
+			suite.productRepoMock.On("GetAllProducts", mock.Anything, wantFilter).Once().
+			Return(func(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
 				var outProducts []models.Product
 				for _, p := range products {
 					outProducts = append(outProducts, p)
@@ -430,7 +886,7 @@ func (suite *prodTestSuite) Test_GetAll() {
 			})
 		}
 		suite.getAllHandler.ServeHTTP(w, r)
-		suite.Require().Equal(tt.wantCode, w.Code)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
 		if tt.wantCode == http.StatusOK {
 			var resp httpmodels.ProductGetAllResponse
 			err := json.NewDecoder(w.Body).Decode(&resp)
@@ -490,8 +946,8 @@ func (suite *prodTestSuite) Test_GetAllByCategory() {
 		}
 		r = mux.SetURLVars(r, vars)
 		if tt.wantGet {
-			suite.productRepoMock.On("GetProductsByCategory", mock.Anything, mock.Anything).Once().
-			Return(func(ctx context.Context, catCode string) ([]models.Product, error) {
+			suite.productRepoMock.On("GetProductsByCategory", mock.Anything, mock.Anything, []string{models.ProductStatePublished}).Once().
+			Return(func(ctx context.Context, catCode string, states []string) ([]models.Product, error) {
 				return products[catCode], nil
 			})
 		}
@@ -506,6 +962,101 @@ func (suite *prodTestSuite) Test_GetAllByCategory() {
 	}
 }
 
+func (suite *prodTestSuite) Test_ChangeState() {
+	product := models.Product{Id: 1, Name: "Test product", Description: "test product", State: models.ProductStateInReview, Version: 3}
+	tests := []struct{
+		name string
+		state string
+		ifMatch string
+		wantSet bool
+		wantVersion int
+		setErr error
+		wantCode int
+	}{
+		{
+			name: "publish",
+			state: models.ProductStatePublished,
+			wantSet: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "back_to_draft",
+			state: models.ProductStateDraft,
+			ifMatch: `"3"`,
+			wantSet: true,
+			wantVersion: 3,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "not_allowed",
+			state: models.ProductStateArchived,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name: "same_state",
+			state: models.ProductStateInReview,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name: "changed_concurrently",
+			state: models.ProductStatePublished,
+			wantSet: true,
+			setErr: storage.ErrProductState,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name: "version_changed_concurrently",
+			state: models.ProductStatePublished,
+			ifMatch: `"3"`,
+			wantSet: true,
+			wantVersion: 3,
+			setErr: storage.ErrVersionConflict,
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "version_changed",
+			state: models.ProductStatePublished,
+			ifMatch: `"2"`,
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "unknown_state",
+			state: "deleted",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		var jsonBody bytes.Buffer
+		err := json.NewEncoder(&jsonBody).Encode(httpmodels.ProductStateRequest{State: tt.state})
+		suite.Require().NoError(err, "failed to encode request")
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/product/1/state", &jsonBody)
+		r = mux.SetURLVars(r, map[string]string{"productId": "1"})
+		if tt.ifMatch != "" {
+			r.Header.Set("If-Match", tt.ifMatch)
+		}
+		if tt.state != "deleted" {
+			suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(product, nil).Once()
+		}
+		if tt.wantSet {
+			changed := product
+			changed.State = tt.state
+			suite.productRepoMock.On("SetProductState", mock.Anything, "1", models.ProductStateInReview, tt.state, tt.wantVersion).Return(changed, tt.setErr).Once()
+			if tt.setErr == nil {
+				expectVersions(suite.versionRepoMock, models.VersionActionUpdate, "1")
+			}
+		}
+		suite.changeStateHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+	suite.productRepoMock.On("GetProductById", mock.Anything, "2").Return(models.Product{}, storage.ErrProductNotFound).Once()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/product/2/state", bytes.NewBufferString(`{"state": "published"}`))
+	r = mux.SetURLVars(r, map[string]string{"productId": "2"})
+	suite.changeStateHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusNotFound, w.Code)
+}
+
 func (suite *prodTestSuite) Test_Delete() {
 	tests := []struct{
 		name string
@@ -516,7 +1067,7 @@ func (suite *prodTestSuite) Test_Delete() {
 	}{
 		{
 			name: "happy_pass",
-			prodId: "test_code",
+			prodId: "1",
 			wantDelete: true,
 			wantCode: http.StatusOK,
 		},
@@ -535,10 +1086,342 @@ func (suite *prodTestSuite) Test_Delete() {
 		}
 		r = mux.SetURLVars(r, vars)
 		if tt.wantDelete {
-			suite.productRepoMock.On("DeleteProductById", mock.Anything, mock.AnythingOfType("string")).
-			Once().Return(nil)
+			suite.productRepoMock.On("DeleteProductById", mock.Anything, mock.AnythingOfType("string"), 0).
+			Once().Return(models.Product{Id: 1, Name: "Test product", Description: "test product"}, nil)
+			expectVersions(suite.versionRepoMock, models.VersionActionDelete, tt.prodId)
 		}
 		suite.deletehHanlder.ServeHTTP(w, r)
 		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
 	}
-}
\ No newline at end of file
+}
+
+func (suite *prodTestSuite) Test_IfMatch() {
+	product := models.Product{Id: 1, Name: "Test product", Description: "test product", State: models.ProductStatePublished, Version: 3}
+	w := httptest.NewRecorder()
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/product/1", nil), map[string]string{"productId": "1"})
+	suite.productRepoMock.On("GetProductById", mock.Anything, "1").Once().Return(product, nil)
+	suite.getOneHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.Require().Equal(`"3"`, w.Header().Get("ETag"))
+
+	tests := []struct{
+		name string
+		handler http.HandlerFunc
+		ifMatch string
+		wantEdit bool
+		wantDelete bool
+		wantCode int
+	}{
+		{
+			name: "edit_version_moved",
+			handler: suite.editHandler,
+			ifMatch: `"2"`,
+			wantEdit: true,
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "edit_weak_tag",
+			handler: suite.editHandler,
+			ifMatch: `W/"3"`,
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "delete_version_moved",
+			handler: suite.deletehHanlder,
+			ifMatch: `"2"`,
+			wantDelete: true,
+			wantCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "delete_not_a_version",
+			handler: suite.deletehHanlder,
+			ifMatch: `"first"`,
+			wantCode: http.StatusPreconditionFailed,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/product/1", bytes.NewBufferString(`{"product_new_data": {"name": "New name"}}`))
+		r.Header.Set("If-Match", tt.ifMatch)
+		r = mux.SetURLVars(r, map[string]string{"productId": "1"})
+		if tt.wantEdit {
+			suite.productRepoMock.On("UpdateProductById", mock.Anything, "1", mock.Anything, []int(nil), 2).Once().Return(models.Product{}, storage.ErrVersionConflict)
+		}
+		if tt.wantDelete {
+			suite.productRepoMock.On("DeleteProductById", mock.Anything, "1", 2).Once().Return(models.Product{}, storage.ErrVersionConflict)
+		}
+		tt.handler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
+
+func (suite *prodTestSuite) Test_Import() {
+	categories := map[string]int{
+		"test_category_one": 1,
+		"test_category_two": 2,
+	}
+	products := map[string]struct{}{
+		"Existing product": {},
+	}
+	tests := []struct{
+		name string
+		contentType string
+		query string
+		body string
+		wantGetCategoryId bool
+		wantSave bool
+		wantCode int
+		wantStatuses []string
+	}{
+		{
+			name: "ndjson_report",
+			contentType: "application/x-ndjson",
+			body: `{"name": "New product", "description": "Description", "category_codes": ["test_category_one", "test_category_two"]}
+{"name": "Existing product", "description": "Description"}
+{"name": "---", "description": "Description"}
+{"name": "Other product", "description": "Description", "category_codes": ["test_category_five"]}
+`,
+			wantGetCategoryId: true,
+			wantSave: true,
+			wantCode: http.StatusOK,
+			wantStatuses: []string{
+				models.ImportStatusCreated,
+				models.ImportStatusSkippedDuplicate,
+				models.ImportStatusInvalid,
+				models.ImportStatusInvalid,
+			},
+		},
+		{
+			name: "csv",
+			contentType: "text/csv",
+			body: "name,description,category_codes\nNew product,Description,test_category_one|test_category_two\n",
+			wantGetCategoryId: true,
+			wantSave: true,
+			wantCode: http.StatusOK,
+			wantStatuses: []string{
+				models.ImportStatusCreated,
+			},
+		},
+		{
+			name: "atomic_with_invalid_row",
+			contentType: "text/csv",
+			query: "?atomic=true",
+			body: "name,description\nNew product,Description\n---,Description\n",
+			wantCode: http.StatusUnprocessableEntity,
+			wantStatuses: []string{
+				models.ImportStatusRolledBack,
+				models.ImportStatusInvalid,
+			},
+		},
+		{
+			name: "not_valid_atomic",
+			contentType: "text/csv",
+			query: "?atomic=yes",
+			body: "name,description\nNew product,Description\n",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "unsupported_content_type",
+			contentType: "text/plain",
+			body: "New product",
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "broken_body",
+			contentType: "application/x-ndjson",
+			body: `{"name": "New product"`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/products/import" + tt.query, bytes.NewBufferString(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		if tt.wantGetCategoryId {
+			suite.categoryCodesRepoMock.On("GetCategoriesIdMapByCodes", mock.Anything, mock.Anything).Once().
+			Return(func(ctx context.Context, catCodes []string) (map[string]int, error) {
+				categoriesId := make(map[string]int)
+				for _, code := range catCodes {
+					if catId, ok := categories[code]; ok {
+						categoriesId[code] = catId
+					}
+				}
+				return categoriesId, nil
+			})
+		}
+		if tt.wantSave {
+			suite.productRepoMock.On("SaveProducts", mock.Anything, mock.Anything, mock.Anything, false).Once().
+			Return(func(ctx context.Context, prods []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error) {
+				results := make([]models.ImportRowResult, len(prods))
+				for idx, p := range prods {
+					results[idx].Name = p.Name
+					if _, ok := products[p.Name]; ok {
+						results[idx].Status = models.ImportStatusSkippedDuplicate
+						continue
+					}
+					results[idx].Status = models.ImportStatusCreated
+					results[idx].ProductId = fmt.Sprint(idx + 1)
+				}
+				return results, nil
+			})
+			expectVersions(suite.versionRepoMock, models.VersionActionCreate, "1")
+		}
+		suite.importHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+		if tt.wantStatuses == nil {
+			continue
+		}
+		var resp httpmodels.ProductImportResponse
+		err := json.NewDecoder(w.Body).Decode(&resp)
+		suite.Require().NoError(err, "test: %s", tt.name)
+		suite.Require().Len(resp.Rows, len(tt.wantStatuses), "test: %s", tt.name)
+		for idx, row := range resp.Rows {
+			suite.Require().Equal(idx + 1, row.Row, "test: %s", tt.name)
+			suite.Require().Equal(tt.wantStatuses[idx], row.Status, "test: %s, row: %d", tt.name, row.Row)
+		}
+	}
+}
+
+func (suite *prodTestSuite) Test_ImportDryRun() {
+	suite.productRepoMock.On("GetAllProducts", mock.Anything, mock.Anything).Once().Return([]models.Product{
+		{Id: 1, Name: "Existing product", Description: "Description", CategoryСodes: []string{"test_category_one"}},
+		{Id: 2, Name: "Old product", Description: "Description"},
+	}, nil)
+	suite.categoryCodesRepoMock.On("GetAllCategories", mock.Anything).Once().Return([]models.Category{
+		{Name: "Test category one", Code: "test_category_one", Description: "Test category one"},
+	}, nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/products/import?dry_run=true", bytes.NewBufferString(
+		"id,name,description,category_codes\n" +
+		"1,Renamed product,New description,\n" +
+		",New product,Description,test_category_one\n" +
+		",---,Description,\n",
+	))
+	r.Header.Set("Content-Type", "text/csv")
+	suite.importHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var resp httpmodels.ProductImportDryRunResponse
+	suite.Require().NoError(json.NewDecoder(w.Body).Decode(&resp))
+	suite.Require().True(resp.DryRun)
+	suite.Require().Equal(models.DiffSummary{New: 1, Changed: 1, Missing: 1, Invalid: 1}, resp.Diff.Summary)
+	change := resp.Diff.ChangedProducts[0]
+	suite.Require().Equal(&models.FieldChange{Old: "Existing product", New: "Renamed product"}, change.NameChange)
+	suite.Require().Equal(&models.FieldChange{Old: "Description", New: "New description"}, change.DescriptionChange)
+	suite.Require().Equal([]string{"test_category_one"}, change.RemovedCategories)
+	suite.Require().Equal(3, resp.Diff.Invalid[0].Row)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/api/products/import?dry_run=true&async=true", bytes.NewBufferString(""))
+	r.Header.Set("Content-Type", "text/csv")
+	suite.importHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusBadRequest, w.Code)
+}
+
+func (suite *prodTestSuite) Test_Unavailable() {
+	suite.productRepoMock.On("GetProductById", mock.Anything, "1").Once().
+		Return(models.Product{}, fmt.Errorf("%w: %w", storage.ErrUnavailable, storage.ErrQuery))
+	suite.productRepoMock.On("GetAllProducts", mock.Anything, mock.Anything).Once().
+		Return(nil, storage.ErrUnavailable)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/product/1", nil)
+	r = mux.SetURLVars(r, map[string]string{
+		"productId": "1",
+	})
+	suite.getOneHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusServiceUnavailable, w.Code)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/api/products", nil)
+	suite.getAllHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusServiceUnavailable, w.Code)
+	suite.Require().NotEmpty(w.Header().Get("Retry-After"))
+}
+
+func (suite *prodTestSuite) Test_Export() {
+	products := []models.Product{
+		{
+			Id: 1,
+			Name: "Test product",
+			Description: "test product",
+			CategoryСodes: []string{
+				"test_category_one",
+			},
+		},
+		{
+			Id: 2,
+			Name: "Test product 2",
+			Description: "test product",
+		},
+	}
+	tests := []struct{
+		name string
+		entity string
+		query string
+		wantFilter models.ProductFilter
+		wantStream bool
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "csv_with_columns_and_category",
+			entity: "products",
+			query: "?format=csv&columns=id,name&category=test_category_one,test_category_two",
+			wantFilter: models.ProductFilter{
+				CategoryCodes: []string{"test_category_one", "test_category_two"},
+			},
+			wantStream: true,
+			wantCode: http.StatusOK,
+			wantBody: "id,name\n1,Test product\n2,Test product 2\n",
+		},
+		{
+			name: "ndjson",
+			entity: "products",
+			query: "?format=ndjson&columns=name,category_codes",
+			wantStream: true,
+			wantCode: http.StatusOK,
+			wantBody: `{"name":"Test product","category_codes":["test_category_one"]}` + "\n" +
+				`{"name":"Test product 2","category_codes":[]}` + "\n",
+		},
+		{
+			name: "unknown_format",
+			entity: "products",
+			query: "?format=pdf",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "unknown_column",
+			entity: "products",
+			query: "?columns=name,price",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "unknown_entity",
+			entity: "users",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/export/%s%s", tt.entity, tt.query), nil)
+		r = mux.SetURLVars(r, map[string]string{
+			"entity": tt.entity,
+		})
+		if tt.wantStream {
+			suite.productRepoMock.On("StreamProducts", mock.Anything, tt.wantFilter, mock.Anything).Once().
+			Return(func(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error {
+				for _, p := range products {
+					if err := fn(p); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		suite.exportHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+		if tt.wantBody != "" {
+			suite.Require().Equal(tt.wantBody, w.Body.String(), "test: %s", tt.name)
+		}
+	}
+}
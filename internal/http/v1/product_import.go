@@ -0,0 +1,208 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/actor"
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/ingestion"
+	"github.com/EwvwGeN/cataloger/internal/validator"
+)
+
+type productImporter interface {
+	ImportProducts(ctx context.Context, rows []models.ImportRow, atomic bool) ([]models.ImportRowResult, error)
+	DiffImport(ctx context.Context, rows []models.ImportRow) (models.CatalogDiff, error)
+}
+
+type importJobSubmitter interface {
+	SubmitImport(ctx context.Context, contentType string, atomic bool, createdBy string, body io.Reader) (models.Job, error)
+}
+
+// ProductImport accepts newline delimited json or csv, fields are read according to the import mapping.
+// With async=true the file is only queued as import job and the job is returned.
+// With dry_run=true nothing is saved and the diff with the current catalog is returned
+func ProductImport(logger *slog.Logger, validCfg config.Validator, ingestCfg config.IngestionConfig, productImporter productImporter, jobSubmitter importJobSubmitter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "product_import"))
+	ingestCfg = ingestCfg.ForImport()
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to import products")
+		atomic := false
+		if atomicParam := r.URL.Query().Get("atomic"); atomicParam != "" {
+			var err error
+			atomic, err = strconv.ParseBool(atomicParam)
+			if err != nil {
+				log.Warn("wrong atomic parameter", slog.String("atomic", atomicParam))
+				http.Error(w, "error while importing products: wrong atomic parameter", http.StatusBadRequest)
+				return
+			}
+		}
+		async := false
+		if asyncParam := r.URL.Query().Get("async"); asyncParam != "" {
+			var err error
+			async, err = strconv.ParseBool(asyncParam)
+			if err != nil {
+				log.Warn("wrong async parameter", slog.String("async", asyncParam))
+				http.Error(w, "error while importing products: wrong async parameter", http.StatusBadRequest)
+				return
+			}
+		}
+		dryRun := false
+		if dryRunParam := r.URL.Query().Get("dry_run"); dryRunParam != "" {
+			var err error
+			dryRun, err = strconv.ParseBool(dryRunParam)
+			if err != nil || dryRun && async {
+				log.Warn("wrong dry_run parameter", slog.String("dry_run", dryRunParam))
+				http.Error(w, "error while importing products: wrong dry_run parameter", http.StatusBadRequest)
+				return
+			}
+		}
+		adapter, err := ingestion.AdapterByContentType(r.Header.Get("Content-Type"), ingestCfg)
+		if err != nil {
+			log.Warn("unsupported content type", slog.String("content_type", r.Header.Get("Content-Type")))
+			http.Error(w, "error while importing products: unsupported content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		// big files may be read and imported longer than the server timeouts
+		rc := http.NewResponseController(w)
+		rc.SetReadDeadline(time.Time{})
+		if dryRun {
+			diffImport(w, r, log, adapter, validCfg, productImporter)
+			return
+		}
+		if async {
+			job, err := jobSubmitter.SubmitImport(r.Context(), r.Header.Get("Content-Type"), atomic, actor.From(r.Context()), r.Body)
+			if err != nil {
+				if unavailable(w, log, err) {
+					return
+				}
+				log.Error("failed to submit import job", slog.String("error", err.Error()))
+				http.Error(w, "error while importing products", http.StatusInternalServerError)
+				return
+			}
+			res := &httpmodels.JobGetOneResponse{
+				Job: job,
+			}
+			resData, err := json.Marshal(res)
+			if err != nil {
+				log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+				http.Error(w, "error while importing products", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Add("Content-Type", "application/json")
+			w.Header().Add("Location", "/api/jobs/" + job.Id)
+			w.WriteHeader(http.StatusAccepted)
+			w.Write(resData)
+			return
+		}
+		rc.SetWriteDeadline(time.Time{})
+		var results []models.ImportRowResult
+		err = ingestion.ImportStream(r.Context(), adapter, r.Body, validCfg, productImporter, atomic, 0, func(batch []models.ImportRowResult) error {
+			results = append(results, batch...)
+			return nil
+		})
+		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
+			if errors.Is(err, ingestion.ErrDecode) || errors.Is(err, ingestion.ErrMissingColumn) {
+				log.Warn("failed to read import data", slog.String("error", err.Error()))
+				http.Error(w, "error while importing products: " + err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Error("failed to import products", slog.String("error", err.Error()))
+			http.Error(w, "error while importing products", http.StatusInternalServerError)
+			return
+		}
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].Row < results[j].Row
+		})
+		res := &httpmodels.ProductImportResponse{
+			Atomic: atomic,
+			Summary: make(map[string]int),
+			Rows: results,
+		}
+		for _, row := range results {
+			res.Summary[row.Status]++
+		}
+		res.RolledBack = atomic && res.Summary[models.ImportStatusCreated] == 0 &&
+			res.Summary[models.ImportStatusInvalid] + res.Summary[models.ImportStatusFailed] != 0
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while importing products", http.StatusInternalServerError)
+			return
+		}
+		status := http.StatusOK
+		if res.RolledBack {
+			status = http.StatusUnprocessableEntity
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(resData)
+	}
+}
+
+func diffImport(w http.ResponseWriter, r *http.Request, log *slog.Logger, adapter ingestion.Adapter, validCfg config.Validator, productImporter productImporter) {
+	var (
+		rows []models.ImportRow
+		invalid []models.ImportRowResult
+	)
+	err := adapter.Each(r.Body, func(item ingestion.Item) error {
+		if reason := validator.ProductInvalidReason(item.Product, validCfg); reason != "" {
+			invalid = append(invalid, models.ImportRowResult{
+				Row: item.Row,
+				Name: item.Product.Name,
+				Status: models.ImportStatusInvalid,
+				Reason: reason,
+			})
+			return nil
+		}
+		rows = append(rows, models.ImportRow{
+			Row: item.Row,
+			Product: item.Product,
+		})
+		return nil
+	})
+	if err != nil {
+		log.Warn("failed to read import data", slog.String("error", err.Error()))
+		http.Error(w, "error while importing products: " + err.Error(), http.StatusBadRequest)
+		return
+	}
+	diff, err := productImporter.DiffImport(r.Context(), rows)
+	if err != nil {
+		if unavailable(w, log, err) {
+			return
+		}
+		log.Error("failed to diff import", slog.String("error", err.Error()))
+		http.Error(w, "error while importing products", http.StatusInternalServerError)
+		return
+	}
+	diff.Invalid = append(diff.Invalid, invalid...)
+	sort.Slice(diff.Invalid, func(i, j int) bool {
+		return diff.Invalid[i].Row < diff.Invalid[j].Row
+	})
+	diff.Summary.Invalid = len(diff.Invalid)
+	res := &httpmodels.ProductImportDryRunResponse{
+		DryRun: true,
+		Diff: diff,
+	}
+	resData, err := json.Marshal(res)
+	if err != nil {
+		log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+		http.Error(w, "error while importing products", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resData)
+}
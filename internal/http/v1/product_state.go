@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	myhttp "github.com/EwvwGeN/cataloger/internal/http"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/gorilla/mux"
+)
+
+type productStateChanger interface {
+	ChangeProductState(ctx context.Context, prodId, state string, version int) error
+}
+
+// ProductChangeState moves the product to the state from the body if the transition is allowed,
+// the version from If-Match is checked like by the edit
+func ProductChangeState(logger *slog.Logger, productStateChanger productStateChanger) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "product_change_state"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to change product state")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while changing product state: empty product id", http.StatusBadRequest)
+			return
+		}
+		version, ok := ifMatch(r)
+		if !ok {
+			log.Warn("wrong if-match header", slog.String("if_match", r.Header.Get("If-Match")))
+			http.Error(w, "precondition failed: wrong If-Match header", http.StatusPreconditionFailed)
+			return
+		}
+		req := httpmodels.ProductStateRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			http.Error(w, "error while decoding request", http.StatusBadRequest)
+			return
+		}
+		log.Debug("got data from request", slog.Any("request_body", req))
+		err := productStateChanger.ChangeProductState(r.Context(), prodId, req.State, version)
+		if err != nil {
+			if unavailable(w, log, err) || preconditionFailed(w, log, err) {
+				return
+			}
+			switch {
+			case errors.Is(err, service.ErrProductNotFound):
+				log.Warn("failed to change product state", slog.String("error", err.Error()))
+				http.Error(w, "product with this id not found", http.StatusNotFound)
+			case errors.Is(err, service.ErrProductStateUnknown):
+				log.Warn("failed to change product state", slog.String("error", err.Error()))
+				http.Error(w, "error while changing product state: " + err.Error(), http.StatusBadRequest)
+			case errors.Is(err, service.ErrStateTransition), errors.Is(err, service.ErrProductStateChanged):
+				log.Warn("failed to change product state", slog.String("error", err.Error()))
+				http.Error(w, "error while changing product state: " + err.Error(), http.StatusConflict)
+			default:
+				log.Error("error while changing product state", slog.String("error", err.Error()))
+				http.Error(w, "failed to change product state", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// productStates reads the state params of the query, every one must be a known state
+func productStates(query url.Values) ([]string, error) {
+	states := query["state"]
+	for _, state := range states {
+		switch state {
+		case models.ProductStateDraft, models.ProductStateInReview, models.ProductStatePublished, models.ProductStateArchived:
+		default:
+			return nil, fmt.Errorf("unknown state %s", state)
+		}
+	}
+	return states, nil
+}
+
+// visibleStates returns the states of products listed by the request. Requests authorized by the optional
+// auth see the states they ask for, any state if none, the others see only published products.
+// ok is false if an anonymous request asks for other states
+func visibleStates(r *http.Request, states []string) ([]string, bool) {
+	if authorized(r) {
+		return states, true
+	}
+	for _, state := range states {
+		if state != models.ProductStatePublished {
+			return nil, false
+		}
+	}
+	return []string{models.ProductStatePublished}, true
+}
+
+// productVisible reports whether the product is shown to the request, like by visibleStates
+// anonymous requests see only published products
+func productVisible(r *http.Request, product models.Product) bool {
+	return authorized(r) || product.State == models.ProductStatePublished
+}
+
+// authorized reports whether the request is authorized by the optional auth
+func authorized(r *http.Request) bool {
+	_, ok := r.Context().Value(myhttp.ContextKey("email")).(string)
+	return ok
+}
+
+// PublishedProduct answers anonymous requests with 404 if the product from the path is missing or not published,
+// otherwise next is called. It guards reads of variants, media and stock of the product
+func PublishedProduct(logger *slog.Logger, productOneGetter productOneGetter, next http.HandlerFunc) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "published_product"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		if authorized(r) {
+			next(w, r)
+			return
+		}
+		prodId := mux.Vars(r)["productId"]
+		product, err := productOneGetter.GetOneProduct(r.Context(), prodId)
+		if err != nil && !errors.Is(err, service.ErrProductNotFound) {
+			if unavailable(w, log, err) {
+				return
+			}
+			log.Error("failed to get product", slog.String("error", err.Error()))
+			http.Error(w, "error while getting product", http.StatusInternalServerError)
+			return
+		}
+		// missing and not published products are answered alike, so their ids can't be told apart
+		if err != nil || !productVisible(r, product) {
+			log.Warn("product not found", slog.String("product_id", prodId))
+			http.Error(w, "product with this id not found", http.StatusNotFound)
+			return
+		}
+		next(w, r)
+	}
+}
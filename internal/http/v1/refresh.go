@@ -35,8 +35,11 @@ func Refresh(logger *slog.Logger, refresher refresher) http.HandlerFunc {
 			http.Error(w, "empty refresh token", http.StatusBadRequest)
 			return
 		}
-		tp, err := refresher.RefreshToken(context.Background(), req.TokenPair.AccessToken, req.TokenPair.RefreshToken)
+		tp, err := refresher.RefreshToken(r.Context(), req.TokenPair.AccessToken, req.TokenPair.RefreshToken)
 		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
 			log.Warn("cant refresh token", slog.String("error", err.Error()))
 			// edit error handling from RefreshToken()
 			http.Error(w, "error while refreshing token", http.StatusBadRequest)
@@ -39,8 +39,11 @@ func Register(logger *slog.Logger, registrator registrator, validateCfg config.V
 			http.Error(w, "error while validating password", http.StatusBadRequest)
 			return
 		}
-		err := registrator.RegisterUser(context.Background(), req.Email, req.Password)
+		err := registrator.RegisterUser(r.Context(), req.Email, req.Password)
 		if err != nil{
+			if unavailable(w, log, err) {
+				return
+			}
 			if errors.Is(err, service.ErrUserExist) {
 				log.Warn("failed to save user", slog.String("error", err.Error()))
 				http.Error(w, "error while registration: user already exist", http.StatusBadRequest)
@@ -0,0 +1,33 @@
+package v1
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+type poolStatsGetter interface {
+	PoolStats() models.PoolStats
+}
+
+func DBStats(logger *slog.Logger, poolStatsGetter poolStatsGetter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "db_stats"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Debug("attempt to get database pool stats")
+		res := &httpmodels.DBStatsResponse{
+			Pool: poolStatsGetter.PoolStats(),
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting stats", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
@@ -0,0 +1,49 @@
+package v1_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	v1 "github.com/EwvwGeN/cataloger/internal/http/v1"
+	"github.com/stretchr/testify/suite"
+)
+
+type poolStatsStub models.PoolStats
+
+func (ps poolStatsStub) PoolStats() models.PoolStats {
+	return models.PoolStats(ps)
+}
+
+type statsTestSuite struct {
+	suite.Suite
+}
+
+func TestStatsSuiteRun(t *testing.T) {
+	suite.Run(t, new(statsTestSuite))
+}
+
+func (suite *statsTestSuite) Test_DBStats() {
+	stats := models.PoolStats{
+		AcquireCount: 10,
+		AcquiredConns: 2,
+		IdleConns: 3,
+		MaxConns: 8,
+		TotalConns: 5,
+	}
+	lg := slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/stats/db", nil)
+	v1.DBStats(lg, poolStatsStub(stats)).ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var resp httpmodels.DBStatsResponse
+	suite.Require().NoError(json.NewDecoder(w.Body).Decode(&resp))
+	suite.Require().Equal(stats, resp.Pool)
+}
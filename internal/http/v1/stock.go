@@ -0,0 +1,391 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/EwvwGeN/cataloger/internal/validator"
+	"github.com/gorilla/mux"
+)
+
+type warehouseAdder interface {
+	AddWarehouse(ctx context.Context, warehouse models.Warehouse) error
+}
+
+type warehouseGetter interface {
+	GetWarehouses(ctx context.Context) ([]models.Warehouse, error)
+}
+
+type stockGetter interface {
+	GetStock(ctx context.Context, prodId string) ([]models.StockLevel, error)
+	GetStockMovements(ctx context.Context, prodId string) ([]models.StockMovement, error)
+}
+
+type stockAdjuster interface {
+	AdjustStock(ctx context.Context, prodId string, change models.StockChange) (models.StockLevel, error)
+}
+
+type stockReserver interface {
+	ReserveStock(ctx context.Context, prodId string, change models.StockChange) (models.Reservation, error)
+	GetReservation(ctx context.Context, reservationId string) (models.Reservation, error)
+	ReleaseReservation(ctx context.Context, reservationId string, reason string) (models.Reservation, error)
+	CommitReservation(ctx context.Context, reservationId string, reason string) (models.Reservation, error)
+}
+
+func WarehouseAdd(logger *slog.Logger, warehouseAdder warehouseAdder) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "warehouse_add"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to add warehouse")
+		req := httpmodels.WarehouseAddRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			http.Error(w, "error while decoding request", http.StatusBadRequest)
+			return
+		}
+		log.Debug("got data from request", slog.Any("request_body", req))
+		if reason := validator.WarehouseInvalidReason(req.Warehouse); reason != "" {
+			log.Info("validate error: " + reason, slog.Any("warehouse", req.Warehouse))
+			http.Error(w, "error while validating warehouse: " + reason, http.StatusBadRequest)
+			return
+		}
+		err := warehouseAdder.AddWarehouse(r.Context(), req.Warehouse)
+		if err != nil {
+			if unavailable(w, log, err) || stockFailed(w, log, err) {
+				return
+			}
+			log.Error("error while adding warehouse", slog.Any("warehouse", req.Warehouse))
+			http.Error(w, "failed to add warehouse", http.StatusBadRequest)
+			return
+		}
+		res := &httpmodels.WarehouseAddResponse{
+			Added: true,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while adding warehouse", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(resData)
+	}
+}
+
+func WarehouseGetAll(logger *slog.Logger, warehouseGetter warehouseGetter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "warehouse_get_all"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get warehouses")
+		warehouses, err := warehouseGetter.GetWarehouses(r.Context())
+		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
+			log.Error("failed to get warehouses", slog.String("error", err.Error()))
+			http.Error(w, "error while getting warehouses", http.StatusBadRequest)
+			return
+		}
+		if warehouses == nil {
+			warehouses = []models.Warehouse{}
+		}
+		res := &httpmodels.WarehousesGetResponse{
+			Warehouses: warehouses,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting warehouses", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+// StockGet returns the stock of the product in every warehouse
+func StockGet(logger *slog.Logger, stockGetter stockGetter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "stock_get"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get stock")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while getting stock: empty product id", http.StatusBadRequest)
+			return
+		}
+		levels, err := stockGetter.GetStock(r.Context(), prodId)
+		if err != nil {
+			if unavailable(w, log, err) || stockFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to get stock", slog.String("error", err.Error()))
+			http.Error(w, "error while getting stock", http.StatusBadRequest)
+			return
+		}
+		if levels == nil {
+			levels = []models.StockLevel{}
+		}
+		res := &httpmodels.StockGetResponse{
+			Stock: levels,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting stock", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+// StockMovements returns the ledger of the stock of the product from the oldest movement
+func StockMovements(logger *slog.Logger, stockGetter stockGetter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "stock_movements"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get stock movements")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while getting stock movements: empty product id", http.StatusBadRequest)
+			return
+		}
+		movements, err := stockGetter.GetStockMovements(r.Context(), prodId)
+		if err != nil {
+			if unavailable(w, log, err) || stockFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to get stock movements", slog.String("error", err.Error()))
+			http.Error(w, "error while getting stock movements", http.StatusBadRequest)
+			return
+		}
+		if movements == nil {
+			movements = []models.StockMovement{}
+		}
+		res := &httpmodels.StockMovementsGetResponse{
+			Movements: movements,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting stock movements", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+// StockAdjust changes units of the product on hand in the warehouse and returns the new stock level
+func StockAdjust(logger *slog.Logger, stockAdjuster stockAdjuster) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "stock_adjust"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to adjust stock")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while adjusting stock: empty product id", http.StatusBadRequest)
+			return
+		}
+		req := httpmodels.StockAdjustRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			http.Error(w, "error while decoding request", http.StatusBadRequest)
+			return
+		}
+		log.Debug("got data from request", slog.Any("request_body", req))
+		if reason := validator.StockChangeInvalidReason(req.Adjustment, true); reason != "" {
+			log.Info("validate error: " + reason, slog.Any("adjustment", req.Adjustment))
+			http.Error(w, "error while validating adjustment: " + reason, http.StatusBadRequest)
+			return
+		}
+		level, err := stockAdjuster.AdjustStock(r.Context(), prodId, req.Adjustment)
+		if err != nil {
+			if unavailable(w, log, err) || stockFailed(w, log, err) {
+				return
+			}
+			log.Error("error while adjusting stock", slog.Any("adjustment", req.Adjustment))
+			http.Error(w, "failed to adjust stock", http.StatusBadRequest)
+			return
+		}
+		res := &httpmodels.StockAdjustResponse{
+			Stock: level,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while adjusting stock", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+// StockReserve reserves available units of the product in the warehouse, it responds with 409 if there are not enough of them
+func StockReserve(logger *slog.Logger, stockReserver stockReserver) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "stock_reserve"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to reserve stock")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while reserving stock: empty product id", http.StatusBadRequest)
+			return
+		}
+		req := httpmodels.StockReserveRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			http.Error(w, "error while decoding request", http.StatusBadRequest)
+			return
+		}
+		log.Debug("got data from request", slog.Any("request_body", req))
+		if reason := validator.StockChangeInvalidReason(req.Reservation, false); reason != "" {
+			log.Info("validate error: " + reason, slog.Any("reservation", req.Reservation))
+			http.Error(w, "error while validating reservation: " + reason, http.StatusBadRequest)
+			return
+		}
+		reservation, err := stockReserver.ReserveStock(r.Context(), prodId, req.Reservation)
+		if err != nil {
+			if unavailable(w, log, err) || stockFailed(w, log, err) {
+				return
+			}
+			log.Error("error while reserving stock", slog.Any("reservation", req.Reservation))
+			http.Error(w, "failed to reserve stock", http.StatusBadRequest)
+			return
+		}
+		res := &httpmodels.ReservationResponse{
+			Reservation: reservation,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while reserving stock", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(resData)
+	}
+}
+
+func ReservationGet(logger *slog.Logger, stockReserver stockReserver) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "reservation_get"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get reservation")
+		reservationId, ok := mux.Vars(r)["reservationId"]
+		if !ok || reservationId == "" {
+			log.Warn("failed to get reservation id")
+			http.Error(w, "error while getting reservation: empty reservation id", http.StatusBadRequest)
+			return
+		}
+		reservation, err := stockReserver.GetReservation(r.Context(), reservationId)
+		if err != nil {
+			if unavailable(w, log, err) || stockFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to get reservation", slog.String("error", err.Error()))
+			http.Error(w, "error while getting reservation", http.StatusBadRequest)
+			return
+		}
+		res := &httpmodels.ReservationResponse{
+			Reservation: reservation,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting reservation", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+// ReservationRelease returns units of the active reservation to available ones
+func ReservationRelease(logger *slog.Logger, stockReserver stockReserver) http.HandlerFunc {
+	return reservationClose(logger.With(slog.String("handler", "reservation_release")), "release", stockReserver.ReleaseReservation)
+}
+
+// ReservationCommit takes units of the active reservation out of the warehouse
+func ReservationCommit(logger *slog.Logger, stockReserver stockReserver) http.HandlerFunc {
+	return reservationClose(logger.With(slog.String("handler", "reservation_commit")), "commit", stockReserver.CommitReservation)
+}
+
+// reservationClose closes the reservation with closeFn, the body with the reason of the action is optional
+func reservationClose(log *slog.Logger, action string, closeFn func(ctx context.Context, reservationId string, reason string) (models.Reservation, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to " + action + " reservation")
+		reservationId, ok := mux.Vars(r)["reservationId"]
+		if !ok || reservationId == "" {
+			log.Warn("failed to get reservation id")
+			http.Error(w, "error while closing reservation: empty reservation id", http.StatusBadRequest)
+			return
+		}
+		req := httpmodels.ReservationCloseRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			http.Error(w, "error while decoding request", http.StatusBadRequest)
+			return
+		}
+		if reason := validator.StockReasonInvalidReason(req.Reason); reason != "" {
+			log.Info("validate error: " + reason)
+			http.Error(w, "error while validating reservation: " + reason, http.StatusBadRequest)
+			return
+		}
+		reservation, err := closeFn(r.Context(), reservationId, req.Reason)
+		if err != nil {
+			if unavailable(w, log, err) || stockFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to " + action + " reservation", slog.String("error", err.Error()))
+			http.Error(w, "error while closing reservation", http.StatusBadRequest)
+			return
+		}
+		res := &httpmodels.ReservationResponse{
+			Reservation: reservation,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while closing reservation", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+// stockFailed responds to the errors of the stock service that a client can fix and reports whether it did
+func stockFailed(w http.ResponseWriter, log *slog.Logger, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrProductNotFound):
+		log.Warn("product not found", slog.String("error", err.Error()))
+		http.Error(w, "product with this id not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrReservationNotFound):
+		log.Warn("reservation not found", slog.String("error", err.Error()))
+		http.Error(w, service.ErrReservationNotFound.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrWarehouseNotFound), errors.Is(err, service.ErrWarehouseExist):
+		log.Warn("wrong warehouse", slog.String("error", err.Error()))
+		http.Error(w, "error while handling stock: " + err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrOutOfStock), errors.Is(err, service.ErrReservationClosed):
+		log.Warn("stock conflict", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		return false
+	}
+	return true
+}
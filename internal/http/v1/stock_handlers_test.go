@@ -0,0 +1,250 @@
+package v1_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	v1 "github.com/EwvwGeN/cataloger/internal/http/v1"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/EwvwGeN/cataloger/internal/service/mocks"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type stockTestSuite struct {
+	suite.Suite
+	stockRepoMock    *mocks.StockRepo
+	productRepoMock  *mocks.ProductRepo
+	warehouseHandler http.HandlerFunc
+	getHandler       http.HandlerFunc
+	adjustHandler    http.HandlerFunc
+	reserveHandler   http.HandlerFunc
+	releaseHandler   http.HandlerFunc
+	commitHandler    http.HandlerFunc
+}
+
+func TestStockSuiteRun(t *testing.T) {
+	suite.Run(t, new(stockTestSuite))
+}
+
+func (suite *stockTestSuite) SetupSuite() {
+	suite.stockRepoMock = mocks.NewStockRepo(suite.T())
+	suite.productRepoMock = mocks.NewProductRepo(suite.T())
+	lg := slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	stockService := service.NewStockService(lg, suite.stockRepoMock, suite.productRepoMock)
+	suite.warehouseHandler = v1.WarehouseAdd(lg, stockService)
+	suite.getHandler = v1.StockGet(lg, stockService)
+	suite.adjustHandler = v1.StockAdjust(lg, stockService)
+	suite.reserveHandler = v1.StockReserve(lg, stockService)
+	suite.releaseHandler = v1.ReservationRelease(lg, stockService)
+	suite.commitHandler = v1.ReservationCommit(lg, stockService)
+}
+
+func (suite *stockTestSuite) Test_AddWarehouse() {
+	tests := []struct{
+		name string
+		warehouse models.Warehouse
+		saveErr error
+		wantSave bool
+		wantCode int
+	}{
+		{
+			name: "happy_pass",
+			warehouse: models.Warehouse{Code: "main", Name: "Main warehouse"},
+			wantSave: true,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "not_valid_code",
+			warehouse: models.Warehouse{Code: "Main Warehouse", Name: "Main warehouse"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "empty_name",
+			warehouse: models.Warehouse{Code: "main"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "already_exist",
+			warehouse: models.Warehouse{Code: "main", Name: "Main warehouse"},
+			saveErr: storage.ErrWarehouseExist,
+			wantSave: true,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		var jsonBody bytes.Buffer
+		err := json.NewEncoder(&jsonBody).Encode(httpmodels.WarehouseAddRequest{Warehouse: tt.warehouse})
+		suite.Require().NoError(err, "failed to encode request")
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/warehouse/add", &jsonBody)
+		if tt.wantSave {
+			suite.stockRepoMock.On("SaveWarehouse", mock.Anything, tt.warehouse).Return(tt.saveErr).Once()
+		}
+		suite.warehouseHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
+
+func (suite *stockTestSuite) Test_Get() {
+	levels := []models.StockLevel{{Warehouse: "main", OnHand: 5, Reserved: 2, Available: 3}}
+	suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(models.Product{Id: 1}, nil).Once()
+	suite.stockRepoMock.On("GetStock", mock.Anything, "1").Return(levels, nil).Once()
+	suite.productRepoMock.On("GetProductById", mock.Anything, "2").Return(models.Product{}, storage.ErrProductNotFound).Once()
+	for prodId, wantCode := range map[string]int{"1": http.StatusOK, "2": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/product/stock", nil), map[string]string{"productId": prodId})
+		suite.getHandler.ServeHTTP(w, r)
+		suite.Require().Equal(wantCode, w.Code, "product: %s", prodId)
+		if wantCode != http.StatusOK {
+			continue
+		}
+		res := httpmodels.StockGetResponse{}
+		suite.Require().NoError(json.NewDecoder(w.Body).Decode(&res))
+		suite.Require().Equal(levels, res.Stock)
+	}
+}
+
+func (suite *stockTestSuite) Test_Change() {
+	tests := []struct{
+		name string
+		handler http.HandlerFunc
+		body any
+		repoMethod string
+		repoResult any
+		repoErr error
+		wantCode int
+	}{
+		{
+			name: "adjust",
+			handler: suite.adjustHandler,
+			body: httpmodels.StockAdjustRequest{Adjustment: models.StockChange{Warehouse: "main", Quantity: -3, Reason: "damaged"}},
+			repoMethod: "AdjustStock",
+			repoResult: models.StockLevel{Warehouse: "main", OnHand: 7, Available: 7},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "adjust_zero",
+			handler: suite.adjustHandler,
+			body: httpmodels.StockAdjustRequest{Adjustment: models.StockChange{Warehouse: "main"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "adjust_below_reserved",
+			handler: suite.adjustHandler,
+			body: httpmodels.StockAdjustRequest{Adjustment: models.StockChange{Warehouse: "main", Quantity: -10}},
+			repoMethod: "AdjustStock",
+			repoResult: models.StockLevel{},
+			repoErr: storage.ErrOutOfStock,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name: "adjust_unknown_warehouse",
+			handler: suite.adjustHandler,
+			body: httpmodels.StockAdjustRequest{Adjustment: models.StockChange{Warehouse: "north", Quantity: 1}},
+			repoMethod: "AdjustStock",
+			repoResult: models.StockLevel{},
+			repoErr: storage.ErrWarehouseNotFound,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "reserve",
+			handler: suite.reserveHandler,
+			body: httpmodels.StockReserveRequest{Reservation: models.StockChange{Warehouse: "main", Quantity: 2}},
+			repoMethod: "ReserveStock",
+			repoResult: models.Reservation{Id: 1, ProductId: 1, Warehouse: "main", Quantity: 2, State: models.ReservationActive},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "reserve_negative",
+			handler: suite.reserveHandler,
+			body: httpmodels.StockReserveRequest{Reservation: models.StockChange{Warehouse: "main", Quantity: -2}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "reserve_out_of_stock",
+			handler: suite.reserveHandler,
+			body: httpmodels.StockReserveRequest{Reservation: models.StockChange{Warehouse: "main", Quantity: 20}},
+			repoMethod: "ReserveStock",
+			repoResult: models.Reservation{},
+			repoErr: storage.ErrOutOfStock,
+			wantCode: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		var jsonBody bytes.Buffer
+		err := json.NewEncoder(&jsonBody).Encode(tt.body)
+		suite.Require().NoError(err, "failed to encode request")
+		w := httptest.NewRecorder()
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/api/product/1/stock", &jsonBody), map[string]string{"productId": "1"})
+		if tt.repoMethod != "" {
+			suite.stockRepoMock.On(tt.repoMethod, mock.Anything, "1", mock.AnythingOfType("models.StockChange")).Return(tt.repoResult, tt.repoErr).Once()
+		}
+		tt.handler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
+
+func (suite *stockTestSuite) Test_Close() {
+	released := models.Reservation{Id: 1, Warehouse: "main", Quantity: 2, State: models.ReservationReleased}
+	suite.stockRepoMock.On("ReleaseReservation", mock.Anything, "1", models.StockChange{Reason: "order cancelled"}).Return(released, nil).Once()
+	suite.stockRepoMock.On("CommitReservation", mock.Anything, "1", models.StockChange{}).Return(models.Reservation{}, storage.ErrReservationClosed).Once()
+	suite.stockRepoMock.On("CommitReservation", mock.Anything, "9", models.StockChange{}).Return(models.Reservation{}, storage.ErrReservationNotFound).Once()
+	tests := []struct{
+		name string
+		handler http.HandlerFunc
+		reservationId string
+		body string
+		wantCode int
+	}{
+		{
+			name: "release",
+			handler: suite.releaseHandler,
+			reservationId: "1",
+			body: `{"reason":"order cancelled"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "commit_closed",
+			handler: suite.commitHandler,
+			reservationId: "1",
+			wantCode: http.StatusConflict,
+		},
+		{
+			name: "commit_not_found",
+			handler: suite.commitHandler,
+			reservationId: "9",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "bad_body",
+			handler: suite.commitHandler,
+			reservationId: "1",
+			body: `{"reason":`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/reservation/close", bytes.NewBufferString(tt.body))
+		r = mux.SetURLVars(r, map[string]string{"reservationId": tt.reservationId})
+		tt.handler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		res := httpmodels.ReservationResponse{}
+		suite.Require().NoError(json.NewDecoder(w.Body).Decode(&res))
+		suite.Require().Equal(released, res.Reservation)
+	}
+}
@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/gorilla/mux"
+)
+
+type trashGetter interface {
+	GetTrash(ctx context.Context) (models.Trash, error)
+}
+
+type productRestorer interface {
+	RestoreProduct(ctx context.Context, prodId string) error
+}
+
+type categoryRestorer interface {
+	RestoreCategory(ctx context.Context, catCode string) error
+}
+
+// TrashGet lists deleted products and categories, the last deleted go first
+func TrashGet(logger *slog.Logger, trashGetter trashGetter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "trash_get"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get trash")
+		trash, err := trashGetter.GetTrash(r.Context())
+		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
+			log.Error("failed to get trash", slog.String("error", err.Error()))
+			http.Error(w, "error while getting trash", http.StatusInternalServerError)
+			return
+		}
+		res := &httpmodels.TrashGetResponse{
+			Products: trash.Products,
+			Categories: trash.Categories,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting trash", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+// ProductRestore takes the product out of the trash with its category links
+func ProductRestore(logger *slog.Logger, productRestorer productRestorer) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "product_restore"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to restore product")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while restoring product: empty product id", http.StatusBadRequest)
+			return
+		}
+		err := productRestorer.RestoreProduct(r.Context(), prodId)
+		if err != nil {
+			if unavailable(w, log, err) || identifierTaken(w, log, err) {
+				return
+			}
+			if errors.Is(err, service.ErrProductNotFound) {
+				log.Warn("failed to restore product", slog.String("error", err.Error()))
+				http.Error(w, "product with this id not found in trash", http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, service.ErrProductExist) {
+				log.Error("failed to restore product", slog.String("error", err.Error()))
+				http.Error(w, "error while restoring product: product with this name already exist", http.StatusBadRequest)
+				return
+			}
+			log.Error("failed to restore product", slog.String("error", err.Error()))
+			http.Error(w, "error while restoring product", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// CategoryRestore takes the last deleted category with the code out of the trash
+func CategoryRestore(logger *slog.Logger, categoryRestorer categoryRestorer) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "category_restore"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to restore category")
+		catCode, ok := mux.Vars(r)["catCode"]
+		if !ok || catCode == "" {
+			log.Warn("failed to get category code")
+			http.Error(w, "error while restoring category: empty category code", http.StatusBadRequest)
+			return
+		}
+		err := categoryRestorer.RestoreCategory(r.Context(), catCode)
+		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
+			if errors.Is(err, service.ErrCategoryNotFound) {
+				log.Warn("failed to restore category", slog.String("error", err.Error()))
+				http.Error(w, "category with this code not found in trash", http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, service.ErrCategoryExist) {
+				log.Error("failed to restore category", slog.String("error", err.Error()))
+				http.Error(w, "error while restoring category: category with this code already exist", http.StatusBadRequest)
+				return
+			}
+			log.Error("failed to restore category", slog.String("error", err.Error()))
+			http.Error(w, "error while restoring category", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,170 @@
+package v1_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	v1 "github.com/EwvwGeN/cataloger/internal/http/v1"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/EwvwGeN/cataloger/internal/service/mocks"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type trashTestSuite struct {
+	suite.Suite
+	trashRepoMock *mocks.TrashRepo
+	getHandler http.HandlerFunc
+	restoreProductHandler http.HandlerFunc
+	restoreCategoryHandler http.HandlerFunc
+}
+
+func TestTrashSuiteRun(t *testing.T) {
+	suite.Run(t, new(trashTestSuite))
+}
+
+func (suite *trashTestSuite) SetupSuite() {
+	suite.trashRepoMock = mocks.NewTrashRepo(suite.T())
+	lg := slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	trashService := service.NewTrashService(lg, config.TrashConfig{}, suite.trashRepoMock)
+	suite.getHandler = v1.TrashGet(lg, trashService)
+	suite.restoreProductHandler = v1.ProductRestore(lg, trashService)
+	suite.restoreCategoryHandler = v1.CategoryRestore(lg, trashService)
+}
+
+func (suite *trashTestSuite) Test_Get() {
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	trash := models.Trash{
+		Products: []models.TrashProduct{
+			{
+				Product: models.Product{Id: 1, Name: "Shirt", Description: "Cotton shirt", CategoryСodes: []string{"first"}},
+				DeletedAt: deletedAt,
+			},
+		},
+		Categories: []models.TrashCategory{
+			{
+				Category: models.Category{Name: "First", Code: "first", Description: "First category"},
+				DeletedAt: deletedAt,
+			},
+		},
+	}
+	suite.trashRepoMock.On("GetTrash", mock.Anything).Once().Return(trash, nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/trash", nil)
+	suite.getHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var res httpmodels.TrashGetResponse
+	suite.Require().NoError(json.NewDecoder(w.Body).Decode(&res))
+	suite.Require().Equal(trash.Products, res.Products)
+	suite.Require().Equal(trash.Categories, res.Categories)
+}
+
+func (suite *trashTestSuite) Test_RestoreProduct() {
+	tests := []struct{
+		name string
+		prodId string
+		restoreErr error
+		wantRestore bool
+		wantCode int
+	}{
+		{
+			name: "happy_pass",
+			prodId: "1",
+			wantRestore: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "not_in_trash",
+			prodId: "2",
+			restoreErr: storage.ErrProductNotFound,
+			wantRestore: true,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "name_taken",
+			prodId: "3",
+			restoreErr: storage.ErrProductExist,
+			wantRestore: true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "without_prod_id",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/product/%s/restore", tt.prodId), nil)
+		r = mux.SetURLVars(r, map[string]string{
+			"productId": tt.prodId,
+		})
+		if tt.wantRestore {
+			suite.trashRepoMock.On("RestoreProductById", mock.Anything, tt.prodId).Once().Return(tt.restoreErr)
+		}
+		suite.restoreProductHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
+
+func (suite *trashTestSuite) Test_RestoreCategory() {
+	tests := []struct{
+		name string
+		catCode string
+		restoreErr error
+		wantRestore bool
+		wantCode int
+	}{
+		{
+			name: "happy_pass",
+			catCode: "first",
+			wantRestore: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "not_in_trash",
+			catCode: "second",
+			restoreErr: storage.ErrCategoryNotFound,
+			wantRestore: true,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "code_taken",
+			catCode: "third",
+			restoreErr: storage.ErrCategoryExist,
+			wantRestore: true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "unavailable",
+			catCode: "fourth",
+			restoreErr: storage.ErrUnavailable,
+			wantRestore: true,
+			wantCode: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/category/%s/restore", tt.catCode), nil)
+		r = mux.SetURLVars(r, map[string]string{
+			"catCode": tt.catCode,
+		})
+		if tt.wantRestore {
+			suite.trashRepoMock.On("RestoreCategoryByCode", mock.Anything, tt.catCode).Once().Return(tt.restoreErr)
+		}
+		suite.restoreCategoryHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
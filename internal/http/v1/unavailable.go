@@ -0,0 +1,20 @@
+package v1
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/EwvwGeN/cataloger/internal/service"
+)
+
+// unavailable responds with 503 if err says the database is unavailable and reports whether it did
+func unavailable(w http.ResponseWriter, log *slog.Logger, err error) bool {
+	if !errors.Is(err, service.ErrUnavailable) {
+		return false
+	}
+	log.Error("database is unavailable", slog.String("error", err.Error()))
+	w.Header().Add("Retry-After", "10")
+	http.Error(w, "service is temporarily unavailable", http.StatusServiceUnavailable)
+	return true
+}
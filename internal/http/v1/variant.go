@@ -0,0 +1,257 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/EwvwGeN/cataloger/internal/validator"
+	"github.com/gorilla/mux"
+)
+
+type variantGetter interface {
+	GetVariants(ctx context.Context, prodId string) ([]models.VariantOption, []models.Variant, error)
+	GetVariant(ctx context.Context, prodId, variantId string) (models.Variant, error)
+}
+
+type variantAdder interface {
+	AddVariant(ctx context.Context, prodId string, variant models.Variant) (string, error)
+}
+
+type variantEditor interface {
+	EditVariant(ctx context.Context, prodId, variantId string, variant models.VariantForPatch) error
+}
+
+type variantDeleter interface {
+	DeleteVariant(ctx context.Context, prodId, variantId string) error
+}
+
+// VariantGetAll returns options of the product with all its variants
+func VariantGetAll(logger *slog.Logger, variantGetter variantGetter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "variant_get_all"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get variants")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while getting variants: empty product id", http.StatusBadRequest)
+			return
+		}
+		options, variants, err := variantGetter.GetVariants(r.Context(), prodId)
+		if err != nil {
+			if unavailable(w, log, err) || variantFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to get variants", slog.String("error", err.Error()))
+			http.Error(w, "error while getting variants", http.StatusBadRequest)
+			return
+		}
+		if options == nil {
+			options = []models.VariantOption{}
+		}
+		if variants == nil {
+			variants = []models.Variant{}
+		}
+		res := &httpmodels.VariantsGetResponse{
+			Options: options,
+			Variants: variants,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting variants", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+func VariantGetOne(logger *slog.Logger, variantGetter variantGetter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "variant_get_one"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get variant")
+		prodId, variantId, ok := variantVars(w, log, r)
+		if !ok {
+			return
+		}
+		variant, err := variantGetter.GetVariant(r.Context(), prodId, variantId)
+		if err != nil {
+			if unavailable(w, log, err) || variantFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to get variant", slog.String("error", err.Error()))
+			http.Error(w, "error while getting variant", http.StatusBadRequest)
+			return
+		}
+		res := &httpmodels.VariantGetOneResponse{
+			Variant: variant,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting variant", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+func VariantAdd(logger *slog.Logger, variantAdder variantAdder) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "variant_add"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to add variant")
+		prodId, ok := mux.Vars(r)["productId"]
+		if !ok || prodId == "" {
+			log.Warn("failed to get product id")
+			http.Error(w, "error while adding variant: empty product id", http.StatusBadRequest)
+			return
+		}
+		req := httpmodels.VariantAddRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			http.Error(w, "error while decoding request", http.StatusBadRequest)
+			return
+		}
+		log.Debug("got data from request", slog.Any("request_body", req))
+		if reason := validator.VariantInvalidReason(req.Variant); reason != "" {
+			log.Info("validate error: " + reason, slog.Any("variant", req.Variant))
+			http.Error(w, "error while validating variant: " + reason, http.StatusBadRequest)
+			return
+		}
+		variantId, err := variantAdder.AddVariant(r.Context(), prodId, req.Variant)
+		if err != nil {
+			if unavailable(w, log, err) || variantFailed(w, log, err) {
+				return
+			}
+			log.Error("error while adding variant", slog.Any("variant", req.Variant))
+			http.Error(w, "failed to add variant", http.StatusBadRequest)
+			return
+		}
+		res := &httpmodels.VariantAddResponse{
+			VariantId: variantId,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while adding variant", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(resData)
+	}
+}
+
+func VariantEdit(logger *slog.Logger, variantEditor variantEditor) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "variant_edit"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to edit variant")
+		prodId, variantId, ok := variantVars(w, log, r)
+		if !ok {
+			return
+		}
+		req := httpmodels.VariantEditRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			http.Error(w, "error while decoding request", http.StatusBadRequest)
+			return
+		}
+		log.Debug("got data from request", slog.Any("request_body", req))
+		newData := req.VariantNewData
+		if newData.Sku == nil && newData.Options == nil && newData.Prices == nil {
+			log.Warn("nothing to update")
+			http.Error(w, "error while editing: nothing to update", http.StatusBadRequest)
+			return
+		}
+		reason := ""
+		switch {
+		case newData.Sku != nil && !validator.ValidSku(*newData.Sku):
+			reason = "incorrect sku"
+		case newData.Options != nil && len(newData.Options) == 0:
+			reason = "variant has no options"
+		case newData.Options != nil:
+			reason = validator.OptionValuesInvalidReason(newData.Options)
+		}
+		if reason == "" {
+			reason = validator.PricesInvalidReason(newData.Prices)
+		}
+		if reason != "" {
+			log.Info("validate error: " + reason, slog.Any("variant", newData))
+			http.Error(w, "error while validating variant: " + reason, http.StatusBadRequest)
+			return
+		}
+		err := variantEditor.EditVariant(r.Context(), prodId, variantId, newData)
+		if err != nil {
+			if unavailable(w, log, err) || variantFailed(w, log, err) {
+				return
+			}
+			log.Error("error while editing variant", slog.Any("variant", newData))
+			http.Error(w, "failed to edit variant", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func VariantDelete(logger *slog.Logger, variantDeleter variantDeleter) http.HandlerFunc {
+	log := logger.With(slog.String("handler", "variant_delete"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to delete variant")
+		prodId, variantId, ok := variantVars(w, log, r)
+		if !ok {
+			return
+		}
+		err := variantDeleter.DeleteVariant(r.Context(), prodId, variantId)
+		if err != nil {
+			if unavailable(w, log, err) || variantFailed(w, log, err) {
+				return
+			}
+			log.Error("failed to delete variant", slog.String("error", err.Error()))
+			http.Error(w, "error while deleting variant", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// variantVars reads ids of the product and its variant from the path, it responds with 400 if one is empty
+func variantVars(w http.ResponseWriter, log *slog.Logger, r *http.Request) (string, string, bool) {
+	vars := mux.Vars(r)
+	prodId, variantId := vars["productId"], vars["variantId"]
+	if prodId == "" || variantId == "" {
+		log.Warn("failed to get variant id")
+		http.Error(w, "error while handling variant: empty product or variant id", http.StatusBadRequest)
+		return "", "", false
+	}
+	return prodId, variantId, true
+}
+
+// variantFailed responds to the errors of the variant service that a client can fix and reports whether it did
+func variantFailed(w http.ResponseWriter, log *slog.Logger, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrProductNotFound):
+		log.Warn("product not found", slog.String("error", err.Error()))
+		http.Error(w, "product with this id not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrVariantNotFound):
+		log.Warn("variant not found", slog.String("error", err.Error()))
+		http.Error(w, service.ErrVariantNotFound.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrVariantOptions):
+		log.Warn("variant doesn't fit the product", slog.String("error", err.Error()))
+		http.Error(w, "error while saving variant: " + err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrVariantExist), errors.Is(err, service.ErrVariantSkuExist):
+		log.Warn("variant is taken", slog.String("error", err.Error()))
+		http.Error(w, "error while saving variant: " + err.Error(), http.StatusBadRequest)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,300 @@
+package v1_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	myhttp "github.com/EwvwGeN/cataloger/internal/http"
+	v1 "github.com/EwvwGeN/cataloger/internal/http/v1"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/EwvwGeN/cataloger/internal/service/mocks"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type variantTestSuite struct {
+	suite.Suite
+	variantRepoMock *mocks.VariantRepo
+	productRepoMock *mocks.ProductRepo
+	versionRepoMock *mocks.VersionRepo
+	txManagerMock   *mocks.TxManager
+	getAllHandler   http.HandlerFunc
+	getOneHandler   http.HandlerFunc
+	publishedHandler http.HandlerFunc
+	addHandler      http.HandlerFunc
+	editHandler     http.HandlerFunc
+	deleteHandler   http.HandlerFunc
+	product         models.Product
+}
+
+func TestVariantSuiteRun(t *testing.T) {
+	suite.Run(t, new(variantTestSuite))
+}
+
+func (suite *variantTestSuite) SetupSuite() {
+	suite.variantRepoMock = mocks.NewVariantRepo(suite.T())
+	suite.productRepoMock = mocks.NewProductRepo(suite.T())
+	suite.versionRepoMock = mocks.NewVersionRepo(suite.T())
+	suite.txManagerMock = mocks.NewTxManager(suite.T())
+	passTx(suite.txManagerMock)
+	lg := slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	variantService := service.NewVariantService(lg, suite.variantRepoMock, suite.productRepoMock, suite.versionRepoMock, suite.txManagerMock)
+	suite.getAllHandler = v1.VariantGetAll(lg, variantService)
+	suite.getOneHandler = v1.VariantGetOne(lg, variantService)
+	suite.addHandler = v1.VariantAdd(lg, variantService)
+	suite.editHandler = v1.VariantEdit(lg, variantService)
+	suite.deleteHandler = v1.VariantDelete(lg, variantService)
+	productService := service.NewProductService(lg, suite.productRepoMock, nil, suite.versionRepoMock, suite.txManagerMock)
+	suite.publishedHandler = v1.PublishedProduct(lg, productService, suite.getAllHandler)
+	suite.product = models.Product{
+		Id: 1,
+		Name: "Shirt",
+		Options: []models.VariantOption{
+			{Name: "size", Values: []string{"S", "M", "L"}},
+			{Name: "colour", Values: []string{"red", "blue"}},
+		},
+		Variants: []models.Variant{
+			{Id: 1, Sku: "SHIRT-S-RED", Options: map[string]string{"size": "S", "colour": "red"}},
+		},
+	}
+}
+
+func (suite *variantTestSuite) Test_Get() {
+	suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Twice()
+	suite.productRepoMock.On("GetProductById", mock.Anything, "2").Return(models.Product{}, storage.ErrProductNotFound).Once()
+	tests := []struct{
+		name string
+		handler http.HandlerFunc
+		vars map[string]string
+		wantCode int
+	}{
+		{
+			name: "all",
+			handler: suite.getAllHandler,
+			vars: map[string]string{"productId": "1"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "one_not_found",
+			handler: suite.getOneHandler,
+			vars: map[string]string{"productId": "1", "variantId": "5"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "product_not_found",
+			handler: suite.getAllHandler,
+			vars: map[string]string{"productId": "2"},
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/product/variants", nil), tt.vars)
+		tt.handler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		res := httpmodels.VariantsGetResponse{}
+		suite.Require().NoError(json.NewDecoder(w.Body).Decode(&res), "test: %s", tt.name)
+		suite.Require().Equal(suite.product.Options, res.Options, "test: %s", tt.name)
+		suite.Require().Equal(suite.product.Variants, res.Variants, "test: %s", tt.name)
+	}
+}
+
+func (suite *variantTestSuite) Test_GetAnonymous() {
+	published := suite.product
+	published.State = models.ProductStatePublished
+	tests := []struct{
+		name string
+		product models.Product
+		getErr error
+		editor bool
+		wantCode int
+	}{
+		{
+			name: "published",
+			product: published,
+			wantCode: http.StatusOK,
+		},
+		{
+			// requests without authorization don't see variants of products which are not published
+			name: "draft",
+			product: suite.product,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			// missing product is answered like the one which is not published
+			name: "missing",
+			getErr: storage.ErrProductNotFound,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "draft_editor",
+			product: suite.product,
+			editor: true,
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/product/1/variants", nil), map[string]string{"productId": "1"})
+		if tt.editor {
+			r = r.WithContext(context.WithValue(r.Context(), myhttp.ContextKey("email"), "test@test.test"))
+		}
+		calls := 1
+		if tt.wantCode == http.StatusOK && !tt.editor {
+			calls++
+		}
+		suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(tt.product, tt.getErr).Times(calls)
+		suite.publishedHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
+
+func (suite *variantTestSuite) Test_Add() {
+	tests := []struct{
+		name string
+		variant models.Variant
+		saveErr error
+		wantGetProduct bool
+		wantSave bool
+		wantCode int
+	}{
+		{
+			name: "happy_pass",
+			variant: models.Variant{Sku: "SHIRT-M-BLUE", Options: map[string]string{"size": "M", "colour": "blue"}},
+			wantGetProduct: true,
+			wantSave: true,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name: "not_valid_sku",
+			variant: models.Variant{Sku: "shirt m", Options: map[string]string{"size": "M", "colour": "blue"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "no_options",
+			variant: models.Variant{Sku: "SHIRT-M-BLUE"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "unknown_value",
+			variant: models.Variant{Sku: "SHIRT-XL-BLUE", Options: map[string]string{"size": "XL", "colour": "blue"}},
+			wantGetProduct: true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "options_already_exist",
+			variant: models.Variant{Sku: "SHIRT-S-RED-2", Options: map[string]string{"size": "S", "colour": "red"}},
+			saveErr: storage.ErrVariantExist,
+			wantGetProduct: true,
+			wantSave: true,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		var jsonBody bytes.Buffer
+		err := json.NewEncoder(&jsonBody).Encode(httpmodels.VariantAddRequest{Variant: tt.variant})
+		suite.Require().NoError(err, "failed to encode request")
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/product/1/variants/add", &jsonBody)
+		r = mux.SetURLVars(r, map[string]string{"productId": "1"})
+		if tt.wantGetProduct {
+			suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+		}
+		if tt.wantSave {
+			suite.variantRepoMock.On("SaveVariant", mock.Anything, "1", tt.variant).Return("2", tt.saveErr).Once()
+		}
+		if tt.wantCode == http.StatusCreated {
+			suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+			expectVersions(suite.versionRepoMock, models.VersionActionUpdate, "1")
+		}
+		suite.addHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
+
+func (suite *variantTestSuite) Test_Edit() {
+	newSku := "SHIRT-L-RED"
+	tests := []struct{
+		name string
+		variantId string
+		patch models.VariantForPatch
+		wantGetProduct bool
+		wantUpdate *models.Variant
+		wantCode int
+	}{
+		{
+			name: "happy_pass",
+			variantId: "1",
+			patch: models.VariantForPatch{Sku: &newSku, Options: map[string]string{"size": "L", "colour": "red"}},
+			wantGetProduct: true,
+			wantUpdate: &models.Variant{Id: 1, Sku: newSku, Options: map[string]string{"size": "L", "colour": "red"}},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "missed_option",
+			variantId: "1",
+			patch: models.VariantForPatch{Options: map[string]string{"size": "L"}},
+			wantGetProduct: true,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "variant_not_found",
+			variantId: "7",
+			patch: models.VariantForPatch{Sku: &newSku},
+			wantGetProduct: true,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "nothing_to_update",
+			variantId: "1",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		var jsonBody bytes.Buffer
+		err := json.NewEncoder(&jsonBody).Encode(httpmodels.VariantEditRequest{VariantNewData: tt.patch})
+		suite.Require().NoError(err, "failed to encode request")
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/product/1/variants/%s/edit", tt.variantId), &jsonBody)
+		r = mux.SetURLVars(r, map[string]string{"productId": "1", "variantId": tt.variantId})
+		if tt.wantGetProduct {
+			suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+		}
+		if tt.wantUpdate != nil {
+			suite.variantRepoMock.On("UpdateVariantById", mock.Anything, "1", tt.variantId, *tt.wantUpdate).Return(nil).Once()
+			suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+			expectVersions(suite.versionRepoMock, models.VersionActionUpdate, "1")
+		}
+		suite.editHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
+
+func (suite *variantTestSuite) Test_Delete() {
+	suite.variantRepoMock.On("DeleteVariantById", mock.Anything, "1", "1").Return(nil).Once()
+	suite.productRepoMock.On("GetProductById", mock.Anything, "1").Return(suite.product, nil).Once()
+	expectVersions(suite.versionRepoMock, models.VersionActionUpdate, "1")
+	suite.variantRepoMock.On("DeleteVariantById", mock.Anything, "1", "9").Return(storage.ErrVariantNotFound).Once()
+	for variantId, wantCode := range map[string]int{"1": http.StatusOK, "9": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/product/1/variants/%s/delete", variantId), nil)
+		r = mux.SetURLVars(r, map[string]string{"productId": "1", "variantId": variantId})
+		suite.deleteHandler.ServeHTTP(w, r)
+		suite.Require().Equal(wantCode, w.Code, "variant: %s", variantId)
+	}
+}
@@ -0,0 +1,191 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/gorilla/mux"
+)
+
+type productVersioner interface {
+	ProductVersions(ctx context.Context, prodId string) ([]models.Version, error)
+	DiffProductVersions(ctx context.Context, prodId string, from, to int) (models.VersionDiff, error)
+	RevertProduct(ctx context.Context, prodId string, number int) error
+}
+
+type categoryVersioner interface {
+	CategoryVersions(ctx context.Context, catCode string) ([]models.Version, error)
+	DiffCategoryVersions(ctx context.Context, catCode string, from, to int) (models.VersionDiff, error)
+	RevertCategory(ctx context.Context, catCode string, number int) error
+}
+
+// versionedEntity tells the shared version handlers where to find the key of the entity
+type versionedEntity struct {
+	name string
+	keyVar string
+	keyName string
+}
+
+var (
+	productEntity = versionedEntity{name: "product", keyVar: "productId", keyName: "id"}
+	categoryEntity = versionedEntity{name: "category", keyVar: "catCode", keyName: "code"}
+)
+
+// ProductVersions lists versions of the product from the first one
+func ProductVersions(logger *slog.Logger, productVersioner productVersioner) http.HandlerFunc {
+	return versionsGet(logger.With(slog.String("handler", "product_versions")), productEntity, productVersioner.ProductVersions)
+}
+
+// ProductVersionsDiff compares versions of the product set by from and to query parameters
+func ProductVersionsDiff(logger *slog.Logger, productVersioner productVersioner) http.HandlerFunc {
+	return versionsDiff(logger.With(slog.String("handler", "product_versions_diff")), productEntity, productVersioner.DiffProductVersions)
+}
+
+// ProductRevert sets the product as it was in the version
+func ProductRevert(logger *slog.Logger, productVersioner productVersioner) http.HandlerFunc {
+	return versionRevert(logger.With(slog.String("handler", "product_revert")), productEntity, productVersioner.RevertProduct)
+}
+
+// CategoryVersions lists versions of the category code from the first one
+func CategoryVersions(logger *slog.Logger, categoryVersioner categoryVersioner) http.HandlerFunc {
+	return versionsGet(logger.With(slog.String("handler", "category_versions")), categoryEntity, categoryVersioner.CategoryVersions)
+}
+
+// CategoryVersionsDiff compares versions of the category set by from and to query parameters
+func CategoryVersionsDiff(logger *slog.Logger, categoryVersioner categoryVersioner) http.HandlerFunc {
+	return versionsDiff(logger.With(slog.String("handler", "category_versions_diff")), categoryEntity, categoryVersioner.DiffCategoryVersions)
+}
+
+// CategoryRevert sets the category as it was in the version
+func CategoryRevert(logger *slog.Logger, categoryVersioner categoryVersioner) http.HandlerFunc {
+	return versionRevert(logger.With(slog.String("handler", "category_revert")), categoryEntity, categoryVersioner.RevertCategory)
+}
+
+func versionsGet(log *slog.Logger, entity versionedEntity, getVersions func(context.Context, string) ([]models.Version, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to get versions")
+		key, ok := mux.Vars(r)[entity.keyVar]
+		if !ok || key == "" {
+			log.Warn(fmt.Sprintf("failed to get %s %s", entity.name, entity.keyName))
+			http.Error(w, fmt.Sprintf("error while getting versions: empty %s %s", entity.name, entity.keyName), http.StatusBadRequest)
+			return
+		}
+		versions, err := getVersions(r.Context(), key)
+		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
+			log.Error("failed to get versions", slog.String("error", err.Error()))
+			http.Error(w, "error while getting versions", http.StatusInternalServerError)
+			return
+		}
+		res := &httpmodels.VersionsGetResponse{
+			Versions: versions,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while getting versions", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+func versionsDiff(log *slog.Logger, entity versionedEntity, diffVersions func(context.Context, string, int, int) (models.VersionDiff, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to diff versions")
+		key, ok := mux.Vars(r)[entity.keyVar]
+		if !ok || key == "" {
+			log.Warn(fmt.Sprintf("failed to get %s %s", entity.name, entity.keyName))
+			http.Error(w, fmt.Sprintf("error while comparing versions: empty %s %s", entity.name, entity.keyName), http.StatusBadRequest)
+			return
+		}
+		from, fromErr := strconv.Atoi(r.URL.Query().Get("from"))
+		to, toErr := strconv.Atoi(r.URL.Query().Get("to"))
+		if fromErr != nil || toErr != nil {
+			log.Warn("wrong versions to compare")
+			http.Error(w, "error while comparing versions: from and to must be version numbers", http.StatusBadRequest)
+			return
+		}
+		diff, err := diffVersions(r.Context(), key, from, to)
+		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
+			if errors.Is(err, service.ErrVersionNotFound) {
+				log.Warn("failed to diff versions", slog.String("error", err.Error()))
+				http.Error(w, "version not found", http.StatusNotFound)
+				return
+			}
+			log.Error("failed to diff versions", slog.String("error", err.Error()))
+			http.Error(w, "error while comparing versions", http.StatusInternalServerError)
+			return
+		}
+		res := &httpmodels.VersionDiffResponse{
+			Diff: diff,
+		}
+		resData, err := json.Marshal(res)
+		if err != nil {
+			log.Error("cant encode response", slog.Any("response", res), slog.String("error", err.Error()))
+			http.Error(w, "error while comparing versions", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(resData)
+	}
+}
+
+func versionRevert(log *slog.Logger, entity versionedEntity, revert func(context.Context, string, int) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Info("attempt to revert " + entity.name)
+		key, ok := mux.Vars(r)[entity.keyVar]
+		if !ok || key == "" {
+			log.Warn(fmt.Sprintf("failed to get %s %s", entity.name, entity.keyName))
+			http.Error(w, fmt.Sprintf("error while reverting %s: empty %s %s", entity.name, entity.name, entity.keyName), http.StatusBadRequest)
+			return
+		}
+		number, err := strconv.Atoi(mux.Vars(r)["version"])
+		if err != nil {
+			log.Warn("wrong version number")
+			http.Error(w, fmt.Sprintf("error while reverting %s: wrong version number", entity.name), http.StatusBadRequest)
+			return
+		}
+		err = revert(r.Context(), key, number)
+		if err != nil {
+			if unavailable(w, log, err) {
+				return
+			}
+			switch {
+			case errors.Is(err, service.ErrVersionNotFound):
+				log.Warn("failed to revert", slog.String("error", err.Error()))
+				http.Error(w, "version not found", http.StatusNotFound)
+			case errors.Is(err, service.ErrProductNotFound), errors.Is(err, service.ErrCategoryNotFound):
+				log.Warn("failed to revert", slog.String("error", err.Error()))
+				http.Error(w, fmt.Sprintf("%s with this %s not found", entity.name, entity.keyName), http.StatusNotFound)
+			case errors.Is(err, service.ErrProductExist):
+				log.Error("failed to revert", slog.String("error", err.Error()))
+				http.Error(w, "error while reverting product: product with this name already exist", http.StatusBadRequest)
+			case errors.Is(err, service.ErrCategoriesCodes):
+				log.Error("failed to revert", slog.String("error", err.Error()))
+				http.Error(w, "error while reverting product: categories of the version not exist", http.StatusBadRequest)
+			default:
+				log.Error("failed to revert", slog.String("error", err.Error()))
+				http.Error(w, fmt.Sprintf("error while reverting %s", entity.name), http.StatusBadRequest)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,302 @@
+package v1_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/actor"
+	"github.com/EwvwGeN/cataloger/internal/domain/httpmodels"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	v1 "github.com/EwvwGeN/cataloger/internal/http/v1"
+	"github.com/EwvwGeN/cataloger/internal/service"
+	"github.com/EwvwGeN/cataloger/internal/service/mocks"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// expectVersions expects one SaveVersions call with a version of the action for every key
+func expectVersions(versionRepo *mocks.VersionRepo, action string, keys ...string) {
+	versionRepo.On("SaveVersions", mock.Anything, mock.MatchedBy(func(versions []models.Version) bool {
+		if len(versions) != len(keys) {
+			return false
+		}
+		for idx, version := range versions {
+			if version.Key != keys[idx] || version.Action != action || len(version.Snapshot) == 0 {
+				return false
+			}
+		}
+		return true
+	})).Once().Return(nil)
+}
+
+type versionsTestSuite struct {
+	suite.Suite
+	productRepoMock *mocks.ProductRepo
+	categoryCodesRepoMock *mocks.CategoryCodesRepo
+	categoryRepoMock *mocks.CategoryRepo
+	versionRepoMock *mocks.VersionRepo
+	txManagerMock *mocks.TxManager
+	productVersionsHandler http.HandlerFunc
+	productDiffHandler http.HandlerFunc
+	productRevertHandler http.HandlerFunc
+	categoryVersionsHandler http.HandlerFunc
+	categoryRevertHandler http.HandlerFunc
+}
+
+func TestVersionsSuiteRun(t *testing.T) {
+	suite.Run(t, new(versionsTestSuite))
+}
+
+func (suite *versionsTestSuite) SetupSuite() {
+	suite.productRepoMock = mocks.NewProductRepo(suite.T())
+	suite.categoryCodesRepoMock = mocks.NewCategoryCodesRepo(suite.T())
+	suite.categoryRepoMock = mocks.NewCategoryRepo(suite.T())
+	suite.versionRepoMock = mocks.NewVersionRepo(suite.T())
+	suite.txManagerMock = mocks.NewTxManager(suite.T())
+	passTx(suite.txManagerMock)
+	lg := slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	productService := service.NewProductService(lg, suite.productRepoMock, suite.categoryCodesRepoMock, suite.versionRepoMock, suite.txManagerMock)
+	categoryService := service.NewCategoryService(lg, suite.categoryRepoMock, suite.versionRepoMock, suite.txManagerMock)
+	suite.productVersionsHandler = v1.ProductVersions(lg, productService)
+	suite.productDiffHandler = v1.ProductVersionsDiff(lg, productService)
+	suite.productRevertHandler = v1.ProductRevert(lg, productService)
+	suite.categoryVersionsHandler = v1.CategoryVersions(lg, categoryService)
+	suite.categoryRevertHandler = v1.CategoryRevert(lg, categoryService)
+}
+
+func productVersion(number int, product models.Product) models.Version {
+	snapshot, _ := json.Marshal(product)
+	return models.Version{
+		Entity: models.VersionEntityProduct,
+		Key: fmt.Sprint(product.Id),
+		Version: number,
+		Action: models.VersionActionUpdate,
+		Actor: "test@test.test",
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Snapshot: snapshot,
+	}
+}
+
+func (suite *versionsTestSuite) Test_ProductVersions() {
+	versions := []models.Version{
+		productVersion(1, models.Product{Id: 1, Name: "Shirt", Description: "Cotton shirt"}),
+		productVersion(2, models.Product{Id: 1, Name: "Linen shirt", Description: "Cotton shirt"}),
+	}
+	suite.versionRepoMock.On("GetVersions", mock.Anything, models.VersionEntityProduct, "1").Once().Return(versions, nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/product/1/versions", nil)
+	r = mux.SetURLVars(r, map[string]string{"productId": "1"})
+	suite.productVersionsHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var res httpmodels.VersionsGetResponse
+	suite.Require().NoError(json.NewDecoder(w.Body).Decode(&res))
+	suite.Require().Len(res.Versions, 2)
+	suite.Require().Equal(2, res.Versions[1].Version)
+	suite.Require().Equal("test@test.test", res.Versions[1].Actor)
+	suite.Require().JSONEq(string(versions[1].Snapshot), string(res.Versions[1].Snapshot))
+}
+
+func (suite *versionsTestSuite) Test_ProductDiff() {
+	first := productVersion(1, models.Product{Id: 1, Name: "Shirt", Description: "Cotton shirt", CategoryСodes: []string{"first"}})
+	second := productVersion(2, models.Product{Id: 1, Name: "Linen shirt", Description: "Cotton shirt"})
+	tests := []struct{
+		name string
+		query string
+		wantGet bool
+		wantCode int
+		wantDiff models.VersionDiff
+	}{
+		{
+			name: "happy_pass",
+			query: "?from=1&to=2",
+			wantGet: true,
+			wantCode: http.StatusOK,
+			wantDiff: models.VersionDiff{
+				From: 1,
+				To: 2,
+				NameChange: &models.FieldChange{Old: "Shirt", New: "Linen shirt"},
+				RemovedCategories: []string{"first"},
+			},
+		},
+		{
+			name: "version_not_found",
+			query: "?from=1&to=3",
+			wantGet: true,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "without_to",
+			query: "?from=1",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		if tt.wantGet {
+			suite.versionRepoMock.On("GetVersion", mock.Anything, models.VersionEntityProduct, "1", mock.AnythingOfType("int")).Twice().
+			Return(func(ctx context.Context, entity, key string, number int) (models.Version, error) {
+				switch number {
+				case 1:
+					return first, nil
+				case 2:
+					return second, nil
+				}
+				return models.Version{}, storage.ErrVersionNotFound
+			})
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/product/1/versions/diff" + tt.query, nil)
+		r = mux.SetURLVars(r, map[string]string{"productId": "1"})
+		suite.productDiffHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		var res httpmodels.VersionDiffResponse
+		suite.Require().NoError(json.NewDecoder(w.Body).Decode(&res))
+		suite.Require().Equal(tt.wantDiff, res.Diff, "test: %s", tt.name)
+	}
+}
+
+func (suite *versionsTestSuite) Test_ProductRevert() {
+	version := productVersion(1, models.Product{Id: 1, Name: "Shirt", Description: "Cotton shirt", CategoryСodes: []string{"first"}})
+	tests := []struct{
+		name string
+		prodId string
+		version string
+		getErr error
+		categoriesId []int
+		updateErr error
+		wantUpdate bool
+		wantCode int
+	}{
+		{
+			name: "happy_pass",
+			prodId: "1",
+			version: "1",
+			categoriesId: []int{1},
+			wantUpdate: true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "version_not_found",
+			prodId: "1",
+			version: "5",
+			getErr: storage.ErrVersionNotFound,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "category_deleted",
+			prodId: "1",
+			version: "1",
+			categoriesId: []int{},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "product_deleted",
+			prodId: "1",
+			version: "1",
+			categoriesId: []int{1},
+			updateErr: storage.ErrProductNotFound,
+			wantUpdate: true,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "not_valid_version",
+			prodId: "1",
+			version: "last",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		if tt.version != "last" {
+			suite.versionRepoMock.On("GetVersion", mock.Anything, models.VersionEntityProduct, tt.prodId, mock.AnythingOfType("int")).Once().
+			Return(version, tt.getErr)
+		}
+		if tt.categoriesId != nil {
+			suite.categoryCodesRepoMock.On("GetCategoriesIdByCodes", mock.Anything, []string{"first"}).Once().Return(tt.categoriesId, nil)
+		}
+		if tt.wantUpdate {
+			suite.productRepoMock.On("UpdateProductById", mock.Anything, tt.prodId, mock.Anything, tt.categoriesId, 0).Once().
+			Return(func(ctx context.Context, prodId string, patch models.ProductForPatch, catIds []int, version int) (models.Product, error) {
+				suite.Require().Equal("Shirt", *patch.Name)
+				suite.Require().Equal("Cotton shirt", *patch.Description)
+				if tt.updateErr != nil {
+					return models.Product{}, tt.updateErr
+				}
+				return models.Product{Id: 1, Name: "Shirt", Description: "Cotton shirt", CategoryСodes: []string{"first"}}, nil
+			})
+		}
+		if tt.wantCode == http.StatusOK {
+			suite.versionRepoMock.On("SaveVersions", mock.Anything, mock.MatchedBy(func(versions []models.Version) bool {
+				return len(versions) == 1 && versions[0].Action == models.VersionActionRevert &&
+					versions[0].RevertedTo == 1 && versions[0].Actor == "test@test.test"
+			})).Once().Return(nil)
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/product/%s/versions/%s/revert", tt.prodId, tt.version), nil)
+		r = mux.SetURLVars(r, map[string]string{"productId": tt.prodId, "version": tt.version})
+		r = r.WithContext(actor.With(r.Context(), "test@test.test"))
+		suite.productRevertHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
+
+func (suite *versionsTestSuite) Test_CategoryVersions() {
+	suite.versionRepoMock.On("GetVersions", mock.Anything, models.VersionEntityCategory, "first").Once().Return([]models.Version{}, nil)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/category/first/versions", nil)
+	r = mux.SetURLVars(r, map[string]string{"catCode": "first"})
+	suite.categoryVersionsHandler.ServeHTTP(w, r)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.Require().JSONEq(`{"versions": []}`, w.Body.String())
+}
+
+func (suite *versionsTestSuite) Test_CategoryRevert() {
+	category := models.Category{Name: "First", Code: "first", Description: "First category"}
+	snapshot, _ := json.Marshal(category)
+	version := models.Version{Version: 1, Action: models.VersionActionCreate, Snapshot: snapshot}
+	tests := []struct{
+		name string
+		catCode string
+		updated models.Category
+		wantCode int
+	}{
+		{
+			name: "happy_pass",
+			catCode: "first",
+			updated: category,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "category_deleted",
+			catCode: "first",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		suite.versionRepoMock.On("GetVersion", mock.Anything, models.VersionEntityCategory, tt.catCode, 1).Once().Return(version, nil)
+		suite.categoryRepoMock.On("UpdateCategoryByCode", mock.Anything, tt.catCode, models.CategoryForPatch{
+			Name: &category.Name,
+			Description: &category.Description,
+		}, 0).Once().Return(tt.updated, nil)
+		if tt.wantCode == http.StatusOK {
+			expectVersions(suite.versionRepoMock, models.VersionActionRevert, tt.catCode)
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/category/%s/versions/1/revert", tt.catCode), nil)
+		r = mux.SetURLVars(r, map[string]string{"catCode": tt.catCode, "version": "1"})
+		suite.categoryRevertHandler.ServeHTTP(w, r)
+		suite.Require().Equal(tt.wantCode, w.Code, "test: %s", tt.name)
+	}
+}
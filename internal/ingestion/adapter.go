@@ -0,0 +1,213 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatXML  = "xml"
+)
+
+// Item is one product read from the source with categories it references.
+// Product.CategoryСodes has the codes of Categories in the same order
+type Item struct {
+	Row        int
+	Product    models.Product
+	Categories []models.Category
+}
+
+// Adapter reads source stream item by item
+type Adapter interface {
+	// Each calls fn for every item of the stream, stops on the first error returned by fn
+	Each(r io.Reader, fn func(Item) error) error
+}
+
+// NewAdapter returns adapter for the given format: json, csv or xml
+func NewAdapter(format string, cfg config.IngestionConfig) (Adapter, error) {
+	m := withDefaults(cfg.Mapping)
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return &jsonAdapter{mapping: m}, nil
+	case FormatCSV:
+		delimiter := ','
+		if cfg.CSVDelimiter != "" {
+			delimiter = []rune(cfg.CSVDelimiter)[0]
+		}
+		return &csvAdapter{mapping: m, delimiter: delimiter}, nil
+	case FormatXML:
+		item := cfg.XMLItem
+		if item == "" {
+			item = "item"
+		}
+		return &xmlAdapter{mapping: m, item: item}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// FormatByContentType maps Content-Type header value to the format name
+func FormatByContentType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", ErrUnknownFormat
+	}
+	switch mediaType {
+	case "application/json", "application/x-ndjson", "application/ndjson":
+		return FormatJSON, nil
+	case "text/csv", "application/csv":
+		return FormatCSV, nil
+	case "application/xml", "text/xml":
+		return FormatXML, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// AdapterByContentType returns adapter by Content-Type header value
+func AdapterByContentType(contentType string, cfg config.IngestionConfig) (Adapter, error) {
+	format, err := FormatByContentType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	return NewAdapter(format, cfg)
+}
+
+// ReadAll reads all items from the stream
+func ReadAll(a Adapter, r io.Reader) ([]Item, error) {
+	var items []Item
+	err := a.Each(r, func(item Item) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func withDefaults(m config.FieldMapping) config.FieldMapping {
+	if m.Name == "" {
+		m.Name = "name"
+	}
+	if m.Description == "" {
+		m.Description = "description"
+	}
+	if m.Category == "" {
+		m.Category = "category"
+	}
+	return m
+}
+
+// buildItem makes item from the source record, values returns all values of the record field.
+// The error is returned if the attributes are not a json object
+func buildItem(row int, m config.FieldMapping, values func(field string) []string) (Item, error) {
+	item := Item{
+		Row: row,
+		Product: models.Product{
+			Name:        strings.TrimSpace(first(values(m.Name))),
+			Description: strings.TrimSpace(first(values(m.Description))),
+		},
+	}
+	if m.Id != "" {
+		// not numeric ids are foreign for the catalog and ignored
+		item.Product.Id, _ = strconv.Atoi(strings.TrimSpace(first(values(m.Id))))
+	}
+	if m.Sku != "" {
+		item.Product.Sku = strings.TrimSpace(first(values(m.Sku)))
+	}
+	if m.Barcode != "" {
+		if barcodes := split(values(m.Barcode), m.CategorySeparator); len(barcodes) != 0 {
+			item.Product.Barcodes = barcodes
+		}
+	}
+	if m.Attributes != "" {
+		if raw := strings.TrimSpace(first(values(m.Attributes))); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &item.Product.Attributes); err != nil {
+				return Item{}, fmt.Errorf("attributes: %w", err)
+			}
+		}
+	}
+	catNames := split(values(m.Category), m.CategorySeparator)
+	var catCodes []string
+	if m.CategoryCode != "" {
+		catCodes = split(values(m.CategoryCode), m.CategorySeparator)
+	}
+	for idx, name := range catNames {
+		code := CodeFromName(name)
+		if idx < len(catCodes) {
+			code = catCodes[idx]
+		}
+		item.addCategory(name, code)
+	}
+	for idx := len(catNames); idx < len(catCodes); idx++ {
+		item.addCategory(catCodes[idx], catCodes[idx])
+	}
+	return item, nil
+}
+
+func (i *Item) addCategory(name, code string) {
+	if code == "" {
+		return
+	}
+	for _, c := range i.Product.CategoryСodes {
+		if c == code {
+			return
+		}
+	}
+	i.Product.CategoryСodes = append(i.Product.CategoryСodes, code)
+	i.Categories = append(i.Categories, models.Category{
+		Name:        name,
+		Code:        code,
+		Description: name,
+	})
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func split(values []string, sep string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		parts := []string{v}
+		if sep != "" {
+			parts = strings.Split(v, sep)
+		}
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+// CodeFromName makes category code from its name: "Smileys and people" -> "smileys_and_people"
+func CodeFromName(name string) string {
+	var sb strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			underscore = false
+			continue
+		}
+		if !underscore && sb.Len() > 0 {
+			sb.WriteRune('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "_")
+}
@@ -0,0 +1,225 @@
+package ingestion_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/ingestion"
+	"github.com/stretchr/testify/suite"
+)
+
+type adapterTestSuite struct {
+	suite.Suite
+	wantItems []ingestion.Item
+}
+
+func TestAdapterSuiteRun(t *testing.T) {
+	suite.Run(t, new(adapterTestSuite))
+}
+
+func (suite *adapterTestSuite) SetupSuite() {
+	suite.wantItems = []ingestion.Item{
+		{
+			Row: 1,
+			Product: models.Product{
+				Name: "Red shirt",
+				Description: "Cotton shirt",
+				CategoryСodes: []string{"clothes", "summer_sale"},
+			},
+			Categories: []models.Category{
+				{Name: "Clothes", Code: "clothes", Description: "Clothes"},
+				{Name: "Summer sale", Code: "summer_sale", Description: "Summer sale"},
+			},
+		},
+		{
+			Row: 2,
+			Product: models.Product{
+				Name: "Blue jeans",
+			},
+		},
+	}
+}
+
+func (suite *adapterTestSuite) Test_Each() {
+	tests := []struct{
+		name string
+		format string
+		cfg config.IngestionConfig
+		data string
+	}{
+		{
+			name: "json_array",
+			format: ingestion.FormatJSON,
+			cfg: config.IngestionConfig{
+				Mapping: config.FieldMapping{
+					Name: "title",
+					Description: "text",
+					Category: "tags",
+				},
+			},
+			data: `[
+				{"title": "Red shirt", "text": "Cotton shirt", "tags": ["Clothes", "Summer sale"]},
+				{"title": "Blue jeans"}
+			]`,
+		},
+		{
+			name: "ndjson",
+			format: ingestion.FormatJSON,
+			cfg: config.IngestionConfig{
+				Mapping: config.FieldMapping{
+					CategorySeparator: ";",
+				},
+			},
+			data: `{"name": "Red shirt", "description": "Cotton shirt", "category": "Clothes; Summer sale"}
+{"name": "Blue jeans", "description": null}
+`,
+		},
+		{
+			name: "csv",
+			format: ingestion.FormatCSV,
+			cfg: config.IngestionConfig{
+				CSVDelimiter: ";",
+				Mapping: config.FieldMapping{
+					Name: "Title",
+					Category: "Category",
+					CategorySeparator: "|",
+				},
+			},
+			data: "Title;description;Category\nRed shirt;Cotton shirt;Clothes|Summer sale\nBlue jeans;;\n",
+		},
+		{
+			name: "xml",
+			format: ingestion.FormatXML,
+			cfg: config.IngestionConfig{
+				XMLItem: "offer",
+				Mapping: config.FieldMapping{
+					Name: "name",
+					Description: "about",
+				},
+			},
+			data: `<?xml version="1.0"?>
+<catalog>
+	<offers>
+		<offer name="Red shirt">
+			<about>Cotton shirt</about>
+			<category>Clothes</category>
+			<category>Summer sale</category>
+		</offer>
+		<offer>
+			<name>Blue jeans</name>
+			<extra><about>not a field of the offer</about></extra>
+		</offer>
+	</offers>
+</catalog>`,
+		},
+	}
+	for _, tt := range tests {
+		adapter, err := ingestion.NewAdapter(tt.format, tt.cfg)
+		suite.Require().NoError(err, "test: %s", tt.name)
+		items, err := ingestion.ReadAll(adapter, strings.NewReader(tt.data))
+		suite.Require().NoError(err, "test: %s", tt.name)
+		suite.Require().Equal(suite.wantItems, items, "test: %s", tt.name)
+	}
+}
+
+func (suite *adapterTestSuite) Test_CategoryCodeMapping() {
+	adapter, err := ingestion.NewAdapter(ingestion.FormatCSV, config.IngestionConfig{
+		Mapping: config.FieldMapping{
+			CategoryCode: "category_code",
+		},
+	})
+	suite.Require().NoError(err)
+	items, err := ingestion.ReadAll(adapter, strings.NewReader("name,category,category_code\nShirt,Clothes,cl_01\nHat,,hats\n"))
+	suite.Require().NoError(err)
+	suite.Require().Len(items, 2)
+	suite.Equal([]models.Category{{Name: "Clothes", Code: "cl_01", Description: "Clothes"}}, items[0].Categories)
+	suite.Equal([]models.Category{{Name: "hats", Code: "hats", Description: "hats"}}, items[1].Categories)
+}
+
+func (suite *adapterTestSuite) Test_IdentifierMapping() {
+	adapter, err := ingestion.NewAdapter(ingestion.FormatCSV, config.IngestionConfig{}.ForImport())
+	suite.Require().NoError(err)
+	items, err := ingestion.ReadAll(adapter, strings.NewReader("name,sku,barcodes\nShirt,SH-01,4006381333931| 96385074\nHat,,\n"))
+	suite.Require().NoError(err)
+	suite.Require().Len(items, 2)
+	suite.Equal("SH-01", items[0].Product.Sku)
+	suite.Equal([]string{"4006381333931", "96385074"}, items[0].Product.Barcodes)
+	suite.Empty(items[1].Product.Sku)
+	suite.Nil(items[1].Product.Barcodes)
+}
+
+func (suite *adapterTestSuite) Test_AttributesMapping() {
+	want := map[string]interface{}{"material": "wool", "weight": 120.5}
+	tests := []struct{
+		name string
+		format string
+		data string
+	}{
+		{
+			name: "csv",
+			format: ingestion.FormatCSV,
+			data: "name,attributes\nScarf,\"{\"\"material\"\": \"\"wool\"\", \"\"weight\"\": 120.5}\"\nHat,\n",
+		},
+		{
+			name: "json",
+			format: ingestion.FormatJSON,
+			data: `[{"name": "Scarf", "attributes": {"material": "wool", "weight": 120.5}}, {"name": "Hat"}]`,
+		},
+		{
+			name: "xml",
+			format: ingestion.FormatXML,
+			data: `<items><item><name>Scarf</name><attributes>{"material": "wool", "weight": 120.5}</attributes></item><item><name>Hat</name></item></items>`,
+		},
+	}
+	for _, tt := range tests {
+		adapter, err := ingestion.NewAdapter(tt.format, config.IngestionConfig{}.ForImport())
+		suite.Require().NoError(err, tt.name)
+		items, err := ingestion.ReadAll(adapter, strings.NewReader(tt.data))
+		suite.Require().NoError(err, tt.name)
+		suite.Require().Len(items, 2, tt.name)
+		suite.Equal(want, items[0].Product.Attributes, tt.name)
+		suite.Nil(items[1].Product.Attributes, tt.name)
+	}
+
+	adapter, err := ingestion.NewAdapter(ingestion.FormatCSV, config.IngestionConfig{}.ForImport())
+	suite.Require().NoError(err)
+	_, err = ingestion.ReadAll(adapter, strings.NewReader("name,attributes\nScarf,wool\n"))
+	suite.Require().ErrorIs(err, ingestion.ErrDecode)
+}
+
+func (suite *adapterTestSuite) Test_Errors() {
+	_, err := ingestion.NewAdapter("yaml", config.IngestionConfig{})
+	suite.Require().ErrorIs(err, ingestion.ErrUnknownFormat)
+
+	adapter, err := ingestion.NewAdapter(ingestion.FormatCSV, config.IngestionConfig{})
+	suite.Require().NoError(err)
+	_, err = ingestion.ReadAll(adapter, strings.NewReader("title,description\nShirt,Cotton\n"))
+	suite.Require().ErrorIs(err, ingestion.ErrMissingColumn)
+
+	adapter, err = ingestion.NewAdapter(ingestion.FormatJSON, config.IngestionConfig{})
+	suite.Require().NoError(err)
+	_, err = ingestion.ReadAll(adapter, strings.NewReader(`[{"name": "Shirt"}, {"name": `))
+	suite.Require().ErrorIs(err, ingestion.ErrDecode)
+}
+
+func (suite *adapterTestSuite) Test_FormatByContentType() {
+	tests := []struct{
+		contentType string
+		wantFormat string
+		wantErr error
+	}{
+		{contentType: "application/json; charset=utf-8", wantFormat: ingestion.FormatJSON},
+		{contentType: "application/x-ndjson", wantFormat: ingestion.FormatJSON},
+		{contentType: "text/csv", wantFormat: ingestion.FormatCSV},
+		{contentType: "text/xml", wantFormat: ingestion.FormatXML},
+		{contentType: "text/plain", wantErr: ingestion.ErrUnknownFormat},
+		{contentType: "", wantErr: ingestion.ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		format, err := ingestion.FormatByContentType(tt.contentType)
+		suite.Require().ErrorIs(err, tt.wantErr, "content type: %s", tt.contentType)
+		suite.Require().Equal(tt.wantFormat, format, "content type: %s", tt.contentType)
+	}
+}
@@ -0,0 +1,61 @@
+package ingestion
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+)
+
+// csvAdapter reads csv with header row, columns are found by mapping names
+type csvAdapter struct {
+	mapping   config.FieldMapping
+	delimiter rune
+}
+
+func (ca *csvAdapter) Each(r io.Reader, fn func(Item) error) error {
+	reader := csv.NewReader(r)
+	reader.Comma = ca.delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: header: %s", ErrDecode, err.Error())
+	}
+	columns := make(map[string]int, len(header))
+	for idx, column := range header {
+		column = strings.TrimPrefix(strings.TrimSpace(column), "\uFEFF")
+		columns[column] = idx
+	}
+	if _, ok := columns[ca.mapping.Name]; !ok {
+		return fmt.Errorf("%w: %s", ErrMissingColumn, ca.mapping.Name)
+	}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: row %d: %s", ErrDecode, row, err.Error())
+		}
+		item, err := buildItem(row, ca.mapping, func(field string) []string {
+			idx, ok := columns[field]
+			if !ok || idx >= len(record) {
+				return nil
+			}
+			return []string{record[idx]}
+		})
+		if err != nil {
+			return fmt.Errorf("%w: row %d: %s", ErrDecode, row, err.Error())
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
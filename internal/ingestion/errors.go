@@ -0,0 +1,9 @@
+package ingestion
+
+import "errors"
+
+var (
+	ErrUnknownFormat = errors.New("unknown source format")
+	ErrDecode = errors.New("failed to decode source data")
+	ErrMissingColumn = errors.New("source has no column for mapped field")
+)
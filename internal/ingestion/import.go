@@ -0,0 +1,79 @@
+package ingestion
+
+import (
+	"context"
+	"io"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/validator"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=ProductImporter
+type ProductImporter interface {
+	ImportProducts(ctx context.Context, rows []models.ImportRow, atomic bool) ([]models.ImportRowResult, error)
+}
+
+// ImportStream reads products from r, validates them with the config regexes and imports valid ones by batches.
+// onBatch gets results of every batch including invalid rows, its error stops the import.
+// Atomic import is done in one batch and nothing is imported if any row is invalid.
+// batchSize <= 0 means one batch for the whole stream
+func ImportStream(ctx context.Context, adapter Adapter, r io.Reader, validCfg config.Validator, importer ProductImporter, atomic bool, batchSize int, onBatch func([]models.ImportRowResult) error) error {
+	if atomic {
+		batchSize = 0
+	}
+	var (
+		rows []models.ImportRow
+		invalid []models.ImportRowResult
+	)
+	flush := func() error {
+		var results []models.ImportRowResult
+		if atomic && len(invalid) != 0 {
+			for _, row := range rows {
+				results = append(results, models.ImportRowResult{
+					Row: row.Row,
+					Name: row.Product.Name,
+					Status: models.ImportStatusRolledBack,
+				})
+			}
+		} else if len(rows) != 0 {
+			var err error
+			results, err = importer.ImportProducts(ctx, rows, atomic)
+			if err != nil {
+				return err
+			}
+		}
+		results = append(results, invalid...)
+		rows, invalid = nil, nil
+		return onBatch(results)
+	}
+	err := adapter.Each(r, func(item Item) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if reason := validator.ProductInvalidReason(item.Product, validCfg); reason != "" {
+			invalid = append(invalid, models.ImportRowResult{
+				Row: item.Row,
+				Name: item.Product.Name,
+				Status: models.ImportStatusInvalid,
+				Reason: reason,
+			})
+		} else {
+			rows = append(rows, models.ImportRow{
+				Row: item.Row,
+				Product: item.Product,
+			})
+		}
+		if batchSize > 0 && len(rows) + len(invalid) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(rows) + len(invalid) == 0 {
+		return nil
+	}
+	return flush()
+}
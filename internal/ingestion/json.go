@@ -0,0 +1,98 @@
+package ingestion
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+)
+
+// jsonAdapter reads json array of objects or newline delimited json objects
+type jsonAdapter struct {
+	mapping config.FieldMapping
+}
+
+func (ja *jsonAdapter) Each(r io.Reader, fn func(Item) error) error {
+	br := bufio.NewReader(r)
+	isArray, err := startsWithArray(br)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+	if isArray {
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("%w: %s", ErrDecode, err.Error())
+		}
+	}
+	for row := 1; ; row++ {
+		if isArray && !dec.More() {
+			break
+		}
+		var record map[string]interface{}
+		err := dec.Decode(&record)
+		if err == io.EOF && !isArray {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: row %d: %s", ErrDecode, row, err.Error())
+		}
+		item, err := buildItem(row, ja.mapping, func(field string) []string {
+			return jsonValues(record[field])
+		})
+		if err != nil {
+			return fmt.Errorf("%w: row %d: %s", ErrDecode, row, err.Error())
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startsWithArray reports whether the first meaningful symbol of the stream is '['
+func startsWithArray(br *bufio.Reader) (bool, error) {
+	for {
+		r, _, err := br.ReadRune()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%w: %s", ErrDecode, err.Error())
+		}
+		if r == '\uFEFF' || unicode.IsSpace(r) {
+			continue
+		}
+		if err := br.UnreadRune(); err != nil {
+			return false, fmt.Errorf("%w: %s", ErrDecode, err.Error())
+		}
+		return r == '[', nil
+	}
+}
+
+func jsonValues(v interface{}) []string {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, vv := range v {
+			out = append(out, jsonValues(vv)...)
+		}
+		return out
+	case map[string]interface{}:
+		// objects like attributes are kept as json text
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		return []string{string(data)}
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
@@ -0,0 +1,60 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// ProductImporter is an autogenerated mock type for the ProductImporter type
+type ProductImporter struct {
+	mock.Mock
+}
+
+// ImportProducts provides a mock function with given fields: ctx, rows, atomic
+func (_m *ProductImporter) ImportProducts(ctx context.Context, rows []models.ImportRow, atomic bool) ([]models.ImportRowResult, error) {
+	ret := _m.Called(ctx, rows, atomic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportProducts")
+	}
+
+	var r0 []models.ImportRowResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.ImportRow, bool) ([]models.ImportRowResult, error)); ok {
+		return rf(ctx, rows, atomic)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []models.ImportRow, bool) []models.ImportRowResult); ok {
+		r0 = rf(ctx, rows, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImportRowResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []models.ImportRow, bool) error); ok {
+		r1 = rf(ctx, rows, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewProductImporter creates a new instance of ProductImporter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewProductImporter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *ProductImporter {
+	mock := &ProductImporter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
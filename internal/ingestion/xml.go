@@ -0,0 +1,89 @@
+package ingestion
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+)
+
+// xmlAdapter reads every element with item name at any depth,
+// fields are taken from its attributes and direct child elements
+type xmlAdapter struct {
+	mapping config.FieldMapping
+	item    string
+}
+
+func (xa *xmlAdapter) Each(r io.Reader, fn func(Item) error) error {
+	dec := xml.NewDecoder(r)
+	row := 0
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrDecode, err.Error())
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != xa.item {
+			continue
+		}
+		row++
+		record, err := readXMLRecord(dec, start)
+		if err != nil {
+			return fmt.Errorf("%w: row %d: %s", ErrDecode, row, err.Error())
+		}
+		item, err := buildItem(row, xa.mapping, func(field string) []string {
+			return record[field]
+		})
+		if err != nil {
+			return fmt.Errorf("%w: row %d: %s", ErrDecode, row, err.Error())
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+// readXMLRecord collects attributes and text of direct children of the start element
+func readXMLRecord(dec *xml.Decoder, start xml.StartElement) (map[string][]string, error) {
+	record := make(map[string][]string)
+	for _, attr := range start.Attr {
+		record[attr.Name.Local] = append(record[attr.Name.Local], attr.Value)
+	}
+	var (
+		depth int
+		field string
+		text  strings.Builder
+	)
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				field = t.Name.Local
+				text.Reset()
+			}
+		case xml.CharData:
+			if depth == 1 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				return record, nil
+			}
+			if depth == 1 {
+				record[field] = append(record[field], text.String())
+			}
+			depth--
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package jobs
+
+import "errors"
+
+var (
+	ErrJobNotFound = errors.New("job with this id not found")
+	ErrJobFinished = errors.New("job is already finished")
+	ErrJobCancelled = errors.New("job is cancelled")
+)
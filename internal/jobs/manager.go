@@ -0,0 +1,294 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/actor"
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/ingestion"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+const (
+	defaultWorkers      = 1
+	defaultBatchSize    = 500
+	defaultPollInterval = 5 * time.Second
+	// reportLimit is the max number of not created rows kept in the job report
+	reportLimit = 1000
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=jobRepo --exported
+type jobRepo interface {
+	SaveJob(ctx context.Context, job models.Job) (string, error)
+	GetJobById(ctx context.Context, jobId string) (models.Job, error)
+	ClaimQueuedJob(ctx context.Context) (models.Job, error)
+	UpdateJobProgress(ctx context.Context, jobId string, counters models.JobCounters) (bool, error)
+	FinishJob(ctx context.Context, job models.Job) error
+	RequestJobCancel(ctx context.Context, jobId string) (string, error)
+	RecoverRunningJobs(ctx context.Context, resume bool) (int, error)
+}
+
+type manager struct {
+	log *slog.Logger
+	cfg config.JobsConfig
+	validCfg config.Validator
+	importCfg config.IngestionConfig
+	jobRepo jobRepo
+	importer ingestion.ProductImporter
+	wakeCh chan struct{}
+	mu sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+func NewManager(logger *slog.Logger, cfg config.JobsConfig, validCfg config.Validator, ingestCfg config.IngestionConfig, jobRepo jobRepo, importer ingestion.ProductImporter) *manager {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.SpoolDir == "" {
+		cfg.SpoolDir = os.TempDir()
+	}
+	return &manager{
+		log: logger.With(slog.String("service", "jobs")),
+		cfg: cfg,
+		validCfg: validCfg,
+		importCfg: ingestCfg.ForImport(),
+		jobRepo: jobRepo,
+		importer: importer,
+		wakeCh: make(chan struct{}, 1),
+		running: make(map[string]context.CancelFunc),
+	}
+}
+
+// SubmitImport saves the import file to the spool directory and queues the job for it
+func (m *manager) SubmitImport(ctx context.Context, contentType string, atomic bool, createdBy string, body io.Reader) (models.Job, error) {
+	m.log.Info("attempt to submit import job")
+	if _, err := ingestion.FormatByContentType(contentType); err != nil {
+		m.log.Warn("unsupported content type", slog.String("content_type", contentType))
+		return models.Job{}, err
+	}
+	if err := os.MkdirAll(m.cfg.SpoolDir, 0o755); err != nil {
+		m.log.Error("failed to create spool directory", slog.String("error", err.Error()))
+		return models.Job{}, fmt.Errorf("can't submit job: %w", err)
+	}
+	file, err := os.CreateTemp(m.cfg.SpoolDir, "import-*")
+	if err != nil {
+		m.log.Error("failed to create spool file", slog.String("error", err.Error()))
+		return models.Job{}, fmt.Errorf("can't submit job: %w", err)
+	}
+	_, err = io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		m.log.Error("failed to save import file", slog.String("error", err.Error()))
+		return models.Job{}, fmt.Errorf("can't submit job: %w", err)
+	}
+	job := models.Job{
+		Kind: models.JobKindProductImport,
+		State: models.JobStateQueued,
+		ContentType: contentType,
+		PayloadPath: file.Name(),
+		Atomic: atomic,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+	}
+	job.Id, err = m.jobRepo.SaveJob(ctx, job)
+	if err != nil {
+		os.Remove(file.Name())
+		m.log.Error("failed to save job", slog.String("error", err.Error()))
+		return models.Job{}, fmt.Errorf("can't submit job: %w", err)
+	}
+	m.log.Info("job queued", slog.String("job_id", job.Id))
+	m.wake()
+	return job, nil
+}
+
+func (m *manager) GetJob(ctx context.Context, jobId string) (models.Job, error) {
+	m.log.Info("attempt to get job")
+	m.log.Debug("got job id", slog.String("job_id", jobId))
+	job, err := m.jobRepo.GetJobById(ctx, jobId)
+	if err != nil {
+		if errors.Is(err, storage.ErrJobNotFound) {
+			m.log.Warn("job not found", slog.String("job_id", jobId))
+			return models.Job{}, ErrJobNotFound
+		}
+		m.log.Error("failed to get job", slog.String("job_id", jobId), slog.String("error", err.Error()))
+		return models.Job{}, err
+	}
+	return job, nil
+}
+
+// CancelJob cancels queued job at once, running job is interrupted and its current batch is rolled back
+func (m *manager) CancelJob(ctx context.Context, jobId string) (models.Job, error) {
+	m.log.Info("attempt to cancel job")
+	m.log.Debug("got job id", slog.String("job_id", jobId))
+	state, err := m.jobRepo.RequestJobCancel(ctx, jobId)
+	if err != nil {
+		if errors.Is(err, storage.ErrJobNotFound) {
+			m.log.Warn("job not found", slog.String("job_id", jobId))
+			return models.Job{}, ErrJobNotFound
+		}
+		m.log.Error("failed to cancel job", slog.String("job_id", jobId), slog.String("error", err.Error()))
+		return models.Job{}, err
+	}
+	if state != models.JobStateRunning && state != models.JobStateCancelled {
+		m.log.Warn("job is already finished", slog.String("job_id", jobId), slog.String("state", state))
+		return models.Job{}, ErrJobFinished
+	}
+	m.mu.Lock()
+	if cancel, ok := m.running[jobId]; ok {
+		cancel()
+	}
+	m.mu.Unlock()
+	job, err := m.GetJob(ctx, jobId)
+	if err != nil {
+		return models.Job{}, err
+	}
+	// file of the queued job is not needed anymore, running job removes its file by itself
+	if state == models.JobStateCancelled && job.PayloadPath != "" {
+		os.Remove(job.PayloadPath)
+	}
+	return job, nil
+}
+
+// Run recovers jobs interrupted by the previous stop and starts workers.
+//
+// The returned channel is closed when all workers have stopped after ctx cancellation.
+func (m *manager) Run(ctx context.Context) (doneCh chan struct{}) {
+	doneCh = make(chan struct{})
+	recovered, err := m.jobRepo.RecoverRunningJobs(ctx, m.cfg.ResumeInterrupted)
+	if err != nil {
+		m.log.Error("failed to recover interrupted jobs", slog.String("error", err.Error()))
+	} else if recovered != 0 {
+		m.log.Info("interrupted jobs recovered", slog.Int("count", recovered), slog.Bool("resumed", m.cfg.ResumeInterrupted))
+	}
+	m.log.Info("starting job workers", slog.Int("workers", m.cfg.Workers))
+	var wg sync.WaitGroup
+	for i := 0; i < m.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.work(ctx)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		m.log.Info("job workers stopped")
+		close(doneCh)
+	}()
+	return
+}
+
+func (m *manager) wake() {
+	select {
+	case m.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+func (m *manager) work(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		job, err := m.jobRepo.ClaimQueuedJob(ctx)
+		if err == nil {
+			m.process(ctx, job)
+			// there may be more queued jobs
+			continue
+		}
+		if !errors.Is(err, storage.ErrJobNotFound) && ctx.Err() == nil {
+			m.log.Error("failed to claim job", slog.String("error", err.Error()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.wakeCh:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *manager) process(ctx context.Context, job models.Job) {
+	log := m.log.With(slog.String("job_id", job.Id))
+	log.Info("job started")
+	jobCtx, cancel := context.WithCancel(ctx)
+	// products of the job are recorded in their history as changed by the user who submitted it
+	jobCtx = actor.With(jobCtx, job.CreatedBy)
+	m.mu.Lock()
+	m.running[job.Id] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, job.Id)
+		m.mu.Unlock()
+		cancel()
+	}()
+
+	job.Counters = models.JobCounters{}
+	job.Report = nil
+	err := m.runImport(jobCtx, &job)
+	switch {
+	case err == nil:
+		job.State = models.JobStateDone
+	case ctx.Err() != nil:
+		// the process is stopping, the job stays running and is recovered on the next start
+		log.Warn("job interrupted by stop")
+		return
+	case errors.Is(err, ErrJobCancelled) || errors.Is(err, context.Canceled):
+		job.State = models.JobStateCancelled
+	default:
+		job.State = models.JobStateFailed
+		job.Error = err.Error()
+	}
+	if err := m.jobRepo.FinishJob(ctx, job); err != nil {
+		log.Error("failed to save job result", slog.String("error", err.Error()))
+		return
+	}
+	if err := os.Remove(job.PayloadPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn("failed to remove job file", slog.String("error", err.Error()))
+	}
+	log.Info("job finished", slog.String("state", job.State), slog.Any("counters", job.Counters))
+}
+
+func (m *manager) runImport(ctx context.Context, job *models.Job) error {
+	adapter, err := ingestion.AdapterByContentType(job.ContentType, m.importCfg)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(job.PayloadPath)
+	if err != nil {
+		return fmt.Errorf("can't open job file: %w", err)
+	}
+	defer file.Close()
+	return ingestion.ImportStream(ctx, adapter, file, m.validCfg, m.importer, job.Atomic, m.cfg.BatchSize, func(results []models.ImportRowResult) error {
+		job.Counters.Add(results)
+		for _, res := range results {
+			if res.Status != models.ImportStatusCreated && len(job.Report) < reportLimit {
+				job.Report = append(job.Report, res)
+			}
+		}
+		cancelRequested, err := m.jobRepo.UpdateJobProgress(ctx, job.Id, job.Counters)
+		if err != nil {
+			return err
+		}
+		if cancelRequested {
+			return ErrJobCancelled
+		}
+		return nil
+	})
+}
@@ -0,0 +1,188 @@
+package jobs_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/ingestion"
+	imocks "github.com/EwvwGeN/cataloger/internal/ingestion/mocks"
+	"github.com/EwvwGeN/cataloger/internal/jobs"
+	"github.com/EwvwGeN/cataloger/internal/jobs/mocks"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type jobsTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+	cfg config.JobsConfig
+	validCfg config.Validator
+}
+
+func TestJobsSuiteRun(t *testing.T) {
+	suite.Run(t, new(jobsTestSuite))
+}
+
+func (suite *jobsTestSuite) SetupSuite() {
+	suite.log = slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	suite.validCfg = config.Validator{
+		ProductNameValidate: "^[a-zA-Z0-9 ]{1,150}$",
+		ProductDescValidate: "^.+$",
+	}
+}
+
+func (suite *jobsTestSuite) SetupTest() {
+	suite.cfg = config.JobsConfig{
+		SpoolDir: suite.T().TempDir(),
+		BatchSize: 2,
+		PollInterval: time.Hour,
+	}
+}
+
+func (suite *jobsTestSuite) Test_SubmitImport() {
+	repoMock := mocks.NewJobRepo(suite.T())
+	repoMock.On("SaveJob", mock.Anything, mock.MatchedBy(func(job models.Job) bool {
+		data, err := os.ReadFile(job.PayloadPath)
+		return err == nil &&
+			string(data) == "name\nShirt\n" &&
+			job.State == models.JobStateQueued &&
+			job.Kind == models.JobKindProductImport &&
+			job.ContentType == "text/csv" &&
+			job.CreatedBy == "user@email.com"
+	})).Once().Return("7", nil)
+	m := jobs.NewManager(suite.log, suite.cfg, suite.validCfg, config.IngestionConfig{}, repoMock, imocks.NewProductImporter(suite.T()))
+
+	job, err := m.SubmitImport(context.Background(), "text/csv", true, "user@email.com", strings.NewReader("name\nShirt\n"))
+	suite.Require().NoError(err)
+	suite.Equal("7", job.Id)
+	suite.True(job.Atomic)
+
+	_, err = m.SubmitImport(context.Background(), "text/plain", false, "", strings.NewReader("Shirt"))
+	suite.Require().ErrorIs(err, ingestion.ErrUnknownFormat)
+}
+
+func (suite *jobsTestSuite) Test_GetAndCancel() {
+	repoMock := mocks.NewJobRepo(suite.T())
+	repoMock.On("GetJobById", mock.Anything, "1").Return(models.Job{Id: "1", State: models.JobStateCancelled}, nil)
+	repoMock.On("GetJobById", mock.Anything, "2").Return(models.Job{}, storage.ErrJobNotFound)
+	repoMock.On("RequestJobCancel", mock.Anything, "1").Once().Return(models.JobStateCancelled, nil)
+	repoMock.On("RequestJobCancel", mock.Anything, "2").Once().Return("", storage.ErrJobNotFound)
+	repoMock.On("RequestJobCancel", mock.Anything, "3").Once().Return(models.JobStateDone, nil)
+	m := jobs.NewManager(suite.log, suite.cfg, suite.validCfg, config.IngestionConfig{}, repoMock, imocks.NewProductImporter(suite.T()))
+
+	_, err := m.GetJob(context.Background(), "2")
+	suite.Require().ErrorIs(err, jobs.ErrJobNotFound)
+
+	job, err := m.CancelJob(context.Background(), "1")
+	suite.Require().NoError(err)
+	suite.Equal(models.JobStateCancelled, job.State)
+	_, err = m.CancelJob(context.Background(), "2")
+	suite.Require().ErrorIs(err, jobs.ErrJobNotFound)
+	_, err = m.CancelJob(context.Background(), "3")
+	suite.Require().ErrorIs(err, jobs.ErrJobFinished)
+}
+
+func (suite *jobsTestSuite) Test_Run() {
+	payload, err := os.CreateTemp(suite.cfg.SpoolDir, "import-*")
+	suite.Require().NoError(err)
+	payload.WriteString("name,description\nShirt,Cotton\nHat,Wool\n---,Bad\n")
+	payload.Close()
+	queued := models.Job{
+		Id: "5",
+		Kind: models.JobKindProductImport,
+		State: models.JobStateRunning,
+		ContentType: "text/csv",
+		PayloadPath: payload.Name(),
+	}
+
+	finished := make(chan models.Job, 1)
+	repoMock := mocks.NewJobRepo(suite.T())
+	repoMock.On("RecoverRunningJobs", mock.Anything, false).Once().Return(0, nil)
+	repoMock.On("ClaimQueuedJob", mock.Anything).Once().Return(queued, nil)
+	repoMock.On("ClaimQueuedJob", mock.Anything).Return(models.Job{}, storage.ErrJobNotFound)
+	repoMock.On("UpdateJobProgress", mock.Anything, "5", models.JobCounters{Processed: 2, Created: 1, Skipped: 1}).Once().Return(false, nil)
+	repoMock.On("UpdateJobProgress", mock.Anything, "5", models.JobCounters{Processed: 3, Created: 1, Skipped: 1, Invalid: 1}).Once().Return(false, nil)
+	repoMock.On("FinishJob", mock.Anything, mock.Anything).Once().Run(func(args mock.Arguments) {
+		finished <- args.Get(1).(models.Job)
+	}).Return(nil)
+	importerMock := imocks.NewProductImporter(suite.T())
+	importerMock.On("ImportProducts", mock.Anything, []models.ImportRow{
+		{Row: 1, Product: models.Product{Name: "Shirt", Description: "Cotton"}},
+		{Row: 2, Product: models.Product{Name: "Hat", Description: "Wool"}},
+	}, false).Once().Return([]models.ImportRowResult{
+		{Row: 1, Name: "Shirt", Status: models.ImportStatusCreated, ProductId: "10"},
+		{Row: 2, Name: "Hat", Status: models.ImportStatusSkippedDuplicate},
+	}, nil)
+
+	m := jobs.NewManager(suite.log, suite.cfg, suite.validCfg, config.IngestionConfig{}, repoMock, importerMock)
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := m.Run(ctx)
+	var job models.Job
+	select {
+	case job = <-finished:
+	case <-time.After(5 * time.Second):
+		suite.FailNow("job is not finished")
+	}
+	cancel()
+	select {
+	case <-doneCh:
+	case <-time.After(5 * time.Second):
+		suite.FailNow("workers did not stop")
+	}
+
+	suite.Equal(models.JobStateDone, job.State)
+	suite.Equal(models.JobCounters{Processed: 3, Created: 1, Skipped: 1, Invalid: 1}, job.Counters)
+	suite.Len(job.Report, 2)
+	_, err = os.Stat(payload.Name())
+	suite.ErrorIs(err, os.ErrNotExist)
+}
+
+func (suite *jobsTestSuite) Test_RunCancelRequested() {
+	payload, err := os.CreateTemp(suite.cfg.SpoolDir, "import-*")
+	suite.Require().NoError(err)
+	payload.WriteString("name,description\nShirt,Cotton\nHat,Wool\nCap,Wool\n")
+	payload.Close()
+
+	finished := make(chan models.Job, 1)
+	repoMock := mocks.NewJobRepo(suite.T())
+	repoMock.On("RecoverRunningJobs", mock.Anything, true).Once().Return(1, nil)
+	repoMock.On("ClaimQueuedJob", mock.Anything).Once().Return(models.Job{
+		Id: "6",
+		State: models.JobStateRunning,
+		ContentType: "text/csv",
+		PayloadPath: payload.Name(),
+	}, nil)
+	repoMock.On("ClaimQueuedJob", mock.Anything).Return(models.Job{}, storage.ErrJobNotFound)
+	repoMock.On("UpdateJobProgress", mock.Anything, "6", mock.Anything).Once().Return(true, nil)
+	repoMock.On("FinishJob", mock.Anything, mock.Anything).Once().Run(func(args mock.Arguments) {
+		finished <- args.Get(1).(models.Job)
+	}).Return(nil)
+	importerMock := imocks.NewProductImporter(suite.T())
+	importerMock.On("ImportProducts", mock.Anything, mock.Anything, false).Once().Return([]models.ImportRowResult{
+		{Row: 1, Name: "Shirt", Status: models.ImportStatusCreated, ProductId: "10"},
+		{Row: 2, Name: "Hat", Status: models.ImportStatusCreated, ProductId: "11"},
+	}, nil)
+
+	suite.cfg.ResumeInterrupted = true
+	m := jobs.NewManager(suite.log, suite.cfg, suite.validCfg, config.IngestionConfig{}, repoMock, importerMock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Run(ctx)
+	select {
+	case job := <-finished:
+		suite.Equal(models.JobStateCancelled, job.State)
+		suite.Equal(2, job.Counters.Created)
+	case <-time.After(5 * time.Second):
+		suite.FailNow("job is not finished")
+	}
+}
@@ -0,0 +1,216 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// JobRepo is an autogenerated mock type for the jobRepo type
+type JobRepo struct {
+	mock.Mock
+}
+
+// ClaimQueuedJob provides a mock function with given fields: ctx
+func (_m *JobRepo) ClaimQueuedJob(ctx context.Context) (models.Job, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimQueuedJob")
+	}
+
+	var r0 models.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (models.Job, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) models.Job); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(models.Job)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FinishJob provides a mock function with given fields: ctx, job
+func (_m *JobRepo) FinishJob(ctx context.Context, job models.Job) error {
+	ret := _m.Called(ctx, job)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FinishJob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Job) error); ok {
+		r0 = rf(ctx, job)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetJobById provides a mock function with given fields: ctx, jobId
+func (_m *JobRepo) GetJobById(ctx context.Context, jobId string) (models.Job, error) {
+	ret := _m.Called(ctx, jobId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetJobById")
+	}
+
+	var r0 models.Job
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Job, error)); ok {
+		return rf(ctx, jobId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Job); ok {
+		r0 = rf(ctx, jobId)
+	} else {
+		r0 = ret.Get(0).(models.Job)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, jobId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecoverRunningJobs provides a mock function with given fields: ctx, resume
+func (_m *JobRepo) RecoverRunningJobs(ctx context.Context, resume bool) (int, error) {
+	ret := _m.Called(ctx, resume)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecoverRunningJobs")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, bool) (int, error)); ok {
+		return rf(ctx, resume)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, bool) int); ok {
+		r0 = rf(ctx, resume)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, bool) error); ok {
+		r1 = rf(ctx, resume)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RequestJobCancel provides a mock function with given fields: ctx, jobId
+func (_m *JobRepo) RequestJobCancel(ctx context.Context, jobId string) (string, error) {
+	ret := _m.Called(ctx, jobId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RequestJobCancel")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, jobId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, jobId)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, jobId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveJob provides a mock function with given fields: ctx, job
+func (_m *JobRepo) SaveJob(ctx context.Context, job models.Job) (string, error) {
+	ret := _m.Called(ctx, job)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveJob")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Job) (string, error)); ok {
+		return rf(ctx, job)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Job) string); ok {
+		r0 = rf(ctx, job)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Job) error); ok {
+		r1 = rf(ctx, job)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateJobProgress provides a mock function with given fields: ctx, jobId, counters
+func (_m *JobRepo) UpdateJobProgress(ctx context.Context, jobId string, counters models.JobCounters) (bool, error) {
+	ret := _m.Called(ctx, jobId, counters)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateJobProgress")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.JobCounters) (bool, error)); ok {
+		return rf(ctx, jobId, counters)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.JobCounters) bool); ok {
+		r0 = rf(ctx, jobId, counters)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.JobCounters) error); ok {
+		r1 = rf(ctx, jobId, counters)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewJobRepo creates a new instance of JobRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewJobRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *JobRepo {
+	mock := &JobRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,12 @@
+package media
+
+import "errors"
+
+var (
+	ErrUnknownStore    = errors.New("unknown media store")
+	ErrInvalidKey      = errors.New("invalid media key")
+	ErrNotFound        = errors.New("media file not found")
+	ErrUnsupportedType = errors.New("unsupported media type, only jpeg, png and gif images are allowed")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+	ErrBadImage        = errors.New("image can't be decoded")
+)
@@ -0,0 +1,136 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// maxPixels keeps decoded images within about 100 MB of memory
+	maxPixels = 25000000
+	thumbnailQuality = 85
+)
+
+// extensions are extensions of files by supported content types
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png": ".png",
+	"image/gif": ".gif",
+}
+
+// Image is a decoded upload, its content type is detected by the content and not by the name of the file
+type Image struct {
+	ContentType string
+	Width int
+	Height int
+	image image.Image
+}
+
+// Decode checks that data is a jpeg, png or gif image and decodes it.
+// Dimensions are read first, so too large images are never decoded
+func Decode(data []byte) (Image, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := extensions[contentType]; !ok {
+		return Image{}, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("%w: %s", ErrBadImage, err.Error())
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return Image{}, ErrBadImage
+	}
+	if cfg.Width * cfg.Height > maxPixels {
+		return Image{}, fmt.Errorf("%w: %dx%d", ErrTooManyPixels, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("%w: %s", ErrBadImage, err.Error())
+	}
+	return Image{
+		ContentType: contentType,
+		Width: cfg.Width,
+		Height: cfg.Height,
+		image: img,
+	}, nil
+}
+
+// Ext returns the extension of the file of the image
+func (img Image) Ext() string {
+	return extensions[img.ContentType]
+}
+
+// ThumbnailExt returns the extension of the thumbnail, jpeg images get jpeg thumbnails and the others png ones
+// to keep their transparency
+func (img Image) ThumbnailExt() string {
+	if img.ContentType == "image/jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// Thumbnail returns the encoded image scaled down to fit size pixels by its longest side,
+// smaller images keep their dimensions
+func (img Image) Thumbnail(size int) ([]byte, error) {
+	width, height := img.Width, img.Height
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height * size / width)
+		} else {
+			width, height = max(1, width * size / height), size
+		}
+	}
+	thumbnail := resize(img.image, width, height)
+	var out bytes.Buffer
+	var err error
+	if img.ThumbnailExt() == ".jpg" {
+		err = jpeg.Encode(&out, thumbnail, &jpeg.Options{Quality: thumbnailQuality})
+	} else {
+		err = png.Encode(&out, thumbnail)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// resize scales src to width and height by averaging the source pixels that fall into every pixel
+// of the result, so downscaled images don't get aliasing
+func resize(src image.Image, width, height int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y * srcHeight / height
+		y1 := max(y0 + 1, bounds.Min.Y + (y + 1) * srcHeight / height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x * srcWidth / width
+			x1 := max(x0 + 1, bounds.Min.X + (x + 1) * srcWidth / width)
+			// colors are summed premultiplied by alpha, so transparent pixels don't tint the result
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r + uint64(cr), g + uint64(cg), b + uint64(cb), a + uint64(ca)
+					count++
+				}
+			}
+			if a == 0 {
+				continue
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r * 0xff / a),
+				G: uint8(g * 0xff / a),
+				B: uint8(b * 0xff / a),
+				A: uint8(a / count >> 8),
+			})
+		}
+	}
+	return dst
+}
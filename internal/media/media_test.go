@@ -0,0 +1,127 @@
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/media"
+	"github.com/stretchr/testify/suite"
+)
+
+type mediaTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func TestMediaSuiteRun(t *testing.T) {
+	suite.Run(t, new(mediaTestSuite))
+}
+
+func (suite *mediaTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+}
+
+// encodedPng returns a png of the size, its left half is red and the right half is transparent
+func (suite *mediaTestSuite) encodedPng(width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width / 2; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var out bytes.Buffer
+	suite.Require().NoError(png.Encode(&out, img))
+	return out.Bytes()
+}
+
+func (suite *mediaTestSuite) Test_Decode() {
+	img, err := media.Decode(suite.encodedPng(40, 20))
+	suite.Require().NoError(err)
+	suite.Require().Equal("image/png", img.ContentType)
+	suite.Require().Equal(".png", img.Ext())
+	suite.Require().Equal(40, img.Width)
+	suite.Require().Equal(20, img.Height)
+
+	_, err = media.Decode([]byte("<html><body>not an image</body></html>"))
+	suite.Require().ErrorIs(err, media.ErrUnsupportedType)
+	_, err = media.Decode(suite.encodedPng(4, 4)[:40])
+	suite.Require().ErrorIs(err, media.ErrBadImage)
+}
+
+func (suite *mediaTestSuite) Test_Thumbnail() {
+	tests := []struct{
+		name string
+		width, height int
+		size int
+		wantWidth, wantHeight int
+	}{
+		{name: "landscape", width: 400, height: 100, size: 64, wantWidth: 64, wantHeight: 16},
+		{name: "portrait", width: 100, height: 400, size: 64, wantWidth: 16, wantHeight: 64},
+		{name: "thin", width: 1000, height: 2, size: 10, wantWidth: 10, wantHeight: 1},
+		{name: "small_is_kept", width: 30, height: 20, size: 64, wantWidth: 30, wantHeight: 20},
+	}
+	for _, tt := range tests {
+		img, err := media.Decode(suite.encodedPng(tt.width, tt.height))
+		suite.Require().NoError(err, "test: %s", tt.name)
+		data, err := img.Thumbnail(tt.size)
+		suite.Require().NoError(err, "test: %s", tt.name)
+		thumbnail, err := png.Decode(bytes.NewReader(data))
+		suite.Require().NoError(err, "test: %s", tt.name)
+		suite.Require().Equal(image.Rect(0, 0, tt.wantWidth, tt.wantHeight), thumbnail.Bounds(), "test: %s", tt.name)
+	}
+
+	// colors are averaged without transparent pixels, the middle column mixes both halves
+	img, err := media.Decode(suite.encodedPng(300, 3))
+	suite.Require().NoError(err)
+	data, err := img.Thumbnail(3)
+	suite.Require().NoError(err)
+	thumbnail, err := png.Decode(bytes.NewReader(data))
+	suite.Require().NoError(err)
+	suite.Require().Equal(color.NRGBA{R: 255, A: 255}, color.NRGBAModel.Convert(thumbnail.At(0, 0)))
+	suite.Require().Equal(color.NRGBA{R: 255, A: 127}, color.NRGBAModel.Convert(thumbnail.At(1, 0)))
+	suite.Require().Equal(uint8(0), color.NRGBAModel.Convert(thumbnail.At(2, 0)).(color.NRGBA).A)
+}
+
+func (suite *mediaTestSuite) Test_LocalStore() {
+	dir := suite.T().TempDir()
+	store, err := media.NewStore(config.MediaConfig{Dir: dir, BaseURL: "https://cdn.example.com/"})
+	suite.Require().NoError(err)
+	key, thumbnailKey, err := media.NewKeys("12", ".jpg", ".png")
+	suite.Require().NoError(err)
+	suite.Require().Regexp(`^products/12/[0-9a-f]{32}\.jpg$`, key)
+	suite.Require().Equal(key[:len(key)-4] + "_thumb.png", thumbnailKey)
+	suite.Require().Equal("https://cdn.example.com/media/" + key, store.URL(key))
+
+	suite.Require().NoError(store.Put(suite.ctx, key, bytes.NewBufferString("image")))
+	file, err := store.Open(suite.ctx, key)
+	suite.Require().NoError(err)
+	data, err := io.ReadAll(file)
+	suite.Require().NoError(err)
+	suite.Require().NoError(file.Close())
+	suite.Require().Equal("image", string(data))
+	entries, err := os.ReadDir(filepath.Dir(filepath.Join(dir, key)))
+	suite.Require().NoError(err)
+	suite.Require().Len(entries, 1, "temporary files are renamed")
+
+	suite.Require().NoError(store.Delete(suite.ctx, key))
+	suite.Require().NoError(store.Delete(suite.ctx, key), "deleting a missing file is not an error")
+	_, err = store.Open(suite.ctx, key)
+	suite.Require().ErrorIs(err, media.ErrNotFound)
+
+	for _, badKey := range []string{"../secret.txt", "products/../../etc/passwd", "/etc/passwd", "products/1/a.b.jpg", "products/1/"} {
+		suite.Require().ErrorIs(store.Put(suite.ctx, badKey, bytes.NewBufferString("x")), media.ErrInvalidKey, "key: %s", badKey)
+		_, err := store.Open(suite.ctx, badKey)
+		suite.Require().ErrorIs(err, media.ErrInvalidKey, "key: %s", badKey)
+	}
+
+	_, err = media.NewStore(config.MediaConfig{Store: "s3"})
+	suite.Require().ErrorIs(err, media.ErrUnknownStore)
+}
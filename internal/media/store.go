@@ -0,0 +1,138 @@
+package media
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+)
+
+const defaultDir = "media"
+
+// keyRegex allows slash separated lowercase names with one extension, so a key never leaves the store
+var keyRegex = regexp.MustCompile(`^[a-z0-9_-]+(?:/[a-z0-9_-]+)*\.[a-z0-9]+$`)
+
+// Store keeps files of product media by keys
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns ErrNotFound if there is no file with the key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete does nothing if there is no file with the key
+	Delete(ctx context.Context, key string) error
+	// URL returns the address the file is served from
+	URL(key string) string
+}
+
+// NewStore returns the store by its name from the config
+func NewStore(cfg config.MediaConfig) (Store, error) {
+	switch cfg.Store {
+	case config.MediaStoreLocal, "":
+		return NewLocalStore(cfg.Dir, cfg.BaseURL)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStore, cfg.Store)
+	}
+}
+
+// NewKeys returns new keys of the file of the product and its thumbnail with the extensions
+func NewKeys(prodId, ext, thumbnailExt string) (string, string, error) {
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", "", err
+	}
+	prefix := "products/" + prodId + "/" + hex.EncodeToString(name)
+	return prefix + ext, prefix + "_thumb" + thumbnailExt, nil
+}
+
+// ValidKey reports whether the key may be kept by a store
+func ValidKey(key string) bool {
+	return keyRegex.MatchString(key)
+}
+
+// localStore keeps files in the directory, keys are their paths inside it
+type localStore struct {
+	dir string
+	baseURL string
+}
+
+func NewLocalStore(dir, baseURL string) (*localStore, error) {
+	if dir == "" {
+		dir = defaultDir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create media dir: %w", err)
+	}
+	return &localStore{
+		dir: dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+// Put writes the file next to its place and renames it, so a file is never read half written
+func (ls *localStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (ls *localStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := ls.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (ls *localStore) Delete(ctx context.Context, key string) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (ls *localStore) URL(key string) string {
+	return ls.baseURL + "/media/" + key
+}
+
+func (ls *localStore) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+	return filepath.Join(ls.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,9 @@
+package outbox
+
+import "errors"
+
+var (
+	ErrUnknownSink = errors.New("unknown sink")
+	ErrEmptySink   = errors.New("sink is not configured")
+	ErrBadStatus   = errors.New("webhook responded with not ok status")
+)
@@ -0,0 +1,49 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/EwvwGeN/cataloger/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// EventStore is an autogenerated mock type for the eventStore type
+type EventStore struct {
+	mock.Mock
+}
+
+// RelayEvents provides a mock function with given fields: ctx, limit, claimTimeout, publish
+func (_m *EventStore) RelayEvents(ctx context.Context, limit int, claimTimeout time.Duration, publish func([]models.Event) models.RelayResult) error {
+	ret := _m.Called(ctx, limit, claimTimeout, publish)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RelayEvents")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Duration, func([]models.Event) models.RelayResult) error); ok {
+		r0 = rf(ctx, limit, claimTimeout, publish)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewEventStore creates a new instance of EventStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewEventStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *EventStore {
+	mock := &EventStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,157 @@
+package outbox
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+const (
+	defaultRelayPeriod  = time.Second
+	defaultBatchSize    = 100
+	defaultMaxAttempts  = 10
+	defaultClaimTimeout = 5 * time.Minute
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=eventStore --exported
+type eventStore interface {
+	RelayEvents(ctx context.Context, limit int, claimTimeout time.Duration, publish func([]models.Event) models.RelayResult) error
+}
+
+type relay struct {
+	log *slog.Logger
+	period time.Duration
+	batchSize int
+	maxAttempts int
+	claimTimeout time.Duration
+	store eventStore
+	sinks []Sink
+}
+
+func NewRelay(logger *slog.Logger, cfg config.OutboxConfig, store eventStore, sinks []Sink) *relay {
+	period := cfg.RelayPeriod
+	if period <= 0 {
+		period = defaultRelayPeriod
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	claimTimeout := cfg.ClaimTimeout
+	if claimTimeout <= 0 {
+		claimTimeout = defaultClaimTimeout
+	}
+	return &relay{
+		log: logger.With(slog.String("service", "outbox")),
+		period: period,
+		batchSize: batchSize,
+		maxAttempts: maxAttempts,
+		claimTimeout: claimTimeout,
+		store: store,
+		sinks: sinks,
+	}
+}
+
+// RelayOnce publishes the oldest batch of events and returns how many of them are published.
+// Events are published in the order they were written. If an event fails, later events
+// of its aggregate are held back until the next call, so the aggregate keeps its order.
+// The event failed maxAttempts times is dead and holds nothing back
+func (r *relay) RelayOnce(ctx context.Context) (int, error) {
+	var published int
+	err := r.store.RelayEvents(ctx, r.batchSize, r.claimTimeout, func(events []models.Event) models.RelayResult {
+		var result models.RelayResult
+		failed := make(map[string]struct{})
+		for _, event := range events {
+			aggregate := event.Aggregate + "/" + event.AggregateId
+			if _, ok := failed[aggregate]; ok {
+				continue
+			}
+			if err := r.publish(ctx, event); err != nil {
+				if event.Attempts+1 >= r.maxAttempts {
+					r.log.Error("event is dead after failed attempts",
+						slog.Int("event_id", event.Id),
+						slog.String("aggregate", aggregate),
+						slog.Int("attempts", event.Attempts+1),
+						slog.String("error", err.Error()))
+					result.Dead = append(result.Dead, event.Id)
+					continue
+				}
+				r.log.Warn("failed to publish event",
+					slog.Int("event_id", event.Id),
+					slog.String("aggregate", aggregate),
+					slog.String("error", err.Error()))
+				failed[aggregate] = struct{}{}
+				result.Failed = append(result.Failed, event.Id)
+				continue
+			}
+			result.Published = append(result.Published, event.Id)
+		}
+		published = len(result.Published)
+		return result
+	})
+	if err != nil {
+		r.log.Error("failed to relay events", slog.String("error", err.Error()))
+		return 0, err
+	}
+	return published, nil
+}
+
+// publish passes the event to every sink, the event is published only if all of them succeed
+func (r *relay) publish(ctx context.Context, event models.Event) error {
+	for _, sink := range r.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run relays events every period, full batches are followed by the next one right away.
+// The returned channel is closed when the relay has stopped after ctx cancellation,
+// sinks holding files are closed then
+func (r *relay) Run(ctx context.Context) (doneCh chan struct{}) {
+	doneCh = make(chan struct{})
+	if len(r.sinks) == 0 {
+		r.log.Warn("outbox relay is disabled: no sinks, events are kept")
+		close(doneCh)
+		return
+	}
+	r.log.Info("starting outbox relay", slog.Duration("period", r.period), slog.Int("batch_size", r.batchSize))
+	go func() {
+		defer close(doneCh)
+		defer closeSinks(r.sinks)
+		ticker := time.NewTicker(r.period)
+		defer ticker.Stop()
+		for {
+			for ctx.Err() == nil {
+				published, err := r.RelayOnce(ctx)
+				if err != nil || published < r.batchSize {
+					break
+				}
+			}
+			select {
+			case <-ctx.Done():
+				r.log.Info("outbox relay stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return
+}
+
+func closeSinks(sinks []Sink) {
+	for _, sink := range sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package outbox_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/outbox"
+	"github.com/EwvwGeN/cataloger/internal/outbox/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type relayTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+	ctx context.Context
+}
+
+func TestRelaySuiteRun(t *testing.T) {
+	suite.Run(t, new(relayTestSuite))
+}
+
+func (suite *relayTestSuite) SetupSuite() {
+	suite.log = slog.New(
+		slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	suite.ctx = context.Background()
+}
+
+// recordSink keeps published event ids and fails events of aggregates in fail, keyed as "product/1"
+type recordSink struct {
+	fail map[string]bool
+	published []int
+}
+
+func (rs *recordSink) Publish(ctx context.Context, event models.Event) error {
+	if rs.fail[event.Aggregate+"/"+event.AggregateId] {
+		return errors.New("sink is down")
+	}
+	rs.published = append(rs.published, event.Id)
+	return nil
+}
+
+// relayed makes the store mock pass events to publish and keep the result it returns
+func relayed(store *mocks.EventStore, events []models.Event, result *models.RelayResult) {
+	store.On("RelayEvents", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, limit int, claimTimeout time.Duration, publish func([]models.Event) models.RelayResult) error {
+			*result = publish(events)
+			return nil
+		}).Once()
+}
+
+func (suite *relayTestSuite) Test_RelayOnce() {
+	events := []models.Event{
+		{Id: 1, Aggregate: models.EventAggregateProduct, AggregateId: "1", Type: models.EventProductCreated},
+		{Id: 2, Aggregate: models.EventAggregateProduct, AggregateId: "2", Type: models.EventProductCreated},
+		{Id: 3, Aggregate: models.EventAggregateProduct, AggregateId: "1", Type: models.EventProductUpdated},
+		{Id: 4, Aggregate: models.EventAggregateCategory, AggregateId: "1", Type: models.EventCategoryCreated},
+	}
+	store := mocks.NewEventStore(suite.T())
+	var result models.RelayResult
+	relayed(store, events, &result)
+	sink := &recordSink{}
+	relay := outbox.NewRelay(suite.log, config.OutboxConfig{BatchSize: 10}, store, []outbox.Sink{sink, &recordSink{}})
+	published, err := relay.RelayOnce(suite.ctx)
+	suite.Require().NoError(err)
+	suite.Require().Equal(4, published)
+	suite.Require().Equal(models.RelayResult{Published: []int{1, 2, 3, 4}}, result)
+	suite.Require().Equal([]int{1, 2, 3, 4}, sink.published)
+}
+
+func (suite *relayTestSuite) Test_RelayOnceKeepsOrderOfAggregate() {
+	events := []models.Event{
+		{Id: 1, Aggregate: models.EventAggregateProduct, AggregateId: "1", Type: models.EventProductCreated},
+		{Id: 2, Aggregate: models.EventAggregateProduct, AggregateId: "2", Type: models.EventProductCreated},
+		{Id: 3, Aggregate: models.EventAggregateProduct, AggregateId: "2", Type: models.EventProductUpdated},
+		{Id: 4, Aggregate: models.EventAggregateCategory, AggregateId: "2", Type: models.EventCategoryCreated},
+	}
+	store := mocks.NewEventStore(suite.T())
+	var result models.RelayResult
+	relayed(store, events, &result)
+	// the first sink has published product 2, but the second one fails it
+	first := &recordSink{}
+	second := &recordSink{fail: map[string]bool{"product/2": true}}
+	relay := outbox.NewRelay(suite.log, config.OutboxConfig{}, store, []outbox.Sink{first, second})
+	published, err := relay.RelayOnce(suite.ctx)
+	suite.Require().NoError(err)
+	// category 2 is another aggregate, only events of product 2 are held back
+	suite.Require().Equal(2, published)
+	suite.Require().Equal(models.RelayResult{Published: []int{1, 4}, Failed: []int{2}}, result)
+	suite.Require().Equal([]int{1, 2, 4}, first.published)
+
+	second.fail = nil
+	relayed(store, events[1:3], &result)
+	published, err = relay.RelayOnce(suite.ctx)
+	suite.Require().NoError(err)
+	suite.Require().Equal(2, published)
+	suite.Require().Equal(models.RelayResult{Published: []int{2, 3}}, result)
+	suite.Require().Equal([]int{1, 2, 4, 2, 3}, first.published)
+}
+
+func (suite *relayTestSuite) Test_RelayOnceDeadEvent() {
+	events := []models.Event{
+		{Id: 1, Aggregate: models.EventAggregateProduct, AggregateId: "1", Type: models.EventProductCreated, Attempts: 1},
+		{Id: 2, Aggregate: models.EventAggregateProduct, AggregateId: "1", Type: models.EventProductUpdated},
+		{Id: 3, Aggregate: models.EventAggregateProduct, AggregateId: "2", Type: models.EventProductCreated},
+	}
+	store := mocks.NewEventStore(suite.T())
+	var result models.RelayResult
+	relayed(store, events, &result)
+	sink := &recordSink{fail: map[string]bool{"product/1": true, "product/2": true}}
+	relay := outbox.NewRelay(suite.log, config.OutboxConfig{MaxAttempts: 2}, store, []outbox.Sink{sink})
+	_, err := relay.RelayOnce(suite.ctx)
+	suite.Require().NoError(err)
+	// the event failed for the second time is dead and doesn't hold back the next event of its product
+	suite.Require().Equal(models.RelayResult{Failed: []int{2, 3}, Dead: []int{1}}, result)
+}
+
+func (suite *relayTestSuite) Test_RelayOnceStoreError() {
+	store := mocks.NewEventStore(suite.T())
+	store.On("RelayEvents", mock.Anything, 100, 5*time.Minute, mock.Anything).Return(errors.New("db is down")).Once()
+	relay := outbox.NewRelay(suite.log, config.OutboxConfig{}, store, []outbox.Sink{&recordSink{}})
+	_, err := relay.RelayOnce(suite.ctx)
+	suite.Require().Error(err)
+}
+
+func (suite *relayTestSuite) Test_Run() {
+	store := mocks.NewEventStore(suite.T())
+	ctx, cancel := context.WithCancel(suite.ctx)
+	var result models.RelayResult
+	// the full batch is followed by the next one right away
+	relayed(store, []models.Event{{Id: 1, AggregateId: "1"}}, &result)
+	store.On("RelayEvents", mock.Anything, 1, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, limit int, claimTimeout time.Duration, publish func([]models.Event) models.RelayResult) error {
+			cancel()
+			publish(nil)
+			return nil
+		}).Once()
+	sink := &recordSink{}
+	relay := outbox.NewRelay(suite.log, config.OutboxConfig{BatchSize: 1}, store, []outbox.Sink{sink})
+	<-relay.Run(ctx)
+	suite.Require().Equal([]int{1}, sink.published)
+
+	disabled := outbox.NewRelay(suite.log, config.OutboxConfig{}, store, nil)
+	<-disabled.Run(suite.ctx)
+}
+
+func (suite *relayTestSuite) Test_NewSinks() {
+	sinks, err := outbox.NewSinks(suite.log, config.OutboxConfig{
+		Sinks: []string{config.OutboxSinkLog, config.OutboxSinkFile},
+		FilePath: filepath.Join(suite.T().TempDir(), "events.ndjson"),
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(sinks, 2)
+
+	_, err = outbox.NewSinks(suite.log, config.OutboxConfig{Sinks: []string{"kafka"}})
+	suite.Require().ErrorIs(err, outbox.ErrUnknownSink)
+	_, err = outbox.NewSinks(suite.log, config.OutboxConfig{Sinks: []string{config.OutboxSinkWebhook}})
+	suite.Require().ErrorIs(err, outbox.ErrEmptySink)
+}
+
+func (suite *relayTestSuite) Test_FileSink() {
+	path := filepath.Join(suite.T().TempDir(), "events.ndjson")
+	sink, err := outbox.NewFileSink(path)
+	suite.Require().NoError(err)
+	for _, id := range []int{1, 2} {
+		suite.Require().NoError(sink.Publish(suite.ctx, models.Event{Id: id, Payload: []byte(`{"id":"1"}`)}))
+	}
+	suite.Require().NoError(sink.Close())
+
+	data, err := os.ReadFile(path)
+	suite.Require().NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	suite.Require().Len(lines, 2)
+	var event models.Event
+	suite.Require().NoError(json.Unmarshal([]byte(lines[1]), &event))
+	suite.Require().Equal(2, event.Id)
+	suite.Require().JSONEq(`{"id":"1"}`, string(event.Payload))
+}
+
+func (suite *relayTestSuite) Test_WebhookSink() {
+	var (
+		got models.Event
+		gotId string
+		status = http.StatusNoContent
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotId = r.Header.Get("X-Event-Id")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	sink, err := outbox.NewWebhookSink(server.URL, 0)
+	suite.Require().NoError(err)
+	event := models.Event{Id: 7, Aggregate: models.EventAggregateCategory, AggregateId: "3", Type: models.EventCategoryDeleted, Payload: []byte(`{"code":"c"}`)}
+	suite.Require().NoError(sink.Publish(suite.ctx, event))
+	suite.Require().Equal("7", gotId)
+	suite.Require().Equal(event.Type, got.Type)
+	suite.Require().JSONEq(`{"code":"c"}`, string(got.Payload))
+
+	status = http.StatusServiceUnavailable
+	suite.Require().ErrorIs(sink.Publish(suite.ctx, event), outbox.ErrBadStatus)
+}
@@ -0,0 +1,156 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+const defaultWebhookTimeout = 5 * time.Second
+
+// Sink gets every event of the outbox. An event is published again if any sink fails,
+// so sinks have to tolerate the same event id more than once
+type Sink interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// NewSinks returns sinks by their names from the config, sinks holding files are closed by the relay
+func NewSinks(logger *slog.Logger, cfg config.OutboxConfig) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(cfg.Sinks))
+	for _, name := range cfg.Sinks {
+		switch name {
+		case config.OutboxSinkLog:
+			sinks = append(sinks, NewLogSink(logger))
+		case config.OutboxSinkFile:
+			sink, err := NewFileSink(cfg.FilePath)
+			if err != nil {
+				closeSinks(sinks)
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case config.OutboxSinkWebhook:
+			sink, err := NewWebhookSink(cfg.WebhookURL, cfg.WebhookTimeout)
+			if err != nil {
+				closeSinks(sinks)
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		default:
+			closeSinks(sinks)
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSink, name)
+		}
+	}
+	return sinks, nil
+}
+
+type logSink struct {
+	log *slog.Logger
+}
+
+func NewLogSink(logger *slog.Logger) *logSink {
+	return &logSink{
+		log: logger.With(slog.String("sink", config.OutboxSinkLog)),
+	}
+}
+
+func (ls *logSink) Publish(ctx context.Context, event models.Event) error {
+	ls.log.Info("event published",
+		slog.Int("event_id", event.Id),
+		slog.String("aggregate", event.Aggregate),
+		slog.String("aggregate_id", event.AggregateId),
+		slog.String("type", event.Type),
+		slog.String("payload", string(event.Payload)))
+	return nil
+}
+
+// fileSink appends events to the file as json lines, every event is synced to disk
+// before it is reported as published
+type fileSink struct {
+	mu sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*fileSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("%w: %s", ErrEmptySink, config.OutboxSinkFile)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open events file: %w", err)
+	}
+	return &fileSink{
+		file: file,
+	}, nil
+}
+
+func (fs *fileSink) Publish(ctx context.Context, event models.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, err := fs.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return fs.file.Sync()
+}
+
+func (fs *fileSink) Close() error {
+	return fs.file.Close()
+}
+
+// webhookSink posts every event as json, the event is published if the response status is 2xx
+type webhookSink struct {
+	url string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) (*webhookSink, error) {
+	if url == "" {
+		return nil, fmt.Errorf("%w: %s", ErrEmptySink, config.OutboxSinkWebhook)
+	}
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &webhookSink{
+		url: url,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+func (ws *webhookSink) Publish(ctx context.Context, event models.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ws.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// receivers drop repeated deliveries by the id
+	req.Header.Set("X-Event-Id", strconv.Itoa(event.Id))
+	req.Header.Set("X-Event-Type", event.Type)
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: %d", ErrBadStatus, resp.StatusCode)
+	}
+	return nil
+}
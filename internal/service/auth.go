@@ -70,6 +70,9 @@ func (a *auth) Login(ctx context.Context, email, password string) (models.TokenP
 	user, err := a.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
 		a.log.Error("failed to get user", slog.String("error", err.Error()))
+		if errors.Is(err, ErrUnavailable) {
+			return models.TokenPair{}, fmt.Errorf("can't login user: %w", err)
+		}
 		return models.TokenPair{}, fmt.Errorf("can't login user: %w", ErrInvalidCredentials)
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PassHash), []byte(password)); err != nil {
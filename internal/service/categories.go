@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
 
@@ -12,6 +13,8 @@ import (
 type categoryService struct {
 	log *slog.Logger
 	categoryRepo categoryRepo
+	versionRepo versionRepo
+	txManager txManager
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=categoryRepo --exported
@@ -19,29 +22,35 @@ type categoryRepo interface{
 	SaveCategory(ctx context.Context, category models.Category) error
 	GetCategoryByCode(ctx context.Context, catCode string) (models.Category, error)
 	GetAllCategories(ctx context.Context) ([]models.Category, error)
-	UpdateCategoryByCode(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch) (error)
-	DeleteCategoryBycode(ctx context.Context, catCode string) (error)
+	UpdateCategoryByCode(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch, version int) (models.Category, error)
+	DeleteCategoryBycode(ctx context.Context, catCode string, version int) (models.Category, error)
+	StreamCategories(ctx context.Context, fn func(models.Category) error) error
 }
 
-func NewCategoryService(log *slog.Logger, categoryRepo categoryRepo) *categoryService {
+func NewCategoryService(log *slog.Logger, categoryRepo categoryRepo, versionRepo versionRepo, txManager txManager) *categoryService {
 	return &categoryService{
 		log: log.With(slog.String("service", "category")),
 		categoryRepo: categoryRepo,
+		versionRepo: versionRepo,
+		txManager: txManager,
 	}
 }
 
+// AddCategory saves the category with its first version in one transaction
 func (cs *categoryService) AddCategory(ctx context.Context, category models.Category) (error) {
 	cs.log.Info("attempt to add category")
 	cs.log.Debug("got category", slog.Any("category", category))
-	if err := cs.categoryRepo.SaveCategory(ctx, category); err != nil {
-		if errors.Is(err, storage.ErrCategoryExist) {
-			cs.log.Error("failed to save category", slog.String("error", ErrCategoryExist.Error()))
-			return ErrCategoryExist
+	return cs.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := cs.categoryRepo.SaveCategory(ctx, category); err != nil {
+			if errors.Is(err, storage.ErrCategoryExist) {
+				cs.log.Error("failed to save category", slog.String("error", ErrCategoryExist.Error()))
+				return ErrCategoryExist
+			}
+			cs.log.Error("failed to save category", slog.String("error", err.Error()))
+			return err
 		}
-		cs.log.Error("failed to save category", slog.String("error", err.Error()))
-		return err
-	}
-	return nil
+		return cs.saveVersion(ctx, category.Code, models.VersionActionCreate, category, 0)
+	})
 }
 
 func (cs *categoryService) GetOneCategory(ctx context.Context, catCode string) (models.Category, error) {
@@ -65,26 +74,112 @@ func (cs *categoryService) GetAllCategories(ctx context.Context) ([]models.Categ
 	return categories, nil
 }
 
-func (cs *categoryService) EditCategory(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch) (error) {
-	cs.log.Info("attempt to update category")
-	cs.log.Debug("got category data", slog.Any("category", catUpdateData))
-	if err := cs.categoryRepo.UpdateCategoryByCode(ctx, catCode, catUpdateData); err != nil {
-		cs.log.Error("failed to update category", slog.String("error", err.Error()))
+// ExportCategories calls fn for every category without loading all of them
+func (cs *categoryService) ExportCategories(ctx context.Context, fn func(models.Category) error) error {
+	cs.log.Info("attempt to export categories")
+	if err := cs.categoryRepo.StreamCategories(ctx, fn); err != nil {
+		cs.log.Error("failed to export categories", slog.String("error", err.Error()))
 		return err
 	}
 	return nil
 }
 
-func (cs *categoryService) DeleteCategory(ctx context.Context, catCode string) (error)  {
+// EditCategory changes the category if version is zero or equal to the version of the category,
+// otherwise ErrVersionConflict is returned
+func (cs *categoryService) EditCategory(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch, version int) (error) {
+	cs.log.Info("attempt to update category")
+	cs.log.Debug("got category data", slog.Any("category", catUpdateData))
+	return cs.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		category, err := cs.categoryRepo.UpdateCategoryByCode(ctx, catCode, catUpdateData, version)
+		if err != nil {
+			if errors.Is(err, storage.ErrVersionConflict) {
+				cs.log.Warn("category version has changed", slog.String("code", catCode), slog.Int("version", version))
+				return ErrVersionConflict
+			}
+			cs.log.Error("failed to update category", slog.String("error", err.Error()))
+			return err
+		}
+		// update of the missing category changes nothing
+		if category.Code == "" {
+			return nil
+		}
+		// history is kept by code, the changed code starts its own history
+		return cs.saveVersion(ctx, category.Code, models.VersionActionUpdate, category, 0)
+	})
+}
+
+// DeleteCategory moves the category to the trash if version is zero or equal to the version of the category,
+// otherwise ErrVersionConflict is returned. The category is kept in the history as it was before deletion
+func (cs *categoryService) DeleteCategory(ctx context.Context, catCode string, version int) (error)  {
 	cs.log.Info("attempt to delete category")
 	cs.log.Debug("got category code", slog.Any("code", catCode))
-	if err := cs.categoryRepo.DeleteCategoryBycode(ctx, catCode); err != nil {
-		if errors.Is(err, storage.ErrCategoryUsed) {
-			cs.log.Error("failed to save category", slog.String("error", ErrCategoryInUse.Error()))
-			return ErrCategoryInUse
+	return cs.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		category, err := cs.categoryRepo.DeleteCategoryBycode(ctx, catCode, version)
+		if err != nil {
+			if errors.Is(err, storage.ErrCategoryUsed) {
+				cs.log.Error("failed to save category", slog.String("error", ErrCategoryInUse.Error()))
+				return ErrCategoryInUse
+			}
+			if errors.Is(err, storage.ErrVersionConflict) {
+				cs.log.Warn("category version has changed", slog.String("code", catCode), slog.Int("version", version))
+				return ErrVersionConflict
+			}
+			cs.log.Error("failed to delete category", slog.String("error", err.Error()))
+			return err
 		}
-		cs.log.Error("failed to delete category", slog.String("error", err.Error()))
+		// the category deleted before is not deleted again
+		if category.Code == "" {
+			return nil
+		}
+		return cs.saveVersion(ctx, catCode, models.VersionActionDelete, category, 0)
+	})
+}
+
+// CategoryVersions returns the history of the category code from the first version
+func (cs *categoryService) CategoryVersions(ctx context.Context, catCode string) ([]models.Version, error) {
+	return getVersions(ctx, cs.log, cs.versionRepo, models.VersionEntityCategory, catCode)
+}
+
+func (cs *categoryService) DiffCategoryVersions(ctx context.Context, catCode string, from, to int) (models.VersionDiff, error) {
+	return diffVersions(ctx, cs.log, cs.versionRepo, models.VersionEntityCategory, catCode, from, to)
+}
+
+// RevertCategory sets name and description of the category from the version,
+// the revert is recorded as a new version. Deleted category must be restored first
+func (cs *categoryService) RevertCategory(ctx context.Context, catCode string, number int) error {
+	cs.log.Info("attempt to revert category")
+	cs.log.Debug("got category version", slog.String("code", catCode), slog.Int("version", number))
+	version, err := getVersion(ctx, cs.log, cs.versionRepo, models.VersionEntityCategory, catCode, number)
+	if err != nil {
 		return err
 	}
-	return nil
-}
\ No newline at end of file
+	var snapshot models.Category
+	if err := json.Unmarshal(version.Snapshot, &snapshot); err != nil {
+		cs.log.Error("failed to read version", slog.String("error", err.Error()))
+		return err
+	}
+	patch := models.CategoryForPatch{
+		Name: &snapshot.Name,
+		Description: &snapshot.Description,
+	}
+	return cs.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		category, err := cs.categoryRepo.UpdateCategoryByCode(ctx, catCode, patch, 0)
+		if err != nil {
+			cs.log.Error("failed to revert category", slog.String("error", err.Error()))
+			return err
+		}
+		// update of the missing category changes nothing
+		if category.Code == "" {
+			cs.log.Warn("category not found", slog.String("code", catCode))
+			return ErrCategoryNotFound
+		}
+		return cs.saveVersion(ctx, catCode, models.VersionActionRevert, category, number)
+	})
+}
+
+// saveVersion saves the category as the change has left it, ctx must carry the transaction of the change
+func (cs *categoryService) saveVersion(ctx context.Context, catCode string, action string, category models.Category, revertedTo int) error {
+	version, err := newVersion(ctx, models.VersionEntityCategory, catCode, action, category)
+	version.RevertedTo = revertedTo
+	return saveVersion(ctx, cs.log, cs.versionRepo, version, err)
+}
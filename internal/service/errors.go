@@ -1,6 +1,12 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
 
 var (
 	ErrUserExist = errors.New("user already exist")
@@ -8,7 +14,62 @@ var (
 	ErrCategoriesCodes = errors.New("categories with some codes not exists")
 	ErrCategoryInUse = errors.New("category with this code in use")
 	ErrProductExist = errors.New("product with this name already exist")
+	ErrSkuExist = errors.New("product with this sku already exist")
+	ErrBarcodeExist = errors.New("product with this barcode already exist")
+	ErrVariantExist = errors.New("variant with these options already exist")
+	ErrVariantSkuExist = errors.New("variant with this sku already exist")
+	ErrVariantNotFound = errors.New("variant with this id not found")
+	// ErrVariantOptions is wrapped with the reason why option values of a variant don't fit options of its product
+	ErrVariantOptions = errors.New("variant options don't fit the product")
+	// ErrAttributes is matched by AttributesError
+	ErrAttributes = errors.New("product attributes don't fit its categories")
+	ErrMediaNotFound = errors.New("media with this id not found")
+	ErrMediaOrder = errors.New("media ids don't match media of the product")
+	ErrMediaType = errors.New("unsupported media type, only jpeg, png and gif images are allowed")
+	// ErrMediaInvalid is wrapped with the reason why the uploaded image can't be used
+	ErrMediaInvalid = errors.New("invalid image")
+	ErrWarehouseExist = errors.New("warehouse with this code already exist")
+	ErrWarehouseNotFound = errors.New("warehouse with this code not found")
+	ErrReservationNotFound = errors.New("reservation with this id not found")
+	ErrReservationClosed = errors.New("reservation is already released or committed")
+	ErrOutOfStock = errors.New("not enough stock")
 	ErrProductNotFound = errors.New("product with this id not found")
+	ErrProductStateUnknown = errors.New("unknown product state")
+	// ErrStateTransition is wrapped with the current and the requested states of the product
+	ErrStateTransition = errors.New("product state transition is not allowed")
+	ErrProductStateChanged = errors.New("product state has changed since it was read")
+	ErrCategoryNotFound = errors.New("category with this code not found")
+	ErrVersionNotFound = errors.New("version not found")
+	ErrVersionConflict = errors.New("version has changed since it was read")
 	ErrInvalidCredentials = errors.New("invalid credential")
 	ErrValidRefresh = errors.New("not valid refresh token")
-)
\ No newline at end of file
+	// ErrUnavailable is returned as is from the storage when the database can't be reached
+	ErrUnavailable = storage.ErrUnavailable
+)
+
+// AttributesError reports every attribute value of a product that doesn't fit the schemas of its categories
+type AttributesError struct {
+	Issues []models.AttributeIssue
+}
+
+func (ae *AttributesError) Error() string {
+	return fmt.Sprintf("%s: %d issues", ErrAttributes.Error(), len(ae.Issues))
+}
+
+func (ae *AttributesError) Unwrap() error {
+	return ErrAttributes
+}
+
+// productExistErr returns the service error for the unique value of the product taken in the storage,
+// nil if err is not about a taken value
+func productExistErr(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrProductExist):
+		return ErrProductExist
+	case errors.Is(err, storage.ErrSkuExist):
+		return ErrSkuExist
+	case errors.Is(err, storage.ErrBarcodeExist):
+		return ErrBarcodeExist
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/media"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+const defaultThumbnailSize = 256
+
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=mediaRepo --exported
+type mediaRepo interface {
+	SaveMedia(ctx context.Context, prodId string, media models.Media) (models.Media, error)
+	DeleteMediaById(ctx context.Context, prodId, mediaId string) (models.Media, error)
+	SetMediaOrder(ctx context.Context, prodId string, mediaIds []int) error
+	SetPrimaryMedia(ctx context.Context, prodId, mediaId string) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=mediaStore --exported
+type mediaStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+type mediaService struct {
+	log *slog.Logger
+	thumbnailSize int
+	mediaStore mediaStore
+	mediaRepo mediaRepo
+	productRepo productRepo
+	versionRepo versionRepo
+	txManager txManager
+}
+
+func NewMediaService(logger *slog.Logger, cfg config.MediaConfig, store mediaStore, mdRepo mediaRepo, prRepo productRepo, verRepo versionRepo, txManager txManager) *mediaService {
+	thumbnailSize := cfg.ThumbnailSize
+	if thumbnailSize <= 0 {
+		thumbnailSize = defaultThumbnailSize
+	}
+	return &mediaService{
+		log: logger.With(slog.String("service", "media")),
+		thumbnailSize: thumbnailSize,
+		mediaStore: store,
+		mediaRepo: mdRepo,
+		productRepo: prRepo,
+		versionRepo: verRepo,
+		txManager: txManager,
+	}
+}
+
+// GetMedia returns media of the product ordered by position
+func (ms *mediaService) GetMedia(ctx context.Context, prodId string) ([]models.Media, error) {
+	ms.log.Info("attempt to get media")
+	ms.log.Debug("got product id", slog.String("product_id", prodId))
+	product, err := ms.productRepo.GetProductById(ctx, prodId)
+	if err != nil {
+		return nil, ms.storageErr("failed to get product", err)
+	}
+	return product.Media, nil
+}
+
+// AddMedia checks that data is a jpeg, png or gif image, puts it with its thumbnail into the store
+// and adds it after the other media of the product. Files are removed from the store if the media is not saved
+func (ms *mediaService) AddMedia(ctx context.Context, prodId string, data []byte, primary bool) (models.Media, error) {
+	ms.log.Info("attempt to add media")
+	ms.log.Debug("got media", slog.String("product_id", prodId), slog.Int("size", len(data)), slog.Bool("primary", primary))
+	if _, err := ms.productRepo.GetProductById(ctx, prodId); err != nil {
+		return models.Media{}, ms.storageErr("failed to get product", err)
+	}
+	img, err := media.Decode(data)
+	if err != nil {
+		ms.log.Warn("failed to decode image", slog.String("error", err.Error()))
+		if errors.Is(err, media.ErrUnsupportedType) {
+			return models.Media{}, ErrMediaType
+		}
+		return models.Media{}, fmt.Errorf("%w: %s", ErrMediaInvalid, err.Error())
+	}
+	thumbnail, err := img.Thumbnail(ms.thumbnailSize)
+	if err != nil {
+		ms.log.Error("failed to make thumbnail", slog.String("error", err.Error()))
+		return models.Media{}, err
+	}
+	key, thumbnailKey, err := media.NewKeys(prodId, img.Ext(), img.ThumbnailExt())
+	if err != nil {
+		ms.log.Error("failed to make media keys", slog.String("error", err.Error()))
+		return models.Media{}, err
+	}
+	if err := ms.mediaStore.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		ms.log.Error("failed to put media file", slog.String("key", key), slog.String("error", err.Error()))
+		return models.Media{}, err
+	}
+	if err := ms.mediaStore.Put(ctx, thumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+		ms.log.Error("failed to put thumbnail file", slog.String("key", thumbnailKey), slog.String("error", err.Error()))
+		ms.deleteFiles(ctx, key)
+		return models.Media{}, err
+	}
+	var saved models.Media
+	err = ms.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		saved, err = ms.mediaRepo.SaveMedia(ctx, prodId, models.Media{
+			Url: ms.mediaStore.URL(key),
+			ThumbnailUrl: ms.mediaStore.URL(thumbnailKey),
+			ContentType: img.ContentType,
+			Size: len(data),
+			Width: img.Width,
+			Height: img.Height,
+			Primary: primary,
+			Key: key,
+			ThumbnailKey: thumbnailKey,
+		})
+		if err != nil {
+			return ms.storageErr("failed to save media", err)
+		}
+		return saveCurrentProduct(ctx, ms.log, ms.productRepo, ms.versionRepo, prodId)
+	})
+	if err != nil {
+		ms.deleteFiles(ctx, key, thumbnailKey)
+		return models.Media{}, err
+	}
+	return saved, nil
+}
+
+// DeleteMedia removes the media of the product and then its files from the store
+func (ms *mediaService) DeleteMedia(ctx context.Context, prodId, mediaId string) error {
+	ms.log.Info("attempt to delete media")
+	ms.log.Debug("got media id", slog.String("product_id", prodId), slog.String("media_id", mediaId))
+	var removed models.Media
+	err := ms.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		removed, err = ms.mediaRepo.DeleteMediaById(ctx, prodId, mediaId)
+		if err != nil {
+			return ms.storageErr("failed to delete media", err)
+		}
+		return saveCurrentProduct(ctx, ms.log, ms.productRepo, ms.versionRepo, prodId)
+	})
+	if err != nil {
+		return err
+	}
+	ms.deleteFiles(ctx, removed.Key, removed.ThumbnailKey)
+	return nil
+}
+
+// OrderMedia sets positions of media of the product by their order in mediaIds,
+// which must have every media of the product exactly once
+func (ms *mediaService) OrderMedia(ctx context.Context, prodId string, mediaIds []int) error {
+	ms.log.Info("attempt to order media")
+	ms.log.Debug("got media ids", slog.String("product_id", prodId), slog.Any("media_ids", mediaIds))
+	return ms.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := ms.mediaRepo.SetMediaOrder(ctx, prodId, mediaIds); err != nil {
+			return ms.storageErr("failed to order media", err)
+		}
+		return saveCurrentProduct(ctx, ms.log, ms.productRepo, ms.versionRepo, prodId)
+	})
+}
+
+func (ms *mediaService) SetPrimaryMedia(ctx context.Context, prodId, mediaId string) error {
+	ms.log.Info("attempt to set primary media")
+	ms.log.Debug("got media id", slog.String("product_id", prodId), slog.String("media_id", mediaId))
+	return ms.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := ms.mediaRepo.SetPrimaryMedia(ctx, prodId, mediaId); err != nil {
+			return ms.storageErr("failed to set primary media", err)
+		}
+		return saveCurrentProduct(ctx, ms.log, ms.productRepo, ms.versionRepo, prodId)
+	})
+}
+
+// deleteFiles removes files from the store, failures only leave unused files and are logged
+func (ms *mediaService) deleteFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := ms.mediaStore.Delete(ctx, key); err != nil {
+			ms.log.Error("failed to delete media file", slog.String("key", key), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// storageErr returns the service error for the storage error of the media
+func (ms *mediaService) storageErr(msg string, err error) error {
+	var serviceErr error
+	switch {
+	case errors.Is(err, storage.ErrProductNotFound):
+		serviceErr = ErrProductNotFound
+	case errors.Is(err, storage.ErrMediaNotFound):
+		serviceErr = ErrMediaNotFound
+	case errors.Is(err, storage.ErrMediaOrder):
+		serviceErr = ErrMediaOrder
+	default:
+		ms.log.Error(msg, slog.String("error", err.Error()))
+		return err
+	}
+	ms.log.Warn(msg, slog.String("error", serviceErr.Error()))
+	return serviceErr
+}
@@ -5,6 +5,7 @@ package mocks
 import (
 	context "context"
 
+	models "github.com/EwvwGeN/cataloger/internal/domain/models"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,6 +14,66 @@ type CategoryCodesRepo struct {
 	mock.Mock
 }
 
+// GetAllCategories provides a mock function with given fields: _a0
+func (_m *CategoryCodesRepo) GetAllCategories(_a0 context.Context) ([]models.Category, error) {
+	ret := _m.Called(_a0)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllCategories")
+	}
+
+	var r0 []models.Category
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Category, error)); ok {
+		return rf(_a0)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Category); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Category)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCategoriesAttributes provides a mock function with given fields: _a0, _a1
+func (_m *CategoryCodesRepo) GetCategoriesAttributes(_a0 context.Context, _a1 []string) (map[string][]models.Attribute, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCategoriesAttributes")
+	}
+
+	var r0 map[string][]models.Attribute
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string][]models.Attribute, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string][]models.Attribute); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]models.Attribute)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCategoriesIdByCodes provides a mock function with given fields: _a0, _a1
 func (_m *CategoryCodesRepo) GetCategoriesIdByCodes(_a0 context.Context, _a1 []string) ([]int, error) {
 	ret := _m.Called(_a0, _a1)
@@ -43,6 +104,36 @@ func (_m *CategoryCodesRepo) GetCategoriesIdByCodes(_a0 context.Context, _a1 []s
 	return r0, r1
 }
 
+// GetCategoriesIdMapByCodes provides a mock function with given fields: _a0, _a1
+func (_m *CategoryCodesRepo) GetCategoriesIdMapByCodes(_a0 context.Context, _a1 []string) (map[string]int, error) {
+	ret := _m.Called(_a0, _a1)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCategoriesIdMapByCodes")
+	}
+
+	var r0 map[string]int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string]int, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]int); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewCategoryCodesRepo creates a new instance of CategoryCodesRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewCategoryCodesRepo(t interface {
@@ -14,22 +14,32 @@ type CategoryRepo struct {
 	mock.Mock
 }
 
-// DeleteCategoryBycode provides a mock function with given fields: ctx, catCode
-func (_m *CategoryRepo) DeleteCategoryBycode(ctx context.Context, catCode string) error {
-	ret := _m.Called(ctx, catCode)
+// DeleteCategoryBycode provides a mock function with given fields: ctx, catCode, version
+func (_m *CategoryRepo) DeleteCategoryBycode(ctx context.Context, catCode string, version int) (models.Category, error) {
+	ret := _m.Called(ctx, catCode, version)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteCategoryBycode")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, catCode)
+	var r0 models.Category
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (models.Category, error)); ok {
+		return rf(ctx, catCode, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) models.Category); ok {
+		r0 = rf(ctx, catCode, version)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(models.Category)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, catCode, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetAllCategories provides a mock function with given fields: ctx
@@ -108,17 +118,17 @@ func (_m *CategoryRepo) SaveCategory(ctx context.Context, category models.Catego
 	return r0
 }
 
-// UpdateCategoryByCode provides a mock function with given fields: ctx, catCode, catUpdateData
-func (_m *CategoryRepo) UpdateCategoryByCode(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch) error {
-	ret := _m.Called(ctx, catCode, catUpdateData)
+// StreamCategories provides a mock function with given fields: ctx, fn
+func (_m *CategoryRepo) StreamCategories(ctx context.Context, fn func(models.Category) error) error {
+	ret := _m.Called(ctx, fn)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateCategoryByCode")
+		panic("no return value specified for StreamCategories")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, models.CategoryForPatch) error); ok {
-		r0 = rf(ctx, catCode, catUpdateData)
+	if rf, ok := ret.Get(0).(func(context.Context, func(models.Category) error) error); ok {
+		r0 = rf(ctx, fn)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -126,6 +136,34 @@ func (_m *CategoryRepo) UpdateCategoryByCode(ctx context.Context, catCode string
 	return r0
 }
 
+// UpdateCategoryByCode provides a mock function with given fields: ctx, catCode, catUpdateData, version
+func (_m *CategoryRepo) UpdateCategoryByCode(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch, version int) (models.Category, error) {
+	ret := _m.Called(ctx, catCode, catUpdateData, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateCategoryByCode")
+	}
+
+	var r0 models.Category
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.CategoryForPatch, int) (models.Category, error)); ok {
+		return rf(ctx, catCode, catUpdateData, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.CategoryForPatch, int) models.Category); ok {
+		r0 = rf(ctx, catCode, catUpdateData, version)
+	} else {
+		r0 = ret.Get(0).(models.Category)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.CategoryForPatch, int) error); ok {
+		r1 = rf(ctx, catCode, catUpdateData, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewCategoryRepo creates a new instance of CategoryRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewCategoryRepo(t interface {
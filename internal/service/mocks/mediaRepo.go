@@ -0,0 +1,121 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/EwvwGeN/cataloger/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MediaRepo is an autogenerated mock type for the mediaRepo type
+type MediaRepo struct {
+	mock.Mock
+}
+
+// DeleteMediaById provides a mock function with given fields: ctx, prodId, mediaId
+func (_m *MediaRepo) DeleteMediaById(ctx context.Context, prodId string, mediaId string) (models.Media, error) {
+	ret := _m.Called(ctx, prodId, mediaId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteMediaById")
+	}
+
+	var r0 models.Media
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (models.Media, error)); ok {
+		return rf(ctx, prodId, mediaId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) models.Media); ok {
+		r0 = rf(ctx, prodId, mediaId)
+	} else {
+		r0 = ret.Get(0).(models.Media)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, prodId, mediaId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveMedia provides a mock function with given fields: ctx, prodId, media
+func (_m *MediaRepo) SaveMedia(ctx context.Context, prodId string, media models.Media) (models.Media, error) {
+	ret := _m.Called(ctx, prodId, media)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveMedia")
+	}
+
+	var r0 models.Media
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.Media) (models.Media, error)); ok {
+		return rf(ctx, prodId, media)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.Media) models.Media); ok {
+		r0 = rf(ctx, prodId, media)
+	} else {
+		r0 = ret.Get(0).(models.Media)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.Media) error); ok {
+		r1 = rf(ctx, prodId, media)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetMediaOrder provides a mock function with given fields: ctx, prodId, mediaIds
+func (_m *MediaRepo) SetMediaOrder(ctx context.Context, prodId string, mediaIds []int) error {
+	ret := _m.Called(ctx, prodId, mediaIds)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMediaOrder")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []int) error); ok {
+		r0 = rf(ctx, prodId, mediaIds)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPrimaryMedia provides a mock function with given fields: ctx, prodId, mediaId
+func (_m *MediaRepo) SetPrimaryMedia(ctx context.Context, prodId string, mediaId string) error {
+	ret := _m.Called(ctx, prodId, mediaId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPrimaryMedia")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, prodId, mediaId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMediaRepo creates a new instance of MediaRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMediaRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MediaRepo {
+	mock := &MediaRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
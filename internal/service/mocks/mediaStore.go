@@ -0,0 +1,83 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MediaStore is an autogenerated mock type for the mediaStore type
+type MediaStore struct {
+	mock.Mock
+}
+
+// Delete provides a mock function with given fields: ctx, key
+func (_m *MediaStore) Delete(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Put provides a mock function with given fields: ctx, key, r
+func (_m *MediaStore) Put(ctx context.Context, key string, r io.Reader) error {
+	ret := _m.Called(ctx, key, r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Put")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, io.Reader) error); ok {
+		r0 = rf(ctx, key, r)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// URL provides a mock function with given fields: key
+func (_m *MediaStore) URL(key string) string {
+	ret := _m.Called(key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for URL")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// NewMediaStore creates a new instance of MediaStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMediaStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MediaStore {
+	mock := &MediaStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
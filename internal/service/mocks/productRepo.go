@@ -14,27 +14,37 @@ type ProductRepo struct {
 	mock.Mock
 }
 
-// DeleteProductById provides a mock function with given fields: _a0, _a1
-func (_m *ProductRepo) DeleteProductById(_a0 context.Context, _a1 string) error {
-	ret := _m.Called(_a0, _a1)
+// DeleteProductById provides a mock function with given fields: ctx, prodId, version
+func (_m *ProductRepo) DeleteProductById(ctx context.Context, prodId string, version int) (models.Product, error) {
+	ret := _m.Called(ctx, prodId, version)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteProductById")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(_a0, _a1)
+	var r0 models.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (models.Product, error)); ok {
+		return rf(ctx, prodId, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) models.Product); ok {
+		r0 = rf(ctx, prodId, version)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(models.Product)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, prodId, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// GetAllProducts provides a mock function with given fields: _a0
-func (_m *ProductRepo) GetAllProducts(_a0 context.Context) ([]models.Product, error) {
-	ret := _m.Called(_a0)
+// GetAllProducts provides a mock function with given fields: _a0, _a1
+func (_m *ProductRepo) GetAllProducts(_a0 context.Context, _a1 models.ProductFilter) ([]models.Product, error) {
+	ret := _m.Called(_a0, _a1)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAllProducts")
@@ -42,19 +52,47 @@ func (_m *ProductRepo) GetAllProducts(_a0 context.Context) ([]models.Product, er
 
 	var r0 []models.Product
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Product, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, models.ProductFilter) ([]models.Product, error)); ok {
+		return rf(_a0, _a1)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []models.Product); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, models.ProductFilter) []models.Product); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.Product)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, models.ProductFilter) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProductByBarcode provides a mock function with given fields: ctx, code
+func (_m *ProductRepo) GetProductByBarcode(ctx context.Context, code string) (models.Product, error) {
+	ret := _m.Called(ctx, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProductByBarcode")
+	}
+
+	var r0 models.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Product, error)); ok {
+		return rf(ctx, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Product); ok {
+		r0 = rf(ctx, code)
+	} else {
+		r0 = ret.Get(0).(models.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, code)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -90,9 +128,37 @@ func (_m *ProductRepo) GetProductById(_a0 context.Context, _a1 string) (models.P
 	return r0, r1
 }
 
-// GetProductsByCategory provides a mock function with given fields: ctx, catCode
-func (_m *ProductRepo) GetProductsByCategory(ctx context.Context, catCode string) ([]models.Product, error) {
-	ret := _m.Called(ctx, catCode)
+// GetProductBySku provides a mock function with given fields: ctx, sku
+func (_m *ProductRepo) GetProductBySku(ctx context.Context, sku string) (models.Product, error) {
+	ret := _m.Called(ctx, sku)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProductBySku")
+	}
+
+	var r0 models.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Product, error)); ok {
+		return rf(ctx, sku)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Product); ok {
+		r0 = rf(ctx, sku)
+	} else {
+		r0 = ret.Get(0).(models.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sku)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProductsByCategory provides a mock function with given fields: ctx, catCode, states
+func (_m *ProductRepo) GetProductsByCategory(ctx context.Context, catCode string, states []string) ([]models.Product, error) {
+	ret := _m.Called(ctx, catCode, states)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetProductsByCategory")
@@ -100,19 +166,19 @@ func (_m *ProductRepo) GetProductsByCategory(ctx context.Context, catCode string
 
 	var r0 []models.Product
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.Product, error)); ok {
-		return rf(ctx, catCode)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) ([]models.Product, error)); ok {
+		return rf(ctx, catCode, states)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) []models.Product); ok {
-		r0 = rf(ctx, catCode)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) []models.Product); ok {
+		r0 = rf(ctx, catCode, states)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.Product)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, catCode)
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string) error); ok {
+		r1 = rf(ctx, catCode, states)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -148,17 +214,75 @@ func (_m *ProductRepo) SaveProduct(_a0 context.Context, _a1 models.Product, _a2
 	return r0, r1
 }
 
-// UpdateProductById provides a mock function with given fields: _a0, _a1, _a2, _a3
-func (_m *ProductRepo) UpdateProductById(_a0 context.Context, _a1 string, _a2 models.ProductForPatch, _a3 []int) error {
-	ret := _m.Called(_a0, _a1, _a2, _a3)
+// SaveProducts provides a mock function with given fields: ctx, products, catsIds, atomic
+func (_m *ProductRepo) SaveProducts(ctx context.Context, products []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error) {
+	ret := _m.Called(ctx, products, catsIds, atomic)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateProductById")
+		panic("no return value specified for SaveProducts")
+	}
+
+	var r0 []models.ImportRowResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Product, [][]int, bool) ([]models.ImportRowResult, error)); ok {
+		return rf(ctx, products, catsIds, atomic)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Product, [][]int, bool) []models.ImportRowResult); ok {
+		r0 = rf(ctx, products, catsIds, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImportRowResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []models.Product, [][]int, bool) error); ok {
+		r1 = rf(ctx, products, catsIds, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetProductState provides a mock function with given fields: ctx, prodId, from, to, version
+func (_m *ProductRepo) SetProductState(ctx context.Context, prodId string, from string, to string, version int) (models.Product, error) {
+	ret := _m.Called(ctx, prodId, from, to, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetProductState")
+	}
+
+	var r0 models.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int) (models.Product, error)); ok {
+		return rf(ctx, prodId, from, to, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int) models.Product); ok {
+		r0 = rf(ctx, prodId, from, to, version)
+	} else {
+		r0 = ret.Get(0).(models.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, int) error); ok {
+		r1 = rf(ctx, prodId, from, to, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StreamProducts provides a mock function with given fields: ctx, filter, fn
+func (_m *ProductRepo) StreamProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error {
+	ret := _m.Called(ctx, filter, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StreamProducts")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, models.ProductForPatch, []int) error); ok {
-		r0 = rf(_a0, _a1, _a2, _a3)
+	if rf, ok := ret.Get(0).(func(context.Context, models.ProductFilter, func(models.Product) error) error); ok {
+		r0 = rf(ctx, filter, fn)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -166,6 +290,34 @@ func (_m *ProductRepo) UpdateProductById(_a0 context.Context, _a1 string, _a2 mo
 	return r0
 }
 
+// UpdateProductById provides a mock function with given fields: ctx, prodId, newPorductdata, catIds, version
+func (_m *ProductRepo) UpdateProductById(ctx context.Context, prodId string, newPorductdata models.ProductForPatch, catIds []int, version int) (models.Product, error) {
+	ret := _m.Called(ctx, prodId, newPorductdata, catIds, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProductById")
+	}
+
+	var r0 models.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.ProductForPatch, []int, int) (models.Product, error)); ok {
+		return rf(ctx, prodId, newPorductdata, catIds, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.ProductForPatch, []int, int) models.Product); ok {
+		r0 = rf(ctx, prodId, newPorductdata, catIds, version)
+	} else {
+		r0 = ret.Get(0).(models.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.ProductForPatch, []int, int) error); ok {
+		r1 = rf(ctx, prodId, newPorductdata, catIds, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewProductRepo creates a new instance of ProductRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewProductRepo(t interface {
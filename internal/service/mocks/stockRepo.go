@@ -0,0 +1,277 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/EwvwGeN/cataloger/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// StockRepo is an autogenerated mock type for the stockRepo type
+type StockRepo struct {
+	mock.Mock
+}
+
+// AdjustStock provides a mock function with given fields: ctx, prodId, change
+func (_m *StockRepo) AdjustStock(ctx context.Context, prodId string, change models.StockChange) (models.StockLevel, error) {
+	ret := _m.Called(ctx, prodId, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AdjustStock")
+	}
+
+	var r0 models.StockLevel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.StockChange) (models.StockLevel, error)); ok {
+		return rf(ctx, prodId, change)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.StockChange) models.StockLevel); ok {
+		r0 = rf(ctx, prodId, change)
+	} else {
+		r0 = ret.Get(0).(models.StockLevel)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.StockChange) error); ok {
+		r1 = rf(ctx, prodId, change)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CommitReservation provides a mock function with given fields: ctx, reservationId, change
+func (_m *StockRepo) CommitReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error) {
+	ret := _m.Called(ctx, reservationId, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CommitReservation")
+	}
+
+	var r0 models.Reservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.StockChange) (models.Reservation, error)); ok {
+		return rf(ctx, reservationId, change)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.StockChange) models.Reservation); ok {
+		r0 = rf(ctx, reservationId, change)
+	} else {
+		r0 = ret.Get(0).(models.Reservation)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.StockChange) error); ok {
+		r1 = rf(ctx, reservationId, change)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetReservation provides a mock function with given fields: ctx, reservationId
+func (_m *StockRepo) GetReservation(ctx context.Context, reservationId string) (models.Reservation, error) {
+	ret := _m.Called(ctx, reservationId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetReservation")
+	}
+
+	var r0 models.Reservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Reservation, error)); ok {
+		return rf(ctx, reservationId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Reservation); ok {
+		r0 = rf(ctx, reservationId)
+	} else {
+		r0 = ret.Get(0).(models.Reservation)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, reservationId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStock provides a mock function with given fields: ctx, prodId
+func (_m *StockRepo) GetStock(ctx context.Context, prodId string) ([]models.StockLevel, error) {
+	ret := _m.Called(ctx, prodId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStock")
+	}
+
+	var r0 []models.StockLevel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.StockLevel, error)); ok {
+		return rf(ctx, prodId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.StockLevel); ok {
+		r0 = rf(ctx, prodId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StockLevel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, prodId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStockMovements provides a mock function with given fields: ctx, prodId
+func (_m *StockRepo) GetStockMovements(ctx context.Context, prodId string) ([]models.StockMovement, error) {
+	ret := _m.Called(ctx, prodId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStockMovements")
+	}
+
+	var r0 []models.StockMovement
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.StockMovement, error)); ok {
+		return rf(ctx, prodId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.StockMovement); ok {
+		r0 = rf(ctx, prodId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StockMovement)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, prodId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWarehouses provides a mock function with given fields: ctx
+func (_m *StockRepo) GetWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWarehouses")
+	}
+
+	var r0 []models.Warehouse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Warehouse, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Warehouse); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Warehouse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReleaseReservation provides a mock function with given fields: ctx, reservationId, change
+func (_m *StockRepo) ReleaseReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error) {
+	ret := _m.Called(ctx, reservationId, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseReservation")
+	}
+
+	var r0 models.Reservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.StockChange) (models.Reservation, error)); ok {
+		return rf(ctx, reservationId, change)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.StockChange) models.Reservation); ok {
+		r0 = rf(ctx, reservationId, change)
+	} else {
+		r0 = ret.Get(0).(models.Reservation)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.StockChange) error); ok {
+		r1 = rf(ctx, reservationId, change)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReserveStock provides a mock function with given fields: ctx, prodId, change
+func (_m *StockRepo) ReserveStock(ctx context.Context, prodId string, change models.StockChange) (models.Reservation, error) {
+	ret := _m.Called(ctx, prodId, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReserveStock")
+	}
+
+	var r0 models.Reservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.StockChange) (models.Reservation, error)); ok {
+		return rf(ctx, prodId, change)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.StockChange) models.Reservation); ok {
+		r0 = rf(ctx, prodId, change)
+	} else {
+		r0 = ret.Get(0).(models.Reservation)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.StockChange) error); ok {
+		r1 = rf(ctx, prodId, change)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveWarehouse provides a mock function with given fields: ctx, warehouse
+func (_m *StockRepo) SaveWarehouse(ctx context.Context, warehouse models.Warehouse) error {
+	ret := _m.Called(ctx, warehouse)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveWarehouse")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Warehouse) error); ok {
+		r0 = rf(ctx, warehouse)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewStockRepo creates a new instance of StockRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewStockRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *StockRepo {
+	mock := &StockRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
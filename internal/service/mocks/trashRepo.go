@@ -0,0 +1,123 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/EwvwGeN/cataloger/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// TrashRepo is an autogenerated mock type for the trashRepo type
+type TrashRepo struct {
+	mock.Mock
+}
+
+// GetTrash provides a mock function with given fields: ctx
+func (_m *TrashRepo) GetTrash(ctx context.Context) (models.Trash, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTrash")
+	}
+
+	var r0 models.Trash
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (models.Trash, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) models.Trash); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(models.Trash)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeTrash provides a mock function with given fields: ctx, before
+func (_m *TrashRepo) PurgeTrash(ctx context.Context, before time.Time) (models.PurgeResult, error) {
+	ret := _m.Called(ctx, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeTrash")
+	}
+
+	var r0 models.PurgeResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (models.PurgeResult, error)); ok {
+		return rf(ctx, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) models.PurgeResult); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(models.PurgeResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreCategoryByCode provides a mock function with given fields: ctx, catCode
+func (_m *TrashRepo) RestoreCategoryByCode(ctx context.Context, catCode string) error {
+	ret := _m.Called(ctx, catCode)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreCategoryByCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, catCode)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreProductById provides a mock function with given fields: ctx, prodId
+func (_m *TrashRepo) RestoreProductById(ctx context.Context, prodId string) error {
+	ret := _m.Called(ctx, prodId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreProductById")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, prodId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewTrashRepo creates a new instance of TrashRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewTrashRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *TrashRepo {
+	mock := &TrashRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
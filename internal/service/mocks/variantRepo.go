@@ -0,0 +1,93 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/EwvwGeN/cataloger/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// VariantRepo is an autogenerated mock type for the variantRepo type
+type VariantRepo struct {
+	mock.Mock
+}
+
+// DeleteVariantById provides a mock function with given fields: ctx, prodId, variantId
+func (_m *VariantRepo) DeleteVariantById(ctx context.Context, prodId string, variantId string) error {
+	ret := _m.Called(ctx, prodId, variantId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteVariantById")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, prodId, variantId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveVariant provides a mock function with given fields: ctx, prodId, variant
+func (_m *VariantRepo) SaveVariant(ctx context.Context, prodId string, variant models.Variant) (string, error) {
+	ret := _m.Called(ctx, prodId, variant)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveVariant")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.Variant) (string, error)); ok {
+		return rf(ctx, prodId, variant)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.Variant) string); ok {
+		r0 = rf(ctx, prodId, variant)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.Variant) error); ok {
+		r1 = rf(ctx, prodId, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateVariantById provides a mock function with given fields: ctx, prodId, variantId, variant
+func (_m *VariantRepo) UpdateVariantById(ctx context.Context, prodId string, variantId string, variant models.Variant) error {
+	ret := _m.Called(ctx, prodId, variantId, variant)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateVariantById")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.Variant) error); ok {
+		r0 = rf(ctx, prodId, variantId, variant)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewVariantRepo creates a new instance of VariantRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewVariantRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *VariantRepo {
+	mock := &VariantRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,105 @@
+// Code generated by mockery v2.40.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/EwvwGeN/cataloger/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// VersionRepo is an autogenerated mock type for the versionRepo type
+type VersionRepo struct {
+	mock.Mock
+}
+
+// GetVersion provides a mock function with given fields: ctx, entity, key, number
+func (_m *VersionRepo) GetVersion(ctx context.Context, entity string, key string, number int) (models.Version, error) {
+	ret := _m.Called(ctx, entity, key, number)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVersion")
+	}
+
+	var r0 models.Version
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) (models.Version, error)); ok {
+		return rf(ctx, entity, key, number)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) models.Version); ok {
+		r0 = rf(ctx, entity, key, number)
+	} else {
+		r0 = ret.Get(0).(models.Version)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int) error); ok {
+		r1 = rf(ctx, entity, key, number)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVersions provides a mock function with given fields: ctx, entity, key
+func (_m *VersionRepo) GetVersions(ctx context.Context, entity string, key string) ([]models.Version, error) {
+	ret := _m.Called(ctx, entity, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVersions")
+	}
+
+	var r0 []models.Version
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]models.Version, error)); ok {
+		return rf(ctx, entity, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []models.Version); ok {
+		r0 = rf(ctx, entity, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Version)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, entity, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveVersions provides a mock function with given fields: ctx, versions
+func (_m *VersionRepo) SaveVersions(ctx context.Context, versions []models.Version) error {
+	ret := _m.Called(ctx, versions)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveVersions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Version) error); ok {
+		r0 = rf(ctx, versions)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewVersionRepo creates a new instance of VersionRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewVersionRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *VersionRepo {
+	mock := &VersionRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -2,68 +2,107 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
 
+	"github.com/EwvwGeN/cataloger/internal/catalogdiff"
 	"github.com/EwvwGeN/cataloger/internal/domain/models"
 	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/EwvwGeN/cataloger/internal/validator"
 )
 
 //go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=productRepo --exported
 type productRepo interface {
 	SaveProduct(context.Context, models.Product, []int) (string, error)
 	GetProductById(context.Context, string) (models.Product, error)
-	GetAllProducts(context.Context) ([]models.Product, error)
-	GetProductsByCategory(ctx context.Context, catCode string) ([]models.Product, error)
-	UpdateProductById(context.Context, string, models.ProductForPatch, []int) (error)
-	DeleteProductById(context.Context, string) (error)
+	GetProductBySku(ctx context.Context, sku string) (models.Product, error)
+	GetProductByBarcode(ctx context.Context, code string) (models.Product, error)
+	GetAllProducts(context.Context, models.ProductFilter) ([]models.Product, error)
+	GetProductsByCategory(ctx context.Context, catCode string, states []string) ([]models.Product, error)
+	UpdateProductById(ctx context.Context, prodId string, newPorductdata models.ProductForPatch, catIds []int, version int) (models.Product, error)
+	DeleteProductById(ctx context.Context, prodId string, version int) (models.Product, error)
+	SetProductState(ctx context.Context, prodId, from, to string, version int) (models.Product, error)
+	SaveProducts(ctx context.Context, products []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error)
+	StreamProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=categoryCodesRepo --exported
 type categoryCodesRepo interface {
 	GetCategoriesIdByCodes(context.Context, []string) ([]int, error)
+	GetCategoriesIdMapByCodes(context.Context, []string) (map[string]int, error)
+	GetAllCategories(context.Context) ([]models.Category, error)
+	GetCategoriesAttributes(context.Context, []string) (map[string][]models.Attribute, error)
+}
+
+// txManager runs fn in one transaction, repository calls made with the context passed to fn join it
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=txManager --exported
+type txManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type productService struct {
 	log *slog.Logger
 	productRepo productRepo
 	categoryRepo categoryCodesRepo
+	versionRepo versionRepo
+	txManager txManager
 }
 
-func NewProductService(logger *slog.Logger, prRepo productRepo, catRepo categoryCodesRepo) *productService {
+func NewProductService(logger *slog.Logger, prRepo productRepo, catRepo categoryCodesRepo, verRepo versionRepo, txManager txManager) *productService {
 	return &productService{
 		log: logger.With(slog.String("service", "product")),
 		productRepo: prRepo,
 		categoryRepo: catRepo,
+		versionRepo: verRepo,
+		txManager: txManager,
 	}
 }
 
+// AddProduct looks up the categories and saves the product with its first version in one transaction,
+// so the categories can not be deleted in between. Attributes of the product must fit
+// the schemas of its categories, otherwise AttributesError is returned. New product is a draft
 func (ps *productService) AddProduct(ctx context.Context, product models.Product) (string, error) {
-	var (
-		categoriesId []int
-		err error
-	)
 	ps.log.Info("attempt to add product")
 	ps.log.Debug("got product", slog.Any("product", product))
-	if product.CategoryСodes != nil {
-		//TODO: handling case when any values from product.codes array does not exist in database
-		categoriesId, err = ps.categoryRepo.GetCategoriesIdByCodes(ctx, product.CategoryСodes)
-	}
-	if err != nil {
-		ps.log.Error("failed to get categories id", slog.String("error", err.Error()))
-		return "", err
-	}
-	if len(product.CategoryСodes) != len(categoriesId) {
-		ps.log.Error("failed to get categories id", slog.String("error", ErrCategoriesCodes.Error()))
-		return "", ErrCategoriesCodes
-	}
-	pId, err := ps.productRepo.SaveProduct(ctx, product, categoriesId)
-	if err != nil {
-		if errors.Is(err, storage.ErrProductExist) {
-			ps.log.Error("failed to save category", slog.String("error", ErrProductExist.Error()))
-			return "", ErrProductExist
+	product.State = models.ProductStateDraft
+	var pId string
+	err := ps.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var (
+			categoriesId []int
+			err error
+		)
+		if product.CategoryСodes != nil {
+			categoriesId, err = ps.categoryRepo.GetCategoriesIdByCodes(ctx, product.CategoryСodes)
+		}
+		if err != nil {
+			ps.log.Error("failed to get categories id", slog.String("error", err.Error()))
+			return err
+		}
+		if len(product.CategoryСodes) != len(categoriesId) {
+			ps.log.Error("failed to get categories id", slog.String("error", ErrCategoriesCodes.Error()))
+			return ErrCategoriesCodes
 		}
-		ps.log.Error("failed to save product", slog.String("error", err.Error()))
+		if err := ps.checkAttributes(ctx, product.CategoryСodes, product.Attributes); err != nil {
+			return err
+		}
+		pId, err = ps.productRepo.SaveProduct(ctx, product, categoriesId)
+		if err != nil {
+			if existErr := productExistErr(err); existErr != nil {
+				ps.log.Error("failed to save product", slog.String("error", existErr.Error()))
+				return existErr
+			}
+			ps.log.Error("failed to save product", slog.String("error", err.Error()))
+			return err
+		}
+		product.Id, _ = strconv.Atoi(pId)
+		return ps.saveVersion(ctx, pId, models.VersionActionCreate, product, 0)
+	})
+	if err != nil {
 		return "", err
 	}
 	return pId, nil
@@ -84,9 +123,42 @@ func (ps *productService) GetOneProduct(ctx context.Context, prodId string) (mod
 	return product, nil
 }
 
-func (ps *productService) GetAllProduct(ctx context.Context) ([]models.Product, error) {
+// GetProductBySku returns the product with the sku
+func (ps *productService) GetProductBySku(ctx context.Context, sku string) (models.Product, error) {
+	ps.log.Info("attempt to get product by sku")
+	ps.log.Debug("got product sku", slog.String("sku", sku))
+	product, err := ps.productRepo.GetProductBySku(ctx, sku)
+	if err != nil {
+		if errors.Is(err, storage.ErrProductNotFound) {
+			ps.log.Warn("product not found", slog.String("sku", sku))
+			return models.Product{}, ErrProductNotFound
+		}
+		ps.log.Error("failed to get product by sku", slog.String("sku", sku), slog.String("error", err.Error()))
+		return models.Product{}, err
+	}
+	return product, nil
+}
+
+// GetProductByBarcode returns the product with the barcode
+func (ps *productService) GetProductByBarcode(ctx context.Context, code string) (models.Product, error) {
+	ps.log.Info("attempt to get product by barcode")
+	ps.log.Debug("got product barcode", slog.String("barcode", code))
+	product, err := ps.productRepo.GetProductByBarcode(ctx, code)
+	if err != nil {
+		if errors.Is(err, storage.ErrProductNotFound) {
+			ps.log.Warn("product not found", slog.String("barcode", code))
+			return models.Product{}, ErrProductNotFound
+		}
+		ps.log.Error("failed to get product by barcode", slog.String("barcode", code), slog.String("error", err.Error()))
+		return models.Product{}, err
+	}
+	return product, nil
+}
+
+func (ps *productService) GetAllProduct(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
 	ps.log.Info("attempt to get all products")
-	products, err := ps.productRepo.GetAllProducts(ctx)
+	ps.log.Debug("got product filter", slog.Any("filter", filter))
+	products, err := ps.productRepo.GetAllProducts(ctx, filter)
 	if err != nil {
 		ps.log.Error("failed to get products", slog.String("error", err.Error()))
 		return nil, err
@@ -94,9 +166,10 @@ func (ps *productService) GetAllProduct(ctx context.Context) ([]models.Product,
 	return products, nil
 }
 
-func (ps *productService) GetAllProductsByCategory(ctx context.Context, catCode string) ([]models.Product, error) {
+// GetAllProductsByCategory returns products of the category in one of states, in any state if states are empty
+func (ps *productService) GetAllProductsByCategory(ctx context.Context, catCode string, states []string) ([]models.Product, error) {
 	ps.log.Info("attempt to get all products by category code")
-	products, err := ps.productRepo.GetProductsByCategory(ctx, catCode)
+	products, err := ps.productRepo.GetProductsByCategory(ctx, catCode, states)
 	if err != nil {
 		ps.log.Error("failed to get products", slog.String("error", err.Error()))
 		return nil, err
@@ -104,43 +177,463 @@ func (ps *productService) GetAllProductsByCategory(ctx context.Context, catCode
 	return products, nil
 }
 
-func (ps *productService) EditProduct(ctx context.Context, prodId string, prodUpdateData models.ProductForPatch) (error) {
-	var (
-		categoriesId []int
-		err error
-	)
+// ExportProducts calls fn for every product matching the filter without loading all of them
+func (ps *productService) ExportProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error {
+	ps.log.Info("attempt to export products")
+	ps.log.Debug("got filter", slog.Any("filter", filter))
+	if err := ps.productRepo.StreamProducts(ctx, filter, fn); err != nil {
+		ps.log.Error("failed to export products", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// EditProduct changes the product if version is zero or equal to the version of the product,
+// otherwise ErrVersionConflict is returned. Categories are looked up in the transaction of the update,
+// new options must fit all variants of the product and attributes must fit the schemas of the categories
+// if either of them is changed
+func (ps *productService) EditProduct(ctx context.Context, prodId string, prodUpdateData models.ProductForPatch, version int) (error) {
 	ps.log.Info("attempt to update product")
 	ps.log.Debug("got product data", slog.Any("product", prodUpdateData))
-	if prodUpdateData.CategoryСodes != nil {
-		categoriesId, err = ps.categoryRepo.GetCategoriesIdByCodes(ctx, prodUpdateData.CategoryСodes)
+	return ps.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var (
+			categoriesId []int
+			err error
+		)
+		if prodUpdateData.CategoryСodes != nil {
+			categoriesId, err = ps.categoryRepo.GetCategoriesIdByCodes(ctx, prodUpdateData.CategoryСodes)
+		}
+		if err != nil {
+			ps.log.Error("failed to get categories id", slog.String("error", err.Error()))
+			return err
+		}
+		if len(prodUpdateData.CategoryСodes) != len(categoriesId) {
+			ps.log.Error("failed to get categories id", slog.String("error", ErrCategoriesCodes.Error()))
+			return ErrCategoriesCodes
+		}
+		if err := ps.checkPatch(ctx, prodId, prodUpdateData); err != nil {
+			return err
+		}
+		product, err := ps.productRepo.UpdateProductById(ctx, prodId, prodUpdateData, categoriesId, version)
+		if err != nil {
+			if existErr := productExistErr(err); existErr != nil {
+				ps.log.Error("failed to save product", slog.String("error", existErr.Error()))
+				return existErr
+			}
+			if errors.Is(err, storage.ErrVersionConflict) {
+				ps.log.Warn("product version has changed", slog.String("product_id", prodId), slog.Int("version", version))
+				return ErrVersionConflict
+			}
+			if errors.Is(err, storage.ErrProductNotFound) {
+				ps.log.Warn("product not found", slog.String("prodcut_id", prodId))
+				return ErrProductNotFound
+			}
+			ps.log.Error("failed to update product", slog.String("error", err.Error()))
+			return err
+		}
+		return ps.saveVersion(ctx, prodId, models.VersionActionUpdate, product, 0)
+	})
+}
+
+// checkPatch reads the product if the patch changes its options, categories or attributes.
+// It returns ErrVariantOptions with the reason if a variant doesn't fit the new options
+// and AttributesError if the attributes don't fit the categories after the change
+func (ps *productService) checkPatch(ctx context.Context, prodId string, patch models.ProductForPatch) error {
+	if patch.Options == nil && patch.CategoryСodes == nil && patch.Attributes == nil {
+		return nil
 	}
+	product, err := ps.productRepo.GetProductById(ctx, prodId)
 	if err != nil {
-		ps.log.Error("failed to get categories id", slog.String("error", err.Error()))
+		if errors.Is(err, storage.ErrProductNotFound) {
+			ps.log.Warn("product not found", slog.String("prodcut_id", prodId))
+			return ErrProductNotFound
+		}
+		ps.log.Error("failed to get product", slog.String("product_id", prodId), slog.String("error", err.Error()))
 		return err
 	}
-	if len(prodUpdateData.CategoryСodes) != len(categoriesId) {
-		ps.log.Error("failed to get categories id", slog.String("error", ErrCategoriesCodes.Error()))
-		return ErrCategoriesCodes
+	if patch.Options != nil {
+		for _, variant := range product.Variants {
+			if reason := validator.VariantMismatchReason(patch.Options, variant.Options); reason != "" {
+				ps.log.Warn("options don't fit variant", slog.Int("variant_id", variant.Id), slog.String("reason", reason))
+				return fmt.Errorf("%w: variant %d: %s", ErrVariantOptions, variant.Id, reason)
+			}
+		}
+	}
+	if patch.CategoryСodes == nil && patch.Attributes == nil {
+		return nil
+	}
+	codes, values := product.CategoryСodes, product.Attributes
+	if patch.CategoryСodes != nil {
+		codes = patch.CategoryСodes
+	}
+	if patch.Attributes != nil {
+		values = patch.Attributes
+	}
+	return ps.checkAttributes(ctx, codes, values)
+}
+
+// checkAttributes returns AttributesError if the values don't fit the schemas of the categories with the codes
+func (ps *productService) checkAttributes(ctx context.Context, codes []string, values map[string]interface{}) error {
+	if len(codes) == 0 && len(values) == 0 {
+		return nil
 	}
-	if err := ps.productRepo.UpdateProductById(ctx, prodId, prodUpdateData, categoriesId); err != nil {
-		if errors.Is(err, storage.ErrProductExist) {
-			ps.log.Error("failed to save category", slog.String("error", ErrProductExist.Error()))
-			return ErrProductExist
+	var schemas map[string][]models.Attribute
+	if len(codes) != 0 {
+		var err error
+		schemas, err = ps.categoryRepo.GetCategoriesAttributes(ctx, codes)
+		if err != nil {
+			ps.log.Error("failed to get attributes of categories", slog.String("error", err.Error()))
+			return err
 		}
-		ps.log.Error("failed to update product", slog.String("error", err.Error()))
-		return err
+	}
+	if issues := validator.AttributeIssues(schemas, codes, values); len(issues) != 0 {
+		ps.log.Warn("attributes don't fit categories", slog.Any("issues", issues))
+		return &AttributesError{Issues: issues}
 	}
 	return nil
 }
 
-func (ps *productService) DelteProduct(ctx context.Context, prodId string) (error) {
+// DelteProduct moves the product to the trash if version is zero or equal to the version of the product,
+// otherwise ErrVersionConflict is returned. The product is kept in the history as it was before deletion
+func (ps *productService) DelteProduct(ctx context.Context, prodId string, version int) (error) {
 	ps.log.Info("attempt to delete product")
 	ps.log.Debug("got product id", slog.Any("product_id", prodId))
-	if err := ps.productRepo.DeleteProductById(ctx, prodId); err != nil {
-		ps.log.Error("failed to delete product", slog.String("product_id", prodId), slog.String("error", err.Error()))
+	return ps.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		product, err := ps.productRepo.DeleteProductById(ctx, prodId, version)
+		if err != nil {
+			if errors.Is(err, storage.ErrVersionConflict) {
+				ps.log.Warn("product version has changed", slog.String("product_id", prodId), slog.Int("version", version))
+				return ErrVersionConflict
+			}
+			ps.log.Error("failed to delete product", slog.String("product_id", prodId), slog.String("error", err.Error()))
+			return err
+		}
+		// the product deleted before is not deleted again
+		if product.Id == 0 {
+			return nil
+		}
+		return ps.saveVersion(ctx, prodId, models.VersionActionDelete, product, 0)
+	})
+}
+
+// productTransitions lists the states a product may be moved to from its state
+var productTransitions = map[string][]string{
+	models.ProductStateDraft: {models.ProductStateInReview},
+	models.ProductStateInReview: {models.ProductStateDraft, models.ProductStatePublished},
+	models.ProductStatePublished: {models.ProductStateArchived},
+	models.ProductStateArchived: {models.ProductStateDraft},
+}
+
+// ChangeProductState moves the product to the state if the transition from its current state is allowed,
+// otherwise ErrStateTransition is returned. If version is not zero it must be equal to the version
+// of the product. The product is read and changed in one transaction, the update compares the state and the version
+// again, so of concurrent changes only one is made and the others get ErrProductStateChanged or ErrVersionConflict.
+// The transition is recorded as a new version
+func (ps *productService) ChangeProductState(ctx context.Context, prodId, state string, version int) error {
+	ps.log.Info("attempt to change product state")
+	ps.log.Debug("got product state", slog.String("product_id", prodId), slog.String("state", state), slog.Int("version", version))
+	if _, ok := productTransitions[state]; !ok {
+		ps.log.Warn("unknown product state", slog.String("state", state))
+		return ErrProductStateUnknown
+	}
+	return ps.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		product, err := ps.productRepo.GetProductById(ctx, prodId)
+		if err != nil {
+			if errors.Is(err, storage.ErrProductNotFound) {
+				ps.log.Warn("product not found", slog.String("prodcut_id", prodId))
+				return ErrProductNotFound
+			}
+			ps.log.Error("failed to get product", slog.String("product_id", prodId), slog.String("error", err.Error()))
+			return err
+		}
+		if version != 0 && product.Version != version {
+			ps.log.Warn("product version has changed", slog.String("product_id", prodId), slog.Int("version", version))
+			return ErrVersionConflict
+		}
+		if !transitionAllowed(product.State, state) {
+			ps.log.Warn("product state transition is not allowed", slog.String("from", product.State), slog.String("to", state))
+			return fmt.Errorf("%w: from %s to %s", ErrStateTransition, product.State, state)
+		}
+		changed, err := ps.productRepo.SetProductState(ctx, prodId, product.State, state, version)
+		if err != nil {
+			if errors.Is(err, storage.ErrVersionConflict) {
+				ps.log.Warn("product version has changed", slog.String("product_id", prodId), slog.Int("version", version))
+				return ErrVersionConflict
+			}
+			if errors.Is(err, storage.ErrProductState) {
+				ps.log.Warn("product state has changed", slog.String("product_id", prodId))
+				return ErrProductStateChanged
+			}
+			if errors.Is(err, storage.ErrProductNotFound) {
+				ps.log.Warn("product not found", slog.String("prodcut_id", prodId))
+				return ErrProductNotFound
+			}
+			ps.log.Error("failed to change product state", slog.String("error", err.Error()))
+			return err
+		}
+		return ps.saveVersion(ctx, prodId, models.VersionActionUpdate, changed, 0)
+	})
+}
+
+func transitionAllowed(from, to string) bool {
+	for _, state := range productTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ProductVersions returns the history of the product from the first version
+func (ps *productService) ProductVersions(ctx context.Context, prodId string) ([]models.Version, error) {
+	return getVersions(ctx, ps.log, ps.versionRepo, models.VersionEntityProduct, prodId)
+}
+
+func (ps *productService) DiffProductVersions(ctx context.Context, prodId string, from, to int) (models.VersionDiff, error) {
+	return diffVersions(ctx, ps.log, ps.versionRepo, models.VersionEntityProduct, prodId, from, to)
+}
+
+// RevertProduct sets name, description, categories, prices, sku and barcodes of the product from the version,
+// options, variants and the state are kept. The revert is recorded as a new version. Deleted product must be restored first
+func (ps *productService) RevertProduct(ctx context.Context, prodId string, number int) error {
+	ps.log.Info("attempt to revert product")
+	ps.log.Debug("got product version", slog.String("product_id", prodId), slog.Int("version", number))
+	version, err := getVersion(ctx, ps.log, ps.versionRepo, models.VersionEntityProduct, prodId, number)
+	if err != nil {
 		return err
 	}
-	return nil
+	var snapshot models.Product
+	if err := json.Unmarshal(version.Snapshot, &snapshot); err != nil {
+		ps.log.Error("failed to read version", slog.String("error", err.Error()))
+		return err
+	}
+	patch := models.ProductForPatch{
+		Name: &snapshot.Name,
+		Description: &snapshot.Description,
+		// not nil prices and barcodes remove the ones the product didn't have in the version
+		Prices: []models.Price{},
+		Sku: &snapshot.Sku,
+		Barcodes: []string{},
+	}
+	if len(snapshot.Prices) != 0 {
+		patch.Prices = snapshot.Prices
+	}
+	if len(snapshot.Barcodes) != 0 {
+		patch.Barcodes = snapshot.Barcodes
+	}
+	// attributes are set as they were even if schemas of the categories have changed since
+	patch.Attributes = map[string]interface{}{}
+	if len(snapshot.Attributes) != 0 {
+		patch.Attributes = snapshot.Attributes
+	}
+	return ps.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		// not nil ids unlink categories the product didn't have in the version
+		categoriesId := []int{}
+		if len(snapshot.CategoryСodes) != 0 {
+			categoriesId, err = ps.categoryRepo.GetCategoriesIdByCodes(ctx, snapshot.CategoryСodes)
+			if err != nil {
+				ps.log.Error("failed to get categories id", slog.String("error", err.Error()))
+				return err
+			}
+		}
+		if len(snapshot.CategoryСodes) != len(categoriesId) {
+			ps.log.Error("failed to get categories id", slog.String("error", ErrCategoriesCodes.Error()))
+			return ErrCategoriesCodes
+		}
+		product, err := ps.productRepo.UpdateProductById(ctx, prodId, patch, categoriesId, 0)
+		if err != nil {
+			if existErr := productExistErr(err); existErr != nil {
+				ps.log.Error("failed to revert product", slog.String("error", existErr.Error()))
+				return existErr
+			}
+			if errors.Is(err, storage.ErrProductNotFound) {
+				ps.log.Warn("product not found", slog.String("prodcut_id", prodId))
+				return ErrProductNotFound
+			}
+			ps.log.Error("failed to revert product", slog.String("error", err.Error()))
+			return err
+		}
+		return ps.saveVersion(ctx, prodId, models.VersionActionRevert, product, number)
+	})
+}
+
+func (ps *productService) saveVersion(ctx context.Context, prodId string, action string, product models.Product, revertedTo int) error {
+	return saveProductVersion(ctx, ps.log, ps.versionRepo, prodId, action, product, revertedTo)
+}
+
+// saveProductVersion saves the product as the change has left it, ctx must carry the transaction of the change
+func saveProductVersion(ctx context.Context, log *slog.Logger, versionRepo versionRepo, prodId string, action string, product models.Product, revertedTo int) error {
+	version, err := newVersion(ctx, models.VersionEntityProduct, prodId, action, product)
+	version.RevertedTo = revertedTo
+	return saveVersion(ctx, log, versionRepo, version, err)
+}
+
+// saveCurrentProduct saves the version of the change of the product made by other repositories.
+// The change has counted the version of the product in the transaction of ctx, so the product row is locked
+// and the read in the same transaction returns it exactly as the change has left it
+func saveCurrentProduct(ctx context.Context, log *slog.Logger, productRepo productRepo, versionRepo versionRepo, prodId string) error {
+	product, err := productRepo.GetProductById(ctx, prodId)
+	if err != nil {
+		log.Error("failed to get product for version", slog.String("product_id", prodId), slog.String("error", err.Error()))
+		return err
+	}
+	return saveProductVersion(ctx, log, versionRepo, prodId, models.VersionActionUpdate, product, 0)
+}
+
+// ImportProducts saves products of the import and reports outcome of every row.
+// Rows with unknown category codes are invalid. In atomic mode nothing is saved
+// if any row is invalid or failed
+func (ps *productService) ImportProducts(ctx context.Context, rows []models.ImportRow, atomic bool) ([]models.ImportRowResult, error) {
+	ps.log.Info("attempt to import products")
+	ps.log.Debug("got import rows", slog.Int("count", len(rows)), slog.Bool("atomic", atomic))
+	results := make([]models.ImportRowResult, len(rows))
+	codesSet := make(map[string]struct{})
+	codes := make([]string, 0)
+	for idx, row := range rows {
+		results[idx] = models.ImportRowResult{
+			Row: row.Row,
+			Name: row.Product.Name,
+		}
+		for _, code := range row.Product.CategoryСodes {
+			if _, ok := codesSet[code]; !ok {
+				codesSet[code] = struct{}{}
+				codes = append(codes, code)
+			}
+		}
+	}
+	var (
+		validIdx []int
+		products []models.Product
+		saved []models.ImportRowResult
+	)
+	// categories are looked up in the transaction of the save, so they can not be deleted in between
+	err := ps.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		categoriesId := map[string]int{}
+		var schemas map[string][]models.Attribute
+		if len(codes) != 0 {
+			var err error
+			categoriesId, err = ps.categoryRepo.GetCategoriesIdMapByCodes(ctx, codes)
+			if err != nil {
+				ps.log.Error("failed to get categories id", slog.String("error", err.Error()))
+				return err
+			}
+			schemas, err = ps.categoryRepo.GetCategoriesAttributes(ctx, codes)
+			if err != nil {
+				ps.log.Error("failed to get attributes of categories", slog.String("error", err.Error()))
+				return err
+			}
+		}
+		validIdx, products = nil, nil
+		var catsIds [][]int
+		for idx, row := range rows {
+			var (
+				ids []int
+				unknown []string
+			)
+			for _, code := range row.Product.CategoryСodes {
+				id, ok := categoriesId[code]
+				if !ok {
+					unknown = append(unknown, code)
+					continue
+				}
+				ids = append(ids, id)
+			}
+			if len(unknown) != 0 {
+				results[idx].Status = models.ImportStatusInvalid
+				results[idx].Reason = fmt.Sprintf("%s: %s", ErrCategoriesCodes.Error(), strings.Join(unknown, ", "))
+				continue
+			}
+			if issues := validator.AttributeIssues(schemas, row.Product.CategoryСodes, row.Product.Attributes); len(issues) != 0 {
+				results[idx].Status = models.ImportStatusInvalid
+				results[idx].Reason = issuesReason(issues)
+				continue
+			}
+			validIdx = append(validIdx, idx)
+			products = append(products, row.Product)
+			catsIds = append(catsIds, ids)
+		}
+		if atomic && len(validIdx) != len(rows) {
+			ps.log.Warn("import rolled back: some rows are invalid")
+			for _, idx := range validIdx {
+				results[idx].Status = models.ImportStatusRolledBack
+			}
+			return nil
+		}
+		if len(products) == 0 {
+			return nil
+		}
+		var err error
+		saved, err = ps.productRepo.SaveProducts(ctx, products, catsIds, atomic)
+		if err != nil {
+			ps.log.Error("failed to save products", slog.String("error", err.Error()))
+			return err
+		}
+		// created products get their first versions in the transaction of the save
+		versions := make([]models.Version, 0, len(saved))
+		for i := range saved {
+			if saved[i].Status != models.ImportStatusCreated {
+				continue
+			}
+			product := products[i]
+			product.Id, _ = strconv.Atoi(saved[i].ProductId)
+			product.State = models.ProductStateDraft
+			version, err := newVersion(ctx, models.VersionEntityProduct, saved[i].ProductId, models.VersionActionCreate, product)
+			if err != nil {
+				ps.log.Error("failed to save versions", slog.String("key", saved[i].ProductId), slog.String("error", err.Error()))
+				return err
+			}
+			versions = append(versions, version)
+		}
+		if len(versions) == 0 {
+			return nil
+		}
+		if err := ps.versionRepo.SaveVersions(ctx, versions); err != nil {
+			ps.log.Error("failed to save versions", slog.String("error", err.Error()))
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, idx := range validIdx {
+		if saved == nil {
+			break
+		}
+		results[idx].Status = saved[i].Status
+		results[idx].Reason = saved[i].Reason
+		results[idx].ProductId = saved[i].ProductId
+	}
+	return results, nil
 }
 
+// DiffImport compares import rows with the current catalog without saving anything
+func (ps *productService) DiffImport(ctx context.Context, rows []models.ImportRow) (models.CatalogDiff, error) {
+	ps.log.Info("attempt to diff import")
+	ps.log.Debug("got import rows", slog.Int("count", len(rows)))
+	products, err := ps.productRepo.GetAllProducts(ctx, models.ProductFilter{})
+	if err != nil {
+		ps.log.Error("failed to get products", slog.String("error", err.Error()))
+		return models.CatalogDiff{}, err
+	}
+	categories, err := ps.categoryRepo.GetAllCategories(ctx)
+	if err != nil {
+		ps.log.Error("failed to get categories", slog.String("error", err.Error()))
+		return models.CatalogDiff{}, err
+	}
+	return catalogdiff.Compute(products, categories, rows, nil), nil
+}
 
+// issuesReason joins the issues of attributes into one reason of an import row
+func issuesReason(issues []models.AttributeIssue) string {
+	reasons := make([]string, len(issues))
+	for idx, issue := range issues {
+		if issue.Category == "" {
+			reasons[idx] = fmt.Sprintf("attribute %s: %s", issue.Attribute, issue.Reason)
+			continue
+		}
+		reasons[idx] = fmt.Sprintf("attribute %s of category %s: %s", issue.Attribute, issue.Category, issue.Reason)
+	}
+	return fmt.Sprintf("%s: %s", ErrAttributes.Error(), strings.Join(reasons, "; "))
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/EwvwGeN/cataloger/internal/actor"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=stockRepo --exported
+type stockRepo interface {
+	SaveWarehouse(ctx context.Context, warehouse models.Warehouse) error
+	GetWarehouses(ctx context.Context) ([]models.Warehouse, error)
+	GetStock(ctx context.Context, prodId string) ([]models.StockLevel, error)
+	AdjustStock(ctx context.Context, prodId string, change models.StockChange) (models.StockLevel, error)
+	ReserveStock(ctx context.Context, prodId string, change models.StockChange) (models.Reservation, error)
+	GetReservation(ctx context.Context, reservationId string) (models.Reservation, error)
+	ReleaseReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error)
+	CommitReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error)
+	GetStockMovements(ctx context.Context, prodId string) ([]models.StockMovement, error)
+}
+
+type stockService struct {
+	log *slog.Logger
+	stockRepo stockRepo
+	productRepo productRepo
+}
+
+func NewStockService(logger *slog.Logger, stRepo stockRepo, prRepo productRepo) *stockService {
+	return &stockService{
+		log: logger.With(slog.String("service", "stock")),
+		stockRepo: stRepo,
+		productRepo: prRepo,
+	}
+}
+
+func (ss *stockService) AddWarehouse(ctx context.Context, warehouse models.Warehouse) error {
+	ss.log.Info("attempt to add warehouse")
+	ss.log.Debug("got warehouse", slog.Any("warehouse", warehouse))
+	if err := ss.stockRepo.SaveWarehouse(ctx, warehouse); err != nil {
+		return ss.storageErr("failed to save warehouse", err)
+	}
+	return nil
+}
+
+func (ss *stockService) GetWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	ss.log.Info("attempt to get warehouses")
+	warehouses, err := ss.stockRepo.GetWarehouses(ctx)
+	if err != nil {
+		return nil, ss.storageErr("failed to get warehouses", err)
+	}
+	return warehouses, nil
+}
+
+// GetStock returns the stock of the product in every warehouse it was ever kept in
+func (ss *stockService) GetStock(ctx context.Context, prodId string) ([]models.StockLevel, error) {
+	ss.log.Info("attempt to get stock")
+	ss.log.Debug("got product id", slog.String("product_id", prodId))
+	if err := ss.productExist(ctx, prodId); err != nil {
+		return nil, err
+	}
+	levels, err := ss.stockRepo.GetStock(ctx, prodId)
+	if err != nil {
+		return nil, ss.storageErr("failed to get stock", err)
+	}
+	return levels, nil
+}
+
+// AdjustStock changes units of the product on hand by the signed quantity of the change,
+// ErrOutOfStock is returned if less units than reserved would be left
+func (ss *stockService) AdjustStock(ctx context.Context, prodId string, change models.StockChange) (models.StockLevel, error) {
+	ss.log.Info("attempt to adjust stock")
+	ss.log.Debug("got stock change", slog.String("product_id", prodId), slog.Any("change", change))
+	change.Actor = actor.From(ctx)
+	level, err := ss.stockRepo.AdjustStock(ctx, prodId, change)
+	if err != nil {
+		return models.StockLevel{}, ss.storageErr("failed to adjust stock", err)
+	}
+	return level, nil
+}
+
+// ReserveStock reserves available units of the product, ErrOutOfStock is returned if there are not enough of them
+func (ss *stockService) ReserveStock(ctx context.Context, prodId string, change models.StockChange) (models.Reservation, error) {
+	ss.log.Info("attempt to reserve stock")
+	ss.log.Debug("got stock change", slog.String("product_id", prodId), slog.Any("change", change))
+	change.Actor = actor.From(ctx)
+	reservation, err := ss.stockRepo.ReserveStock(ctx, prodId, change)
+	if err != nil {
+		return models.Reservation{}, ss.storageErr("failed to reserve stock", err)
+	}
+	return reservation, nil
+}
+
+func (ss *stockService) GetReservation(ctx context.Context, reservationId string) (models.Reservation, error) {
+	ss.log.Info("attempt to get reservation")
+	ss.log.Debug("got reservation id", slog.String("reservation_id", reservationId))
+	reservation, err := ss.stockRepo.GetReservation(ctx, reservationId)
+	if err != nil {
+		return models.Reservation{}, ss.storageErr("failed to get reservation", err)
+	}
+	return reservation, nil
+}
+
+// ReleaseReservation returns units of the active reservation to available ones
+func (ss *stockService) ReleaseReservation(ctx context.Context, reservationId string, reason string) (models.Reservation, error) {
+	ss.log.Info("attempt to release reservation")
+	ss.log.Debug("got reservation id", slog.String("reservation_id", reservationId))
+	reservation, err := ss.stockRepo.ReleaseReservation(ctx, reservationId, models.StockChange{Reason: reason, Actor: actor.From(ctx)})
+	if err != nil {
+		return models.Reservation{}, ss.storageErr("failed to release reservation", err)
+	}
+	return reservation, nil
+}
+
+// CommitReservation takes units of the active reservation out of the warehouse
+func (ss *stockService) CommitReservation(ctx context.Context, reservationId string, reason string) (models.Reservation, error) {
+	ss.log.Info("attempt to commit reservation")
+	ss.log.Debug("got reservation id", slog.String("reservation_id", reservationId))
+	reservation, err := ss.stockRepo.CommitReservation(ctx, reservationId, models.StockChange{Reason: reason, Actor: actor.From(ctx)})
+	if err != nil {
+		return models.Reservation{}, ss.storageErr("failed to commit reservation", err)
+	}
+	return reservation, nil
+}
+
+// GetStockMovements returns the ledger of every change of the stock of the product
+func (ss *stockService) GetStockMovements(ctx context.Context, prodId string) ([]models.StockMovement, error) {
+	ss.log.Info("attempt to get stock movements")
+	ss.log.Debug("got product id", slog.String("product_id", prodId))
+	if err := ss.productExist(ctx, prodId); err != nil {
+		return nil, err
+	}
+	movements, err := ss.stockRepo.GetStockMovements(ctx, prodId)
+	if err != nil {
+		return nil, ss.storageErr("failed to get stock movements", err)
+	}
+	return movements, nil
+}
+
+func (ss *stockService) productExist(ctx context.Context, prodId string) error {
+	if _, err := ss.productRepo.GetProductById(ctx, prodId); err != nil {
+		return ss.storageErr("failed to get product", err)
+	}
+	return nil
+}
+
+// storageErr returns the service error for the storage error of the stock
+func (ss *stockService) storageErr(msg string, err error) error {
+	var serviceErr error
+	switch {
+	case errors.Is(err, storage.ErrProductNotFound):
+		serviceErr = ErrProductNotFound
+	case errors.Is(err, storage.ErrWarehouseExist):
+		serviceErr = ErrWarehouseExist
+	case errors.Is(err, storage.ErrWarehouseNotFound):
+		serviceErr = ErrWarehouseNotFound
+	case errors.Is(err, storage.ErrReservationNotFound):
+		serviceErr = ErrReservationNotFound
+	case errors.Is(err, storage.ErrReservationClosed):
+		serviceErr = ErrReservationClosed
+	case errors.Is(err, storage.ErrOutOfStock):
+		serviceErr = ErrOutOfStock
+	default:
+		ss.log.Error(msg, slog.String("error", err.Error()))
+		return err
+	}
+	ss.log.Warn(msg, slog.String("error", serviceErr.Error()))
+	return serviceErr
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+const defaultPurgePeriod = time.Hour
+
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=trashRepo --exported
+type trashRepo interface {
+	GetTrash(ctx context.Context) (models.Trash, error)
+	RestoreProductById(ctx context.Context, prodId string) error
+	RestoreCategoryByCode(ctx context.Context, catCode string) error
+	PurgeTrash(ctx context.Context, before time.Time) (models.PurgeResult, error)
+}
+
+type trashService struct {
+	log *slog.Logger
+	cfg config.TrashConfig
+	trashRepo trashRepo
+}
+
+func NewTrashService(logger *slog.Logger, cfg config.TrashConfig, trashRepo trashRepo) *trashService {
+	return &trashService{
+		log: logger.With(slog.String("service", "trash")),
+		cfg: cfg,
+		trashRepo: trashRepo,
+	}
+}
+
+func (ts *trashService) GetTrash(ctx context.Context) (models.Trash, error) {
+	ts.log.Info("attempt to get trash")
+	trash, err := ts.trashRepo.GetTrash(ctx)
+	if err != nil {
+		ts.log.Error("failed to get trash", slog.String("error", err.Error()))
+		return models.Trash{}, err
+	}
+	return trash, nil
+}
+
+func (ts *trashService) RestoreProduct(ctx context.Context, prodId string) error {
+	ts.log.Info("attempt to restore product")
+	ts.log.Debug("got product id", slog.String("product_id", prodId))
+	if err := ts.trashRepo.RestoreProductById(ctx, prodId); err != nil {
+		if errors.Is(err, storage.ErrProductNotFound) {
+			ts.log.Warn("product not found in trash", slog.String("product_id", prodId))
+			return ErrProductNotFound
+		}
+		if existErr := productExistErr(err); existErr != nil {
+			ts.log.Error("failed to restore product", slog.String("error", existErr.Error()))
+			return existErr
+		}
+		ts.log.Error("failed to restore product", slog.String("product_id", prodId), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (ts *trashService) RestoreCategory(ctx context.Context, catCode string) error {
+	ts.log.Info("attempt to restore category")
+	ts.log.Debug("got category code", slog.String("code", catCode))
+	if err := ts.trashRepo.RestoreCategoryByCode(ctx, catCode); err != nil {
+		if errors.Is(err, storage.ErrCategoryNotFound) {
+			ts.log.Warn("category not found in trash", slog.String("code", catCode))
+			return ErrCategoryNotFound
+		}
+		if errors.Is(err, storage.ErrCategoryExist) {
+			ts.log.Error("failed to restore category", slog.String("error", ErrCategoryExist.Error()))
+			return ErrCategoryExist
+		}
+		ts.log.Error("failed to restore category", slog.String("code", catCode), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// Purge removes products and categories which are in the trash longer than the retention
+func (ts *trashService) Purge(ctx context.Context) (models.PurgeResult, error) {
+	ts.log.Info("attempt to purge trash")
+	result, err := ts.trashRepo.PurgeTrash(ctx, time.Now().Add(-ts.cfg.Retention))
+	if err != nil {
+		ts.log.Error("failed to purge trash", slog.String("error", err.Error()))
+		return models.PurgeResult{}, err
+	}
+	return result, nil
+}
+
+// Run purges the trash right away and then every purge period.
+// The returned channel is closed when purging has stopped after ctx cancellation
+func (ts *trashService) Run(ctx context.Context) (doneCh chan struct{}) {
+	doneCh = make(chan struct{})
+	if ts.cfg.Retention <= 0 {
+		ts.log.Warn("trash purge is disabled: empty retention")
+		close(doneCh)
+		return
+	}
+	period := ts.cfg.PurgePeriod
+	if period <= 0 {
+		period = defaultPurgePeriod
+	}
+	ts.log.Info("starting trash purge", slog.Duration("retention", ts.cfg.Retention), slog.Duration("period", period))
+	go func() {
+		defer close(doneCh)
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			if result, err := ts.Purge(ctx); err == nil && (result.Products != 0 || result.Categories != 0) {
+				ts.log.Info("trash purged", slog.Int("products", result.Products), slog.Int("categories", result.Categories))
+			}
+			select {
+			case <-ctx.Done():
+				ts.log.Info("trash purge stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return
+}
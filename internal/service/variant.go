@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/EwvwGeN/cataloger/internal/validator"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=variantRepo --exported
+type variantRepo interface {
+	SaveVariant(ctx context.Context, prodId string, variant models.Variant) (string, error)
+	UpdateVariantById(ctx context.Context, prodId, variantId string, variant models.Variant) error
+	DeleteVariantById(ctx context.Context, prodId, variantId string) error
+}
+
+type variantService struct {
+	log *slog.Logger
+	variantRepo variantRepo
+	productRepo productRepo
+	versionRepo versionRepo
+	txManager txManager
+}
+
+func NewVariantService(logger *slog.Logger, varRepo variantRepo, prRepo productRepo, verRepo versionRepo, txManager txManager) *variantService {
+	return &variantService{
+		log: logger.With(slog.String("service", "variant")),
+		variantRepo: varRepo,
+		productRepo: prRepo,
+		versionRepo: verRepo,
+		txManager: txManager,
+	}
+}
+
+// GetVariants returns options of the product and its variants
+func (vs *variantService) GetVariants(ctx context.Context, prodId string) ([]models.VariantOption, []models.Variant, error) {
+	vs.log.Info("attempt to get variants")
+	vs.log.Debug("got product id", slog.String("product_id", prodId))
+	product, err := vs.product(ctx, prodId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return product.Options, product.Variants, nil
+}
+
+func (vs *variantService) GetVariant(ctx context.Context, prodId, variantId string) (models.Variant, error) {
+	vs.log.Info("attempt to get variant")
+	vs.log.Debug("got variant id", slog.String("product_id", prodId), slog.String("variant_id", variantId))
+	product, err := vs.product(ctx, prodId)
+	if err != nil {
+		return models.Variant{}, err
+	}
+	return vs.variant(product, variantId)
+}
+
+// AddVariant saves the variant if its option values fit options of the product,
+// otherwise ErrVariantOptions is returned with the reason
+func (vs *variantService) AddVariant(ctx context.Context, prodId string, variant models.Variant) (string, error) {
+	vs.log.Info("attempt to add variant")
+	vs.log.Debug("got variant", slog.String("product_id", prodId), slog.Any("variant", variant))
+	var variantId string
+	err := vs.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		product, err := vs.product(ctx, prodId)
+		if err != nil {
+			return err
+		}
+		if err := vs.checkOptions(product, variant); err != nil {
+			return err
+		}
+		variantId, err = vs.variantRepo.SaveVariant(ctx, prodId, variant)
+		if err != nil {
+			return vs.storageErr("failed to save variant", err)
+		}
+		return saveCurrentProduct(ctx, vs.log, vs.productRepo, vs.versionRepo, prodId)
+	})
+	if err != nil {
+		return "", err
+	}
+	return variantId, nil
+}
+
+// EditVariant changes the fields of the variant that are set, the changed variant must fit options of the product
+func (vs *variantService) EditVariant(ctx context.Context, prodId, variantId string, variantUpdateData models.VariantForPatch) error {
+	vs.log.Info("attempt to update variant")
+	vs.log.Debug("got variant data", slog.String("product_id", prodId), slog.String("variant_id", variantId), slog.Any("variant", variantUpdateData))
+	return vs.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		product, err := vs.product(ctx, prodId)
+		if err != nil {
+			return err
+		}
+		variant, err := vs.variant(product, variantId)
+		if err != nil {
+			return err
+		}
+		if variantUpdateData.Sku != nil {
+			variant.Sku = *variantUpdateData.Sku
+		}
+		if variantUpdateData.Options != nil {
+			variant.Options = variantUpdateData.Options
+		}
+		if variantUpdateData.Prices != nil {
+			variant.Prices = variantUpdateData.Prices
+		}
+		if err := vs.checkOptions(product, variant); err != nil {
+			return err
+		}
+		if err := vs.variantRepo.UpdateVariantById(ctx, prodId, variantId, variant); err != nil {
+			return vs.storageErr("failed to update variant", err)
+		}
+		return saveCurrentProduct(ctx, vs.log, vs.productRepo, vs.versionRepo, prodId)
+	})
+}
+
+func (vs *variantService) DeleteVariant(ctx context.Context, prodId, variantId string) error {
+	vs.log.Info("attempt to delete variant")
+	vs.log.Debug("got variant id", slog.String("product_id", prodId), slog.String("variant_id", variantId))
+	return vs.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := vs.variantRepo.DeleteVariantById(ctx, prodId, variantId); err != nil {
+			return vs.storageErr("failed to delete variant", err)
+		}
+		return saveCurrentProduct(ctx, vs.log, vs.productRepo, vs.versionRepo, prodId)
+	})
+}
+
+func (vs *variantService) product(ctx context.Context, prodId string) (models.Product, error) {
+	product, err := vs.productRepo.GetProductById(ctx, prodId)
+	if err != nil {
+		if errors.Is(err, storage.ErrProductNotFound) {
+			vs.log.Warn("product not found", slog.String("prodcut_id", prodId))
+			return models.Product{}, ErrProductNotFound
+		}
+		vs.log.Error("failed to get product", slog.String("product_id", prodId), slog.String("error", err.Error()))
+		return models.Product{}, err
+	}
+	return product, nil
+}
+
+func (vs *variantService) variant(product models.Product, variantId string) (models.Variant, error) {
+	id, err := strconv.Atoi(variantId)
+	if err == nil {
+		for _, variant := range product.Variants {
+			if variant.Id == id {
+				return variant, nil
+			}
+		}
+	}
+	vs.log.Warn("variant not found", slog.Int("product_id", product.Id), slog.String("variant_id", variantId))
+	return models.Variant{}, ErrVariantNotFound
+}
+
+func (vs *variantService) checkOptions(product models.Product, variant models.Variant) error {
+	if reason := validator.VariantMismatchReason(product.Options, variant.Options); reason != "" {
+		vs.log.Warn("variant doesn't fit options", slog.Int("product_id", product.Id), slog.String("reason", reason))
+		return fmt.Errorf("%w: %s", ErrVariantOptions, reason)
+	}
+	return nil
+}
+
+// storageErr returns the service error for the storage error of the variant change
+func (vs *variantService) storageErr(msg string, err error) error {
+	var serviceErr error
+	switch {
+	case errors.Is(err, storage.ErrProductNotFound):
+		serviceErr = ErrProductNotFound
+	case errors.Is(err, storage.ErrVariantNotFound):
+		serviceErr = ErrVariantNotFound
+	case errors.Is(err, storage.ErrVariantExist):
+		serviceErr = ErrVariantExist
+	case errors.Is(err, storage.ErrVariantSkuExist):
+		serviceErr = ErrVariantSkuExist
+	default:
+		vs.log.Error(msg, slog.String("error", err.Error()))
+		return err
+	}
+	vs.log.Warn(msg, slog.String("error", serviceErr.Error()))
+	return serviceErr
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/EwvwGeN/cataloger/internal/actor"
+	"github.com/EwvwGeN/cataloger/internal/catalogdiff"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.40.3 --name=versionRepo --exported
+type versionRepo interface {
+	SaveVersions(ctx context.Context, versions []models.Version) error
+	GetVersions(ctx context.Context, entity, key string) ([]models.Version, error)
+	GetVersion(ctx context.Context, entity, key string, number int) (models.Version, error)
+}
+
+func newVersion(ctx context.Context, entity, key, action string, snapshot interface{}) (models.Version, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return models.Version{}, err
+	}
+	return models.Version{
+		Entity: entity,
+		Key: key,
+		Action: action,
+		Actor: actor.From(ctx),
+		Snapshot: data,
+	}, nil
+}
+
+// saveVersion saves the version of the change. It is called with the context of the transaction
+// making the change, so the change is rolled back by the returned error if its version is not saved
+func saveVersion(ctx context.Context, log *slog.Logger, repo versionRepo, version models.Version, err error) error {
+	if err == nil {
+		err = repo.SaveVersions(ctx, []models.Version{version})
+	}
+	if err != nil {
+		log.Error("failed to save version", slog.String("entity", version.Entity), slog.String("key", version.Key), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func getVersions(ctx context.Context, log *slog.Logger, repo versionRepo, entity, key string) ([]models.Version, error) {
+	log.Info("attempt to get versions")
+	log.Debug("got versions key", slog.String("entity", entity), slog.String("key", key))
+	versions, err := repo.GetVersions(ctx, entity, key)
+	if err != nil {
+		log.Error("failed to get versions", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return versions, nil
+}
+
+func getVersion(ctx context.Context, log *slog.Logger, repo versionRepo, entity, key string, number int) (models.Version, error) {
+	version, err := repo.GetVersion(ctx, entity, key, number)
+	if err != nil {
+		if errors.Is(err, storage.ErrVersionNotFound) {
+			log.Warn("version not found", slog.String("key", key), slog.Int("version", number))
+			return models.Version{}, ErrVersionNotFound
+		}
+		log.Error("failed to get version", slog.String("error", err.Error()))
+		return models.Version{}, err
+	}
+	return version, nil
+}
+
+func diffVersions(ctx context.Context, log *slog.Logger, repo versionRepo, entity, key string, from, to int) (models.VersionDiff, error) {
+	log.Info("attempt to diff versions")
+	log.Debug("got versions", slog.String("entity", entity), slog.String("key", key), slog.Int("from", from), slog.Int("to", to))
+	fromVersion, err := getVersion(ctx, log, repo, entity, key, from)
+	if err != nil {
+		return models.VersionDiff{}, err
+	}
+	toVersion, err := getVersion(ctx, log, repo, entity, key, to)
+	if err != nil {
+		return models.VersionDiff{}, err
+	}
+	diff, err := catalogdiff.Versions(fromVersion, toVersion)
+	if err != nil {
+		log.Error("failed to diff versions", slog.String("error", err.Error()))
+		return models.VersionDiff{}, err
+	}
+	return diff, nil
+}
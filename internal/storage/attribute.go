@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// GetCategoriesAttributes returns attribute schemas of the not deleted categories by their codes,
+// codes that do not exist are absent in the map
+func (pp *postgresProvider) GetCategoriesAttributes(ctx context.Context, catCodes []string) (map[string][]models.Attribute, error) {
+	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`
+SELECT code, attributes
+FROM "%s"
+WHERE code = ANY ($1) AND deleted_at IS NULL`,
+	pp.cfg.CatogoryTable),
+	catCodes)
+	if err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	schemas := make(map[string][]models.Attribute, len(catCodes))
+	for rows.Next() {
+		var (
+			code string
+			attributes []models.Attribute
+		)
+		if err := rows.Scan(&code, &attributes); err != nil {
+			return nil, dbErr(ErrQuery, err)
+		}
+		schemas[code] = attributes
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	return schemas, nil
+}
+
+// attributeFilter returns the condition on the product p for attributes of the filter,
+// its arguments are bound to placeholders from $n
+func (pp *postgresProvider) attributeFilter(filter models.ProductFilter, n int) (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	args := make([]interface{}, 0)
+	for _, attribute := range filter.Attributes {
+		args = append(args, attribute.Name)
+		key := n + len(args) - 1
+		// casts of not numbers are never evaluated, CASE keeps the order of checks
+		number := fmt.Sprintf("CASE WHEN jsonb_typeof(p.attributes->$%[1]d::text) = 'number' THEN (p.attributes->>$%[1]d::text)::numeric END", key)
+		if len(attribute.Values) != 0 {
+			args = append(args, attribute.Values, attributeNumbers(attribute.Values))
+			conditions = append(conditions, fmt.Sprintf("(p.attributes->>$%d::text = ANY ($%d::text[]) OR %s = ANY ($%d::numeric[]))",
+				key, n+len(args)-2, number, n+len(args)-1))
+		}
+		if attribute.Min != nil || attribute.Max != nil {
+			args = append(args, attribute.Min, attribute.Max)
+			conditions = append(conditions, fmt.Sprintf("(%[1]s IS NOT NULL AND ($%[2]d::numeric IS NULL OR %[1]s >= $%[2]d::numeric) AND ($%[3]d::numeric IS NULL OR %[1]s <= $%[3]d::numeric))",
+				number, n+len(args)-2, n+len(args)-1))
+		}
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// attributeNumbers returns the values that are numbers, they match number values of attributes
+func attributeNumbers(values []string) []float64 {
+	numbers := make([]float64, 0, len(values))
+	for _, value := range values {
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// productIdentifiers returns the columns with sku of the product p, empty if it has none,
+// and its barcodes as array, NULL if it has no barcodes
+func (pp *postgresProvider) productIdentifiers() string {
+	return fmt.Sprintf(`COALESCE(p.sku, '') as sku, (
+	SELECT array_agg(b.code ORDER BY b.code)
+	FROM "%s" as b
+	WHERE b.product_id = p.product_id
+) as barcodes`,
+	pp.cfg.BarcodeTable)
+}
+
+// productExistErr tells which unique column of the product is taken by the error,
+// it is nil if the error is not a unique violation
+func (pp *postgresProvider) productExistErr(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return nil
+	}
+	switch pgErr.ConstraintName {
+	case pp.cfg.ProductTable + "_sku_idx":
+		return ErrSkuExist
+	case pp.cfg.BarcodeTable + "_pkey":
+		return ErrBarcodeExist
+	}
+	return ErrProductExist
+}
+
+// nullSku saves empty sku as NULL, so products without sku don't conflict
+func nullSku(sku string) interface{} {
+	if sku == "" {
+		return nil
+	}
+	return sku
+}
+
+// insertBarcodes adds barcodes to the product by one statement
+func (pp *postgresProvider) insertBarcodes(ctx context.Context, transaction pgx.Tx, prodId interface{}, barcodes []string) error {
+	if len(barcodes) == 0 {
+		return nil
+	}
+	_, err := transaction.Exec(ctx, fmt.Sprintf(`
+INSERT INTO "%s" (code, product_id)
+SELECT code, $1::int FROM unnest($2::varchar[]) as code`,
+	pp.cfg.BarcodeTable),
+	prodId,
+	barcodes)
+	if err != nil {
+		if existErr := pp.productExistErr(err); existErr != nil {
+			return existErr
+		}
+		return dbErr(ErrQuery, err)
+	}
+	return nil
+}
+
+// replaceBarcodes sets exactly the barcodes to the product
+func (pp *postgresProvider) replaceBarcodes(ctx context.Context, transaction pgx.Tx, prodId string, barcodes []string) error {
+	_, err := transaction.Exec(ctx, fmt.Sprintf(`DELETE FROM "%s" WHERE product_id = $1`, pp.cfg.BarcodeTable), prodId)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	return pp.insertBarcodes(ctx, transaction, prodId, barcodes)
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// staging tables of bulk writes, rows are copied into them with COPY and saved by one statement
+const (
+	productStaging = "import_product"
+	productCategoryStaging = "import_product_category"
+	productPriceStaging = "import_product_price"
+	productBarcodeStaging = "import_product_barcode"
+	categoryStaging = "import_category"
+)
+
+// stage copies rows into the temporary table with the columns, the table is dropped at the end of the transaction.
+// It is emptied before the copy since the transaction may be the outer one of WithinTx which has staged rows before
+func stage(ctx context.Context, transaction pgx.Tx, table string, columns []string, definition string, rows [][]interface{}) error {
+	_, err := transaction.Exec(ctx, fmt.Sprintf(`
+CREATE TEMP TABLE IF NOT EXISTS "%s" (%s) ON COMMIT DROP;
+TRUNCATE "%s";`,
+	table,
+	definition,
+	table))
+	if err != nil {
+		return err
+	}
+	_, err = transaction.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+	return err
+}
@@ -11,12 +11,22 @@ import (
 )
 
 func (pp *postgresProvider) SaveCategory(ctx context.Context, category models.Category) error {
-	_, err := pp.dbConn.Exec(ctx, fmt.Sprintf(`INSERT INTO "%s" (name, code, description)
-VALUES($1,$2,$3);`,
-	pp.cfg.CatogoryTable),
+	attributes, err := jsonb(category.Attributes, len(category.Attributes) == 0)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	_, err = pp.dbConn.Exec(ctx, fmt.Sprintf(`
+WITH added AS (
+	INSERT INTO "%s" (name, code, description, attributes)
+	VALUES($1,$2,$3,$4)
+	RETURNING *
+)%s`,
+	pp.cfg.CatogoryTable,
+	pp.categoryEvent(models.EventCategoryCreated, "added as c")),
 	category.Name,
 	category.Code,
-	category.Description)
+	category.Description,
+	attributes)
 	if err == nil {
 		return nil
 	}
@@ -26,100 +36,241 @@ VALUES($1,$2,$3);`,
 			return ErrCategoryExist
 		}
 	}
-	return ErrQuery
+	return dbErr(ErrQuery, err)
 }
 
 func (pp *postgresProvider) InserOrGetCategiriesId(ctx context.Context, categories []models.Category) (map[string]int, error) {
+	var categoriesMap map[string]int
+	err := pp.dbConn.retry(ctx, func() error {
+		var err error
+		categoriesMap, err = pp.inserOrGetCategiriesId(ctx, categories)
+		return err
+	})
+	return categoriesMap, err
+}
+
+// inserOrGetCategiriesId copies categories into the staging table and inserts the missing ones by one statement,
+// the first category of repeated codes is inserted
+func (pp *postgresProvider) inserOrGetCategiriesId(ctx context.Context, categories []models.Category) (map[string]int, error) {
+	categoriesMap := make(map[string]int, len(categories))
+	if len(categories) == 0 {
+		return categoriesMap, nil
+	}
 	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return nil, ErrStartTx
+		return nil, dbErr(ErrStartTx, err)
 	}
-	categoriesMap := make(map[string]int, len(categories))
-	for _, catg := range categories {
-		var id int
-		err = transaction.QueryRow(ctx, fmt.Sprintf(`
-WITH ins AS(
+	categoryRows := make([][]interface{}, len(categories))
+	for idx, catg := range categories {
+		categoryRows[idx] = []interface{}{idx, catg.Name, catg.Code, catg.Description}
+	}
+	err = stage(ctx, transaction, categoryStaging, []string{"idx", "name", "code", "description"}, "idx int, name text, code text, description text", categoryRows)
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return nil, dbErr(ErrRollbackTx, err)
+		}
+		return nil, dbErr(ErrQuery, err)
+	}
+	rows, err := transaction.Query(ctx, fmt.Sprintf(`
+WITH input AS (
+	SELECT DISTINCT ON (code) idx, name, code, description
+	FROM "%s"
+	ORDER BY code, idx
+), ins AS (
 	INSERT INTO "%s" (name, code, description)
-			VALUES ($1, $2, $3) 
-	ON CONFLICT ("code") DO NOTHING
-	RETURNING category_id
+	SELECT name, code, description FROM input ORDER BY idx
+	ON CONFLICT ("code") WHERE deleted_at IS NULL DO NOTHING
+	RETURNING *
+), ev AS (%s
 )
-SELECT category_id FROM ins
+SELECT code, category_id FROM ins
 UNION
-	SELECT category_id FROM "%s" WHERE code = $2;`,
-		pp.cfg.CatogoryTable, pp.cfg.CatogoryTable),
-		catg.Name,
-		catg.Code,
-		catg.Description).
-		Scan(&id)
-		if err != nil {
-			if err := transaction.Rollback(ctx); err != nil {
-				return nil, ErrRollbackTx
-			}
-			return nil, ErrQuery
+	SELECT c.code, c.category_id
+	FROM "%s" as c
+	JOIN input as i ON i.code = c.code
+	WHERE c.deleted_at IS NULL;`,
+	categoryStaging,
+	pp.cfg.CatogoryTable,
+	pp.categoryEvent(models.EventCategoryCreated, "ins as c"),
+	pp.cfg.CatogoryTable))
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return nil, dbErr(ErrRollbackTx, err)
 		}
-		categoriesMap[catg.Code] = id
+		return nil, dbErr(ErrQuery, err)
+	}
+	for rows.Next() {
+		var (
+			code string
+			id int
+		)
+		if err := rows.Scan(&code, &id); err != nil {
+			rows.Close()
+			transaction.Rollback(ctx)
+			return nil, dbErr(ErrQuery, err)
+		}
+		categoriesMap[code] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return nil, dbErr(ErrRollbackTx, err)
+		}
+		return nil, dbErr(ErrQuery, err)
 	}
 	if err := transaction.Commit(ctx); err != nil {
-		return nil, ErrCommitTx
+		return nil, dbErr(ErrCommitTx, err)
 	}
 	return categoriesMap, nil
 }
 
 func (pp *postgresProvider) GetCategoryByCode(ctx context.Context, catCode string) (models.Category, error) {
-	row := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
-SELECT "name", "code", "description"
+	var out models.Category
+	err := pp.read(ctx, func(db *retryDB) error {
+		var err error
+		out, err = pp.getCategoryByCode(ctx, db, catCode)
+		return err
+	})
+	return out, err
+}
+
+// getCategoryByCode reads the category from db, which may be the transaction of a write to read the category it changes
+func (pp *postgresProvider) getCategoryByCode(ctx context.Context, db querier, catCode string) (models.Category, error) {
+	row := db.QueryRow(ctx, fmt.Sprintf(`
+SELECT "name", "code", "description", "attributes", "version"
 FROM "%s"
-WHERE "code"=$1;`,
+WHERE "code"=$1 AND deleted_at IS NULL;`,
 	pp.cfg.CatogoryTable),
 	catCode)
 	var (
 		category models.Category
 	)
-	err := row.Scan(&category.Name, &category.Code, &category.Description)
+	err := row.Scan(&category.Name, &category.Code, &category.Description, &category.Attributes, &category.Version)
 	if err != nil {
-		return models.Category{}, ErrQuery
+		return models.Category{}, dbErr(ErrQuery, err)
 	}
 	return category, nil
 }
 
+// GetCategoriesIdByCodes locks the found categories with FOR SHARE, inside WithinTx
+// they can not be deleted until the transaction ends
 func (pp *postgresProvider) GetCategoriesIdByCodes(ctx context.Context, catCodes []string) ([]int, error) {
 	row:= pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
 SELECT array_agg(category_id)
-FROM "%s"
-WHERE code = ANY ($1)`,
+FROM (
+	SELECT category_id
+	FROM "%s"
+	WHERE code = ANY ($1) AND deleted_at IS NULL
+	FOR SHARE
+) c`,
 	pp.cfg.CatogoryTable),
 	catCodes)
 	var outCategoriesId []int
 	err := row.Scan(&outCategoriesId)
 	if err != nil {
-		return nil, ErrQuery
+		return nil, dbErr(ErrQuery, err)
 	}
 	return outCategoriesId, nil
 }
 
-func (pp *postgresProvider) GetAllCategories(ctx context.Context) ([]models.Category, error) {
+// GetCategoriesIdMapByCodes returns ids of existing categories by their codes,
+// codes that do not exist are absent in the map. Found categories are locked like in GetCategoriesIdByCodes
+func (pp *postgresProvider) GetCategoriesIdMapByCodes(ctx context.Context, catCodes []string) (map[string]int, error) {
 	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`
-SELECT "name", "code", "description"
-FROM "%s"`,
+SELECT code, category_id
+FROM "%s"
+WHERE code = ANY ($1) AND deleted_at IS NULL
+FOR SHARE`,
+	pp.cfg.CatogoryTable),
+	catCodes)
+	if err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	categoriesId := make(map[string]int, len(catCodes))
+	for rows.Next() {
+		var (
+			code string
+			id int
+		)
+		if err := rows.Scan(&code, &id); err != nil {
+			return nil, dbErr(ErrQuery, err)
+		}
+		categoriesId[code] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	return categoriesId, nil
+}
+
+func (pp *postgresProvider) GetAllCategories(ctx context.Context) ([]models.Category, error) {
+	var out []models.Category
+	err := pp.read(ctx, func(db *retryDB) error {
+		var err error
+		out, err = pp.getAllCategories(ctx, db)
+		return err
+	})
+	return out, err
+}
+
+func (pp *postgresProvider) getAllCategories(ctx context.Context, db *retryDB) ([]models.Category, error) {
+	rows, err := db.Query(ctx, fmt.Sprintf(`
+SELECT "name", "code", "description", "attributes"
+FROM "%s"
+WHERE deleted_at IS NULL`,
 	pp.cfg.CatogoryTable))
 	if err != nil {
-		return nil, ErrQuery
+		return nil, dbErr(ErrQuery, err)
 	}
+	defer rows.Close()
 	var outCategorys []models.Category
 	for rows.Next() {
 		var category models.Category
-		err := rows.Scan(&category.Name, &category.Code, &category.Description)
+		err := rows.Scan(&category.Name, &category.Code, &category.Description, &category.Attributes)
 		if err != nil {
-			return nil, ErrQuery
+			return nil, dbErr(ErrQuery, err)
 		}
 		outCategorys = append(outCategorys, category)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
 	return outCategorys, nil
 }
 
-func (pp *postgresProvider) UpdateCategoryByCode(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch) error {
-	preparedQuery := fmt.Sprintf("UPDATE \"%s\" SET ", pp.cfg.CatogoryTable)
+// StreamCategories calls fn for every category reading rows one by one
+func (pp *postgresProvider) StreamCategories(ctx context.Context, fn func(models.Category) error) error {
+	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`
+SELECT "name", "code", "description", "attributes"
+FROM "%s"
+WHERE deleted_at IS NULL
+ORDER BY category_id`,
+	pp.cfg.CatogoryTable))
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var category models.Category
+		if err := rows.Scan(&category.Name, &category.Code, &category.Description, &category.Attributes); err != nil {
+			return dbErr(ErrQuery, err)
+		}
+		if err := fn(category); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	return nil
+}
+
+// UpdateCategoryByCode changes the fields that are set and counts the version of the category,
+// the attribute schema is replaced if it is not nil. Returns the changed category, the zero category if there is no one,
+// and ErrVersionConflict if version is not zero and the category has another one
+func (pp *postgresProvider) UpdateCategoryByCode(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch, version int) (models.Category, error) {
+	preparedQuery := fmt.Sprintf("UPDATE \"%s\" SET \"version\" = \"version\" + 1, ", pp.cfg.CatogoryTable)
 	// is it faster to use marshal to json and unmarshal to map[string]interface{} and then range it by for statement?
 	usedFields := 0
 	usedData := make([]interface{}, 0)
@@ -138,32 +289,154 @@ func (pp *postgresProvider) UpdateCategoryByCode(ctx context.Context, catCode st
 		usedFields++
 		usedData = append(usedData, *catUpdateData.Description)
 	}
+	if catUpdateData.Attributes != nil {
+		attributes, err := jsonb(catUpdateData.Attributes, len(catUpdateData.Attributes) == 0)
+		if err != nil {
+			return models.Category{}, dbErr(ErrQuery, err)
+		}
+		preparedQuery += fmt.Sprintf("\"attributes\" = $%d, ", usedFields+1)
+		usedFields++
+		usedData = append(usedData, attributes)
+	}
+	if usedFields == 0 {
+		return models.Category{}, ErrQuery
+	}
 	// the worst but fast solution
 	preparedQuery = preparedQuery[:len(preparedQuery)-2]
-	usedData = append(usedData, catCode)
-	_, err := pp.dbConn.Exec(ctx, fmt.Sprintf("%s WHERE \"code\" = $%d", preparedQuery, usedFields+1), usedData...)
+	usedData = append(usedData, catCode, version)
+	preparedQuery = fmt.Sprintf(`WITH updated AS (%s WHERE "code" = $%d AND deleted_at IS NULL AND ($%d = 0 OR "version" = $%d) RETURNING *),
+ev AS (%s
+)
+SELECT "name", "code", "description", "attributes", "version" FROM updated`,
+		preparedQuery, usedFields+1, usedFields+2, usedFields+2, pp.categoryEvent(models.EventCategoryUpdated, "updated as c"))
+	var category models.Category
+	err := pp.dbConn.QueryRow(ctx, preparedQuery, usedData...).Scan(&category.Name, &category.Code, &category.Description, &category.Attributes, &category.Version)
 	if err == nil {
-		return nil
+		return category, nil
 	}
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) {
-		if pgErr.Code == "23505" {
-			return ErrCategoryExist
+	if !errors.Is(err, pgx.ErrNoRows) {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23505" {
+				return models.Category{}, ErrCategoryExist
+			}
 		}
+		return models.Category{}, dbErr(ErrQuery, err)
 	}
-	return ErrQuery
+	if version == 0 {
+		return models.Category{}, nil
+	}
+	return models.Category{}, pp.categoryConflict(ctx, catCode)
 }
 
-func (pp *postgresProvider) DeleteCategoryBycode(ctx context.Context, catCode string) error {
-	_, err := pp.dbConn.Exec(ctx, fmt.Sprintf("DELETE FROM \"%s\" WHERE \"code\" = $1", pp.cfg.CatogoryTable), catCode)
+// categoryConflict is called when nothing is changed by the version check,
+// the category is either missing or has another version
+func (pp *postgresProvider) categoryConflict(ctx context.Context, catCode string) error {
+	var exist bool
+	err := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
+SELECT EXISTS (SELECT 1 FROM "%s" WHERE "code" = $1 AND deleted_at IS NULL)`,
+	pp.cfg.CatogoryTable),
+	catCode).Scan(&exist)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	if exist {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// DeleteCategoryBycode moves the category to the trash.
+// Returns ErrCategoryUsed if any not deleted product has this category and
+// ErrVersionConflict if version is not zero and the category has another one, otherwise the category
+// as it was before deletion or the zero category if there is no one
+func (pp *postgresProvider) DeleteCategoryBycode(ctx context.Context, catCode string, version int) (models.Category, error) {
+	var category models.Category
+	err := pp.dbConn.retry(ctx, func() error {
+		var err error
+		category, err = pp.deleteCategoryBycode(ctx, catCode, version)
+		return err
+	})
+	return category, err
+}
+
+func (pp *postgresProvider) deleteCategoryBycode(ctx context.Context, catCode string, version int) (models.Category, error) {
+	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.Category{}, dbErr(ErrStartTx, err)
+	}
+	// the row lock waits for transactions linking products to the category with FOR SHARE
+	var current int
+	err = transaction.QueryRow(ctx, fmt.Sprintf(`
+SELECT version FROM "%s"
+WHERE "code" = $1 AND deleted_at IS NULL
+FOR UPDATE`,
+	pp.cfg.CatogoryTable),
+	catCode).Scan(&current)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Category{}, dbErr(ErrRollbackTx, err)
+		}
+		return models.Category{}, dbErr(ErrQuery, err)
+	}
+	// missing category is not deleted like without the version
+	if err == nil && version != 0 && current != version {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Category{}, dbErr(ErrRollbackTx, err)
+		}
+		return models.Category{}, ErrVersionConflict
+	}
+	// the category is returned as it is before deletion, the missing one as the zero category
+	var category models.Category
 	if err == nil {
-		return nil
+		category, err = pp.getCategoryByCode(ctx, transaction, catCode)
+		if err != nil {
+			transaction.Rollback(ctx)
+			return models.Category{}, err
+		}
 	}
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) {
-		if pgErr.Code == "23503" {
-			return ErrCategoryUsed
+	var used bool
+	err = transaction.QueryRow(ctx, fmt.Sprintf(`
+SELECT EXISTS (
+	SELECT 1
+	FROM "%s" as c
+	JOIN "%s" as pc ON pc.category_id = c.category_id
+	JOIN "%s" as p ON p.product_id = pc.product_id
+	WHERE c.code = $1 AND c.deleted_at IS NULL AND p.deleted_at IS NULL
+)`,
+	pp.cfg.CatogoryTable,
+	pp.cfg.ProductCategoryTable,
+	pp.cfg.ProductTable),
+	catCode).Scan(&used)
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Category{}, dbErr(ErrRollbackTx, err)
 		}
+		return models.Category{}, dbErr(ErrQuery, err)
 	}
-	return nil
+	if used {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Category{}, dbErr(ErrRollbackTx, err)
+		}
+		return models.Category{}, ErrCategoryUsed
+	}
+	_, err = transaction.Exec(ctx, fmt.Sprintf(`
+WITH deleted AS (
+	UPDATE "%s" SET deleted_at = now()
+	WHERE "code" = $1 AND deleted_at IS NULL
+	RETURNING *
+)%s`,
+	pp.cfg.CatogoryTable,
+	pp.categoryEvent(models.EventCategoryDeleted, "deleted as c")),
+	catCode)
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Category{}, dbErr(ErrRollbackTx, err)
+		}
+		return models.Category{}, dbErr(ErrQuery, err)
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return models.Category{}, dbErr(ErrCommitTx, err)
+	}
+	return category, nil
 }
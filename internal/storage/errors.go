@@ -7,9 +7,31 @@ var (
 	ErrCategoryExist = errors.New("category with this code already exist")
 	ErrCategoryUsed = errors.New("category used")
 	ErrProductExist = errors.New("product with this name already exist")
+	ErrSkuExist = errors.New("product with this sku already exist")
+	ErrBarcodeExist = errors.New("product with this barcode already exist")
+	ErrVariantExist = errors.New("variant with these options already exist")
+	ErrVariantSkuExist = errors.New("variant with this sku already exist")
+	ErrVariantNotFound = errors.New("variant with this id not found")
+	ErrMediaNotFound = errors.New("media with this id not found")
+	// ErrMediaOrder is returned if the new order doesn't have every media of the product exactly once
+	ErrMediaOrder = errors.New("media ids don't match media of the product")
 	ErrProductNotFound = errors.New("product with this id not found")
+	ErrCategoryNotFound = errors.New("category with this code not found")
+	ErrWarehouseExist = errors.New("warehouse with this code already exist")
+	ErrWarehouseNotFound = errors.New("warehouse with this code not found")
+	ErrReservationNotFound = errors.New("reservation with this id not found")
+	ErrReservationClosed = errors.New("reservation is already released or committed")
+	// ErrOutOfStock is returned if the change would leave less units on hand than reserved or reserve more than available
+	ErrOutOfStock = errors.New("not enough stock")
+	ErrJobNotFound = errors.New("job with this id not found")
+	ErrVersionNotFound = errors.New("version not found")
+	ErrVersionConflict = errors.New("version of the row has changed")
+	// ErrProductState is returned if the product is not in the state the transition starts from
+	ErrProductState = errors.New("state of the product has changed")
 	ErrStartTx = errors.New("failed to begin transaction")
 	ErrCommitTx = errors.New("error while commiting transaction")
 	ErrRollbackTx = errors.New("failed to rollback transaction")
 	ErrQuery = errors.New("error while executing query")
+	ErrUnavailable = errors.New("database is unavailable")
+	ErrMigration = errors.New("migration error")
 )
\ No newline at end of file
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgx/v4"
+)
+
+const jobColumns = `job_id, kind, state, content_type, payload_path, atomic, cancel_requested,
+processed, created, skipped, invalid, failed, error, report::text, created_by,
+created_at, started_at, finished_at`
+
+func (pp *postgresProvider) SaveJob(ctx context.Context, job models.Job) (string, error) {
+	var id int
+	err := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
+INSERT INTO "%s" (kind, state, content_type, payload_path, atomic, created_by)
+VALUES($1,$2,$3,$4,$5,$6)
+RETURNING job_id;`,
+	pp.cfg.JobTable),
+	job.Kind,
+	job.State,
+	job.ContentType,
+	job.PayloadPath,
+	job.Atomic,
+	job.CreatedBy).Scan(&id)
+	if err != nil {
+		return "", dbErr(ErrQuery, err)
+	}
+	return strconv.Itoa(id), nil
+}
+
+func (pp *postgresProvider) GetJobById(ctx context.Context, jobId string) (models.Job, error) {
+	row := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
+SELECT %s
+FROM "%s"
+WHERE job_id = $1`,
+	jobColumns,
+	pp.cfg.JobTable),
+	jobId)
+	job, err := scanJob(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Job{}, ErrJobNotFound
+		}
+		return models.Job{}, dbErr(ErrQuery, err)
+	}
+	return job, nil
+}
+
+// ClaimQueuedJob moves the oldest queued job to running state and returns it.
+// Locked rows are skipped, so several workers never get the same job
+func (pp *postgresProvider) ClaimQueuedJob(ctx context.Context) (models.Job, error) {
+	row := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
+UPDATE "%s" SET state = $1, started_at = now()
+WHERE job_id = (
+	SELECT job_id FROM "%s"
+	WHERE state = $2
+	ORDER BY job_id
+	FOR UPDATE SKIP LOCKED
+	LIMIT 1
+)
+RETURNING %s`,
+	pp.cfg.JobTable,
+	pp.cfg.JobTable,
+	jobColumns),
+	models.JobStateRunning,
+	models.JobStateQueued)
+	job, err := scanJob(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Job{}, ErrJobNotFound
+		}
+		return models.Job{}, dbErr(ErrQuery, err)
+	}
+	return job, nil
+}
+
+// UpdateJobProgress saves counters of the running job and reports whether its cancellation was requested
+func (pp *postgresProvider) UpdateJobProgress(ctx context.Context, jobId string, counters models.JobCounters) (bool, error) {
+	var cancelRequested bool
+	err := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
+UPDATE "%s" SET processed = $1, created = $2, skipped = $3, invalid = $4, failed = $5
+WHERE job_id = $6
+RETURNING cancel_requested`,
+	pp.cfg.JobTable),
+	counters.Processed,
+	counters.Created,
+	counters.Skipped,
+	counters.Invalid,
+	counters.Failed,
+	jobId).Scan(&cancelRequested)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrJobNotFound
+		}
+		return false, dbErr(ErrQuery, err)
+	}
+	return cancelRequested, nil
+}
+
+// FinishJob sets the final state of the job with its counters, error and report
+func (pp *postgresProvider) FinishJob(ctx context.Context, job models.Job) error {
+	var report *string
+	if len(job.Report) != 0 {
+		data, err := json.Marshal(job.Report)
+		if err != nil {
+			return err
+		}
+		reportStr := string(data)
+		report = &reportStr
+	}
+	var jobErr *string
+	if job.Error != "" {
+		jobErr = &job.Error
+	}
+	_, err := pp.dbConn.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET state = $1, processed = $2, created = $3, skipped = $4, invalid = $5, failed = $6,
+error = $7, report = $8::jsonb, finished_at = now()
+WHERE job_id = $9`,
+	pp.cfg.JobTable),
+	job.State,
+	job.Counters.Processed,
+	job.Counters.Created,
+	job.Counters.Skipped,
+	job.Counters.Invalid,
+	job.Counters.Failed,
+	jobErr,
+	report,
+	job.Id)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	return nil
+}
+
+// RequestJobCancel cancels queued job right away and marks running job to be cancelled by its worker.
+// Returns the job state after the request
+func (pp *postgresProvider) RequestJobCancel(ctx context.Context, jobId string) (string, error) {
+	var state string
+	err := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
+UPDATE "%s" SET
+	cancel_requested = state IN ($1, $2),
+	state = CASE WHEN state = $1 THEN $3 ELSE state END,
+	finished_at = CASE WHEN state = $1 THEN now() ELSE finished_at END
+WHERE job_id = $4
+RETURNING state`,
+	pp.cfg.JobTable),
+	models.JobStateQueued,
+	models.JobStateRunning,
+	models.JobStateCancelled,
+	jobId).Scan(&state)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrJobNotFound
+		}
+		return "", dbErr(ErrQuery, err)
+	}
+	return state, nil
+}
+
+// RecoverRunningJobs handles jobs left in running state by stopped process:
+// they are queued again with reset counters if resume is true, otherwise failed
+func (pp *postgresProvider) RecoverRunningJobs(ctx context.Context, resume bool) (int, error) {
+	query := fmt.Sprintf(`
+UPDATE "%s" SET state = $1, error = $2, finished_at = now()
+WHERE state = $3`,
+	pp.cfg.JobTable)
+	args := []interface{}{models.JobStateFailed, "interrupted by restart", models.JobStateRunning}
+	if resume {
+		query = fmt.Sprintf(`
+UPDATE "%s" SET state = CASE WHEN cancel_requested THEN $1 ELSE $2 END,
+finished_at = CASE WHEN cancel_requested THEN now() ELSE NULL END,
+processed = 0, created = 0, skipped = 0, invalid = 0, failed = 0, started_at = NULL
+WHERE state = $3`,
+		pp.cfg.JobTable)
+		args = []interface{}{models.JobStateCancelled, models.JobStateQueued, models.JobStateRunning}
+	}
+	tag, err := pp.dbConn.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, dbErr(ErrQuery, err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func scanJob(row pgx.Row) (models.Job, error) {
+	var (
+		job models.Job
+		id int
+		jobErr *string
+		report *string
+		createdBy *string
+	)
+	err := row.Scan(&id, &job.Kind, &job.State, &job.ContentType, &job.PayloadPath, &job.Atomic, &job.CancelRequested,
+		&job.Counters.Processed, &job.Counters.Created, &job.Counters.Skipped, &job.Counters.Invalid, &job.Counters.Failed,
+		&jobErr, &report, &createdBy,
+		&job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if err != nil {
+		return models.Job{}, err
+	}
+	job.Id = strconv.Itoa(id)
+	if jobErr != nil {
+		job.Error = *jobErr
+	}
+	if createdBy != nil {
+		job.CreatedBy = *createdBy
+	}
+	if report != nil {
+		if err := json.Unmarshal([]byte(*report), &job.Report); err != nil {
+			return models.Job{}, err
+		}
+	}
+	return job, nil
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// productMedia returns the column with media of the product p as json array ordered by position,
+// NULL if the product has none
+func (pp *postgresProvider) productMedia() string {
+	return fmt.Sprintf(`(
+	SELECT json_agg(json_build_object(
+		'id', m.media_id, 'url', m.url, 'thumbnail_url', m.thumbnail_url, 'content_type', m.content_type,
+		'size', m.size, 'width', m.width, 'height', m.height, 'position', m.position,
+		'primary', m.is_primary, 'created_at', m.created_at
+	) ORDER BY m.position)
+	FROM "%s" as m
+	WHERE m.product_id = p.product_id
+) as media`,
+	pp.cfg.MediaTable)
+}
+
+// SaveMedia adds the media after the other media of the not deleted product and counts the version of the product.
+// The first media of the product is primary, the media replaces the primary one if it has Primary set
+func (pp *postgresProvider) SaveMedia(ctx context.Context, prodId string, media models.Media) (models.Media, error) {
+	var saved models.Media
+	err := pp.dbConn.retry(ctx, func() error {
+		saved = media
+		return pp.changeProduct(ctx, prodId, func(transaction pgx.Tx) error {
+			err := transaction.QueryRow(ctx, fmt.Sprintf(`
+INSERT INTO "%s" (product_id, file_key, thumbnail_key, url, thumbnail_url, content_type, size, width, height, position, is_primary)
+SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE(MAX(position) + 1, 0), COUNT(*) = 0
+FROM "%s" WHERE product_id = $1
+RETURNING media_id, position, is_primary, created_at`,
+			pp.cfg.MediaTable,
+			pp.cfg.MediaTable),
+			prodId,
+			media.Key,
+			media.ThumbnailKey,
+			media.Url,
+			media.ThumbnailUrl,
+			media.ContentType,
+			media.Size,
+			media.Width,
+			media.Height).Scan(&saved.Id, &saved.Position, &saved.Primary, &saved.CreatedAt)
+			if err != nil {
+				return dbErr(ErrQuery, err)
+			}
+			if media.Primary && !saved.Primary {
+				saved.Primary = true
+				return pp.setPrimaryMedia(ctx, transaction, prodId, saved.Id)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return models.Media{}, err
+	}
+	return saved, nil
+}
+
+// DeleteMediaById removes the media of the not deleted product and counts the version of the product.
+// Media after it are moved up, the first one becomes primary if the removed one was. The removed media
+// is returned with the keys of its files
+func (pp *postgresProvider) DeleteMediaById(ctx context.Context, prodId, mediaId string) (models.Media, error) {
+	var media models.Media
+	err := pp.dbConn.retry(ctx, func() error {
+		return pp.changeProduct(ctx, prodId, func(transaction pgx.Tx) error {
+			err := transaction.QueryRow(ctx, fmt.Sprintf(`
+DELETE FROM "%s" WHERE media_id = $1 AND product_id = $2
+RETURNING media_id, file_key, thumbnail_key, url, thumbnail_url, content_type, size, width, height, position, is_primary, created_at`,
+			pp.cfg.MediaTable),
+			mediaId,
+			prodId).Scan(&media.Id, &media.Key, &media.ThumbnailKey, &media.Url, &media.ThumbnailUrl, &media.ContentType,
+				&media.Size, &media.Width, &media.Height, &media.Position, &media.Primary, &media.CreatedAt)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return ErrMediaNotFound
+				}
+				return dbErr(ErrQuery, err)
+			}
+			_, err = transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET position = position - 1
+WHERE product_id = $1 AND position > $2`,
+			pp.cfg.MediaTable),
+			prodId,
+			media.Position)
+			if err != nil {
+				return dbErr(ErrQuery, err)
+			}
+			if !media.Primary {
+				return nil
+			}
+			_, err = transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET is_primary = true
+WHERE product_id = $1 AND position = 0`,
+			pp.cfg.MediaTable),
+			prodId)
+			if err != nil {
+				return dbErr(ErrQuery, err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return models.Media{}, err
+	}
+	return media, nil
+}
+
+// SetMediaOrder sets positions of media of the not deleted product by their order in mediaIds
+// and counts the version of the product. ErrMediaOrder is returned if mediaIds don't have
+// every media of the product exactly once
+func (pp *postgresProvider) SetMediaOrder(ctx context.Context, prodId string, mediaIds []int) error {
+	return pp.dbConn.retry(ctx, func() error {
+		return pp.changeProduct(ctx, prodId, func(transaction pgx.Tx) error {
+			var count int
+			err := transaction.QueryRow(ctx, fmt.Sprintf(`
+SELECT COUNT(*) FROM "%s" WHERE product_id = $1`,
+			pp.cfg.MediaTable),
+			prodId).Scan(&count)
+			if err != nil {
+				return dbErr(ErrQuery, err)
+			}
+			if count != len(mediaIds) {
+				return ErrMediaOrder
+			}
+			tag, err := transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" as m SET position = o.position - 1
+FROM unnest($2::int[]) WITH ORDINALITY as o(media_id, position)
+WHERE m.media_id = o.media_id AND m.product_id = $1`,
+			pp.cfg.MediaTable),
+			prodId,
+			mediaIds)
+			if err != nil {
+				return dbErr(ErrQuery, err)
+			}
+			// a repeated id updates its row once, so some media are left out
+			if tag.RowsAffected() != int64(len(mediaIds)) {
+				return ErrMediaOrder
+			}
+			return nil
+		})
+	})
+}
+
+// SetPrimaryMedia makes the media primary instead of the current one and counts the version of the product
+func (pp *postgresProvider) SetPrimaryMedia(ctx context.Context, prodId, mediaId string) error {
+	return pp.dbConn.retry(ctx, func() error {
+		return pp.changeProduct(ctx, prodId, func(transaction pgx.Tx) error {
+			return pp.setPrimaryMedia(ctx, transaction, prodId, mediaId)
+		})
+	})
+}
+
+// setPrimaryMedia unsets the current primary media before the new one is set,
+// so the unique index of primary media is never violated in between
+func (pp *postgresProvider) setPrimaryMedia(ctx context.Context, transaction pgx.Tx, prodId string, mediaId interface{}) error {
+	_, err := transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET is_primary = false
+WHERE product_id = $1 AND is_primary AND media_id <> $2`,
+	pp.cfg.MediaTable),
+	prodId,
+	mediaId)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	tag, err := transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET is_primary = true
+WHERE product_id = $1 AND media_id = $2`,
+	pp.cfg.MediaTable),
+	prodId,
+	mediaId)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMediaNotFound
+	}
+	return nil
+}
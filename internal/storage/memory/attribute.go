@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// GetCategoriesAttributes returns attribute schemas of the not deleted categories by their codes,
+// codes that do not exist are absent in the map
+func (mp *memoryProvider) GetCategoriesAttributes(ctx context.Context, catCodes []string) (map[string][]models.Attribute, error) {
+	defer mp.rlock(ctx)()
+	schemas := make(map[string][]models.Attribute, len(catCodes))
+	for _, code := range catCodes {
+		if id, ok := mp.categoryIds[code]; ok {
+			schemas[code] = mp.categories[id].Attributes
+		}
+	}
+	return schemas, nil
+}
+
+// schemaOf returns the copy of the attribute schema, nil for no attributes like the other providers read them
+func schemaOf(attributes []models.Attribute) []models.Attribute {
+	if len(attributes) == 0 {
+		return nil
+	}
+	return append([]models.Attribute(nil), attributes...)
+}
+
+// attributesOf returns the copy of attribute values passed through json,
+// so numbers are float64 like the other providers read them
+func attributesOf(values map[string]interface{}) (map[string]interface{}, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, storage.ErrQuery
+	}
+	return out, nil
+}
+
+// matchAttributes reports whether the product fits all attribute filters,
+// strings and booleans are compared by text and numbers by value
+func matchAttributes(product models.Product, filter models.ProductFilter) bool {
+	for _, attribute := range filter.Attributes {
+		value, ok := product.Attributes[attribute.Name]
+		if !ok {
+			return false
+		}
+		number, isNumber := value.(float64)
+		if len(attribute.Values) != 0 {
+			found := false
+			for _, want := range attribute.Values {
+				switch typed := value.(type) {
+				case string:
+					found = typed == want
+				case bool:
+					found = strconv.FormatBool(typed) == want
+				case float64:
+					wantNumber, err := strconv.ParseFloat(want, 64)
+					found = err == nil && wantNumber == typed
+				}
+				if found {
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		if attribute.Min != nil || attribute.Max != nil {
+			if !isNumber || (attribute.Min != nil && number < *attribute.Min) || (attribute.Max != nil && number > *attribute.Max) {
+				return false
+			}
+		}
+	}
+	return true
+}
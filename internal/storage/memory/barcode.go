@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// barcodesOf checks barcodes of the product like the postgres table does and returns their sorted copy,
+// barcodes of the product with the id may be taken again. Must be called under the lock
+func (mp *memoryProvider) barcodesOf(id int, barcodes []string) ([]string, error) {
+	if len(barcodes) == 0 {
+		return nil, nil
+	}
+	out := make([]string, len(barcodes))
+	seen := make(map[string]struct{}, len(barcodes))
+	for idx, code := range barcodes {
+		if !barcodeFormat(code) {
+			return nil, storage.ErrQuery
+		}
+		if owner, ok := mp.barcodeIds[code]; ok && owner != id {
+			return nil, storage.ErrBarcodeExist
+		}
+		if _, ok := seen[code]; ok {
+			return nil, storage.ErrBarcodeExist
+		}
+		seen[code] = struct{}{}
+		out[idx] = code
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func barcodeFormat(code string) bool {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
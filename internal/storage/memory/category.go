@@ -0,0 +1,212 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+func (mp *memoryProvider) SaveCategory(ctx context.Context, category models.Category) error {
+	defer mp.lock(ctx)()
+	_, err := mp.insertCategory(category)
+	return err
+}
+
+// insertCategory must be called under the write lock
+func (mp *memoryProvider) insertCategory(category models.Category) (int, error) {
+	if category.Name == "" || category.Code == "" {
+		return 0, storage.ErrQuery
+	}
+	if _, ok := mp.categoryIds[category.Code]; ok {
+		return 0, storage.ErrCategoryExist
+	}
+	mp.lastCategoryId++
+	category.Attributes = schemaOf(category.Attributes)
+	mp.categories[mp.lastCategoryId] = category
+	mp.categoryIds[category.Code] = mp.lastCategoryId
+	mp.categoryVersions[mp.lastCategoryId] = 1
+	mp.categoryEvent(models.EventCategoryCreated, mp.lastCategoryId)
+	return mp.lastCategoryId, nil
+}
+
+func (mp *memoryProvider) InserOrGetCategiriesId(ctx context.Context, categories []models.Category) (map[string]int, error) {
+	defer mp.lock(ctx)()
+	categoriesMap := make(map[string]int, len(categories))
+	var inserted []int
+	events := len(mp.events)
+	for _, catg := range categories {
+		if id, ok := mp.categoryIds[catg.Code]; ok {
+			categoriesMap[catg.Code] = id
+			continue
+		}
+		id, err := mp.insertCategory(catg)
+		if err != nil {
+			// the whole call is one transaction
+			for _, id := range inserted {
+				delete(mp.categoryIds, mp.categories[id].Code)
+				delete(mp.categories, id)
+				delete(mp.categoryVersions, id)
+			}
+			mp.events = mp.events[:events]
+			return nil, err
+		}
+		inserted = append(inserted, id)
+		categoriesMap[catg.Code] = id
+	}
+	return categoriesMap, nil
+}
+
+// GetCategoryByCode returns ErrQuery if category not found like postgres provider does
+func (mp *memoryProvider) GetCategoryByCode(ctx context.Context, catCode string) (models.Category, error) {
+	defer mp.rlock(ctx)()
+	id, ok := mp.categoryIds[catCode]
+	if !ok {
+		return models.Category{}, storage.ErrQuery
+	}
+	return mp.currentCategory(id), nil
+}
+
+// currentCategory returns the category with its version, must be called under the lock
+func (mp *memoryProvider) currentCategory(id int) models.Category {
+	category := mp.categories[id]
+	category.Version = mp.categoryVersions[id]
+	return category
+}
+
+func (mp *memoryProvider) GetCategoriesIdByCodes(ctx context.Context, catCodes []string) ([]int, error) {
+	defer mp.rlock(ctx)()
+	var outCategoriesId []int
+	for _, id := range mp.categoriesIdByCodes(catCodes) {
+		outCategoriesId = append(outCategoriesId, id)
+	}
+	sort.Ints(outCategoriesId)
+	return outCategoriesId, nil
+}
+
+// GetCategoriesIdMapByCodes returns ids of existing categories by their codes,
+// codes that do not exist are absent in the map
+func (mp *memoryProvider) GetCategoriesIdMapByCodes(ctx context.Context, catCodes []string) (map[string]int, error) {
+	defer mp.rlock(ctx)()
+	return mp.categoriesIdByCodes(catCodes), nil
+}
+
+func (mp *memoryProvider) categoriesIdByCodes(catCodes []string) map[string]int {
+	categoriesId := make(map[string]int, len(catCodes))
+	for _, code := range catCodes {
+		if id, ok := mp.categoryIds[code]; ok {
+			categoriesId[code] = id
+		}
+	}
+	return categoriesId
+}
+
+func (mp *memoryProvider) GetAllCategories(ctx context.Context) ([]models.Category, error) {
+	defer mp.rlock(ctx)()
+	var outCategorys []models.Category
+	for _, id := range sortedKeys(mp.categories) {
+		if _, ok := mp.deletedCategories[id]; ok {
+			continue
+		}
+		outCategorys = append(outCategorys, mp.categories[id])
+	}
+	return outCategorys, nil
+}
+
+// StreamCategories calls fn for every category, fn is called without the lock
+// on the snapshot of categories, so it may use the provider itself
+func (mp *memoryProvider) StreamCategories(ctx context.Context, fn func(models.Category) error) error {
+	categories, _ := mp.GetAllCategories(ctx)
+	for _, category := range categories {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(category); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateCategoryByCode changes the fields that are set and counts the version of the category,
+// the attribute schema is replaced if it is not nil. Returns the changed category, the zero category if there is no one,
+// and ErrVersionConflict if version is not zero and the category has another one
+func (mp *memoryProvider) UpdateCategoryByCode(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch, version int) (models.Category, error) {
+	if catUpdateData.Name == nil && catUpdateData.Code == nil && catUpdateData.Description == nil && catUpdateData.Attributes == nil {
+		return models.Category{}, storage.ErrQuery
+	}
+	if (catUpdateData.Name != nil && *catUpdateData.Name == "") || (catUpdateData.Code != nil && *catUpdateData.Code == "") {
+		return models.Category{}, storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	id, ok := mp.categoryIds[catCode]
+	if !ok {
+		return models.Category{}, nil
+	}
+	if version != 0 && version != mp.categoryVersions[id] {
+		return models.Category{}, storage.ErrVersionConflict
+	}
+	category := mp.categories[id]
+	if catUpdateData.Code != nil && *catUpdateData.Code != catCode {
+		if _, ok := mp.categoryIds[*catUpdateData.Code]; ok {
+			return models.Category{}, storage.ErrCategoryExist
+		}
+		delete(mp.categoryIds, catCode)
+		mp.categoryIds[*catUpdateData.Code] = id
+		category.Code = *catUpdateData.Code
+	}
+	if catUpdateData.Name != nil {
+		category.Name = *catUpdateData.Name
+	}
+	if catUpdateData.Description != nil {
+		category.Description = *catUpdateData.Description
+	}
+	if catUpdateData.Attributes != nil {
+		category.Attributes = schemaOf(catUpdateData.Attributes)
+	}
+	mp.categories[id] = category
+	mp.categoryVersions[id]++
+	mp.categoryEvent(models.EventCategoryUpdated, id)
+	return mp.currentCategory(id), nil
+}
+
+// DeleteCategoryBycode moves the category to the trash.
+// Returns ErrCategoryUsed if any not deleted product has this category and
+// ErrVersionConflict if version is not zero and the category has another one, otherwise the category
+// as it was before deletion or the zero category if there is no one
+func (mp *memoryProvider) DeleteCategoryBycode(ctx context.Context, catCode string, version int) (models.Category, error) {
+	defer mp.lock(ctx)()
+	id, ok := mp.categoryIds[catCode]
+	if !ok {
+		return models.Category{}, nil
+	}
+	if version != 0 && version != mp.categoryVersions[id] {
+		return models.Category{}, storage.ErrVersionConflict
+	}
+	for prodId, catIds := range mp.productCategories {
+		if _, ok := mp.deletedProducts[prodId]; ok {
+			continue
+		}
+		for _, cid := range catIds {
+			if cid == id {
+				return models.Category{}, storage.ErrCategoryUsed
+			}
+		}
+	}
+	category := mp.currentCategory(id)
+	delete(mp.categoryIds, catCode)
+	mp.deletedCategories[id] = time.Now()
+	mp.categoryEvent(models.EventCategoryDeleted, id)
+	return category, nil
+}
+
+func sortedKeys[T any](m map[int]T) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+func (mp *memoryProvider) SaveJob(ctx context.Context, job models.Job) (string, error) {
+	defer mp.lock(ctx)()
+	mp.lastJobId++
+	mp.jobs[mp.lastJobId] = models.Job{
+		Id: strconv.Itoa(mp.lastJobId),
+		Kind: job.Kind,
+		State: job.State,
+		ContentType: job.ContentType,
+		PayloadPath: job.PayloadPath,
+		Atomic: job.Atomic,
+		CreatedBy: job.CreatedBy,
+		CreatedAt: time.Now(),
+	}
+	return strconv.Itoa(mp.lastJobId), nil
+}
+
+func (mp *memoryProvider) GetJobById(ctx context.Context, jobId string) (models.Job, error) {
+	id, err := strconv.Atoi(jobId)
+	if err != nil {
+		return models.Job{}, storage.ErrQuery
+	}
+	defer mp.rlock(ctx)()
+	job, ok := mp.jobs[id]
+	if !ok {
+		return models.Job{}, storage.ErrJobNotFound
+	}
+	return job, nil
+}
+
+// ClaimQueuedJob moves the oldest queued job to running state and returns it
+func (mp *memoryProvider) ClaimQueuedJob(ctx context.Context) (models.Job, error) {
+	defer mp.lock(ctx)()
+	for _, id := range sortedKeys(mp.jobs) {
+		job := mp.jobs[id]
+		if job.State != models.JobStateQueued {
+			continue
+		}
+		now := time.Now()
+		job.State = models.JobStateRunning
+		job.StartedAt = &now
+		mp.jobs[id] = job
+		return job, nil
+	}
+	return models.Job{}, storage.ErrJobNotFound
+}
+
+// UpdateJobProgress saves counters of the running job and reports whether its cancellation was requested
+func (mp *memoryProvider) UpdateJobProgress(ctx context.Context, jobId string, counters models.JobCounters) (bool, error) {
+	id, err := strconv.Atoi(jobId)
+	if err != nil {
+		return false, storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	job, ok := mp.jobs[id]
+	if !ok {
+		return false, storage.ErrJobNotFound
+	}
+	job.Counters = counters
+	mp.jobs[id] = job
+	return job.CancelRequested, nil
+}
+
+// FinishJob sets the final state of the job with its counters, error and report
+func (mp *memoryProvider) FinishJob(ctx context.Context, job models.Job) error {
+	id, err := strconv.Atoi(job.Id)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	current, ok := mp.jobs[id]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	current.State = job.State
+	current.Counters = job.Counters
+	current.Error = job.Error
+	current.Report = job.Report
+	current.FinishedAt = &now
+	mp.jobs[id] = current
+	return nil
+}
+
+// RequestJobCancel cancels queued job right away and marks running job to be cancelled by its worker.
+// Returns the job state after the request
+func (mp *memoryProvider) RequestJobCancel(ctx context.Context, jobId string) (string, error) {
+	id, err := strconv.Atoi(jobId)
+	if err != nil {
+		return "", storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	job, ok := mp.jobs[id]
+	if !ok {
+		return "", storage.ErrJobNotFound
+	}
+	job.CancelRequested = job.State == models.JobStateQueued || job.State == models.JobStateRunning
+	if job.State == models.JobStateQueued {
+		now := time.Now()
+		job.State = models.JobStateCancelled
+		job.FinishedAt = &now
+	}
+	mp.jobs[id] = job
+	return job.State, nil
+}
+
+// RecoverRunningJobs handles jobs left in running state: they are queued again
+// with reset counters if resume is true, otherwise failed. Jobs do not outlive
+// the process in memory, so there is nothing to recover after restart
+func (mp *memoryProvider) RecoverRunningJobs(ctx context.Context, resume bool) (int, error) {
+	defer mp.lock(ctx)()
+	count := 0
+	for id, job := range mp.jobs {
+		if job.State != models.JobStateRunning {
+			continue
+		}
+		count++
+		now := time.Now()
+		if !resume {
+			job.State = models.JobStateFailed
+			job.Error = "interrupted by restart"
+			job.FinishedAt = &now
+			mp.jobs[id] = job
+			continue
+		}
+		if job.CancelRequested {
+			job.State = models.JobStateCancelled
+			job.FinishedAt = &now
+		} else {
+			job.State = models.JobStateQueued
+			job.FinishedAt = nil
+		}
+		job.Counters = models.JobCounters{}
+		job.StartedAt = nil
+		mp.jobs[id] = job
+	}
+	return count, nil
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// SaveMedia adds the media after the other media of the not deleted product and counts the version of the product.
+// The first media of the product is primary, the media replaces the primary one if it has Primary set
+func (mp *memoryProvider) SaveMedia(ctx context.Context, prodId string, media models.Media) (models.Media, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return models.Media{}, storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	if !mp.productExist(id) {
+		return models.Media{}, storage.ErrProductNotFound
+	}
+	mp.lastMediaId++
+	media.Id = mp.lastMediaId
+	media.CreatedAt = time.Now().UTC()
+	current := mp.media[id]
+	media.Primary = media.Primary || len(current) == 0
+	out := make([]models.Media, 0, len(current)+1)
+	for _, other := range current {
+		other.Primary = other.Primary && !media.Primary
+		out = append(out, other)
+	}
+	mp.setMedia(id, append(out, media))
+	media.Position = len(current)
+	return media, nil
+}
+
+// DeleteMediaById removes the media of the not deleted product and counts the version of the product.
+// Media after it are moved up, the first one becomes primary if the removed one was. The removed media
+// is returned with the keys of its files
+func (mp *memoryProvider) DeleteMediaById(ctx context.Context, prodId, mediaId string) (models.Media, error) {
+	id, mid, err := mediaIds(prodId, mediaId)
+	if err != nil {
+		return models.Media{}, err
+	}
+	defer mp.lock(ctx)()
+	if !mp.productExist(id) {
+		return models.Media{}, storage.ErrProductNotFound
+	}
+	idx := mp.mediaIdx(id, mid)
+	if idx < 0 {
+		return models.Media{}, storage.ErrMediaNotFound
+	}
+	removed := mp.media[id][idx]
+	out := append([]models.Media(nil), mp.media[id][:idx]...)
+	out = append(out, mp.media[id][idx+1:]...)
+	if removed.Primary && len(out) != 0 {
+		out[0].Primary = true
+	}
+	mp.setMedia(id, out)
+	return removed, nil
+}
+
+// SetMediaOrder sets positions of media of the not deleted product by their order in mediaIds
+// and counts the version of the product. ErrMediaOrder is returned if mediaIds don't have
+// every media of the product exactly once
+func (mp *memoryProvider) SetMediaOrder(ctx context.Context, prodId string, mediaIds []int) error {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	if !mp.productExist(id) {
+		return storage.ErrProductNotFound
+	}
+	current := mp.media[id]
+	if len(mediaIds) != len(current) {
+		return storage.ErrMediaOrder
+	}
+	out := make([]models.Media, 0, len(current))
+	seen := make(map[int]struct{}, len(mediaIds))
+	for _, mid := range mediaIds {
+		idx := mp.mediaIdx(id, mid)
+		if _, ok := seen[mid]; ok || idx < 0 {
+			return storage.ErrMediaOrder
+		}
+		seen[mid] = struct{}{}
+		out = append(out, current[idx])
+	}
+	mp.setMedia(id, out)
+	return nil
+}
+
+// SetPrimaryMedia makes the media primary instead of the current one and counts the version of the product
+func (mp *memoryProvider) SetPrimaryMedia(ctx context.Context, prodId, mediaId string) error {
+	id, mid, err := mediaIds(prodId, mediaId)
+	if err != nil {
+		return err
+	}
+	defer mp.lock(ctx)()
+	if !mp.productExist(id) {
+		return storage.ErrProductNotFound
+	}
+	if mp.mediaIdx(id, mid) < 0 {
+		return storage.ErrMediaNotFound
+	}
+	out := append([]models.Media(nil), mp.media[id]...)
+	for idx := range out {
+		out[idx].Primary = out[idx].Id == mid
+	}
+	mp.setMedia(id, out)
+	return nil
+}
+
+func mediaIds(prodId, mediaId string) (int, int, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return 0, 0, storage.ErrQuery
+	}
+	mid, err := strconv.Atoi(mediaId)
+	if err != nil {
+		return 0, 0, storage.ErrQuery
+	}
+	return id, mid, nil
+}
+
+// mediaIdx returns the index of the media among media of the product or -1, must be called under the lock
+func (mp *memoryProvider) mediaIdx(prodId, mediaId int) int {
+	for idx, media := range mp.media[prodId] {
+		if media.Id == mediaId {
+			return idx
+		}
+	}
+	return -1
+}
+
+// setMedia replaces media of the product numbering their positions, counts the version of the product
+// and appends the update event. Must be called under the write lock
+func (mp *memoryProvider) setMedia(prodId int, media []models.Media) {
+	for idx := range media {
+		media[idx].Position = idx
+	}
+	if len(media) == 0 {
+		delete(mp.media, prodId)
+	} else {
+		mp.media[prodId] = media
+	}
+	mp.productVersions[prodId]++
+	mp.productEvent(models.EventProductUpdated, prodId)
+}
+
+// mediaOf returns media without the keys of their files like the other providers read them with the product
+func mediaOf(media []models.Media) []models.Media {
+	if len(media) == 0 {
+		return nil
+	}
+	out := make([]models.Media, len(media))
+	for idx, m := range media {
+		m.Key, m.ThumbnailKey = "", ""
+		out[idx] = m
+	}
+	return out
+}
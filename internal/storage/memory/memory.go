@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// memoryProvider keeps the whole catalog in maps guarded by one lock.
+// It follows the postgres schema: the same unique and not empty columns,
+// foreign keys of product categories and the same errors of the storage package
+type memoryProvider struct {
+	mu sync.RWMutex
+	memoryState
+	// relayMu lets one relay read and remove events at a time
+	relayMu sync.Mutex
+}
+
+// memoryState is the data of the provider, WithinTx restores its copy on rollback
+type memoryState struct {
+	users map[string]models.User
+	// categories and products keep deleted ones too, ids by codes and names keep only not deleted
+	categories map[int]models.Category
+	categoryIds map[string]int
+	deletedCategories map[int]time.Time
+	products map[int]models.Product
+	productIds map[string]int
+	// skuIds keeps only not deleted products, barcodeIds keeps deleted ones too until they are purged
+	skuIds map[string]int
+	barcodeIds map[string]int
+	// variants keeps variants of the product ordered by id, variantSkus keeps ids of products by skus of their variants
+	variants map[int][]models.Variant
+	variantSkus map[string]int
+	// media keeps media of the product ordered by position
+	media map[int][]models.Media
+	// warehouses are keyed by code, stock keeps levels of products in warehouses they were ever kept in
+	warehouses map[string]models.Warehouse
+	stock map[stockKey]models.StockLevel
+	reservations map[int]models.Reservation
+	// stockMovements keeps the ledger of the product in the order of the changes
+	stockMovements map[int][]models.StockMovement
+	deletedProducts map[int]time.Time
+	// productCategories keeps category ids of the product in the order they were linked
+	productCategories map[int][]int
+	// categoryVersions and productVersions count updates of the rows by their ids
+	categoryVersions map[int]int
+	productVersions map[int]int
+	jobs map[int]models.Job
+	// versions keeps histories by entity and key joined with "/"
+	versions map[string][]models.Version
+	// events is the outbox ordered by id, deadEvents keeps events failed too many times
+	events []models.Event
+	deadEvents []models.Event
+	lastCategoryId int
+	lastProductId int
+	lastVariantId int
+	lastMediaId int
+	lastReservationId int
+	lastMovementId int
+	lastJobId int
+	lastEventId int
+}
+
+func NewMemoryProvider() *memoryProvider {
+	return &memoryProvider{
+		memoryState: memoryState{
+			users: make(map[string]models.User),
+			categories: make(map[int]models.Category),
+			categoryIds: make(map[string]int),
+			deletedCategories: make(map[int]time.Time),
+			products: make(map[int]models.Product),
+			productIds: make(map[string]int),
+			skuIds: make(map[string]int),
+			barcodeIds: make(map[string]int),
+			variants: make(map[int][]models.Variant),
+			variantSkus: make(map[string]int),
+			media: make(map[int][]models.Media),
+			warehouses: make(map[string]models.Warehouse),
+			stock: make(map[stockKey]models.StockLevel),
+			reservations: make(map[int]models.Reservation),
+			stockMovements: make(map[int][]models.StockMovement),
+			deletedProducts: make(map[int]time.Time),
+			productCategories: make(map[int][]int),
+			categoryVersions: make(map[int]int),
+			productVersions: make(map[int]int),
+			jobs: make(map[int]models.Job),
+			versions: make(map[string][]models.Version),
+		},
+	}
+}
+
+// Close does nothing, it is here to be replaceable with postgres provider
+func (mp *memoryProvider) Close() {}
+
+// PoolStats returns empty stats, there are no connections
+func (mp *memoryProvider) PoolStats() models.PoolStats {
+	return models.PoolStats{}
+}
@@ -0,0 +1,14 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/storage/memory"
+	"github.com/EwvwGeN/cataloger/internal/storage/storagetest"
+)
+
+func TestMemoryBehaviorSuiteRun(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Repository {
+		return memory.NewMemoryProvider()
+	})
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// productEvent appends the event of the product with its state after the change,
+// must be called under the write lock
+func (mp *memoryProvider) productEvent(eventType string, id int) {
+	product := mp.products[id]
+	mp.appendEvent(models.EventAggregateProduct, id, eventType, struct{
+		Id          string `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		State       string `json:"state"`
+		Version     int    `json:"version"`
+	}{
+		Id: strconv.Itoa(id),
+		Name: product.Name,
+		Description: product.Description,
+		State: product.State,
+		Version: mp.productVersions[id],
+	})
+}
+
+// categoryEvent appends the event of the category with its state after the change,
+// must be called under the write lock
+func (mp *memoryProvider) categoryEvent(eventType string, id int) {
+	category := mp.categories[id]
+	mp.appendEvent(models.EventAggregateCategory, id, eventType, struct{
+		Name        string `json:"name"`
+		Code        string `json:"code"`
+		Description string `json:"description"`
+		Version     int    `json:"version"`
+	}{
+		Name: category.Name,
+		Code: category.Code,
+		Description: category.Description,
+		Version: mp.categoryVersions[id],
+	})
+}
+
+func (mp *memoryProvider) appendEvent(aggregate string, id int, eventType string, payload interface{}) {
+	// payloads are plain structs, they are always marshaled
+	data, _ := json.Marshal(payload)
+	mp.lastEventId++
+	mp.events = append(mp.events, models.Event{
+		Id: mp.lastEventId,
+		Aggregate: aggregate,
+		AggregateId: strconv.Itoa(id),
+		Type: eventType,
+		Payload: data,
+		CreatedAt: time.Now(),
+	})
+}
+
+// RelayEvents passes up to limit oldest events to publish and applies its result, dead events are moved
+// out of the outbox. publish is called without the lock, relays wait for each other instead of claiming
+// events for claimTimeout, so events are not passed twice at once
+func (mp *memoryProvider) RelayEvents(ctx context.Context, limit int, claimTimeout time.Duration, publish func([]models.Event) models.RelayResult) error {
+	mp.relayMu.Lock()
+	defer mp.relayMu.Unlock()
+	unlock := mp.rlock(ctx)
+	events := make([]models.Event, 0, limit)
+	for _, event := range mp.events {
+		if len(events) == limit {
+			break
+		}
+		events = append(events, event)
+	}
+	unlock()
+	if len(events) == 0 {
+		return nil
+	}
+	result := publish(events)
+	remove := make(map[int]struct{}, len(result.Published))
+	for _, id := range result.Published {
+		remove[id] = struct{}{}
+	}
+	failed := make(map[int]struct{}, len(result.Failed))
+	for _, id := range result.Failed {
+		failed[id] = struct{}{}
+	}
+	dead := make(map[int]struct{}, len(result.Dead))
+	for _, id := range result.Dead {
+		dead[id] = struct{}{}
+	}
+	defer mp.lock(ctx)()
+	kept := mp.events[:0]
+	for _, event := range mp.events {
+		if _, ok := remove[event.Id]; ok {
+			continue
+		}
+		if _, ok := dead[event.Id]; ok {
+			event.Attempts++
+			mp.deadEvents = append(mp.deadEvents, event)
+			continue
+		}
+		if _, ok := failed[event.Id]; ok {
+			event.Attempts++
+		}
+		kept = append(kept, event)
+	}
+	mp.events = kept
+	return nil
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// pricesOf checks prices like the postgres table does and returns their copy
+// in the order the other providers read them
+func pricesOf(prices []models.Price) ([]models.Price, error) {
+	if len(prices) == 0 {
+		return nil, nil
+	}
+	out := make([]models.Price, len(prices))
+	for idx, price := range prices {
+		if price.Kind != models.PriceKindList && price.Kind != models.PriceKindSale {
+			return nil, storage.ErrQuery
+		}
+		if price.Amount < 0 || !currencyCode(price.Currency) {
+			return nil, storage.ErrQuery
+		}
+		if price.ValidFrom != nil && price.ValidTo != nil && !price.ValidFrom.Before(*price.ValidTo) {
+			return nil, storage.ErrQuery
+		}
+		out[idx] = price
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		if a.ValidFrom == nil || b.ValidFrom == nil {
+			return a.ValidFrom == nil && b.ValidFrom != nil
+		}
+		return a.ValidFrom.Before(*b.ValidFrom)
+	})
+	return out, nil
+}
+
+func currencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPrices reports whether the lowest price of the product valid now in any currency
+// or in the currency of the filter is within the bounds of the filter
+func matchPrices(product models.Product, filter models.ProductFilter) bool {
+	if filter.MinPrice == nil && filter.MaxPrice == nil && filter.Currency == "" {
+		return true
+	}
+	now := time.Now()
+	lowest := make(map[string]int64)
+	for _, price := range product.Prices {
+		if filter.Currency != "" && price.Currency != filter.Currency {
+			continue
+		}
+		if (price.ValidFrom != nil && price.ValidFrom.After(now)) || (price.ValidTo != nil && !price.ValidTo.After(now)) {
+			continue
+		}
+		if amount, ok := lowest[price.Currency]; !ok || price.Amount < amount {
+			lowest[price.Currency] = price.Amount
+		}
+	}
+	for _, amount := range lowest {
+		if (filter.MinPrice == nil || amount >= *filter.MinPrice) && (filter.MaxPrice == nil || amount <= *filter.MaxPrice) {
+			return true
+		}
+	}
+	return false
+}
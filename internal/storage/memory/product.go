@@ -0,0 +1,405 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+func (mp *memoryProvider) SaveProduct(ctx context.Context, product models.Product, catIds []int) (string, error) {
+	defer mp.lock(ctx)()
+	if _, ok := mp.productIds[product.Name]; ok {
+		return "", storage.ErrProductExist
+	}
+	// repeated category of the product breaks the primary key of links
+	seen := make(map[int]struct{}, len(catIds))
+	for _, cid := range catIds {
+		if _, ok := seen[cid]; ok {
+			return "", storage.ErrQuery
+		}
+		seen[cid] = struct{}{}
+	}
+	id, err := mp.insertProduct(product, catIds)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(id), nil
+}
+
+// insertProduct checks unique columns, columns, category links and barcodes of the product in the order
+// the other providers save them and saves it, repeated category ids are linked once. Must be called under the write lock
+func (mp *memoryProvider) insertProduct(product models.Product, catIds []int) (int, error) {
+	if _, ok := mp.skuIds[product.Sku]; ok && product.Sku != "" {
+		return 0, storage.ErrSkuExist
+	}
+	if _, ok := mp.productIds[product.Name]; ok {
+		return 0, storage.ErrProductExist
+	}
+	if product.Name == "" || product.Description == "" {
+		return 0, storage.ErrQuery
+	}
+	if !mp.categoriesExist(catIds) {
+		return 0, storage.ErrQuery
+	}
+	prices, err := pricesOf(product.Prices)
+	if err != nil {
+		return 0, err
+	}
+	barcodes, err := mp.barcodesOf(0, product.Barcodes)
+	if err != nil {
+		return 0, err
+	}
+	attributes, err := attributesOf(product.Attributes)
+	if err != nil {
+		return 0, err
+	}
+	mp.lastProductId++
+	product.Id = mp.lastProductId
+	product.CategoryСodes = nil
+	product.State = models.ProductStateDraft
+	product.Prices = prices
+	product.Barcodes = barcodes
+	product.Options = optionsOf(product.Options)
+	product.Variants = nil
+	product.Media = nil
+	product.Attributes = attributes
+	mp.products[product.Id] = product
+	mp.productIds[product.Name] = product.Id
+	if product.Sku != "" {
+		mp.skuIds[product.Sku] = product.Id
+	}
+	for _, code := range barcodes {
+		mp.barcodeIds[code] = product.Id
+	}
+	mp.productVersions[product.Id] = 1
+	mp.linkCategories(product.Id, catIds)
+	mp.productEvent(models.EventProductCreated, product.Id)
+	return product.Id, nil
+}
+
+// deleteProduct removes the product for good, must be called under the write lock
+func (mp *memoryProvider) deleteProduct(id int) {
+	if _, ok := mp.deletedProducts[id]; !ok {
+		delete(mp.productIds, mp.products[id].Name)
+		delete(mp.skuIds, mp.products[id].Sku)
+	}
+	for _, code := range mp.products[id].Barcodes {
+		delete(mp.barcodeIds, code)
+	}
+	for _, variant := range mp.variants[id] {
+		delete(mp.variantSkus, variant.Sku)
+	}
+	delete(mp.variants, id)
+	delete(mp.media, id)
+	mp.deleteStock(id)
+	delete(mp.products, id)
+	delete(mp.productVersions, id)
+	delete(mp.deletedProducts, id)
+	delete(mp.productCategories, id)
+}
+
+func (mp *memoryProvider) categoriesExist(catIds []int) bool {
+	for _, cid := range catIds {
+		if _, ok := mp.categories[cid]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// linkCategories adds not linked categories to the product
+func (mp *memoryProvider) linkCategories(prodId int, catIds []int) {
+	linked := make(map[int]struct{}, len(catIds))
+	for _, cid := range mp.productCategories[prodId] {
+		linked[cid] = struct{}{}
+	}
+	for _, cid := range catIds {
+		if _, ok := linked[cid]; ok {
+			continue
+		}
+		linked[cid] = struct{}{}
+		mp.productCategories[prodId] = append(mp.productCategories[prodId], cid)
+	}
+}
+
+// SaveProducts inserts products with their category links and reports outcome of every product.
+// A failed product does not break the others, in atomic mode the first failed product
+// removes the products saved by this call
+func (mp *memoryProvider) SaveProducts(ctx context.Context, products []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error) {
+	defer mp.lock(ctx)()
+	results := make([]models.ImportRowResult, len(products))
+	var created []int
+	events := len(mp.events)
+	for idx, product := range products {
+		results[idx].Name = product.Name
+		prodId, err := mp.insertProduct(product, catsIds[idx])
+		if err == nil {
+			created = append(created, prodId)
+			results[idx].Status = models.ImportStatusCreated
+			results[idx].ProductId = strconv.Itoa(prodId)
+			continue
+		}
+		// products with sku are matched by sku, the others by name
+		if errors.Is(err, storage.ErrSkuExist) || (errors.Is(err, storage.ErrProductExist) && product.Sku == "") {
+			results[idx].Status = models.ImportStatusSkippedDuplicate
+			results[idx].Reason = err.Error()
+			continue
+		}
+		results[idx].Status = models.ImportStatusFailed
+		results[idx].Reason = err.Error()
+		if atomic {
+			for _, prodId := range created {
+				mp.deleteProduct(prodId)
+			}
+			mp.events = mp.events[:events]
+			for i := range results {
+				if i == idx || results[i].Status == models.ImportStatusSkippedDuplicate {
+					continue
+				}
+				results[i].Name = products[i].Name
+				results[i].Status = models.ImportStatusRolledBack
+				results[i].ProductId = ""
+			}
+			return results, nil
+		}
+	}
+	return results, nil
+}
+
+// GetProductById returns ErrQuery if the id is not a number like postgres provider does
+func (mp *memoryProvider) GetProductById(ctx context.Context, prodId string) (models.Product, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return models.Product{}, storage.ErrQuery
+	}
+	defer mp.rlock(ctx)()
+	if !mp.productExist(id) {
+		return models.Product{}, storage.ErrProductNotFound
+	}
+	return mp.currentProduct(id), nil
+}
+
+// GetProductBySku returns the not deleted product with the sku
+func (mp *memoryProvider) GetProductBySku(ctx context.Context, sku string) (models.Product, error) {
+	defer mp.rlock(ctx)()
+	id, ok := mp.skuIds[sku]
+	if !ok {
+		return models.Product{}, storage.ErrProductNotFound
+	}
+	return mp.currentProduct(id), nil
+}
+
+// GetProductByBarcode returns the not deleted product with the barcode
+func (mp *memoryProvider) GetProductByBarcode(ctx context.Context, code string) (models.Product, error) {
+	defer mp.rlock(ctx)()
+	id, ok := mp.barcodeIds[code]
+	if !ok || !mp.productExist(id) {
+		return models.Product{}, storage.ErrProductNotFound
+	}
+	return mp.currentProduct(id), nil
+}
+
+// productExist reports whether the product is saved and not deleted, must be called under the lock
+func (mp *memoryProvider) productExist(id int) bool {
+	if _, ok := mp.products[id]; !ok {
+		return false
+	}
+	_, deleted := mp.deletedProducts[id]
+	return !deleted
+}
+
+// currentProduct returns the not deleted product with its version, must be called under the lock
+func (mp *memoryProvider) currentProduct(id int) models.Product {
+	product := mp.product(id, false)
+	product.Version = mp.productVersions[id]
+	return product
+}
+
+// product returns the product with codes of its categories, codes of deleted categories
+// are added if withDeleted is true. Must be called under the lock
+func (mp *memoryProvider) product(id int, withDeleted bool) models.Product {
+	product := mp.products[id]
+	for _, cid := range mp.productCategories[id] {
+		if _, deleted := mp.deletedCategories[cid]; deleted && !withDeleted {
+			continue
+		}
+		product.CategoryСodes = append(product.CategoryСodes, mp.categories[cid].Code)
+	}
+	product.Variants = mp.variants[id]
+	product.Media = mediaOf(mp.media[id])
+	return product
+}
+
+func (mp *memoryProvider) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
+	return mp.filterProducts(ctx, func(product models.Product) bool {
+		return (len(filter.CategoryCodes) == 0 || hasAnyCode(product, filter.CategoryCodes)) && matchPrices(product, filter) &&
+			matchAttributes(product, filter) && mp.matchStock(product, filter) && matchStates(product, filter.States)
+	}), nil
+}
+
+// StreamProducts calls fn for every product matching the filter, fn is called without the lock
+// on the snapshot of products, so it may use the provider itself
+func (mp *memoryProvider) StreamProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error {
+	products := mp.filterProducts(ctx, func(product models.Product) bool {
+		return (len(filter.CategoryCodes) == 0 || hasAnyCode(product, filter.CategoryCodes)) && matchPrices(product, filter) &&
+			matchAttributes(product, filter) && mp.matchStock(product, filter) && matchStates(product, filter.States)
+	})
+	for _, product := range products {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetProductsByCategory returns products of the category in one of states, in any state if states are empty
+func (mp *memoryProvider) GetProductsByCategory(ctx context.Context, catCode string, states []string) ([]models.Product, error) {
+	return mp.filterProducts(ctx, func(product models.Product) bool {
+		return hasAnyCode(product, []string{catCode}) && matchStates(product, states)
+	}), nil
+}
+
+// filterProducts returns products accepted by fn ordered by id
+func (mp *memoryProvider) filterProducts(ctx context.Context, fn func(models.Product) bool) []models.Product {
+	defer mp.rlock(ctx)()
+	var outProducts []models.Product
+	for _, id := range sortedKeys(mp.products) {
+		if _, ok := mp.deletedProducts[id]; ok {
+			continue
+		}
+		product := mp.product(id, false)
+		if fn(product) {
+			outProducts = append(outProducts, product)
+		}
+	}
+	return outProducts
+}
+
+func hasAnyCode(product models.Product, codes []string) bool {
+	for _, code := range product.CategoryСodes {
+		for _, other := range codes {
+			if code == other {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpdateProductById changes the fields that are set and counts the version of the product.
+// Categories of the product are replaced with catIds, prices, barcodes, options and attributes with the new ones if they are not nil. Returns ErrProductNotFound
+// if the product is missing or deleted and ErrVersionConflict if version is not zero
+// and the product has another one, otherwise the changed product
+func (mp *memoryProvider) UpdateProductById(ctx context.Context, prodId string, newPorductdata models.ProductForPatch, catIds []int, version int) (models.Product, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return models.Product{}, storage.ErrQuery
+	}
+	if (newPorductdata.Name != nil && *newPorductdata.Name == "") || (newPorductdata.Description != nil && *newPorductdata.Description == "") {
+		return models.Product{}, storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	if !mp.productExist(id) {
+		return models.Product{}, storage.ErrProductNotFound
+	}
+	if version != 0 && version != mp.productVersions[id] {
+		return models.Product{}, storage.ErrVersionConflict
+	}
+	product := mp.products[id]
+	if newPorductdata.Name != nil && *newPorductdata.Name != product.Name {
+		if _, ok := mp.productIds[*newPorductdata.Name]; ok {
+			return models.Product{}, storage.ErrProductExist
+		}
+	}
+	if newPorductdata.Sku != nil && *newPorductdata.Sku != product.Sku {
+		if _, ok := mp.skuIds[*newPorductdata.Sku]; ok {
+			return models.Product{}, storage.ErrSkuExist
+		}
+	}
+	if catIds != nil && !mp.categoriesExist(catIds) {
+		return models.Product{}, storage.ErrQuery
+	}
+	prices, err := pricesOf(newPorductdata.Prices)
+	if err != nil {
+		return models.Product{}, err
+	}
+	barcodes, err := mp.barcodesOf(id, newPorductdata.Barcodes)
+	if err != nil {
+		return models.Product{}, err
+	}
+	attributes, err := attributesOf(newPorductdata.Attributes)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if newPorductdata.Name != nil {
+		delete(mp.productIds, product.Name)
+		product.Name = *newPorductdata.Name
+		mp.productIds[product.Name] = id
+	}
+	if newPorductdata.Description != nil {
+		product.Description = *newPorductdata.Description
+	}
+	if newPorductdata.Sku != nil {
+		delete(mp.skuIds, product.Sku)
+		product.Sku = *newPorductdata.Sku
+		if product.Sku != "" {
+			mp.skuIds[product.Sku] = id
+		}
+	}
+	if newPorductdata.Prices != nil {
+		product.Prices = prices
+	}
+	if newPorductdata.Options != nil {
+		product.Options = optionsOf(newPorductdata.Options)
+	}
+	if newPorductdata.Attributes != nil {
+		product.Attributes = attributes
+	}
+	if newPorductdata.Barcodes != nil {
+		for _, code := range product.Barcodes {
+			delete(mp.barcodeIds, code)
+		}
+		product.Barcodes = barcodes
+		for _, code := range barcodes {
+			mp.barcodeIds[code] = id
+		}
+	}
+	mp.products[id] = product
+	mp.productVersions[id]++
+	if catIds != nil {
+		delete(mp.productCategories, id)
+		mp.linkCategories(id, catIds)
+	}
+	mp.productEvent(models.EventProductUpdated, id)
+	return mp.currentProduct(id), nil
+}
+
+// DeleteProductById moves the product to the trash, its category links are kept for restore.
+// Returns the product as it was before deletion, the zero product if it is already deleted,
+// and ErrVersionConflict if version is not zero and the product has another one
+func (mp *memoryProvider) DeleteProductById(ctx context.Context, id string, version int) (models.Product, error) {
+	prodId, err := strconv.Atoi(id)
+	if err != nil {
+		return models.Product{}, storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	if !mp.productExist(prodId) {
+		return models.Product{}, nil
+	}
+	if version != 0 && version != mp.productVersions[prodId] {
+		return models.Product{}, storage.ErrVersionConflict
+	}
+	product := mp.currentProduct(prodId)
+	delete(mp.productIds, mp.products[prodId].Name)
+	delete(mp.skuIds, mp.products[prodId].Sku)
+	mp.deletedProducts[prodId] = time.Now()
+	mp.productEvent(models.EventProductDeleted, prodId)
+	return product, nil
+}
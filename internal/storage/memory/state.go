@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// SetProductState moves the product from the state to the other one and returns the changed product,
+// ErrVersionConflict is returned if version is not zero and the product has another one
+// and ErrProductState if the product is in another state
+func (mp *memoryProvider) SetProductState(ctx context.Context, prodId, from, to string, version int) (models.Product, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return models.Product{}, storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	if !mp.productExist(id) {
+		return models.Product{}, storage.ErrProductNotFound
+	}
+	if version != 0 && version != mp.productVersions[id] {
+		return models.Product{}, storage.ErrVersionConflict
+	}
+	product := mp.products[id]
+	if product.State != from {
+		return models.Product{}, storage.ErrProductState
+	}
+	product.State = to
+	mp.products[id] = product
+	mp.productVersions[id]++
+	mp.productEvent(models.EventProductUpdated, id)
+	return mp.currentProduct(id), nil
+}
+
+// matchStates reports whether the product is in one of states, any state matches empty states
+func matchStates(product models.Product, states []string) bool {
+	if len(states) == 0 {
+		return true
+	}
+	for _, state := range states {
+		if product.State == state {
+			return true
+		}
+	}
+	return false
+}
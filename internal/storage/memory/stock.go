@@ -0,0 +1,263 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+type stockKey struct {
+	productId int
+	warehouse string
+}
+
+func (mp *memoryProvider) SaveWarehouse(ctx context.Context, warehouse models.Warehouse) error {
+	defer mp.lock(ctx)()
+	if warehouse.Code == "" || warehouse.Name == "" {
+		return storage.ErrQuery
+	}
+	if _, ok := mp.warehouses[warehouse.Code]; ok {
+		return storage.ErrWarehouseExist
+	}
+	mp.warehouses[warehouse.Code] = warehouse
+	return nil
+}
+
+// GetWarehouses returns all warehouses ordered by code
+func (mp *memoryProvider) GetWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	defer mp.rlock(ctx)()
+	var warehouses []models.Warehouse
+	for _, warehouse := range mp.warehouses {
+		warehouses = append(warehouses, warehouse)
+	}
+	sort.Slice(warehouses, func(i, j int) bool {
+		return warehouses[i].Code < warehouses[j].Code
+	})
+	return warehouses, nil
+}
+
+// GetStock returns the stock of the product in every warehouse it was ever kept in ordered by warehouse code
+func (mp *memoryProvider) GetStock(ctx context.Context, prodId string) ([]models.StockLevel, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	defer mp.rlock(ctx)()
+	var levels []models.StockLevel
+	for key, level := range mp.stock {
+		if key.productId == id {
+			levels = append(levels, level)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].Warehouse < levels[j].Warehouse
+	})
+	return levels, nil
+}
+
+// AdjustStock adds the signed quantity of the change to units of the not deleted product on hand in the warehouse.
+// ErrOutOfStock is returned if less units than reserved would be left on hand
+func (mp *memoryProvider) AdjustStock(ctx context.Context, prodId string, change models.StockChange) (models.StockLevel, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return models.StockLevel{}, storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	if err := mp.stockExist(id, change.Warehouse); err != nil {
+		return models.StockLevel{}, err
+	}
+	key := stockKey{productId: id, warehouse: change.Warehouse}
+	level, ok := mp.stock[key]
+	if !ok {
+		level = models.StockLevel{Warehouse: change.Warehouse}
+	}
+	level.OnHand += change.Quantity
+	if err := mp.setStock(key, level); err != nil {
+		return models.StockLevel{}, err
+	}
+	mp.addMovement(id, models.StockMovement{
+		Warehouse: change.Warehouse,
+		Kind: models.MovementAdjust,
+		OnHand: change.Quantity,
+		Reason: change.Reason,
+		Actor: change.Actor,
+	})
+	return mp.stock[key], nil
+}
+
+// ReserveStock reserves the quantity of the change from available units of the not deleted product in the warehouse,
+// ErrOutOfStock is returned if there are less available units
+func (mp *memoryProvider) ReserveStock(ctx context.Context, prodId string, change models.StockChange) (models.Reservation, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return models.Reservation{}, storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	if err := mp.stockExist(id, change.Warehouse); err != nil {
+		return models.Reservation{}, err
+	}
+	key := stockKey{productId: id, warehouse: change.Warehouse}
+	level, ok := mp.stock[key]
+	if !ok || change.Quantity <= 0 || level.Available < change.Quantity {
+		return models.Reservation{}, storage.ErrOutOfStock
+	}
+	level.Reserved += change.Quantity
+	if err := mp.setStock(key, level); err != nil {
+		return models.Reservation{}, err
+	}
+	mp.lastReservationId++
+	reservation := models.Reservation{
+		Id: mp.lastReservationId,
+		ProductId: id,
+		Warehouse: change.Warehouse,
+		Quantity: change.Quantity,
+		State: models.ReservationActive,
+		CreatedAt: time.Now().UTC(),
+	}
+	mp.reservations[reservation.Id] = reservation
+	mp.addMovement(id, models.StockMovement{
+		Warehouse: change.Warehouse,
+		Kind: models.MovementReserve,
+		Reserved: change.Quantity,
+		ReservationId: reservation.Id,
+		Reason: change.Reason,
+		Actor: change.Actor,
+	})
+	return reservation, nil
+}
+
+func (mp *memoryProvider) GetReservation(ctx context.Context, reservationId string) (models.Reservation, error) {
+	id, err := strconv.Atoi(reservationId)
+	if err != nil {
+		return models.Reservation{}, storage.ErrQuery
+	}
+	defer mp.rlock(ctx)()
+	reservation, ok := mp.reservations[id]
+	if !ok {
+		return models.Reservation{}, storage.ErrReservationNotFound
+	}
+	return reservation, nil
+}
+
+// ReleaseReservation returns units of the active reservation to available ones
+func (mp *memoryProvider) ReleaseReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error) {
+	return mp.closeReservation(ctx, reservationId, models.ReservationReleased, change)
+}
+
+// CommitReservation takes units of the active reservation out of the warehouse
+func (mp *memoryProvider) CommitReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error) {
+	return mp.closeReservation(ctx, reservationId, models.ReservationCommitted, change)
+}
+
+// closeReservation moves the active reservation to the state, ErrReservationClosed is returned if it is not active
+func (mp *memoryProvider) closeReservation(ctx context.Context, reservationId string, state string, change models.StockChange) (models.Reservation, error) {
+	id, err := strconv.Atoi(reservationId)
+	if err != nil {
+		return models.Reservation{}, storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	reservation, ok := mp.reservations[id]
+	if !ok {
+		return models.Reservation{}, storage.ErrReservationNotFound
+	}
+	if reservation.State != models.ReservationActive {
+		return models.Reservation{}, storage.ErrReservationClosed
+	}
+	movement := models.StockMovement{
+		Warehouse: reservation.Warehouse,
+		Kind: models.MovementRelease,
+		Reserved: -reservation.Quantity,
+		ReservationId: reservation.Id,
+		Reason: change.Reason,
+		Actor: change.Actor,
+	}
+	if state == models.ReservationCommitted {
+		movement.Kind = models.MovementCommit
+		movement.OnHand = -reservation.Quantity
+	}
+	key := stockKey{productId: reservation.ProductId, warehouse: reservation.Warehouse}
+	level := mp.stock[key]
+	level.OnHand += movement.OnHand
+	level.Reserved += movement.Reserved
+	if err := mp.setStock(key, level); err != nil {
+		return models.Reservation{}, err
+	}
+	closedAt := time.Now().UTC()
+	reservation.State, reservation.ClosedAt = state, &closedAt
+	mp.reservations[id] = reservation
+	mp.addMovement(reservation.ProductId, movement)
+	return reservation, nil
+}
+
+// GetStockMovements returns the ledger of the stock of the product in the order of the changes
+func (mp *memoryProvider) GetStockMovements(ctx context.Context, prodId string) ([]models.StockMovement, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	defer mp.rlock(ctx)()
+	return append([]models.StockMovement(nil), mp.stockMovements[id]...), nil
+}
+
+// stockExist returns the error of the storage if the product is deleted or the warehouse does not exist
+func (mp *memoryProvider) stockExist(prodId int, warehouse string) error {
+	if !mp.productExist(prodId) {
+		return storage.ErrProductNotFound
+	}
+	if _, ok := mp.warehouses[warehouse]; !ok {
+		return storage.ErrWarehouseNotFound
+	}
+	return nil
+}
+
+// setStock saves the level if it fits the check of the other providers: reserved units are within on hand ones
+func (mp *memoryProvider) setStock(key stockKey, level models.StockLevel) error {
+	if level.Reserved < 0 || level.OnHand < level.Reserved {
+		return storage.ErrOutOfStock
+	}
+	level.Available = level.OnHand - level.Reserved
+	mp.stock[key] = level
+	return nil
+}
+
+func (mp *memoryProvider) addMovement(prodId int, movement models.StockMovement) {
+	mp.lastMovementId++
+	movement.Id = mp.lastMovementId
+	movement.CreatedAt = time.Now().UTC()
+	movements := append([]models.StockMovement(nil), mp.stockMovements[prodId]...)
+	mp.stockMovements[prodId] = append(movements, movement)
+}
+
+// deleteStock removes the stock of the purged product with its reservations and ledger
+func (mp *memoryProvider) deleteStock(prodId int) {
+	for key := range mp.stock {
+		if key.productId == prodId {
+			delete(mp.stock, key)
+		}
+	}
+	for id, reservation := range mp.reservations {
+		if reservation.ProductId == prodId {
+			delete(mp.reservations, id)
+		}
+	}
+	delete(mp.stockMovements, prodId)
+}
+
+// matchStock reports whether the product has available units in any warehouse as InStock of the filter says
+func (mp *memoryProvider) matchStock(product models.Product, filter models.ProductFilter) bool {
+	if filter.InStock == nil {
+		return true
+	}
+	inStock := false
+	for key, level := range mp.stock {
+		if key.productId == product.Id && level.Available > 0 {
+			inStock = true
+			break
+		}
+	}
+	return inStock == *filter.InStock
+}
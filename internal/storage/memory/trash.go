@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// GetTrash returns deleted products with codes of all their categories and deleted categories,
+// the last deleted go first
+func (mp *memoryProvider) GetTrash(ctx context.Context) (models.Trash, error) {
+	defer mp.rlock(ctx)()
+	trash := models.Trash{
+		Products: []models.TrashProduct{},
+		Categories: []models.TrashCategory{},
+	}
+	for _, id := range lastDeleted(mp.deletedProducts) {
+		trash.Products = append(trash.Products, models.TrashProduct{
+			Product: mp.product(id, true),
+			DeletedAt: mp.deletedProducts[id],
+		})
+	}
+	for _, id := range lastDeleted(mp.deletedCategories) {
+		trash.Categories = append(trash.Categories, models.TrashCategory{
+			Category: mp.categories[id],
+			DeletedAt: mp.deletedCategories[id],
+		})
+	}
+	return trash, nil
+}
+
+// lastDeleted returns ids ordered by deletion time from the last one
+func lastDeleted(deleted map[int]time.Time) []int {
+	ids := make([]int, 0, len(deleted))
+	for id := range deleted {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if !deleted[ids[i]].Equal(deleted[ids[j]]) {
+			return deleted[ids[i]].After(deleted[ids[j]])
+		}
+		return ids[i] > ids[j]
+	})
+	return ids
+}
+
+// RestoreProductById takes the product out of the trash with its category links.
+// Returns ErrProductExist or ErrSkuExist if its name or sku is taken by another product
+func (mp *memoryProvider) RestoreProductById(ctx context.Context, prodId string) error {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	if _, ok := mp.deletedProducts[id]; !ok {
+		return storage.ErrProductNotFound
+	}
+	name, sku := mp.products[id].Name, mp.products[id].Sku
+	if _, ok := mp.productIds[name]; ok {
+		return storage.ErrProductExist
+	}
+	if _, ok := mp.skuIds[sku]; ok && sku != "" {
+		return storage.ErrSkuExist
+	}
+	delete(mp.deletedProducts, id)
+	mp.productIds[name] = id
+	if sku != "" {
+		mp.skuIds[sku] = id
+	}
+	mp.productEvent(models.EventProductRestored, id)
+	return nil
+}
+
+// RestoreCategoryByCode takes the last deleted category with the code out of the trash.
+// Returns ErrCategoryExist if the code is taken by another category
+func (mp *memoryProvider) RestoreCategoryByCode(ctx context.Context, catCode string) error {
+	defer mp.lock(ctx)()
+	for _, id := range lastDeleted(mp.deletedCategories) {
+		if mp.categories[id].Code != catCode {
+			continue
+		}
+		if _, ok := mp.categoryIds[catCode]; ok {
+			return storage.ErrCategoryExist
+		}
+		delete(mp.deletedCategories, id)
+		mp.categoryIds[catCode] = id
+		mp.categoryEvent(models.EventCategoryRestored, id)
+		return nil
+	}
+	return storage.ErrCategoryNotFound
+}
+
+// PurgeTrash removes products and categories deleted before the time for good.
+// Links of restored products to purged categories are removed too
+func (mp *memoryProvider) PurgeTrash(ctx context.Context, before time.Time) (models.PurgeResult, error) {
+	defer mp.lock(ctx)()
+	var result models.PurgeResult
+	for id, deletedAt := range mp.deletedProducts {
+		if deletedAt.Before(before) {
+			mp.deleteProduct(id)
+			result.Products++
+		}
+	}
+	purged := make(map[int]struct{})
+	for id, deletedAt := range mp.deletedCategories {
+		if deletedAt.Before(before) {
+			purged[id] = struct{}{}
+			delete(mp.categories, id)
+			delete(mp.categoryVersions, id)
+			delete(mp.deletedCategories, id)
+			result.Categories++
+		}
+	}
+	if len(purged) == 0 {
+		return result, nil
+	}
+	for prodId, catIds := range mp.productCategories {
+		left := catIds[:0]
+		for _, cid := range catIds {
+			if _, ok := purged[cid]; !ok {
+				left = append(left, cid)
+			}
+		}
+		mp.productCategories[prodId] = left
+	}
+	return result, nil
+}
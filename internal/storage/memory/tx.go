@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+type txKey struct{}
+
+// WithinTx runs fn under the write lock with the context that lets calls of the provider skip the lock.
+// Changes made by fn are undone if it returns an error or panics. fn must call the provider
+// only with the passed context, other calls wait for the end of the transaction.
+// Nested WithinTx joins the outer transaction
+func (mp *memoryProvider) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mp.inTx(ctx) {
+		return fn(ctx)
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	snapshot := mp.memoryState.clone()
+	defer func() {
+		if p := recover(); p != nil {
+			mp.memoryState = snapshot
+			panic(p)
+		}
+	}()
+	if err := fn(context.WithValue(ctx, txKey{}, mp)); err != nil {
+		mp.memoryState = snapshot
+		return err
+	}
+	return nil
+}
+
+func (mp *memoryProvider) inTx(ctx context.Context) bool {
+	current, ok := ctx.Value(txKey{}).(*memoryProvider)
+	return ok && current == mp
+}
+
+// lock takes the write lock unless ctx carries the transaction of the provider, it returns the unlock
+func (mp *memoryProvider) lock(ctx context.Context) func() {
+	if mp.inTx(ctx) {
+		return func() {}
+	}
+	mp.mu.Lock()
+	return mp.mu.Unlock
+}
+
+// rlock is lock for readers
+func (mp *memoryProvider) rlock(ctx context.Context) func() {
+	if mp.inTx(ctx) {
+		return func() {}
+	}
+	mp.mu.RLock()
+	return mp.mu.RUnlock
+}
+
+// clone copies maps and slices of the state, values are copied as they are
+// since they are replaced and never changed in place
+func (ms memoryState) clone() memoryState {
+	out := ms
+	out.users = cloneMap(ms.users)
+	out.categories = cloneMap(ms.categories)
+	out.categoryIds = cloneMap(ms.categoryIds)
+	out.deletedCategories = cloneMap(ms.deletedCategories)
+	out.products = cloneMap(ms.products)
+	out.productIds = cloneMap(ms.productIds)
+	out.skuIds = cloneMap(ms.skuIds)
+	out.barcodeIds = cloneMap(ms.barcodeIds)
+	out.variants = cloneMap(ms.variants)
+	out.variantSkus = cloneMap(ms.variantSkus)
+	out.media = cloneMap(ms.media)
+	out.warehouses = cloneMap(ms.warehouses)
+	out.stock = cloneMap(ms.stock)
+	out.reservations = cloneMap(ms.reservations)
+	out.stockMovements = cloneMap(ms.stockMovements)
+	out.deletedProducts = cloneMap(ms.deletedProducts)
+	out.productCategories = make(map[int][]int, len(ms.productCategories))
+	for id, catIds := range ms.productCategories {
+		out.productCategories[id] = append([]int(nil), catIds...)
+	}
+	out.categoryVersions = cloneMap(ms.categoryVersions)
+	out.productVersions = cloneMap(ms.productVersions)
+	out.jobs = cloneMap(ms.jobs)
+	out.versions = make(map[string][]models.Version, len(ms.versions))
+	for key, history := range ms.versions {
+		out.versions[key] = append([]models.Version(nil), history...)
+	}
+	out.events = append([]models.Event(nil), ms.events...)
+	out.deadEvents = append([]models.Event(nil), ms.deadEvents...)
+	return out
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	out := make(map[K]V, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
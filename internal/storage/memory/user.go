@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+func (mp *memoryProvider) SaveUser(ctx context.Context, email string, passHash string) error {
+	if email == "" || passHash == "" {
+		return storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	if _, ok := mp.users[email]; ok {
+		return storage.ErrUserExist
+	}
+	mp.users[email] = models.User{
+		Email: email,
+		PassHash: passHash,
+	}
+	return nil
+}
+
+// GetUserByEmail returns ErrQuery if user not found like postgres provider does
+func (mp *memoryProvider) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	defer mp.rlock(ctx)()
+	user, ok := mp.users[email]
+	if !ok {
+		return models.User{}, storage.ErrQuery
+	}
+	return user, nil
+}
+
+func (mp *memoryProvider) SaveRefreshToken(ctx context.Context, email string, refreshToken string, rttl int64) error {
+	defer mp.lock(ctx)()
+	user, ok := mp.users[email]
+	if !ok {
+		return nil
+	}
+	user.RefreshHash = refreshToken
+	user.ExpiresAt = rttl
+	mp.users[email] = user
+	return nil
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// optionsOf returns nil for no options like the other providers read them
+func optionsOf(options []models.VariantOption) []models.VariantOption {
+	if len(options) == 0 {
+		return nil
+	}
+	return options
+}
+
+// SaveVariant adds the variant to the not deleted product and counts the version of the product
+func (mp *memoryProvider) SaveVariant(ctx context.Context, prodId string, variant models.Variant) (string, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return "", storage.ErrQuery
+	}
+	defer mp.lock(ctx)()
+	if !mp.productExist(id) {
+		return "", storage.ErrProductNotFound
+	}
+	variant, err = mp.variantOf(id, 0, variant)
+	if err != nil {
+		return "", err
+	}
+	mp.lastVariantId++
+	variant.Id = mp.lastVariantId
+	variants := append([]models.Variant(nil), mp.variants[id]...)
+	mp.setVariants(id, append(variants, variant))
+	return strconv.Itoa(variant.Id), nil
+}
+
+// UpdateVariantById replaces sku, options and prices of the variant of the not deleted product
+// and counts the version of the product
+func (mp *memoryProvider) UpdateVariantById(ctx context.Context, prodId, variantId string, variant models.Variant) error {
+	id, vid, err := variantIds(prodId, variantId)
+	if err != nil {
+		return err
+	}
+	defer mp.lock(ctx)()
+	if !mp.productExist(id) {
+		return storage.ErrProductNotFound
+	}
+	idx := mp.variantIdx(id, vid)
+	if idx < 0 {
+		return storage.ErrVariantNotFound
+	}
+	variant, err = mp.variantOf(id, vid, variant)
+	if err != nil {
+		return err
+	}
+	variant.Id = vid
+	variants := append([]models.Variant(nil), mp.variants[id]...)
+	variants[idx] = variant
+	mp.setVariants(id, variants)
+	return nil
+}
+
+// DeleteVariantById removes the variant of the not deleted product and counts the version of the product
+func (mp *memoryProvider) DeleteVariantById(ctx context.Context, prodId, variantId string) error {
+	id, vid, err := variantIds(prodId, variantId)
+	if err != nil {
+		return err
+	}
+	defer mp.lock(ctx)()
+	if !mp.productExist(id) {
+		return storage.ErrProductNotFound
+	}
+	idx := mp.variantIdx(id, vid)
+	if idx < 0 {
+		return storage.ErrVariantNotFound
+	}
+	variants := append([]models.Variant(nil), mp.variants[id][:idx]...)
+	mp.setVariants(id, append(variants, mp.variants[id][idx+1:]...))
+	return nil
+}
+
+func variantIds(prodId, variantId string) (int, int, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return 0, 0, storage.ErrQuery
+	}
+	vid, err := strconv.Atoi(variantId)
+	if err != nil {
+		return 0, 0, storage.ErrQuery
+	}
+	return id, vid, nil
+}
+
+// variantIdx returns the index of the variant among variants of the product or -1, must be called under the lock
+func (mp *memoryProvider) variantIdx(prodId, variantId int) int {
+	for idx, variant := range mp.variants[prodId] {
+		if variant.Id == variantId {
+			return idx
+		}
+	}
+	return -1
+}
+
+// variantOf checks the variant like the postgres table does and returns its copy,
+// the variant with variantId is not compared with itself. Must be called under the lock
+func (mp *memoryProvider) variantOf(prodId, variantId int, variant models.Variant) (models.Variant, error) {
+	if variant.Sku == "" || len(variant.Options) == 0 {
+		return models.Variant{}, storage.ErrQuery
+	}
+	if _, ok := mp.variantSkus[variant.Sku]; ok && mp.variantSkuOwner(variant.Sku) != variantId {
+		return models.Variant{}, storage.ErrVariantSkuExist
+	}
+	for _, other := range mp.variants[prodId] {
+		if other.Id != variantId && sameOptions(other.Options, variant.Options) {
+			return models.Variant{}, storage.ErrVariantExist
+		}
+	}
+	options := make(map[string]string, len(variant.Options))
+	for name, value := range variant.Options {
+		options[name] = value
+	}
+	variant.Options = options
+	// prices of variants are kept in one json column as they are sent
+	prices := variant.Prices
+	variant.Prices = nil
+	if len(prices) != 0 {
+		variant.Prices = append([]models.Price(nil), prices...)
+	}
+	return variant, nil
+}
+
+// variantSkuOwner returns the id of the variant with the sku, must be called under the lock
+func (mp *memoryProvider) variantSkuOwner(sku string) int {
+	for _, variant := range mp.variants[mp.variantSkus[sku]] {
+		if variant.Sku == sku {
+			return variant.Id
+		}
+	}
+	return 0
+}
+
+// setVariants replaces variants of the product, counts its version and appends the update event.
+// Must be called under the write lock
+func (mp *memoryProvider) setVariants(prodId int, variants []models.Variant) {
+	for _, variant := range mp.variants[prodId] {
+		delete(mp.variantSkus, variant.Sku)
+	}
+	for _, variant := range variants {
+		mp.variantSkus[variant.Sku] = prodId
+	}
+	if len(variants) == 0 {
+		delete(mp.variants, prodId)
+	} else {
+		mp.variants[prodId] = variants
+	}
+	mp.productVersions[prodId]++
+	mp.productEvent(models.EventProductUpdated, prodId)
+}
+
+func sameOptions(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if other, ok := b[name]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+func versionsKey(entity, key string) string {
+	return entity + "/" + key
+}
+
+// SaveVersions appends versions to the history of their products and categories,
+// every version gets the next number of its history
+func (mp *memoryProvider) SaveVersions(ctx context.Context, versions []models.Version) error {
+	defer mp.lock(ctx)()
+	now := time.Now().UTC()
+	for _, version := range versions {
+		historyKey := versionsKey(version.Entity, version.Key)
+		version.Version = len(mp.versions[historyKey]) + 1
+		version.CreatedAt = now
+		mp.versions[historyKey] = append(mp.versions[historyKey], version)
+	}
+	return nil
+}
+
+// GetVersions returns the history of the product or category from the first version
+func (mp *memoryProvider) GetVersions(ctx context.Context, entity, key string) ([]models.Version, error) {
+	defer mp.rlock(ctx)()
+	history := mp.versions[versionsKey(entity, key)]
+	versions := make([]models.Version, len(history))
+	copy(versions, history)
+	return versions, nil
+}
+
+// GetVersion returns ErrVersionNotFound if the history has no such version
+func (mp *memoryProvider) GetVersion(ctx context.Context, entity, key string, number int) (models.Version, error) {
+	defer mp.rlock(ctx)()
+	history := mp.versions[versionsKey(entity, key)]
+	if number < 1 || number > len(history) {
+		return models.Version{}, storage.ErrVersionNotFound
+	}
+	return history[number-1], nil
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgx/v4"
+)
+
+const defaultMigrationTable = "schema_migrations"
+
+// migrationLockKey is the advisory lock held while migrations are applied,
+// so several instances starting together don't apply the same migration
+const migrationLockKey = 7415289
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type migration struct {
+	version int
+	name string
+	up string
+	down string
+}
+
+// loadMigrations reads embedded migrations sorted by version,
+// table names in them are templated from the config fields: "{{.ProductTable}}"
+func loadMigrations(cfg config.PostgresConfig) ([]migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		parts := migrationName.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("%w: wrong file name %s", ErrMigration, entry.Name())
+		}
+		version, _ := strconv.Atoi(parts[1])
+		data, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := template.New(entry.Name()).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMigration, err.Error())
+		}
+		var sql bytes.Buffer
+		if err := tmpl.Execute(&sql, cfg); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMigration, err.Error())
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: parts[2]}
+			byVersion[version] = m
+		}
+		if m.name != parts[2] {
+			return nil, fmt.Errorf("%w: different names of version %d", ErrMigration, version)
+		}
+		if parts[3] == "up" {
+			m.up = sql.String()
+		} else {
+			m.down = sql.String()
+		}
+	}
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("%w: no up migration of version %d", ErrMigration, m.version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+func (pp *postgresProvider) migrationTable() string {
+	if pp.cfg.MigrationTable == "" {
+		return defaultMigrationTable
+	}
+	return pp.cfg.MigrationTable
+}
+
+func (pp *postgresProvider) ensureMigrationTable(ctx context.Context) error {
+	_, err := pp.dbConn.Exec(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS "%s" (
+	version bigint PRIMARY KEY,
+	name varchar NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`,
+	pp.migrationTable()))
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	return nil
+}
+
+// MigrateUp applies all not applied migrations in order, each in its own transaction.
+// Returns applied migrations
+func (pp *postgresProvider) MigrateUp(ctx context.Context) ([]models.Migration, error) {
+	migrations, err := loadMigrations(pp.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := pp.ensureMigrationTable(ctx); err != nil {
+		return nil, err
+	}
+	var applied []models.Migration
+	for _, m := range migrations {
+		done, err := pp.applyMigration(ctx, m, true)
+		if err != nil {
+			return applied, err
+		}
+		if done {
+			applied = append(applied, models.Migration{
+				Version: m.version,
+				Name: m.name,
+				Applied: true,
+			})
+		}
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts the last steps applied migrations. Returns reverted migrations
+func (pp *postgresProvider) MigrateDown(ctx context.Context, steps int) ([]models.Migration, error) {
+	migrations, err := loadMigrations(pp.cfg)
+	if err != nil {
+		return nil, err
+	}
+	status, err := pp.MigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var reverted []models.Migration
+	for idx := len(migrations) - 1; idx >= 0 && len(reverted) < steps; idx-- {
+		if !status[idx].Applied {
+			continue
+		}
+		m := migrations[idx]
+		if m.down == "" {
+			return reverted, fmt.Errorf("%w: no down migration of version %d", ErrMigration, m.version)
+		}
+		done, err := pp.applyMigration(ctx, m, false)
+		if err != nil {
+			return reverted, err
+		}
+		if done {
+			reverted = append(reverted, models.Migration{
+				Version: m.version,
+				Name: m.name,
+			})
+		}
+	}
+	return reverted, nil
+}
+
+// MigrationStatus returns all known migrations with the time they were applied at
+func (pp *postgresProvider) MigrationStatus(ctx context.Context) ([]models.Migration, error) {
+	migrations, err := loadMigrations(pp.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := pp.ensureMigrationTable(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`SELECT version, applied_at FROM "%s"`, pp.migrationTable()))
+	if err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	appliedAt := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version int
+			at time.Time
+		)
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, dbErr(ErrQuery, err)
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	status := make([]models.Migration, len(migrations))
+	for idx, m := range migrations {
+		status[idx] = models.Migration{
+			Version: m.version,
+			Name: m.name,
+		}
+		if at, ok := appliedAt[m.version]; ok {
+			status[idx].Applied = true
+			status[idx].AppliedAt = &at
+		}
+	}
+	return status, nil
+}
+
+// applyMigration runs up or down sql of the migration and records it under the advisory lock.
+// Returns false if another instance has already done it
+func (pp *postgresProvider) applyMigration(ctx context.Context, m migration, up bool) (bool, error) {
+	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, dbErr(ErrStartTx, err)
+	}
+	defer transaction.Rollback(ctx)
+	if _, err := transaction.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockKey); err != nil {
+		return false, dbErr(ErrQuery, err)
+	}
+	var exists bool
+	err = transaction.QueryRow(ctx, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM "%s" WHERE version = $1)`, pp.migrationTable()), m.version).Scan(&exists)
+	if err != nil {
+		return false, dbErr(ErrQuery, err)
+	}
+	if exists == up {
+		return false, nil
+	}
+	sql, record, args := m.up, fmt.Sprintf(`INSERT INTO "%s" (version, name) VALUES ($1, $2)`, pp.migrationTable()), []interface{}{m.version, m.name}
+	if !up {
+		sql, record, args = m.down, fmt.Sprintf(`DELETE FROM "%s" WHERE version = $1`, pp.migrationTable()), []interface{}{m.version}
+	}
+	// without arguments the script is sent as simple query, so it may hold several statements
+	if _, err := transaction.Exec(ctx, sql); err != nil {
+		return false, fmt.Errorf("%w: version %d: %s", ErrMigration, m.version, err.Error())
+	}
+	if _, err := transaction.Exec(ctx, record, args...); err != nil {
+		return false, dbErr(ErrQuery, err)
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return false, dbErr(ErrCommitTx, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/stretchr/testify/suite"
+)
+
+type migrateTestSuite struct {
+	suite.Suite
+	cfg config.PostgresConfig
+}
+
+func TestMigrateSuiteRun(t *testing.T) {
+	suite.Run(t, new(migrateTestSuite))
+}
+
+func (suite *migrateTestSuite) SetupSuite() {
+	suite.cfg = config.PostgresConfig{
+		UserTable: "t-user",
+		CatogoryTable: "t-category",
+		ProductTable: "t-product",
+		ProductCategoryTable: "t-product_category",
+		JobTable: "t-job",
+		VersionTable: "t-version",
+		OutboxTable: "t-outbox",
+		PriceTable: "t-price",
+		BarcodeTable: "t-barcode",
+		VariantTable: "t-variant",
+		WarehouseTable: "t-warehouse",
+		StockTable: "t-stock",
+		ReservationTable: "t-reservation",
+		StockMovementTable: "t-stock_movement",
+		MediaTable: "t-media",
+	}
+}
+
+func (suite *migrateTestSuite) Test_LoadMigrations() {
+	migrations, err := loadMigrations(suite.cfg)
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(migrations)
+	for idx, m := range migrations {
+		if idx > 0 {
+			suite.Require().Greater(m.version, migrations[idx-1].version, "migration: %s", m.name)
+		}
+		suite.Require().NotEmpty(m.up, "migration: %s", m.name)
+		suite.Require().NotEmpty(m.down, "migration: %s", m.name)
+		suite.Require().NotContains(m.up, "{{", "migration: %s", m.name)
+		suite.Require().NotContains(m.down, "{{", "migration: %s", m.name)
+	}
+	suite.Require().Equal(1, migrations[0].version)
+	suite.Require().Contains(migrations[0].up, `"t-product"`)
+	suite.Require().Contains(migrations[0].up, `"t-product_category"`)
+	// constraint must be separated from the foreign key
+	suite.Require().Contains(migrations[0].up, "ON UPDATE CASCADE,")
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "{{.ProductCategoryTable}}";
+DROP TABLE IF EXISTS "{{.ProductTable}}";
+DROP TABLE IF EXISTS "{{.CatogoryTable}}";
+DROP TABLE IF EXISTS "{{.UserTable}}";
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS "{{.JobTable}}" (
+    job_id bigint PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    kind varchar(40) NOT NULL,
+    state varchar(20) NOT NULL,
+    content_type varchar NOT NULL,
+    payload_path varchar NOT NULL,
+    atomic boolean NOT NULL DEFAULT false,
+    cancel_requested boolean NOT NULL DEFAULT false,
+    processed integer NOT NULL DEFAULT 0,
+    created integer NOT NULL DEFAULT 0,
+    skipped integer NOT NULL DEFAULT 0,
+    invalid integer NOT NULL DEFAULT 0,
+    failed integer NOT NULL DEFAULT 0,
+    error varchar,
+    report jsonb,
+    created_by varchar(40),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    started_at timestamptz,
+    finished_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS "{{.JobTable}}_state_idx" ON "{{.JobTable}}" (state, job_id);
@@ -0,0 +1,14 @@
+-- the trash is emptied, unique constraints can't be restored with deleted duplicates
+DELETE FROM "{{.ProductTable}}" WHERE deleted_at IS NOT NULL;
+DELETE FROM "{{.ProductCategoryTable}}" WHERE category_id IN (
+    SELECT category_id FROM "{{.CatogoryTable}}" WHERE deleted_at IS NOT NULL
+);
+DELETE FROM "{{.CatogoryTable}}" WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS "{{.CatogoryTable}}_deleted_at_idx";
+DROP INDEX IF EXISTS "{{.ProductTable}}_deleted_at_idx";
+DROP INDEX IF EXISTS "{{.CatogoryTable}}_code_idx";
+DROP INDEX IF EXISTS "{{.ProductTable}}_name_idx";
+ALTER TABLE "{{.CatogoryTable}}" ADD CONSTRAINT "{{.CatogoryTable}}_code_key" UNIQUE (code);
+ALTER TABLE "{{.ProductTable}}" ADD CONSTRAINT "{{.ProductTable}}_name_key" UNIQUE (name);
+ALTER TABLE "{{.CatogoryTable}}" DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE "{{.ProductTable}}" DROP COLUMN IF EXISTS deleted_at;
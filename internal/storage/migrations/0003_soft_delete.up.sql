@@ -0,0 +1,9 @@
+ALTER TABLE "{{.CatogoryTable}}" ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE "{{.ProductTable}}" ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+-- codes and names are unique only among not deleted rows, the deleted ones may be taken again
+ALTER TABLE "{{.CatogoryTable}}" DROP CONSTRAINT IF EXISTS "{{.CatogoryTable}}_code_key";
+ALTER TABLE "{{.ProductTable}}" DROP CONSTRAINT IF EXISTS "{{.ProductTable}}_name_key";
+CREATE UNIQUE INDEX IF NOT EXISTS "{{.CatogoryTable}}_code_idx" ON "{{.CatogoryTable}}" (code) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS "{{.ProductTable}}_name_idx" ON "{{.ProductTable}}" (name) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS "{{.CatogoryTable}}_deleted_at_idx" ON "{{.CatogoryTable}}" (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS "{{.ProductTable}}_deleted_at_idx" ON "{{.ProductTable}}" (deleted_at) WHERE deleted_at IS NOT NULL;
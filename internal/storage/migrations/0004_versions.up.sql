@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "{{.VersionTable}}" (
+    entity varchar(20) NOT NULL,
+    entity_key varchar NOT NULL,
+    version integer NOT NULL,
+    action varchar(20) NOT NULL,
+    actor varchar,
+    reverted_to integer,
+    snapshot jsonb NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (entity, entity_key, version)
+);
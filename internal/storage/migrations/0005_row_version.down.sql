@@ -0,0 +1,2 @@
+ALTER TABLE "{{.ProductTable}}" DROP COLUMN IF EXISTS version;
+ALTER TABLE "{{.CatogoryTable}}" DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+-- version is compared by edits with If-Match and counted by every update
+ALTER TABLE "{{.CatogoryTable}}" ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+ALTER TABLE "{{.ProductTable}}" ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "{{.OutboxTable}}" (
+    event_id bigserial PRIMARY KEY,
+    aggregate varchar(20) NOT NULL,
+    aggregate_id varchar NOT NULL,
+    event_type varchar(50) NOT NULL,
+    payload json NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
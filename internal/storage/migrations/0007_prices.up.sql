@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "{{.PriceTable}}" (
+    product_id int NOT NULL,
+    kind varchar(10) NOT NULL CHECK (kind IN ('list', 'sale')),
+    amount bigint NOT NULL CHECK (amount >= 0),
+    currency char(3) NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    valid_from timestamptz,
+    valid_to timestamptz,
+    FOREIGN KEY (product_id) REFERENCES "{{.ProductTable}}" ON DELETE CASCADE,
+    CHECK (valid_from < valid_to)
+);
+CREATE INDEX IF NOT EXISTS "{{.PriceTable}}_product_idx" ON "{{.PriceTable}}" (product_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "{{.BarcodeTable}}";
+DROP INDEX IF EXISTS "{{.ProductTable}}_sku_idx";
+ALTER TABLE "{{.ProductTable}}" DROP COLUMN IF EXISTS sku;
@@ -0,0 +1,10 @@
+ALTER TABLE "{{.ProductTable}}" ADD COLUMN IF NOT EXISTS sku varchar(64) CHECK (sku <> '');
+-- skus are unique only among not deleted products like names
+CREATE UNIQUE INDEX IF NOT EXISTS "{{.ProductTable}}_sku_idx" ON "{{.ProductTable}}" (sku) WHERE deleted_at IS NULL;
+-- barcodes of deleted products stay taken until they are purged
+CREATE TABLE IF NOT EXISTS "{{.BarcodeTable}}" (
+    code varchar(14) PRIMARY KEY CHECK (code ~ '^([0-9]{8}|[0-9]{12,14})$'),
+    product_id int NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES "{{.ProductTable}}" ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "{{.BarcodeTable}}_product_idx" ON "{{.BarcodeTable}}" (product_id);
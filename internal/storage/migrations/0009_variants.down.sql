@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "{{.VariantTable}}";
+ALTER TABLE "{{.ProductTable}}" DROP COLUMN IF EXISTS options;
@@ -0,0 +1,11 @@
+ALTER TABLE "{{.ProductTable}}" ADD COLUMN IF NOT EXISTS options jsonb CHECK (jsonb_typeof(options) = 'array');
+-- options of a variant are a json object, jsonb equality ignores key order so they are unique as sets
+CREATE TABLE IF NOT EXISTS "{{.VariantTable}}" (
+    variant_id serial PRIMARY KEY,
+    product_id int NOT NULL,
+    sku varchar(64) NOT NULL UNIQUE CHECK (sku <> ''),
+    options jsonb NOT NULL CHECK (jsonb_typeof(options) = 'object'),
+    prices jsonb CHECK (jsonb_typeof(prices) = 'array'),
+    FOREIGN KEY (product_id) REFERENCES "{{.ProductTable}}" ON DELETE CASCADE,
+    CONSTRAINT "{{.VariantTable}}_options_key" UNIQUE (product_id, options)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE "{{.CatogoryTable}}" ADD COLUMN IF NOT EXISTS attributes jsonb CHECK (jsonb_typeof(attributes) = 'array');
+ALTER TABLE "{{.ProductTable}}" ADD COLUMN IF NOT EXISTS attributes jsonb CHECK (jsonb_typeof(attributes) = 'object');
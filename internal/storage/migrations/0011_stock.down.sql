@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "{{.StockMovementTable}}";
+DROP TABLE IF EXISTS "{{.ReservationTable}}";
+DROP TABLE IF EXISTS "{{.StockTable}}";
+DROP TABLE IF EXISTS "{{.WarehouseTable}}";
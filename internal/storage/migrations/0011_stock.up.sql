@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS "{{.WarehouseTable}}" (
+    warehouse_id serial PRIMARY KEY,
+    code varchar(64) NOT NULL UNIQUE CHECK (code <> ''),
+    name varchar(255) NOT NULL CHECK (name <> '')
+);
+-- the check is evaluated on every change of the row under its lock,
+-- so concurrent reservations can't take more units than there are on hand
+CREATE TABLE IF NOT EXISTS "{{.StockTable}}" (
+    product_id int NOT NULL,
+    warehouse_id int NOT NULL,
+    on_hand int NOT NULL DEFAULT 0,
+    reserved int NOT NULL DEFAULT 0,
+    PRIMARY KEY (product_id, warehouse_id),
+    FOREIGN KEY (product_id) REFERENCES "{{.ProductTable}}" ON DELETE CASCADE,
+    FOREIGN KEY (warehouse_id) REFERENCES "{{.WarehouseTable}}",
+    CONSTRAINT "{{.StockTable}}_reserved_check" CHECK (reserved >= 0 AND on_hand >= reserved)
+);
+CREATE INDEX IF NOT EXISTS "{{.StockTable}}_available_idx" ON "{{.StockTable}}" (product_id) WHERE on_hand > reserved;
+CREATE TABLE IF NOT EXISTS "{{.ReservationTable}}" (
+    reservation_id serial PRIMARY KEY,
+    product_id int NOT NULL,
+    warehouse_id int NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    state varchar(10) NOT NULL CHECK (state IN ('active', 'released', 'committed')),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    closed_at timestamptz,
+    FOREIGN KEY (product_id, warehouse_id) REFERENCES "{{.StockTable}}" ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS "{{.StockMovementTable}}" (
+    movement_id bigserial PRIMARY KEY,
+    product_id int NOT NULL,
+    warehouse_id int NOT NULL,
+    kind varchar(10) NOT NULL CHECK (kind IN ('adjust', 'reserve', 'release', 'commit')),
+    on_hand int NOT NULL,
+    reserved int NOT NULL,
+    reservation_id int,
+    reason text,
+    actor varchar,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (product_id, warehouse_id) REFERENCES "{{.StockTable}}" ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "{{.StockMovementTable}}_product_idx" ON "{{.StockMovementTable}}" (product_id, movement_id);
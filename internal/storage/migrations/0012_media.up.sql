@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS "{{.MediaTable}}" (
+    media_id serial PRIMARY KEY,
+    product_id int NOT NULL REFERENCES "{{.ProductTable}}" ON DELETE CASCADE,
+    file_key varchar(255) NOT NULL UNIQUE,
+    thumbnail_key varchar(255) NOT NULL UNIQUE,
+    url text NOT NULL,
+    thumbnail_url text NOT NULL,
+    content_type varchar(50) NOT NULL,
+    size int NOT NULL CHECK (size > 0),
+    width int NOT NULL CHECK (width > 0),
+    height int NOT NULL CHECK (height > 0),
+    position int NOT NULL CHECK (position >= 0),
+    is_primary boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS "{{.MediaTable}}_product_idx" ON "{{.MediaTable}}" (product_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS "{{.MediaTable}}_primary_idx" ON "{{.MediaTable}}" (product_id) WHERE is_primary;
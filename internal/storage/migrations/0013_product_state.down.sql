@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "{{.ProductTable}}_state_idx";
+ALTER TABLE "{{.ProductTable}}" DROP COLUMN IF EXISTS state;
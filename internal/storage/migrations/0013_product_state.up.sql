@@ -0,0 +1,5 @@
+-- products saved before the workflow stay published, new ones start as drafts
+ALTER TABLE "{{.ProductTable}}" ADD COLUMN IF NOT EXISTS state varchar(16) NOT NULL DEFAULT 'published'
+    CHECK (state IN ('draft', 'in_review', 'published', 'archived'));
+ALTER TABLE "{{.ProductTable}}" ALTER COLUMN state SET DEFAULT 'draft';
+CREATE INDEX IF NOT EXISTS "{{.ProductTable}}_state_idx" ON "{{.ProductTable}}" (state) WHERE deleted_at IS NULL;
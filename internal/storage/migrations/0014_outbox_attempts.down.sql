@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "{{.OutboxTable}}_aggregate_idx";
+ALTER TABLE "{{.OutboxTable}}" DROP COLUMN IF EXISTS dead_at;
+ALTER TABLE "{{.OutboxTable}}" DROP COLUMN IF EXISTS claimed_until;
+ALTER TABLE "{{.OutboxTable}}" DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,6 @@
+-- the relay claims events for a while instead of holding a transaction while it publishes them,
+-- events failed too many times are dead and are not relayed anymore
+ALTER TABLE "{{.OutboxTable}}" ADD COLUMN IF NOT EXISTS attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE "{{.OutboxTable}}" ADD COLUMN IF NOT EXISTS claimed_until timestamptz;
+ALTER TABLE "{{.OutboxTable}}" ADD COLUMN IF NOT EXISTS dead_at timestamptz;
+CREATE INDEX IF NOT EXISTS "{{.OutboxTable}}_aggregate_idx" ON "{{.OutboxTable}}" (aggregate, aggregate_id, event_id) WHERE dead_at IS NULL;
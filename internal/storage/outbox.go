@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// outboxLockKey is the advisory lock held by the relay while it claims events,
+// so two relays don't claim the same events at once
+const outboxLockKey = 7415290
+
+// productEvent returns the statement writing the event for every product row selected by from,
+// the row has to be named p
+func (pp *postgresProvider) productEvent(eventType, from string) string {
+	return fmt.Sprintf(`
+INSERT INTO "%s" (aggregate, aggregate_id, event_type, payload)
+SELECT '%s', p.product_id::text, '%s',
+json_build_object('id', p.product_id::text, 'name', p.name, 'description', p.description, 'state', p.state, 'version', p.version)
+FROM %s`,
+	pp.cfg.OutboxTable,
+	models.EventAggregateProduct,
+	eventType,
+	from)
+}
+
+// productEventById writes the event of the product with id $1
+func (pp *postgresProvider) productEventById(eventType string) string {
+	return pp.productEvent(eventType, fmt.Sprintf(`"%s" as p WHERE p.product_id = $1`, pp.cfg.ProductTable))
+}
+
+// categoryEvent returns the statement writing the event for every category row selected by from,
+// the row has to be named c
+func (pp *postgresProvider) categoryEvent(eventType, from string) string {
+	return fmt.Sprintf(`
+INSERT INTO "%s" (aggregate, aggregate_id, event_type, payload)
+SELECT '%s', c.category_id::text, '%s',
+json_build_object('name', c.name, 'code', c.code, 'description', c.description, 'version', c.version)
+FROM %s`,
+	pp.cfg.OutboxTable,
+	models.EventAggregateCategory,
+	eventType,
+	from)
+}
+
+// RelayEvents claims up to limit oldest events for claimTimeout, passes them to publish and applies its result.
+// Claimed events are not claimed by other relays, nor later events of their aggregates, so aggregates keep their order
+// while relays publish without a transaction. Events stay in the outbox until they are published, so they are
+// delivered at least once. Events of the relay stopped before the end are claimed again after claimTimeout
+func (pp *postgresProvider) RelayEvents(ctx context.Context, limit int, claimTimeout time.Duration, publish func([]models.Event) models.RelayResult) error {
+	events, err := pp.claimEvents(ctx, limit, claimTimeout)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	result := publish(events)
+	claimed := make([]int, len(events))
+	for idx, event := range events {
+		claimed[idx] = event.Id
+	}
+	return pp.finishEvents(ctx, claimed, result)
+}
+
+// claimEvents claims events in its own transaction, nothing is claimed if another relay is claiming right now
+func (pp *postgresProvider) claimEvents(ctx context.Context, limit int, claimTimeout time.Duration) ([]models.Event, error) {
+	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, dbErr(ErrStartTx, err)
+	}
+	var locked bool
+	if err := transaction.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", outboxLockKey).Scan(&locked); err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return nil, dbErr(ErrRollbackTx, err)
+		}
+		return nil, dbErr(ErrQuery, err)
+	}
+	if !locked {
+		if err := transaction.Rollback(ctx); err != nil {
+			return nil, dbErr(ErrRollbackTx, err)
+		}
+		return nil, nil
+	}
+	events, err := pp.pendingEvents(ctx, transaction, limit, claimTimeout)
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return nil, dbErr(ErrRollbackTx, err)
+		}
+		return nil, err
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return nil, dbErr(ErrCommitTx, err)
+	}
+	return events, nil
+}
+
+// pendingEvents claims events which are not dead nor claimed and have no claimed earlier events of their aggregates
+func (pp *postgresProvider) pendingEvents(ctx context.Context, transaction pgx.Tx, limit int, claimTimeout time.Duration) ([]models.Event, error) {
+	rows, err := transaction.Query(ctx, fmt.Sprintf(`
+WITH pending AS (
+	SELECT o.event_id
+	FROM "%[1]s" as o
+	WHERE o.dead_at IS NULL
+	AND (o.claimed_until IS NULL OR o.claimed_until < now())
+	AND NOT EXISTS (
+		SELECT 1 FROM "%[1]s" as c
+		WHERE c.aggregate = o.aggregate AND c.aggregate_id = o.aggregate_id AND c.event_id < o.event_id
+		AND c.dead_at IS NULL AND c.claimed_until >= now()
+	)
+	ORDER BY o.event_id
+	LIMIT $1
+)
+UPDATE "%[1]s" as o
+SET claimed_until = now() + $2 * interval '1 microsecond'
+FROM pending
+WHERE o.event_id = pending.event_id
+RETURNING o.event_id, o.aggregate, o.aggregate_id, o.event_type, o.payload::text, o.created_at, o.attempts`,
+	pp.cfg.OutboxTable),
+	limit,
+	claimTimeout.Microseconds())
+	if err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	events := []models.Event{}
+	for rows.Next() {
+		var (
+			event models.Event
+			payload string
+		)
+		if err := rows.Scan(&event.Id, &event.Aggregate, &event.AggregateId, &event.Type, &payload, &event.CreatedAt, &event.Attempts); err != nil {
+			return nil, dbErr(ErrQuery, err)
+		}
+		event.Payload = []byte(payload)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	// returned rows have no order
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Id < events[j].Id
+	})
+	return events, nil
+}
+
+// finishEvents removes published events and releases the rest of claimed ones in a short transaction,
+// failed and dead events get their attempt counted
+func (pp *postgresProvider) finishEvents(ctx context.Context, claimed []int, result models.RelayResult) error {
+	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return dbErr(ErrStartTx, err)
+	}
+	if len(result.Published) != 0 {
+		_, err = transaction.Exec(ctx, fmt.Sprintf(`
+DELETE FROM "%s" WHERE event_id = ANY ($1)`,
+		pp.cfg.OutboxTable),
+		result.Published)
+		if err != nil {
+			if err := transaction.Rollback(ctx); err != nil {
+				return dbErr(ErrRollbackTx, err)
+			}
+			return dbErr(ErrQuery, err)
+		}
+	}
+	_, err = transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s"
+SET claimed_until = NULL,
+attempts = attempts + CASE WHEN event_id = ANY ($2) OR event_id = ANY ($3) THEN 1 ELSE 0 END,
+dead_at = CASE WHEN event_id = ANY ($3) THEN now() END
+WHERE event_id = ANY ($1)`,
+	pp.cfg.OutboxTable),
+	claimed,
+	result.Failed,
+	result.Dead)
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return dbErr(ErrRollbackTx, err)
+		}
+		return dbErr(ErrQuery, err)
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return dbErr(ErrCommitTx, err)
+	}
+	return nil
+}
@@ -3,17 +3,20 @@ package storage
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/EwvwGeN/cataloger/internal/config"
-	"github.com/jackc/pgx/v4"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 type postgresProvider struct {
 	cfg config.PostgresConfig
-	dbConn *pgx.Conn
+	dbConn *retryDB
+	replicas *replicaSet
 }
 
-func NewPostgresProvider(ctx context.Context, cfg config.PostgresConfig) (*postgresProvider, error) {
+func NewPostgresProvider(ctx context.Context, logger *slog.Logger, cfg config.PostgresConfig) (*postgresProvider, error) {
 	connString := fmt.Sprintf("%s://%s:%s@%s:%s/%s",
 		cfg.ConectionFormat,
 		cfg.User,
@@ -22,12 +25,94 @@ func NewPostgresProvider(ctx context.Context, cfg config.PostgresConfig) (*postg
 		cfg.Port,
 		cfg.Database,
 	)
-	conn, err := pgx.Connect(ctx, connString)
+	pool, err := newPool(ctx, connString, cfg)
 	if err != nil {
+		return nil, err
+	}
+	// pool connects lazily, ping to fail at startup if the database is unreachable
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to connect to postgresql: %w", err)
 	}
-	return &postgresProvider{
+	pp := &postgresProvider{
 		cfg: cfg,
-		dbConn: conn,
-	}, nil
-}
\ No newline at end of file
+		dbConn: newRetryDB(pool, cfg),
+	}
+	if len(cfg.Replicas) == 0 {
+		return pp, nil
+	}
+	pp.replicas = &replicaSet{
+		log: logger.With(slog.String("component", "replicas")),
+		maxLag: cfg.ReplicaMaxLag,
+		period: cfg.ReplicaCheckPeriod,
+	}
+	if pp.replicas.period <= 0 {
+		pp.replicas.period = defaultReplicaCheckPeriod
+	}
+	// failed read on the replica goes to the primary at once instead of retries
+	replicaCfg := cfg
+	replicaCfg.RetryAttempts = 1
+	for _, dsn := range cfg.Replicas {
+		replicaPool, err := newPool(ctx, dsn, cfg)
+		if err != nil {
+			pp.Close()
+			return nil, fmt.Errorf("replica: %w", err)
+		}
+		pp.replicas.replicas = append(pp.replicas.replicas, &replica{
+			host: replicaPool.Config().ConnConfig.Host,
+			db: newRetryDB(replicaPool, replicaCfg),
+		})
+	}
+	// unreachable replicas do not stop the start, they get reads after they become healthy
+	pp.replicas.check(ctx)
+	pp.replicas.run()
+	return pp, nil
+}
+
+func newPool(ctx context.Context, connString string, cfg config.PostgresConfig) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgresql config: %w", err)
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = int32(cfg.MinConns)
+	}
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxConns)
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgresql: %w", err)
+	}
+	return pool, nil
+}
+
+// Close stops replica checks, waits for acquired connections to be released and closes the pools
+func (pp *postgresProvider) Close() {
+	pp.replicas.close()
+	pp.dbConn.Close()
+}
+
+func (pp *postgresProvider) PoolStats() models.PoolStats {
+	stat := pp.dbConn.Stat()
+	return models.PoolStats{
+		AcquireCount: stat.AcquireCount(),
+		AcquireDuration: stat.AcquireDuration(),
+		AcquiredConns: stat.AcquiredConns(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		ConstructingConns: stat.ConstructingConns(),
+		EmptyAcquireCount: stat.EmptyAcquireCount(),
+		IdleConns: stat.IdleConns(),
+		MaxConns: stat.MaxConns(),
+		TotalConns: stat.TotalConns(),
+	}
+}
@@ -0,0 +1,131 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/EwvwGeN/cataloger/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+type migrator interface {
+	MigrateDown(ctx context.Context, steps int) ([]models.Migration, error)
+	MigrationStatus(ctx context.Context) ([]models.Migration, error)
+}
+
+// TestPostgresBehaviorSuiteRun needs a database, it is set by TEST_POSTGRES_DB_* variables.
+// Every test migrates its own tables up and down
+func TestPostgresBehaviorSuiteRun(t *testing.T) {
+	if os.Getenv("TEST_POSTGRES_DB_HOST") == "" {
+		t.Skip("TEST_POSTGRES_DB_HOST is not set")
+	}
+	cfg := testPostgresConfig("behavior")
+	storagetest.Run(t, func(t *testing.T) storagetest.Repository {
+		return newTestPostgres(t, cfg)
+	})
+}
+
+// BenchmarkPostgresSaveProducts compares the bulk save of import batches with saving their products one by one,
+// it needs a database like TestPostgresBehaviorSuiteRun
+func BenchmarkPostgresSaveProducts(b *testing.B) {
+	if os.Getenv("TEST_POSTGRES_DB_HOST") == "" {
+		b.Skip("TEST_POSTGRES_DB_HOST is not set")
+	}
+	ctx := context.Background()
+	postgres := newTestPostgres(b, testPostgresConfig("bench"))
+	catIds, err := postgres.InserOrGetCategiriesId(ctx, []models.Category{
+		{Name: "first", Code: "first", Description: "first"},
+		{Name: "second", Code: "second", Description: "second"},
+	})
+	require.NoError(b, err)
+	batch := func(prefix string, size int) ([]models.Product, [][]int) {
+		products := make([]models.Product, size)
+		catsIds := make([][]int, size)
+		for idx := range products {
+			products[idx] = models.Product{Name: fmt.Sprintf("%s-%d", prefix, idx), Description: "bench product"}
+			catsIds[idx] = []int{catIds["first"], catIds["second"]}
+		}
+		return products, catsIds
+	}
+	for _, size := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("by_row/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				products, catsIds := batch(fmt.Sprintf("row-%d-%d", size, i), size)
+				b.StartTimer()
+				for idx, product := range products {
+					_, err := postgres.SaveProduct(ctx, product, catsIds[idx])
+					require.NoError(b, err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("bulk/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				products, catsIds := batch(fmt.Sprintf("bulk-%d-%d", size, i), size)
+				b.StartTimer()
+				results, err := postgres.SaveProducts(ctx, products, catsIds, false)
+				require.NoError(b, err)
+				require.Equal(b, models.ImportStatusCreated, results[size-1].Status)
+			}
+		})
+	}
+}
+
+// testPostgresConfig returns the config of the test database with tables named by prefix
+func testPostgresConfig(prefix string) config.PostgresConfig {
+	return config.PostgresConfig{
+		ConectionFormat: "postgres",
+		Host: os.Getenv("TEST_POSTGRES_DB_HOST"),
+		Port: os.Getenv("TEST_POSTGRES_DB_PORT"),
+		User: os.Getenv("TEST_POSTGRES_DB_USER"),
+		Password: os.Getenv("TEST_POSTGRES_DB_PASS"),
+		Database: os.Getenv("TEST_POSTGRES_DB_NAME"),
+		UserTable: prefix + "-user",
+		CatogoryTable: prefix + "-category",
+		ProductTable: prefix + "-product",
+		ProductCategoryTable: prefix + "-product_category",
+		JobTable: prefix + "-job",
+		VersionTable: prefix + "-version",
+		OutboxTable: prefix + "-outbox",
+		PriceTable: prefix + "-price",
+		BarcodeTable: prefix + "-barcode",
+		VariantTable: prefix + "-variant",
+		WarehouseTable: prefix + "-warehouse",
+		StockTable: prefix + "-stock",
+		ReservationTable: prefix + "-reservation",
+		StockMovementTable: prefix + "-stock_movement",
+		MediaTable: prefix + "-media",
+		MigrationTable: prefix + "_migrations",
+	}
+}
+
+// newTestPostgres returns the provider with migrated tables which are dropped at the end of the test
+func newTestPostgres(tb testing.TB, cfg config.PostgresConfig) storagetest.Repository {
+	ctx := context.Background()
+	postgres, err := storage.NewPostgresProvider(ctx, slog.New(slog.NewTextHandler(io.Discard, nil)), cfg)
+	require.NoError(tb, err)
+	// tables left by an interrupted run are dropped too
+	migrateDownAll(tb, postgres)
+	_, err = postgres.MigrateUp(ctx)
+	require.NoError(tb, err)
+	tb.Cleanup(func() {
+		migrateDownAll(tb, postgres)
+		postgres.Close()
+	})
+	return postgres
+}
+
+func migrateDownAll(tb testing.TB, m migrator) {
+	status, err := m.MigrationStatus(context.Background())
+	require.NoError(tb, err)
+	_, err = m.MigrateDown(context.Background(), len(status))
+	require.NoError(tb, err)
+}
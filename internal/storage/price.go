@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// productPrices returns the column with prices of the product p as json array, NULL if it has no prices
+func (pp *postgresProvider) productPrices() string {
+	return fmt.Sprintf(`(
+	SELECT json_agg(json_build_object(
+		'kind', pr.kind, 'amount', pr.amount, 'currency', pr.currency, 'valid_from', pr.valid_from, 'valid_to', pr.valid_to
+	) ORDER BY pr.kind, pr.currency, pr.valid_from NULLS FIRST)
+	FROM "%s" as pr
+	WHERE pr.product_id = p.product_id
+) as prices`,
+	pp.cfg.PriceTable)
+}
+
+// priceFilter returns the condition on the product p for price bounds and currency of the filter,
+// its arguments are bound to placeholders from $n to $n+2
+func (pp *postgresProvider) priceFilter(filter models.ProductFilter, n int) (string, []interface{}) {
+	return fmt.Sprintf(`($%[2]d::bigint IS NULL AND $%[3]d::bigint IS NULL AND $%[4]d::varchar = '' OR EXISTS (
+	SELECT 1
+	FROM "%[1]s" as pr
+	WHERE pr.product_id = p.product_id
+	AND (pr.valid_from IS NULL OR pr.valid_from <= now())
+	AND (pr.valid_to IS NULL OR pr.valid_to > now())
+	AND ($%[4]d::varchar = '' OR pr.currency = $%[4]d::varchar)
+	GROUP BY pr.currency
+	HAVING ($%[2]d::bigint IS NULL OR MIN(pr.amount) >= $%[2]d::bigint)
+	AND ($%[3]d::bigint IS NULL OR MIN(pr.amount) <= $%[3]d::bigint)
+))`,
+	pp.cfg.PriceTable,
+	n,
+	n+1,
+	n+2), []interface{}{filter.MinPrice, filter.MaxPrice, filter.Currency}
+}
+
+// insertPrices adds prices to the product by one statement
+func (pp *postgresProvider) insertPrices(ctx context.Context, transaction pgx.Tx, prodId interface{}, prices []models.Price) error {
+	if len(prices) == 0 {
+		return nil
+	}
+	var (
+		kinds = make([]string, len(prices))
+		amounts = make([]int64, len(prices))
+		currencies = make([]string, len(prices))
+		validFrom = make([]*time.Time, len(prices))
+		validTo = make([]*time.Time, len(prices))
+	)
+	for idx, price := range prices {
+		kinds[idx] = price.Kind
+		amounts[idx] = price.Amount
+		currencies[idx] = price.Currency
+		validFrom[idx] = price.ValidFrom
+		validTo[idx] = price.ValidTo
+	}
+	_, err := transaction.Exec(ctx, fmt.Sprintf(`
+INSERT INTO "%s" (product_id, kind, amount, currency, valid_from, valid_to)
+SELECT $1::int, * FROM unnest($2::varchar[], $3::bigint[], $4::varchar[], $5::timestamptz[], $6::timestamptz[])`,
+	pp.cfg.PriceTable),
+	prodId,
+	kinds,
+	amounts,
+	currencies,
+	validFrom,
+	validTo)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	return nil
+}
+
+// replacePrices sets exactly the prices to the product
+func (pp *postgresProvider) replacePrices(ctx context.Context, transaction pgx.Tx, prodId string, prices []models.Price) error {
+	_, err := transaction.Exec(ctx, fmt.Sprintf(`DELETE FROM "%s" WHERE product_id = $1`, pp.cfg.PriceTable), prodId)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	return pp.insertPrices(ctx, transaction, prodId, prices)
+}
@@ -7,34 +7,54 @@ import (
 	"strconv"
 
 	"github.com/EwvwGeN/cataloger/internal/domain/models"
-	"github.com/jackc/pgconn"
+	"github.com/EwvwGeN/cataloger/internal/validator"
 	"github.com/jackc/pgx/v4"
 )
 
 func (pp *postgresProvider) SaveProduct(ctx context.Context, product models.Product, catIds []int) (string, error) {
+	var id string
+	err := pp.dbConn.retry(ctx, func() error {
+		var err error
+		id, err = pp.saveProduct(ctx, product, catIds)
+		return err
+	})
+	return id, err
+}
+
+func (pp *postgresProvider) saveProduct(ctx context.Context, product models.Product, catIds []int) (string, error) {
 	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return "", ErrStartTx
+		return "", dbErr(ErrStartTx, err)
+	}
+	options, err := jsonb(product.Options, len(product.Options) == 0)
+	if err != nil {
+		transaction.Rollback(ctx)
+		return "", dbErr(ErrQuery, err)
+	}
+	attributes, err := jsonb(product.Attributes, len(product.Attributes) == 0)
+	if err != nil {
+		transaction.Rollback(ctx)
+		return "", dbErr(ErrQuery, err)
 	}
 	var id int
 	err = transaction.QueryRow(ctx, fmt.Sprintf(`
-INSERT INTO "%s" (name, description)
-VALUES($1,$2)
+INSERT INTO "%s" (name, description, sku, options, attributes)
+VALUES($1,$2,$3,$4,$5)
 RETURNING product_id;`,
 	pp.cfg.ProductTable),
 	product.Name,
-	product.Description).Scan(&id)
+	product.Description,
+	nullSku(product.Sku),
+	options,
+	attributes).Scan(&id)
 	if err != nil {
 		if err := transaction.Rollback(ctx); err != nil {
-			return "", ErrRollbackTx
+			return "", dbErr(ErrRollbackTx, err)
 		}
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23505" {
-				return "", ErrProductExist
-			}
+		if existErr := pp.productExistErr(err); existErr != nil {
+			return "", existErr
 		}
-		return "", ErrQuery
+		return "", dbErr(ErrQuery, err)
 	}
 	for _, сid := range catIds {
 		_, err = transaction.Exec(ctx, fmt.Sprintf(`
@@ -45,141 +65,601 @@ VALUES ($1, $2)`,
 		сid)
 		if err != nil {
 			transaction.Rollback(ctx)
-			return "", ErrQuery
+			return "", dbErr(ErrQuery, err)
 		}
 	}
+	if err := pp.insertPrices(ctx, transaction, id, product.Prices); err != nil {
+		transaction.Rollback(ctx)
+		return "", err
+	}
+	if err := pp.insertBarcodes(ctx, transaction, id, product.Barcodes); err != nil {
+		transaction.Rollback(ctx)
+		return "", err
+	}
+	if _, err = transaction.Exec(ctx, pp.productEventById(models.EventProductCreated), id); err != nil {
+		transaction.Rollback(ctx)
+		return "", dbErr(ErrQuery, err)
+	}
 	if err := transaction.Commit(ctx); err != nil {
-		return "", ErrCommitTx
+		return "", dbErr(ErrCommitTx, err)
 	}
 	return strconv.Itoa(id), nil
 }
 
-func (pp *postgresProvider) SaveProducts(ctx context.Context, products []models.Product, catsIds [][]int) (error) {
+// SaveProducts inserts products with their category links and reports outcome of every product.
+// Products are copied into the staging table and saved by one statement, rows failing the checks
+// of the product table or linked to missing categories are not saved and do not break the others.
+// In atomic mode the first failed product rolls back the whole batch
+func (pp *postgresProvider) SaveProducts(ctx context.Context, products []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error) {
+	var results []models.ImportRowResult
+	err := pp.dbConn.retry(ctx, func() error {
+		var err error
+		results, err = pp.saveProducts(ctx, products, catsIds, atomic)
+		return err
+	})
+	return results, err
+}
+
+func (pp *postgresProvider) saveProducts(ctx context.Context, products []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error) {
+	results := make([]models.ImportRowResult, len(products))
+	if len(products) == 0 {
+		return results, nil
+	}
 	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return ErrStartTx
+		return nil, dbErr(ErrStartTx, err)
+	}
+	candidates, err := pp.checkProducts(ctx, transaction, products, catsIds, results)
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return nil, dbErr(ErrRollbackTx, err)
+		}
+		return nil, err
+	}
+	for idx := range results {
+		if atomic && results[idx].Status == models.ImportStatusFailed {
+			if err := transaction.Rollback(ctx); err != nil {
+				return nil, dbErr(ErrRollbackTx, err)
+			}
+			rollBackResults(results, products, idx)
+			return results, nil
+		}
+	}
+	if len(candidates) != 0 {
+		if err := pp.insertStagedProducts(ctx, transaction, candidates, results); err != nil {
+			if err := transaction.Rollback(ctx); err != nil {
+				return nil, dbErr(ErrRollbackTx, err)
+			}
+			return nil, err
+		}
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return nil, dbErr(ErrCommitTx, err)
 	}
+	return results, nil
+}
+
+// checkProducts stages products and sets the outcome of the rows which are not saved, indexes
+// of the rest are returned. The checks follow the savepoint per row which the batch replaces,
+// see classifyProducts. Fields are checked by the validator before staging, so the staged rows are
+// checked only against the saved ones and the constraints of the tables stay the only checks in SQL
+func (pp *postgresProvider) checkProducts(ctx context.Context, transaction pgx.Tx, products []models.Product, catsIds [][]int, results []models.ImportRowResult) ([]int, error) {
+	checks := make([]productCheck, len(products))
+	productRows := make([][]interface{}, len(products))
+	var linkRows, priceRows, barcodeRows [][]interface{}
 	for idx, product := range products {
-		var prodId int
-		err = transaction.QueryRow(ctx, fmt.Sprintf(`
-INSERT INTO "%s" (name, description)
-VALUES($1,$2)
-ON CONFLICT (name) DO NOTHING
-RETURNING product_id;`,
-			pp.cfg.ProductTable),
-			product.Name,
-			product.Description).Scan(&prodId)
+		checks[idx].failed = validator.ProductLimitsInvalidReason(product) != ""
+		options, err := jsonb(product.Options, len(product.Options) == 0)
 		if err != nil {
-			continue
+			return nil, dbErr(ErrQuery, err)
+		}
+		attributes, err := jsonb(product.Attributes, len(product.Attributes) == 0)
+		if err != nil {
+			return nil, dbErr(ErrQuery, err)
+		}
+		productRows[idx] = []interface{}{idx, product.Name, product.Description, nullSku(product.Sku), options, attributes}
+		for _, cid := range catsIds[idx] {
+			linkRows = append(linkRows, []interface{}{idx, cid})
+		}
+		for _, price := range product.Prices {
+			priceRows = append(priceRows, []interface{}{idx, price.Kind, price.Amount, price.Currency, price.ValidFrom, price.ValidTo})
+		}
+		for _, code := range product.Barcodes {
+			barcodeRows = append(barcodeRows, []interface{}{idx, code})
 		}
+	}
+	productColumns := []string{"idx", "name", "description", "sku", "options", "attributes"}
+	productDefinition := "idx int, name text, description text, sku text, options text, attributes text"
+	if err := stage(ctx, transaction, productStaging, productColumns, productDefinition, productRows); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	if err := stage(ctx, transaction, productCategoryStaging, []string{"idx", "category_id"}, "idx int, category_id int", linkRows); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	priceColumns := []string{"idx", "kind", "amount", "currency", "valid_from", "valid_to"}
+	priceDefinition := "idx int, kind text, amount bigint, currency text, valid_from timestamptz, valid_to timestamptz"
+	if err := stage(ctx, transaction, productPriceStaging, priceColumns, priceDefinition, priceRows); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	if err := stage(ctx, transaction, productBarcodeStaging, []string{"idx", "code"}, "idx int, code text", barcodeRows); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	rows, err := transaction.Query(ctx, fmt.Sprintf(`
+SELECT s.idx,
+EXISTS (
+	SELECT 1
+	FROM "%[1]s" as l
+	LEFT JOIN "%[2]s" as c ON c.category_id = l.category_id
+	WHERE l.idx = s.idx AND c.category_id IS NULL
+) as category_missing,
+EXISTS (SELECT 1 FROM "%[3]s" as p WHERE p.name = s.name AND p.deleted_at IS NULL) as name_taken,
+EXISTS (SELECT 1 FROM "%[3]s" as p WHERE p.sku = s.sku AND p.deleted_at IS NULL) as sku_taken,
+EXISTS (
+	SELECT 1
+	FROM "%[4]s" as b
+	JOIN "%[5]s" as t ON t.code = b.code
+	WHERE b.idx = s.idx
+) as barcode_taken
+FROM "%[6]s" as s`,
+	productCategoryStaging,
+	pp.cfg.CatogoryTable,
+	pp.cfg.ProductTable,
+	productBarcodeStaging,
+	pp.cfg.BarcodeTable,
+	productStaging))
+	if err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			idx int
+			categoryMissing bool
+			check productCheck
+		)
+		if err := rows.Scan(&idx, &categoryMissing, &check.nameTaken, &check.skuTaken, &check.barcodeTaken); err != nil {
+			return nil, dbErr(ErrQuery, err)
+		}
+		check.failed = checks[idx].failed || categoryMissing
+		checks[idx] = check
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	return classifyProducts(products, checks, results), nil
+}
 
-		for _, сid := range catsIds[idx] {
-			_, err = transaction.Exec(ctx, fmt.Sprintf(`
-INSERT INTO "%s" (product_id, category_id)
-VALUES ($1, $2)
-ON CONFLICT (product_id, category_id) DO NOTHING;`,
-			pp.cfg.ProductCategoryTable),
-			prodId,
-			сid)
-			if err != nil {
-				continue
+// productCheck is the state of the staged product before the batch is saved
+type productCheck struct {
+	// failed is set if the product breaks limits of the validator or is linked to a missing category
+	failed bool
+	nameTaken bool
+	skuTaken bool
+	barcodeTaken bool
+}
+
+// classifyProducts sets the outcome of the rows which are not saved and returns indexes of the rest.
+// Products with sku are matched by sku and the others by name: the sku or the name taken by a saved product
+// or by a previous row of the batch makes the row a duplicate. A product with sku whose name is taken,
+// a failed product and a product with a taken barcode are failed, a failed row frees its name, sku and barcodes for the next rows
+func classifyProducts(products []models.Product, checks []productCheck, results []models.ImportRowResult) []int {
+	var (
+		candidates []int
+		names = make(map[string]struct{})
+		skus = make(map[string]struct{})
+		barcodes = make(map[string]struct{})
+	)
+	for idx, product := range products {
+		results[idx].Name = product.Name
+		_, nameTaken := names[product.Name]
+		nameTaken = nameTaken || checks[idx].nameTaken
+		_, skuTaken := skus[product.Sku]
+		skuTaken = skuTaken || checks[idx].skuTaken
+		barcodeTaken := checks[idx].barcodeTaken
+		for _, code := range product.Barcodes {
+			if _, ok := barcodes[code]; ok {
+				barcodeTaken = true
+			}
+		}
+		var reason error
+		switch {
+		case product.Sku == "" && nameTaken:
+			results[idx].Status = models.ImportStatusSkippedDuplicate
+			reason = ErrProductExist
+		case product.Sku != "" && skuTaken:
+			results[idx].Status = models.ImportStatusSkippedDuplicate
+			reason = ErrSkuExist
+		case nameTaken:
+			results[idx].Status = models.ImportStatusFailed
+			reason = ErrProductExist
+		case checks[idx].failed:
+			results[idx].Status = models.ImportStatusFailed
+			reason = ErrQuery
+		case barcodeTaken:
+			results[idx].Status = models.ImportStatusFailed
+			reason = ErrBarcodeExist
+		default:
+			candidates = append(candidates, idx)
+			names[product.Name] = struct{}{}
+			if product.Sku != "" {
+				skus[product.Sku] = struct{}{}
+			}
+			for _, code := range product.Barcodes {
+				barcodes[code] = struct{}{}
 			}
+			continue
 		}
+		results[idx].Reason = reason.Error()
 	}
-	if err := transaction.Commit(ctx); err != nil {
-		return ErrCommitTx
+	return candidates
+}
+
+// insertStagedProducts saves staged products with the indexes, their options, attributes, links, prices, barcodes and events.
+// A product whose name or sku is taken after the check is a duplicate
+func (pp *postgresProvider) insertStagedProducts(ctx context.Context, transaction pgx.Tx, candidates []int, results []models.ImportRowResult) error {
+	rows, err := transaction.Query(ctx, fmt.Sprintf(`
+WITH ins AS (
+	INSERT INTO "%s" (name, description, sku, options, attributes)
+	SELECT name, description, sku, options::jsonb, attributes::jsonb FROM "%s" WHERE idx = ANY ($1) ORDER BY idx
+	ON CONFLICT DO NOTHING
+	RETURNING *
+), saved AS (
+	SELECT s.idx, ins.product_id
+	FROM ins
+	JOIN "%s" as s ON s.name = ins.name AND s.idx = ANY ($1)
+), links AS (
+	INSERT INTO "%s" (product_id, category_id)
+	SELECT DISTINCT saved.product_id, l.category_id
+	FROM saved
+	JOIN "%s" as l ON l.idx = saved.idx
+	ON CONFLICT (product_id, category_id) DO NOTHING
+), prices AS (
+	INSERT INTO "%s" (product_id, kind, amount, currency, valid_from, valid_to)
+	SELECT saved.product_id, pr.kind, pr.amount, pr.currency, pr.valid_from, pr.valid_to
+	FROM saved
+	JOIN "%s" as pr ON pr.idx = saved.idx
+), codes AS (
+	INSERT INTO "%s" (code, product_id)
+	SELECT b.code, saved.product_id
+	FROM saved
+	JOIN "%s" as b ON b.idx = saved.idx
+), ev AS (%s
+)
+SELECT idx, product_id FROM saved`,
+	pp.cfg.ProductTable,
+	productStaging,
+	productStaging,
+	pp.cfg.ProductCategoryTable,
+	productCategoryStaging,
+	pp.cfg.PriceTable,
+	productPriceStaging,
+	pp.cfg.BarcodeTable,
+	productBarcodeStaging,
+	pp.productEvent(models.EventProductCreated, "ins as p")),
+	candidates)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var idx, prodId int
+		if err := rows.Scan(&idx, &prodId); err != nil {
+			return dbErr(ErrQuery, err)
+		}
+		results[idx].Status = models.ImportStatusCreated
+		results[idx].ProductId = strconv.Itoa(prodId)
+	}
+	if err := rows.Err(); err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	for _, idx := range candidates {
+		if results[idx].Status == "" {
+			results[idx].Status = models.ImportStatusSkippedDuplicate
+			results[idx].Reason = ErrProductExist.Error()
+		}
 	}
 	return nil
 }
 
+// rollBackResults marks the results of atomic batch rolled back by the product failed at idx,
+// duplicates found before it are kept
+func rollBackResults(results []models.ImportRowResult, products []models.Product, failed int) {
+	for i := range results {
+		if i == failed || (i < failed && results[i].Status == models.ImportStatusSkippedDuplicate) {
+			continue
+		}
+		results[i].Name = products[i].Name
+		results[i].Status = models.ImportStatusRolledBack
+		results[i].ProductId = ""
+		results[i].Reason = ""
+	}
+}
+
 func (pp *postgresProvider) GetProductById(ctx context.Context, prodId string) (models.Product, error) {
-	row := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
-SELECT p.product_id, p.name, p.description, array_agg(c.code) as category_codes
+	var out models.Product
+	err := pp.read(ctx, func(db *retryDB) error {
+		var err error
+		out, err = pp.getProduct(ctx, db, "p.product_id = $1", prodId)
+		return err
+	})
+	return out, err
+}
+
+// GetProductBySku returns the not deleted product with the sku
+func (pp *postgresProvider) GetProductBySku(ctx context.Context, sku string) (models.Product, error) {
+	var out models.Product
+	err := pp.read(ctx, func(db *retryDB) error {
+		var err error
+		out, err = pp.getProduct(ctx, db, "p.sku = $1", sku)
+		return err
+	})
+	return out, err
+}
+
+// GetProductByBarcode returns the not deleted product with the barcode
+func (pp *postgresProvider) GetProductByBarcode(ctx context.Context, code string) (models.Product, error) {
+	var out models.Product
+	err := pp.read(ctx, func(db *retryDB) error {
+		var err error
+		out, err = pp.getProduct(ctx, db, fmt.Sprintf(`p.product_id = (SELECT product_id FROM "%s" WHERE code = $1)`, pp.cfg.BarcodeTable), code)
+		return err
+	})
+	return out, err
+}
+
+// getProduct returns the not deleted product found by the condition on p with one argument,
+// db may be the transaction of a write to read the product it has changed
+func (pp *postgresProvider) getProduct(ctx context.Context, db querier, condition string, arg interface{}) (models.Product, error) {
+	row := db.QueryRow(ctx, fmt.Sprintf(`
+SELECT p.product_id, p.name, p.description, p.state, p.version,
+CASE
+	WHEN COUNT(pc.category_id) = 0 THEN NULL
+	ELSE array_agg(c.code)
+END as category_codes,
+%s,
+%s,
+%s,
+%s,
+p.attributes
 FROM "%s" as p
-LEFT JOIN "%s" as pc ON pc.product_id = $1
-Left JOIN "%s" as c ON c.category_id = pc.category_id
-WHERE p.product_id = $1
+LEFT JOIN ("%s" as pc
+	JOIN "%s" as c ON c.category_id = pc.category_id AND c.deleted_at IS NULL
+) ON pc.product_id = p.product_id
+WHERE %s AND p.deleted_at IS NULL
 GROUP BY p.product_id`,
+	pp.productPrices(),
+	pp.productIdentifiers(),
+	pp.productVariants(),
+	pp.productMedia(),
 	pp.cfg.ProductTable,
 	pp.cfg.ProductCategoryTable,
-	pp.cfg.CatogoryTable),
-	prodId)
+	pp.cfg.CatogoryTable,
+	condition),
+	arg)
 	var (
 		product models.Product
 	)
-	err := row.Scan(&product.Id, &product.Name, &product.Description, &product.CategoryСodes)
+	err := row.Scan(&product.Id, &product.Name, &product.Description, &product.State, &product.Version, &product.CategoryСodes, &product.Prices, &product.Sku, &product.Barcodes, &product.Options, &product.Variants, &product.Media, &product.Attributes)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return models.Product{}, ErrProductNotFound
 		}
-		return models.Product{}, ErrQuery
+		return models.Product{}, dbErr(ErrQuery, err)
 	}
 	return product, nil
 }
 
-func (pp *postgresProvider) GetAllProducts(ctx context.Context) ([]models.Product, error) {
-	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`
-SELECT p.product_id, p.name, p.description,
-CASE 
-	WHEN COUNT(pc.category_id) = 0 THEN NULL 
-	ELSE array_agg(c.code) 
-END as category_codes
-FROM "%s" as p
-LEFT JOIN "%s" as pc ON pc.product_id = p.product_id
-Left JOIN "%s" as c ON c.category_id = pc.category_id
-GROUP BY p.product_id`,
-	pp.cfg.ProductTable,
-	pp.cfg.ProductCategoryTable,
-	pp.cfg.CatogoryTable))
+// GetAllProducts returns products matching the filter ordered by id
+func (pp *postgresProvider) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
+	var out []models.Product
+	err := pp.read(ctx, func(db *retryDB) error {
+		var err error
+		out, err = pp.getAllProducts(ctx, db, filter)
+		return err
+	})
+	return out, err
+}
+
+func (pp *postgresProvider) getAllProducts(ctx context.Context, db *retryDB, filter models.ProductFilter) ([]models.Product, error) {
+	query, args := pp.filterProducts(filter)
+	rows, err := db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, ErrQuery
+		return nil, dbErr(ErrQuery, err)
 	}
+	defer rows.Close()
 	var outProducts []models.Product
 	for rows.Next() {
 		var product models.Product
-		err := rows.Scan(&product.Id, &product.Name, &product.Description, &product.CategoryСodes)
+		err := rows.Scan(&product.Id, &product.Name, &product.Description, &product.State, &product.CategoryСodes, &product.Prices, &product.Sku, &product.Barcodes, &product.Options, &product.Variants, &product.Media, &product.Attributes)
 		if err != nil {
-			return nil, ErrQuery
+			return nil, dbErr(ErrQuery, err)
 		}
 		outProducts = append(outProducts, product)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
 	return outProducts, nil
 }
 
-func (pp *postgresProvider) GetProductsByCategory(ctx context.Context, catCode string) ([]models.Product, error) {
-	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`
-SELECT p.product_id, p.name, p.description, array_agg(c.code) as category_codes
+// filterProducts returns the query of products matching the filter ordered by id with its arguments
+func (pp *postgresProvider) filterProducts(filter models.ProductFilter) (string, []interface{}) {
+	var catCodes []string
+	if len(filter.CategoryCodes) != 0 {
+		catCodes = filter.CategoryCodes
+	}
+	priceFilter, priceArgs := pp.priceFilter(filter, 2)
+	attributeFilter, attributeArgs := pp.attributeFilter(filter, 2+len(priceArgs))
+	stockFilter, stockArgs := pp.stockFilter(filter, 2+len(priceArgs)+len(attributeArgs))
+	stateFilter, stateArgs := pp.stateFilter(filter, 2+len(priceArgs)+len(attributeArgs)+len(stockArgs))
+	return fmt.Sprintf(`
+SELECT p.product_id, p.name, p.description, p.state,
+CASE
+	WHEN COUNT(pc.category_id) = 0 THEN NULL
+	ELSE array_agg(c.code)
+END as category_codes,
+%s,
+%s,
+%s,
+%s,
+p.attributes
 FROM "%s" as p
-LEFT JOIN "%s" as pc ON pc.product_id = p.product_id
-Left JOIN "%s" as c ON c.category_id = pc.category_id
+LEFT JOIN ("%s" as pc
+	JOIN "%s" as c ON c.category_id = pc.category_id AND c.deleted_at IS NULL
+) ON pc.product_id = p.product_id
+WHERE p.deleted_at IS NULL AND %s AND %s AND %s AND %s
+GROUP BY p.product_id
+HAVING $1::varchar[] IS NULL OR array_agg(c.code) && $1::varchar[]
+ORDER BY p.product_id`,
+	pp.productPrices(),
+	pp.productIdentifiers(),
+	pp.productVariants(),
+	pp.productMedia(),
+	pp.cfg.ProductTable,
+	pp.cfg.ProductCategoryTable,
+	pp.cfg.CatogoryTable,
+	priceFilter,
+	attributeFilter,
+	stockFilter,
+	stateFilter), append(append(append(append([]interface{}{catCodes}, priceArgs...), attributeArgs...), stockArgs...), stateArgs...)
+}
+
+// StreamProducts calls fn for every product matching the filter,
+// rows are read from the connection one by one without loading the whole result
+func (pp *postgresProvider) StreamProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error {
+	query, args := pp.filterProducts(filter)
+	rows, err := pp.dbConn.Query(ctx, query, args...)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var product models.Product
+		if err := rows.Scan(&product.Id, &product.Name, &product.Description, &product.State, &product.CategoryСodes, &product.Prices, &product.Sku, &product.Barcodes, &product.Options, &product.Variants, &product.Media, &product.Attributes); err != nil {
+			return dbErr(ErrQuery, err)
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	return nil
+}
+
+// GetProductsByCategory returns products of the category in one of states, in any state if states are empty
+func (pp *postgresProvider) GetProductsByCategory(ctx context.Context, catCode string, states []string) ([]models.Product, error) {
+	var out []models.Product
+	err := pp.read(ctx, func(db *retryDB) error {
+		var err error
+		out, err = pp.getProductsByCategory(ctx, db, catCode, states)
+		return err
+	})
+	return out, err
+}
+
+func (pp *postgresProvider) getProductsByCategory(ctx context.Context, db *retryDB, catCode string, states []string) ([]models.Product, error) {
+	stateFilter, stateArgs := pp.stateFilter(models.ProductFilter{States: states}, 2)
+	rows, err := db.Query(ctx, fmt.Sprintf(`
+SELECT p.product_id, p.name, p.description, p.state, array_agg(c.code) as category_codes,
+%s,
+%s,
+%s,
+%s,
+p.attributes
+FROM "%s" as p
+LEFT JOIN ("%s" as pc
+	JOIN "%s" as c ON c.category_id = pc.category_id AND c.deleted_at IS NULL
+) ON pc.product_id = p.product_id
+WHERE p.deleted_at IS NULL AND %s
 GROUP BY p.product_id
 HAVING $1 = ANY (array_agg(c.code));`,
+	pp.productPrices(),
+	pp.productIdentifiers(),
+	pp.productVariants(),
+	pp.productMedia(),
 	pp.cfg.ProductTable,
 	pp.cfg.ProductCategoryTable,
-	pp.cfg.CatogoryTable),
-	catCode)
+	pp.cfg.CatogoryTable,
+	stateFilter),
+	append([]interface{}{catCode}, stateArgs...)...)
 	if err != nil {
-		return nil, ErrQuery
+		return nil, dbErr(ErrQuery, err)
 	}
+	defer rows.Close()
 	var outProducts []models.Product
 	for rows.Next() {
 		var product models.Product
-		err := rows.Scan(&product.Id, &product.Name, &product.Description, &product.CategoryСodes)
+		err := rows.Scan(&product.Id, &product.Name, &product.Description, &product.State, &product.CategoryСodes, &product.Prices, &product.Sku, &product.Barcodes, &product.Options, &product.Variants, &product.Media, &product.Attributes)
 		if err != nil {
-			return nil, ErrQuery
+			return nil, dbErr(ErrQuery, err)
 		}
 		outProducts = append(outProducts, product)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
 	return outProducts, nil
 }
 
-func (pp *postgresProvider) UpdateProductById(ctx context.Context, prodId string, newPorductdata models.ProductForPatch, catIds []int) error {
+// UpdateProductById changes the fields that are set and counts the version of the product,
+// categories, prices, barcodes, options and attributes are replaced if they are not nil. Returns the changed product,
+// ErrProductExist, ErrSkuExist or ErrBarcodeExist if the value is taken and ErrVersionConflict if version is not zero
+// and the product has another one
+func (pp *postgresProvider) UpdateProductById(ctx context.Context, prodId string, newPorductdata models.ProductForPatch, catIds []int, version int) (models.Product, error) {
+	var product models.Product
+	err := pp.dbConn.retry(ctx, func() error {
+		var err error
+		product, err = pp.updateProductById(ctx, prodId, newPorductdata, catIds, version)
+		return err
+	})
+	return product, err
+}
+
+func (pp *postgresProvider) updateProductById(ctx context.Context, prodId string, newPorductdata models.ProductForPatch, catIds []int, version int) (models.Product, error) {
 	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return ErrStartTx
+		return models.Product{}, dbErr(ErrStartTx, err)
+	}
+	// deleted product is edited only after it is restored,
+	// the row is locked so the version can't change until commit
+	var current int
+	err = transaction.QueryRow(ctx, fmt.Sprintf(`
+SELECT version FROM "%s"
+WHERE product_id = $1 AND deleted_at IS NULL
+FOR UPDATE`,
+	pp.cfg.ProductTable),
+	prodId).Scan(&current)
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Product{}, dbErr(ErrRollbackTx, err)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Product{}, ErrProductNotFound
+		}
+		return models.Product{}, dbErr(ErrQuery, err)
+	}
+	if version != 0 && version != current {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Product{}, dbErr(ErrRollbackTx, err)
+		}
+		return models.Product{}, ErrVersionConflict
+	}
+	_, err = transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET version = version + 1
+WHERE product_id = $1`,
+	pp.cfg.ProductTable),
+	prodId)
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Product{}, dbErr(ErrRollbackTx, err)
+		}
+		return models.Product{}, dbErr(ErrQuery, err)
 	}
 	//TODO: rewritre it, hotfix
-	if newPorductdata.Name != nil || newPorductdata.Description != nil {
+	if newPorductdata.Name != nil || newPorductdata.Description != nil || newPorductdata.Sku != nil || newPorductdata.Options != nil ||
+		newPorductdata.Attributes != nil {
 		preparedQuery := fmt.Sprintf("UPDATE \"%s\" SET ", pp.cfg.ProductTable)
 		usedFields := 0
 		usedData := make([]interface{}, 0)
@@ -193,77 +673,174 @@ func (pp *postgresProvider) UpdateProductById(ctx context.Context, prodId string
 			usedFields++
 			usedData = append(usedData, *newPorductdata.Description)
 		}
+		if newPorductdata.Sku != nil {
+			preparedQuery += fmt.Sprintf("\"sku\" = $%d, ", usedFields+1)
+			usedFields++
+			usedData = append(usedData, nullSku(*newPorductdata.Sku))
+		}
+		if newPorductdata.Options != nil {
+			options, err := jsonb(newPorductdata.Options, len(newPorductdata.Options) == 0)
+			if err != nil {
+				transaction.Rollback(ctx)
+				return models.Product{}, dbErr(ErrQuery, err)
+			}
+			preparedQuery += fmt.Sprintf("\"options\" = $%d, ", usedFields+1)
+			usedFields++
+			usedData = append(usedData, options)
+		}
+		if newPorductdata.Attributes != nil {
+			attributes, err := jsonb(newPorductdata.Attributes, len(newPorductdata.Attributes) == 0)
+			if err != nil {
+				transaction.Rollback(ctx)
+				return models.Product{}, dbErr(ErrQuery, err)
+			}
+			preparedQuery += fmt.Sprintf("\"attributes\" = $%d, ", usedFields+1)
+			usedFields++
+			usedData = append(usedData, attributes)
+		}
 		preparedQuery = preparedQuery[:len(preparedQuery)-2]
 		usedData = append(usedData, prodId)
 		_, err = transaction.Exec(ctx, fmt.Sprintf("%s WHERE \"product_id\" = $%d", preparedQuery, usedFields+1), usedData...)
 		if err != nil {
 			if err := transaction.Rollback(ctx); err != nil {
-				return ErrRollbackTx
+				return models.Product{}, dbErr(ErrRollbackTx, err)
 			}
-			var pgErr *pgconn.PgError
-			if errors.As(err, &pgErr) {
-				if pgErr.Code == "23505" {
-					return ErrProductExist
-				}
+			if existErr := pp.productExistErr(err); existErr != nil {
+				return models.Product{}, existErr
 			}
-			return ErrQuery
+			return models.Product{}, dbErr(ErrQuery, err)
 		}
 	}
-	
-	if catIds == nil {
-		if err := transaction.Commit(ctx); err != nil {
-			return ErrCommitTx
+
+	if catIds != nil {
+		if err := pp.replaceProductCategories(ctx, transaction, prodId, catIds); err != nil {
+			if err := transaction.Rollback(ctx); err != nil {
+				return models.Product{}, dbErr(ErrRollbackTx, err)
+			}
+			return models.Product{}, err
+		}
+	}
+	if newPorductdata.Prices != nil {
+		if err := pp.replacePrices(ctx, transaction, prodId, newPorductdata.Prices); err != nil {
+			if err := transaction.Rollback(ctx); err != nil {
+				return models.Product{}, dbErr(ErrRollbackTx, err)
+			}
+			return models.Product{}, err
+		}
+	}
+	if newPorductdata.Barcodes != nil {
+		if err := pp.replaceBarcodes(ctx, transaction, prodId, newPorductdata.Barcodes); err != nil {
+			if err := transaction.Rollback(ctx); err != nil {
+				return models.Product{}, dbErr(ErrRollbackTx, err)
+			}
+			return models.Product{}, err
+		}
+	}
+	if _, err = transaction.Exec(ctx, pp.productEventById(models.EventProductUpdated), prodId); err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Product{}, dbErr(ErrRollbackTx, err)
 		}
-		return nil
+		return models.Product{}, dbErr(ErrQuery, err)
+	}
+	product, err := pp.getProduct(ctx, transaction, "p.product_id = $1", prodId)
+	if err != nil {
+		transaction.Rollback(ctx)
+		return models.Product{}, err
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return models.Product{}, dbErr(ErrCommitTx, err)
 	}
+	return product, nil
+}
 
+// replaceProductCategories links the product to exactly the categories
+func (pp *postgresProvider) replaceProductCategories(ctx context.Context, transaction pgx.Tx, prodId string, catIds []int) error {
 	for _, cId := range catIds {
-		_, err = transaction.Exec(ctx, fmt.Sprintf(`
+		_, err := transaction.Exec(ctx, fmt.Sprintf(`
 INSERT INTO "%s" (product_id, category_id)
 VALUES ($1, $2)  ON CONFLICT (product_id, category_id) DO NOTHING;`,
 		pp.cfg.ProductCategoryTable),
 		prodId,
 		cId)
 		if err != nil {
-			if err := transaction.Rollback(ctx); err != nil {
-				return ErrRollbackTx
-			}
-			return ErrQuery
+			return dbErr(ErrQuery, err)
 		}
 	}
-	
-	_, err = transaction.Exec(ctx, fmt.Sprintf(`
+	_, err := transaction.Exec(ctx, fmt.Sprintf(`
 DELETE FROM "%s"
-WHERE product_id = $1 AND category_id <> ANY ($2);`,
+WHERE product_id = $1 AND category_id <> ALL ($2);`,
 	pp.cfg.ProductCategoryTable),
 	prodId,
 	catIds)
 	if err != nil {
-		if err := transaction.Rollback(ctx); err != nil {
-			return ErrRollbackTx
-		}
-		return ErrQuery
-	}
-	if err := transaction.Commit(ctx); err != nil {
-		return ErrCommitTx
+		return dbErr(ErrQuery, err)
 	}
 	return nil
 }
 
-func (pp *postgresProvider) DeleteProductById(ctx context.Context, id string) error {
+// DeleteProductById moves the product to the trash, its category links are kept for restore.
+// Returns the product as it was before deletion, the zero product if it is already deleted,
+// and ErrVersionConflict if version is not zero and the product has another one
+func (pp *postgresProvider) DeleteProductById(ctx context.Context, id string, version int) (models.Product, error) {
+	var product models.Product
+	err := pp.dbConn.retry(ctx, func() error {
+		var err error
+		product, err = pp.deleteProductById(ctx, id, version)
+		return err
+	})
+	return product, err
+}
+
+func (pp *postgresProvider) deleteProductById(ctx context.Context, id string, version int) (models.Product, error) {
 	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return ErrStartTx
+		return models.Product{}, dbErr(ErrStartTx, err)
 	}
-	_, err = transaction.Exec(ctx, fmt.Sprintf("DELETE FROM \"%s\" WHERE \"product_id\" = $1", pp.cfg.ProductTable), id)
+	// the row is locked so the product is deleted as it is read
+	var current int
+	err = transaction.QueryRow(ctx, fmt.Sprintf(`
+SELECT version FROM "%s"
+WHERE product_id = $1 AND deleted_at IS NULL
+FOR UPDATE`,
+	pp.cfg.ProductTable),
+	id).Scan(&current)
 	if err != nil {
 		if err := transaction.Rollback(ctx); err != nil {
-			return ErrRollbackTx
+			return models.Product{}, dbErr(ErrRollbackTx, err)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Product{}, nil
 		}
-		return ErrQuery
+		return models.Product{}, dbErr(ErrQuery, err)
+	}
+	if version != 0 && version != current {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Product{}, dbErr(ErrRollbackTx, err)
+		}
+		return models.Product{}, ErrVersionConflict
+	}
+	product, err := pp.getProduct(ctx, transaction, "p.product_id = $1", id)
+	if err != nil {
+		transaction.Rollback(ctx)
+		return models.Product{}, err
+	}
+	_, err = transaction.Exec(ctx, fmt.Sprintf(`
+WITH deleted AS (
+	UPDATE "%s" SET deleted_at = now()
+	WHERE "product_id" = $1
+	RETURNING *
+)%s`,
+	pp.cfg.ProductTable,
+	pp.productEvent(models.EventProductDeleted, "deleted as p")),
+	id)
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return models.Product{}, dbErr(ErrRollbackTx, err)
+		}
+		return models.Product{}, dbErr(ErrQuery, err)
 	}
 	if err := transaction.Commit(ctx); err != nil {
-		return ErrCommitTx
+		return models.Product{}, dbErr(ErrCommitTx, err)
 	}
-	return nil
+	return product, nil
 }
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultReplicaCheckPeriod = 5 * time.Second
+
+// replica is a read only pool, reads go to it while it is healthy
+type replica struct {
+	host string
+	db *retryDB
+	healthy atomic.Bool
+}
+
+// replicaSet spreads reads over healthy replicas by turns
+type replicaSet struct {
+	log *slog.Logger
+	replicas []*replica
+	next atomic.Uint32
+	maxLag time.Duration
+	period time.Duration
+	stop context.CancelFunc
+	done sync.WaitGroup
+}
+
+// pick returns the next healthy replica, nil if there is no one
+func (rs *replicaSet) pick() *replica {
+	if rs == nil {
+		return nil
+	}
+	for range rs.replicas {
+		r := rs.replicas[int(rs.next.Add(1)) % len(rs.replicas)]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+func (rs *replicaSet) setHealthy(r *replica, healthy bool, err error) {
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		rs.log.Info("replica is healthy", slog.String("host", r.host))
+		return
+	}
+	log := rs.log
+	if err != nil {
+		log = log.With(slog.String("error", err.Error()))
+	}
+	log.Warn("replica is unhealthy, reads go to other replicas or the primary", slog.String("host", r.host))
+}
+
+// run checks replicas every period until Close is called
+func (rs *replicaSet) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	rs.stop = cancel
+	rs.done.Add(1)
+	go func() {
+		defer rs.done.Done()
+		ticker := time.NewTicker(rs.period)
+		defer ticker.Stop()
+		for {
+			rs.check(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (rs *replicaSet) check(ctx context.Context) {
+	for _, r := range rs.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, rs.period)
+		err := rs.checkReplica(checkCtx, r)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		rs.setHealthy(r, err == nil, err)
+	}
+}
+
+// checkReplica pings the replica and compares its lag with maxLag.
+// The replica which has replayed all received wal is not behind, otherwise the lag is the time
+// since the last replayed transaction, so an idle primary doesn't make the replica unhealthy
+func (rs *replicaSet) checkReplica(ctx context.Context, r *replica) error {
+	if err := r.db.Ping(ctx); err != nil {
+		return err
+	}
+	if rs.maxLag <= 0 {
+		return nil
+	}
+	var lag *time.Duration
+	err := r.db.pool.QueryRow(ctx, `
+SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN interval '0'
+	ELSE now() - pg_last_xact_replay_timestamp() END`).Scan(&lag)
+	if err != nil {
+		return err
+	}
+	if lag != nil && *lag > rs.maxLag {
+		return errReplicaLag
+	}
+	return nil
+}
+
+func (rs *replicaSet) close() {
+	if rs == nil {
+		return
+	}
+	if rs.stop != nil {
+		rs.stop()
+		rs.done.Wait()
+	}
+	for _, r := range rs.replicas {
+		r.db.Close()
+	}
+}
+
+var errReplicaLag = errors.New("replica lag is too big")
+
+type readSessionKey struct{}
+
+// readSession remembers that the request has written to the primary
+type readSession struct {
+	wrote atomic.Bool
+}
+
+// ReadYourWrites returns the context of the read session: after any write
+// made with it the following reads go to the primary
+func (pp *postgresProvider) ReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readSessionKey{}, &readSession{})
+}
+
+func markWrite(ctx context.Context) {
+	if session, ok := ctx.Value(readSessionKey{}).(*readSession); ok {
+		session.wrote.Store(true)
+	}
+}
+
+func hasWritten(ctx context.Context) bool {
+	session, ok := ctx.Value(readSessionKey{}).(*readSession)
+	return ok && session.wrote.Load()
+}
+
+// read runs fn on a healthy replica or on the primary if there is no one, the session has written
+// or ctx carries the transaction. If the replica is unavailable it is marked unhealthy and fn is run on the primary
+func (pp *postgresProvider) read(ctx context.Context, fn func(db *retryDB) error) error {
+	if !hasWritten(ctx) && txFrom(ctx, pp.dbConn) == nil {
+		if r := pp.replicas.pick(); r != nil {
+			err := fn(r.db)
+			if !errors.Is(err, ErrUnavailable) {
+				return err
+			}
+			pp.replicas.setHealthy(r, false, err)
+		}
+	}
+	return fn(pp.dbConn)
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/stretchr/testify/suite"
+)
+
+type replicaTestSuite struct {
+	suite.Suite
+	primary *retryDB
+	pp *postgresProvider
+}
+
+func TestReplicaSuiteRun(t *testing.T) {
+	suite.Run(t, new(replicaTestSuite))
+}
+
+func (suite *replicaTestSuite) SetupTest() {
+	suite.primary = newRetryDB(nil, config.PostgresConfig{})
+	suite.pp = &postgresProvider{
+		dbConn: suite.primary,
+		replicas: &replicaSet{
+			log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+	}
+	for _, host := range []string{"first", "second"} {
+		r := &replica{
+			host: host,
+			db: newRetryDB(nil, config.PostgresConfig{}),
+		}
+		r.healthy.Store(true)
+		suite.pp.replicas.replicas = append(suite.pp.replicas.replicas, r)
+	}
+}
+
+func (suite *replicaTestSuite) readFrom(ctx context.Context, fn func(db *retryDB) error) []*retryDB {
+	var used []*retryDB
+	suite.pp.read(ctx, func(db *retryDB) error {
+		used = append(used, db)
+		return fn(db)
+	})
+	return used
+}
+
+func (suite *replicaTestSuite) Test_Pick() {
+	first, second := suite.pp.replicas.replicas[0], suite.pp.replicas.replicas[1]
+	suite.Require().Equal(second, suite.pp.replicas.pick())
+	suite.Require().Equal(first, suite.pp.replicas.pick())
+	second.healthy.Store(false)
+	suite.Require().Equal(first, suite.pp.replicas.pick())
+	suite.Require().Equal(first, suite.pp.replicas.pick())
+	first.healthy.Store(false)
+	suite.Require().Nil(suite.pp.replicas.pick())
+	var noReplicas *replicaSet
+	suite.Require().Nil(noReplicas.pick())
+}
+
+func (suite *replicaTestSuite) Test_Read() {
+	ok := func(db *retryDB) error { return nil }
+	tests := []struct{
+		name string
+		prepare func(ctx context.Context) context.Context
+		fn func(db *retryDB) error
+		want func() []*retryDB
+		wantHealthy []bool
+	}{
+		{
+			name: "healthy_replica",
+			fn: ok,
+			want: func() []*retryDB { return []*retryDB{suite.pp.replicas.replicas[1].db} },
+			wantHealthy: []bool{true, true},
+		},
+		{
+			name: "unhealthy_replicas",
+			prepare: func(ctx context.Context) context.Context {
+				for _, r := range suite.pp.replicas.replicas {
+					r.healthy.Store(false)
+				}
+				return ctx
+			},
+			fn: ok,
+			want: func() []*retryDB { return []*retryDB{suite.primary} },
+			wantHealthy: []bool{false, false},
+		},
+		{
+			name: "unavailable_replica",
+			fn: func(db *retryDB) error {
+				if db == suite.primary {
+					return nil
+				}
+				return ErrUnavailable
+			},
+			want: func() []*retryDB { return []*retryDB{suite.pp.replicas.replicas[1].db, suite.primary} },
+			wantHealthy: []bool{true, false},
+		},
+		{
+			name: "query_error_on_replica",
+			fn: func(db *retryDB) error { return ErrQuery },
+			want: func() []*retryDB { return []*retryDB{suite.pp.replicas.replicas[1].db} },
+			wantHealthy: []bool{true, true},
+		},
+		{
+			name: "session_without_writes",
+			prepare: func(ctx context.Context) context.Context {
+				return suite.pp.ReadYourWrites(ctx)
+			},
+			fn: ok,
+			want: func() []*retryDB { return []*retryDB{suite.pp.replicas.replicas[1].db} },
+			wantHealthy: []bool{true, true},
+		},
+		{
+			name: "session_with_write",
+			prepare: func(ctx context.Context) context.Context {
+				ctx = suite.pp.ReadYourWrites(ctx)
+				markWrite(ctx)
+				return ctx
+			},
+			fn: ok,
+			want: func() []*retryDB { return []*retryDB{suite.primary} },
+			wantHealthy: []bool{true, true},
+		},
+	}
+	for _, tt := range tests {
+		suite.SetupTest()
+		ctx := context.Background()
+		if tt.prepare != nil {
+			ctx = tt.prepare(ctx)
+		}
+		used := suite.readFrom(ctx, tt.fn)
+		suite.Require().Equal(tt.want(), used, "test: %s", tt.name)
+		for idx, r := range suite.pp.replicas.replicas {
+			suite.Require().Equal(tt.wantHealthy[idx], r.healthy.Load(), "test: %s, replica: %s", tt.name, r.host)
+		}
+	}
+}
+
+func (suite *replicaTestSuite) Test_MarkWrite() {
+	ctx := context.Background()
+	markWrite(ctx)
+	suite.Require().False(hasWritten(ctx))
+	ctx = suite.pp.ReadYourWrites(ctx)
+	suite.Require().False(hasWritten(ctx))
+	markWrite(context.WithValue(ctx, struct{}{}, "child"))
+	suite.Require().True(hasWritten(ctx))
+}
@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	defaultRetryAttempts    = 3
+	defaultRetryBaseDelay   = 50 * time.Millisecond
+	defaultRetryMaxDelay    = time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 10 * time.Second
+)
+
+// retryDB wraps the pool: statements failed with transient errors are retried
+// with exponential backoff, and after BreakerThreshold failures in a row
+// all calls fail fast with ErrUnavailable until BreakerCooldown passes
+type retryDB struct {
+	pool *pgxpool.Pool
+	attempts int
+	baseDelay time.Duration
+	maxDelay time.Duration
+	breaker *breaker
+}
+
+func newRetryDB(pool *pgxpool.Pool, cfg config.PostgresConfig) *retryDB {
+	db := &retryDB{
+		pool: pool,
+		attempts: cfg.RetryAttempts,
+		baseDelay: cfg.RetryBaseDelay,
+		maxDelay: cfg.RetryMaxDelay,
+		breaker: &breaker{
+			threshold: cfg.BreakerThreshold,
+			cooldown: cfg.BreakerCooldown,
+		},
+	}
+	if db.attempts <= 0 {
+		db.attempts = defaultRetryAttempts
+	}
+	if db.baseDelay <= 0 {
+		db.baseDelay = defaultRetryBaseDelay
+	}
+	if db.maxDelay <= 0 {
+		db.maxDelay = defaultRetryMaxDelay
+	}
+	if db.breaker.threshold <= 0 {
+		db.breaker.threshold = defaultBreakerThreshold
+	}
+	if db.breaker.cooldown <= 0 {
+		db.breaker.cooldown = defaultBreakerCooldown
+	}
+	return db
+}
+
+// retry calls fn until it succeeds, fails with not transient error or attempts are over.
+// fn is taken for a write: a lost connection leaves its outcome unknown, so it is repeated
+// only if the error is safe to retry. Errors left after the last attempt are wrapped with ErrUnavailable.
+// Inside the transaction of ctx fn is called once, the whole transaction is retried by WithinTx
+func (db *retryDB) retry(ctx context.Context, fn func() error) error {
+	return db.do(ctx, false, fn)
+}
+
+// retryRead is retry for fn which doesn't change anything, it is repeated after any transient error
+func (db *retryDB) retryRead(ctx context.Context, fn func() error) error {
+	return db.do(ctx, true, fn)
+}
+
+func (db *retryDB) do(ctx context.Context, read bool, fn func() error) error {
+	if txFrom(ctx, db) != nil {
+		return fn()
+	}
+	var err error
+	for attempt := 0; attempt < db.attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, db.backoff(attempt)); err != nil {
+				return err
+			}
+		}
+		if !db.breaker.allow() {
+			return ErrUnavailable
+		}
+		err = fn()
+		db.breaker.done(err)
+		if !isTransient(err) {
+			return err
+		}
+		if !retryable(err, read) {
+			break
+		}
+	}
+	return fmt.Errorf("%w: %w", ErrUnavailable, err)
+}
+
+// backoff returns delay before the attempt: base * 2^(attempt-1) with jitter, but not more than max
+func (db *retryDB) backoff(attempt int) time.Duration {
+	delay := db.baseDelay << (attempt - 1)
+	if delay <= 0 || delay > db.maxDelay {
+		delay = db.maxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// Exec and BeginTx are used only for writes, they mark the read session of ctx
+func (db *retryDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	markWrite(ctx)
+	var tag pgconn.CommandTag
+	err := db.retry(ctx, func() error {
+		var err error
+		tag, err = db.conn(ctx).Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+// Query retries only getting the rows, errors while reading them are returned by rows.Err
+func (db *retryDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := db.do(ctx, isSelect(sql), func() error {
+		var err error
+		rows, err = db.conn(ctx).Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow runs the query when the row is scanned, so the whole query is retried on Scan
+func (db *retryDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &retryRow{
+		db: db,
+		ctx: ctx,
+		sql: sql,
+		args: args,
+	}
+}
+
+// BeginTx is not retried, the whole transaction is retried by the caller with retry.
+// Inside the transaction of ctx it starts a savepoint, its rollback keeps the outer transaction
+func (db *retryDB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	markWrite(ctx)
+	if tx := txFrom(ctx, db); tx != nil {
+		return tx.Begin(ctx)
+	}
+	if !db.breaker.allow() {
+		return nil, ErrUnavailable
+	}
+	transaction, err := db.pool.BeginTx(ctx, txOptions)
+	db.breaker.done(err)
+	return transaction, err
+}
+
+// begin starts the transaction of WithinTx, the breaker is already passed by retry around it
+func (db *retryDB) begin(ctx context.Context) (pgx.Tx, error) {
+	markWrite(ctx)
+	return db.pool.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// conn returns the transaction of ctx or the pool if there is no one
+func (db *retryDB) conn(ctx context.Context) querier {
+	if tx := txFrom(ctx, db); tx != nil {
+		return tx
+	}
+	return db.pool
+}
+
+func (db *retryDB) Ping(ctx context.Context) error {
+	return db.pool.Ping(ctx)
+}
+
+func (db *retryDB) Stat() *pgxpool.Stat {
+	return db.pool.Stat()
+}
+
+func (db *retryDB) Close() {
+	db.pool.Close()
+}
+
+type retryRow struct {
+	db *retryDB
+	ctx context.Context
+	sql string
+	args []interface{}
+}
+
+func (rr *retryRow) Scan(dest ...interface{}) error {
+	return rr.db.do(rr.ctx, isSelect(rr.sql), func() error {
+		return rr.db.conn(rr.ctx).QueryRow(rr.ctx, rr.sql, rr.args...).Scan(dest...)
+	})
+}
+
+// breaker is opened after threshold transient failures in a row.
+// When cooldown passes it is half-open: one call is let through as a probe and the others
+// fail fast until the probe closes the breaker by success or reopens it by failure
+type breaker struct {
+	mu sync.Mutex
+	threshold int
+	cooldown time.Duration
+	failures int
+	openedAt time.Time
+	probing bool
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// done records the result of the call let through by allow
+func (b *breaker) done(err error) {
+	switch {
+	case isTransient(err):
+		b.failure()
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// the call tells nothing about the database, another one may probe it
+		b.mu.Lock()
+		b.probing = false
+		b.mu.Unlock()
+	default:
+		b.success()
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	b.failures = 0
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// isTransient reports whether the query may succeed if it is repeated:
+// lost connection, serialization failure or deadlock
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", "40P01", "57P01", "57P02", "57P03":
+			return true
+		}
+		// class 08 - connection exception
+		return len(pgErr.Code) == 5 && pgErr.Code[:2] == "08"
+	}
+	if safeToRetry(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// retryable reports whether the statement failed with the transient err may be repeated.
+// Serialization failures and deadlocks roll the statement back, so it is always repeated.
+// After a lost connection a write may be already applied, it is repeated only if nothing was sent
+func retryable(err error, read bool) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01") {
+		return true
+	}
+	return read || safeToRetry(err)
+}
+
+// safeToRetry is pgconn.SafeToRetry for wrapped errors
+func safeToRetry(err error) bool {
+	var safe interface{ SafeToRetry() bool }
+	return errors.As(err, &safe) && safe.SafeToRetry()
+}
+
+// isSelect reports whether the statement only reads, statements starting with WITH are taken for writes
+func isSelect(sql string) bool {
+	sql = strings.TrimSpace(sql)
+	return len(sql) >= 6 && strings.EqualFold(sql[:6], "SELECT")
+}
+
+// dbErr returns the storage error for err keeping the cause if the database is unavailable,
+// so callers can tell it from query errors with errors.Is(err, ErrUnavailable)
+func dbErr(storageErr error, err error) error {
+	if errors.Is(err, ErrUnavailable) || isTransient(err) {
+		return fmt.Errorf("%w: %w", storageErr, err)
+	}
+	return storageErr
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
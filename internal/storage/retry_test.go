@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/suite"
+)
+
+type retryTestSuite struct {
+	suite.Suite
+}
+
+func TestRetrySuiteRun(t *testing.T) {
+	suite.Run(t, new(retryTestSuite))
+}
+
+func (suite *retryTestSuite) newDB() *retryDB {
+	return newRetryDB(nil, config.PostgresConfig{
+		RetryAttempts: 3,
+		RetryBaseDelay: time.Millisecond,
+		RetryMaxDelay: 2 * time.Millisecond,
+		BreakerThreshold: 4,
+		BreakerCooldown: 50 * time.Millisecond,
+	})
+}
+
+func (suite *retryTestSuite) Test_IsTransient() {
+	tests := []struct{
+		name string
+		err error
+		want bool
+	}{
+		{name: "serialization_failure", err: &pgconn.PgError{Code: "40001"}, want: true},
+		{name: "deadlock", err: fmt.Errorf("%w: %w", ErrQuery, &pgconn.PgError{Code: "40P01"}), want: true},
+		{name: "connection_exception", err: &pgconn.PgError{Code: "08006"}, want: true},
+		{name: "admin_shutdown", err: &pgconn.PgError{Code: "57P01"}, want: true},
+		{name: "connection_reset", err: io.ErrUnexpectedEOF, want: true},
+		{name: "unique_violation", err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "query_error", err: ErrQuery, want: false},
+		{name: "nil", err: nil, want: false},
+	}
+	for _, tt := range tests {
+		suite.Require().Equal(tt.want, isTransient(tt.err), "test: %s", tt.name)
+	}
+}
+
+func (suite *retryTestSuite) Test_Retry() {
+	db := suite.newDB()
+	calls := 0
+	err := db.retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return &pgconn.PgError{Code: "40P01"}
+		}
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(3, calls)
+
+	calls = 0
+	notTransient := errors.New("syntax error")
+	err = db.retry(context.Background(), func() error {
+		calls++
+		return notTransient
+	})
+	suite.Require().ErrorIs(err, notTransient)
+	suite.Require().Equal(1, calls)
+
+	calls = 0
+	err = db.retryRead(context.Background(), func() error {
+		calls++
+		return dbErr(ErrQuery, io.EOF)
+	})
+	suite.Require().ErrorIs(err, ErrUnavailable)
+	suite.Require().ErrorIs(err, ErrQuery)
+	suite.Require().Equal(3, calls)
+}
+
+func (suite *retryTestSuite) Test_RetryWrite() {
+	db := suite.newDB()
+	// the write may be applied before the connection is lost, so it is not repeated
+	calls := 0
+	err := db.retry(context.Background(), func() error {
+		calls++
+		return dbErr(ErrQuery, io.EOF)
+	})
+	suite.Require().ErrorIs(err, ErrUnavailable)
+	suite.Require().ErrorIs(err, ErrQuery)
+	suite.Require().Equal(1, calls)
+
+	calls = 0
+	err = db.retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return dbErr(ErrQuery, unsentErr{})
+		}
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(3, calls)
+
+	calls = 0
+	err = db.retry(context.Background(), func() error {
+		calls++
+		if calls < 2 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(2, calls)
+}
+
+func (suite *retryTestSuite) Test_IsSelect() {
+	suite.Require().True(isSelect("\nSELECT id FROM products"))
+	suite.Require().True(isSelect("select 1"))
+	suite.Require().False(isSelect("INSERT INTO jobs (kind) VALUES ($1) RETURNING job_id"))
+	suite.Require().False(isSelect("WITH moved AS (UPDATE outbox SET attempts = 1 RETURNING id) SELECT id FROM moved"))
+}
+
+// unsentErr is a connection error that happened before anything was sent
+type unsentErr struct{}
+
+func (unsentErr) Error() string {
+	return "failed to connect"
+}
+
+func (unsentErr) SafeToRetry() bool {
+	return true
+}
+
+func (suite *retryTestSuite) Test_Breaker() {
+	db := suite.newDB()
+	failing := func() error {
+		return io.EOF
+	}
+	suite.Require().ErrorIs(db.retryRead(context.Background(), failing), ErrUnavailable)
+	// the fourth failure in a row opens the breaker
+	suite.Require().ErrorIs(db.retryRead(context.Background(), failing), ErrUnavailable)
+
+	calls := 0
+	err := db.retryRead(context.Background(), func() error {
+		calls++
+		return nil
+	})
+	suite.Require().ErrorIs(err, ErrUnavailable)
+	suite.Require().Equal(0, calls, "open breaker must fail fast")
+
+	time.Sleep(60 * time.Millisecond)
+	// the probe fails and reopens the breaker, its retries fail fast
+	err = db.retryRead(context.Background(), func() error {
+		calls++
+		return io.EOF
+	})
+	suite.Require().ErrorIs(err, ErrUnavailable)
+	suite.Require().Equal(1, calls, "half-open breaker must let one probe through")
+
+	time.Sleep(60 * time.Millisecond)
+	probeStarted := make(chan struct{})
+	releaseProbe := make(chan struct{})
+	probeErr := make(chan error)
+	go func() {
+		probeErr <- db.retryRead(context.Background(), func() error {
+			close(probeStarted)
+			<-releaseProbe
+			return nil
+		})
+	}()
+	<-probeStarted
+	err = db.retryRead(context.Background(), func() error {
+		calls++
+		return nil
+	})
+	suite.Require().ErrorIs(err, ErrUnavailable, "calls must fail fast while the probe runs")
+	suite.Require().Equal(1, calls)
+
+	close(releaseProbe)
+	suite.Require().NoError(<-probeErr)
+	err = db.retryRead(context.Background(), func() error {
+		calls++
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(2, calls)
+	suite.Require().True(db.breaker.allow())
+}
@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// attributeValues matches products whose attribute is a string or a boolean with the text
+// in the first json array or a number in the second one
+const attributeValues = `EXISTS (
+	SELECT 1
+	FROM json_each(p.attributes) as a
+	WHERE a.key = ?
+	AND (a.type = 'text' AND a.value IN (SELECT value FROM json_each(?))
+		OR a.type IN ('true', 'false') AND a.type IN (SELECT value FROM json_each(?))
+		OR a.type IN ('integer', 'real') AND a.value IN (SELECT value FROM json_each(?)))
+)`
+
+// attributeRange matches products whose attribute is a number within the bounds
+const attributeRange = `EXISTS (
+	SELECT 1
+	FROM json_each(p.attributes) as a
+	WHERE a.key = ?
+	AND a.type IN ('integer', 'real')
+	AND (? IS NULL OR a.value >= ?)
+	AND (? IS NULL OR a.value <= ?)
+)`
+
+// GetCategoriesAttributes returns attribute schemas of the not deleted categories by their codes,
+// codes that do not exist are absent in the map
+func (sp *sqliteProvider) GetCategoriesAttributes(ctx context.Context, catCodes []string) (map[string][]models.Attribute, error) {
+	codes, err := json.Marshal(catCodes)
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	rows, err := sp.conn(ctx).QueryContext(ctx, `
+SELECT code, attributes
+FROM categories
+WHERE code IN (SELECT value FROM json_each(?)) AND deleted_at IS NULL`,
+	string(codes))
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	defer rows.Close()
+	schemas := make(map[string][]models.Attribute, len(catCodes))
+	for rows.Next() {
+		var (
+			code string
+			attributes sql.NullString
+		)
+		if err := rows.Scan(&code, &attributes); err != nil {
+			return nil, storage.ErrQuery
+		}
+		schema, err := scanAttributes(attributes)
+		if err != nil {
+			return nil, storage.ErrQuery
+		}
+		schemas[code] = schema
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storage.ErrQuery
+	}
+	return schemas, nil
+}
+
+func scanAttributes(attributes sql.NullString) ([]models.Attribute, error) {
+	if !attributes.Valid {
+		return nil, nil
+	}
+	var schema []models.Attribute
+	if err := json.Unmarshal([]byte(attributes.String), &schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// attributeFilter returns the condition on the product p for attributes of the filter with its arguments
+func attributeFilter(filter models.ProductFilter) (string, []interface{}, error) {
+	conditions := []string{"TRUE"}
+	args := make([]interface{}, 0)
+	for _, attribute := range filter.Attributes {
+		if len(attribute.Values) != 0 {
+			values, err := json.Marshal(attribute.Values)
+			if err != nil {
+				return "", nil, err
+			}
+			numbers := make([]float64, 0, len(attribute.Values))
+			for _, value := range attribute.Values {
+				if number, err := strconv.ParseFloat(value, 64); err == nil {
+					numbers = append(numbers, number)
+				}
+			}
+			numbersText, err := json.Marshal(numbers)
+			if err != nil {
+				return "", nil, err
+			}
+			conditions = append(conditions, attributeValues)
+			args = append(args, attribute.Name, string(values), string(values), string(numbersText))
+		}
+		if attribute.Min != nil || attribute.Max != nil {
+			conditions = append(conditions, attributeRange)
+			args = append(args, attribute.Name, attribute.Min, attribute.Min, attribute.Max, attribute.Max)
+		}
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
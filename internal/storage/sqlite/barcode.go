@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// productIdentifiers selects sku of the product p, empty if it has none,
+// and its barcodes as json array, NULL if it has no barcodes
+const productIdentifiers = `COALESCE(p.sku, '') as sku, (
+	SELECT CASE
+		WHEN COUNT(*) = 0 THEN NULL
+		ELSE json_group_array(b.code)
+	END
+	FROM (
+		SELECT code FROM product_barcodes
+		WHERE product_id = p.product_id
+		ORDER BY code
+	) as b
+) as barcodes`
+
+// productExistErr tells which unique column of the product is taken by the error,
+// it is nil if the error is not a unique violation
+func productExistErr(err error) error {
+	if !isUnique(err) {
+		return nil
+	}
+	switch {
+	case strings.Contains(err.Error(), "products.sku"):
+		return storage.ErrSkuExist
+	case strings.Contains(err.Error(), "product_barcodes.code"):
+		return storage.ErrBarcodeExist
+	}
+	return storage.ErrProductExist
+}
+
+// nullSku saves empty sku as NULL, so products without sku don't conflict
+func nullSku(sku string) interface{} {
+	if sku == "" {
+		return nil
+	}
+	return sku
+}
+
+// GetProductBySku returns the not deleted product with the sku
+func (sp *sqliteProvider) GetProductBySku(ctx context.Context, sku string) (models.Product, error) {
+	return sp.getProduct(ctx, "p.sku = ?", sku)
+}
+
+// GetProductByBarcode returns the not deleted product with the barcode
+func (sp *sqliteProvider) GetProductByBarcode(ctx context.Context, code string) (models.Product, error) {
+	return sp.getProduct(ctx, "p.product_id = (SELECT product_id FROM product_barcodes WHERE code = ?)", code)
+}
+
+// getProduct returns the not deleted product found by the condition on p with one argument
+func (sp *sqliteProvider) getProduct(ctx context.Context, condition string, arg interface{}) (models.Product, error) {
+	return queryProduct(ctx, sp.conn(ctx), condition, arg)
+}
+
+// queryProduct is getProduct on db, which may be the transaction of a write to read the product it has changed
+func queryProduct(ctx context.Context, db querier, condition string, arg interface{}) (models.Product, error) {
+	row := db.QueryRowContext(ctx, fmt.Sprintf(selectProducts, condition, "TRUE"), arg)
+	product, err := scanProduct(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Product{}, storage.ErrProductNotFound
+		}
+		return models.Product{}, storage.ErrQuery
+	}
+	return product, nil
+}
+
+// skuTaken reports whether a not deleted product has the sku
+func skuTaken(ctx context.Context, transaction querier, sku string) (bool, error) {
+	var taken bool
+	err := transaction.QueryRowContext(ctx, `
+SELECT EXISTS (SELECT 1 FROM products WHERE sku = ? AND deleted_at IS NULL)`,
+	sku).Scan(&taken)
+	if err != nil {
+		return false, storage.ErrQuery
+	}
+	return taken, nil
+}
+
+// insertBarcodes adds barcodes to the product
+func insertBarcodes(ctx context.Context, transaction querier, prodId int, barcodes []string) error {
+	for _, code := range barcodes {
+		_, err := transaction.ExecContext(ctx, "INSERT INTO product_barcodes (code, product_id) VALUES (?, ?)", code, prodId)
+		if err != nil {
+			if existErr := productExistErr(err); existErr != nil {
+				return existErr
+			}
+			return storage.ErrQuery
+		}
+	}
+	return nil
+}
+
+// replaceBarcodes sets exactly the barcodes to the product
+func replaceBarcodes(ctx context.Context, transaction querier, prodId int, barcodes []string) error {
+	if _, err := transaction.ExecContext(ctx, "DELETE FROM product_barcodes WHERE product_id = ?", prodId); err != nil {
+		return storage.ErrQuery
+	}
+	return insertBarcodes(ctx, transaction, prodId, barcodes)
+}
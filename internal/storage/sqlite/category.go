@@ -0,0 +1,361 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+func (sp *sqliteProvider) SaveCategory(ctx context.Context, category models.Category) error {
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	attributes, err := jsonText(category.Attributes, len(category.Attributes) == 0)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	var id int
+	err = transaction.QueryRowContext(ctx, `INSERT INTO categories (name, code, description, attributes)
+VALUES(?,?,?,?)
+RETURNING category_id;`,
+	category.Name,
+	category.Code,
+	category.Description,
+	attributes).Scan(&id)
+	if err != nil {
+		if isUnique(err) {
+			return storage.ErrCategoryExist
+		}
+		return storage.ErrQuery
+	}
+	if err := categoryEvent(ctx, transaction, models.EventCategoryCreated, id); err != nil {
+		return err
+	}
+	if err := transaction.Commit(); err != nil {
+		return storage.ErrCommitTx
+	}
+	return nil
+}
+
+func (sp *sqliteProvider) InserOrGetCategiriesId(ctx context.Context, categories []models.Category) (map[string]int, error) {
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return nil, storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	categoriesMap := make(map[string]int, len(categories))
+	for _, catg := range categories {
+		res, err := transaction.ExecContext(ctx, `
+INSERT INTO categories (name, code, description)
+VALUES (?, ?, ?)
+ON CONFLICT (code) WHERE deleted_at IS NULL DO NOTHING;`,
+		catg.Name,
+		catg.Code,
+		catg.Description)
+		if err != nil {
+			return nil, storage.ErrQuery
+		}
+		var id int
+		err = transaction.QueryRowContext(ctx, `SELECT category_id FROM categories WHERE code = ? AND deleted_at IS NULL;`, catg.Code).Scan(&id)
+		if err != nil {
+			return nil, storage.ErrQuery
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return nil, storage.ErrQuery
+		}
+		if inserted != 0 {
+			if err := categoryEvent(ctx, transaction, models.EventCategoryCreated, id); err != nil {
+				return nil, err
+			}
+		}
+		categoriesMap[catg.Code] = id
+	}
+	if err := transaction.Commit(); err != nil {
+		return nil, storage.ErrCommitTx
+	}
+	return categoriesMap, nil
+}
+
+func (sp *sqliteProvider) GetCategoryByCode(ctx context.Context, catCode string) (models.Category, error) {
+	return queryCategory(ctx, sp.conn(ctx), "code = ?", catCode)
+}
+
+// queryCategory returns the not deleted category found by the condition with one argument,
+// db may be the transaction of a write to read the category it changes
+func queryCategory(ctx context.Context, db querier, condition string, arg interface{}) (models.Category, error) {
+	row := db.QueryRowContext(ctx, fmt.Sprintf(`
+SELECT name, code, description, attributes, version
+FROM categories
+WHERE %s AND deleted_at IS NULL;`,
+	condition),
+	arg)
+	var (
+		category models.Category
+		attributes sql.NullString
+	)
+	if err := row.Scan(&category.Name, &category.Code, &category.Description, &attributes, &category.Version); err != nil {
+		return models.Category{}, storage.ErrQuery
+	}
+	var err error
+	if category.Attributes, err = scanAttributes(attributes); err != nil {
+		return models.Category{}, storage.ErrQuery
+	}
+	return category, nil
+}
+
+// GetCategoriesIdByCodes returns ids of existing categories, codes are passed as json array
+func (sp *sqliteProvider) GetCategoriesIdByCodes(ctx context.Context, catCodes []string) ([]int, error) {
+	codes, err := json.Marshal(catCodes)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := sp.conn(ctx).QueryContext(ctx, `
+SELECT category_id
+FROM categories
+WHERE code IN (SELECT value FROM json_each(?)) AND deleted_at IS NULL
+ORDER BY category_id`,
+	string(codes))
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	defer rows.Close()
+	var outCategoriesId []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, storage.ErrQuery
+		}
+		outCategoriesId = append(outCategoriesId, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storage.ErrQuery
+	}
+	return outCategoriesId, nil
+}
+
+// GetCategoriesIdMapByCodes returns ids of existing categories by their codes,
+// codes that do not exist are absent in the map
+func (sp *sqliteProvider) GetCategoriesIdMapByCodes(ctx context.Context, catCodes []string) (map[string]int, error) {
+	codes, err := json.Marshal(catCodes)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := sp.conn(ctx).QueryContext(ctx, `
+SELECT code, category_id
+FROM categories
+WHERE code IN (SELECT value FROM json_each(?)) AND deleted_at IS NULL`,
+	string(codes))
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	defer rows.Close()
+	categoriesId := make(map[string]int, len(catCodes))
+	for rows.Next() {
+		var (
+			code string
+			id int
+		)
+		if err := rows.Scan(&code, &id); err != nil {
+			return nil, storage.ErrQuery
+		}
+		categoriesId[code] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storage.ErrQuery
+	}
+	return categoriesId, nil
+}
+
+func (sp *sqliteProvider) GetAllCategories(ctx context.Context) ([]models.Category, error) {
+	var outCategorys []models.Category
+	err := sp.StreamCategories(ctx, func(category models.Category) error {
+		outCategorys = append(outCategorys, category)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outCategorys, nil
+}
+
+// StreamCategories calls fn for every category reading rows one by one
+func (sp *sqliteProvider) StreamCategories(ctx context.Context, fn func(models.Category) error) error {
+	rows, err := sp.conn(ctx).QueryContext(ctx, `
+SELECT name, code, description, attributes
+FROM categories
+WHERE deleted_at IS NULL
+ORDER BY category_id`)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			category models.Category
+			attributes sql.NullString
+		)
+		if err := rows.Scan(&category.Name, &category.Code, &category.Description, &attributes); err != nil {
+			return storage.ErrQuery
+		}
+		if category.Attributes, err = scanAttributes(attributes); err != nil {
+			return storage.ErrQuery
+		}
+		if err := fn(category); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return storage.ErrQuery
+	}
+	return nil
+}
+
+// UpdateCategoryByCode changes the fields that are set and counts the version of the category,
+// the attribute schema is replaced if it is not nil. Returns the changed category, the zero category if there is no one,
+// and ErrVersionConflict if version is not zero and the category has another one
+func (sp *sqliteProvider) UpdateCategoryByCode(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch, version int) (models.Category, error) {
+	preparedQuery := "UPDATE categories SET version = version + 1, "
+	usedData := make([]interface{}, 0)
+	if catUpdateData.Name != nil {
+		preparedQuery += "name = ?, "
+		usedData = append(usedData, *catUpdateData.Name)
+	}
+	if catUpdateData.Code != nil {
+		preparedQuery += "code = ?, "
+		usedData = append(usedData, *catUpdateData.Code)
+	}
+	if catUpdateData.Description != nil {
+		preparedQuery += "description = ?, "
+		usedData = append(usedData, *catUpdateData.Description)
+	}
+	if catUpdateData.Attributes != nil {
+		attributes, err := jsonText(catUpdateData.Attributes, len(catUpdateData.Attributes) == 0)
+		if err != nil {
+			return models.Category{}, storage.ErrQuery
+		}
+		preparedQuery += "attributes = ?, "
+		usedData = append(usedData, attributes)
+	}
+	if len(usedData) == 0 {
+		return models.Category{}, storage.ErrQuery
+	}
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return models.Category{}, storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	// the category is found by id, so its event is written after the code is changed
+	id, current, err := categoryVersion(ctx, transaction, catCode)
+	if err != nil {
+		return models.Category{}, err
+	}
+	if id == 0 {
+		return models.Category{}, nil
+	}
+	if version != 0 && version != current {
+		return models.Category{}, storage.ErrVersionConflict
+	}
+	preparedQuery = preparedQuery[:len(preparedQuery)-2]
+	usedData = append(usedData, id)
+	_, err = transaction.ExecContext(ctx, fmt.Sprintf("%s WHERE category_id = ?", preparedQuery), usedData...)
+	if err != nil {
+		if isUnique(err) {
+			return models.Category{}, storage.ErrCategoryExist
+		}
+		return models.Category{}, storage.ErrQuery
+	}
+	if err := categoryEvent(ctx, transaction, models.EventCategoryUpdated, id); err != nil {
+		return models.Category{}, err
+	}
+	category, err := queryCategory(ctx, transaction, "category_id = ?", id)
+	if err != nil {
+		return models.Category{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return models.Category{}, storage.ErrCommitTx
+	}
+	return category, nil
+}
+
+// categoryVersion returns id and version of the not deleted category, zero id if there is no such category
+func categoryVersion(ctx context.Context, transaction querier, catCode string) (int, int, error) {
+	var id, version int
+	err := transaction.QueryRowContext(ctx, `
+SELECT category_id, version FROM categories
+WHERE code = ? AND deleted_at IS NULL`,
+	catCode).Scan(&id, &version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, nil
+		}
+		return 0, 0, storage.ErrQuery
+	}
+	return id, version, nil
+}
+
+// DeleteCategoryBycode moves the category to the trash.
+// Returns ErrCategoryUsed if any not deleted product has this category and
+// ErrVersionConflict if version is not zero and the category has another one, otherwise the category
+// as it was before deletion or the zero category if there is no one
+func (sp *sqliteProvider) DeleteCategoryBycode(ctx context.Context, catCode string, version int) (models.Category, error) {
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return models.Category{}, storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	var used bool
+	err = transaction.QueryRowContext(ctx, `
+SELECT EXISTS (
+	SELECT 1
+	FROM categories as c
+	JOIN product_category as pc ON pc.category_id = c.category_id
+	JOIN products as p ON p.product_id = pc.product_id
+	WHERE c.code = ? AND c.deleted_at IS NULL AND p.deleted_at IS NULL
+)`,
+	catCode).Scan(&used)
+	if err != nil {
+		return models.Category{}, storage.ErrQuery
+	}
+	if used {
+		return models.Category{}, storage.ErrCategoryUsed
+	}
+	id, current, err := categoryVersion(ctx, transaction, catCode)
+	if err != nil {
+		return models.Category{}, err
+	}
+	// missing category is not deleted like without the version
+	if id == 0 {
+		return models.Category{}, nil
+	}
+	if version != 0 && version != current {
+		return models.Category{}, storage.ErrVersionConflict
+	}
+	category, err := queryCategory(ctx, transaction, "category_id = ?", id)
+	if err != nil {
+		return models.Category{}, err
+	}
+	_, err = transaction.ExecContext(ctx, `
+UPDATE categories SET deleted_at = ?
+WHERE category_id = ?`,
+	time.Now().UTC(),
+	id)
+	if err != nil {
+		return models.Category{}, storage.ErrQuery
+	}
+	if err := categoryEvent(ctx, transaction, models.EventCategoryDeleted, id); err != nil {
+		return models.Category{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return models.Category{}, storage.ErrCommitTx
+	}
+	return category, nil
+}
@@ -0,0 +1,204 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+const jobColumns = `job_id, kind, state, content_type, payload_path, atomic, cancel_requested,
+processed, created, skipped, invalid, failed, error, report, created_by,
+created_at, started_at, finished_at`
+
+func (sp *sqliteProvider) SaveJob(ctx context.Context, job models.Job) (string, error) {
+	var id int
+	err := sp.conn(ctx).QueryRowContext(ctx, `
+INSERT INTO jobs (kind, state, content_type, payload_path, atomic, created_by, created_at)
+VALUES(?,?,?,?,?,?,?)
+RETURNING job_id;`,
+	job.Kind,
+	job.State,
+	job.ContentType,
+	job.PayloadPath,
+	job.Atomic,
+	job.CreatedBy,
+	time.Now()).Scan(&id)
+	if err != nil {
+		return "", storage.ErrQuery
+	}
+	return strconv.Itoa(id), nil
+}
+
+func (sp *sqliteProvider) GetJobById(ctx context.Context, jobId string) (models.Job, error) {
+	row := sp.conn(ctx).QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE job_id = ?`, jobId)
+	return scanJobRow(row)
+}
+
+// ClaimQueuedJob moves the oldest queued job to running state and returns it.
+// Writes are serialized by sqlite, so several workers never get the same job
+func (sp *sqliteProvider) ClaimQueuedJob(ctx context.Context) (models.Job, error) {
+	row := sp.conn(ctx).QueryRowContext(ctx, `
+UPDATE jobs SET state = ?, started_at = ?
+WHERE job_id = (
+	SELECT job_id FROM jobs
+	WHERE state = ?
+	ORDER BY job_id
+	LIMIT 1
+)
+RETURNING `+jobColumns,
+	models.JobStateRunning,
+	time.Now(),
+	models.JobStateQueued)
+	return scanJobRow(row)
+}
+
+// UpdateJobProgress saves counters of the running job and reports whether its cancellation was requested
+func (sp *sqliteProvider) UpdateJobProgress(ctx context.Context, jobId string, counters models.JobCounters) (bool, error) {
+	var cancelRequested bool
+	err := sp.conn(ctx).QueryRowContext(ctx, `
+UPDATE jobs SET processed = ?, created = ?, skipped = ?, invalid = ?, failed = ?
+WHERE job_id = ?
+RETURNING cancel_requested`,
+	counters.Processed,
+	counters.Created,
+	counters.Skipped,
+	counters.Invalid,
+	counters.Failed,
+	jobId).Scan(&cancelRequested)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, storage.ErrJobNotFound
+		}
+		return false, storage.ErrQuery
+	}
+	return cancelRequested, nil
+}
+
+// FinishJob sets the final state of the job with its counters, error and report
+func (sp *sqliteProvider) FinishJob(ctx context.Context, job models.Job) error {
+	var report *string
+	if len(job.Report) != 0 {
+		data, err := json.Marshal(job.Report)
+		if err != nil {
+			return err
+		}
+		reportStr := string(data)
+		report = &reportStr
+	}
+	var jobErr *string
+	if job.Error != "" {
+		jobErr = &job.Error
+	}
+	_, err := sp.conn(ctx).ExecContext(ctx, `
+UPDATE jobs SET state = ?, processed = ?, created = ?, skipped = ?, invalid = ?, failed = ?,
+error = ?, report = ?, finished_at = ?
+WHERE job_id = ?`,
+	job.State,
+	job.Counters.Processed,
+	job.Counters.Created,
+	job.Counters.Skipped,
+	job.Counters.Invalid,
+	job.Counters.Failed,
+	jobErr,
+	report,
+	time.Now(),
+	job.Id)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	return nil
+}
+
+// RequestJobCancel cancels queued job right away and marks running job to be cancelled by its worker.
+// Returns the job state after the request
+func (sp *sqliteProvider) RequestJobCancel(ctx context.Context, jobId string) (string, error) {
+	var state string
+	err := sp.conn(ctx).QueryRowContext(ctx, `
+UPDATE jobs SET
+	cancel_requested = state IN (?1, ?2),
+	state = CASE WHEN state = ?1 THEN ?3 ELSE state END,
+	finished_at = CASE WHEN state = ?1 THEN ?4 ELSE finished_at END
+WHERE job_id = ?5
+RETURNING state`,
+	models.JobStateQueued,
+	models.JobStateRunning,
+	models.JobStateCancelled,
+	time.Now(),
+	jobId).Scan(&state)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", storage.ErrJobNotFound
+		}
+		return "", storage.ErrQuery
+	}
+	return state, nil
+}
+
+// RecoverRunningJobs handles jobs left in running state by stopped process:
+// they are queued again with reset counters if resume is true, otherwise failed
+func (sp *sqliteProvider) RecoverRunningJobs(ctx context.Context, resume bool) (int, error) {
+	query := `
+UPDATE jobs SET state = ?1, error = ?2, finished_at = ?3
+WHERE state = ?4`
+	args := []interface{}{models.JobStateFailed, "interrupted by restart", time.Now(), models.JobStateRunning}
+	if resume {
+		query = `
+UPDATE jobs SET state = CASE WHEN cancel_requested THEN ?1 ELSE ?2 END,
+finished_at = CASE WHEN cancel_requested THEN ?3 ELSE NULL END,
+processed = 0, created = 0, skipped = 0, invalid = 0, failed = 0, started_at = NULL
+WHERE state = ?4`
+		args = []interface{}{models.JobStateCancelled, models.JobStateQueued, time.Now(), models.JobStateRunning}
+	}
+	res, err := sp.conn(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, storage.ErrQuery
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, storage.ErrQuery
+	}
+	return int(count), nil
+}
+
+func scanJobRow(row *sql.Row) (models.Job, error) {
+	var (
+		job models.Job
+		id int
+		jobErr sql.NullString
+		report sql.NullString
+		createdBy sql.NullString
+		startedAt sql.NullTime
+		finishedAt sql.NullTime
+	)
+	err := row.Scan(&id, &job.Kind, &job.State, &job.ContentType, &job.PayloadPath, &job.Atomic, &job.CancelRequested,
+		&job.Counters.Processed, &job.Counters.Created, &job.Counters.Skipped, &job.Counters.Invalid, &job.Counters.Failed,
+		&jobErr, &report, &createdBy,
+		&job.CreatedAt, &startedAt, &finishedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Job{}, storage.ErrJobNotFound
+		}
+		return models.Job{}, storage.ErrQuery
+	}
+	job.Id = strconv.Itoa(id)
+	job.Error = jobErr.String
+	job.CreatedBy = createdBy.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	if report.Valid {
+		if err := json.Unmarshal([]byte(report.String), &job.Report); err != nil {
+			return models.Job{}, err
+		}
+	}
+	return job, nil
+}
@@ -0,0 +1,178 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// productMedia selects media of the product p as json array ordered by position, NULL if the product has none
+const productMedia = `(
+	SELECT CASE
+		WHEN COUNT(*) = 0 THEN NULL
+		ELSE json_group_array(json_object(
+			'id', m.media_id, 'url', m.url, 'thumbnail_url', m.thumbnail_url, 'content_type', m.content_type,
+			'size', m.size, 'width', m.width, 'height', m.height, 'position', m.position,
+			'primary', json(CASE WHEN m.is_primary THEN 'true' ELSE 'false' END), 'created_at', m.created_at
+		))
+	END
+	FROM (
+		SELECT * FROM product_media
+		WHERE product_id = p.product_id
+		ORDER BY position
+	) as m
+) as media`
+
+// SaveMedia adds the media after the other media of the not deleted product and counts the version of the product.
+// The first media of the product is primary, the media replaces the primary one if it has Primary set
+func (sp *sqliteProvider) SaveMedia(ctx context.Context, prodId string, media models.Media) (models.Media, error) {
+	saved := media
+	saved.CreatedAt = time.Now().UTC()
+	err := sp.changeProduct(ctx, prodId, func(transaction querier, prodId int) error {
+		err := transaction.QueryRowContext(ctx, `
+INSERT INTO product_media (product_id, file_key, thumbnail_key, url, thumbnail_url, content_type, size, width, height, position, is_primary, created_at)
+SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(MAX(position) + 1, 0), COUNT(*) = 0, ?
+FROM product_media WHERE product_id = ?
+RETURNING media_id, position, is_primary`,
+		prodId,
+		media.Key,
+		media.ThumbnailKey,
+		media.Url,
+		media.ThumbnailUrl,
+		media.ContentType,
+		media.Size,
+		media.Width,
+		media.Height,
+		saved.CreatedAt.Format(time.RFC3339Nano),
+		prodId).Scan(&saved.Id, &saved.Position, &saved.Primary)
+		if err != nil {
+			return storage.ErrQuery
+		}
+		if media.Primary && !saved.Primary {
+			saved.Primary = true
+			return setPrimaryMedia(ctx, transaction, prodId, saved.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Media{}, err
+	}
+	return saved, nil
+}
+
+// DeleteMediaById removes the media of the not deleted product and counts the version of the product.
+// Media after it are moved up, the first one becomes primary if the removed one was. The removed media
+// is returned with the keys of its files
+func (sp *sqliteProvider) DeleteMediaById(ctx context.Context, prodId, mediaId string) (models.Media, error) {
+	id, err := strconv.Atoi(mediaId)
+	if err != nil {
+		return models.Media{}, storage.ErrQuery
+	}
+	var media models.Media
+	err = sp.changeProduct(ctx, prodId, func(transaction querier, prodId int) error {
+		var createdAt string
+		err := transaction.QueryRowContext(ctx, `
+DELETE FROM product_media WHERE media_id = ? AND product_id = ?
+RETURNING media_id, file_key, thumbnail_key, url, thumbnail_url, content_type, size, width, height, position, is_primary, created_at`,
+		id,
+		prodId).Scan(&media.Id, &media.Key, &media.ThumbnailKey, &media.Url, &media.ThumbnailUrl, &media.ContentType,
+			&media.Size, &media.Width, &media.Height, &media.Position, &media.Primary, &createdAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return storage.ErrMediaNotFound
+			}
+			return storage.ErrQuery
+		}
+		if media.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+			return storage.ErrQuery
+		}
+		_, err = transaction.ExecContext(ctx, "UPDATE product_media SET position = position - 1 WHERE product_id = ? AND position > ?", prodId, media.Position)
+		if err != nil {
+			return storage.ErrQuery
+		}
+		if !media.Primary {
+			return nil
+		}
+		_, err = transaction.ExecContext(ctx, "UPDATE product_media SET is_primary = 1 WHERE product_id = ? AND position = 0", prodId)
+		if err != nil {
+			return storage.ErrQuery
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Media{}, err
+	}
+	return media, nil
+}
+
+// SetMediaOrder sets positions of media of the not deleted product by their order in mediaIds
+// and counts the version of the product. ErrMediaOrder is returned if mediaIds don't have
+// every media of the product exactly once
+func (sp *sqliteProvider) SetMediaOrder(ctx context.Context, prodId string, mediaIds []int) error {
+	return sp.changeProduct(ctx, prodId, func(transaction querier, prodId int) error {
+		var count int
+		err := transaction.QueryRowContext(ctx, "SELECT COUNT(*) FROM product_media WHERE product_id = ?", prodId).Scan(&count)
+		if err != nil {
+			return storage.ErrQuery
+		}
+		if count != len(mediaIds) {
+			return storage.ErrMediaOrder
+		}
+		seen := make(map[int]struct{}, len(mediaIds))
+		for position, id := range mediaIds {
+			if _, ok := seen[id]; ok {
+				return storage.ErrMediaOrder
+			}
+			seen[id] = struct{}{}
+			res, err := transaction.ExecContext(ctx, "UPDATE product_media SET position = ? WHERE media_id = ? AND product_id = ?", position, id, prodId)
+			if err != nil {
+				return storage.ErrQuery
+			}
+			changed, err := res.RowsAffected()
+			if err != nil {
+				return storage.ErrQuery
+			}
+			if changed == 0 {
+				return storage.ErrMediaOrder
+			}
+		}
+		return nil
+	})
+}
+
+// SetPrimaryMedia makes the media primary instead of the current one and counts the version of the product
+func (sp *sqliteProvider) SetPrimaryMedia(ctx context.Context, prodId, mediaId string) error {
+	id, err := strconv.Atoi(mediaId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	return sp.changeProduct(ctx, prodId, func(transaction querier, prodId int) error {
+		return setPrimaryMedia(ctx, transaction, prodId, id)
+	})
+}
+
+// setPrimaryMedia unsets the current primary media before the new one is set,
+// so the unique index of primary media is never violated in between
+func setPrimaryMedia(ctx context.Context, transaction querier, prodId, mediaId int) error {
+	_, err := transaction.ExecContext(ctx, "UPDATE product_media SET is_primary = 0 WHERE product_id = ? AND is_primary AND media_id <> ?", prodId, mediaId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	res, err := transaction.ExecContext(ctx, "UPDATE product_media SET is_primary = 1 WHERE product_id = ? AND media_id = ?", prodId, mediaId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	changed, err := res.RowsAffected()
+	if err != nil {
+		return storage.ErrQuery
+	}
+	if changed == 0 {
+		return storage.ErrMediaNotFound
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// productEvent writes the event of the product with its row after the change
+func productEvent(ctx context.Context, transaction querier, eventType string, prodId int) error {
+	_, err := transaction.ExecContext(ctx, `
+INSERT INTO outbox (aggregate, aggregate_id, event_type, payload, created_at)
+SELECT ?, CAST(product_id AS TEXT), ?,
+json_object('id', CAST(product_id AS TEXT), 'name', name, 'description', description, 'state', state, 'version', version), ?
+FROM products WHERE product_id = ?`,
+	models.EventAggregateProduct,
+	eventType,
+	time.Now().UTC(),
+	prodId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	return nil
+}
+
+// categoryEvent writes the event of the category with its row after the change
+func categoryEvent(ctx context.Context, transaction querier, eventType string, catId int) error {
+	_, err := transaction.ExecContext(ctx, `
+INSERT INTO outbox (aggregate, aggregate_id, event_type, payload, created_at)
+SELECT ?, CAST(category_id AS TEXT), ?,
+json_object('name', name, 'code', code, 'description', description, 'version', version), ?
+FROM categories WHERE category_id = ?`,
+	models.EventAggregateCategory,
+	eventType,
+	time.Now().UTC(),
+	catId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	return nil
+}
+
+// RelayEvents passes up to limit oldest events which are not dead to publish and applies its result.
+// Events stay in the outbox until they are published, so they are delivered at least once.
+// The database has a single writer, so relays are serialized inside the process instead of claiming events
+// for claimTimeout, and no transaction holds the write lock while events are published
+func (sp *sqliteProvider) RelayEvents(ctx context.Context, limit int, claimTimeout time.Duration, publish func([]models.Event) models.RelayResult) error {
+	sp.relayMu.Lock()
+	defer sp.relayMu.Unlock()
+	rows, err := sp.conn(ctx).QueryContext(ctx, `
+SELECT event_id, aggregate, aggregate_id, event_type, payload, created_at, attempts
+FROM outbox
+WHERE dead_at IS NULL
+ORDER BY event_id
+LIMIT ?`,
+	limit)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	defer rows.Close()
+	events := []models.Event{}
+	for rows.Next() {
+		var (
+			event models.Event
+			payload string
+		)
+		if err := rows.Scan(&event.Id, &event.Aggregate, &event.AggregateId, &event.Type, &payload, &event.CreatedAt, &event.Attempts); err != nil {
+			return storage.ErrQuery
+		}
+		event.Payload = []byte(payload)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return storage.ErrQuery
+	}
+	rows.Close()
+	if len(events) == 0 {
+		return nil
+	}
+	return sp.finishEvents(ctx, publish(events))
+}
+
+// finishEvents removes published events and counts the attempt of failed and dead ones in one transaction
+func (sp *sqliteProvider) finishEvents(ctx context.Context, result models.RelayResult) error {
+	published, err := json.Marshal(result.Published)
+	if err != nil {
+		return err
+	}
+	failed, err := json.Marshal(result.Failed)
+	if err != nil {
+		return err
+	}
+	dead, err := json.Marshal(result.Dead)
+	if err != nil {
+		return err
+	}
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	if _, err := transaction.ExecContext(ctx, "DELETE FROM outbox WHERE event_id IN (SELECT value FROM json_each(?))", string(published)); err != nil {
+		return storage.ErrQuery
+	}
+	if _, err := transaction.ExecContext(ctx, "UPDATE outbox SET attempts = attempts + 1 WHERE event_id IN (SELECT value FROM json_each(?))", string(failed)); err != nil {
+		return storage.ErrQuery
+	}
+	_, err = transaction.ExecContext(ctx, `
+UPDATE outbox SET attempts = attempts + 1, dead_at = ?
+WHERE event_id IN (SELECT value FROM json_each(?))`,
+	time.Now().UTC(),
+	string(dead))
+	if err != nil {
+		return storage.ErrQuery
+	}
+	if err := transaction.Commit(); err != nil {
+		return storage.ErrCommitTx
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// priceTimeLayout is RFC 3339 with the fixed number of fraction digits,
+// times in this layout are ordered like strings
+const priceTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// productPrices selects prices of the product p as json array, NULL if it has no prices
+const productPrices = `(
+	SELECT CASE
+		WHEN COUNT(*) = 0 THEN NULL
+		ELSE json_group_array(json_object(
+			'kind', pr.kind, 'amount', pr.amount, 'currency', pr.currency, 'valid_from', pr.valid_from, 'valid_to', pr.valid_to
+		))
+	END
+	FROM (
+		SELECT * FROM product_prices
+		WHERE product_id = p.product_id
+		ORDER BY kind, currency, valid_from
+	) as pr
+) as prices`
+
+// priceFilter matches products whose lowest price valid now in any currency or in the given one is within the bounds,
+// its arguments are returned by priceFilterArgs
+const priceFilter = `EXISTS (
+	SELECT 1
+	FROM product_prices as pr
+	WHERE pr.product_id = p.product_id
+	AND (pr.valid_from IS NULL OR pr.valid_from <= ?)
+	AND (pr.valid_to IS NULL OR pr.valid_to > ?)
+	AND (? = '' OR pr.currency = ?)
+	GROUP BY pr.currency
+	HAVING (? IS NULL OR MIN(pr.amount) >= ?) AND (? IS NULL OR MIN(pr.amount) <= ?)
+)`
+
+func priceFilterArgs(filter models.ProductFilter) []interface{} {
+	now := priceTime(time.Now())
+	return []interface{}{now, now, filter.Currency, filter.Currency, filter.MinPrice, filter.MinPrice, filter.MaxPrice, filter.MaxPrice}
+}
+
+func priceTime(t time.Time) string {
+	return t.UTC().Format(priceTimeLayout)
+}
+
+// priceBound returns the bound of validity to be saved, NULL for the open one
+func priceBound(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return priceTime(*t)
+}
+
+// insertPrices adds prices to the product
+func insertPrices(ctx context.Context, transaction querier, prodId int, prices []models.Price) error {
+	for _, price := range prices {
+		_, err := transaction.ExecContext(ctx, `
+INSERT INTO product_prices (product_id, kind, amount, currency, valid_from, valid_to)
+VALUES (?, ?, ?, ?, ?, ?)`,
+		prodId,
+		price.Kind,
+		price.Amount,
+		price.Currency,
+		priceBound(price.ValidFrom),
+		priceBound(price.ValidTo))
+		if err != nil {
+			return storage.ErrQuery
+		}
+	}
+	return nil
+}
+
+// replacePrices sets exactly the prices to the product
+func replacePrices(ctx context.Context, transaction querier, prodId int, prices []models.Price) error {
+	if _, err := transaction.ExecContext(ctx, "DELETE FROM product_prices WHERE product_id = ?", prodId); err != nil {
+		return storage.ErrQuery
+	}
+	return insertPrices(ctx, transaction, prodId, prices)
+}
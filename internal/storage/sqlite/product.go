@@ -0,0 +1,505 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// selectProducts selects not deleted products with codes of their not deleted categories as json array,
+// NULL if the product has no categories, their prices, skus, barcodes, options, variants, media and attributes. Conditions are put into WHERE and HAVING
+const selectProducts = `
+SELECT p.product_id, p.name, p.description, p.state, p.version,
+CASE
+	WHEN COUNT(pc.category_id) = 0 THEN NULL
+	ELSE json_group_array(c.code)
+END as category_codes,
+` + productPrices + `,
+` + productIdentifiers + `,
+` + productVariants + `,
+` + productMedia + `,
+p.attributes
+FROM products as p
+LEFT JOIN (product_category as pc
+	JOIN categories as c ON c.category_id = pc.category_id AND c.deleted_at IS NULL
+) ON pc.product_id = p.product_id
+WHERE p.deleted_at IS NULL AND %s
+GROUP BY p.product_id
+HAVING %s
+ORDER BY p.product_id`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(row scanner) (models.Product, error) {
+	var (
+		product models.Product
+		codes, prices, barcodes, options, variants, media, attributes sql.NullString
+	)
+	if err := row.Scan(&product.Id, &product.Name, &product.Description, &product.State, &product.Version, &codes, &prices, &product.Sku, &barcodes, &options, &variants, &media, &attributes); err != nil {
+		return models.Product{}, err
+	}
+	if codes.Valid {
+		if err := json.Unmarshal([]byte(codes.String), &product.CategoryСodes); err != nil {
+			return models.Product{}, err
+		}
+	}
+	if prices.Valid {
+		if err := json.Unmarshal([]byte(prices.String), &product.Prices); err != nil {
+			return models.Product{}, err
+		}
+	}
+	if barcodes.Valid {
+		if err := json.Unmarshal([]byte(barcodes.String), &product.Barcodes); err != nil {
+			return models.Product{}, err
+		}
+	}
+	if options.Valid {
+		if err := json.Unmarshal([]byte(options.String), &product.Options); err != nil {
+			return models.Product{}, err
+		}
+	}
+	if variants.Valid {
+		if err := json.Unmarshal([]byte(variants.String), &product.Variants); err != nil {
+			return models.Product{}, err
+		}
+	}
+	if media.Valid {
+		if err := json.Unmarshal([]byte(media.String), &product.Media); err != nil {
+			return models.Product{}, err
+		}
+	}
+	if attributes.Valid {
+		if err := json.Unmarshal([]byte(attributes.String), &product.Attributes); err != nil {
+			return models.Product{}, err
+		}
+	}
+	return product, nil
+}
+
+func (sp *sqliteProvider) SaveProduct(ctx context.Context, product models.Product, catIds []int) (string, error) {
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return "", storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	options, err := jsonText(product.Options, len(product.Options) == 0)
+	if err != nil {
+		return "", storage.ErrQuery
+	}
+	attributes, err := jsonText(product.Attributes, len(product.Attributes) == 0)
+	if err != nil {
+		return "", storage.ErrQuery
+	}
+	var id int
+	err = transaction.QueryRowContext(ctx, `
+INSERT INTO products (name, description, sku, options, attributes)
+VALUES(?,?,?,?,?)
+RETURNING product_id;`,
+	product.Name,
+	product.Description,
+	nullSku(product.Sku),
+	options,
+	attributes).Scan(&id)
+	if err != nil {
+		if existErr := productExistErr(err); existErr != nil {
+			return "", existErr
+		}
+		return "", storage.ErrQuery
+	}
+	for _, сid := range catIds {
+		_, err = transaction.ExecContext(ctx, `
+INSERT INTO product_category (product_id, category_id)
+VALUES (?, ?)`,
+		id,
+		сid)
+		if err != nil {
+			return "", storage.ErrQuery
+		}
+	}
+	if err := insertPrices(ctx, transaction, id, product.Prices); err != nil {
+		return "", err
+	}
+	if err := insertBarcodes(ctx, transaction, id, product.Barcodes); err != nil {
+		return "", err
+	}
+	if err := productEvent(ctx, transaction, models.EventProductCreated, id); err != nil {
+		return "", err
+	}
+	if err := transaction.Commit(); err != nil {
+		return "", storage.ErrCommitTx
+	}
+	return strconv.Itoa(id), nil
+}
+
+// SaveProducts inserts products with their category links and reports outcome of every product.
+// Every product is saved in its own savepoint, so a failed product does not break the others.
+// In atomic mode the first failed product rolls back the whole batch
+func (sp *sqliteProvider) SaveProducts(ctx context.Context, products []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error) {
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return nil, storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	results := make([]models.ImportRowResult, len(products))
+	for idx, product := range products {
+		results[idx].Name = product.Name
+		prodId, err := saveProductSavepoint(ctx, transaction, product, catsIds[idx])
+		if err == nil {
+			results[idx].Status = models.ImportStatusCreated
+			results[idx].ProductId = strconv.Itoa(prodId)
+			continue
+		}
+		// products with sku are matched by sku, the others by name
+		if errors.Is(err, storage.ErrSkuExist) || (errors.Is(err, storage.ErrProductExist) && product.Sku == "") {
+			results[idx].Status = models.ImportStatusSkippedDuplicate
+			results[idx].Reason = err.Error()
+			continue
+		}
+		results[idx].Status = models.ImportStatusFailed
+		results[idx].Reason = err.Error()
+		if atomic {
+			if err := transaction.Rollback(); err != nil {
+				return nil, storage.ErrRollbackTx
+			}
+			for i := range results {
+				if i == idx || results[i].Status == models.ImportStatusSkippedDuplicate {
+					continue
+				}
+				results[i].Name = products[i].Name
+				results[i].Status = models.ImportStatusRolledBack
+				results[i].ProductId = ""
+			}
+			return results, nil
+		}
+	}
+	if err := transaction.Commit(); err != nil {
+		return nil, storage.ErrCommitTx
+	}
+	return results, nil
+}
+
+// saveProductSavepoint inserts one product of the batch inside the savepoint of transaction.
+// Returns ErrSkuExist or ErrProductExist if product with this sku or name already exist
+func saveProductSavepoint(ctx context.Context, transaction querier, product models.Product, catIds []int) (int, error) {
+	if _, err := transaction.ExecContext(ctx, "SAVEPOINT product"); err != nil {
+		return 0, storage.ErrStartTx
+	}
+	prodId, err := insertProduct(ctx, transaction, product, catIds)
+	if err != nil {
+		if _, err := transaction.ExecContext(ctx, "ROLLBACK TO product"); err != nil {
+			return 0, storage.ErrRollbackTx
+		}
+	}
+	if _, err := transaction.ExecContext(ctx, "RELEASE product"); err != nil {
+		return 0, storage.ErrCommitTx
+	}
+	return prodId, err
+}
+
+func insertProduct(ctx context.Context, transaction querier, product models.Product, catIds []int) (int, error) {
+	if product.Sku != "" {
+		taken, err := skuTaken(ctx, transaction, product.Sku)
+		if err != nil {
+			return 0, err
+		}
+		if taken {
+			return 0, storage.ErrSkuExist
+		}
+	}
+	options, err := jsonText(product.Options, len(product.Options) == 0)
+	if err != nil {
+		return 0, storage.ErrQuery
+	}
+	attributes, err := jsonText(product.Attributes, len(product.Attributes) == 0)
+	if err != nil {
+		return 0, storage.ErrQuery
+	}
+	var prodId int
+	err = transaction.QueryRowContext(ctx, `
+INSERT INTO products (name, description, sku, options, attributes)
+VALUES(?,?,?,?,?)
+ON CONFLICT (name) WHERE deleted_at IS NULL DO NOTHING
+RETURNING product_id;`,
+	product.Name,
+	product.Description,
+	nullSku(product.Sku),
+	options,
+	attributes).Scan(&prodId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrProductExist
+		}
+		return 0, storage.ErrQuery
+	}
+	for _, сid := range catIds {
+		_, err = transaction.ExecContext(ctx, `
+INSERT INTO product_category (product_id, category_id)
+VALUES (?, ?)
+ON CONFLICT (product_id, category_id) DO NOTHING;`,
+		prodId,
+		сid)
+		if err != nil {
+			return 0, storage.ErrQuery
+		}
+	}
+	if err := insertPrices(ctx, transaction, prodId, product.Prices); err != nil {
+		return 0, err
+	}
+	if err := insertBarcodes(ctx, transaction, prodId, product.Barcodes); err != nil {
+		return 0, err
+	}
+	if err := productEvent(ctx, transaction, models.EventProductCreated, prodId); err != nil {
+		return 0, err
+	}
+	return prodId, nil
+}
+
+// GetProductById returns ErrQuery if the id is not a number like postgres provider does
+func (sp *sqliteProvider) GetProductById(ctx context.Context, prodId string) (models.Product, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return models.Product{}, storage.ErrQuery
+	}
+	return sp.getProduct(ctx, "p.product_id = ?", id)
+}
+
+func (sp *sqliteProvider) GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
+	return sp.collectProducts(ctx, filter)
+}
+
+// GetProductsByCategory returns products of the category in one of states, in any state if states are empty
+func (sp *sqliteProvider) GetProductsByCategory(ctx context.Context, catCode string, states []string) ([]models.Product, error) {
+	return sp.collectProducts(ctx, models.ProductFilter{CategoryCodes: []string{catCode}, States: states})
+}
+
+func (sp *sqliteProvider) collectProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
+	var outProducts []models.Product
+	err := sp.StreamProducts(ctx, filter, func(product models.Product) error {
+		outProducts = append(outProducts, product)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outProducts, nil
+}
+
+// StreamProducts calls fn for every product matching the filter reading rows one by one
+func (sp *sqliteProvider) StreamProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error {
+	where, having, args := "TRUE", "TRUE", []interface{}{}
+	if filter.MinPrice != nil || filter.MaxPrice != nil || filter.Currency != "" {
+		where = priceFilter
+		args = append(args, priceFilterArgs(filter)...)
+	}
+	attributeWhere, attributeArgs, err := attributeFilter(filter)
+	if err != nil {
+		return err
+	}
+	where += " AND " + attributeWhere
+	args = append(args, attributeArgs...)
+	if filter.InStock != nil {
+		where += " AND " + stockFilter
+		args = append(args, *filter.InStock)
+	}
+	if len(filter.States) != 0 {
+		states, err := json.Marshal(filter.States)
+		if err != nil {
+			return err
+		}
+		where += " AND " + stateFilter
+		args = append(args, string(states))
+	}
+	if len(filter.CategoryCodes) != 0 {
+		codes, err := json.Marshal(filter.CategoryCodes)
+		if err != nil {
+			return err
+		}
+		having = "SUM(c.code IN (SELECT value FROM json_each(?))) > 0"
+		args = append(args, string(codes))
+	}
+	rows, err := sp.conn(ctx).QueryContext(ctx, fmt.Sprintf(selectProducts, where, having), args...)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	defer rows.Close()
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return storage.ErrQuery
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return storage.ErrQuery
+	}
+	return nil
+}
+
+// UpdateProductById changes the fields that are set and counts the version of the product,
+// categories, prices, barcodes, options and attributes are replaced if they are not nil. Returns the changed product
+// and ErrVersionConflict if version is not zero and the product has another one
+func (sp *sqliteProvider) UpdateProductById(ctx context.Context, prodId string, newPorductdata models.ProductForPatch, catIds []int, version int) (models.Product, error) {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return models.Product{}, storage.ErrQuery
+	}
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return models.Product{}, storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	// deleted product is edited only after it is restored,
+	// the transaction holds the write lock, so the version can't change until commit
+	var current int
+	err = transaction.QueryRowContext(ctx, `
+SELECT version FROM products
+WHERE product_id = ? AND deleted_at IS NULL`,
+	id).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Product{}, storage.ErrProductNotFound
+		}
+		return models.Product{}, storage.ErrQuery
+	}
+	if version != 0 && version != current {
+		return models.Product{}, storage.ErrVersionConflict
+	}
+	if _, err := transaction.ExecContext(ctx, "UPDATE products SET version = version + 1 WHERE product_id = ?", id); err != nil {
+		return models.Product{}, storage.ErrQuery
+	}
+	if newPorductdata.Name != nil || newPorductdata.Description != nil || newPorductdata.Sku != nil || newPorductdata.Options != nil ||
+		newPorductdata.Attributes != nil {
+		preparedQuery := "UPDATE products SET "
+		usedData := make([]interface{}, 0)
+		if newPorductdata.Name != nil {
+			preparedQuery += "name = ?, "
+			usedData = append(usedData, *newPorductdata.Name)
+		}
+		if newPorductdata.Description != nil {
+			preparedQuery += "description = ?, "
+			usedData = append(usedData, *newPorductdata.Description)
+		}
+		if newPorductdata.Sku != nil {
+			preparedQuery += "sku = ?, "
+			usedData = append(usedData, nullSku(*newPorductdata.Sku))
+		}
+		if newPorductdata.Options != nil {
+			options, err := jsonText(newPorductdata.Options, len(newPorductdata.Options) == 0)
+			if err != nil {
+				return models.Product{}, storage.ErrQuery
+			}
+			preparedQuery += "options = ?, "
+			usedData = append(usedData, options)
+		}
+		if newPorductdata.Attributes != nil {
+			attributes, err := jsonText(newPorductdata.Attributes, len(newPorductdata.Attributes) == 0)
+			if err != nil {
+				return models.Product{}, storage.ErrQuery
+			}
+			preparedQuery += "attributes = ?, "
+			usedData = append(usedData, attributes)
+		}
+		preparedQuery = preparedQuery[:len(preparedQuery)-2]
+		usedData = append(usedData, id)
+		_, err = transaction.ExecContext(ctx, fmt.Sprintf("%s WHERE product_id = ?", preparedQuery), usedData...)
+		if err != nil {
+			if existErr := productExistErr(err); existErr != nil {
+				return models.Product{}, existErr
+			}
+			return models.Product{}, storage.ErrQuery
+		}
+	}
+	if catIds != nil {
+		for _, cId := range catIds {
+			_, err = transaction.ExecContext(ctx, `
+INSERT INTO product_category (product_id, category_id)
+VALUES (?, ?) ON CONFLICT (product_id, category_id) DO NOTHING;`,
+			id,
+			cId)
+			if err != nil {
+				return models.Product{}, storage.ErrQuery
+			}
+		}
+		ids, err := json.Marshal(catIds)
+		if err != nil {
+			return models.Product{}, err
+		}
+		_, err = transaction.ExecContext(ctx, `
+DELETE FROM product_category
+WHERE product_id = ? AND category_id NOT IN (SELECT value FROM json_each(?));`,
+		id,
+		string(ids))
+		if err != nil {
+			return models.Product{}, storage.ErrQuery
+		}
+	}
+	if newPorductdata.Prices != nil {
+		if err := replacePrices(ctx, transaction, id, newPorductdata.Prices); err != nil {
+			return models.Product{}, err
+		}
+	}
+	if newPorductdata.Barcodes != nil {
+		if err := replaceBarcodes(ctx, transaction, id, newPorductdata.Barcodes); err != nil {
+			return models.Product{}, err
+		}
+	}
+	if err := productEvent(ctx, transaction, models.EventProductUpdated, id); err != nil {
+		return models.Product{}, err
+	}
+	product, err := queryProduct(ctx, transaction, "p.product_id = ?", id)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return models.Product{}, storage.ErrCommitTx
+	}
+	return product, nil
+}
+
+// DeleteProductById moves the product to the trash, its category links are kept for restore.
+// Returns the product as it was before deletion, the zero product if it is already deleted,
+// and ErrVersionConflict if version is not zero and the product has another one
+func (sp *sqliteProvider) DeleteProductById(ctx context.Context, id string, version int) (models.Product, error) {
+	prodId, err := strconv.Atoi(id)
+	if err != nil {
+		return models.Product{}, storage.ErrQuery
+	}
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return models.Product{}, storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	// the transaction holds the write lock, so the product is deleted as it is read
+	product, err := queryProduct(ctx, transaction, "p.product_id = ?", prodId)
+	if err != nil {
+		if errors.Is(err, storage.ErrProductNotFound) {
+			return models.Product{}, nil
+		}
+		return models.Product{}, err
+	}
+	if version != 0 && version != product.Version {
+		return models.Product{}, storage.ErrVersionConflict
+	}
+	_, err = transaction.ExecContext(ctx, "UPDATE products SET deleted_at = ? WHERE product_id = ?", time.Now().UTC(), prodId)
+	if err != nil {
+		return models.Product{}, storage.ErrQuery
+	}
+	if err := productEvent(ctx, transaction, models.EventProductDeleted, prodId); err != nil {
+		return models.Product{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return models.Product{}, storage.ErrCommitTx
+	}
+	return product, nil
+}
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS users (
+    user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email TEXT NOT NULL UNIQUE CHECK (email <> ''),
+    pass_hash TEXT NOT NULL CHECK (pass_hash <> ''),
+    refresh_hash TEXT,
+    expires_at INTEGER
+);
+CREATE TABLE IF NOT EXISTS categories (
+    category_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL CHECK (name <> ''),
+    code TEXT NOT NULL UNIQUE CHECK (code <> ''),
+    description TEXT
+);
+CREATE TABLE IF NOT EXISTS products (
+    product_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE CHECK (name <> ''),
+    description TEXT NOT NULL CHECK (description <> '')
+);
+CREATE TABLE IF NOT EXISTS product_category (
+    product_id INTEGER NOT NULL REFERENCES products ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories ON UPDATE CASCADE,
+    PRIMARY KEY (product_id, category_id)
+);
+CREATE TABLE IF NOT EXISTS jobs (
+    job_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    state TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    payload_path TEXT NOT NULL,
+    atomic BOOLEAN NOT NULL DEFAULT FALSE,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    processed INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    invalid INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    report TEXT,
+    created_by TEXT,
+    created_at TIMESTAMP NOT NULL,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS jobs_state_idx ON jobs (state);
@@ -0,0 +1,28 @@
+-- unique constraints of sqlite can't be dropped, so tables are rebuilt with unique indexes
+-- on not deleted rows. Foreign keys are off while the schema is changed
+CREATE TABLE categories_new (
+    category_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL CHECK (name <> ''),
+    code TEXT NOT NULL CHECK (code <> ''),
+    description TEXT,
+    deleted_at TIMESTAMP
+);
+INSERT INTO categories_new (category_id, name, code, description)
+SELECT category_id, name, code, description FROM categories;
+DROP TABLE categories;
+ALTER TABLE categories_new RENAME TO categories;
+CREATE UNIQUE INDEX categories_code_idx ON categories (code) WHERE deleted_at IS NULL;
+CREATE INDEX categories_deleted_at_idx ON categories (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE products_new (
+    product_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL CHECK (name <> ''),
+    description TEXT NOT NULL CHECK (description <> ''),
+    deleted_at TIMESTAMP
+);
+INSERT INTO products_new (product_id, name, description)
+SELECT product_id, name, description FROM products;
+DROP TABLE products;
+ALTER TABLE products_new RENAME TO products;
+CREATE UNIQUE INDEX products_name_idx ON products (name) WHERE deleted_at IS NULL;
+CREATE INDEX products_deleted_at_idx ON products (deleted_at) WHERE deleted_at IS NOT NULL;
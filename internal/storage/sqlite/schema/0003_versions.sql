@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS versions (
+    entity TEXT NOT NULL,
+    entity_key TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    actor TEXT,
+    reverted_to INTEGER,
+    snapshot TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (entity, entity_key, version)
+);
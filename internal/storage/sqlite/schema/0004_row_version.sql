@@ -0,0 +1,3 @@
+-- version is compared by edits with If-Match and counted by every update
+ALTER TABLE categories ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE products ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    aggregate TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
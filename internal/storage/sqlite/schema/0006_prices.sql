@@ -0,0 +1,11 @@
+-- bounds of validity are UTC times formatted with priceTimeLayout, so they are compared as strings
+CREATE TABLE IF NOT EXISTS product_prices (
+    product_id INTEGER NOT NULL REFERENCES products ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('list', 'sale')),
+    amount INTEGER NOT NULL CHECK (amount >= 0),
+    currency TEXT NOT NULL CHECK (currency GLOB '[A-Z][A-Z][A-Z]'),
+    valid_from TEXT,
+    valid_to TEXT,
+    CHECK (valid_from < valid_to)
+);
+CREATE INDEX IF NOT EXISTS product_prices_product_idx ON product_prices (product_id);
@@ -0,0 +1,9 @@
+ALTER TABLE products ADD COLUMN sku TEXT CHECK (sku <> '');
+-- skus are unique only among not deleted products like names
+CREATE UNIQUE INDEX IF NOT EXISTS products_sku_idx ON products (sku) WHERE deleted_at IS NULL;
+-- barcodes of deleted products stay taken until they are purged
+CREATE TABLE IF NOT EXISTS product_barcodes (
+    code TEXT PRIMARY KEY CHECK (length(code) IN (8, 12, 13, 14) AND code NOT GLOB '*[^0-9]*'),
+    product_id INTEGER NOT NULL REFERENCES products ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS product_barcodes_product_idx ON product_barcodes (product_id);
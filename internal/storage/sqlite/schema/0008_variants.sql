@@ -0,0 +1,10 @@
+ALTER TABLE products ADD COLUMN options TEXT CHECK (json_type(options) = 'array');
+-- options of a variant are a json object with sorted keys, so equal sets of options are equal strings
+CREATE TABLE IF NOT EXISTS product_variants (
+    variant_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL REFERENCES products ON DELETE CASCADE,
+    sku TEXT NOT NULL UNIQUE CHECK (sku <> ''),
+    options TEXT NOT NULL CHECK (json_type(options) = 'object'),
+    prices TEXT CHECK (json_type(prices) = 'array'),
+    UNIQUE (product_id, options)
+);
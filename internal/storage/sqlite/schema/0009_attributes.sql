@@ -0,0 +1,2 @@
+ALTER TABLE categories ADD COLUMN attributes TEXT CHECK (json_type(attributes) = 'array');
+ALTER TABLE products ADD COLUMN attributes TEXT CHECK (json_type(attributes) = 'object');
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    warehouse_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT NOT NULL UNIQUE CHECK (code <> ''),
+    name TEXT NOT NULL CHECK (name <> '')
+);
+-- writers are serialized by the database lock, the check keeps reserved units within on hand ones
+CREATE TABLE IF NOT EXISTS stock (
+    product_id INTEGER NOT NULL REFERENCES products ON DELETE CASCADE,
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses,
+    on_hand INTEGER NOT NULL DEFAULT 0,
+    reserved INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (product_id, warehouse_id),
+    CHECK (reserved >= 0 AND on_hand >= reserved)
+);
+CREATE TABLE IF NOT EXISTS reservations (
+    reservation_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL,
+    warehouse_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    state TEXT NOT NULL CHECK (state IN ('active', 'released', 'committed')),
+    created_at TIMESTAMP NOT NULL,
+    closed_at TIMESTAMP,
+    FOREIGN KEY (product_id, warehouse_id) REFERENCES stock ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS stock_movements (
+    movement_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL,
+    warehouse_id INTEGER NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('adjust', 'reserve', 'release', 'commit')),
+    on_hand INTEGER NOT NULL,
+    reserved INTEGER NOT NULL,
+    reservation_id INTEGER,
+    reason TEXT,
+    actor TEXT,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (product_id, warehouse_id) REFERENCES stock ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS stock_movements_product_idx ON stock_movements (product_id, movement_id);
@@ -0,0 +1,18 @@
+-- created_at is RFC 3339 text, so it is read as it is by json of the product
+CREATE TABLE IF NOT EXISTS product_media (
+    media_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL REFERENCES products ON DELETE CASCADE,
+    file_key TEXT NOT NULL UNIQUE,
+    thumbnail_key TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    thumbnail_url TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL CHECK (size > 0),
+    width INTEGER NOT NULL CHECK (width > 0),
+    height INTEGER NOT NULL CHECK (height > 0),
+    position INTEGER NOT NULL CHECK (position >= 0),
+    is_primary INTEGER NOT NULL DEFAULT 0,
+    created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS product_media_product_idx ON product_media (product_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS product_media_primary_idx ON product_media (product_id) WHERE is_primary;
@@ -0,0 +1,4 @@
+-- products saved before the workflow stay published, new ones start as drafts
+ALTER TABLE products ADD COLUMN state TEXT NOT NULL DEFAULT 'draft' CHECK (state IN ('draft', 'in_review', 'published', 'archived'));
+UPDATE products SET state = 'published';
+CREATE INDEX IF NOT EXISTS products_state_idx ON products (state) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- events failed too many times are dead and are not relayed anymore
+ALTER TABLE outbox ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE outbox ADD COLUMN dead_at TIMESTAMP;
@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const defaultBusyTimeout = 5 * time.Second
+
+// schema files are applied in order of their numbers, the number of the last applied
+// file is kept in user_version of the database
+//
+//go:embed schema/*.sql
+var schemaFiles embed.FS
+
+// sqliteProvider keeps the catalog in a single database file. Tables are the same
+// as postgres ones, category codes are aggregated with json_group_array instead of array_agg
+type sqliteProvider struct {
+	db *sql.DB
+	// relayMu lets one relay read and remove outbox events at a time
+	relayMu sync.Mutex
+}
+
+func NewSqliteProvider(ctx context.Context, cfg config.SqliteConfig) (*sqliteProvider, error) {
+	busyTimeout := cfg.BusyTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = defaultBusyTimeout
+	}
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	// transactions take the write lock at once, so they wait for each other
+	// instead of failing when a read transaction is upgraded
+	params.Add("_txlock", "immediate")
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?%s", cfg.Path, params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+	return &sqliteProvider{
+		db: db,
+	}, nil
+}
+
+// migrate applies schema files newer than user_version, each in its own transaction.
+// Foreign keys are off while tables are rebuilt, otherwise dropping them removes linked rows
+func migrate(ctx context.Context, db *sql.DB) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var version int
+	if err := conn.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	entries, err := schemaFiles.ReadDir("schema")
+	if err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	for _, entry := range entries {
+		fileVersion, err := strconv.Atoi(strings.SplitN(entry.Name(), "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("wrong schema file name %s", entry.Name())
+		}
+		if fileVersion <= version {
+			continue
+		}
+		data, err := schemaFiles.ReadFile(path.Join("schema", entry.Name()))
+		if err != nil {
+			return err
+		}
+		transaction, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := transaction.ExecContext(ctx, string(data)); err != nil {
+			transaction.Rollback()
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if _, err := transaction.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", fileVersion)); err != nil {
+			transaction.Rollback()
+			return err
+		}
+		if err := transaction.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sp *sqliteProvider) Close() {
+	sp.db.Close()
+}
+
+func (sp *sqliteProvider) PoolStats() models.PoolStats {
+	stat := sp.db.Stats()
+	return models.PoolStats{
+		EmptyAcquireCount: stat.WaitCount,
+		AcquiredConns: int32(stat.InUse),
+		IdleConns: int32(stat.Idle),
+		MaxConns: int32(stat.MaxOpenConnections),
+		TotalConns: int32(stat.OpenConnections),
+	}
+}
+
+// isConstraint reports whether err is a violation of the constraint with the extended code:
+// SQLITE_CONSTRAINT_UNIQUE, SQLITE_CONSTRAINT_FOREIGNKEY and so on
+func isConstraint(err error, code int) bool {
+	var sqliteErr *sqlitedriver.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == code
+}
+
+func isUnique(err error) bool {
+	return isConstraint(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE) || isConstraint(err, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+func isForeignKey(err error) bool {
+	return isConstraint(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY)
+}
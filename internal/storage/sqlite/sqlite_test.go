@@ -0,0 +1,23 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/storage/sqlite"
+	"github.com/EwvwGeN/cataloger/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqliteBehaviorSuiteRun(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Repository {
+		provider, err := sqlite.NewSqliteProvider(context.Background(), config.SqliteConfig{
+			Path: filepath.Join(t.TempDir(), "cataloger.db"),
+		})
+		require.NoError(t, err)
+		t.Cleanup(provider.Close)
+		return provider
+	})
+}
@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// stateFilter matches products in one of the states given as json array
+const stateFilter = `p.state IN (SELECT value FROM json_each(?))`
+
+// SetProductState moves the product from the state to the other one and returns the changed product,
+// ErrVersionConflict is returned if version is not zero and the product has another one
+// and ErrProductState if the product is in another state
+func (sp *sqliteProvider) SetProductState(ctx context.Context, prodId, from, to string, version int) (models.Product, error) {
+	var product models.Product
+	err := sp.changeProduct(ctx, prodId, func(transaction querier, prodId int) error {
+		// changeProduct has already counted the version
+		var (
+			state   string
+			current int
+		)
+		err := transaction.QueryRowContext(ctx, "SELECT state, version FROM products WHERE product_id = ?", prodId).Scan(&state, &current)
+		if err != nil {
+			return storage.ErrQuery
+		}
+		if version != 0 && version != current-1 {
+			return storage.ErrVersionConflict
+		}
+		if state != from {
+			return storage.ErrProductState
+		}
+		if _, err := transaction.ExecContext(ctx, "UPDATE products SET state = ? WHERE product_id = ?", to, prodId); err != nil {
+			return storage.ErrQuery
+		}
+		product, err = queryProduct(ctx, transaction, "p.product_id = ?", prodId)
+		return err
+	})
+	return product, err
+}
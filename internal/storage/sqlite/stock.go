@@ -0,0 +1,347 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// stockFilter matches products with available units in any warehouse if its argument is true
+// and products without them if it is false
+const stockFilter = `EXISTS (
+	SELECT 1 FROM stock as s
+	WHERE s.product_id = p.product_id AND s.on_hand > s.reserved
+) = ?`
+
+const reservationColumns = `r.reservation_id, r.product_id, w.code, r.quantity, r.state, r.created_at, r.closed_at`
+
+func (sp *sqliteProvider) SaveWarehouse(ctx context.Context, warehouse models.Warehouse) error {
+	_, err := sp.conn(ctx).ExecContext(ctx, "INSERT INTO warehouses (code, name) VALUES (?, ?)", warehouse.Code, warehouse.Name)
+	if err != nil {
+		if isUnique(err) {
+			return storage.ErrWarehouseExist
+		}
+		return storage.ErrQuery
+	}
+	return nil
+}
+
+// GetWarehouses returns all warehouses ordered by code
+func (sp *sqliteProvider) GetWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	rows, err := sp.conn(ctx).QueryContext(ctx, "SELECT code, name FROM warehouses ORDER BY code")
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	defer rows.Close()
+	var warehouses []models.Warehouse
+	for rows.Next() {
+		var warehouse models.Warehouse
+		if err := rows.Scan(&warehouse.Code, &warehouse.Name); err != nil {
+			return nil, storage.ErrQuery
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storage.ErrQuery
+	}
+	return warehouses, nil
+}
+
+// GetStock returns the stock of the product in every warehouse it was ever kept in ordered by warehouse code
+func (sp *sqliteProvider) GetStock(ctx context.Context, prodId string) ([]models.StockLevel, error) {
+	rows, err := sp.conn(ctx).QueryContext(ctx, `
+SELECT w.code, s.on_hand, s.reserved
+FROM stock as s
+JOIN warehouses as w ON w.warehouse_id = s.warehouse_id
+WHERE s.product_id = ?
+ORDER BY w.code`,
+	prodId)
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	defer rows.Close()
+	var levels []models.StockLevel
+	for rows.Next() {
+		var level models.StockLevel
+		if err := rows.Scan(&level.Warehouse, &level.OnHand, &level.Reserved); err != nil {
+			return nil, storage.ErrQuery
+		}
+		level.Available = level.OnHand - level.Reserved
+		levels = append(levels, level)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storage.ErrQuery
+	}
+	return levels, nil
+}
+
+// AdjustStock adds the signed quantity of the change to units of the not deleted product on hand in the warehouse.
+// ErrOutOfStock is returned if less units than reserved would be left on hand
+func (sp *sqliteProvider) AdjustStock(ctx context.Context, prodId string, change models.StockChange) (models.StockLevel, error) {
+	level := models.StockLevel{Warehouse: change.Warehouse}
+	err := sp.changeStock(ctx, prodId, change.Warehouse, func(transaction querier, prodId, warehouseId int) error {
+		err := transaction.QueryRowContext(ctx, `
+INSERT INTO stock (product_id, warehouse_id, on_hand)
+VALUES (?, ?, ?)
+ON CONFLICT (product_id, warehouse_id) DO UPDATE SET on_hand = on_hand + excluded.on_hand
+RETURNING on_hand, reserved`,
+		prodId,
+		warehouseId,
+		change.Quantity).Scan(&level.OnHand, &level.Reserved)
+		if err != nil {
+			return stockErr(err)
+		}
+		return saveMovement(ctx, transaction, prodId, warehouseId, models.StockMovement{
+			Kind: models.MovementAdjust,
+			OnHand: change.Quantity,
+			Reason: change.Reason,
+			Actor: change.Actor,
+		})
+	})
+	if err != nil {
+		return models.StockLevel{}, err
+	}
+	level.Available = level.OnHand - level.Reserved
+	return level, nil
+}
+
+// ReserveStock reserves the quantity of the change from available units of the not deleted product in the warehouse,
+// ErrOutOfStock is returned if there are less available units
+func (sp *sqliteProvider) ReserveStock(ctx context.Context, prodId string, change models.StockChange) (models.Reservation, error) {
+	reservation := models.Reservation{
+		Warehouse: change.Warehouse,
+		Quantity: change.Quantity,
+		State: models.ReservationActive,
+		CreatedAt: time.Now().UTC(),
+	}
+	err := sp.changeStock(ctx, prodId, change.Warehouse, func(transaction querier, prodId, warehouseId int) error {
+		res, err := transaction.ExecContext(ctx, `
+UPDATE stock SET reserved = reserved + ?3
+WHERE product_id = ?1 AND warehouse_id = ?2 AND on_hand - reserved >= ?3`,
+		prodId,
+		warehouseId,
+		change.Quantity)
+		if err != nil {
+			return stockErr(err)
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return storage.ErrQuery
+		}
+		if count == 0 {
+			return storage.ErrOutOfStock
+		}
+		err = transaction.QueryRowContext(ctx, `
+INSERT INTO reservations (product_id, warehouse_id, quantity, state, created_at)
+VALUES (?, ?, ?, ?, ?)
+RETURNING reservation_id`,
+		prodId,
+		warehouseId,
+		change.Quantity,
+		models.ReservationActive,
+		reservation.CreatedAt).Scan(&reservation.Id)
+		if err != nil {
+			return storage.ErrQuery
+		}
+		reservation.ProductId = prodId
+		return saveMovement(ctx, transaction, prodId, warehouseId, models.StockMovement{
+			Kind: models.MovementReserve,
+			Reserved: change.Quantity,
+			ReservationId: reservation.Id,
+			Reason: change.Reason,
+			Actor: change.Actor,
+		})
+	})
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	return reservation, nil
+}
+
+func (sp *sqliteProvider) GetReservation(ctx context.Context, reservationId string) (models.Reservation, error) {
+	reservation, _, err := getReservation(ctx, sp.conn(ctx), reservationId)
+	return reservation, err
+}
+
+// ReleaseReservation returns units of the active reservation to available ones
+func (sp *sqliteProvider) ReleaseReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error) {
+	return sp.closeReservation(ctx, reservationId, models.ReservationReleased, change)
+}
+
+// CommitReservation takes units of the active reservation out of the warehouse
+func (sp *sqliteProvider) CommitReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error) {
+	return sp.closeReservation(ctx, reservationId, models.ReservationCommitted, change)
+}
+
+// closeReservation moves the active reservation to the state, ErrReservationClosed is returned if it is not active
+func (sp *sqliteProvider) closeReservation(ctx context.Context, reservationId string, state string, change models.StockChange) (models.Reservation, error) {
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return models.Reservation{}, storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	reservation, warehouseId, err := getReservation(ctx, transaction, reservationId)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	if reservation.State != models.ReservationActive {
+		return models.Reservation{}, storage.ErrReservationClosed
+	}
+	closedAt := time.Now().UTC()
+	_, err = transaction.ExecContext(ctx, "UPDATE reservations SET state = ?, closed_at = ? WHERE reservation_id = ?", state, closedAt, reservation.Id)
+	if err != nil {
+		return models.Reservation{}, storage.ErrQuery
+	}
+	reservation.State, reservation.ClosedAt = state, &closedAt
+	movement := models.StockMovement{
+		Kind: models.MovementRelease,
+		Reserved: -reservation.Quantity,
+		ReservationId: reservation.Id,
+		Reason: change.Reason,
+		Actor: change.Actor,
+	}
+	if state == models.ReservationCommitted {
+		movement.Kind = models.MovementCommit
+		movement.OnHand = -reservation.Quantity
+	}
+	_, err = transaction.ExecContext(ctx, `
+UPDATE stock SET on_hand = on_hand + ?, reserved = reserved + ?
+WHERE product_id = ? AND warehouse_id = ?`,
+	movement.OnHand,
+	movement.Reserved,
+	reservation.ProductId,
+	warehouseId)
+	if err != nil {
+		return models.Reservation{}, stockErr(err)
+	}
+	if err := saveMovement(ctx, transaction, reservation.ProductId, warehouseId, movement); err != nil {
+		return models.Reservation{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return models.Reservation{}, storage.ErrCommitTx
+	}
+	return reservation, nil
+}
+
+// getReservation returns the reservation with the id of its warehouse
+func getReservation(ctx context.Context, db querier, reservationId string) (models.Reservation, int, error) {
+	var (
+		reservation models.Reservation
+		warehouseId int
+	)
+	err := db.QueryRowContext(ctx, `
+SELECT `+reservationColumns+`, r.warehouse_id
+FROM reservations as r
+JOIN warehouses as w ON w.warehouse_id = r.warehouse_id
+WHERE r.reservation_id = ?`,
+	reservationId).Scan(&reservation.Id, &reservation.ProductId, &reservation.Warehouse, &reservation.Quantity,
+		&reservation.State, &reservation.CreatedAt, &reservation.ClosedAt, &warehouseId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Reservation{}, 0, storage.ErrReservationNotFound
+		}
+		return models.Reservation{}, 0, storage.ErrQuery
+	}
+	return reservation, warehouseId, nil
+}
+
+// GetStockMovements returns the ledger of the stock of the product in the order of the changes
+func (sp *sqliteProvider) GetStockMovements(ctx context.Context, prodId string) ([]models.StockMovement, error) {
+	rows, err := sp.conn(ctx).QueryContext(ctx, `
+SELECT m.movement_id, w.code, m.kind, m.on_hand, m.reserved, COALESCE(m.reservation_id, 0),
+COALESCE(m.reason, ''), COALESCE(m.actor, ''), m.created_at
+FROM stock_movements as m
+JOIN warehouses as w ON w.warehouse_id = m.warehouse_id
+WHERE m.product_id = ?
+ORDER BY m.movement_id`,
+	prodId)
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	defer rows.Close()
+	var movements []models.StockMovement
+	for rows.Next() {
+		var movement models.StockMovement
+		err := rows.Scan(&movement.Id, &movement.Warehouse, &movement.Kind, &movement.OnHand, &movement.Reserved,
+			&movement.ReservationId, &movement.Reason, &movement.Actor, &movement.CreatedAt)
+		if err != nil {
+			return nil, storage.ErrQuery
+		}
+		movements = append(movements, movement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storage.ErrQuery
+	}
+	return movements, nil
+}
+
+// changeStock runs fn in the transaction with the id of the warehouse by its code
+// if the product is not deleted
+func (sp *sqliteProvider) changeStock(ctx context.Context, prodId, warehouseCode string, fn func(transaction querier, prodId, warehouseId int) error) error {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	var exist bool
+	err = transaction.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM products WHERE product_id = ? AND deleted_at IS NULL)", id).Scan(&exist)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	if !exist {
+		return storage.ErrProductNotFound
+	}
+	var warehouseId int
+	err = transaction.QueryRowContext(ctx, "SELECT warehouse_id FROM warehouses WHERE code = ?", warehouseCode).Scan(&warehouseId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrWarehouseNotFound
+		}
+		return storage.ErrQuery
+	}
+	if err := fn(transaction, id, warehouseId); err != nil {
+		return err
+	}
+	if err := transaction.Commit(); err != nil {
+		return storage.ErrCommitTx
+	}
+	return nil
+}
+
+// saveMovement writes the movement to the ledger in the transaction of the change
+func saveMovement(ctx context.Context, transaction querier, prodId, warehouseId int, movement models.StockMovement) error {
+	_, err := transaction.ExecContext(ctx, `
+INSERT INTO stock_movements (product_id, warehouse_id, kind, on_hand, reserved, reservation_id, reason, actor, created_at)
+VALUES (?, ?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''), ?)`,
+	prodId,
+	warehouseId,
+	movement.Kind,
+	movement.OnHand,
+	movement.Reserved,
+	movement.ReservationId,
+	movement.Reason,
+	movement.Actor,
+	time.Now().UTC())
+	if err != nil {
+		return storage.ErrQuery
+	}
+	return nil
+}
+
+// stockErr returns ErrOutOfStock if the change broke the check of the stock row
+func stockErr(err error) error {
+	if isConstraint(err, sqlite3.SQLITE_CONSTRAINT_CHECK) {
+		return storage.ErrOutOfStock
+	}
+	return storage.ErrQuery
+}
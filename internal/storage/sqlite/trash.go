@@ -0,0 +1,200 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// GetTrash returns deleted products with codes of all their categories and deleted categories,
+// the last deleted go first
+func (sp *sqliteProvider) GetTrash(ctx context.Context) (models.Trash, error) {
+	rows, err := sp.conn(ctx).QueryContext(ctx, `
+SELECT p.product_id, p.name, p.description,
+CASE
+	WHEN COUNT(pc.category_id) = 0 THEN NULL
+	ELSE json_group_array(c.code)
+END as category_codes,
+p.deleted_at
+FROM products as p
+LEFT JOIN product_category as pc ON pc.product_id = p.product_id
+LEFT JOIN categories as c ON c.category_id = pc.category_id
+WHERE p.deleted_at IS NOT NULL
+GROUP BY p.product_id
+ORDER BY p.deleted_at DESC, p.product_id DESC`)
+	if err != nil {
+		return models.Trash{}, storage.ErrQuery
+	}
+	defer rows.Close()
+	trash := models.Trash{
+		Products: []models.TrashProduct{},
+		Categories: []models.TrashCategory{},
+	}
+	for rows.Next() {
+		var (
+			product models.TrashProduct
+			codes sql.NullString
+		)
+		if err := rows.Scan(&product.Id, &product.Name, &product.Description, &codes, &product.DeletedAt); err != nil {
+			return models.Trash{}, storage.ErrQuery
+		}
+		if codes.Valid {
+			if err := json.Unmarshal([]byte(codes.String), &product.CategoryСodes); err != nil {
+				return models.Trash{}, storage.ErrQuery
+			}
+		}
+		trash.Products = append(trash.Products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Trash{}, storage.ErrQuery
+	}
+	rows, err = sp.conn(ctx).QueryContext(ctx, `
+SELECT name, code, description, deleted_at
+FROM categories
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, category_id DESC`)
+	if err != nil {
+		return models.Trash{}, storage.ErrQuery
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var category models.TrashCategory
+		if err := rows.Scan(&category.Name, &category.Code, &category.Description, &category.DeletedAt); err != nil {
+			return models.Trash{}, storage.ErrQuery
+		}
+		trash.Categories = append(trash.Categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Trash{}, storage.ErrQuery
+	}
+	return trash, nil
+}
+
+// RestoreProductById takes the product out of the trash with its category links.
+// Returns ErrProductExist or ErrSkuExist if its name or sku is taken by another product
+func (sp *sqliteProvider) RestoreProductById(ctx context.Context, prodId string) error {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return storage.ErrProductNotFound
+	}
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	res, err := transaction.ExecContext(ctx, `
+UPDATE products SET deleted_at = NULL
+WHERE product_id = ? AND deleted_at IS NOT NULL`,
+	id)
+	if err != nil {
+		if existErr := productExistErr(err); existErr != nil {
+			return existErr
+		}
+		return storage.ErrQuery
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return storage.ErrQuery
+	}
+	if count == 0 {
+		return storage.ErrProductNotFound
+	}
+	if err := productEvent(ctx, transaction, models.EventProductRestored, id); err != nil {
+		return err
+	}
+	if err := transaction.Commit(); err != nil {
+		return storage.ErrCommitTx
+	}
+	return nil
+}
+
+// RestoreCategoryByCode takes the last deleted category with the code out of the trash.
+// Returns ErrCategoryExist if the code is taken by another category
+func (sp *sqliteProvider) RestoreCategoryByCode(ctx context.Context, catCode string) error {
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	var id int
+	err = transaction.QueryRowContext(ctx, `
+SELECT category_id FROM categories
+WHERE code = ? AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, category_id DESC
+LIMIT 1`,
+	catCode).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrCategoryNotFound
+		}
+		return storage.ErrQuery
+	}
+	_, err = transaction.ExecContext(ctx, "UPDATE categories SET deleted_at = NULL WHERE category_id = ?", id)
+	if err != nil {
+		if isUnique(err) {
+			return storage.ErrCategoryExist
+		}
+		return storage.ErrQuery
+	}
+	if err := categoryEvent(ctx, transaction, models.EventCategoryRestored, id); err != nil {
+		return err
+	}
+	if err := transaction.Commit(); err != nil {
+		return storage.ErrCommitTx
+	}
+	return nil
+}
+
+// PurgeTrash removes products and categories deleted before the time for good.
+// Links of restored products to purged categories are removed too
+func (sp *sqliteProvider) PurgeTrash(ctx context.Context, before time.Time) (models.PurgeResult, error) {
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return models.PurgeResult{}, storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	before = before.UTC()
+	var result models.PurgeResult
+	queries := []struct{
+		sql string
+		count *int
+	}{
+		{
+			sql: `DELETE FROM products WHERE deleted_at < ?`,
+			count: &result.Products,
+		},
+		{
+			sql: `
+DELETE FROM product_category
+WHERE category_id IN (SELECT category_id FROM categories WHERE deleted_at < ?)`,
+		},
+		{
+			sql: `DELETE FROM categories WHERE deleted_at < ?`,
+			count: &result.Categories,
+		},
+	}
+	for _, query := range queries {
+		res, err := transaction.ExecContext(ctx, query.sql, before)
+		if err != nil {
+			return models.PurgeResult{}, storage.ErrQuery
+		}
+		if query.count == nil {
+			continue
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return models.PurgeResult{}, storage.ErrQuery
+		}
+		*query.count = int(count)
+	}
+	if err := transaction.Commit(); err != nil {
+		return models.PurgeResult{}, storage.ErrCommitTx
+	}
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+type txKey struct{}
+
+// ctxTx is the transaction of WithinTx, sp is the provider the transaction belongs to
+type ctxTx struct {
+	sp *sqliteProvider
+	tx *sql.Tx
+}
+
+// querier runs statements on the database or on the transaction of the context
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// dbTx is a transaction or a savepoint inside the transaction of WithinTx
+type dbTx interface {
+	querier
+	Commit() error
+	Rollback() error
+}
+
+// savepointSeq makes names of nested savepoints unique
+var savepointSeq atomic.Int64
+
+// savepoint is released by Commit and rolled back by Rollback, calls after the first one do nothing
+// like Rollback of sql.Tx after Commit
+type savepoint struct {
+	*sql.Tx
+	ctx context.Context
+	name string
+	done bool
+}
+
+func newSavepoint(ctx context.Context, tx *sql.Tx) (*savepoint, error) {
+	name := fmt.Sprintf("sp_%d", savepointSeq.Add(1))
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+	return &savepoint{
+		Tx: tx,
+		ctx: ctx,
+		name: name,
+	}, nil
+}
+
+func (s *savepoint) Commit() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	_, err := s.Tx.ExecContext(s.ctx, "RELEASE "+s.name)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	if _, err := s.Tx.ExecContext(s.ctx, "ROLLBACK TO "+s.name); err != nil {
+		return err
+	}
+	_, err := s.Tx.ExecContext(s.ctx, "RELEASE "+s.name)
+	return err
+}
+
+// WithinTx runs fn in one transaction carried by the context passed to fn. Every call of the provider
+// made with this context joins the transaction, transactions of the calls become savepoints.
+// The transaction is committed if fn returns nil and rolled back if it returns an error or panics.
+// Nested WithinTx joins the outer transaction
+func (sp *sqliteProvider) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if sp.txFrom(ctx) != nil {
+		return fn(ctx)
+	}
+	transaction, err := sp.db.BeginTx(ctx, nil)
+	if err != nil {
+		return storage.ErrStartTx
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			transaction.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(context.WithValue(ctx, txKey{}, &ctxTx{sp: sp, tx: transaction})); err != nil {
+		if err := transaction.Rollback(); err != nil {
+			return storage.ErrRollbackTx
+		}
+		return err
+	}
+	if err := transaction.Commit(); err != nil {
+		return storage.ErrCommitTx
+	}
+	return nil
+}
+
+// txFrom returns the transaction of WithinTx if ctx carries one of the provider
+func (sp *sqliteProvider) txFrom(ctx context.Context) *sql.Tx {
+	if current, ok := ctx.Value(txKey{}).(*ctxTx); ok && current.sp == sp {
+		return current.tx
+	}
+	return nil
+}
+
+// conn returns the transaction of ctx or the database if there is no one
+func (sp *sqliteProvider) conn(ctx context.Context) querier {
+	if tx := sp.txFrom(ctx); tx != nil {
+		return tx
+	}
+	return sp.db
+}
+
+// begin starts a transaction or a savepoint inside the transaction of ctx
+func (sp *sqliteProvider) begin(ctx context.Context) (dbTx, error) {
+	if tx := sp.txFrom(ctx); tx != nil {
+		return newSavepoint(ctx, tx)
+	}
+	return sp.db.BeginTx(ctx, nil)
+}
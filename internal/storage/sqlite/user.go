@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+func (sp *sqliteProvider) SaveUser(ctx context.Context, email string, passHash string) error {
+	_, err := sp.conn(ctx).ExecContext(ctx, `INSERT INTO users (email, pass_hash) VALUES(?,?);`, email, passHash)
+	if err == nil {
+		return nil
+	}
+	if isUnique(err) {
+		return storage.ErrUserExist
+	}
+	return storage.ErrQuery
+}
+
+func (sp *sqliteProvider) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	row := sp.conn(ctx).QueryRowContext(ctx, `
+SELECT email, pass_hash, refresh_hash, expires_at
+FROM users
+WHERE email = ?;`,
+	email)
+	var (
+		user models.User
+		refHash sql.NullString
+		expiresAt sql.NullInt64
+	)
+	err := row.Scan(&user.Email, &user.PassHash, &refHash, &expiresAt)
+	if err != nil {
+		return models.User{}, storage.ErrQuery
+	}
+	user.RefreshHash = refHash.String
+	user.ExpiresAt = expiresAt.Int64
+	return user, nil
+}
+
+func (sp *sqliteProvider) SaveRefreshToken(ctx context.Context, email string, refreshToken string, rttl int64) error {
+	_, err := sp.conn(ctx).ExecContext(ctx, `
+UPDATE users SET
+refresh_hash = ?, expires_at = ?
+WHERE email = ?`,
+	refreshToken,
+	rttl,
+	email)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	return nil
+}
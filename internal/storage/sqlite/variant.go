@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+// productVariants selects options of the product p and its variants as json array,
+// both are NULL if the product has none
+const productVariants = `p.options, (
+	SELECT CASE
+		WHEN COUNT(*) = 0 THEN NULL
+		ELSE json_group_array(json_object(
+			'id', v.variant_id, 'sku', v.sku, 'options', json(v.options), 'prices', json(v.prices)
+		))
+	END
+	FROM (
+		SELECT * FROM product_variants
+		WHERE product_id = p.product_id
+		ORDER BY variant_id
+	) as v
+) as variants`
+
+// jsonText returns value as json text, empty value is saved as NULL.
+// Keys of maps are sorted by json.Marshal, so equal options are equal texts
+func jsonText(value interface{}, empty bool) (interface{}, error) {
+	if empty {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// variantExistErr tells which unique column of the variant is taken by the error,
+// it is nil if the error is not a unique violation
+func variantExistErr(err error) error {
+	if !isUnique(err) {
+		return nil
+	}
+	if strings.Contains(err.Error(), "product_variants.sku") {
+		return storage.ErrVariantSkuExist
+	}
+	return storage.ErrVariantExist
+}
+
+// SaveVariant adds the variant to the not deleted product and counts the version of the product
+func (sp *sqliteProvider) SaveVariant(ctx context.Context, prodId string, variant models.Variant) (string, error) {
+	options, prices, err := variantColumns(variant)
+	if err != nil {
+		return "", storage.ErrQuery
+	}
+	var id int
+	err = sp.changeProduct(ctx, prodId, func(transaction querier, prodId int) error {
+		err := transaction.QueryRowContext(ctx, `
+INSERT INTO product_variants (product_id, sku, options, prices)
+VALUES (?, ?, ?, ?)
+RETURNING variant_id`,
+		prodId,
+		variant.Sku,
+		options,
+		prices).Scan(&id)
+		if err != nil {
+			if existErr := variantExistErr(err); existErr != nil {
+				return existErr
+			}
+			return storage.ErrQuery
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(id), nil
+}
+
+// UpdateVariantById replaces sku, options and prices of the variant of the not deleted product
+// and counts the version of the product
+func (sp *sqliteProvider) UpdateVariantById(ctx context.Context, prodId, variantId string, variant models.Variant) error {
+	id, err := strconv.Atoi(variantId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	options, prices, err := variantColumns(variant)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	return sp.changeProduct(ctx, prodId, func(transaction querier, prodId int) error {
+		res, err := transaction.ExecContext(ctx, `
+UPDATE product_variants SET sku = ?, options = ?, prices = ?
+WHERE variant_id = ? AND product_id = ?`,
+		variant.Sku,
+		options,
+		prices,
+		id,
+		prodId)
+		if err != nil {
+			if existErr := variantExistErr(err); existErr != nil {
+				return existErr
+			}
+			return storage.ErrQuery
+		}
+		return variantChanged(res)
+	})
+}
+
+// DeleteVariantById removes the variant of the not deleted product and counts the version of the product
+func (sp *sqliteProvider) DeleteVariantById(ctx context.Context, prodId, variantId string) error {
+	id, err := strconv.Atoi(variantId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	return sp.changeProduct(ctx, prodId, func(transaction querier, prodId int) error {
+		res, err := transaction.ExecContext(ctx, "DELETE FROM product_variants WHERE variant_id = ? AND product_id = ?", id, prodId)
+		if err != nil {
+			return storage.ErrQuery
+		}
+		return variantChanged(res)
+	})
+}
+
+// variantChanged returns ErrVariantNotFound if the statement found no variant
+func variantChanged(res sql.Result) error {
+	count, err := res.RowsAffected()
+	if err != nil {
+		return storage.ErrQuery
+	}
+	if count == 0 {
+		return storage.ErrVariantNotFound
+	}
+	return nil
+}
+
+// changeProduct runs fn in the transaction after the version of the product is counted
+// and writes the update event of the product after fn
+func (sp *sqliteProvider) changeProduct(ctx context.Context, prodId string, fn func(transaction querier, prodId int) error) error {
+	id, err := strconv.Atoi(prodId)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	res, err := transaction.ExecContext(ctx, "UPDATE products SET version = version + 1 WHERE product_id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		return storage.ErrQuery
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return storage.ErrQuery
+	}
+	if count == 0 {
+		return storage.ErrProductNotFound
+	}
+	if err := fn(transaction, id); err != nil {
+		return err
+	}
+	if err := productEvent(ctx, transaction, models.EventProductUpdated, id); err != nil {
+		return err
+	}
+	if err := transaction.Commit(); err != nil {
+		return storage.ErrCommitTx
+	}
+	return nil
+}
+
+// variantColumns returns options and prices of the variant as json texts
+func variantColumns(variant models.Variant) (interface{}, interface{}, error) {
+	options, err := jsonText(variant.Options, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	prices, err := jsonText(variant.Prices, len(variant.Prices) == 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return options, prices, nil
+}
@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+)
+
+const versionColumns = `version, action, COALESCE(actor, ''), COALESCE(reverted_to, 0), created_at, snapshot`
+
+// SaveVersions appends versions to the history of their products and categories,
+// every version gets the next number of its history
+func (sp *sqliteProvider) SaveVersions(ctx context.Context, versions []models.Version) error {
+	if len(versions) == 0 {
+		return nil
+	}
+	transaction, err := sp.begin(ctx)
+	if err != nil {
+		return storage.ErrStartTx
+	}
+	defer transaction.Rollback()
+	now := time.Now().UTC()
+	for _, version := range versions {
+		_, err := transaction.ExecContext(ctx, `
+INSERT INTO versions (entity, entity_key, version, action, actor, reverted_to, snapshot, created_at)
+SELECT ?1, ?2, COALESCE(MAX(version), 0) + 1, ?3, ?4, NULLIF(?5, 0), ?6, ?7
+FROM versions
+WHERE entity = ?1 AND entity_key = ?2`,
+		version.Entity,
+		version.Key,
+		version.Action,
+		version.Actor,
+		version.RevertedTo,
+		string(version.Snapshot),
+		now)
+		if err != nil {
+			return storage.ErrQuery
+		}
+	}
+	if err := transaction.Commit(); err != nil {
+		return storage.ErrCommitTx
+	}
+	return nil
+}
+
+// GetVersions returns the history of the product or category from the first version
+func (sp *sqliteProvider) GetVersions(ctx context.Context, entity, key string) ([]models.Version, error) {
+	rows, err := sp.conn(ctx).QueryContext(ctx, `
+SELECT `+versionColumns+`
+FROM versions
+WHERE entity = ? AND entity_key = ?
+ORDER BY version`,
+	entity,
+	key)
+	if err != nil {
+		return nil, storage.ErrQuery
+	}
+	defer rows.Close()
+	versions := []models.Version{}
+	for rows.Next() {
+		version, err := scanVersion(rows, entity, key)
+		if err != nil {
+			return nil, storage.ErrQuery
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storage.ErrQuery
+	}
+	return versions, nil
+}
+
+// GetVersion returns ErrVersionNotFound if the history has no such version
+func (sp *sqliteProvider) GetVersion(ctx context.Context, entity, key string, number int) (models.Version, error) {
+	row := sp.conn(ctx).QueryRowContext(ctx, `
+SELECT `+versionColumns+`
+FROM versions
+WHERE entity = ? AND entity_key = ? AND version = ?`,
+	entity,
+	key,
+	number)
+	version, err := scanVersion(row, entity, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Version{}, storage.ErrVersionNotFound
+		}
+		return models.Version{}, storage.ErrQuery
+	}
+	return version, nil
+}
+
+func scanVersion(row scanner, entity, key string) (models.Version, error) {
+	version := models.Version{
+		Entity: entity,
+		Key: key,
+	}
+	var snapshot string
+	if err := row.Scan(&version.Version, &version.Action, &version.Actor, &version.RevertedTo, &version.CreatedAt, &snapshot); err != nil {
+		return models.Version{}, err
+	}
+	version.Snapshot = []byte(snapshot)
+	return version, nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// SetProductState moves the product from the state to the other one and returns the changed product,
+// ErrVersionConflict is returned if version is not zero and the product has another one
+// and ErrProductState if the product is in another state
+func (pp *postgresProvider) SetProductState(ctx context.Context, prodId, from, to string, version int) (models.Product, error) {
+	var product models.Product
+	err := pp.dbConn.retry(ctx, func() error {
+		return pp.changeProduct(ctx, prodId, func(transaction pgx.Tx) error {
+			// the row is locked by changeProduct, which has already counted the version
+			var (
+				state   string
+				current int
+			)
+			err := transaction.QueryRow(ctx, fmt.Sprintf(`
+SELECT state, version FROM "%s"
+WHERE product_id = $1`,
+			pp.cfg.ProductTable),
+			prodId).Scan(&state, &current)
+			if err != nil {
+				return dbErr(ErrQuery, err)
+			}
+			if version != 0 && version != current-1 {
+				return ErrVersionConflict
+			}
+			if state != from {
+				return ErrProductState
+			}
+			_, err = transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET state = $2
+WHERE product_id = $1`,
+			pp.cfg.ProductTable),
+			prodId,
+			to)
+			if err != nil {
+				return dbErr(ErrQuery, err)
+			}
+			product, err = pp.getProduct(ctx, transaction, "p.product_id = $1", prodId)
+			return err
+		})
+	})
+	return product, err
+}
+
+// stateFilter returns the condition on the product p for States of the filter bound to $n
+func (pp *postgresProvider) stateFilter(filter models.ProductFilter, n int) (string, []interface{}) {
+	var states []string
+	if len(filter.States) != 0 {
+		states = filter.States
+	}
+	return fmt.Sprintf(`($%[1]d::varchar[] IS NULL OR p.state = ANY($%[1]d::varchar[]))`, n), []interface{}{states}
+}
@@ -0,0 +1,390 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+func (pp *postgresProvider) SaveWarehouse(ctx context.Context, warehouse models.Warehouse) error {
+	_, err := pp.dbConn.Exec(ctx, fmt.Sprintf(`
+INSERT INTO "%s" (code, name)
+VALUES ($1, $2)`,
+	pp.cfg.WarehouseTable),
+	warehouse.Code,
+	warehouse.Name)
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrWarehouseExist
+	}
+	return dbErr(ErrQuery, err)
+}
+
+// GetWarehouses returns all warehouses ordered by code
+func (pp *postgresProvider) GetWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`
+SELECT code, name FROM "%s" ORDER BY code`,
+	pp.cfg.WarehouseTable))
+	if err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	var warehouses []models.Warehouse
+	for rows.Next() {
+		var warehouse models.Warehouse
+		if err := rows.Scan(&warehouse.Code, &warehouse.Name); err != nil {
+			return nil, dbErr(ErrQuery, err)
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	return warehouses, nil
+}
+
+// GetStock returns the stock of the product in every warehouse it was ever kept in ordered by warehouse code.
+// Stock is read from the primary only, replicas may not have the latest reservations yet
+func (pp *postgresProvider) GetStock(ctx context.Context, prodId string) ([]models.StockLevel, error) {
+	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`
+SELECT w.code, s.on_hand, s.reserved
+FROM "%s" as s
+JOIN "%s" as w ON w.warehouse_id = s.warehouse_id
+WHERE s.product_id = $1
+ORDER BY w.code`,
+	pp.cfg.StockTable,
+	pp.cfg.WarehouseTable),
+	prodId)
+	if err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	var levels []models.StockLevel
+	for rows.Next() {
+		var level models.StockLevel
+		if err := rows.Scan(&level.Warehouse, &level.OnHand, &level.Reserved); err != nil {
+			return nil, dbErr(ErrQuery, err)
+		}
+		level.Available = level.OnHand - level.Reserved
+		levels = append(levels, level)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	return levels, nil
+}
+
+// AdjustStock adds the signed quantity of the change to units of the not deleted product on hand in the warehouse.
+// ErrOutOfStock is returned if less units than reserved would be left on hand
+func (pp *postgresProvider) AdjustStock(ctx context.Context, prodId string, change models.StockChange) (models.StockLevel, error) {
+	level := models.StockLevel{Warehouse: change.Warehouse}
+	err := pp.dbConn.retry(ctx, func() error {
+		return pp.changeStock(ctx, prodId, change.Warehouse, func(transaction pgx.Tx, warehouseId int) error {
+			err := transaction.QueryRow(ctx, fmt.Sprintf(`
+INSERT INTO "%[1]s" as s (product_id, warehouse_id, on_hand)
+VALUES ($1, $2, $3)
+ON CONFLICT (product_id, warehouse_id) DO UPDATE SET on_hand = s.on_hand + EXCLUDED.on_hand
+RETURNING on_hand, reserved`,
+			pp.cfg.StockTable),
+			prodId,
+			warehouseId,
+			change.Quantity).Scan(&level.OnHand, &level.Reserved)
+			if err != nil {
+				return stockErr(err)
+			}
+			return pp.saveMovement(ctx, transaction, prodId, warehouseId, models.StockMovement{
+				Kind: models.MovementAdjust,
+				OnHand: change.Quantity,
+				Reason: change.Reason,
+				Actor: change.Actor,
+			})
+		})
+	})
+	if err != nil {
+		return models.StockLevel{}, err
+	}
+	level.Available = level.OnHand - level.Reserved
+	return level, nil
+}
+
+// ReserveStock reserves the quantity of the change from available units of the not deleted product in the warehouse.
+// The row of the stock is locked by the update and the condition is checked again after the lock,
+// so concurrent reservations never take more than available, ErrOutOfStock is returned instead
+func (pp *postgresProvider) ReserveStock(ctx context.Context, prodId string, change models.StockChange) (models.Reservation, error) {
+	reservation := models.Reservation{
+		Warehouse: change.Warehouse,
+		Quantity: change.Quantity,
+		State: models.ReservationActive,
+	}
+	err := pp.dbConn.retry(ctx, func() error {
+		return pp.changeStock(ctx, prodId, change.Warehouse, func(transaction pgx.Tx, warehouseId int) error {
+			tag, err := transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET reserved = reserved + $3
+WHERE product_id = $1 AND warehouse_id = $2 AND on_hand - reserved >= $3`,
+			pp.cfg.StockTable),
+			prodId,
+			warehouseId,
+			change.Quantity)
+			if err != nil {
+				return stockErr(err)
+			}
+			if tag.RowsAffected() == 0 {
+				return ErrOutOfStock
+			}
+			err = transaction.QueryRow(ctx, fmt.Sprintf(`
+INSERT INTO "%s" (product_id, warehouse_id, quantity, state)
+VALUES ($1, $2, $3, $4)
+RETURNING reservation_id, product_id, created_at`,
+			pp.cfg.ReservationTable),
+			prodId,
+			warehouseId,
+			change.Quantity,
+			models.ReservationActive).Scan(&reservation.Id, &reservation.ProductId, &reservation.CreatedAt)
+			if err != nil {
+				return dbErr(ErrQuery, err)
+			}
+			return pp.saveMovement(ctx, transaction, prodId, warehouseId, models.StockMovement{
+				Kind: models.MovementReserve,
+				Reserved: change.Quantity,
+				ReservationId: reservation.Id,
+				Reason: change.Reason,
+				Actor: change.Actor,
+			})
+		})
+	})
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	return reservation, nil
+}
+
+func (pp *postgresProvider) GetReservation(ctx context.Context, reservationId string) (models.Reservation, error) {
+	var reservation models.Reservation
+	err := pp.dbConn.QueryRow(ctx, fmt.Sprintf(`
+SELECT r.reservation_id, r.product_id, w.code, r.quantity, r.state, r.created_at, r.closed_at
+FROM "%s" as r
+JOIN "%s" as w ON w.warehouse_id = r.warehouse_id
+WHERE r.reservation_id = $1`,
+	pp.cfg.ReservationTable,
+	pp.cfg.WarehouseTable),
+	reservationId).Scan(&reservation.Id, &reservation.ProductId, &reservation.Warehouse, &reservation.Quantity,
+		&reservation.State, &reservation.CreatedAt, &reservation.ClosedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Reservation{}, ErrReservationNotFound
+		}
+		return models.Reservation{}, dbErr(ErrQuery, err)
+	}
+	return reservation, nil
+}
+
+// ReleaseReservation returns units of the active reservation to available ones
+func (pp *postgresProvider) ReleaseReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error) {
+	return pp.closeReservation(ctx, reservationId, models.ReservationReleased, change)
+}
+
+// CommitReservation takes units of the active reservation out of the warehouse
+func (pp *postgresProvider) CommitReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error) {
+	return pp.closeReservation(ctx, reservationId, models.ReservationCommitted, change)
+}
+
+// closeReservation moves the active reservation to the state, the state is changed by the update
+// that locks the reservation, so it is closed once. ErrReservationClosed is returned if it is not active
+func (pp *postgresProvider) closeReservation(ctx context.Context, reservationId string, state string, change models.StockChange) (models.Reservation, error) {
+	var reservation models.Reservation
+	err := pp.dbConn.retry(ctx, func() error {
+		transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			return dbErr(ErrStartTx, err)
+		}
+		err = pp.closeReservationTx(ctx, transaction, reservationId, state, change, &reservation)
+		if err != nil {
+			if err := transaction.Rollback(ctx); err != nil {
+				return dbErr(ErrRollbackTx, err)
+			}
+			return err
+		}
+		if err := transaction.Commit(ctx); err != nil {
+			return dbErr(ErrCommitTx, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	return reservation, nil
+}
+
+func (pp *postgresProvider) closeReservationTx(ctx context.Context, transaction pgx.Tx, reservationId string, state string, change models.StockChange, reservation *models.Reservation) error {
+	var warehouseId int
+	err := transaction.QueryRow(ctx, fmt.Sprintf(`
+WITH closed AS (
+	UPDATE "%s" SET state = $2, closed_at = now()
+	WHERE reservation_id = $1 AND state = $3
+	RETURNING *
+)
+SELECT c.reservation_id, c.product_id, c.warehouse_id, w.code, c.quantity, c.state, c.created_at, c.closed_at
+FROM closed as c
+JOIN "%s" as w ON w.warehouse_id = c.warehouse_id`,
+	pp.cfg.ReservationTable,
+	pp.cfg.WarehouseTable),
+	reservationId,
+	state,
+	models.ReservationActive).Scan(&reservation.Id, &reservation.ProductId, &warehouseId, &reservation.Warehouse,
+		&reservation.Quantity, &reservation.State, &reservation.CreatedAt, &reservation.ClosedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := pp.GetReservation(ctx, reservationId); err != nil {
+			return err
+		}
+		return ErrReservationClosed
+	}
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	movement := models.StockMovement{
+		Kind: models.MovementRelease,
+		Reserved: -reservation.Quantity,
+		ReservationId: reservation.Id,
+		Reason: change.Reason,
+		Actor: change.Actor,
+	}
+	if state == models.ReservationCommitted {
+		movement.Kind = models.MovementCommit
+		movement.OnHand = -reservation.Quantity
+	}
+	_, err = transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET on_hand = on_hand + $3, reserved = reserved + $4
+WHERE product_id = $1 AND warehouse_id = $2`,
+	pp.cfg.StockTable),
+	reservation.ProductId,
+	warehouseId,
+	movement.OnHand,
+	movement.Reserved)
+	if err != nil {
+		return stockErr(err)
+	}
+	return pp.saveMovement(ctx, transaction, strconv.Itoa(reservation.ProductId), warehouseId, movement)
+}
+
+// GetStockMovements returns the ledger of the stock of the product in the order of the changes
+func (pp *postgresProvider) GetStockMovements(ctx context.Context, prodId string) ([]models.StockMovement, error) {
+	rows, err := pp.dbConn.Query(ctx, fmt.Sprintf(`
+SELECT m.movement_id, w.code, m.kind, m.on_hand, m.reserved, COALESCE(m.reservation_id, 0),
+COALESCE(m.reason, ''), COALESCE(m.actor, ''), m.created_at
+FROM "%s" as m
+JOIN "%s" as w ON w.warehouse_id = m.warehouse_id
+WHERE m.product_id = $1
+ORDER BY m.movement_id`,
+	pp.cfg.StockMovementTable,
+	pp.cfg.WarehouseTable),
+	prodId)
+	if err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	var movements []models.StockMovement
+	for rows.Next() {
+		var movement models.StockMovement
+		err := rows.Scan(&movement.Id, &movement.Warehouse, &movement.Kind, &movement.OnHand, &movement.Reserved,
+			&movement.ReservationId, &movement.Reason, &movement.Actor, &movement.CreatedAt)
+		if err != nil {
+			return nil, dbErr(ErrQuery, err)
+		}
+		movements = append(movements, movement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	return movements, nil
+}
+
+// changeStock runs fn in the transaction with the id of the warehouse by its code
+// if the product is not deleted
+func (pp *postgresProvider) changeStock(ctx context.Context, prodId, warehouseCode string, fn func(transaction pgx.Tx, warehouseId int) error) error {
+	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return dbErr(ErrStartTx, err)
+	}
+	var exist bool
+	err = transaction.QueryRow(ctx, fmt.Sprintf(`
+SELECT EXISTS (SELECT 1 FROM "%s" WHERE product_id = $1 AND deleted_at IS NULL)`,
+	pp.cfg.ProductTable),
+	prodId).Scan(&exist)
+	if err != nil {
+		err = dbErr(ErrQuery, err)
+	} else if !exist {
+		err = ErrProductNotFound
+	}
+	var warehouseId int
+	if err == nil {
+		err = transaction.QueryRow(ctx, fmt.Sprintf(`
+SELECT warehouse_id FROM "%s" WHERE code = $1`,
+		pp.cfg.WarehouseTable),
+		warehouseCode).Scan(&warehouseId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrWarehouseNotFound
+		} else if err != nil {
+			err = dbErr(ErrQuery, err)
+		}
+	}
+	if err == nil {
+		err = fn(transaction, warehouseId)
+	}
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return dbErr(ErrRollbackTx, err)
+		}
+		return err
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return dbErr(ErrCommitTx, err)
+	}
+	return nil
+}
+
+// saveMovement writes the movement to the ledger in the transaction of the change
+func (pp *postgresProvider) saveMovement(ctx context.Context, transaction pgx.Tx, prodId string, warehouseId int, movement models.StockMovement) error {
+	_, err := transaction.Exec(ctx, fmt.Sprintf(`
+INSERT INTO "%s" (product_id, warehouse_id, kind, on_hand, reserved, reservation_id, reason, actor)
+VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NULLIF($7, ''), NULLIF($8, ''))`,
+	pp.cfg.StockMovementTable),
+	prodId,
+	warehouseId,
+	movement.Kind,
+	movement.OnHand,
+	movement.Reserved,
+	movement.ReservationId,
+	movement.Reason,
+	movement.Actor)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	return nil
+}
+
+// stockErr returns ErrOutOfStock if the change broke the check of the stock row
+func stockErr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23514" {
+		return ErrOutOfStock
+	}
+	return dbErr(ErrQuery, err)
+}
+
+// stockFilter returns the condition on the product p for InStock of the filter bound to $n
+func (pp *postgresProvider) stockFilter(filter models.ProductFilter, n int) (string, []interface{}) {
+	return fmt.Sprintf(`($%[1]d::boolean IS NULL OR EXISTS (
+	SELECT 1 FROM "%[2]s" as s
+	WHERE s.product_id = p.product_id AND s.on_hand > s.reserved
+) = $%[1]d::boolean)`,
+	n,
+	pp.cfg.StockTable), []interface{}{filter.InStock}
+}
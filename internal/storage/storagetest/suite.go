@@ -0,0 +1,1451 @@
+// Package storagetest holds the behavior suite every storage backend must pass
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/EwvwGeN/cataloger/internal/storage"
+	"github.com/stretchr/testify/suite"
+)
+
+// Repository is the set of storage methods used by the services
+type Repository interface {
+	SaveUser(ctx context.Context, email string, passHash string) error
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	SaveRefreshToken(ctx context.Context, email string, refreshToken string, rttl int64) error
+
+	SaveCategory(ctx context.Context, category models.Category) error
+	InserOrGetCategiriesId(ctx context.Context, categories []models.Category) (map[string]int, error)
+	GetCategoryByCode(ctx context.Context, catCode string) (models.Category, error)
+	GetAllCategories(ctx context.Context) ([]models.Category, error)
+	UpdateCategoryByCode(ctx context.Context, catCode string, catUpdateData models.CategoryForPatch, version int) (models.Category, error)
+	DeleteCategoryBycode(ctx context.Context, catCode string, version int) (models.Category, error)
+	StreamCategories(ctx context.Context, fn func(models.Category) error) error
+	GetCategoriesIdByCodes(ctx context.Context, catCodes []string) ([]int, error)
+	GetCategoriesIdMapByCodes(ctx context.Context, catCodes []string) (map[string]int, error)
+	GetCategoriesAttributes(ctx context.Context, catCodes []string) (map[string][]models.Attribute, error)
+
+	SaveProduct(ctx context.Context, product models.Product, catIds []int) (string, error)
+	SaveProducts(ctx context.Context, products []models.Product, catsIds [][]int, atomic bool) ([]models.ImportRowResult, error)
+	GetProductById(ctx context.Context, prodId string) (models.Product, error)
+	GetProductBySku(ctx context.Context, sku string) (models.Product, error)
+	GetProductByBarcode(ctx context.Context, code string) (models.Product, error)
+	GetAllProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error)
+	GetProductsByCategory(ctx context.Context, catCode string, states []string) ([]models.Product, error)
+	StreamProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error
+	UpdateProductById(ctx context.Context, prodId string, newPorductdata models.ProductForPatch, catIds []int, version int) (models.Product, error)
+	DeleteProductById(ctx context.Context, id string, version int) (models.Product, error)
+	SetProductState(ctx context.Context, prodId, from, to string, version int) (models.Product, error)
+
+	SaveVariant(ctx context.Context, prodId string, variant models.Variant) (string, error)
+	UpdateVariantById(ctx context.Context, prodId, variantId string, variant models.Variant) error
+	DeleteVariantById(ctx context.Context, prodId, variantId string) error
+
+	SaveMedia(ctx context.Context, prodId string, media models.Media) (models.Media, error)
+	DeleteMediaById(ctx context.Context, prodId, mediaId string) (models.Media, error)
+	SetMediaOrder(ctx context.Context, prodId string, mediaIds []int) error
+	SetPrimaryMedia(ctx context.Context, prodId, mediaId string) error
+
+	SaveWarehouse(ctx context.Context, warehouse models.Warehouse) error
+	GetWarehouses(ctx context.Context) ([]models.Warehouse, error)
+	GetStock(ctx context.Context, prodId string) ([]models.StockLevel, error)
+	AdjustStock(ctx context.Context, prodId string, change models.StockChange) (models.StockLevel, error)
+	ReserveStock(ctx context.Context, prodId string, change models.StockChange) (models.Reservation, error)
+	GetReservation(ctx context.Context, reservationId string) (models.Reservation, error)
+	ReleaseReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error)
+	CommitReservation(ctx context.Context, reservationId string, change models.StockChange) (models.Reservation, error)
+	GetStockMovements(ctx context.Context, prodId string) ([]models.StockMovement, error)
+
+	GetTrash(ctx context.Context) (models.Trash, error)
+	RestoreProductById(ctx context.Context, prodId string) error
+	RestoreCategoryByCode(ctx context.Context, catCode string) error
+	PurgeTrash(ctx context.Context, before time.Time) (models.PurgeResult, error)
+
+	SaveVersions(ctx context.Context, versions []models.Version) error
+	GetVersions(ctx context.Context, entity, key string) ([]models.Version, error)
+	GetVersion(ctx context.Context, entity, key string, number int) (models.Version, error)
+
+	RelayEvents(ctx context.Context, limit int, claimTimeout time.Duration, publish func([]models.Event) models.RelayResult) error
+
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Run runs the suite, newRepo must return an empty repository for every test
+func Run(t *testing.T, newRepo func(t *testing.T) Repository) {
+	suite.Run(t, &behaviorTestSuite{newRepo: newRepo})
+}
+
+type behaviorTestSuite struct {
+	suite.Suite
+	newRepo func(t *testing.T) Repository
+	repo Repository
+	ctx context.Context
+}
+
+func (suite *behaviorTestSuite) SetupTest() {
+	suite.repo = suite.newRepo(suite.T())
+	suite.ctx = context.Background()
+}
+
+// addCategories saves categories with codes equal to names and returns their ids by codes
+func (suite *behaviorTestSuite) addCategories(codes ...string) map[string]int {
+	for _, code := range codes {
+		suite.Require().NoError(suite.repo.SaveCategory(suite.ctx, models.Category{Name: code, Code: code, Description: code}))
+	}
+	ids, err := suite.repo.GetCategoriesIdMapByCodes(suite.ctx, codes)
+	suite.Require().NoError(err)
+	suite.Require().Len(ids, len(codes))
+	return ids
+}
+
+func (suite *behaviorTestSuite) Test_Users() {
+	suite.Require().NoError(suite.repo.SaveUser(suite.ctx, "test@test.test", "hash"))
+	suite.Require().ErrorIs(suite.repo.SaveUser(suite.ctx, "test@test.test", "other"), storage.ErrUserExist)
+
+	suite.Require().NoError(suite.repo.SaveRefreshToken(suite.ctx, "test@test.test", "refresh", 100))
+	user, err := suite.repo.GetUserByEmail(suite.ctx, "test@test.test")
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.User{
+		Email: "test@test.test",
+		PassHash: "hash",
+		RefreshHash: "refresh",
+		ExpiresAt: 100,
+	}, user)
+
+	_, err = suite.repo.GetUserByEmail(suite.ctx, "missing@test.test")
+	suite.Require().ErrorIs(err, storage.ErrQuery)
+	suite.Require().NoError(suite.repo.SaveRefreshToken(suite.ctx, "missing@test.test", "refresh", 100))
+}
+
+func (suite *behaviorTestSuite) Test_Categories() {
+	first := models.Category{Name: "First", Code: "first", Description: "First category"}
+	second := models.Category{Name: "Second", Code: "second", Description: "Second category"}
+	suite.Require().NoError(suite.repo.SaveCategory(suite.ctx, first))
+	suite.Require().NoError(suite.repo.SaveCategory(suite.ctx, second))
+	suite.Require().ErrorIs(suite.repo.SaveCategory(suite.ctx, models.Category{Name: "Other", Code: "first"}), storage.ErrCategoryExist)
+
+	category, err := suite.repo.GetCategoryByCode(suite.ctx, "first")
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.Category{Name: "First", Code: "first", Description: "First category", Version: 1}, category)
+	_, err = suite.repo.GetCategoryByCode(suite.ctx, "missing")
+	suite.Require().ErrorIs(err, storage.ErrQuery)
+
+	categories, err := suite.repo.GetAllCategories(suite.ctx)
+	suite.Require().NoError(err)
+	suite.Require().ElementsMatch([]models.Category{first, second}, categories)
+
+	var streamed []models.Category
+	suite.Require().NoError(suite.repo.StreamCategories(suite.ctx, func(category models.Category) error {
+		streamed = append(streamed, category)
+		return nil
+	}))
+	suite.Require().Equal([]models.Category{first, second}, streamed)
+
+	tests := []struct{
+		name string
+		code string
+		patch models.CategoryForPatch
+		wantErr error
+	}{
+		{
+			name: "used_code",
+			code: "first",
+			patch: models.CategoryForPatch{Code: strPtr("second")},
+			wantErr: storage.ErrCategoryExist,
+		},
+		{
+			name: "same_code",
+			code: "first",
+			patch: models.CategoryForPatch{Name: strPtr("Renamed"), Code: strPtr("first")},
+		},
+		{
+			name: "new_code",
+			code: "first",
+			patch: models.CategoryForPatch{Code: strPtr("third")},
+		},
+		{
+			name: "missing",
+			code: "missing",
+			patch: models.CategoryForPatch{Name: strPtr("Missing")},
+		},
+	}
+	for _, tt := range tests {
+		err := categoryErr(suite.repo.UpdateCategoryByCode(suite.ctx, tt.code, tt.patch, 0))
+		suite.Require().ErrorIs(err, tt.wantErr, "test: %s", tt.name)
+	}
+	category, err = suite.repo.GetCategoryByCode(suite.ctx, "third")
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.Category{Name: "Renamed", Code: "third", Description: "First category", Version: 3}, category)
+
+	suite.Require().NoError(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "third", 0)))
+	suite.Require().NoError(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "missing", 0)))
+	_, err = suite.repo.GetCategoryByCode(suite.ctx, "third")
+	suite.Require().ErrorIs(err, storage.ErrQuery)
+}
+
+func (suite *behaviorTestSuite) Test_CategoryCodes() {
+	ids := suite.addCategories("first", "second")
+
+	catIds, err := suite.repo.GetCategoriesIdByCodes(suite.ctx, []string{"second", "missing", "first"})
+	suite.Require().NoError(err)
+	suite.Require().ElementsMatch([]int{ids["first"], ids["second"]}, catIds)
+	catIds, err = suite.repo.GetCategoriesIdByCodes(suite.ctx, []string{"missing"})
+	suite.Require().NoError(err)
+	suite.Require().Empty(catIds)
+
+	idsMap, err := suite.repo.GetCategoriesIdMapByCodes(suite.ctx, []string{"first", "missing"})
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]int{"first": ids["first"]}, idsMap)
+
+	idsMap, err = suite.repo.InserOrGetCategiriesId(suite.ctx, []models.Category{
+		{Name: "Changed name", Code: "first", Description: "first"},
+		{Name: "Third", Code: "third", Description: "third"},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(idsMap, 2)
+	suite.Require().Equal(ids["first"], idsMap["first"])
+	suite.Require().NotContains([]int{ids["first"], ids["second"]}, idsMap["third"])
+	category, err := suite.repo.GetCategoryByCode(suite.ctx, "first")
+	suite.Require().NoError(err)
+	suite.Require().Equal("first", category.Name)
+
+	// the first category of repeated codes is inserted
+	idsMap, err = suite.repo.InserOrGetCategiriesId(suite.ctx, []models.Category{
+		{Name: "Fourth", Code: "fourth", Description: "fourth"},
+		{Name: "Other fourth", Code: "fourth", Description: "other"},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(idsMap, 1)
+	category, err = suite.repo.GetCategoryByCode(suite.ctx, "fourth")
+	suite.Require().NoError(err)
+	suite.Require().Equal("Fourth", category.Name)
+}
+
+func (suite *behaviorTestSuite) Test_Products() {
+	ids := suite.addCategories("first", "second")
+
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt"}, []int{ids["first"], ids["second"]})
+	suite.Require().NoError(err)
+	hatId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "Wool hat"}, nil)
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Other shirt"}, nil)
+	suite.Require().ErrorIs(err, storage.ErrProductExist)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Jeans", Description: "Blue jeans"}, []int{ids["first"] + ids["second"] + 100})
+	suite.Require().ErrorIs(err, storage.ErrQuery)
+
+	product, err := suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(shirtId, strconv.Itoa(product.Id))
+	suite.Require().Equal("Shirt", product.Name)
+	suite.Require().ElementsMatch([]string{"first", "second"}, product.CategoryСodes)
+	product, err = suite.repo.GetProductById(suite.ctx, hatId)
+	suite.Require().NoError(err)
+	suite.Require().Empty(product.CategoryСodes)
+	_, err = suite.repo.GetProductById(suite.ctx, "100000")
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+
+	products, err := suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{})
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 2)
+	products, err = suite.repo.GetProductsByCategory(suite.ctx, "second", nil)
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 1)
+	suite.Require().Equal("Shirt", products[0].Name)
+
+	tests := []struct{
+		name string
+		filter models.ProductFilter
+		want []string
+	}{
+		{
+			name: "all",
+			want: []string{"Shirt", "Hat"},
+		},
+		{
+			name: "category",
+			filter: models.ProductFilter{CategoryCodes: []string{"first", "missing"}},
+			want: []string{"Shirt"},
+		},
+		{
+			name: "no_products",
+			filter: models.ProductFilter{CategoryCodes: []string{"missing"}},
+		},
+	}
+	for _, tt := range tests {
+		var names []string
+		err := suite.repo.StreamProducts(suite.ctx, tt.filter, func(product models.Product) error {
+			names = append(names, product.Name)
+			return nil
+		})
+		suite.Require().NoError(err, "test: %s", tt.name)
+		suite.Require().Equal(tt.want, names, "test: %s", tt.name)
+	}
+
+	suite.Require().ErrorIs(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "second", 0)), storage.ErrCategoryUsed)
+}
+
+func (suite *behaviorTestSuite) Test_UpdateProduct() {
+	ids := suite.addCategories("first", "second", "third")
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt"}, []int{ids["first"], ids["second"]})
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "Wool hat"}, nil)
+	suite.Require().NoError(err)
+
+	tests := []struct{
+		name string
+		patch models.ProductForPatch
+		catIds []int
+		wantErr error
+		wantName string
+		wantCodes []string
+	}{
+		{
+			name: "used_name",
+			patch: models.ProductForPatch{Name: strPtr("Hat")},
+			wantErr: storage.ErrProductExist,
+			wantName: "Shirt",
+			wantCodes: []string{"first", "second"},
+		},
+		{
+			name: "name_only",
+			patch: models.ProductForPatch{Name: strPtr("Linen shirt")},
+			wantName: "Linen shirt",
+			wantCodes: []string{"first", "second"},
+		},
+		{
+			name: "replace_categories",
+			catIds: []int{ids["second"], ids["third"]},
+			wantName: "Linen shirt",
+			wantCodes: []string{"second", "third"},
+		},
+	}
+	for _, tt := range tests {
+		err := productErr(suite.repo.UpdateProductById(suite.ctx, shirtId, tt.patch, tt.catIds, 0))
+		suite.Require().ErrorIs(err, tt.wantErr, "test: %s", tt.name)
+		product, err := suite.repo.GetProductById(suite.ctx, shirtId)
+		suite.Require().NoError(err, "test: %s", tt.name)
+		suite.Require().Equal(tt.wantName, product.Name, "test: %s", tt.name)
+		suite.Require().ElementsMatch(tt.wantCodes, product.CategoryСodes, "test: %s", tt.name)
+	}
+	suite.Require().NoError(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "first", 0)))
+
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 0)))
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 0)))
+	_, err = suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+	suite.Require().NoError(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "second", 0)))
+}
+
+func (suite *behaviorTestSuite) Test_RowVersions() {
+	suite.addCategories("first")
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt"}, nil)
+	suite.Require().NoError(err)
+	product, err := suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(1, product.Version)
+
+	// the change returns the product as it is written
+	changed, err := suite.repo.UpdateProductById(suite.ctx, shirtId, models.ProductForPatch{Name: strPtr("Linen shirt")}, nil, 1)
+	suite.Require().NoError(err)
+	suite.Require().Equal("Linen shirt", changed.Name)
+	suite.Require().Equal(2, changed.Version)
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, shirtId, models.ProductForPatch{}, []int{}, 0)))
+	suite.Require().ErrorIs(productErr(suite.repo.UpdateProductById(suite.ctx, shirtId, models.ProductForPatch{Name: strPtr("Wool shirt")}, nil, 2)), storage.ErrVersionConflict)
+	product, err = suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal("Linen shirt", product.Name)
+	suite.Require().Equal(3, product.Version)
+	suite.Require().ErrorIs(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 2)), storage.ErrVersionConflict)
+	// the deletion returns the product as it was and nothing when it is deleted again
+	deleted, err := suite.repo.DeleteProductById(suite.ctx, shirtId, 3)
+	suite.Require().NoError(err)
+	suite.Require().Equal(product, deleted)
+	deleted, err = suite.repo.DeleteProductById(suite.ctx, shirtId, 3)
+	suite.Require().NoError(err)
+	suite.Require().Zero(deleted.Id)
+
+	renamed, err := suite.repo.UpdateCategoryByCode(suite.ctx, "first", models.CategoryForPatch{Name: strPtr("Renamed")}, 1)
+	suite.Require().NoError(err)
+	suite.Require().Equal("Renamed", renamed.Name)
+	suite.Require().Equal(2, renamed.Version)
+	suite.Require().ErrorIs(categoryErr(suite.repo.UpdateCategoryByCode(suite.ctx, "first", models.CategoryForPatch{Name: strPtr("Other")}, 1)), storage.ErrVersionConflict)
+	suite.Require().NoError(categoryErr(suite.repo.UpdateCategoryByCode(suite.ctx, "missing", models.CategoryForPatch{Name: strPtr("Other")}, 1)))
+	category, err := suite.repo.GetCategoryByCode(suite.ctx, "first")
+	suite.Require().NoError(err)
+	suite.Require().Equal("Renamed", category.Name)
+	suite.Require().Equal(2, category.Version)
+	suite.Require().ErrorIs(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "first", 1)), storage.ErrVersionConflict)
+	deletedCategory, err := suite.repo.DeleteCategoryBycode(suite.ctx, "first", 2)
+	suite.Require().NoError(err)
+	suite.Require().Equal(category, deletedCategory)
+	suite.Require().NoError(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "missing", 2)))
+}
+
+func (suite *behaviorTestSuite) Test_SaveProducts() {
+	ids := suite.addCategories("first")
+	_, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "Wool hat"}, nil)
+	suite.Require().NoError(err)
+	missingId := ids["first"] + 100
+
+	results, err := suite.repo.SaveProducts(suite.ctx, []models.Product{
+		{Name: "Shirt", Description: "Cotton shirt"},
+		{Name: "Hat", Description: "Other hat"},
+		{Name: "Jeans", Description: "Blue jeans"},
+	}, [][]int{{ids["first"]}, nil, {ids["first"], ids["first"]}}, false)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{
+		models.ImportStatusCreated,
+		models.ImportStatusSkippedDuplicate,
+		models.ImportStatusCreated,
+	}, statuses(results))
+	product, err := suite.repo.GetProductById(suite.ctx, results[2].ProductId)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"first"}, product.CategoryСodes)
+
+	results, err = suite.repo.SaveProducts(suite.ctx, []models.Product{
+		{Name: "Scarf", Description: "Wool scarf"},
+		{Name: "Shirt", Description: "Cotton shirt"},
+		{Name: "Gloves", Description: "Wool gloves"},
+		{Name: "Socks", Description: "Socks"},
+	}, [][]int{nil, nil, {missingId}, nil}, true)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{
+		models.ImportStatusRolledBack,
+		models.ImportStatusSkippedDuplicate,
+		models.ImportStatusFailed,
+		models.ImportStatusRolledBack,
+	}, statuses(results))
+	products, err := suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{})
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 3)
+
+	// a failed row frees its name for the next rows, the saved one makes the rest duplicates
+	results, err = suite.repo.SaveProducts(suite.ctx, []models.Product{
+		{Name: "Coat", Description: "Wool coat"},
+		{Name: "Coat", Description: "Rain coat"},
+		{Name: "Coat", Description: "Fur coat"},
+		{Name: "", Description: "Nameless"},
+		{Name: "Belt", Description: ""},
+	}, [][]int{{missingId}, {ids["first"]}, nil, nil, nil}, false)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{
+		models.ImportStatusFailed,
+		models.ImportStatusCreated,
+		models.ImportStatusSkippedDuplicate,
+		models.ImportStatusFailed,
+		models.ImportStatusFailed,
+	}, statuses(results))
+	suite.Require().Equal(storage.ErrQuery.Error(), results[0].Reason)
+	suite.Require().Equal(storage.ErrProductExist.Error(), results[2].Reason)
+	product, err = suite.repo.GetProductById(suite.ctx, results[1].ProductId)
+	suite.Require().NoError(err)
+	suite.Require().Equal("Rain coat", product.Description)
+	suite.Require().Equal([]string{"first"}, product.CategoryСodes)
+}
+
+func (suite *behaviorTestSuite) Test_Prices() {
+	ptr := func(v int64) *int64 { return &v }
+	at := func(d time.Duration) *time.Time {
+		t := time.Now().Add(d).UTC().Truncate(time.Second)
+		return &t
+	}
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt", Prices: []models.Price{
+		{Kind: models.PriceKindSale, Amount: 800, Currency: "USD", ValidFrom: at(-time.Hour), ValidTo: at(time.Hour)},
+		{Kind: models.PriceKindList, Amount: 1000, Currency: "USD"},
+		{Kind: models.PriceKindList, Amount: 900, Currency: "EUR"},
+	}}, nil)
+	suite.Require().NoError(err)
+	hatId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "Warm hat", Prices: []models.Price{
+		{Kind: models.PriceKindList, Amount: 2000, Currency: "USD"},
+		{Kind: models.PriceKindSale, Amount: 100, Currency: "USD", ValidTo: at(-time.Hour)},
+		{Kind: models.PriceKindSale, Amount: 200, Currency: "USD", ValidFrom: at(time.Hour)},
+	}}, nil)
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Scarf", Description: "Long scarf"}, nil)
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Coat", Description: "Wool coat", Prices: []models.Price{
+		{Kind: models.PriceKindList, Amount: -1, Currency: "USD"},
+	}}, nil)
+	suite.Require().ErrorIs(err, storage.ErrQuery)
+
+	product, err := suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Len(product.Prices, 3)
+	suite.Require().Equal(models.PriceKindList, product.Prices[0].Kind)
+	suite.Require().Equal("EUR", product.Prices[0].Currency)
+	suite.Require().Equal(int64(900), product.Prices[0].Amount)
+	suite.Require().Nil(product.Prices[0].ValidFrom)
+	suite.Require().Equal("USD", product.Prices[1].Currency)
+	suite.Require().Equal(models.PriceKindSale, product.Prices[2].Kind)
+	suite.Require().True(at(-time.Hour).Add(-time.Minute).Before(*product.Prices[2].ValidFrom))
+	suite.Require().True(product.Prices[2].ValidFrom.Before(*product.Prices[2].ValidTo))
+
+	tests := []struct{
+		name string
+		filter models.ProductFilter
+		want []string
+	}{
+		{name: "no filter", filter: models.ProductFilter{}, want: []string{"Shirt", "Hat", "Scarf"}},
+		{name: "sale price is the lowest", filter: models.ProductFilter{MaxPrice: ptr(800)}, want: []string{"Shirt"}},
+		{name: "expired and future prices are skipped", filter: models.ProductFilter{MaxPrice: ptr(1999)}, want: []string{"Shirt"}},
+		{name: "any currency within bounds", filter: models.ProductFilter{MinPrice: ptr(850), MaxPrice: ptr(950)}, want: []string{"Shirt"}},
+		{name: "lowest price is below bounds", filter: models.ProductFilter{MinPrice: ptr(850), MaxPrice: ptr(950), Currency: "USD"}, want: nil},
+		{name: "min price", filter: models.ProductFilter{MinPrice: ptr(1000)}, want: []string{"Hat"}},
+		{name: "currency only", filter: models.ProductFilter{Currency: "EUR"}, want: []string{"Shirt"}},
+	}
+	for _, tt := range tests {
+		products, err := suite.repo.GetAllProducts(suite.ctx, tt.filter)
+		suite.Require().NoError(err, tt.name)
+		var names []string
+		for _, product := range products {
+			names = append(names, product.Name)
+		}
+		suite.Require().Equal(tt.want, names, tt.name)
+	}
+
+	// nil prices keep the current ones, not nil replace them
+	name := "Fedora"
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, hatId, models.ProductForPatch{Name: &name}, nil, 0)))
+	product, err = suite.repo.GetProductById(suite.ctx, hatId)
+	suite.Require().NoError(err)
+	suite.Require().Len(product.Prices, 3)
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, hatId, models.ProductForPatch{Prices: []models.Price{
+		{Kind: models.PriceKindList, Amount: 500, Currency: "GBP"},
+	}}, nil, 0)))
+	product, err = suite.repo.GetProductById(suite.ctx, hatId)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]models.Price{{Kind: models.PriceKindList, Amount: 500, Currency: "GBP"}}, product.Prices)
+	err = productErr(suite.repo.UpdateProductById(suite.ctx, hatId, models.ProductForPatch{Prices: []models.Price{
+		{Kind: models.PriceKindList, Amount: 500, Currency: "GBP", ValidFrom: at(time.Hour), ValidTo: at(-time.Hour)},
+	}}, nil, 0))
+	suite.Require().ErrorIs(err, storage.ErrQuery)
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, hatId, models.ProductForPatch{Prices: []models.Price{}}, nil, 0)))
+	product, err = suite.repo.GetProductById(suite.ctx, hatId)
+	suite.Require().NoError(err)
+	suite.Require().Empty(product.Prices)
+
+	results, err := suite.repo.SaveProducts(suite.ctx, []models.Product{
+		{Name: "Gloves", Description: "Leather gloves", Prices: []models.Price{{Kind: models.PriceKindList, Amount: 1500, Currency: "USD"}}},
+		{Name: "Belt", Description: "Leather belt", Prices: []models.Price{{Kind: "retail", Amount: 1500, Currency: "USD"}}},
+	}, [][]int{nil, nil}, false)
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.ImportStatusCreated, results[0].Status)
+	suite.Require().Equal(models.ImportStatusFailed, results[1].Status)
+	product, err = suite.repo.GetProductById(suite.ctx, results[0].ProductId)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]models.Price{{Kind: models.PriceKindList, Amount: 1500, Currency: "USD"}}, product.Prices)
+}
+
+func (suite *behaviorTestSuite) Test_Identifiers() {
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{
+		Name: "Shirt",
+		Description: "Cotton shirt",
+		Sku: "SHIRT-1",
+		Barcodes: []string{"4006381333931", "036000291452"},
+	}, nil)
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "Warm hat"}, nil)
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Scarf", Description: "Long scarf"}, nil)
+	suite.Require().NoError(err, "products without sku don't conflict")
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Coat", Description: "Wool coat", Sku: "SHIRT-1"}, nil)
+	suite.Require().ErrorIs(err, storage.ErrSkuExist)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Coat", Description: "Wool coat", Barcodes: []string{"036000291452"}}, nil)
+	suite.Require().ErrorIs(err, storage.ErrBarcodeExist)
+
+	product, err := suite.repo.GetProductBySku(suite.ctx, "SHIRT-1")
+	suite.Require().NoError(err)
+	suite.Require().Equal(shirtId, strconv.Itoa(product.Id))
+	suite.Require().Equal([]string{"036000291452", "4006381333931"}, product.Barcodes)
+	suite.Require().Equal(1, product.Version)
+	product, err = suite.repo.GetProductByBarcode(suite.ctx, "4006381333931")
+	suite.Require().NoError(err)
+	suite.Require().Equal("SHIRT-1", product.Sku)
+	_, err = suite.repo.GetProductBySku(suite.ctx, "HAT-1")
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+	_, err = suite.repo.GetProductByBarcode(suite.ctx, "96385074")
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+
+	// the sku of a deleted product is free, its barcodes are taken until it is purged
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 0)))
+	_, err = suite.repo.GetProductBySku(suite.ctx, "SHIRT-1")
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+	_, err = suite.repo.GetProductByBarcode(suite.ctx, "4006381333931")
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+	linenId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Linen shirt", Description: "Linen shirt", Sku: "SHIRT-1"}, nil)
+	suite.Require().NoError(err)
+	err = productErr(suite.repo.UpdateProductById(suite.ctx, linenId, models.ProductForPatch{Barcodes: []string{"4006381333931"}}, nil, 0))
+	suite.Require().ErrorIs(err, storage.ErrBarcodeExist)
+	suite.Require().ErrorIs(suite.repo.RestoreProductById(suite.ctx, shirtId), storage.ErrSkuExist)
+
+	sku := "SHIRT-2"
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, linenId, models.ProductForPatch{Sku: &sku, Barcodes: []string{"96385074"}}, nil, 0)))
+	suite.Require().NoError(suite.repo.RestoreProductById(suite.ctx, shirtId))
+	product, err = suite.repo.GetProductByBarcode(suite.ctx, "96385074")
+	suite.Require().NoError(err)
+	suite.Require().Equal("SHIRT-2", product.Sku)
+	err = productErr(suite.repo.UpdateProductById(suite.ctx, linenId, models.ProductForPatch{Sku: func() *string { sku := "SHIRT-1"; return &sku }()}, nil, 0))
+	suite.Require().ErrorIs(err, storage.ErrSkuExist)
+	// empty sku removes it, nil barcodes keep them
+	sku = ""
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, linenId, models.ProductForPatch{Sku: &sku}, nil, 0)))
+	product, err = suite.repo.GetProductById(suite.ctx, linenId)
+	suite.Require().NoError(err)
+	suite.Require().Empty(product.Sku)
+	suite.Require().Equal([]string{"96385074"}, product.Barcodes)
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, linenId, models.ProductForPatch{Barcodes: []string{}}, nil, 0)))
+	_, err = suite.repo.GetProductByBarcode(suite.ctx, "96385074")
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+}
+
+func (suite *behaviorTestSuite) Test_SaveProductsBySku() {
+	_, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt", Sku: "SHIRT-1", Barcodes: []string{"96385074"}}, nil)
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "Warm hat"}, nil)
+	suite.Require().NoError(err)
+
+	results, err := suite.repo.SaveProducts(suite.ctx, []models.Product{
+		{Name: "Renamed shirt", Description: "Cotton shirt", Sku: "SHIRT-1"},
+		{Name: "Hat", Description: "Another hat", Sku: "HAT-1"},
+		{Name: "Coat", Description: "Wool coat", Sku: "COAT-1", Barcodes: []string{"96385074"}},
+		{Name: "Scarf", Description: "", Sku: "SCARF-1"},
+		{Name: "Long scarf", Description: "Long scarf", Sku: "SCARF-1", Barcodes: []string{"036000291452"}},
+		{Name: "Short scarf", Description: "Short scarf", Sku: "SCARF-1"},
+		{Name: "Gloves", Description: "Leather gloves", Barcodes: []string{"036000291452"}},
+		{Name: "Hat", Description: "Hat"},
+	}, make([][]int, 8), false)
+	suite.Require().NoError(err)
+	statuses := make([]string, len(results))
+	for idx, result := range results {
+		statuses[idx] = result.Status
+	}
+	suite.Require().Equal([]string{
+		models.ImportStatusSkippedDuplicate,
+		models.ImportStatusFailed,
+		models.ImportStatusFailed,
+		models.ImportStatusFailed,
+		models.ImportStatusCreated,
+		models.ImportStatusSkippedDuplicate,
+		models.ImportStatusFailed,
+		models.ImportStatusSkippedDuplicate,
+	}, statuses)
+	suite.Require().Equal(storage.ErrSkuExist.Error(), results[0].Reason)
+	suite.Require().Equal(storage.ErrProductExist.Error(), results[1].Reason)
+	suite.Require().Equal(storage.ErrBarcodeExist.Error(), results[2].Reason)
+	suite.Require().Equal(storage.ErrBarcodeExist.Error(), results[6].Reason)
+	product, err := suite.repo.GetProductBySku(suite.ctx, "SCARF-1")
+	suite.Require().NoError(err)
+	suite.Require().Equal("Long scarf", product.Name)
+	suite.Require().Equal([]string{"036000291452"}, product.Barcodes)
+}
+
+func (suite *behaviorTestSuite) Test_Variants() {
+	options := []models.VariantOption{
+		{Name: "Size", Values: []string{"S", "M"}},
+		{Name: "Colour", Values: []string{"Red", "Blue"}},
+	}
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt", Options: options}, nil)
+	suite.Require().NoError(err)
+	hatId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "Warm hat"}, nil)
+	suite.Require().NoError(err)
+
+	price := []models.Price{{Kind: models.PriceKindList, Amount: 1500, Currency: "USD"}}
+	smallId, err := suite.repo.SaveVariant(suite.ctx, shirtId, models.Variant{Sku: "SHIRT-S-RED", Options: map[string]string{"Size": "S", "Colour": "Red"}})
+	suite.Require().NoError(err)
+	mediumId, err := suite.repo.SaveVariant(suite.ctx, shirtId, models.Variant{Sku: "SHIRT-M-RED", Options: map[string]string{"Colour": "Red", "Size": "M"}, Prices: price})
+	suite.Require().NoError(err)
+	// options are unique as a set per product, skus among all variants
+	_, err = suite.repo.SaveVariant(suite.ctx, shirtId, models.Variant{Sku: "SHIRT-S-RED-2", Options: map[string]string{"Colour": "Red", "Size": "S"}})
+	suite.Require().ErrorIs(err, storage.ErrVariantExist)
+	_, err = suite.repo.SaveVariant(suite.ctx, hatId, models.Variant{Sku: "SHIRT-S-RED", Options: map[string]string{"Size": "S"}})
+	suite.Require().ErrorIs(err, storage.ErrVariantSkuExist)
+	_, err = suite.repo.SaveVariant(suite.ctx, hatId, models.Variant{Sku: "HAT-S-RED", Options: map[string]string{"Size": "S", "Colour": "Red"}})
+	suite.Require().NoError(err, "the same options of another product don't conflict")
+	_, err = suite.repo.SaveVariant(suite.ctx, "0", models.Variant{Sku: "NONE", Options: map[string]string{"Size": "S"}})
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+
+	product, err := suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(options, product.Options)
+	suite.Require().Equal(3, product.Version, "every change of variants counts the version of the product")
+	suite.Require().Len(product.Variants, 2)
+	suite.Require().Equal(smallId, strconv.Itoa(product.Variants[0].Id))
+	suite.Require().Equal(models.Variant{Id: product.Variants[1].Id, Sku: "SHIRT-M-RED", Options: map[string]string{"Size": "M", "Colour": "Red"}, Prices: price}, product.Variants[1])
+	suite.Require().Equal(mediumId, strconv.Itoa(product.Variants[1].Id))
+	products, err := suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{})
+	suite.Require().NoError(err)
+	suite.Require().Equal(product.Variants, products[0].Variants)
+
+	err = suite.repo.UpdateVariantById(suite.ctx, shirtId, smallId, models.Variant{Sku: "SHIRT-M-RED", Options: map[string]string{"Size": "S", "Colour": "Blue"}})
+	suite.Require().ErrorIs(err, storage.ErrVariantSkuExist)
+	err = suite.repo.UpdateVariantById(suite.ctx, shirtId, smallId, models.Variant{Sku: "SHIRT-S-RED", Options: map[string]string{"Size": "M", "Colour": "Red"}})
+	suite.Require().ErrorIs(err, storage.ErrVariantExist)
+	err = suite.repo.UpdateVariantById(suite.ctx, hatId, smallId, models.Variant{Sku: "SHIRT-S-RED", Options: map[string]string{"Size": "S"}})
+	suite.Require().ErrorIs(err, storage.ErrVariantNotFound, "the variant belongs to another product")
+	suite.Require().NoError(suite.repo.UpdateVariantById(suite.ctx, shirtId, smallId, models.Variant{Sku: "SHIRT-S-BLUE", Options: map[string]string{"Size": "S", "Colour": "Blue"}, Prices: price}))
+	suite.Require().NoError(suite.repo.DeleteVariantById(suite.ctx, shirtId, mediumId))
+	suite.Require().ErrorIs(suite.repo.DeleteVariantById(suite.ctx, shirtId, mediumId), storage.ErrVariantNotFound)
+	_, err = suite.repo.SaveVariant(suite.ctx, shirtId, models.Variant{Sku: "SHIRT-M-RED", Options: map[string]string{"Size": "M", "Colour": "Red"}})
+	suite.Require().NoError(err, "sku and options of the deleted variant are free")
+
+	// options are replaced by the product update, empty options remove them
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, hatId, models.ProductForPatch{Options: options[:1]}, nil, 0)))
+	product, err = suite.repo.GetProductById(suite.ctx, hatId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(options[:1], product.Options)
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, hatId, models.ProductForPatch{Options: []models.VariantOption{}}, nil, 0)))
+	product, err = suite.repo.GetProductById(suite.ctx, hatId)
+	suite.Require().NoError(err)
+	suite.Require().Nil(product.Options)
+	suite.Require().Len(product.Variants, 1)
+
+	// variants of a deleted product can't be changed and their skus stay taken until the product is purged
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, hatId, 0)))
+	suite.Require().ErrorIs(suite.repo.DeleteVariantById(suite.ctx, hatId, strconv.Itoa(product.Variants[0].Id)), storage.ErrProductNotFound)
+	_, err = suite.repo.SaveVariant(suite.ctx, shirtId, models.Variant{Sku: "HAT-S-RED", Options: map[string]string{"Size": "M", "Colour": "Blue"}})
+	suite.Require().ErrorIs(err, storage.ErrVariantSkuExist)
+	_, err = suite.repo.PurgeTrash(suite.ctx, time.Now().Add(time.Hour))
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveVariant(suite.ctx, shirtId, models.Variant{Sku: "HAT-S-RED", Options: map[string]string{"Size": "M", "Colour": "Blue"}})
+	suite.Require().NoError(err)
+}
+
+func (suite *behaviorTestSuite) Test_Media() {
+	prodId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt"}, nil)
+	suite.Require().NoError(err)
+	newMedia := func(name string, primary bool) models.Media {
+		return models.Media{
+			Url: "/media/" + name + ".jpg",
+			ThumbnailUrl: "/media/" + name + "_thumb.jpg",
+			ContentType: "image/jpeg",
+			Size: 100,
+			Width: 40,
+			Height: 30,
+			Primary: primary,
+			Key: name + ".jpg",
+			ThumbnailKey: name + "_thumb.jpg",
+		}
+	}
+	front, err := suite.repo.SaveMedia(suite.ctx, prodId, newMedia("front", false))
+	suite.Require().NoError(err)
+	suite.Require().True(front.Primary, "the first media is primary")
+	suite.Require().Equal(0, front.Position)
+	back, err := suite.repo.SaveMedia(suite.ctx, prodId, newMedia("back", false))
+	suite.Require().NoError(err)
+	suite.Require().False(back.Primary)
+	suite.Require().Equal(1, back.Position)
+	side, err := suite.repo.SaveMedia(suite.ctx, prodId, newMedia("side", true))
+	suite.Require().NoError(err)
+	suite.Require().True(side.Primary)
+	suite.Require().Equal("side.jpg", side.Key)
+	_, err = suite.repo.SaveMedia(suite.ctx, "0", newMedia("none", false))
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+
+	product, err := suite.repo.GetProductById(suite.ctx, prodId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(4, product.Version, "every change of media counts the version of the product")
+	suite.Require().Equal([]int{front.Id, back.Id, side.Id}, mediaIdsOf(product.Media))
+	suite.Require().Equal([]bool{false, false, true}, primaryOf(product.Media), "the primary media is replaced")
+	suite.Require().Equal("/media/back_thumb.jpg", product.Media[1].ThumbnailUrl)
+	suite.Require().Equal(1, product.Media[1].Position)
+	suite.Require().Empty(product.Media[1].Key, "keys of files are not read with the product")
+	products, err := suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{})
+	suite.Require().NoError(err)
+	suite.Require().Equal(product.Media, products[0].Media)
+
+	suite.Require().ErrorIs(suite.repo.SetMediaOrder(suite.ctx, prodId, []int{side.Id, front.Id}), storage.ErrMediaOrder)
+	suite.Require().ErrorIs(suite.repo.SetMediaOrder(suite.ctx, prodId, []int{side.Id, front.Id, front.Id}), storage.ErrMediaOrder)
+	suite.Require().ErrorIs(suite.repo.SetMediaOrder(suite.ctx, prodId, []int{side.Id, front.Id, back.Id + 100}), storage.ErrMediaOrder)
+	suite.Require().NoError(suite.repo.SetMediaOrder(suite.ctx, prodId, []int{side.Id, front.Id, back.Id}))
+	suite.Require().NoError(suite.repo.SetPrimaryMedia(suite.ctx, prodId, strconv.Itoa(back.Id)))
+	suite.Require().ErrorIs(suite.repo.SetPrimaryMedia(suite.ctx, prodId, strconv.Itoa(back.Id+100)), storage.ErrMediaNotFound)
+	product, err = suite.repo.GetProductById(suite.ctx, prodId)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]int{side.Id, front.Id, back.Id}, mediaIdsOf(product.Media))
+	suite.Require().Equal([]bool{false, false, true}, primaryOf(product.Media))
+
+	// the first media becomes primary instead of the removed one, media after it are moved up
+	removed, err := suite.repo.DeleteMediaById(suite.ctx, prodId, strconv.Itoa(back.Id))
+	suite.Require().NoError(err)
+	suite.Require().Equal("back.jpg", removed.Key)
+	suite.Require().Equal("back_thumb.jpg", removed.ThumbnailKey)
+	_, err = suite.repo.DeleteMediaById(suite.ctx, prodId, strconv.Itoa(back.Id))
+	suite.Require().ErrorIs(err, storage.ErrMediaNotFound)
+	_, err = suite.repo.DeleteMediaById(suite.ctx, prodId, strconv.Itoa(side.Id))
+	suite.Require().NoError(err)
+	product, err = suite.repo.GetProductById(suite.ctx, prodId)
+	suite.Require().NoError(err)
+	suite.Require().Len(product.Media, 1)
+	suite.Require().Equal(front.Id, product.Media[0].Id)
+	suite.Require().Equal(0, product.Media[0].Position)
+	suite.Require().True(product.Media[0].Primary)
+
+	// media of a deleted product can't be changed and are removed with the product from the trash
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, prodId, 0)))
+	_, err = suite.repo.SaveMedia(suite.ctx, prodId, newMedia("back", false))
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+	_, err = suite.repo.PurgeTrash(suite.ctx, time.Now().Add(time.Hour))
+	suite.Require().NoError(err)
+	otherId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "Warm hat"}, nil)
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveMedia(suite.ctx, otherId, newMedia("front", false))
+	suite.Require().NoError(err, "keys of purged media are free")
+}
+
+func mediaIdsOf(media []models.Media) []int {
+	ids := make([]int, 0, len(media))
+	for _, m := range media {
+		ids = append(ids, m.Id)
+	}
+	return ids
+}
+
+func primaryOf(media []models.Media) []bool {
+	primary := make([]bool, 0, len(media))
+	for _, m := range media {
+		primary = append(primary, m.Primary)
+	}
+	return primary
+}
+
+func (suite *behaviorTestSuite) Test_ProductStates() {
+	ids := suite.addCategories("first")
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt", State: models.ProductStatePublished}, []int{ids["first"]})
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProducts(suite.ctx, []models.Product{{Name: "Hat", Description: "Wool hat"}}, [][]int{{ids["first"]}}, true)
+	suite.Require().NoError(err)
+
+	// new products are drafts whatever state they are saved with
+	product, err := suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.ProductStateDraft, product.State)
+	suite.Require().Equal(1, product.Version)
+	products, err := suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{})
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 2)
+	for _, product := range products {
+		suite.Require().Equal(models.ProductStateDraft, product.State)
+	}
+
+	suite.Require().NoError(productErr(suite.repo.SetProductState(suite.ctx, shirtId, models.ProductStateDraft, models.ProductStateInReview, 1)))
+	// the version is compared by the update
+	suite.Require().ErrorIs(productErr(suite.repo.SetProductState(suite.ctx, shirtId, models.ProductStateInReview, models.ProductStatePublished, 1)), storage.ErrVersionConflict)
+	changed, err := suite.repo.SetProductState(suite.ctx, shirtId, models.ProductStateInReview, models.ProductStatePublished, 2)
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.ProductStatePublished, changed.State)
+	suite.Require().Equal(3, changed.Version)
+	// the transition starts only from the state the product is in
+	suite.Require().ErrorIs(productErr(suite.repo.SetProductState(suite.ctx, shirtId, models.ProductStateDraft, models.ProductStateInReview, 0)), storage.ErrProductState)
+	suite.Require().ErrorIs(productErr(suite.repo.SetProductState(suite.ctx, "100000", models.ProductStateDraft, models.ProductStateInReview, 0)), storage.ErrProductNotFound)
+	product, err = suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.ProductStatePublished, product.State)
+	suite.Require().Equal(3, product.Version)
+
+	published := []string{models.ProductStatePublished}
+	products, err = suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{States: published})
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 1)
+	suite.Require().Equal("Shirt", products[0].Name)
+	suite.Require().Equal(models.ProductStatePublished, products[0].State)
+	products, err = suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{States: []string{models.ProductStateDraft, models.ProductStateArchived}})
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 1)
+	suite.Require().Equal("Hat", products[0].Name)
+	products, err = suite.repo.GetProductsByCategory(suite.ctx, "first", published)
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 1)
+	suite.Require().Equal("Shirt", products[0].Name)
+	products, err = suite.repo.GetProductsByCategory(suite.ctx, "first", nil)
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 2)
+	var streamed []string
+	suite.Require().NoError(suite.repo.StreamProducts(suite.ctx, models.ProductFilter{States: published}, func(product models.Product) error {
+		streamed = append(streamed, product.Name)
+		return nil
+	}))
+	suite.Require().Equal([]string{"Shirt"}, streamed)
+
+	// deleted product keeps its state until it is restored
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 0)))
+	suite.Require().ErrorIs(productErr(suite.repo.SetProductState(suite.ctx, shirtId, models.ProductStatePublished, models.ProductStateArchived, 0)), storage.ErrProductNotFound)
+	suite.Require().NoError(suite.repo.RestoreProductById(suite.ctx, shirtId))
+	product, err = suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.ProductStatePublished, product.State)
+}
+
+func (suite *behaviorTestSuite) Test_Attributes() {
+	schema := []models.Attribute{
+		{Name: "material", Type: models.AttributeEnum, Required: true, Values: []string{"wool", "cotton"}},
+		{Name: "weight", Type: models.AttributeUnit, Unit: "g"},
+		{Name: "waterproof", Type: models.AttributeBool},
+	}
+	suite.Require().NoError(suite.repo.SaveCategory(suite.ctx, models.Category{Name: "Clothes", Code: "clothes", Description: "Clothes", Attributes: schema}))
+	ids := suite.addCategories("other")
+	category, err := suite.repo.GetCategoryByCode(suite.ctx, "clothes")
+	suite.Require().NoError(err)
+	suite.Require().Equal(schema, category.Attributes)
+	schemas, err := suite.repo.GetCategoriesAttributes(suite.ctx, []string{"clothes", "other", "missing"})
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string][]models.Attribute{"clothes": schema, "other": nil}, schemas)
+
+	// not nil attributes replace the schema, the empty one removes it
+	suite.Require().NoError(categoryErr(suite.repo.UpdateCategoryByCode(suite.ctx, "other", models.CategoryForPatch{Attributes: schema[1:]}, 0)))
+	category, err = suite.repo.GetCategoryByCode(suite.ctx, "other")
+	suite.Require().NoError(err)
+	suite.Require().Equal(schema[1:], category.Attributes)
+	suite.Require().Equal(2, category.Version)
+	suite.Require().NoError(categoryErr(suite.repo.UpdateCategoryByCode(suite.ctx, "other", models.CategoryForPatch{Attributes: []models.Attribute{}}, 0)))
+	categories, err := suite.repo.GetAllCategories(suite.ctx)
+	suite.Require().NoError(err)
+	suite.Require().Len(categories, 2)
+	suite.Require().Equal(schema, categories[0].Attributes)
+	suite.Require().Nil(categories[1].Attributes)
+
+	clothesIds, err := suite.repo.GetCategoriesIdByCodes(suite.ctx, []string{"clothes"})
+	suite.Require().NoError(err)
+	scarfId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Scarf", Description: "Long scarf",
+		Attributes: map[string]interface{}{"material": "wool", "weight": 120.5, "waterproof": false}}, clothesIds)
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt",
+		Attributes: map[string]interface{}{"material": "cotton", "weight": float64(200), "waterproof": true}}, clothesIds)
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Ball", Description: "Red ball",
+		Attributes: map[string]interface{}{"weight": "heavy"}}, []int{ids["other"]})
+	suite.Require().NoError(err)
+	product, err := suite.repo.GetProductById(suite.ctx, scarfId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]interface{}{"material": "wool", "weight": 120.5, "waterproof": false}, product.Attributes)
+
+	min, max := 100.0, 150.0
+	tests := []struct{
+		name string
+		filter []models.AttributeFilter
+		want []string
+	}{
+		{name: "no filter", want: []string{"Scarf", "Shirt", "Ball"}},
+		{name: "enum value", filter: []models.AttributeFilter{{Name: "material", Values: []string{"wool"}}}, want: []string{"Scarf"}},
+		{name: "any of values", filter: []models.AttributeFilter{{Name: "material", Values: []string{"wool", "cotton"}}}, want: []string{"Scarf", "Shirt"}},
+		{name: "bool value", filter: []models.AttributeFilter{{Name: "waterproof", Values: []string{"true"}}}, want: []string{"Shirt"}},
+		{name: "number by value", filter: []models.AttributeFilter{{Name: "weight", Values: []string{"200.0"}}}, want: []string{"Shirt"}},
+		{name: "string equal to number text", filter: []models.AttributeFilter{{Name: "weight", Values: []string{"heavy"}}}, want: []string{"Ball"}},
+		{name: "number range skips strings", filter: []models.AttributeFilter{{Name: "weight", Min: &min}}, want: []string{"Scarf", "Shirt"}},
+		{name: "all filters", filter: []models.AttributeFilter{
+			{Name: "weight", Min: &min, Max: &max},
+			{Name: "material", Values: []string{"wool"}},
+		}, want: []string{"Scarf"}},
+		{name: "missing attribute", filter: []models.AttributeFilter{{Name: "colour", Values: []string{"red"}}}, want: nil},
+	}
+	for _, tt := range tests {
+		products, err := suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{Attributes: tt.filter})
+		suite.Require().NoError(err, tt.name)
+		var names []string
+		for _, product := range products {
+			names = append(names, product.Name)
+		}
+		suite.Require().Equal(tt.want, names, tt.name)
+	}
+
+	// not nil attributes replace the values, the empty ones remove them
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, scarfId, models.ProductForPatch{Attributes: map[string]interface{}{"material": "cotton"}}, nil, 0)))
+	product, err = suite.repo.GetProductById(suite.ctx, scarfId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]interface{}{"material": "cotton"}, product.Attributes)
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, scarfId, models.ProductForPatch{Attributes: map[string]interface{}{}}, nil, 0)))
+	product, err = suite.repo.GetProductById(suite.ctx, scarfId)
+	suite.Require().NoError(err)
+	suite.Require().Nil(product.Attributes)
+}
+
+func (suite *behaviorTestSuite) Test_SaveProductsAttributes() {
+	ids := suite.addCategories("clothes")
+	options := []models.VariantOption{{Name: "size", Values: []string{"S", "M"}}}
+	attributes := map[string]interface{}{"material": "wool", "weight": 120.5, "waterproof": false}
+	results, err := suite.repo.SaveProducts(suite.ctx, []models.Product{
+		{Name: "Scarf", Description: "Long scarf", Options: options, Attributes: attributes},
+		{Name: "Hat", Description: "Wool hat"},
+	}, [][]int{{ids["clothes"]}, {ids["clothes"]}}, true)
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.ImportStatusCreated, results[0].Status)
+	suite.Require().Equal(models.ImportStatusCreated, results[1].Status)
+	// the bulk save keeps options and attributes like the save of one product
+	product, err := suite.repo.GetProductById(suite.ctx, results[0].ProductId)
+	suite.Require().NoError(err)
+	suite.Require().Equal(options, product.Options)
+	suite.Require().Equal(attributes, product.Attributes)
+	product, err = suite.repo.GetProductById(suite.ctx, results[1].ProductId)
+	suite.Require().NoError(err)
+	suite.Require().Nil(product.Options)
+	suite.Require().Nil(product.Attributes)
+	products, err := suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{Attributes: []models.AttributeFilter{{Name: "material", Values: []string{"wool"}}}})
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 1)
+	suite.Require().Equal("Scarf", products[0].Name)
+}
+
+func (suite *behaviorTestSuite) Test_Stock() {
+	suite.Require().NoError(suite.repo.SaveWarehouse(suite.ctx, models.Warehouse{Code: "main", Name: "Main"}))
+	suite.Require().NoError(suite.repo.SaveWarehouse(suite.ctx, models.Warehouse{Code: "east", Name: "East"}))
+	suite.Require().ErrorIs(suite.repo.SaveWarehouse(suite.ctx, models.Warehouse{Code: "main", Name: "Other"}), storage.ErrWarehouseExist)
+	warehouses, err := suite.repo.GetWarehouses(suite.ctx)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]models.Warehouse{{Code: "east", Name: "East"}, {Code: "main", Name: "Main"}}, warehouses)
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Shirt"}, nil)
+	suite.Require().NoError(err)
+	hatId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "Hat"}, nil)
+	suite.Require().NoError(err)
+
+	level, err := suite.repo.AdjustStock(suite.ctx, shirtId, models.StockChange{Warehouse: "main", Quantity: 10, Reason: "delivery", Actor: "a@b.com"})
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.StockLevel{Warehouse: "main", OnHand: 10, Available: 10}, level)
+	_, err = suite.repo.AdjustStock(suite.ctx, shirtId, models.StockChange{Warehouse: "west", Quantity: 1})
+	suite.Require().ErrorIs(err, storage.ErrWarehouseNotFound)
+	_, err = suite.repo.AdjustStock(suite.ctx, "0", models.StockChange{Warehouse: "main", Quantity: 1})
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+	_, err = suite.repo.AdjustStock(suite.ctx, hatId, models.StockChange{Warehouse: "east", Quantity: -1})
+	suite.Require().ErrorIs(err, storage.ErrOutOfStock, "stock can't start below zero")
+
+	// concurrent reservations take exactly the units on hand
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		reserved []models.Reservation
+		outOfStock int
+	)
+	for i := 0; i < 15; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, err := suite.repo.ReserveStock(suite.ctx, shirtId, models.StockChange{Warehouse: "main", Quantity: 1})
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, storage.ErrOutOfStock) {
+				outOfStock++
+				return
+			}
+			suite.NoError(err)
+			reserved = append(reserved, reservation)
+		}()
+	}
+	wg.Wait()
+	suite.Require().Len(reserved, 10)
+	suite.Require().Equal(5, outOfStock)
+	levels, err := suite.repo.GetStock(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]models.StockLevel{{Warehouse: "main", OnHand: 10, Reserved: 10, Available: 0}}, levels)
+	_, err = suite.repo.AdjustStock(suite.ctx, shirtId, models.StockChange{Warehouse: "main", Quantity: -1})
+	suite.Require().ErrorIs(err, storage.ErrOutOfStock, "reserved units can't be taken")
+
+	released, err := suite.repo.ReleaseReservation(suite.ctx, strconv.Itoa(reserved[0].Id), models.StockChange{Actor: "a@b.com"})
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.ReservationReleased, released.State)
+	suite.Require().NotNil(released.ClosedAt)
+	_, err = suite.repo.ReleaseReservation(suite.ctx, strconv.Itoa(reserved[0].Id), models.StockChange{})
+	suite.Require().ErrorIs(err, storage.ErrReservationClosed)
+	committed, err := suite.repo.CommitReservation(suite.ctx, strconv.Itoa(reserved[1].Id), models.StockChange{Reason: "order 1"})
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.ReservationCommitted, committed.State)
+	_, err = suite.repo.CommitReservation(suite.ctx, strconv.Itoa(reserved[1].Id), models.StockChange{})
+	suite.Require().ErrorIs(err, storage.ErrReservationClosed)
+	_, err = suite.repo.CommitReservation(suite.ctx, "100000", models.StockChange{})
+	suite.Require().ErrorIs(err, storage.ErrReservationNotFound)
+	reservation, err := suite.repo.GetReservation(suite.ctx, strconv.Itoa(reserved[1].Id))
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.ReservationCommitted, reservation.State)
+	suite.Require().Equal(shirtId, strconv.Itoa(reservation.ProductId))
+	suite.Require().Equal("main", reservation.Warehouse)
+	suite.Require().Equal(1, reservation.Quantity)
+	levels, err = suite.repo.GetStock(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]models.StockLevel{{Warehouse: "main", OnHand: 9, Reserved: 8, Available: 1}}, levels)
+
+	movements, err := suite.repo.GetStockMovements(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Len(movements, 13)
+	suite.Require().Equal(models.MovementAdjust, movements[0].Kind)
+	suite.Require().Equal(10, movements[0].OnHand)
+	suite.Require().Equal("delivery", movements[0].Reason)
+	suite.Require().Equal("a@b.com", movements[0].Actor)
+	onHand, reservedUnits := 0, 0
+	for idx, movement := range movements {
+		if idx > 0 {
+			suite.Require().Greater(movement.Id, movements[idx-1].Id)
+		}
+		onHand += movement.OnHand
+		reservedUnits += movement.Reserved
+	}
+	suite.Require().Equal(9, onHand, "the ledger sums up to the stock")
+	suite.Require().Equal(8, reservedUnits)
+	last := movements[len(movements)-1]
+	suite.Require().Equal(models.StockMovement{Id: last.Id, Warehouse: "main", Kind: models.MovementCommit, OnHand: -1, Reserved: -1,
+		ReservationId: reserved[1].Id, Reason: "order 1", CreatedAt: last.CreatedAt}, last)
+
+	_, err = suite.repo.AdjustStock(suite.ctx, hatId, models.StockChange{Warehouse: "east", Quantity: 2})
+	suite.Require().NoError(err)
+	_, err = suite.repo.ReserveStock(suite.ctx, hatId, models.StockChange{Warehouse: "east", Quantity: 2})
+	suite.Require().NoError(err)
+	inStock, notInStock := true, false
+	products, err := suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{InStock: &inStock})
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 1)
+	suite.Require().Equal("Shirt", products[0].Name)
+	products, err = suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{InStock: &notInStock})
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 1)
+	suite.Require().Equal("Hat", products[0].Name)
+
+	// stock of the deleted product can't change and is removed with the product from the trash
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 0)))
+	_, err = suite.repo.ReserveStock(suite.ctx, shirtId, models.StockChange{Warehouse: "main", Quantity: 1})
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+	_, err = suite.repo.PurgeTrash(suite.ctx, time.Now().Add(time.Hour))
+	suite.Require().NoError(err)
+	levels, err = suite.repo.GetStock(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Empty(levels)
+	movements, err = suite.repo.GetStockMovements(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Empty(movements)
+	_, err = suite.repo.GetReservation(suite.ctx, strconv.Itoa(reserved[2].Id))
+	suite.Require().ErrorIs(err, storage.ErrReservationNotFound)
+}
+
+func (suite *behaviorTestSuite) Test_Trash() {
+	ids := suite.addCategories("first", "second")
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt"}, []int{ids["first"], ids["second"]})
+	suite.Require().NoError(err)
+	hatId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "Wool hat"}, []int{ids["first"]})
+	suite.Require().NoError(err)
+
+	suite.Require().ErrorIs(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "second", 0)), storage.ErrCategoryUsed)
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 0)))
+	_, err = suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().ErrorIs(err, storage.ErrProductNotFound)
+	suite.Require().ErrorIs(productErr(suite.repo.UpdateProductById(suite.ctx, shirtId, models.ProductForPatch{Name: strPtr("Linen shirt")}, nil, 0)), storage.ErrProductNotFound)
+	products, err := suite.repo.GetProductsByCategory(suite.ctx, "second", nil)
+	suite.Require().NoError(err)
+	suite.Require().Empty(products)
+
+	// category of the deleted product may be deleted too
+	suite.Require().NoError(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "second", 0)))
+	_, err = suite.repo.GetCategoryByCode(suite.ctx, "second")
+	suite.Require().ErrorIs(err, storage.ErrQuery)
+	idsMap, err := suite.repo.GetCategoriesIdMapByCodes(suite.ctx, []string{"first", "second"})
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]int{"first": ids["first"]}, idsMap)
+	categories, err := suite.repo.GetAllCategories(suite.ctx)
+	suite.Require().NoError(err)
+	suite.Require().Len(categories, 1)
+
+	trash, err := suite.repo.GetTrash(suite.ctx)
+	suite.Require().NoError(err)
+	suite.Require().Len(trash.Products, 1)
+	suite.Require().Equal("Shirt", trash.Products[0].Name)
+	suite.Require().ElementsMatch([]string{"first", "second"}, trash.Products[0].CategoryСodes)
+	suite.Require().False(trash.Products[0].DeletedAt.IsZero())
+	suite.Require().Len(trash.Categories, 1)
+	suite.Require().Equal("second", trash.Categories[0].Code)
+
+	// links to the deleted category come back with it
+	suite.Require().NoError(suite.repo.RestoreProductById(suite.ctx, shirtId))
+	product, err := suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"first"}, product.CategoryСodes)
+	suite.Require().NoError(suite.repo.RestoreCategoryByCode(suite.ctx, "second"))
+	product, err = suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().ElementsMatch([]string{"first", "second"}, product.CategoryСodes)
+	suite.Require().ErrorIs(suite.repo.RestoreProductById(suite.ctx, shirtId), storage.ErrProductNotFound)
+	suite.Require().ErrorIs(suite.repo.RestoreProductById(suite.ctx, "100000"), storage.ErrProductNotFound)
+	suite.Require().ErrorIs(suite.repo.RestoreCategoryByCode(suite.ctx, "second"), storage.ErrCategoryNotFound)
+
+	// names and codes of deleted rows may be taken again
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, hatId, 0)))
+	_, err = suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Hat", Description: "New hat"}, nil)
+	suite.Require().NoError(err)
+	suite.Require().ErrorIs(suite.repo.RestoreProductById(suite.ctx, hatId), storage.ErrProductExist)
+	suite.addCategories("third")
+	suite.Require().NoError(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "third", 0)))
+	suite.addCategories("third")
+	suite.Require().ErrorIs(suite.repo.RestoreCategoryByCode(suite.ctx, "third"), storage.ErrCategoryExist)
+
+	// purged category is unlinked from the restored product
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 0)))
+	suite.Require().NoError(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "second", 0)))
+	suite.Require().NoError(suite.repo.RestoreProductById(suite.ctx, shirtId))
+
+	result, err := suite.repo.PurgeTrash(suite.ctx, time.Now().Add(-time.Hour))
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.PurgeResult{}, result)
+	result, err = suite.repo.PurgeTrash(suite.ctx, time.Now().Add(time.Hour))
+	suite.Require().NoError(err)
+	suite.Require().Equal(models.PurgeResult{Products: 1, Categories: 2}, result)
+	trash, err = suite.repo.GetTrash(suite.ctx)
+	suite.Require().NoError(err)
+	suite.Require().Empty(trash.Products)
+	suite.Require().Empty(trash.Categories)
+	suite.Require().ErrorIs(suite.repo.RestoreCategoryByCode(suite.ctx, "second"), storage.ErrCategoryNotFound)
+	product, err = suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"first"}, product.CategoryСodes)
+}
+
+func (suite *behaviorTestSuite) Test_Versions() {
+	versions, err := suite.repo.GetVersions(suite.ctx, models.VersionEntityProduct, "1")
+	suite.Require().NoError(err)
+	suite.Require().Empty(versions)
+
+	suite.Require().NoError(suite.repo.SaveVersions(suite.ctx, nil))
+	suite.Require().NoError(suite.repo.SaveVersions(suite.ctx, []models.Version{
+		{
+			Entity: models.VersionEntityProduct,
+			Key: "1",
+			Action: models.VersionActionCreate,
+			Actor: "test@test.test",
+			Snapshot: []byte(`{"id": 1, "name": "Shirt"}`),
+		},
+		{
+			Entity: models.VersionEntityProduct,
+			Key: "2",
+			Action: models.VersionActionCreate,
+			Snapshot: []byte(`{"id": 2, "name": "Hat"}`),
+		},
+		{
+			Entity: models.VersionEntityCategory,
+			Key: "1",
+			Action: models.VersionActionCreate,
+			Snapshot: []byte(`{"code": "1"}`),
+		},
+	}))
+	suite.Require().NoError(suite.repo.SaveVersions(suite.ctx, []models.Version{
+		{
+			Entity: models.VersionEntityProduct,
+			Key: "1",
+			Action: models.VersionActionRevert,
+			Actor: "other@test.test",
+			RevertedTo: 1,
+			Snapshot: []byte(`{"id": 1, "name": "Linen shirt"}`),
+		},
+	}))
+
+	versions, err = suite.repo.GetVersions(suite.ctx, models.VersionEntityProduct, "1")
+	suite.Require().NoError(err)
+	suite.Require().Len(versions, 2)
+	suite.Require().Equal(1, versions[0].Version)
+	suite.Require().Equal(models.VersionActionCreate, versions[0].Action)
+	suite.Require().Equal("test@test.test", versions[0].Actor)
+	suite.Require().Zero(versions[0].RevertedTo)
+	suite.Require().False(versions[0].CreatedAt.IsZero())
+	suite.Require().JSONEq(`{"id": 1, "name": "Shirt"}`, string(versions[0].Snapshot))
+	suite.Require().Equal(2, versions[1].Version)
+	suite.Require().Equal(1, versions[1].RevertedTo)
+
+	version, err := suite.repo.GetVersion(suite.ctx, models.VersionEntityProduct, "1", 2)
+	suite.Require().NoError(err)
+	suite.Require().Equal(versions[1], version)
+	version, err = suite.repo.GetVersion(suite.ctx, models.VersionEntityCategory, "1", 1)
+	suite.Require().NoError(err)
+	suite.Require().JSONEq(`{"code": "1"}`, string(version.Snapshot))
+	_, err = suite.repo.GetVersion(suite.ctx, models.VersionEntityProduct, "1", 3)
+	suite.Require().ErrorIs(err, storage.ErrVersionNotFound)
+	_, err = suite.repo.GetVersion(suite.ctx, models.VersionEntityCategory, "2", 1)
+	suite.Require().ErrorIs(err, storage.ErrVersionNotFound)
+}
+
+func (suite *behaviorTestSuite) Test_Outbox() {
+	ids := suite.addCategories("first")
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt"}, []int{ids["first"]})
+	suite.Require().NoError(err)
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, shirtId, models.ProductForPatch{Name: strPtr("Linen shirt")}, nil, 0)))
+	suite.Require().ErrorIs(productErr(suite.repo.UpdateProductById(suite.ctx, shirtId, models.ProductForPatch{Name: strPtr("Wool shirt")}, nil, 1)), storage.ErrVersionConflict)
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 0)))
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 0)))
+	suite.Require().NoError(suite.repo.RestoreProductById(suite.ctx, shirtId))
+	suite.Require().NoError(categoryErr(suite.repo.UpdateCategoryByCode(suite.ctx, "first", models.CategoryForPatch{Code: strPtr("renamed")}, 0)))
+	_, err = suite.repo.InserOrGetCategiriesId(suite.ctx, []models.Category{
+		{Name: "renamed", Code: "renamed", Description: "renamed"},
+		{Name: "second", Code: "second", Description: "second"},
+	})
+	suite.Require().NoError(err)
+	_, err = suite.repo.SaveProducts(suite.ctx, []models.Product{
+		{Name: "Hat", Description: "Wool hat"},
+		{Name: "Cap", Description: "Cotton cap"},
+	}, [][]int{nil, {ids["first"] + 100}}, true)
+	suite.Require().NoError(err)
+	suite.Require().NoError(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "second", 0)))
+
+	events := suite.pendingEvents(100)
+	types := make([]string, len(events))
+	for idx, event := range events {
+		types[idx] = event.Type
+		suite.Require().False(event.CreatedAt.IsZero())
+		if idx > 0 {
+			suite.Require().Greater(event.Id, events[idx-1].Id)
+		}
+	}
+	suite.Require().Equal([]string{
+		models.EventCategoryCreated,
+		models.EventProductCreated,
+		models.EventProductUpdated,
+		models.EventProductDeleted,
+		models.EventProductRestored,
+		models.EventCategoryUpdated,
+		models.EventCategoryCreated,
+		models.EventCategoryDeleted,
+	}, types)
+	suite.Require().Equal(models.EventAggregateProduct, events[2].Aggregate)
+	suite.Require().Equal(shirtId, events[2].AggregateId)
+	suite.Require().JSONEq(`{"id": "`+shirtId+`", "name": "Linen shirt", "description": "Cotton shirt", "state": "draft", "version": 2}`, string(events[2].Payload))
+	suite.Require().Equal(models.EventAggregateCategory, events[5].Aggregate)
+	suite.Require().Equal(events[0].AggregateId, events[5].AggregateId)
+	suite.Require().JSONEq(`{"name": "first", "code": "renamed", "description": "first", "version": 2}`, string(events[5].Payload))
+
+	// only published events are removed, the rest are passed again from the oldest
+	suite.Require().NoError(suite.repo.RelayEvents(suite.ctx, 2, time.Minute, func(batch []models.Event) models.RelayResult {
+		suite.Require().Len(batch, 2)
+		return models.RelayResult{Published: []int{batch[0].Id}}
+	}))
+	suite.Require().Equal(events[1:], suite.pendingEvents(100))
+	suite.Require().NoError(suite.repo.RelayEvents(suite.ctx, 100, time.Minute, func(batch []models.Event) models.RelayResult {
+		published := make([]int, len(batch))
+		for idx, event := range batch {
+			published[idx] = event.Id
+		}
+		return models.RelayResult{Published: published}
+	}))
+	suite.Require().Empty(suite.pendingEvents(100))
+}
+
+func (suite *behaviorTestSuite) Test_OutboxAttempts() {
+	suite.addCategories("first")
+	shirtId, err := suite.repo.SaveProduct(suite.ctx, models.Product{Name: "Shirt", Description: "Cotton shirt"}, nil)
+	suite.Require().NoError(err)
+	suite.Require().NoError(productErr(suite.repo.UpdateProductById(suite.ctx, shirtId, models.ProductForPatch{Name: strPtr("Linen shirt")}, nil, 0)))
+	events := suite.pendingEvents(100)
+	suite.Require().Len(events, 3)
+
+	// failed events are passed again with the attempt counted
+	suite.Require().NoError(suite.repo.RelayEvents(suite.ctx, 100, time.Minute, func(batch []models.Event) models.RelayResult {
+		return models.RelayResult{Published: []int{batch[0].Id}, Failed: []int{batch[1].Id}}
+	}))
+	pending := suite.pendingEvents(100)
+	suite.Require().Len(pending, 2)
+	suite.Require().Equal(events[1].Id, pending[0].Id)
+	suite.Require().Equal(1, pending[0].Attempts)
+	suite.Require().Equal(0, pending[1].Attempts)
+
+	// dead events are not passed again and don't hold back later events of their aggregate
+	suite.Require().NoError(suite.repo.RelayEvents(suite.ctx, 100, time.Minute, func(batch []models.Event) models.RelayResult {
+		return models.RelayResult{Dead: []int{batch[0].Id}}
+	}))
+	pending = suite.pendingEvents(100)
+	suite.Require().Len(pending, 1)
+	suite.Require().Equal(events[2].Id, pending[0].Id)
+}
+
+func (suite *behaviorTestSuite) Test_WithinTx() {
+	ids := suite.addCategories("first")
+	errAbort := errors.New("abort")
+
+	var shirtId string
+	err := suite.repo.WithinTx(suite.ctx, func(ctx context.Context) error {
+		catIds, err := suite.repo.GetCategoriesIdByCodes(ctx, []string{"first"})
+		if err != nil {
+			return err
+		}
+		shirtId, err = suite.repo.SaveProduct(ctx, models.Product{Name: "Shirt", Description: "Cotton shirt"}, catIds)
+		if err != nil {
+			return err
+		}
+		// the change is seen inside the transaction
+		product, err := suite.repo.GetProductById(ctx, shirtId)
+		suite.Require().NoError(err)
+		suite.Require().Equal([]string{"first"}, product.CategoryСodes)
+		return nil
+	})
+	suite.Require().NoError(err)
+	product, err := suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal("Shirt", product.Name)
+	events := suite.pendingEvents(100)
+
+	// error of fn rolls back every call, nested WithinTx joins the outer one
+	err = suite.repo.WithinTx(suite.ctx, func(ctx context.Context) error {
+		if err := productErr(suite.repo.UpdateProductById(ctx, shirtId, models.ProductForPatch{Name: strPtr("Linen shirt")}, nil, 0)); err != nil {
+			return err
+		}
+		return suite.repo.WithinTx(ctx, func(ctx context.Context) error {
+			if _, err := suite.repo.SaveProduct(ctx, models.Product{Name: "Hat", Description: "Wool hat"}, []int{ids["first"]}); err != nil {
+				return err
+			}
+			if err := categoryErr(suite.repo.DeleteCategoryBycode(ctx, "first", 0)); !errors.Is(err, storage.ErrCategoryUsed) {
+				return err
+			}
+			return errAbort
+		})
+	})
+	suite.Require().ErrorIs(err, errAbort)
+	product, err = suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+	suite.Require().Equal("Shirt", product.Name)
+	suite.Require().Equal(1, product.Version)
+	products, err := suite.repo.GetAllProducts(suite.ctx, models.ProductFilter{})
+	suite.Require().NoError(err)
+	suite.Require().Len(products, 1)
+	suite.Require().Equal(events, suite.pendingEvents(100))
+
+	// panic of fn rolls back too and goes on
+	suite.Require().PanicsWithValue("boom", func() {
+		suite.repo.WithinTx(suite.ctx, func(ctx context.Context) error {
+			if err := productErr(suite.repo.DeleteProductById(ctx, shirtId, 0)); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	})
+	_, err = suite.repo.GetProductById(suite.ctx, shirtId)
+	suite.Require().NoError(err)
+
+	// the provider is usable after the rollbacks
+	suite.Require().NoError(productErr(suite.repo.DeleteProductById(suite.ctx, shirtId, 0)))
+	suite.Require().NoError(categoryErr(suite.repo.DeleteCategoryBycode(suite.ctx, "first", 0)))
+}
+
+// pendingEvents returns up to limit events of the outbox without removing them
+func (suite *behaviorTestSuite) pendingEvents(limit int) []models.Event {
+	var events []models.Event
+	suite.Require().NoError(suite.repo.RelayEvents(suite.ctx, limit, time.Minute, func(batch []models.Event) models.RelayResult {
+		events = batch
+		return models.RelayResult{}
+	}))
+	return events
+}
+
+func statuses(results []models.ImportRowResult) []string {
+	out := make([]string, len(results))
+	for idx, result := range results {
+		out[idx] = result.Status
+	}
+	return out
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// productErr drops the product returned by a change when only its error is checked
+func productErr(_ models.Product, err error) error {
+	return err
+}
+
+// categoryErr drops the category returned by a change when only its error is checked
+func categoryErr(_ models.Category, err error) error {
+	return err
+}
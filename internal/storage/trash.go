@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// GetTrash returns deleted products with codes of all their categories and deleted categories
+func (pp *postgresProvider) GetTrash(ctx context.Context) (models.Trash, error) {
+	var out models.Trash
+	err := pp.read(ctx, func(db *retryDB) error {
+		var err error
+		out, err = pp.getTrash(ctx, db)
+		return err
+	})
+	return out, err
+}
+
+func (pp *postgresProvider) getTrash(ctx context.Context, db *retryDB) (models.Trash, error) {
+	rows, err := db.Query(ctx, fmt.Sprintf(`
+SELECT p.product_id, p.name, p.description,
+CASE
+	WHEN COUNT(pc.category_id) = 0 THEN NULL
+	ELSE array_agg(c.code)
+END as category_codes,
+p.deleted_at
+FROM "%s" as p
+LEFT JOIN "%s" as pc ON pc.product_id = p.product_id
+LEFT JOIN "%s" as c ON c.category_id = pc.category_id
+WHERE p.deleted_at IS NOT NULL
+GROUP BY p.product_id
+ORDER BY p.deleted_at DESC, p.product_id DESC`,
+	pp.cfg.ProductTable,
+	pp.cfg.ProductCategoryTable,
+	pp.cfg.CatogoryTable))
+	if err != nil {
+		return models.Trash{}, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	trash := models.Trash{
+		Products: []models.TrashProduct{},
+		Categories: []models.TrashCategory{},
+	}
+	for rows.Next() {
+		var product models.TrashProduct
+		err := rows.Scan(&product.Id, &product.Name, &product.Description, &product.CategoryСodes, &product.DeletedAt)
+		if err != nil {
+			return models.Trash{}, dbErr(ErrQuery, err)
+		}
+		trash.Products = append(trash.Products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Trash{}, dbErr(ErrQuery, err)
+	}
+	rows, err = db.Query(ctx, fmt.Sprintf(`
+SELECT "name", "code", "description", deleted_at
+FROM "%s"
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, category_id DESC`,
+	pp.cfg.CatogoryTable))
+	if err != nil {
+		return models.Trash{}, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var category models.TrashCategory
+		if err := rows.Scan(&category.Name, &category.Code, &category.Description, &category.DeletedAt); err != nil {
+			return models.Trash{}, dbErr(ErrQuery, err)
+		}
+		trash.Categories = append(trash.Categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Trash{}, dbErr(ErrQuery, err)
+	}
+	return trash, nil
+}
+
+// RestoreProductById takes the product out of the trash with its category links.
+// Returns ErrProductExist or ErrSkuExist if its name or sku is taken by another product
+func (pp *postgresProvider) RestoreProductById(ctx context.Context, prodId string) error {
+	tag, err := pp.dbConn.Exec(ctx, fmt.Sprintf(`
+WITH restored AS (
+	UPDATE "%s" SET deleted_at = NULL
+	WHERE product_id = $1 AND deleted_at IS NOT NULL
+	RETURNING *
+)%s`,
+	pp.cfg.ProductTable,
+	pp.productEvent(models.EventProductRestored, "restored as p")),
+	prodId)
+	if err != nil {
+		if existErr := pp.productExistErr(err); existErr != nil {
+			return existErr
+		}
+		return dbErr(ErrQuery, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+// RestoreCategoryByCode takes the last deleted category with the code out of the trash.
+// Returns ErrCategoryExist if the code is taken by another category
+func (pp *postgresProvider) RestoreCategoryByCode(ctx context.Context, catCode string) error {
+	tag, err := pp.dbConn.Exec(ctx, fmt.Sprintf(`
+WITH restored AS (
+	UPDATE "%s" SET deleted_at = NULL
+	WHERE category_id = (
+		SELECT category_id FROM "%s"
+		WHERE "code" = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, category_id DESC
+		LIMIT 1
+	)
+	RETURNING *
+)%s`,
+	pp.cfg.CatogoryTable,
+	pp.cfg.CatogoryTable,
+	pp.categoryEvent(models.EventCategoryRestored, "restored as c")),
+	catCode)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23505" {
+				return ErrCategoryExist
+			}
+		}
+		return dbErr(ErrQuery, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// PurgeTrash removes products and categories deleted before the time for good.
+// Links of restored products to purged categories are removed too
+func (pp *postgresProvider) PurgeTrash(ctx context.Context, before time.Time) (models.PurgeResult, error) {
+	var result models.PurgeResult
+	err := pp.dbConn.retry(ctx, func() error {
+		var err error
+		result, err = pp.purgeTrash(ctx, before)
+		return err
+	})
+	return result, err
+}
+
+func (pp *postgresProvider) purgeTrash(ctx context.Context, before time.Time) (models.PurgeResult, error) {
+	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.PurgeResult{}, dbErr(ErrStartTx, err)
+	}
+	var result models.PurgeResult
+	queries := []struct{
+		sql string
+		count *int
+	}{
+		{
+			sql: fmt.Sprintf(`DELETE FROM "%s" WHERE deleted_at < $1`, pp.cfg.ProductTable),
+			count: &result.Products,
+		},
+		{
+			sql: fmt.Sprintf(`
+DELETE FROM "%s"
+WHERE category_id IN (SELECT category_id FROM "%s" WHERE deleted_at < $1)`,
+			pp.cfg.ProductCategoryTable,
+			pp.cfg.CatogoryTable),
+		},
+		{
+			sql: fmt.Sprintf(`DELETE FROM "%s" WHERE deleted_at < $1`, pp.cfg.CatogoryTable),
+			count: &result.Categories,
+		},
+	}
+	for _, query := range queries {
+		tag, err := transaction.Exec(ctx, query.sql, before)
+		if err != nil {
+			if err := transaction.Rollback(ctx); err != nil {
+				return models.PurgeResult{}, dbErr(ErrRollbackTx, err)
+			}
+			return models.PurgeResult{}, dbErr(ErrQuery, err)
+		}
+		if query.count != nil {
+			*query.count = int(tag.RowsAffected())
+		}
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return models.PurgeResult{}, dbErr(ErrCommitTx, err)
+	}
+	return result, nil
+}
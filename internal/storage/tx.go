@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+type txKey struct{}
+
+// ctxTx is the transaction of WithinTx, db is the connection the transaction belongs to
+type ctxTx struct {
+	db *retryDB
+	tx pgx.Tx
+}
+
+// querier runs statements on the pool or on the transaction of the context
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// WithinTx runs fn in one transaction carried by the context passed to fn. Every call of the provider
+// made with this context joins the transaction, transactions of the calls become savepoints.
+// The transaction is committed if fn returns nil and rolled back if it returns an error or panics.
+// Error of any call inside fn aborts the transaction, so fn has to return it.
+// fn is called again if the transaction fails with a transient error, it must not use ctx concurrently.
+// Nested WithinTx joins the outer transaction
+func (pp *postgresProvider) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFrom(ctx, pp.dbConn) != nil {
+		return fn(ctx)
+	}
+	return pp.dbConn.retry(ctx, func() error {
+		return pp.withinTx(ctx, fn)
+	})
+}
+
+func (pp *postgresProvider) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	transaction, err := pp.dbConn.begin(ctx)
+	if err != nil {
+		return dbErr(ErrStartTx, err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			transaction.Rollback(ctx)
+			panic(p)
+		}
+	}()
+	if err := fn(context.WithValue(ctx, txKey{}, &ctxTx{db: pp.dbConn, tx: transaction})); err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return dbErr(ErrRollbackTx, err)
+		}
+		return err
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return dbErr(ErrCommitTx, err)
+	}
+	return nil
+}
+
+// txFrom returns the transaction of WithinTx if ctx carries one of db
+func txFrom(ctx context.Context, db *retryDB) pgx.Tx {
+	if current, ok := ctx.Value(txKey{}).(*ctxTx); ok && current.db == db {
+		return current.tx
+	}
+	return nil
+}
@@ -21,7 +21,7 @@ VALUES($1,$2);`, pp.cfg.UserTable), email, passHash)
 			return ErrUserExist
 		}
 	}
-	return ErrQuery
+	return dbErr(ErrQuery, err)
 }
 
 func (pp *postgresProvider) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
@@ -40,7 +40,7 @@ WHERE "email"=$1;`,
 	)
 	err := row.Scan(&user.Email, &user.PassHash, &refHash, &expiresAt)
 	if err != nil {
-		return models.User{}, ErrQuery
+		return models.User{}, dbErr(ErrQuery, err)
 	}
 	if refHash != nil {
 		user.RefreshHash = *refHash
@@ -62,7 +62,7 @@ WHERE "email" = $3`,
 	email,
 	)
 	if err != nil {
-		return ErrQuery
+		return dbErr(ErrQuery, err)
 	}
 	return nil
 }
\ No newline at end of file
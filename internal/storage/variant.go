@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// productVariants returns the columns with options of the product p and its variants as json array,
+// both are NULL if the product has none
+func (pp *postgresProvider) productVariants() string {
+	return fmt.Sprintf(`p.options, (
+	SELECT json_agg(json_build_object(
+		'id', v.variant_id, 'sku', v.sku, 'options', v.options, 'prices', v.prices
+	) ORDER BY v.variant_id)
+	FROM "%s" as v
+	WHERE v.product_id = p.product_id
+) as variants`,
+	pp.cfg.VariantTable)
+}
+
+// jsonb returns value as json text for a jsonb column, empty value is saved as NULL
+func jsonb(value interface{}, empty bool) (interface{}, error) {
+	if empty {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// variantExistErr tells which unique column of the variant is taken by the error,
+// it is nil if the error is not a unique violation
+func (pp *postgresProvider) variantExistErr(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return nil
+	}
+	if pgErr.ConstraintName == pp.cfg.VariantTable + "_sku_key" {
+		return ErrVariantSkuExist
+	}
+	return ErrVariantExist
+}
+
+// SaveVariant adds the variant to the not deleted product and counts the version of the product
+func (pp *postgresProvider) SaveVariant(ctx context.Context, prodId string, variant models.Variant) (string, error) {
+	var id string
+	err := pp.dbConn.retry(ctx, func() error {
+		var err error
+		id, err = pp.saveVariant(ctx, prodId, variant)
+		return err
+	})
+	return id, err
+}
+
+func (pp *postgresProvider) saveVariant(ctx context.Context, prodId string, variant models.Variant) (string, error) {
+	options, prices, err := variantColumns(variant)
+	if err != nil {
+		return "", dbErr(ErrQuery, err)
+	}
+	var id int
+	err = pp.changeProduct(ctx, prodId, func(transaction pgx.Tx) error {
+		err := transaction.QueryRow(ctx, fmt.Sprintf(`
+INSERT INTO "%s" (product_id, sku, options, prices)
+VALUES ($1, $2, $3, $4)
+RETURNING variant_id`,
+		pp.cfg.VariantTable),
+		prodId,
+		variant.Sku,
+		options,
+		prices).Scan(&id)
+		if err != nil {
+			if existErr := pp.variantExistErr(err); existErr != nil {
+				return existErr
+			}
+			return dbErr(ErrQuery, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(id), nil
+}
+
+// UpdateVariantById replaces sku, options and prices of the variant of the not deleted product
+// and counts the version of the product
+func (pp *postgresProvider) UpdateVariantById(ctx context.Context, prodId, variantId string, variant models.Variant) error {
+	options, prices, err := variantColumns(variant)
+	if err != nil {
+		return dbErr(ErrQuery, err)
+	}
+	return pp.dbConn.retry(ctx, func() error {
+		return pp.changeProduct(ctx, prodId, func(transaction pgx.Tx) error {
+			tag, err := transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET sku = $3, options = $4, prices = $5
+WHERE variant_id = $1 AND product_id = $2`,
+			pp.cfg.VariantTable),
+			variantId,
+			prodId,
+			variant.Sku,
+			options,
+			prices)
+			if err != nil {
+				if existErr := pp.variantExistErr(err); existErr != nil {
+					return existErr
+				}
+				return dbErr(ErrQuery, err)
+			}
+			if tag.RowsAffected() == 0 {
+				return ErrVariantNotFound
+			}
+			return nil
+		})
+	})
+}
+
+// DeleteVariantById removes the variant of the not deleted product and counts the version of the product
+func (pp *postgresProvider) DeleteVariantById(ctx context.Context, prodId, variantId string) error {
+	return pp.dbConn.retry(ctx, func() error {
+		return pp.changeProduct(ctx, prodId, func(transaction pgx.Tx) error {
+			tag, err := transaction.Exec(ctx, fmt.Sprintf(`
+DELETE FROM "%s" WHERE variant_id = $1 AND product_id = $2`,
+			pp.cfg.VariantTable),
+			variantId,
+			prodId)
+			if err != nil {
+				return dbErr(ErrQuery, err)
+			}
+			if tag.RowsAffected() == 0 {
+				return ErrVariantNotFound
+			}
+			return nil
+		})
+	})
+}
+
+// changeProduct runs fn in the transaction after the version of the product is counted,
+// so the product row is locked and the change is seen by If-Match of the product.
+// The update event of the product is written after fn
+func (pp *postgresProvider) changeProduct(ctx context.Context, prodId string, fn func(transaction pgx.Tx) error) error {
+	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return dbErr(ErrStartTx, err)
+	}
+	tag, err := transaction.Exec(ctx, fmt.Sprintf(`
+UPDATE "%s" SET version = version + 1
+WHERE product_id = $1 AND deleted_at IS NULL`,
+	pp.cfg.ProductTable),
+	prodId)
+	if err == nil && tag.RowsAffected() == 0 {
+		err = ErrProductNotFound
+	} else if err != nil {
+		err = dbErr(ErrQuery, err)
+	}
+	if err == nil {
+		err = fn(transaction)
+	}
+	if err == nil {
+		if _, err = transaction.Exec(ctx, pp.productEventById(models.EventProductUpdated), prodId); err != nil {
+			err = dbErr(ErrQuery, err)
+		}
+	}
+	if err != nil {
+		if err := transaction.Rollback(ctx); err != nil {
+			return dbErr(ErrRollbackTx, err)
+		}
+		return err
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return dbErr(ErrCommitTx, err)
+	}
+	return nil
+}
+
+// variantColumns returns options and prices of the variant for jsonb columns
+func variantColumns(variant models.Variant) (interface{}, interface{}, error) {
+	options, err := jsonb(variant.Options, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	prices, err := jsonb(variant.Prices, len(variant.Prices) == 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return options, prices, nil
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// SaveVersions appends versions to the history of their products and categories,
+// every version gets the next number of its history
+func (pp *postgresProvider) SaveVersions(ctx context.Context, versions []models.Version) error {
+	if len(versions) == 0 {
+		return nil
+	}
+	return pp.dbConn.retry(ctx, func() error {
+		return pp.saveVersions(ctx, versions)
+	})
+}
+
+func (pp *postgresProvider) saveVersions(ctx context.Context, versions []models.Version) error {
+	transaction, err := pp.dbConn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return dbErr(ErrStartTx, err)
+	}
+	query := fmt.Sprintf(`
+INSERT INTO "%s" (entity, entity_key, version, action, actor, reverted_to, snapshot)
+SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, NULLIF($5::integer, 0), $6::jsonb
+FROM "%s"
+WHERE entity = $1 AND entity_key = $2`,
+	pp.cfg.VersionTable,
+	pp.cfg.VersionTable)
+	for _, version := range versions {
+		_, err := transaction.Exec(ctx, query,
+			version.Entity,
+			version.Key,
+			version.Action,
+			version.Actor,
+			version.RevertedTo,
+			string(version.Snapshot))
+		if err != nil {
+			if err := transaction.Rollback(ctx); err != nil {
+				return dbErr(ErrRollbackTx, err)
+			}
+			return dbErr(ErrQuery, err)
+		}
+	}
+	if err := transaction.Commit(ctx); err != nil {
+		return dbErr(ErrCommitTx, err)
+	}
+	return nil
+}
+
+// GetVersions returns the history of the product or category from the first version
+func (pp *postgresProvider) GetVersions(ctx context.Context, entity, key string) ([]models.Version, error) {
+	var out []models.Version
+	err := pp.read(ctx, func(db *retryDB) error {
+		var err error
+		out, err = pp.getVersions(ctx, db, entity, key)
+		return err
+	})
+	return out, err
+}
+
+func (pp *postgresProvider) getVersions(ctx context.Context, db *retryDB, entity, key string) ([]models.Version, error) {
+	rows, err := db.Query(ctx, fmt.Sprintf(`
+SELECT version, action, COALESCE(actor, ''), COALESCE(reverted_to, 0), created_at, snapshot::text
+FROM "%s"
+WHERE entity = $1 AND entity_key = $2
+ORDER BY version`,
+	pp.cfg.VersionTable),
+	entity,
+	key)
+	if err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	defer rows.Close()
+	versions := []models.Version{}
+	for rows.Next() {
+		version := models.Version{
+			Entity: entity,
+			Key: key,
+		}
+		var snapshot string
+		if err := rows.Scan(&version.Version, &version.Action, &version.Actor, &version.RevertedTo, &version.CreatedAt, &snapshot); err != nil {
+			return nil, dbErr(ErrQuery, err)
+		}
+		version.Snapshot = []byte(snapshot)
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbErr(ErrQuery, err)
+	}
+	return versions, nil
+}
+
+// GetVersion returns ErrVersionNotFound if the history has no such version
+func (pp *postgresProvider) GetVersion(ctx context.Context, entity, key string, number int) (models.Version, error) {
+	var out models.Version
+	err := pp.read(ctx, func(db *retryDB) error {
+		var err error
+		out, err = pp.getVersion(ctx, db, entity, key, number)
+		return err
+	})
+	return out, err
+}
+
+func (pp *postgresProvider) getVersion(ctx context.Context, db *retryDB, entity, key string, number int) (models.Version, error) {
+	row := db.QueryRow(ctx, fmt.Sprintf(`
+SELECT version, action, COALESCE(actor, ''), COALESCE(reverted_to, 0), created_at, snapshot::text
+FROM "%s"
+WHERE entity = $1 AND entity_key = $2 AND version = $3`,
+	pp.cfg.VersionTable),
+	entity,
+	key,
+	number)
+	version := models.Version{
+		Entity: entity,
+		Key: key,
+	}
+	var snapshot string
+	if err := row.Scan(&version.Version, &version.Action, &version.Actor, &version.RevertedTo, &version.CreatedAt, &snapshot); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Version{}, ErrVersionNotFound
+		}
+		return models.Version{}, dbErr(ErrQuery, err)
+	}
+	version.Snapshot = []byte(snapshot)
+	return version, nil
+}
@@ -0,0 +1,148 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+var (
+	// attributeNameRegex keeps names usable as keys of listing filters like attr.<name>
+	attributeNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+	unitRegex = regexp.MustCompile(`^[\p{L}\p{N}%°/.²³]{1,16}$`)
+)
+
+const (
+	maxAttributes      = 50
+	maxAttributeValues = 200
+	maxAttributeText   = 1000
+)
+
+// ValidAttributeName reports whether name can be a name of an attribute
+func ValidAttributeName(name string) bool {
+	return attributeNameRegex.MatchString(name)
+}
+
+// AttributesInvalidReason returns the reason why the attribute schema of a category is invalid or empty string
+func AttributesInvalidReason(attributes []models.Attribute) string {
+	if len(attributes) > maxAttributes {
+		return fmt.Sprintf("more than %d attributes", maxAttributes)
+	}
+	for idx, attribute := range attributes {
+		if !ValidAttributeName(attribute.Name) {
+			return fmt.Sprintf("incorrect name of attribute %d", idx+1)
+		}
+		for otherIdx, other := range attributes[:idx] {
+			if other.Name == attribute.Name {
+				return fmt.Sprintf("attribute %d repeats attribute %d", idx+1, otherIdx+1)
+			}
+		}
+		switch attribute.Type {
+		case models.AttributeString, models.AttributeEnum:
+			if attribute.Type == models.AttributeEnum && len(attribute.Values) == 0 || len(attribute.Values) > maxAttributeValues {
+				return fmt.Sprintf("attribute %d must have from 1 to %d values", idx+1, maxAttributeValues)
+			}
+		case models.AttributeNumber, models.AttributeBool, models.AttributeUnit:
+			if len(attribute.Values) != 0 {
+				return fmt.Sprintf("attribute %d of type %s can't have values", idx+1, attribute.Type)
+			}
+		default:
+			return fmt.Sprintf("unknown type of attribute %d", idx+1)
+		}
+		if attribute.Type == models.AttributeUnit && !unitRegex.MatchString(attribute.Unit) {
+			return fmt.Sprintf("attribute %d must have a unit", idx+1)
+		}
+		if attribute.Type != models.AttributeUnit && attribute.Unit != "" {
+			return fmt.Sprintf("attribute %d of type %s can't have a unit", idx+1, attribute.Type)
+		}
+		for valueIdx, value := range attribute.Values {
+			if !validText(value) {
+				return fmt.Sprintf("incorrect value %d of attribute %d", valueIdx+1, idx+1)
+			}
+			for otherIdx, other := range attribute.Values[:valueIdx] {
+				if other == value {
+					return fmt.Sprintf("value %d of attribute %d repeats value %d", valueIdx+1, idx+1, otherIdx+1)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// AttributeIssues checks attribute values of a product in the categories with the codes against schemas of the categories.
+// Every required attribute must have a value, every value must fit all the categories declaring its attribute
+// and must be declared by one of them. Issues are ordered by the categories, values not declared are the last
+func AttributeIssues(schemas map[string][]models.Attribute, codes []string, values map[string]interface{}) []models.AttributeIssue {
+	var issues []models.AttributeIssue
+	declared := make(map[string]struct{})
+	for _, code := range codes {
+		for _, attribute := range schemas[code] {
+			declared[attribute.Name] = struct{}{}
+			value, ok := values[attribute.Name]
+			if !ok {
+				if attribute.Required {
+					issues = append(issues, models.AttributeIssue{Attribute: attribute.Name, Category: code, Reason: "value is required"})
+				}
+				continue
+			}
+			if reason := attributeValueReason(attribute, value); reason != "" {
+				issues = append(issues, models.AttributeIssue{Attribute: attribute.Name, Category: code, Reason: reason})
+			}
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := declared[name]; !ok {
+			issues = append(issues, models.AttributeIssue{Attribute: name, Reason: "attribute is not declared by categories of the product"})
+		}
+	}
+	return issues
+}
+
+// attributeValueReason returns the reason why the value doesn't fit the attribute or empty string,
+// values are read from json so numbers are float64
+func attributeValueReason(attribute models.Attribute, value interface{}) string {
+	switch attribute.Type {
+	case models.AttributeString, models.AttributeEnum:
+		text, ok := value.(string)
+		if !ok {
+			return "value must be a string"
+		}
+		if !validText(text) {
+			return fmt.Sprintf("value must have from 1 to %d characters without spaces around", maxAttributeText)
+		}
+		if len(attribute.Values) == 0 {
+			return ""
+		}
+		for _, allowed := range attribute.Values {
+			if allowed == text {
+				return ""
+			}
+		}
+		return fmt.Sprintf("value %q is not allowed", text)
+	case models.AttributeNumber, models.AttributeUnit:
+		if _, ok := value.(float64); !ok {
+			if attribute.Type == models.AttributeUnit {
+				return fmt.Sprintf("value must be a number of %s", attribute.Unit)
+			}
+			return "value must be a number"
+		}
+	case models.AttributeBool:
+		if _, ok := value.(bool); !ok {
+			return "value must be true or false"
+		}
+	}
+	return ""
+}
+
+func validText(text string) bool {
+	return text != "" && text == strings.TrimSpace(text) && utf8.RuneCountInString(text) <= maxAttributeText
+}
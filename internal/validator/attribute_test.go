@@ -0,0 +1,98 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+func TestAttributesInvalidReason(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes []models.Attribute
+		want       string
+	}{
+		{name: "no attributes", want: ""},
+		{name: "all types", attributes: []models.Attribute{
+			{Name: "material", Type: models.AttributeString},
+			{Name: "pages", Type: models.AttributeNumber, Required: true},
+			{Name: "season", Type: models.AttributeEnum, Values: []string{"summer", "winter"}},
+			{Name: "waterproof", Type: models.AttributeBool},
+			{Name: "weight", Type: models.AttributeUnit, Unit: "kg"},
+		}, want: ""},
+		{name: "name with dot", attributes: []models.Attribute{{Name: "size.eu", Type: models.AttributeNumber}}, want: "incorrect name of attribute 1"},
+		{name: "repeated name", attributes: []models.Attribute{
+			{Name: "size", Type: models.AttributeNumber},
+			{Name: "size", Type: models.AttributeString},
+		}, want: "attribute 2 repeats attribute 1"},
+		{name: "unknown type", attributes: []models.Attribute{{Name: "size", Type: "date"}}, want: "unknown type of attribute 1"},
+		{name: "enum without values", attributes: []models.Attribute{{Name: "season", Type: models.AttributeEnum}}, want: "attribute 1 must have from 1 to 200 values"},
+		{name: "bool with values", attributes: []models.Attribute{{Name: "waterproof", Type: models.AttributeBool, Values: []string{"yes"}}}, want: "attribute 1 of type bool can't have values"},
+		{name: "unit without unit", attributes: []models.Attribute{{Name: "weight", Type: models.AttributeUnit}}, want: "attribute 1 must have a unit"},
+		{name: "number with unit", attributes: []models.Attribute{{Name: "weight", Type: models.AttributeNumber, Unit: "kg"}}, want: "attribute 1 of type number can't have a unit"},
+		{name: "repeated value", attributes: []models.Attribute{{Name: "season", Type: models.AttributeEnum, Values: []string{"summer", "summer"}}}, want: "value 2 of attribute 1 repeats value 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AttributesInvalidReason(tt.attributes); got != tt.want {
+				t.Errorf("AttributesInvalidReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttributeIssues(t *testing.T) {
+	schemas := map[string][]models.Attribute{
+		"books": {
+			{Name: "pages", Type: models.AttributeNumber, Required: true},
+			{Name: "cover", Type: models.AttributeEnum, Values: []string{"hard", "soft"}},
+		},
+		"gifts": {
+			{Name: "wrapped", Type: models.AttributeBool, Required: true},
+			{Name: "weight", Type: models.AttributeUnit, Unit: "g"},
+		},
+	}
+	tests := []struct {
+		name   string
+		codes  []string
+		values map[string]interface{}
+		want   []models.AttributeIssue
+	}{
+		{
+			name: "fits",
+			codes: []string{"books", "gifts"},
+			values: map[string]interface{}{"pages": float64(320), "cover": "hard", "wrapped": false, "weight": 450.5},
+		},
+		{
+			name: "category without schema",
+			codes: []string{"other"},
+		},
+		{
+			name: "missing and wrong values",
+			codes: []string{"books", "gifts"},
+			values: map[string]interface{}{"pages": "320", "cover": "leather", "weight": 450.5},
+			want: []models.AttributeIssue{
+				{Attribute: "pages", Category: "books", Reason: "value must be a number"},
+				{Attribute: "cover", Category: "books", Reason: `value "leather" is not allowed`},
+				{Attribute: "wrapped", Category: "gifts", Reason: "value is required"},
+			},
+		},
+		{
+			name: "not declared",
+			codes: []string{"books"},
+			values: map[string]interface{}{"pages": float64(10), "weight": 450.5, "colour": "red"},
+			want: []models.AttributeIssue{
+				{Attribute: "colour", Reason: "attribute is not declared by categories of the product"},
+				{Attribute: "weight", Reason: "attribute is not declared by categories of the product"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AttributeIssues(schemas, tt.codes, tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AttributeIssues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// skuRegex allows letters, digits and separators inside, up to 64 characters
+var skuRegex = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9._/-]{0,62}[A-Za-z0-9])?$`)
+
+func ValidSku(sku string) bool {
+	return skuRegex.MatchString(sku)
+}
+
+// ValidGTIN checks length and check digit of GTIN-8, UPC-A (GTIN-12), EAN-13 and GTIN-14 codes
+func ValidGTIN(code string) bool {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+	sum := 0
+	for idx := len(code) - 1; idx >= 0; idx-- {
+		if code[idx] < '0' || code[idx] > '9' {
+			return false
+		}
+		digit := int(code[idx] - '0')
+		// weights from the right are 1 for the check digit, then 3, 1, 3...
+		if (len(code) - 1 - idx) % 2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum % 10 == 0
+}
+
+// IdentifiersInvalidReason returns the reason why the sku or the barcodes are invalid or empty string,
+// empty sku means the product has no sku
+func IdentifiersInvalidReason(sku string, barcodes []string) string {
+	if sku != "" && !ValidSku(sku) {
+		return "incorrect sku"
+	}
+	for idx, code := range barcodes {
+		if !ValidGTIN(code) {
+			return fmt.Sprintf("incorrect barcode %d", idx+1)
+		}
+		for otherIdx, other := range barcodes[:idx] {
+			if other == code {
+				return fmt.Sprintf("barcode %d repeats barcode %d", idx+1, otherIdx+1)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package validator
+
+import "testing"
+
+func TestValidGTIN(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{name: "gtin-8", code: "96385074", want: true},
+		{name: "upc-a", code: "036000291452", want: true},
+		{name: "ean-13", code: "4006381333931", want: true},
+		{name: "gtin-14", code: "10614141000415", want: true},
+		{name: "wrong check digit", code: "4006381333932", want: false},
+		{name: "wrong length", code: "400638133393", want: false},
+		{name: "not a number", code: "40063813339a1", want: false},
+		{name: "empty", code: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidGTIN(tt.code); got != tt.want {
+				t.Errorf("ValidGTIN() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentifiersInvalidReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		sku      string
+		barcodes []string
+		want     string
+	}{
+		{name: "no identifiers", want: ""},
+		{name: "sku and barcodes", sku: "SHIRT-001/L", barcodes: []string{"4006381333931", "96385074"}, want: ""},
+		{name: "sku with space", sku: "SHIRT 001", want: "incorrect sku"},
+		{name: "sku ends with separator", sku: "SHIRT-", want: "incorrect sku"},
+		{name: "bad barcode", sku: "SHIRT-001", barcodes: []string{"96385074", "4006381333932"}, want: "incorrect barcode 2"},
+		{name: "repeated barcode", barcodes: []string{"96385074", "96385074"}, want: "barcode 2 repeats barcode 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IdentifiersInvalidReason(tt.sku, tt.barcodes); got != tt.want {
+				t.Errorf("IdentifiersInvalidReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
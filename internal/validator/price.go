@@ -0,0 +1,71 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// currencies are active ISO 4217 codes without precious metals, funds and testing codes
+var currencies = map[string]struct{}{}
+
+func init() {
+	for _, code := range []string{
+		"AED", "AFN", "ALL", "AMD", "ANG", "AOA", "ARS", "AUD", "AWG", "AZN",
+		"BAM", "BBD", "BDT", "BGN", "BHD", "BIF", "BMD", "BND", "BOB", "BRL",
+		"BSD", "BTN", "BWP", "BYN", "BZD", "CAD", "CDF", "CHF", "CLP", "CNY",
+		"COP", "CRC", "CUP", "CVE", "CZK", "DJF", "DKK", "DOP", "DZD", "EGP",
+		"ERN", "ETB", "EUR", "FJD", "FKP", "GBP", "GEL", "GHS", "GIP", "GMD",
+		"GNF", "GTQ", "GYD", "HKD", "HNL", "HTG", "HUF", "IDR", "ILS", "INR",
+		"IQD", "IRR", "ISK", "JMD", "JOD", "JPY", "KES", "KGS", "KHR", "KMF",
+		"KPW", "KRW", "KWD", "KYD", "KZT", "LAK", "LBP", "LKR", "LRD", "LSL",
+		"LYD", "MAD", "MDL", "MGA", "MKD", "MMK", "MNT", "MOP", "MRU", "MUR",
+		"MVR", "MWK", "MXN", "MYR", "MZN", "NAD", "NGN", "NIO", "NOK", "NPR",
+		"NZD", "OMR", "PAB", "PEN", "PGK", "PHP", "PKR", "PLN", "PYG", "QAR",
+		"RON", "RSD", "RUB", "RWF", "SAR", "SBD", "SCR", "SDG", "SEK", "SGD",
+		"SHP", "SLE", "SOS", "SRD", "SSP", "STN", "SVC", "SYP", "SZL", "THB",
+		"TJS", "TMT", "TND", "TOP", "TRY", "TTD", "TWD", "TZS", "UAH", "UGX",
+		"USD", "UYU", "UZS", "VES", "VND", "VUV", "WST", "XAF", "XCD", "XOF",
+		"XPF", "YER", "ZAR", "ZMW", "ZWG",
+	} {
+		currencies[code] = struct{}{}
+	}
+}
+
+// ValidCurrency reports whether code is an ISO 4217 currency code in upper case
+func ValidCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
+// PricesInvalidReason checks prices of the product and returns the reason why they are invalid or empty string.
+// Prices of the same kind and currency must not be valid at the same time
+func PricesInvalidReason(prices []models.Price) string {
+	for idx, price := range prices {
+		if price.Kind != models.PriceKindList && price.Kind != models.PriceKindSale {
+			return fmt.Sprintf("incorrect kind of price %d", idx+1)
+		}
+		if price.Amount < 0 {
+			return fmt.Sprintf("negative amount of price %d", idx+1)
+		}
+		if !ValidCurrency(price.Currency) {
+			return fmt.Sprintf("unknown currency of price %d", idx+1)
+		}
+		if price.ValidFrom != nil && price.ValidTo != nil && !price.ValidFrom.Before(*price.ValidTo) {
+			return fmt.Sprintf("empty validity window of price %d", idx+1)
+		}
+		for otherIdx, other := range prices[:idx] {
+			if other.Kind == price.Kind && other.Currency == price.Currency && overlap(other, price) {
+				return fmt.Sprintf("price %d overlaps price %d", idx+1, otherIdx+1)
+			}
+		}
+	}
+	return ""
+}
+
+// overlap reports whether validity windows of the prices have common time
+func overlap(a, b models.Price) bool {
+	aStartsBeforeBEnds := a.ValidFrom == nil || b.ValidTo == nil || a.ValidFrom.Before(*b.ValidTo)
+	bStartsBeforeAEnds := b.ValidFrom == nil || a.ValidTo == nil || b.ValidFrom.Before(*a.ValidTo)
+	return aStartsBeforeBEnds && bStartsBeforeAEnds
+}
@@ -0,0 +1,76 @@
+package validator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+func TestPricesInvalidReason(t *testing.T) {
+	day := func(d int) *time.Time {
+		date := time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+	tests := []struct {
+		name   string
+		prices []models.Price
+		want   string
+	}{
+		{
+			name: "no prices",
+			want: "",
+		},
+		{
+			name: "list and sale in windows",
+			prices: []models.Price{
+				{Kind: models.PriceKindList, Amount: 1999, Currency: "USD"},
+				{Kind: models.PriceKindSale, Amount: 1499, Currency: "USD", ValidFrom: day(1), ValidTo: day(8)},
+				{Kind: models.PriceKindSale, Amount: 1299, Currency: "USD", ValidFrom: day(8)},
+				{Kind: models.PriceKindList, Amount: 1800, Currency: "EUR"},
+			},
+			want: "",
+		},
+		{
+			name: "unknown kind",
+			prices: []models.Price{{Kind: "wholesale", Amount: 100, Currency: "USD"}},
+			want: "incorrect kind of price 1",
+		},
+		{
+			name: "negative amount",
+			prices: []models.Price{{Kind: models.PriceKindList, Amount: -1, Currency: "USD"}},
+			want: "negative amount of price 1",
+		},
+		{
+			name: "lower case currency",
+			prices: []models.Price{{Kind: models.PriceKindList, Amount: 100, Currency: "usd"}},
+			want: "unknown currency of price 1",
+		},
+		{
+			name: "not a currency",
+			prices: []models.Price{{Kind: models.PriceKindList, Amount: 100, Currency: "XAU"}},
+			want: "unknown currency of price 1",
+		},
+		{
+			name: "window ends before it starts",
+			prices: []models.Price{{Kind: models.PriceKindSale, Amount: 100, Currency: "USD", ValidFrom: day(8), ValidTo: day(8)}},
+			want: "empty validity window of price 1",
+		},
+		{
+			name: "open windows overlap",
+			prices: []models.Price{
+				{Kind: models.PriceKindSale, Amount: 100, Currency: "USD", ValidTo: day(8)},
+				{Kind: models.PriceKindList, Amount: 100, Currency: "USD"},
+				{Kind: models.PriceKindSale, Amount: 90, Currency: "USD", ValidFrom: day(7)},
+			},
+			want: "price 3 overlaps price 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PricesInvalidReason(tt.prices); got != tt.want {
+				t.Errorf("PricesInvalidReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
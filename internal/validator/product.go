@@ -0,0 +1,41 @@
+package validator
+
+import (
+	"unicode/utf8"
+
+	"github.com/EwvwGeN/cataloger/internal/config"
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// ProductNameMaxLen is the longest name of the product in characters
+const ProductNameMaxLen = 150
+
+// ProductInvalidReason checks product fields with the config regexes and the limits of the catalog
+// and returns the reason why the product is invalid or empty string
+func ProductInvalidReason(product models.Product, validCfg config.Validator) string {
+	if !ValideteByRegex(product.Name, validCfg.ProductNameValidate) {
+		return "incorrect product name"
+	}
+	if !ValideteByRegex(product.Description, validCfg.ProductDescValidate) {
+		return "incorrect product description"
+	}
+	if reason := ProductLimitsInvalidReason(product); reason != "" {
+		return reason
+	}
+	return OptionsInvalidReason(product.Options)
+}
+
+// ProductLimitsInvalidReason checks the limits every saved product keeps whatever its source: not empty name
+// and description, sku, barcodes and prices. It returns the reason why the product is invalid or empty string
+func ProductLimitsInvalidReason(product models.Product) string {
+	if product.Name == "" || utf8.RuneCountInString(product.Name) > ProductNameMaxLen {
+		return "incorrect product name"
+	}
+	if product.Description == "" {
+		return "incorrect product description"
+	}
+	if reason := IdentifiersInvalidReason(product.Sku, product.Barcodes); reason != "" {
+		return reason
+	}
+	return PricesInvalidReason(product.Prices)
+}
@@ -0,0 +1,59 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+func TestProductLimitsInvalidReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		product models.Product
+		want    string
+	}{
+		{
+			name: "longest name",
+			product: models.Product{Name: strings.Repeat("я", ProductNameMaxLen), Description: "Cotton shirt", Sku: "SH-1"},
+			want: "",
+		},
+		{
+			name: "empty name",
+			product: models.Product{Description: "Cotton shirt"},
+			want: "incorrect product name",
+		},
+		{
+			name: "too long name",
+			product: models.Product{Name: strings.Repeat("a", ProductNameMaxLen+1), Description: "Cotton shirt"},
+			want: "incorrect product name",
+		},
+		{
+			name: "empty description",
+			product: models.Product{Name: "Shirt"},
+			want: "incorrect product description",
+		},
+		{
+			name: "too long sku",
+			product: models.Product{Name: "Shirt", Description: "Cotton shirt", Sku: strings.Repeat("a", 65)},
+			want: "incorrect sku",
+		},
+		{
+			name: "wrong barcode",
+			product: models.Product{Name: "Shirt", Description: "Cotton shirt", Barcodes: []string{"96385075"}},
+			want: "incorrect barcode 1",
+		},
+		{
+			name: "wrong price",
+			product: models.Product{Name: "Shirt", Description: "Cotton shirt", Prices: []models.Price{{Kind: models.PriceKindList, Amount: -1, Currency: "USD"}}},
+			want: "negative amount of price 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProductLimitsInvalidReason(tt.product); got != tt.want {
+				t.Errorf("ProductLimitsInvalidReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// warehouseCodeRegex keeps codes usable in paths and query params, up to 64 characters
+var warehouseCodeRegex = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9_-]{0,62}[a-z0-9])?$`)
+
+const (
+	maxWarehouseName = 255
+	maxStockQuantity = 1000000
+	maxStockReason   = 500
+)
+
+// WarehouseInvalidReason returns the reason why the warehouse is invalid or empty string
+func WarehouseInvalidReason(warehouse models.Warehouse) string {
+	if !warehouseCodeRegex.MatchString(warehouse.Code) {
+		return "incorrect warehouse code"
+	}
+	if strings.TrimSpace(warehouse.Name) == "" || utf8.RuneCountInString(warehouse.Name) > maxWarehouseName {
+		return fmt.Sprintf("warehouse name must have from 1 to %d characters", maxWarehouseName)
+	}
+	return ""
+}
+
+// StockChangeInvalidReason returns the reason why the change is invalid or empty string.
+// Adjustments have a signed not zero quantity, reservations a positive one
+func StockChangeInvalidReason(change models.StockChange, adjust bool) string {
+	if !warehouseCodeRegex.MatchString(change.Warehouse) {
+		return "incorrect warehouse code"
+	}
+	switch {
+	case change.Quantity > maxStockQuantity || change.Quantity < -maxStockQuantity:
+		return fmt.Sprintf("quantity must be within %d units", maxStockQuantity)
+	case adjust && change.Quantity == 0:
+		return "quantity can't be zero"
+	case !adjust && change.Quantity <= 0:
+		return "quantity must be positive"
+	}
+	return StockReasonInvalidReason(change.Reason)
+}
+
+// StockReasonInvalidReason returns the reason why the reason of a stock change is invalid or empty string
+func StockReasonInvalidReason(reason string) string {
+	if utf8.RuneCountInString(reason) > maxStockReason {
+		return fmt.Sprintf("reason is longer than %d characters", maxStockReason)
+	}
+	return ""
+}
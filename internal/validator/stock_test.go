@@ -0,0 +1,52 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+func TestWarehouseInvalidReason(t *testing.T) {
+	tests := []struct {
+		name      string
+		warehouse models.Warehouse
+		want      string
+	}{
+		{name: "valid", warehouse: models.Warehouse{Code: "main-2", Name: "Main warehouse"}, want: ""},
+		{name: "upper case code", warehouse: models.Warehouse{Code: "Main", Name: "Main"}, want: "incorrect warehouse code"},
+		{name: "trailing dash", warehouse: models.Warehouse{Code: "main-", Name: "Main"}, want: "incorrect warehouse code"},
+		{name: "blank name", warehouse: models.Warehouse{Code: "main", Name: " "}, want: "warehouse name must have from 1 to 255 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WarehouseInvalidReason(tt.warehouse); got != tt.want {
+				t.Errorf("WarehouseInvalidReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStockChangeInvalidReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		change models.StockChange
+		adjust bool
+		want   string
+	}{
+		{name: "write off", change: models.StockChange{Warehouse: "main", Quantity: -3, Reason: "damaged"}, adjust: true, want: ""},
+		{name: "zero adjustment", change: models.StockChange{Warehouse: "main"}, adjust: true, want: "quantity can't be zero"},
+		{name: "reservation", change: models.StockChange{Warehouse: "main", Quantity: 2}, want: ""},
+		{name: "negative reservation", change: models.StockChange{Warehouse: "main", Quantity: -2}, want: "quantity must be positive"},
+		{name: "too many units", change: models.StockChange{Warehouse: "main", Quantity: 1000001}, adjust: true, want: "quantity must be within 1000000 units"},
+		{name: "no warehouse", change: models.StockChange{Quantity: 1}, want: "incorrect warehouse code"},
+		{name: "long reason", change: models.StockChange{Warehouse: "main", Quantity: 1, Reason: strings.Repeat("a", 501)}, want: "reason is longer than 500 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StockChangeInvalidReason(tt.change, tt.adjust); got != tt.want {
+				t.Errorf("StockChangeInvalidReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
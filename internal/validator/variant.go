@@ -0,0 +1,117 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+// optionRegex allows letters, digits and separators inside names and values of options, up to 32 characters
+var optionRegex = regexp.MustCompile(`^[\p{L}\p{N}](?:[\p{L}\p{N} ._/+-]{0,30}[\p{L}\p{N}])?$`)
+
+const (
+	maxOptions      = 5
+	maxOptionValues = 100
+)
+
+// OptionsInvalidReason returns the reason why the options of the product are invalid or empty string
+func OptionsInvalidReason(options []models.VariantOption) string {
+	if len(options) > maxOptions {
+		return fmt.Sprintf("more than %d options", maxOptions)
+	}
+	for idx, option := range options {
+		if !optionRegex.MatchString(option.Name) {
+			return fmt.Sprintf("incorrect name of option %d", idx+1)
+		}
+		for otherIdx, other := range options[:idx] {
+			if other.Name == option.Name {
+				return fmt.Sprintf("option %d repeats option %d", idx+1, otherIdx+1)
+			}
+		}
+		if len(option.Values) == 0 || len(option.Values) > maxOptionValues {
+			return fmt.Sprintf("option %d must have from 1 to %d values", idx+1, maxOptionValues)
+		}
+		for valueIdx, value := range option.Values {
+			if !optionRegex.MatchString(value) {
+				return fmt.Sprintf("incorrect value %d of option %d", valueIdx+1, idx+1)
+			}
+			for otherIdx, other := range option.Values[:valueIdx] {
+				if other == value {
+					return fmt.Sprintf("value %d of option %d repeats value %d", valueIdx+1, idx+1, otherIdx+1)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// VariantInvalidReason returns the reason why the variant is invalid by itself or empty string,
+// its option values are matched with options of the product by VariantMismatchReason
+func VariantInvalidReason(variant models.Variant) string {
+	if !ValidSku(variant.Sku) {
+		return "incorrect sku"
+	}
+	if len(variant.Options) == 0 {
+		return "variant has no options"
+	}
+	if reason := OptionValuesInvalidReason(variant.Options); reason != "" {
+		return reason
+	}
+	return PricesInvalidReason(variant.Prices)
+}
+
+// OptionValuesInvalidReason checks names and values of variant options and returns the reason why they are invalid or empty string
+func OptionValuesInvalidReason(values map[string]string) string {
+	for _, name := range sortedNames(values) {
+		if !optionRegex.MatchString(name) {
+			return fmt.Sprintf("incorrect option %q", name)
+		}
+		if !optionRegex.MatchString(values[name]) {
+			return fmt.Sprintf("incorrect value of option %q", name)
+		}
+	}
+	return ""
+}
+
+// VariantMismatchReason returns the reason why the option values of the variant don't fit options of the product
+// or empty string. The variant must have one of the allowed values for every option and no other options
+func VariantMismatchReason(options []models.VariantOption, values map[string]string) string {
+	if len(options) == 0 {
+		return "product has no options"
+	}
+	known := make(map[string]struct{}, len(options))
+	for _, option := range options {
+		known[option.Name] = struct{}{}
+		value, ok := values[option.Name]
+		if !ok {
+			return fmt.Sprintf("no value of option %q", option.Name)
+		}
+		allowed := false
+		for _, other := range option.Values {
+			if other == value {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Sprintf("option %q has no value %q", option.Name, value)
+		}
+	}
+	for _, name := range sortedNames(values) {
+		if _, ok := known[name]; !ok {
+			return fmt.Sprintf("unknown option %q", name)
+		}
+	}
+	return ""
+}
+
+func sortedNames(values map[string]string) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/EwvwGeN/cataloger/internal/domain/models"
+)
+
+func TestOptionsInvalidReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []models.VariantOption
+		want    string
+	}{
+		{name: "no options", want: ""},
+		{name: "size and colour", options: []models.VariantOption{
+			{Name: "Size", Values: []string{"S", "M", "L"}},
+			{Name: "Colour", Values: []string{"Dark blue", "Red"}},
+		}, want: ""},
+		{name: "empty name", options: []models.VariantOption{{Values: []string{"S"}}}, want: "incorrect name of option 1"},
+		{name: "repeated name", options: []models.VariantOption{
+			{Name: "Size", Values: []string{"S"}},
+			{Name: "Size", Values: []string{"M"}},
+		}, want: "option 2 repeats option 1"},
+		{name: "no values", options: []models.VariantOption{{Name: "Size"}}, want: "option 1 must have from 1 to 100 values"},
+		{name: "value with trailing space", options: []models.VariantOption{{Name: "Size", Values: []string{"S", "M "}}}, want: "incorrect value 2 of option 1"},
+		{name: "repeated value", options: []models.VariantOption{{Name: "Size", Values: []string{"S", "M", "S"}}}, want: "value 3 of option 1 repeats value 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OptionsInvalidReason(tt.options); got != tt.want {
+				t.Errorf("OptionsInvalidReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariantMismatchReason(t *testing.T) {
+	options := []models.VariantOption{
+		{Name: "Size", Values: []string{"S", "M"}},
+		{Name: "Colour", Values: []string{"Red"}},
+	}
+	tests := []struct {
+		name    string
+		options []models.VariantOption
+		values  map[string]string
+		want    string
+	}{
+		{name: "fits", options: options, values: map[string]string{"Size": "M", "Colour": "Red"}, want: ""},
+		{name: "product without options", values: map[string]string{"Size": "M"}, want: "product has no options"},
+		{name: "missing option", options: options, values: map[string]string{"Size": "M"}, want: `no value of option "Colour"`},
+		{name: "not allowed value", options: options, values: map[string]string{"Size": "XL", "Colour": "Red"}, want: `option "Size" has no value "XL"`},
+		{name: "unknown option", options: options, values: map[string]string{"Size": "S", "Colour": "Red", "Fit": "Slim"}, want: `unknown option "Fit"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VariantMismatchReason(tt.options, tt.values); got != tt.want {
+				t.Errorf("VariantMismatchReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}